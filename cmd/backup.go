@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"dbbackup/internal/cloud"
+	"dbbackup/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -14,39 +16,82 @@ var backupCmd = &cobra.Command{
 	Long: `Create database backups with support for various modes:
 
 Backup Modes:
-  cluster    - Full cluster backup (all databases + globals) [PostgreSQL only]
+  cluster    - Full cluster backup (all databases + globals)
   single     - Single database backup
   sample     - Sample database backup (reduced dataset)
+  base       - Physical base backup for PITR (PostgreSQL, pg_basebackup)
 
 Examples:
-  # Full cluster backup (PostgreSQL)
+  # Full cluster backup
   dbbackup backup cluster --db-type postgres
+  dbbackup backup cluster --db-type mariadb
 
   # Single database backup
   dbbackup backup single mydb --db-type postgres
   dbbackup backup single mydb --db-type mysql
 
   # Sample database backup
-  dbbackup backup sample mydb --sample-ratio 10 --db-type postgres`,
+  dbbackup backup sample mydb --sample-ratio 10 --db-type postgres
+
+  # Physical base backup for point-in-time recovery
+  dbbackup backup base --db-type postgres`,
 }
 
 var clusterCmd = &cobra.Command{
 	Use:   "cluster",
-	Short: "Create full cluster backup (PostgreSQL only)",
-	Long:  `Create a complete backup of the entire PostgreSQL cluster including all databases and global objects (roles, tablespaces, etc.)`,
+	Short: "Create full cluster backup",
+	Long: `Create a complete backup of the entire database server including all databases and global objects.
+
+PostgreSQL: every database plus roles and tablespaces (pg_dumpall --globals-only).
+MySQL/MariaDB: every schema (one mysqldump each) plus users and grants.`,
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runClusterBackup(cmd.Context())
 	},
 }
 
+var baseCmd = &cobra.Command{
+	Use:   "base",
+	Short: "Create physical base backup for PITR",
+	Long: `Create a physical base backup of the whole PostgreSQL cluster with pg_basebackup.
+
+The backup is taken in tar format with the required WAL streamed alongside the
+data (--wal-method=stream) and stored as a single base_<timestamp>.tar.gz
+(.tar.zst or .tar.lz4 with --compression-algorithm) that extracts into a
+ready-to-recover data directory. The WAL start/stop position and
+timeline are recorded in the .meta.json so 'restore pitr' can check the base
+backup against the WAL archive before extracting it.
+
+Requires a user with the REPLICATION attribute and a pg_hba.conf entry that
+allows replication connections.
+
+Examples:
+  # Base backup into the default backup directory
+  dbbackup backup base
+
+  # Encrypted base backup uploaded to S3
+  dbbackup backup base --encrypt --encryption-key-file key.bin --cloud s3://backups/pg`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBaseBackup(cmd.Context())
+	},
+}
+
 // Global variables for backup flags (to avoid initialization cycle)
 var (
 	backupTypeFlag     string
 	baseBackupFlag     string
+	dataDirFlag        string
 	encryptBackupFlag  bool
 	encryptionKeyFile  string
 	encryptionKeyEnv   string
+	keyProviderFlag    string
+	keyIDFlag          string
+	keyEndpointFlag    string
+	recipientFlags     []string
+	recipientsFileFlag string
+	cloudStreamFlag    bool
+	targetFlags        []string
 )
 
 var singleCmd = &cobra.Command{
@@ -56,14 +101,25 @@ var singleCmd = &cobra.Command{
 
 Backup Types:
   --backup-type full         - Complete full backup (default)
-  --backup-type incremental  - Incremental backup (only changed files since base) [NOT IMPLEMENTED]
+  --backup-type incremental  - Incremental backup (only data files changed since base)
+
+Incremental backups scan the server's data directory (auto-detected, or --data-dir)
+for files modified since the base backup. The base may be a full backup or a previous
+incremental; the resulting .meta.json records the complete backup chain.
 
 Examples:
   # Full backup (default)
   dbbackup backup single mydb
   
-  # Incremental backup (requires previous full backup) [COMING IN v2.2.1]
-  dbbackup backup single mydb --backup-type incremental --base-backup mydb_20250126.tar.gz`,
+  # Incremental backup (requires previous full backup)
+  dbbackup backup single mydb --backup-type incremental --base-backup mydb_20250126.tar.gz
+
+  # Incremental on top of the previous incremental, explicit data directory
+  dbbackup backup single mydb --backup-type incremental \
+    --base-backup mydb_incr_20250126_130000.tar.gz --data-dir /var/lib/postgresql/16/main
+
+  # Stream an encrypted dump into S3 without staging it on local disk
+  dbbackup backup single mydb --cloud s3://backups/pg --cloud-stream --encrypt --encryption-key-file key.bin`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		dbName := ""
@@ -89,7 +145,8 @@ Sampling Strategies:
   --sample-percent N   - Take N% of records (e.g., 20 = 20% of data)  
   --sample-count N     - Take first N records from each table
 
-Warning: Sample backups may break referential integrity due to sampling!`,
+Parent rows referenced through foreign keys are always included, so the
+sample restores without constraint violations.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		dbName := ""
@@ -110,27 +167,40 @@ func init() {
 	backupCmd.AddCommand(clusterCmd)
 	backupCmd.AddCommand(singleCmd)
 	backupCmd.AddCommand(sampleCmd)
+	backupCmd.AddCommand(baseCmd)
 	
 	// Incremental backup flags (single backup only) - using global vars to avoid initialization cycle
-	singleCmd.Flags().StringVar(&backupTypeFlag, "backup-type", "full", "Backup type: full or incremental [incremental NOT IMPLEMENTED]")
-	singleCmd.Flags().StringVar(&baseBackupFlag, "base-backup", "", "Path to base backup (required for incremental)")
+	singleCmd.Flags().StringVar(&backupTypeFlag, "backup-type", "full", "Backup type: full or incremental")
+	singleCmd.Flags().StringVar(&baseBackupFlag, "base-backup", "", "Path to base backup (required for incremental, relative paths resolved against --backup-dir)")
+	singleCmd.Flags().StringVar(&dataDirFlag, "data-dir", "", "Database data directory to scan for incremental backups (auto-detected if empty)")
+	singleCmd.Flags().BoolVar(&cloudStreamFlag, "cloud-stream", false, "Stream the dump straight to cloud storage without a local backup file (requires --cloud)")
 	
 	// Encryption flags for all backup commands
-	for _, cmd := range []*cobra.Command{clusterCmd, singleCmd, sampleCmd} {
+	for _, cmd := range []*cobra.Command{clusterCmd, singleCmd, sampleCmd, baseCmd} {
 		cmd.Flags().BoolVar(&encryptBackupFlag, "encrypt", false, "Encrypt backup with AES-256-GCM")
 		cmd.Flags().StringVar(&encryptionKeyFile, "encryption-key-file", "", "Path to encryption key file (32 bytes)")
 		cmd.Flags().StringVar(&encryptionKeyEnv, "encryption-key-env", "DBBACKUP_ENCRYPTION_KEY", "Environment variable containing encryption key/passphrase")
+		cmd.Flags().StringVar(&keyProviderFlag, "key-provider", "", "Master key provider wrapping the backup's data key: file, passphrase, vault, kms or x25519 (default: x25519 with --recipient, otherwise file or passphrase, by key)")
+		cmd.Flags().StringVar(&keyIDFlag, "key-id", "", "Vault transit key (mount/key) or KMS key ID/ARN")
+		cmd.Flags().StringVar(&keyEndpointFlag, "key-endpoint", "", "Vault address (default: $VAULT_ADDR) or KMS-compatible endpoint")
+		cmd.Flags().StringArrayVar(&recipientFlags, "recipient", nil, "Encrypt to an age X25519 public key (age1...); repeatable. Restores need a matching --identity-file")
+		cmd.Flags().StringVar(&recipientsFileFlag, "recipients-file", "", "File with age X25519 public keys to encrypt to, one per line")
 	}
 	
 	// Cloud storage flags for all backup commands
-	for _, cmd := range []*cobra.Command{clusterCmd, singleCmd, sampleCmd} {
+	for _, cmd := range []*cobra.Command{clusterCmd, singleCmd, sampleCmd, baseCmd} {
 		cmd.Flags().String("cloud", "", "Cloud storage URI (e.g., s3://bucket/path) - takes precedence over individual flags")
 		cmd.Flags().Bool("cloud-auto-upload", false, "Automatically upload backup to cloud after completion")
-		cmd.Flags().String("cloud-provider", "", "Cloud provider (s3, minio, b2)")
+		cmd.Flags().String("cloud-provider", "", "Cloud provider (s3, minio, b2, azure, gcs, sftp, file, webdav, webdavs)")
 		cmd.Flags().String("cloud-bucket", "", "Cloud bucket name")
 		cmd.Flags().String("cloud-region", "us-east-1", "Cloud region")
 		cmd.Flags().String("cloud-endpoint", "", "Cloud endpoint (for MinIO/B2)")
 		cmd.Flags().String("cloud-prefix", "", "Cloud key prefix")
+		cmd.Flags().StringArrayVar(&targetFlags, "target", nil, "Also replicate the backup to a named target (name=URI, e.g. dr=s3://dr-bucket/prod/); repeatable, replaces targets from the config file")
+		cmd.Flags().String("object-lock", "", "Make uploaded backups immutable with object lock: governance or compliance (S3, Azure, GCS)")
+		cmd.Flags().String("lock-until", "", "Object lock retain-until period (30d, 12w, 1y) or date (2027-01-31)")
+		cmd.Flags().Bool("legal-hold", false, "Place a legal hold on uploaded backups (GCS: temporary hold); it has no expiry and must be released by hand")
+		cmd.Flags().String("storage-class", "", "Storage class for uploaded backups (S3: STANDARD_IA, GLACIER_IR, GLACIER...; Azure: Cool, Cold, Archive; GCS: NEARLINE, COLDLINE, ARCHIVE)")
 		
 		// Add PreRunE to update config from flags
 		originalPreRun := cmd.PreRunE
@@ -177,6 +247,28 @@ func init() {
 				}
 			}
 			
+			if c.Flags().Changed("target") {
+				if err := parseTargetFlags(); err != nil {
+					return err
+				}
+			}
+			
+			if c.Flags().Changed("object-lock") {
+				cfg.CloudLockMode, _ = c.Flags().GetString("object-lock")
+			}
+			if c.Flags().Changed("lock-until") {
+				cfg.CloudLockUntil, _ = c.Flags().GetString("lock-until")
+			}
+			if c.Flags().Changed("legal-hold") {
+				cfg.CloudLegalHold, _ = c.Flags().GetBool("legal-hold")
+			}
+			if c.Flags().Changed("storage-class") {
+				cfg.CloudStorageClass, _ = c.Flags().GetString("storage-class")
+			}
+			if _, err := cloud.NewObjectLock(cfg.CloudLockMode, cfg.CloudLockUntil, cfg.CloudLegalHold, time.Now()); err != nil {
+				return err
+			}
+			
 			return nil
 		}
 	}
@@ -219,6 +311,29 @@ func init() {
 	sampleCmd.MarkFlagsMutuallyExclusive("sample-ratio", "sample-percent", "sample-count")
 }
 
+// parseTargetFlags replaces the configured replication targets with the
+// --target flags
+func parseTargetFlags() error {
+	targets := make([]config.CloudTarget, 0, len(targetFlags))
+	seen := make(map[string]bool)
+	for _, spec := range targetFlags {
+		target, err := config.ParseCloudTarget(spec)
+		if err != nil {
+			return err
+		}
+		if seen[target.Name] {
+			return fmt.Errorf("duplicate target %q", target.Name)
+		}
+		seen[target.Name] = true
+		if _, err := cloud.ParseCloudURI(target.URI); err != nil {
+			return fmt.Errorf("invalid URI for target %s: %w", target.Name, err)
+		}
+		targets = append(targets, target)
+	}
+	cfg.CloudTargets = targets
+	return nil
+}
+
 // parseCloudURIFlag parses the --cloud URI flag and updates config
 func parseCloudURIFlag(cmd *cobra.Command) error {
 	cloudURI, _ := cmd.Flags().GetString("cloud")
@@ -248,6 +363,10 @@ func parseCloudURIFlag(cmd *cobra.Command) error {
 		cfg.CloudEndpoint = uri.Endpoint
 	}
 	
+	if uri.User != "" {
+		cfg.CloudAccessKey = uri.User
+	}
+	
 	if uri.Path != "" {
 		cfg.CloudPrefix = uri.Dir()
 	}
@@ -5,19 +5,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"dbbackup/internal/backup"
 	"dbbackup/internal/config"
 	"dbbackup/internal/database"
+	"dbbackup/internal/metadata"
+	"dbbackup/internal/retention"
 	"dbbackup/internal/security"
 )
 
 // runClusterBackup performs a full cluster backup
 func runClusterBackup(ctx context.Context) error {
-	if !cfg.IsPostgreSQL() {
-		return fmt.Errorf("cluster backup requires PostgreSQL (detected: %s). Use 'backup single' for individual database backups", cfg.DisplayDatabaseType())
+	if !cfg.IsPostgreSQL() && !cfg.IsMySQL() {
+		return fmt.Errorf("cluster backup requires PostgreSQL, MySQL or MariaDB (detected: %s). Use 'backup single' for individual database backups", cfg.DisplayDatabaseType())
 	}
 	
 	// Update config from environment
@@ -77,28 +78,30 @@ func runClusterBackup(ctx context.Context) error {
 	// Create backup engine
 	engine := backup.New(cfg, log, db)
 	
+	// Dumps are encrypted while they are written
+	if isEncryptionEnabled() {
+		encOpts, envelope, err := newBackupEncryption(ctx)
+		if err != nil {
+			auditLogger.LogBackupFailed(user, "all_databases", err)
+			return err
+		}
+		engine.SetEncryption(encOpts, envelope)
+	}
+	
 	// Perform cluster backup
 	if err := engine.BackupCluster(ctx); err != nil {
 		auditLogger.LogBackupFailed(user, "all_databases", err)
 		return err
 	}
 	
-	// Apply encryption if requested
-	if isEncryptionEnabled() {
-		if err := encryptLatestClusterBackup(); err != nil {
-			log.Error("Failed to encrypt backup", "error", err)
-			return fmt.Errorf("backup completed successfully but encryption failed. Unencrypted backup remains in %s: %w", cfg.BackupDir, err)
-		}
-		log.Info("Cluster backup encrypted successfully")
-	}
-	
 	// Audit log: backup success
 	auditLogger.LogBackupComplete(user, "all_databases", cfg.BackupDir, 0)
 	
 	// Cleanup old backups if retention policy is enabled
-	if cfg.RetentionDays > 0 {
-		retentionPolicy := security.NewRetentionPolicy(cfg.RetentionDays, cfg.MinBackups, log)
-		if deleted, freed, err := retentionPolicy.CleanupOldBackups(cfg.BackupDir); err != nil {
+	if cfg.RetentionDays > 0 || cfg.HasRetentionTiers() {
+		if retentionPolicy, err := newRetentionPolicy(); err != nil {
+			log.Warn("Invalid retention tiers, skipping cleanup", "error", err)
+		} else if deleted, freed, err := retentionPolicy.CleanupOldBackups(cfg.BackupDir); err != nil {
 			log.Warn("Failed to cleanup old backups", "error", err)
 		} else if deleted > 0 {
 			log.Info("Cleaned up old backups", "deleted", deleted, "freed_mb", freed/1024/1024)
@@ -124,10 +127,10 @@ func runSingleBackup(ctx context.Context, databaseName string) error {
 	// Update config from environment
 	cfg.UpdateFromEnvironment()
 	
-	// Get backup type and base backup from command line flags (set via global vars in PreRunE)
-	// These are populated by cobra flag binding in cmd/backup.go
-	backupType := "full"  // Default to full backup if not specified
-	baseBackup := ""      // Base backup path for incremental backups
+	backupType := backupTypeFlag
+	if backupType == "" {
+		backupType = "full"
+	}
 	
 	// Validate backup type
 	if backupType != "full" && backupType != "incremental" {
@@ -135,16 +138,32 @@ func runSingleBackup(ctx context.Context, databaseName string) error {
 	}
 	
 	// Validate incremental backup requirements
+	baseBackup := ""
 	if backupType == "incremental" {
 		if !cfg.IsPostgreSQL() && !cfg.IsMySQL() {
 			return fmt.Errorf("incremental backups require PostgreSQL or MySQL/MariaDB (detected: %s). Use --backup-type=full for other databases", cfg.DisplayDatabaseType())
 		}
-		if baseBackup == "" {
+		if baseBackupFlag == "" {
 			return fmt.Errorf("incremental backup requires --base-backup flag pointing to initial full backup archive")
 		}
-		// Verify base backup exists
-		if _, err := os.Stat(baseBackup); os.IsNotExist(err) {
-			return fmt.Errorf("base backup file not found at %s. Ensure path is correct and file exists", baseBackup)
+		resolved, err := resolveBaseBackup(baseBackupFlag, cfg.BackupDir)
+		if err != nil {
+			return err
+		}
+		baseBackup = resolved
+	}
+	
+	// Streaming writes nothing locally, so it needs a bucket and a full backup
+	cloudStream := cloudStreamFlag || cfg.CloudStream
+	if cloudStream {
+		if backupType == "incremental" {
+			return fmt.Errorf("--cloud-stream only supports full backups")
+		}
+		if !cfg.CloudEnabled || cfg.CloudBucket == "" {
+			return fmt.Errorf("--cloud-stream requires cloud storage (use --cloud or --cloud-provider/--cloud-bucket with --cloud-auto-upload)")
+		}
+		if len(cfg.CloudTargets) > 0 {
+			return fmt.Errorf("--cloud-stream uploads to a single destination and can't be combined with --target")
 		}
 	}
 	
@@ -220,39 +239,77 @@ func runSingleBackup(ctx context.Context, databaseName string) error {
 		log.Info("Creating incremental backup", "base_backup", baseBackup)
 		
 		// Create appropriate incremental engine based on database type
-		var incrEngine interface {
-			FindChangedFiles(context.Context, *backup.IncrementalBackupConfig) ([]backup.ChangedFile, error)
-			CreateIncrementalBackup(context.Context, *backup.IncrementalBackupConfig, []backup.ChangedFile) error
-		}
-		
+		var incrEngine backup.IncrementalBackupEngine
 		if cfg.IsPostgreSQL() {
 			incrEngine = backup.NewPostgresIncrementalEngine(log)
 		} else {
 			incrEngine = backup.NewMySQLIncrementalEngine(log)
 		}
 		
+		// Determine data directory (flag takes precedence over server setting)
+		dataDir := dataDirFlag
+		if dataDir == "" {
+			dataDir, err = db.GetDataDirectory(ctx)
+			if err != nil {
+				auditLogger.LogBackupFailed(user, databaseName, err)
+				return fmt.Errorf("failed to detect data directory (use --data-dir): %w", err)
+			}
+		}
+		if _, err := os.Stat(dataDir); err != nil {
+			err = fmt.Errorf("data directory %s is not accessible from this host: %w", dataDir, err)
+			auditLogger.LogBackupFailed(user, databaseName, err)
+			return err
+		}
+		
 		// Configure incremental backup
 		incrConfig := &backup.IncrementalBackupConfig{
 			BaseBackupPath:   baseBackup,
-			DataDirectory:    cfg.BackupDir, // Note: This should be the actual data directory
+			DataDirectory:    dataDir,
 			CompressionLevel: cfg.CompressionLevel,
+			OutputDir:        cfg.BackupDir,
+		}
+		
+		// Like full backups, the archive is encrypted while it is written
+		if isEncryptionEnabled() {
+			encOpts, envelope, err := newBackupEncryption(ctx)
+			if err != nil {
+				auditLogger.LogBackupFailed(user, databaseName, err)
+				return err
+			}
+			incrConfig.Encryption = encOpts
+			incrConfig.Envelope = envelope
 		}
 		
 		// Find changed files
 		changedFiles, err := incrEngine.FindChangedFiles(ctx, incrConfig)
 		if err != nil {
+			auditLogger.LogBackupFailed(user, databaseName, err)
 			return fmt.Errorf("failed to find changed files: %w", err)
 		}
 		
 		// Create incremental backup
-		if err := incrEngine.CreateIncrementalBackup(ctx, incrConfig, changedFiles); err != nil {
+		incrPath, err := incrEngine.CreateIncrementalBackup(ctx, incrConfig, changedFiles)
+		if err != nil {
+			auditLogger.LogBackupFailed(user, databaseName, err)
 			return fmt.Errorf("failed to create incremental backup: %w", err)
 		}
 		
-		log.Info("Incremental backup completed", "changed_files", len(changedFiles))
+		log.Info("Incremental backup completed", "output", incrPath, "changed_files", len(changedFiles))
 	} else {
-		// Full backup
-		backupErr = engine.BackupSingle(ctx, databaseName)
+		// Full backup, encrypted while it is written
+		if isEncryptionEnabled() {
+			encOpts, envelope, err := newBackupEncryption(ctx)
+			if err != nil {
+				auditLogger.LogBackupFailed(user, databaseName, err)
+				return err
+			}
+			engine.SetEncryption(encOpts, envelope)
+		}
+		if cloudStream {
+			backupErr = engine.BackupSingleToCloud(ctx, databaseName)
+		} else {
+			backupErr = engine.BackupSingle(ctx, databaseName)
+		}
 	}
 	
 	if backupErr != nil {
@@ -260,22 +317,14 @@ func runSingleBackup(ctx context.Context, databaseName string) error {
 		return backupErr
 	}
 	
-	// Apply encryption if requested
-	if isEncryptionEnabled() {
-		if err := encryptLatestBackup(databaseName); err != nil {
-			log.Error("Failed to encrypt backup", "error", err)
-			return fmt.Errorf("backup succeeded but encryption failed: %w", err)
-		}
-		log.Info("Backup encrypted successfully")
-	}
-	
 	// Audit log: backup success
 	auditLogger.LogBackupComplete(user, databaseName, cfg.BackupDir, 0)
 	
 	// Cleanup old backups if retention policy is enabled
-	if cfg.RetentionDays > 0 {
-		retentionPolicy := security.NewRetentionPolicy(cfg.RetentionDays, cfg.MinBackups, log)
-		if deleted, freed, err := retentionPolicy.CleanupOldBackups(cfg.BackupDir); err != nil {
+	if cfg.RetentionDays > 0 || cfg.HasRetentionTiers() {
+		if retentionPolicy, err := newRetentionPolicy(); err != nil {
+			log.Warn("Invalid retention tiers, skipping cleanup", "error", err)
+		} else if deleted, freed, err := retentionPolicy.CleanupOldBackups(cfg.BackupDir); err != nil {
 			log.Warn("Failed to cleanup old backups", "error", err)
 		} else if deleted > 0 {
 			log.Info("Cleaned up old backups", "deleted", deleted, "freed_mb", freed/1024/1024)
@@ -383,21 +432,22 @@ func runSampleBackup(ctx context.Context, databaseName string) error {
 	// Create backup engine
 	engine := backup.New(cfg, log, db)
 	
+	// Sample is encrypted while it is written
+	if isEncryptionEnabled() {
+		encOpts, envelope, err := newBackupEncryption(ctx)
+		if err != nil {
+			auditLogger.LogBackupFailed(user, databaseName, err)
+			return err
+		}
+		engine.SetEncryption(encOpts, envelope)
+	}
+	
 	// Perform sample backup
 	if err := engine.BackupSample(ctx, databaseName); err != nil {
 		auditLogger.LogBackupFailed(user, databaseName, err)
 		return err
 	}
 	
-	// Apply encryption if requested
-	if isEncryptionEnabled() {
-		if err := encryptLatestBackup(databaseName); err != nil {
-			log.Error("Failed to encrypt backup", "error", err)
-			return fmt.Errorf("backup succeeded but encryption failed: %w", err)
-		}
-		log.Info("Sample backup encrypted successfully")
-	}
-	
 	// Audit log: backup success
 	auditLogger.LogBackupComplete(user, databaseName, cfg.BackupDir, 0)
 	
@@ -414,124 +464,138 @@ func runSampleBackup(ctx context.Context, databaseName string) error {
 	
 	return nil
 }
-// encryptLatestBackup finds and encrypts the most recent backup for a database
-func encryptLatestBackup(databaseName string) error {
-	// Load encryption key
-	key, err := loadEncryptionKey(encryptionKeyFile, encryptionKeyEnv)
-	if err != nil {
-		return err
-	}
 
-	// Find most recent backup file for this database
-	backupPath, err := findLatestBackup(cfg.BackupDir, databaseName)
-	if err != nil {
+// runBaseBackup performs a physical base backup of the PostgreSQL cluster
+func runBaseBackup(ctx context.Context) error {
+	if !cfg.IsPostgreSQL() {
+		return fmt.Errorf("base backups require PostgreSQL (detected: %s)", cfg.DisplayDatabaseType())
+	}
+	
+	// Update config from environment
+	cfg.UpdateFromEnvironment()
+	
+	// Validate configuration
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	
+	// Check privileges
+	privChecker := security.NewPrivilegeChecker(log)
+	if err := privChecker.CheckAndWarn(cfg.AllowRoot); err != nil {
 		return err
 	}
-
-	// Encrypt the backup
-	return backup.EncryptBackupFile(backupPath, key, log)
-}
-
-// encryptLatestClusterBackup finds and encrypts the most recent cluster backup
-func encryptLatestClusterBackup() error {
-	// Load encryption key
-	key, err := loadEncryptionKey(encryptionKeyFile, encryptionKeyEnv)
+	
+	log.Info("Starting base backup", 
+		"host", cfg.Host, 
+		"port", cfg.Port,
+		"backup_dir", cfg.BackupDir)
+	
+	// Audit log: backup start
+	user := security.GetCurrentUser()
+	auditLogger.LogBackupStart(user, "cluster", "base")
+	
+	// Rate limit connection attempts
+	host := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	if err := rateLimiter.CheckAndWait(host); err != nil {
+		auditLogger.LogBackupFailed(user, "cluster", err)
+		return fmt.Errorf("rate limit exceeded: %w", err)
+	}
+	
+	// Create database instance (used for version detection)
+	db, err := database.New(cfg, log)
 	if err != nil {
-		return err
+		auditLogger.LogBackupFailed(user, "cluster", err)
+		return fmt.Errorf("failed to create database instance: %w", err)
 	}
-
-	// Find most recent cluster backup
-	backupPath, err := findLatestClusterBackup(cfg.BackupDir)
+	defer db.Close()
+	
+	if err := db.Connect(ctx); err != nil {
+		rateLimiter.RecordFailure(host)
+		auditLogger.LogBackupFailed(user, "cluster", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	rateLimiter.RecordSuccess(host)
+	
+	engine := backup.New(cfg, log, db)
+	
+	// Archive is encrypted while it is written
+	if isEncryptionEnabled() {
+		encOpts, envelope, err := newBackupEncryption(ctx)
+		if err != nil {
+			auditLogger.LogBackupFailed(user, "cluster", err)
+			return err
+		}
+		engine.SetEncryption(encOpts, envelope)
+	}
+	
+	basePath, err := engine.BackupBase(ctx)
 	if err != nil {
+		auditLogger.LogBackupFailed(user, "cluster", err)
 		return err
 	}
-
-	// Encrypt the backup
-	return backup.EncryptBackupFile(backupPath, key, log)
-}
-
-// findLatestBackup finds the most recently created backup file for a database
-func findLatestBackup(backupDir, databaseName string) (string, error) {
-entries, err := os.ReadDir(backupDir)
-if err != nil {
-return "", fmt.Errorf("failed to read backup directory: %w", err)
-}
-
-var latestPath string
-var latestTime time.Time
-
-prefix := "db_" + databaseName + "_"
-for _, entry := range entries {
-if entry.IsDir() {
-continue
-}
-
-name := entry.Name()
-// Skip metadata files and already encrypted files
-if strings.HasSuffix(name, ".meta.json") || strings.HasSuffix(name, ".encrypted") {
-continue
-}
-
-// Match database backup files
-if strings.HasPrefix(name, prefix) && (strings.HasSuffix(name, ".dump") || 
-strings.HasSuffix(name, ".dump.gz") || strings.HasSuffix(name, ".sql.gz")) {
-info, err := entry.Info()
-if err != nil {
-continue
-}
-
-if info.ModTime().After(latestTime) {
-latestTime = info.ModTime()
-latestPath = filepath.Join(backupDir, name)
-}
-}
-}
-
-if latestPath == "" {
-return "", fmt.Errorf("no backup found for database: %s", databaseName)
-}
-
-return latestPath, nil
-}
-
-// findLatestClusterBackup finds the most recently created cluster backup
-func findLatestClusterBackup(backupDir string) (string, error) {
-entries, err := os.ReadDir(backupDir)
-if err != nil {
-return "", fmt.Errorf("failed to read backup directory: %w", err)
-}
-
-var latestPath string
-var latestTime time.Time
-
-for _, entry := range entries {
-if entry.IsDir() {
-continue
-}
-
-name := entry.Name()
-// Skip metadata files and already encrypted files
-if strings.HasSuffix(name, ".meta.json") || strings.HasSuffix(name, ".encrypted") {
-continue
-}
-
-// Match cluster backup files
-if strings.HasPrefix(name, "cluster_") && strings.HasSuffix(name, ".tar.gz") {
-info, err := entry.Info()
-if err != nil {
-continue
+	
+	// Audit log: backup success
+	auditLogger.LogBackupComplete(user, "cluster", basePath, 0)
+	
+	return nil
 }
 
-if info.ModTime().After(latestTime) {
-latestTime = info.ModTime()
-latestPath = filepath.Join(backupDir, name)
-}
-}
+// resolveBaseBackup locates the base backup for an incremental backup.
+// Relative paths are tried as given first, then relative to the backup directory.
+func resolveBaseBackup(path, backupDir string) (string, error) {
+	candidates := []string{path}
+	if !filepath.IsAbs(path) {
+		candidates = append(candidates, filepath.Join(backupDir, path))
+	}
+	
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err != nil {
+			continue
+		}
+		absPath, err := filepath.Abs(candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve base backup path: %w", err)
+		}
+		// Incrementals are tracked by the base's metadata (timestamp, checksum, chain)
+		if _, err := metadata.Load(absPath); err != nil {
+			return "", fmt.Errorf("base backup %s has no readable metadata (.meta.json): %w", absPath, err)
+		}
+		return absPath, nil
+	}
+	
+	return "", fmt.Errorf("base backup file not found at %s. Ensure path is correct and file exists", path)
 }
 
-if latestPath == "" {
-return "", fmt.Errorf("no cluster backup found")
+// newRetentionPolicy returns the retention policy applied after backups,
+// with the grandfather-father-son tiers from the configuration
+func newRetentionPolicy() (*security.RetentionPolicy, error) {
+	policy := security.NewRetentionPolicy(cfg.RetentionDays, cfg.MinBackups, log)
+	within, err := retention.ParseWithin(cfg.KeepWithin)
+	if err != nil {
+		return nil, fmt.Errorf("KEEP_WITHIN: %w", err)
+	}
+	loc, err := retentionLocation(cfg.RetentionTimezone)
+	if err != nil {
+		return nil, err
+	}
+	policy.KeepLast = cfg.KeepLast
+	policy.KeepWithin = within
+	policy.KeepDaily = cfg.KeepDaily
+	policy.KeepWeekly = cfg.KeepWeekly
+	policy.KeepMonthly = cfg.KeepMonthly
+	policy.KeepYearly = cfg.KeepYearly
+	policy.Location = loc
+	return policy, nil
 }
 
-return latestPath, nil
+// retentionLocation loads the time zone retention tiers are bucketed in
+func retentionLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retention time zone %q: %w", name, err)
+	}
+	return loc, nil
 }
@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -21,9 +23,13 @@ var cleanupCmd = &cobra.Command{
 
 The retention policy ensures:
 1. Backups older than --retention-days are eligible for deletion
-2. At least --min-backups most recent backups are always kept
+2. At least --min-backups most recent backups of every database and backup
+   type are always kept
 3. Both conditions must be met for deletion
 
+A backup's .meta.json, .sha256 and .info files are kept and deleted with it.
+Local directories and cloud URIs are planned the same way.
+
 Examples:
   # Clean up backups older than 30 days (keep at least 5)
   dbbackup cleanup /backups --retention-days 30 --min-backups 5
@@ -35,7 +41,11 @@ Examples:
   dbbackup cleanup /backups --pattern "mydb_*.dump"
 
   # Aggressive cleanup (keep only 3 most recent)
-  dbbackup cleanup /backups --retention-days 1 --min-backups 3`,
+  dbbackup cleanup /backups --retention-days 1 --min-backups 3
+
+  # Write a reviewable plan, then delete exactly what it lists
+  dbbackup cleanup s3://my-bucket/backups/ --plan plan.json
+  dbbackup cleanup s3://my-bucket/backups/ --apply-plan plan.json`,
 	Args: cobra.ExactArgs(1),
 	RunE: runCleanup,
 }
@@ -45,6 +55,18 @@ var (
 	minBackups    int
 	dryRun        bool
 	cleanupPattern string
+	planFile      string
+	applyPlanFile string
+	explainPlan   bool
+
+	// Grandfather-father-son tiers
+	keepLast    int
+	keepWithin  string
+	keepDaily   int
+	keepWeekly  int
+	keepMonthly int
+	keepYearly  int
+	retentionTZ string
 )
 
 func init() {
@@ -53,11 +75,67 @@ func init() {
 	cleanupCmd.Flags().IntVar(&minBackups, "min-backups", 5, "Always keep at least this many backups")
 	cleanupCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be deleted without actually deleting")
 	cleanupCmd.Flags().StringVar(&cleanupPattern, "pattern", "", "Only clean up backups matching this pattern (e.g., 'mydb_*.dump')")
+	cleanupCmd.Flags().StringVar(&planFile, "plan", "", "Write the retention plan as JSON to this file ('-' for stdout) and delete nothing")
+	cleanupCmd.Flags().StringVar(&applyPlanFile, "apply-plan", "", "Delete exactly the backups listed in a plan written with --plan")
+	cleanupCmd.MarkFlagsMutuallyExclusive("plan", "apply-plan")
+	cleanupCmd.Flags().BoolVar(&explainPlan, "explain", false, "Show every backup with the reason it is kept or deleted")
+	cleanupCmd.Flags().IntVar(&keepLast, "keep-last", 0, "Keep the N newest backups of each database")
+	cleanupCmd.Flags().StringVar(&keepWithin, "keep-within", "", "Keep every backup younger than this (e.g. 36h, 7d, 2w)")
+	cleanupCmd.Flags().IntVar(&keepDaily, "keep-daily", 0, "Keep the newest backup of each of the last N days")
+	cleanupCmd.Flags().IntVar(&keepWeekly, "keep-weekly", 0, "Keep the newest backup of each of the last N weeks")
+	cleanupCmd.Flags().IntVar(&keepMonthly, "keep-monthly", 0, "Keep the newest backup of each of the last N months")
+	cleanupCmd.Flags().IntVar(&keepYearly, "keep-yearly", 0, "Keep the newest backup of each of the last N years")
+	cleanupCmd.Flags().StringVar(&retentionTZ, "timezone", "", "Time zone of the day/week/month/year boundaries (default: local)")
+}
+
+// cleanupPolicy returns the retention policy given by the flags
+func cleanupPolicy() (retention.Policy, error) {
+	within, err := retention.ParseWithin(keepWithin)
+	if err != nil {
+		return retention.Policy{}, fmt.Errorf("invalid --keep-within: %w", err)
+	}
+	loc, err := retentionLocation(retentionTZ)
+	if err != nil {
+		return retention.Policy{}, err
+	}
+	return retention.Policy{
+		RetentionDays: retentionDays,
+		MinBackups:    minBackups,
+		DryRun:        dryRun || planFile != "",
+		KeepLast:      keepLast,
+		KeepWithin:    within,
+		KeepDaily:     keepDaily,
+		KeepWeekly:    keepWeekly,
+		KeepMonthly:   keepMonthly,
+		KeepYearly:    keepYearly,
+		Location:      loc,
+	}, nil
+}
+
+// printPolicy shows the retention rules of a cleanup
+func printPolicy(policy retention.Policy) {
+	if policy.HasTiers() {
+		fmt.Printf("   Tiers: %s\n", policy.TiersString())
+	} else {
+		fmt.Printf("   Retention: %d days\n", policy.RetentionDays)
+	}
+	fmt.Printf("   Min backups: %d per database\n", policy.MinBackups)
+	if cleanupPattern != "" {
+		fmt.Printf("   Pattern: %s\n", cleanupPattern)
+	}
+	if policy.DryRun {
+		fmt.Printf("   Mode: DRY RUN (no files will be deleted)\n")
+	}
+	fmt.Println()
 }
 
 func runCleanup(cmd *cobra.Command, args []string) error {
 	backupPath := args[0]
 	
+	if applyPlanFile != "" {
+		return runApplyPlan(cmd.Context(), backupPath)
+	}
+	
 	// Check if this is a cloud URI
 	if isCloudURIPath(backupPath) {
 		return runCloudCleanup(cmd.Context(), backupPath)
@@ -72,39 +150,128 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create retention policy
-	policy := retention.Policy{
-		RetentionDays: retentionDays,
-		MinBackups:    minBackups,
-		DryRun:        dryRun,
+	policy, err := cleanupPolicy()
+	if err != nil {
+		return err
+	}
+
+	plan, err := retention.PlanLocal(backupDir, cleanupPattern, policy)
+	if err != nil {
+		return fmt.Errorf("cleanup failed: %w", err)
+	}
+	if planFile == "-" {
+		return writePlan(plan)
 	}
 
 	fmt.Printf("🗑️  Cleanup Policy:\n")
 	fmt.Printf("   Directory: %s\n", backupDir)
-	fmt.Printf("   Retention: %d days\n", policy.RetentionDays)
-	fmt.Printf("   Min backups: %d\n", policy.MinBackups)
-	if cleanupPattern != "" {
-		fmt.Printf("   Pattern: %s\n", cleanupPattern)
+	printPolicy(policy)
+
+	printRetentionPlan(plan)
+	if planFile != "" {
+		return writePlan(plan)
 	}
-	if dryRun {
-		fmt.Printf("   Mode: DRY RUN (no files will be deleted)\n")
+
+	result := plan.Apply(retention.RemoveFile, policy.DryRun)
+	printCleanupResult(result, policy.DryRun)
+	return nil
+}
+
+// printRetentionPlan shows per database and backup type what is deleted,
+// and why. With --explain the kept backups are listed too.
+func printRetentionPlan(plan *retention.Plan) {
+	// Times are shown in the calendar the tiers were bucketed in
+	loc, err := retentionLocation(retentionTZ)
+	if err != nil {
+		loc = time.Local
+	}
+	fmt.Printf("📋 Plan:\n")
+	for _, g := range plan.Groups {
+		fmt.Printf("   %s (%s): keep %d, delete %d\n", displayGroupName(g.Database), displayGroupName(g.BackupType), len(g.Keep), len(g.Delete))
+		if explainPlan {
+			for _, d := range g.Keep {
+				fmt.Printf("     + %s (%s, %s)\n", path.Base(filepath.ToSlash(d.Path)),
+					d.Timestamp.In(loc).Format("2006-01-02 15:04 MST"), d.Reason)
+			}
+		}
+		for _, d := range g.Delete {
+			fmt.Printf("     - %s (%s, %s, %s)\n", path.Base(filepath.ToSlash(d.Path)),
+				metadata.FormatSize(d.SizeBytes), formatBackupAge(d.Timestamp), d.Reason)
+		}
+	}
+	for _, orphan := range plan.Orphans {
+		fmt.Printf("   ⚠️  %s has no backup (left in place)\n", orphan)
 	}
 	fmt.Println()
+}
 
-	var result *retention.CleanupResult
-	var err error
+func displayGroupName(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
 
-	// Apply policy
-	if cleanupPattern != "" {
-		result, err = retention.CleanupByPattern(backupDir, cleanupPattern, policy)
-	} else {
-		result, err = retention.ApplyPolicy(backupDir, policy)
+// writePlan writes the plan as JSON to --plan without deleting anything
+func writePlan(plan *retention.Plan) error {
+	if planFile == "-" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	if err := plan.Save(planFile); err != nil {
+		return err
 	}
+	fmt.Printf("📝 Plan written to %s: %d backup(s) to delete, %s to free\n",
+		planFile, len(plan.Deletions()), metadata.FormatSize(plan.ReclaimBytes()))
+	fmt.Printf("   Review it, then run: dbbackup cleanup %s --apply-plan %s\n", plan.Location, planFile)
+	return nil
+}
 
+// runApplyPlan deletes the backups listed in a plan written with --plan
+func runApplyPlan(ctx context.Context, location string) error {
+	plan, err := retention.LoadPlan(applyPlanFile)
 	if err != nil {
-		return fmt.Errorf("cleanup failed: %w", err)
+		return err
+	}
+	if plan.Location != location {
+		return fmt.Errorf("plan %s was made for %s, not %s", applyPlanFile, plan.Location, location)
 	}
 
-	// Display results
+	remove := retention.RemoveFile
+	if isCloudURIPath(location) {
+		cloudURI, err := cloud.ParseCloudURI(location)
+		if err != nil {
+			return fmt.Errorf("invalid cloud URI: %w", err)
+		}
+		backend, err := cloud.NewBackend(cloudURI.ToConfig())
+		if err != nil {
+			return fmt.Errorf("failed to create cloud backend: %w", err)
+		}
+		defer cloud.CloseBackend(backend)
+		remove = deleteUnlocked(ctx, backend)
+	}
+
+	fmt.Printf("🗑️  Applying plan %s (created %s)\n\n", applyPlanFile, plan.CreatedAt.Format("2006-01-02 15:04:05"))
+	printRetentionPlan(plan)
+	printCleanupResult(plan.Apply(remove, dryRun), dryRun)
+	return nil
+}
+
+// deleteUnlocked deletes cloud objects, refusing those still under object
+// lock. Plans can be applied long after they were made, when a backup may
+// have been locked since.
+func deleteUnlocked(ctx context.Context, backend cloud.Backend) func(string) error {
+	return func(key string) error {
+		if err := cloud.CheckDeletable(ctx, backend, key); err != nil {
+			return err
+		}
+		return backend.Delete(ctx, key)
+	}
+}
+
+// printCleanupResult shows the outcome of applying a plan
+func printCleanupResult(result *retention.CleanupResult, dryRun bool) {
 	fmt.Printf("📊 Results:\n")
 	fmt.Printf("   Total backups: %d\n", result.TotalBackups)
 	fmt.Printf("   Eligible for deletion: %d\n", result.EligibleForDeletion)
@@ -117,14 +284,14 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 			fmt.Printf("✅ Deleted %d backup(s):\n", len(result.Deleted))
 		}
 		for _, file := range result.Deleted {
-			fmt.Printf("   - %s\n", filepath.Base(file))
+			fmt.Printf("   - %s\n", path.Base(filepath.ToSlash(file)))
 		}
 	}
 
 	if len(result.Kept) > 0 && len(result.Kept) <= 10 {
 		fmt.Printf("\n📦 Kept %d backup(s):\n", len(result.Kept))
 		for _, file := range result.Kept {
-			fmt.Printf("   - %s\n", filepath.Base(file))
+			fmt.Printf("   - %s\n", path.Base(filepath.ToSlash(file)))
 		}
 	} else if len(result.Kept) > 10 {
 		fmt.Printf("\n📦 Kept %d backup(s)\n", len(result.Kept))
@@ -150,8 +317,6 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 	} else {
 		fmt.Println("ℹ️  No backups eligible for deletion")
 	}
-
-	return nil
 }
 
 func dirExists(path string) bool {
@@ -175,19 +340,10 @@ func runCloudCleanup(ctx context.Context, uri string) error {
 		return fmt.Errorf("invalid cloud URI: %w", err)
 	}
 	
-	fmt.Printf("☁️  Cloud Cleanup Policy:\n")
-	fmt.Printf("   URI: %s\n", uri)
-	fmt.Printf("   Provider: %s\n", cloudURI.Provider)
-	fmt.Printf("   Bucket: %s\n", cloudURI.Bucket)
-	if cloudURI.Path != "" {
-		fmt.Printf("   Prefix: %s\n", cloudURI.Path)
-	}
-	fmt.Printf("   Retention: %d days\n", retentionDays)
-	fmt.Printf("   Min backups: %d\n", minBackups)
-	if dryRun {
-		fmt.Printf("   Mode: DRY RUN (no files will be deleted)\n")
+	policy, err := cleanupPolicy()
+	if err != nil {
+		return err
 	}
-	fmt.Println()
 	
 	// Create cloud backend
 	cfg := cloudURI.ToConfig()
@@ -195,115 +351,62 @@ func runCloudCleanup(ctx context.Context, uri string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create cloud backend: %w", err)
 	}
+	defer cloud.CloseBackend(backend)
 	
 	// List all backups
-	backups, err := backend.List(ctx, cloudURI.Path)
+	files, err := backend.List(ctx, cloudURI.Path)
 	if err != nil {
 		return fmt.Errorf("failed to list cloud backups: %w", err)
 	}
 	
-	if len(backups) == 0 {
-		fmt.Println("No backups found in cloud storage")
-		return nil
+	// Backups with their .meta.json/.sha256 companions, grouped by database
+	items, orphans := retention.CloudItems(files)
+	items, err = retention.FilterItems(items, cleanupPattern)
+	if err != nil {
+		return err
 	}
-	
-	fmt.Printf("Found %d backup(s) in cloud storage\n\n", len(backups))
-	
-	// Filter backups based on pattern if specified
-	var filteredBackups []cloud.BackupInfo
-	if cleanupPattern != "" {
-		for _, backup := range backups {
-			matched, _ := filepath.Match(cleanupPattern, backup.Name)
-			if matched {
-				filteredBackups = append(filteredBackups, backup)
-			}
-		}
-		fmt.Printf("Pattern matched %d backup(s)\n\n", len(filteredBackups))
-	} else {
-		filteredBackups = backups
+	if err := retention.ReadCloudChains(ctx, backend, items); err != nil {
+		return fmt.Errorf("failed to read incremental chains: %w", err)
 	}
-	
-	// Sort by modification time (oldest first)
-	// Already sorted by backend.List
-	
-	// Calculate retention date
-	cutoffDate := time.Now().AddDate(0, 0, -retentionDays)
-	
-	// Determine which backups to delete
-	var toDelete []cloud.BackupInfo
-	var toKeep []cloud.BackupInfo
-	
-	for _, backup := range filteredBackups {
-		if backup.LastModified.Before(cutoffDate) {
-			toDelete = append(toDelete, backup)
-		} else {
-			toKeep = append(toKeep, backup)
-		}
+	plan := retention.NewPlan(uri, items, policy, time.Now())
+	plan.Orphans = orphans
+	locked, err := retention.KeepLocked(ctx, backend, plan)
+	if err != nil {
+		return err
+	}
+	if planFile == "-" {
+		return writePlan(plan)
 	}
 	
-	// Ensure we keep minimum backups
-	totalBackups := len(filteredBackups)
-	if totalBackups-len(toDelete) < minBackups {
-		// Need to keep more backups
-		keepCount := minBackups - len(toKeep)
-		if keepCount > len(toDelete) {
-			keepCount = len(toDelete)
-		}
-		
-		// Move oldest from toDelete to toKeep
-		for i := len(toDelete) - 1; i >= len(toDelete)-keepCount && i >= 0; i-- {
-			toKeep = append(toKeep, toDelete[i])
-			toDelete = toDelete[:i]
-		}
+	fmt.Printf("☁️  Cloud Cleanup Policy:\n")
+	fmt.Printf("   URI: %s\n", uri)
+	fmt.Printf("   Provider: %s\n", cloudURI.Provider)
+	fmt.Printf("   Bucket: %s\n", cloudURI.Bucket)
+	if cloudURI.Path != "" {
+		fmt.Printf("   Prefix: %s\n", cloudURI.Path)
 	}
+	printPolicy(policy)
 	
-	// Display results
-	fmt.Printf("📊 Results:\n")
-	fmt.Printf("   Total backups: %d\n", totalBackups)
-	fmt.Printf("   Eligible for deletion: %d\n", len(toDelete))
-	fmt.Printf("   Will keep: %d\n", len(toKeep))
-	fmt.Println()
+	if len(files) == 0 {
+		fmt.Println("No backups found in cloud storage")
+		return nil
+	}
+	fmt.Printf("Found %d backup(s) in cloud storage\n\n", len(items))
 	
-	if len(toDelete) > 0 {
-		if dryRun {
-			fmt.Printf("🔍 Would delete %d backup(s):\n", len(toDelete))
-		} else {
-			fmt.Printf("🗑️  Deleting %d backup(s):\n", len(toDelete))
-		}
-		
-		var totalSize int64
-		var deletedCount int
-		
-		for _, backup := range toDelete {
-			fmt.Printf("   - %s (%s, %s old)\n", 
-				backup.Name, 
-				cloud.FormatSize(backup.Size),
-				formatBackupAge(backup.LastModified))
-			
-			totalSize += backup.Size
-			
-			if !dryRun {
-				if err := backend.Delete(ctx, backup.Key); err != nil {
-					fmt.Printf("     ❌ Error: %v\n", err)
-				} else {
-					deletedCount++
-					// Also try to delete metadata
-					backend.Delete(ctx, backup.Key+".meta.json")
-				}
-			}
+	printRetentionPlan(plan)
+	if len(locked) > 0 {
+		fmt.Printf("🔒 %d backup(s) past retention are immutable and will not be deleted:\n", len(locked))
+		for _, d := range locked {
+			fmt.Printf("   - %s (%s)\n", path.Base(d.Path), strings.TrimPrefix(d.Reason, "immutable, "))
 		}
-		
-		fmt.Printf("\n💾 Space %s: %s\n", 
-			map[bool]string{true: "would be freed", false: "freed"}[dryRun],
-			cloud.FormatSize(totalSize))
-			
-		if !dryRun && deletedCount > 0 {
-			fmt.Printf("✅ Successfully deleted %d backup(s)\n", deletedCount)
-		}
-	} else {
-		fmt.Println("No backups eligible for deletion")
+		fmt.Println()
+	}
+	if planFile != "" {
+		return writePlan(plan)
 	}
 	
+	result := plan.Apply(deleteUnlocked(ctx, backend), policy.DryRun)
+	printCleanupResult(result, policy.DryRun)
 	return nil
 }
 
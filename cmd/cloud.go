@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"dbbackup/internal/cloud"
+	"dbbackup/internal/retention"
 	"github.com/spf13/cobra"
 )
 
@@ -101,6 +103,85 @@ Examples:
 	RunE: runCloudDelete,
 }
 
+var cloudResumeCmd = &cobra.Command{
+	Use:   "resume [remote-file...]",
+	Short: "Resume interrupted uploads and downloads",
+	Long: `Resume uploads and downloads that were interrupted.
+
+Large transfers record their progress in a state directory
+($DBBACKUP_STATE_DIR, default ~/.dbbackup/transfers): the S3 multipart
+upload ID and completed parts, the staged Azure blocks, or the GCS
+resumable session. Running the same upload or download again continues
+from there; this command does that for every recorded transfer.
+
+Uploads still open in the bucket without local state cannot be resumed;
+remove them with 'dbbackup cloud abort'.
+
+Examples:
+  # Resume everything interrupted for this bucket
+  dbbackup cloud resume
+
+  # Show interrupted transfers without resuming them
+  dbbackup cloud resume --list
+
+  # Resume one upload
+  dbbackup cloud resume mydb_20251125.dump`,
+	RunE: runCloudResume,
+}
+
+var cloudAbortCmd = &cobra.Command{
+	Use:   "abort [prefix]",
+	Short: "Abort incomplete uploads",
+	Long: `Abort uploads that were started but never completed, deleting the data
+stored for them and their local state.
+
+S3 keeps (and bills) the parts of an incomplete multipart upload until it
+is completed or aborted; these are listed from the bucket. Azure and GCS
+uploads are listed from the local state directory.
+
+Examples:
+  # Abort all incomplete uploads in the bucket
+  dbbackup cloud abort
+
+  # Abort incomplete uploads of one database without prompting
+  dbbackup cloud abort mydb_ --confirm`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCloudAbort,
+}
+
+var cloudLifecycleCmd = &cobra.Command{
+	Use:   "lifecycle [prefix]",
+	Short: "Move old backups to colder storage classes",
+	Long: `Move backups older than a given age to a colder, cheaper storage class.
+
+Each --rule is age=class. A backup gets the coldest class of the rules its
+age matches and is never moved back to a warmer class. Only backup files
+are moved: their .meta.json stays in the default class, so listing,
+verification and retention planning never have to rehydrate anything.
+
+Storage classes (warmest first):
+  S3:    STANDARD, INTELLIGENT_TIERING, STANDARD_IA, ONEZONE_IA, GLACIER_IR,
+         GLACIER, DEEP_ARCHIVE
+  Azure: Hot, Cool, Cold, Archive
+  GCS:   STANDARD, NEARLINE, COLDLINE, ARCHIVE
+
+Backups in GLACIER, DEEP_ARCHIVE or Azure Archive must be rehydrated before
+they can be restored, which 'dbbackup restore' requests and waits for.
+
+S3 and GCS change the class by copying the object onto itself. In a
+versioned bucket the old version keeps its class until it expires; prefer
+bucket lifecycle rules there.
+
+Examples:
+  # Show what would move
+  dbbackup cloud lifecycle --rule 30d=STANDARD_IA --rule 90d=GLACIER --dry-run
+
+  # Move backups of one database to cool storage after a week
+  dbbackup cloud lifecycle mydb_ --cloud-provider azure --rule 7d=Cool`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCloudLifecycle,
+}
+
 var (
 	cloudProvider  string
 	cloudBucket    string
@@ -111,15 +192,21 @@ var (
 	cloudPrefix    string
 	cloudVerbose   bool
 	cloudConfirm   bool
+
+	cloudResumeList bool
+
+	cloudStorageClass   string
+	cloudLifecycleRules []string
+	cloudLifecycleDry   bool
 )
 
 func init() {
 	rootCmd.AddCommand(cloudCmd)
-	cloudCmd.AddCommand(cloudUploadCmd, cloudDownloadCmd, cloudListCmd, cloudDeleteCmd)
+	cloudCmd.AddCommand(cloudUploadCmd, cloudDownloadCmd, cloudListCmd, cloudDeleteCmd, cloudResumeCmd, cloudAbortCmd, cloudLifecycleCmd)
 
 	// Cloud configuration flags
-	for _, cmd := range []*cobra.Command{cloudUploadCmd, cloudDownloadCmd, cloudListCmd, cloudDeleteCmd} {
-		cmd.Flags().StringVar(&cloudProvider, "cloud-provider", getEnv("DBBACKUP_CLOUD_PROVIDER", "s3"), "Cloud provider (s3, minio, b2)")
+	for _, cmd := range []*cobra.Command{cloudUploadCmd, cloudDownloadCmd, cloudListCmd, cloudDeleteCmd, cloudResumeCmd, cloudAbortCmd, cloudLifecycleCmd} {
+		cmd.Flags().StringVar(&cloudProvider, "cloud-provider", getEnv("DBBACKUP_CLOUD_PROVIDER", "s3"), "Cloud provider (s3, minio, b2, azure, gcs, sftp, file, webdav, webdavs)")
 		cmd.Flags().StringVar(&cloudBucket, "cloud-bucket", getEnv("DBBACKUP_CLOUD_BUCKET", ""), "Bucket name")
 		cmd.Flags().StringVar(&cloudRegion, "cloud-region", getEnv("DBBACKUP_CLOUD_REGION", "us-east-1"), "Region")
 		cmd.Flags().StringVar(&cloudEndpoint, "cloud-endpoint", getEnv("DBBACKUP_CLOUD_ENDPOINT", ""), "Custom endpoint (for MinIO)")
@@ -130,6 +217,12 @@ func init() {
 	}
 
 	cloudDeleteCmd.Flags().BoolVar(&cloudConfirm, "confirm", false, "Skip confirmation prompt")
+	cloudAbortCmd.Flags().BoolVar(&cloudConfirm, "confirm", false, "Skip confirmation prompt")
+	cloudResumeCmd.Flags().BoolVar(&cloudResumeList, "list", false, "List interrupted transfers without resuming them")
+	cloudUploadCmd.Flags().StringVar(&cloudStorageClass, "storage-class", getEnv("DBBACKUP_CLOUD_STORAGE_CLASS", ""), "Storage class for uploaded files (e.g. STANDARD_IA, GLACIER_IR, Cool, NEARLINE)")
+	cloudLifecycleCmd.Flags().StringArrayVar(&cloudLifecycleRules, "rule", nil, "Move backups older than an age to a storage class (age=class, e.g. 30d=STANDARD_IA); repeatable")
+	cloudLifecycleCmd.Flags().BoolVar(&cloudLifecycleDry, "dry-run", false, "Show what would be moved without moving anything")
+	cloudLifecycleCmd.MarkFlagRequired("rule")
 }
 
 func getEnv(key, defaultValue string) string {
@@ -141,17 +234,18 @@ func getEnv(key, defaultValue string) string {
 
 func getCloudBackend() (cloud.Backend, error) {
 	cfg := &cloud.Config{
-		Provider:   cloudProvider,
-		Bucket:     cloudBucket,
-		Region:     cloudRegion,
-		Endpoint:   cloudEndpoint,
-		AccessKey:  cloudAccessKey,
-		SecretKey:  cloudSecretKey,
-		Prefix:     cloudPrefix,
-		UseSSL:     true,
-		PathStyle:  cloudProvider == "minio",
-		Timeout:    300,
-		MaxRetries: 3,
+		Provider:     cloudProvider,
+		Bucket:       cloudBucket,
+		Region:       cloudRegion,
+		Endpoint:     cloudEndpoint,
+		AccessKey:    cloudAccessKey,
+		SecretKey:    cloudSecretKey,
+		Prefix:       cloudPrefix,
+		UseSSL:       true,
+		PathStyle:    cloudProvider == "minio",
+		StorageClass: cloudStorageClass,
+		Timeout:      300,
+		MaxRetries:   3,
 	}
 
 	if cfg.Bucket == "" {
@@ -171,6 +265,7 @@ func runCloudUpload(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	defer cloud.CloseBackend(backend)
 
 	ctx := context.Background()
 
@@ -237,6 +332,7 @@ func runCloudDownload(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	defer cloud.CloseBackend(backend)
 
 	ctx := context.Background()
 	remotePath := args[0]
@@ -286,6 +382,7 @@ func runCloudList(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	defer cloud.CloseBackend(backend)
 
 	ctx := context.Background()
 	prefix := ""
@@ -338,6 +435,7 @@ func runCloudDelete(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	defer cloud.CloseBackend(backend)
 
 	ctx := context.Background()
 	remotePath := args[0]
@@ -357,6 +455,11 @@ func runCloudDelete(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get file info: %w", err)
 	}
 
+	// Locked objects can't be deleted; S3 would only hide them
+	if err := cloud.CheckDeletable(ctx, backend, remotePath); err != nil {
+		return fmt.Errorf("refusing to delete %s: %w", remotePath, err)
+	}
+
 	// Confirmation prompt
 	if !cloudConfirm {
 		fmt.Printf("⚠️  Delete %s (%s) from cloud storage?\n", remotePath, cloud.FormatSize(size))
@@ -381,6 +484,241 @@ func runCloudDelete(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runCloudResume(cmd *cobra.Command, args []string) error {
+	backend, err := getCloudBackend()
+	if err != nil {
+		return err
+	}
+	defer cloud.CloseBackend(backend)
+
+	ctx := context.Background()
+
+	states, err := cloud.ListTransferStates(cloud.DefaultStateDir())
+	if err != nil {
+		return fmt.Errorf("failed to read transfer state: %w", err)
+	}
+
+	var pending []*cloud.TransferState
+	for _, state := range states {
+		if state.Provider != backend.Name() || state.Bucket != cloudBucket {
+			continue
+		}
+		if len(args) > 0 && !slices.Contains(args, state.RemotePath) {
+			continue
+		}
+		pending = append(pending, state)
+	}
+
+	// Uploads open in the bucket that this host has no state for
+	if rb, ok := backend.(cloud.ResumableBackend); ok && len(args) == 0 {
+		if uploads, err := rb.ListIncompleteUploads(ctx, ""); err == nil {
+			for _, u := range uploads {
+				if u.LocalPath == "" {
+					fmt.Printf("⚠️  %s: incomplete upload with no local state (started %s), remove with 'dbbackup cloud abort'\n",
+						u.RemotePath, formatAge(time.Since(u.Initiated)))
+				}
+			}
+		}
+	}
+
+	if len(pending) == 0 {
+		fmt.Println("No interrupted transfers to resume")
+		return nil
+	}
+
+	if cloudResumeList {
+		for _, state := range pending {
+			fmt.Printf("%-8s %-40s %12s / %-12s %s\n",
+				state.Operation,
+				state.RemotePath,
+				cloud.FormatSize(state.Completed()),
+				cloud.FormatSize(state.Size),
+				state.LocalPath)
+		}
+		return nil
+	}
+
+	fmt.Printf("☁️  Resuming %d transfer(s) with %s...\n\n", len(pending), backend.Name())
+
+	successCount := 0
+	for _, state := range pending {
+		fmt.Printf("🔁 %s %s (%s of %s done)\n", state.Operation, state.RemotePath,
+			cloud.FormatSize(state.Completed()), cloud.FormatSize(state.Size))
+
+		if state.Operation == cloud.OperationDownload {
+			err = backend.Download(ctx, state.RemotePath, state.LocalPath, cloudProgress())
+		} else {
+			err = backend.Upload(ctx, state.LocalPath, state.RemotePath, cloudProgress())
+		}
+		if err != nil {
+			fmt.Printf("   ❌ Failed: %v\n\n", err)
+			continue
+		}
+
+		fmt.Printf("   ✅ Completed\n\n")
+		successCount++
+	}
+
+	fmt.Println(strings.Repeat("─", 50))
+	fmt.Printf("✅ Resumed %d/%d transfer(s)\n", successCount, len(pending))
+
+	if successCount < len(pending) {
+		return fmt.Errorf("%d transfer(s) failed", len(pending)-successCount)
+	}
+	return nil
+}
+
+func runCloudAbort(cmd *cobra.Command, args []string) error {
+	backend, err := getCloudBackend()
+	if err != nil {
+		return err
+	}
+	defer cloud.CloseBackend(backend)
+
+	rb, ok := backend.(cloud.ResumableBackend)
+	if !ok {
+		return fmt.Errorf("%s does not support resumable uploads", backend.Name())
+	}
+
+	ctx := context.Background()
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	uploads, err := rb.ListIncompleteUploads(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	if len(uploads) == 0 {
+		fmt.Println("No incomplete uploads")
+		return nil
+	}
+
+	fmt.Printf("Incomplete uploads in %s/%s:\n\n", backend.Name(), cloudBucket)
+	for _, u := range uploads {
+		fmt.Printf("%-50s %12s  %s\n", u.RemotePath, cloud.FormatSize(u.Uploaded), formatAge(time.Since(u.Initiated)))
+	}
+	fmt.Println()
+
+	// Confirmation prompt
+	if !cloudConfirm {
+		fmt.Printf("⚠️  Abort %d upload(s) and delete their uploaded data?\n", len(uploads))
+		fmt.Print("Type 'yes' to confirm: ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "yes" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+	}
+
+	aborted := 0
+	for _, u := range uploads {
+		if err := rb.AbortUpload(ctx, u); err != nil {
+			fmt.Printf("❌ %s: %v\n", u.RemotePath, err)
+			continue
+		}
+		aborted++
+	}
+
+	fmt.Printf("✅ Aborted %d/%d upload(s)\n", aborted, len(uploads))
+
+	return nil
+}
+
+func runCloudLifecycle(cmd *cobra.Command, args []string) error {
+	var rules []retention.TierRule
+	for _, spec := range cloudLifecycleRules {
+		rule, err := retention.ParseTierRule(spec)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+
+	backend, err := getCloudBackend()
+	if err != nil {
+		return err
+	}
+	defer cloud.CloseBackend(backend)
+	tb, ok := backend.(cloud.TieringBackend)
+	if !ok {
+		return fmt.Errorf("%s does not support storage classes", backend.Name())
+	}
+
+	ctx := context.Background()
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	files, err := backend.List(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+	items, _ := retention.CloudItems(files)
+	transitions, err := retention.PlanTransitions(backend.Name(), items, rules, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(transitions) == 0 {
+		fmt.Println("No backups to move")
+		return nil
+	}
+
+	var totalSize int64
+	fmt.Printf("🧊 Backups to move in %s/%s:\n\n", backend.Name(), cloudBucket)
+	for _, t := range transitions {
+		from := t.From
+		if from == "" {
+			from = "default"
+		}
+		fmt.Printf("%-50s %12s  %-14s %s → %s\n", filepath.Base(t.Path), cloud.FormatSize(t.SizeBytes),
+			formatAge(time.Since(t.Timestamp)), from, t.To)
+		totalSize += t.SizeBytes
+	}
+	fmt.Println()
+
+	if cloudLifecycleDry {
+		fmt.Printf("Dry run: %d backup(s), %s would be moved\n", len(transitions), cloud.FormatSize(totalSize))
+		return nil
+	}
+
+	moved := 0
+	for _, t := range transitions {
+		if err := tb.SetStorageClass(ctx, t.Path, t.To); err != nil {
+			fmt.Printf("❌ %s: %v\n", t.Path, err)
+			continue
+		}
+		moved++
+	}
+
+	fmt.Printf("✅ Moved %d/%d backup(s)\n", moved, len(transitions))
+	if moved < len(transitions) {
+		return fmt.Errorf("%d backup(s) could not be moved", len(transitions)-moved)
+	}
+	return nil
+}
+
+// cloudProgress returns a progress callback printing every 10% with --verbose
+func cloudProgress() cloud.ProgressCallback {
+	var lastPercent int
+	return func(transferred, total int64) {
+		if !cloudVerbose || total == 0 {
+			return
+		}
+		percent := int(float64(transferred) / float64(total) * 100)
+		if percent != lastPercent && percent%10 == 0 {
+			fmt.Printf("   Progress: %d%% (%s / %s)\n",
+				percent,
+				cloud.FormatSize(transferred),
+				cloud.FormatSize(total))
+			lastPercent = percent
+		}
+	}
+}
+
 func formatAge(d time.Duration) string {
 	if d < time.Minute {
 		return "just now"
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"dbbackup/internal/metadata"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [old-backup] [new-backup]",
+	Short: "Show what changed between two backups without restoring them",
+	Long: `Compare the content recorded in the metadata of two backups: tables added or
+removed, tables whose definition (columns, constraints, indexes) changed, row count
+and size changes, and added, removed or updated extensions and sequences.
+
+Backups record their tables with exact row counts and sizes, and a fingerprint of
+the normalized schema, when they are taken. Neither backup is restored or read,
+and no database connection is needed. Cluster backups are compared database by
+database.
+
+Examples:
+  # What changed in the last week
+  dbbackup diff /backups/shop_20261001_020000.dump /backups/shop_20261008_020000.dump
+
+  # Also list unchanged tables
+  dbbackup diff old.dump new.dump --verbose
+
+  # Compare two cluster backups
+  dbbackup diff /backups/cluster_20261001.tar.gz /backups/cluster_20261008.tar.gz`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+var verboseDiff bool
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().BoolVarP(&verboseDiff, "verbose", "v", false, "Also list unchanged tables")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	oldFile, newFile := args[0], args[1]
+
+	oldCluster, oldErr := metadata.LoadCluster(oldFile)
+	newCluster, newErr := metadata.LoadCluster(newFile)
+	oldIsCluster := oldErr == nil && len(oldCluster.Databases) > 0
+	newIsCluster := newErr == nil && len(newCluster.Databases) > 0
+	if oldIsCluster != newIsCluster {
+		return fmt.Errorf("can't compare a cluster backup with a single database backup")
+	}
+
+	fmt.Printf("\n📊 Comparing backups\n")
+	if oldIsCluster {
+		fmt.Printf("   Old: %s (%s)\n", filepath.Base(oldFile), oldCluster.Timestamp.Format("2006-01-02 15:04:05"))
+		fmt.Printf("   New: %s (%s)\n", filepath.Base(newFile), newCluster.Timestamp.Format("2006-01-02 15:04:05"))
+
+		diff := metadata.DiffCluster(oldCluster, newCluster)
+		for _, name := range diff.DatabasesAdded {
+			fmt.Printf("\n➕ Database added: %s\n", name)
+		}
+		for _, name := range diff.DatabasesRemoved {
+			fmt.Printf("\n➖ Database removed: %s\n", name)
+		}
+		oldDBs := make(map[string]metadata.BackupMetadata, len(oldCluster.Databases))
+		for _, db := range oldCluster.Databases {
+			oldDBs[db.Database] = db
+		}
+		newDBs := make(map[string]metadata.BackupMetadata, len(newCluster.Databases))
+		for _, db := range newCluster.Databases {
+			newDBs[db.Database] = db
+		}
+		for _, dbDiff := range diff.Databases {
+			before, after := oldDBs[dbDiff.Database], newDBs[dbDiff.Database]
+			printDatabaseDiff(dbDiff, &before, &after)
+		}
+		fmt.Println()
+		return nil
+	}
+
+	oldMeta, err := metadata.Load(oldFile)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata of %s: %w", oldFile, err)
+	}
+	newMeta, err := metadata.Load(newFile)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata of %s: %w", newFile, err)
+	}
+	if oldMeta.Database != newMeta.Database {
+		log.Warn("Comparing backups of different databases", "old", oldMeta.Database, "new", newMeta.Database)
+	}
+
+	fmt.Printf("   Old: %s (%s)\n", filepath.Base(oldFile), oldMeta.Timestamp.Format("2006-01-02 15:04:05"))
+	fmt.Printf("   New: %s (%s)\n", filepath.Base(newFile), newMeta.Timestamp.Format("2006-01-02 15:04:05"))
+	printDatabaseDiff(metadata.Diff(oldMeta, newMeta), oldMeta, newMeta)
+	fmt.Println()
+	return nil
+}
+
+// printDatabaseDiff shows what changed in one database
+func printDatabaseDiff(diff *metadata.DatabaseDiff, oldMeta, newMeta *metadata.BackupMetadata) {
+	fmt.Printf("\n🗄️  Database: %s\n", diff.Database)
+	for label, meta := range map[string]*metadata.BackupMetadata{"old": oldMeta, "new": newMeta} {
+		if !meta.HasContent() {
+			fmt.Printf("   ⚠️  The %s backup has no recorded content (taken by an older version, or a sample)\n", label)
+			return
+		}
+	}
+	if !diff.Changed() {
+		fmt.Printf("   ✅ No changes (%d tables)\n", len(diff.Tables))
+		if !verboseDiff {
+			return
+		}
+	}
+
+	if diff.SchemaChanged {
+		fmt.Printf("   Schema: changed (fingerprint %s -> %s)\n", shortFingerprint(oldMeta.SchemaFingerprint), shortFingerprint(newMeta.SchemaFingerprint))
+	} else {
+		fmt.Printf("   Schema: unchanged\n")
+	}
+
+	width := 0
+	for _, t := range diff.Tables {
+		width = max(width, len(t.Name))
+	}
+	for _, t := range diff.Tables {
+		switch t.Status {
+		case metadata.TableAdded:
+			fmt.Printf("   + %-*s  %d rows, %s\n", width, t.Name, t.New.Rows, metadata.FormatSize(t.New.SizeBytes))
+		case metadata.TableRemoved:
+			fmt.Printf("   - %-*s  %d rows, %s\n", width, t.Name, t.Old.Rows, metadata.FormatSize(t.Old.SizeBytes))
+		case metadata.TableChanged:
+			details := []string{fmt.Sprintf("%+d rows", t.RowDelta()), signedSize(t.SizeDelta())}
+			if t.DefinitionChanged {
+				details = append(details, "definition changed")
+			}
+			fmt.Printf("   ~ %-*s  %s\n", width, t.Name, strings.Join(details, ", "))
+		default:
+			if verboseDiff {
+				fmt.Printf("   = %-*s  %d rows, %s\n", width, t.Name, t.New.Rows, metadata.FormatSize(t.New.SizeBytes))
+			}
+		}
+	}
+
+	var extensions []string
+	for _, ext := range diff.ExtensionsAdded {
+		extensions = append(extensions, "+ "+ext)
+	}
+	for _, ext := range diff.ExtensionsRemoved {
+		extensions = append(extensions, "- "+ext)
+	}
+	extensions = append(extensions, diff.ExtensionsUpdated...)
+	if len(extensions) > 0 {
+		fmt.Printf("   Extensions: %s\n", strings.Join(extensions, ", "))
+	}
+
+	var sequences []string
+	for _, seq := range diff.SequencesAdded {
+		sequences = append(sequences, "+ "+seq)
+	}
+	for _, seq := range diff.SequencesRemoved {
+		sequences = append(sequences, "- "+seq)
+	}
+	if len(sequences) > 0 {
+		fmt.Printf("   Sequences: %s\n", strings.Join(sequences, ", "))
+	}
+}
+
+// signedSize formats a size change, e.g. +1.2 MiB
+func signedSize(delta int64) string {
+	if delta < 0 {
+		return "-" + metadata.FormatSize(-delta)
+	}
+	return "+" + metadata.FormatSize(delta)
+}
+
+func shortFingerprint(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"dbbackup/internal/backup"
+	"dbbackup/internal/crypto"
+	"dbbackup/internal/encryption"
+	"dbbackup/internal/metadata"
+
+	"github.com/spf13/cobra"
+)
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Manage backup encryption keys",
+	Long: `Manage the keys of encrypted backups.
+
+Every encrypted backup has its own random data key. The data key is stored in the
+backup's metadata, wrapped by a master key from a key provider (file, passphrase,
+HashiCorp Vault transit or AWS KMS) or to age X25519 public keys. Rotating the master key only requires
+re-wrapping the data keys; the backups themselves are not re-encrypted.`,
+}
+
+var encryptMigrateCmd = &cobra.Command{
+	Use:   "migrate [file...]",
+	Short: "Re-encrypt legacy encrypted files in the current container format",
+	Long: `Re-encrypt backups and WAL archives written in an older encryption format
+in the current container format (per-file salt, versioned header, authenticated
+chunks). The key stays the same; metadata checksums are updated, including the
+base references of incremental backups.
+
+Files are decrypted with the data key from their metadata (unwrapped like on
+restore) or with --encryption-key-file/--encryption-key-env. Legacy files
+without a header need the raw 32-byte key.
+
+Examples:
+  # Show the format of every file without changing anything
+  dbbackup encrypt migrate /backups/* --dry-run
+
+  # Migrate WAL archives encrypted with a key file
+  dbbackup encrypt migrate /wal_archive/*.enc --encryption-key-file wal.key`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runEncryptMigrate,
+}
+
+var encryptRewrapCmd = &cobra.Command{
+	Use:   "rewrap [backup-file...]",
+	Short: "Re-wrap backup data keys with a new master key",
+	Long: `Unwrap the data key of each backup with the key provider recorded in its
+metadata and wrap it again with a new master key. Only the .meta.json files
+are rewritten.
+
+The current master key is given with --old-key-file/--old-key-env (file and
+passphrase providers), --old-identity-file (x25519) or taken from the Vault/AWS
+environment. The new master key is selected like for 'backup --encrypt'.
+
+Examples:
+  # Rotate a local master key
+  dbbackup encrypt rewrap /backups/*.dump --old-key-file old.key --encryption-key-file new.key
+
+  # Move backups from a local key to a Vault transit key
+  export VAULT_ADDR=https://vault:8200 VAULT_TOKEN=...
+  dbbackup encrypt rewrap /backups/*.dump --old-key-file old.key \
+    --key-provider vault --key-id transit/dbbackup
+
+  # Add a second recipient to backups encrypted with public keys
+  dbbackup encrypt rewrap /backups/*.dump --old-identity-file ops.agekey \
+    --recipient age1... --recipient age1...`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runEncryptRewrap,
+}
+
+var (
+	rewrapOldKeyFile     string
+	rewrapOldKeyEnv      string
+	rewrapOldKeyEndpoint string
+	rewrapKeyProvider    string
+	rewrapKeyID          string
+	rewrapKeyEndpoint    string
+	rewrapKeyFile        string
+	rewrapKeyEnv         string
+	rewrapOldIdentity    string
+	rewrapRecipients     []string
+	rewrapRecipientsFile string
+
+	migrateKeyFile      string
+	migrateKeyEnv       string
+	migrateKeyEndpoint  string
+	migrateIdentityFile string
+	migrateDryRun       bool
+)
+
+func init() {
+	rootCmd.AddCommand(encryptCmd)
+	encryptCmd.AddCommand(encryptRewrapCmd)
+	encryptCmd.AddCommand(encryptMigrateCmd)
+
+	encryptRewrapCmd.Flags().StringVar(&rewrapOldKeyFile, "old-key-file", "", "Current master key or passphrase file")
+	encryptRewrapCmd.Flags().StringVar(&rewrapOldKeyEnv, "old-key-env", "", "Environment variable containing the current master key or passphrase")
+	encryptRewrapCmd.Flags().StringVar(&rewrapOldKeyEndpoint, "old-key-endpoint", "", "Vault address or KMS endpoint of the current master key")
+	encryptRewrapCmd.Flags().StringVar(&rewrapOldIdentity, "old-identity-file", "", "age identity file for backups encrypted to X25519 recipients")
+	encryptRewrapCmd.Flags().StringVar(&rewrapKeyProvider, "key-provider", "", "New master key provider: file, passphrase, vault, kms or x25519 (default: x25519 with --recipient, otherwise file or passphrase, by key)")
+	encryptRewrapCmd.Flags().StringVar(&rewrapKeyID, "key-id", "", "New Vault transit key (mount/key) or KMS key ID/ARN")
+	encryptRewrapCmd.Flags().StringVar(&rewrapKeyEndpoint, "key-endpoint", "", "New Vault address (default: $VAULT_ADDR) or KMS-compatible endpoint")
+	encryptRewrapCmd.Flags().StringVar(&rewrapKeyFile, "encryption-key-file", "", "New master key or passphrase file")
+	encryptRewrapCmd.Flags().StringVar(&rewrapKeyEnv, "encryption-key-env", "DBBACKUP_ENCRYPTION_KEY", "Environment variable containing the new master key or passphrase")
+	encryptRewrapCmd.Flags().StringArrayVar(&rewrapRecipients, "recipient", nil, "Wrap to an age X25519 public key (age1...); repeatable")
+	encryptRewrapCmd.Flags().StringVar(&rewrapRecipientsFile, "recipients-file", "", "File with age X25519 public keys to wrap to, one per line")
+
+	encryptMigrateCmd.Flags().StringVar(&migrateKeyFile, "encryption-key-file", "", "Encryption key or passphrase file (or master key for wrapped data keys)")
+	encryptMigrateCmd.Flags().StringVar(&migrateKeyEnv, "encryption-key-env", "DBBACKUP_ENCRYPTION_KEY", "Environment variable containing the encryption key or passphrase")
+	encryptMigrateCmd.Flags().StringVar(&migrateKeyEndpoint, "key-endpoint", "", "Vault address (default: $VAULT_ADDR) or KMS-compatible endpoint for wrapped data keys")
+	encryptMigrateCmd.Flags().StringVar(&migrateIdentityFile, "identity-file", "", "age identity file for backups encrypted to X25519 recipients")
+	encryptMigrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Only show the encryption format of each file")
+}
+
+func runEncryptRewrap(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	newProvider, err := newKeyProvider(ctx, rewrapKeyProvider, rewrapKeyID, rewrapKeyFile, rewrapKeyEnv, rewrapKeyEndpoint,
+		rewrapRecipients, rewrapRecipientsFile)
+	if err != nil {
+		return fmt.Errorf("failed to set up new key provider: %w", err)
+	}
+
+	var backupFiles []string
+	for _, pattern := range args {
+		matches, err := filepath.Glob(pattern)
+		if err != nil || len(matches) == 0 {
+			backupFiles = append(backupFiles, pattern)
+			continue
+		}
+		backupFiles = append(backupFiles, matches...)
+	}
+
+	failures := 0
+	for _, backupFile := range backupFiles {
+		if strings.HasSuffix(backupFile, ".meta.json") || strings.HasSuffix(backupFile, ".sha256") {
+			continue
+		}
+
+		count, err := rewrapBackup(ctx, backupFile, newProvider)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", filepath.Base(backupFile), err)
+			failures++
+			continue
+		}
+		target := newProvider.KeyID()
+		if rp, ok := newProvider.(*crypto.RecipientKeyProvider); ok {
+			target = strings.Join(rp.Recipients(), ", ")
+		}
+		fmt.Printf("✅ %s: %d data key(s) re-wrapped with %s key %s\n",
+			filepath.Base(backupFile), count, newProvider.Name(), target)
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("failed to re-wrap %d backup(s)", failures)
+	}
+	return nil
+}
+
+// rewrapBackup re-wraps the data keys in a backup's metadata and returns how many were changed
+func rewrapBackup(ctx context.Context, backupFile string, newProvider crypto.KeyProvider) (int, error) {
+	rewrap := func(envelope *crypto.EncryptionMetadata) (*crypto.EncryptionMetadata, error) {
+		oldProvider, err := envelopeKeyProvider(ctx, envelope, rewrapOldKeyFile, rewrapOldKeyEnv, rewrapOldKeyEndpoint, rewrapOldIdentity)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.RewrapDataKey(ctx, oldProvider, newProvider, envelope)
+	}
+
+	// Cluster backups record one envelope per database
+	if clusterMeta, err := metadata.LoadCluster(backupFile); err == nil && len(clusterMeta.Databases) > 0 {
+		count := 0
+		for i := range clusterMeta.Databases {
+			envelope := clusterMeta.Databases[i].Encryption
+			if envelope == nil || envelope.WrappedKey == "" {
+				continue
+			}
+			rewrapped, err := rewrap(envelope)
+			if err != nil {
+				return 0, err
+			}
+			clusterMeta.Databases[i].Encryption = rewrapped
+			count++
+		}
+		if count == 0 {
+			return 0, fmt.Errorf("no wrapped data keys in metadata (not encrypted with envelope encryption)")
+		}
+		return count, clusterMeta.Save(backupFile)
+	}
+
+	meta, err := metadata.Load(backupFile)
+	if err != nil {
+		return 0, err
+	}
+	if meta.Encryption == nil || meta.Encryption.WrappedKey == "" {
+		return 0, fmt.Errorf("no wrapped data key in metadata (not encrypted with envelope encryption)")
+	}
+	rewrapped, err := rewrap(meta.Encryption)
+	if err != nil {
+		return 0, err
+	}
+	meta.Encryption = rewrapped
+	return 1, metadata.Save(backupFile+".meta.json", meta)
+}
+
+func runEncryptMigrate(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	var files []string
+	for _, pattern := range args {
+		matches, err := filepath.Glob(pattern)
+		if err != nil || len(matches) == 0 {
+			files = append(files, pattern)
+			continue
+		}
+		files = append(files, matches...)
+	}
+
+	migrated, failures := 0, 0
+	for _, file := range files {
+		if strings.HasSuffix(file, ".meta.json") || strings.HasSuffix(file, ".sha256") {
+			continue
+		}
+		name := filepath.Base(file)
+
+		format := backup.EncryptionFormat(file)
+		switch {
+		case format == encryption.FormatContainer:
+			fmt.Printf("✅ %s: %s (current)\n", name, format)
+			continue
+		case format == encryption.FormatUnknown && backup.IsStreamEncrypted(file):
+			fmt.Printf("⏭️  %s: cluster archive with encrypted dumps inside (readable in every format, not migrated)\n", name)
+			continue
+		case format == encryption.FormatUnknown:
+			fmt.Printf("⏭️  %s: not encrypted\n", name)
+			continue
+		}
+
+		if migrateDryRun {
+			fmt.Printf("🔄 %s: %s (would migrate)\n", name, format)
+			continue
+		}
+
+		opts, err := migrateEncryptionOptions(ctx, file)
+		if err == nil {
+			err = backup.MigrateEncryptedFile(file, *opts, log)
+		}
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", name, err)
+			failures++
+			continue
+		}
+		fmt.Printf("✅ %s: migrated from %s\n", name, format)
+		migrated++
+	}
+
+	if !migrateDryRun {
+		fmt.Printf("\n%d file(s) migrated\n", migrated)
+	}
+	if failures > 0 {
+		return fmt.Errorf("failed to migrate %d file(s)", failures)
+	}
+	return nil
+}
+
+// migrateEncryptionOptions returns the key a file is encrypted with: its
+// unwrapped data key, or the configured key or passphrase
+func migrateEncryptionOptions(ctx context.Context, file string) (*encryption.EncryptionOptions, error) {
+	envelope := backup.LoadEncryptionEnvelope(file)
+	if envelope == nil {
+		return loadEncryptionOptions(migrateKeyFile, migrateKeyEnv)
+	}
+
+	provider, err := envelopeKeyProvider(ctx, envelope, migrateKeyFile, migrateKeyEnv, migrateKeyEndpoint, migrateIdentityFile)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := crypto.UnwrapDataKey(ctx, provider, envelope)
+	if err != nil {
+		return nil, err
+	}
+	return &encryption.EncryptionOptions{Key: dataKey}, nil
+}
@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"os"
 	"strings"
 
+	"dbbackup/internal/backup"
 	"dbbackup/internal/crypto"
+	"dbbackup/internal/encryption"
 )
 
 // loadEncryptionKey loads encryption key from file or environment variable
@@ -61,6 +64,247 @@ func loadEncryptionKey(keyFile, keyEnvVar string) ([]byte, error) {
 	return nil, fmt.Errorf("encryption enabled but no key source specified (use --encryption-key-file or set %s)", keyEnvVar)
 }
 
+// loadEncryptionOptions loads the key for streaming encryption from file or
+// environment variable. 32-byte keys (raw or base64) are used directly; anything
+// else is treated as a passphrase whose salt is stored in the encrypted file.
+func loadEncryptionOptions(keyFile, keyEnvVar string) (*encryption.EncryptionOptions, error) {
+	keyData, err := readKeyMaterial(keyFile, keyEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	
+	if key := parseRawKey(keyData); key != nil {
+		return &encryption.EncryptionOptions{Key: key}, nil
+	}
+	return &encryption.EncryptionOptions{Passphrase: strings.TrimSpace(string(keyData))}, nil
+}
+
+// readKeyMaterial reads a key or passphrase from file or environment variable
+func readKeyMaterial(keyFile, keyEnvVar string) ([]byte, error) {
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+		}
+		return data, nil
+	}
+	if keyEnvVar != "" {
+		value := os.Getenv(keyEnvVar)
+		if value == "" {
+			return nil, fmt.Errorf("encryption enabled but %s environment variable not set", keyEnvVar)
+		}
+		return []byte(value), nil
+	}
+	return nil, fmt.Errorf("encryption enabled but no key source specified (use --encryption-key-file or set %s)", keyEnvVar)
+}
+
+// parseRawKey returns keyData as a 32-byte key (base64 or raw), or nil if it is a passphrase
+func parseRawKey(keyData []byte) []byte {
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(keyData))); err == nil && len(decoded) == crypto.KeySize {
+		return decoded
+	}
+	if len(keyData) == crypto.KeySize {
+		return keyData
+	}
+	return nil
+}
+
+// loadKeyProvider creates the key provider that wraps backup data keys.
+// file and passphrase take the master key from keyFile/keyEnvVar (an empty
+// name picks one by the key material); vault and kms use keyID and endpoint,
+// with credentials from the usual VAULT_* and AWS_* environment.
+func loadKeyProvider(ctx context.Context, name, keyID, keyFile, keyEnvVar, endpoint string) (crypto.KeyProvider, error) {
+	var provider crypto.KeyProvider
+	var err error
+	
+	switch name {
+	case "", crypto.ProviderFile, crypto.ProviderPassphrase:
+		keyData, readErr := readKeyMaterial(keyFile, keyEnvVar)
+		if readErr != nil {
+			return nil, readErr
+		}
+		if key := parseRawKey(keyData); key != nil && name != crypto.ProviderPassphrase {
+			provider, err = crypto.NewFileKeyProvider(key)
+		} else if name == crypto.ProviderFile {
+			return nil, fmt.Errorf("file key provider requires a 32-byte master key (raw or base64)")
+		} else {
+			provider, err = crypto.NewPassphraseKeyProvider(strings.TrimSpace(string(keyData)))
+		}
+		
+	case crypto.ProviderVault:
+		if endpoint == "" {
+			endpoint = os.Getenv("VAULT_ADDR")
+		}
+		provider, err = crypto.NewVaultTransitProvider(crypto.VaultConfig{
+			Address:   endpoint,
+			Token:     os.Getenv("VAULT_TOKEN"),
+			Namespace: os.Getenv("VAULT_NAMESPACE"),
+			Key:       keyID,
+		})
+		
+	case crypto.ProviderKMS:
+		provider, err = crypto.NewKMSKeyProvider(ctx, crypto.KMSConfig{
+			KeyID:    keyID,
+			Endpoint: endpoint,
+		})
+		
+	case crypto.ProviderX25519:
+		return nil, fmt.Errorf("x25519 key provider requires --recipient or --recipients-file")
+		
+	default:
+		return nil, fmt.Errorf("unknown key provider %q (use file, passphrase, vault, kms or x25519)", name)
+	}
+	
+	if err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// loadRecipientKeyProvider creates an x25519 provider from age recipients
+// (given directly and/or read from recipientsFile) and an age identity file.
+// Backups only need recipients; restores only need identities.
+func loadRecipientKeyProvider(recipients []string, recipientsFile, identityFile string) (*crypto.RecipientKeyProvider, error) {
+	var parsed []*crypto.X25519Recipient
+	for _, r := range recipients {
+		recipient, err := crypto.ParseX25519Recipient(r)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, recipient)
+	}
+	if recipientsFile != "" {
+		f, err := os.Open(recipientsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recipients file: %w", err)
+		}
+		fromFile, err := crypto.ParseX25519Recipients(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse recipients file %s: %w", recipientsFile, err)
+		}
+		parsed = append(parsed, fromFile...)
+	}
+	
+	var identities []*crypto.X25519Identity
+	if identityFile != "" {
+		f, err := os.Open(identityFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity file: %w", err)
+		}
+		identities, err = crypto.ParseX25519Identities(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse identity file %s: %w", identityFile, err)
+		}
+	}
+	
+	return crypto.NewRecipientKeyProvider(parsed, identities)
+}
+
+// newKeyProvider creates the provider new data keys are wrapped with: x25519
+// when recipients are given, otherwise the provider loadKeyProvider selects
+func newKeyProvider(ctx context.Context, name, keyID, keyFile, keyEnvVar, endpoint string, recipients []string, recipientsFile string) (crypto.KeyProvider, error) {
+	if len(recipients) == 0 && recipientsFile == "" {
+		return loadKeyProvider(ctx, name, keyID, keyFile, keyEnvVar, endpoint)
+	}
+	if name != "" && name != crypto.ProviderX25519 {
+		return nil, fmt.Errorf("--recipient and --recipients-file require the x25519 key provider, not %s", name)
+	}
+	
+	provider, err := loadRecipientKeyProvider(recipients, recipientsFile, "")
+	if err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// newBackupEncryption sets up envelope encryption for a new backup: a random
+// data key encrypts the backup and is stored in its metadata, wrapped by the
+// key provider selected with --key-provider (or to --recipient public keys)
+func newBackupEncryption(ctx context.Context) (*encryption.EncryptionOptions, *crypto.EncryptionMetadata, error) {
+	provider, err := newKeyProvider(ctx, keyProviderFlag, keyIDFlag, encryptionKeyFile, encryptionKeyEnv, keyEndpointFlag,
+		recipientFlags, recipientsFileFlag)
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	dataKey, envelope, err := crypto.NewDataKey(ctx, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	if len(envelope.Recipients) > 0 {
+		log.Info("Generated backup data key", "key_provider", envelope.KeyProvider, "recipients", strings.Join(envelope.Recipients, ","))
+	} else {
+		log.Info("Generated backup data key", "key_provider", envelope.KeyProvider, "key_id", envelope.KeyID)
+	}
+	return &encryption.EncryptionOptions{Key: dataKey}, envelope, nil
+}
+
+// restoreEncryptionOptions returns the key to decrypt a streaming-encrypted
+// backup. Envelope-encrypted backups have their data key unwrapped by the key
+// provider recorded in their metadata; older backups use the configured key.
+func restoreEncryptionOptions(ctx context.Context, backupPath string) (*encryption.EncryptionOptions, error) {
+	envelope := backup.LoadEncryptionEnvelope(backupPath)
+	if envelope == nil {
+		return loadEncryptionOptions(restoreEncryptionKeyFile, restoreEncryptionKeyEnv)
+	}
+	
+	dataKey, err := unwrapBackupKey(ctx, envelope)
+	if err != nil {
+		return nil, err
+	}
+	return &encryption.EncryptionOptions{Key: dataKey}, nil
+}
+
+// restoreEncryptionKey is restoreEncryptionOptions for backups encrypted
+// after they were written (EncryptBackupFile), which need a raw key
+func restoreEncryptionKey(ctx context.Context, backupPath string) ([]byte, error) {
+	if envelope := backup.LoadEncryptionEnvelope(backupPath); envelope != nil {
+		return unwrapBackupKey(ctx, envelope)
+	}
+	return loadEncryptionKey(restoreEncryptionKeyFile, restoreEncryptionKeyEnv)
+}
+
+// unwrapBackupKey unwraps a backup's data key with the provider recorded in its
+// metadata, using the restore key flags for the master key
+func unwrapBackupKey(ctx context.Context, envelope *crypto.EncryptionMetadata) ([]byte, error) {
+	provider, err := envelopeKeyProvider(ctx, envelope, restoreEncryptionKeyFile, restoreEncryptionKeyEnv, restoreKeyEndpoint, restoreIdentityFile)
+	if err != nil {
+		return nil, err
+	}
+	
+	dataKey, err := crypto.UnwrapDataKey(ctx, provider, envelope)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Unwrapped backup data key", "key_provider", envelope.KeyProvider, "key_id", envelope.KeyID)
+	return dataKey, nil
+}
+
+// envelopeKeyProvider creates the provider that wrapped a backup's data key.
+// Data keys wrapped to X25519 recipients are unwrapped with identityFile.
+func envelopeKeyProvider(ctx context.Context, envelope *crypto.EncryptionMetadata, keyFile, keyEnvVar, endpoint, identityFile string) (crypto.KeyProvider, error) {
+	if envelope.KeyProvider == crypto.ProviderX25519 {
+		if identityFile == "" {
+			return nil, fmt.Errorf("backup is encrypted to X25519 recipients %s; a matching private key is required (use --identity-file)",
+				strings.Join(envelope.Recipients, ", "))
+		}
+		provider, err := loadRecipientKeyProvider(nil, "", identityFile)
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
+	}
+	
+	provider, err := loadKeyProvider(ctx, envelope.KeyProvider, envelope.KeyID, keyFile, keyEnvVar, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up %s key provider: %w", envelope.KeyProvider, err)
+	}
+	return provider, nil
+}
+
 // isEncryptionEnabled checks if encryption is requested
 func isEncryptionEnabled() bool {
 	return encryptBackupFlag
@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"dbbackup/internal/compression"
 	"dbbackup/internal/wal"
 )
 
@@ -204,7 +205,7 @@ func init() {
 
 	// WAL archive flags
 	walArchiveCmd.Flags().StringVar(&walArchiveDir, "archive-dir", "", "WAL archive directory (required)")
-	walArchiveCmd.Flags().BoolVar(&walCompress, "compress", false, "Compress WAL files with gzip")
+	walArchiveCmd.Flags().BoolVar(&walCompress, "compress", false, "Compress WAL files (algorithm and level from --compression-algorithm and --compression)")
 	walArchiveCmd.Flags().BoolVar(&walEncrypt, "encrypt", false, "Encrypt WAL files")
 	walArchiveCmd.Flags().StringVar(&walEncryptionKeyFile, "encryption-key-file", "", "Path to encryption key file (32 bytes)")
 	walArchiveCmd.Flags().StringVar(&walEncryptionKeyEnv, "encryption-key-env", "DBBACKUP_ENCRYPTION_KEY", "Environment variable containing encryption key")
@@ -324,22 +325,35 @@ func runWALArchive(cmd *cobra.Command, args []string) error {
 	walPath := args[0]
 	walFilename := args[1]
 
-	// Load encryption key if encryption is enabled
+	// Load encryption key or passphrase if encryption is enabled
 	var encryptionKey []byte
+	var encryptionPassphrase string
 	if walEncrypt {
-		key, err := loadEncryptionKey(walEncryptionKeyFile, walEncryptionKeyEnv)
+		encOpts, err := loadEncryptionOptions(walEncryptionKeyFile, walEncryptionKeyEnv)
 		if err != nil {
 			return fmt.Errorf("failed to load WAL encryption key: %w", err)
 		}
-		encryptionKey = key
+		encryptionKey = encOpts.Key
+		encryptionPassphrase = encOpts.Passphrase
 	}
 
+	// A WAL segment is small and archive_command runs once per segment,
+	// so one compressor thread is enough. --compress asks for compression,
+	// so level 0 (uncompressed dumps) uses the algorithm's fastest level.
+	walCompression := cfg.CompressionOptions()
+	if cfg.CompressionLevel == 0 {
+		walCompression.Algorithm, _ = compression.ParseAlgorithm(cfg.CompressionAlgorithm)
+	}
+	walCompression.Threads = 1
+
 	archiver := wal.NewArchiver(cfg, log)
 	archiveConfig := wal.ArchiveConfig{
-		ArchiveDir:    walArchiveDir,
-		CompressWAL:   walCompress,
-		EncryptWAL:    walEncrypt,
-		EncryptionKey: encryptionKey,
+		ArchiveDir:           walArchiveDir,
+		CompressWAL:          walCompress,
+		Compression:          walCompression,
+		EncryptWAL:           walEncrypt,
+		EncryptionKey:        encryptionKey,
+		EncryptionPassphrase: encryptionPassphrase,
 	}
 
 	info, err := archiver.ArchiveWALFile(ctx, walPath, walFilename, archiveConfig)
@@ -194,7 +194,7 @@ type backupFile struct {
 // isBackupFile checks if a file is a backup file based on extension
 func isBackupFile(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
-	return ext == ".dump" || ext == ".sql" || ext == ".tar" || ext == ".gz" ||
+	return ext == ".dump" || ext == ".sql" || ext == ".tar" || ext == ".gz" || ext == ".zst" || ext == ".lz4" ||
 		strings.HasSuffix(filename, ".tar.gz") || strings.HasSuffix(filename, ".dump.gz")
 }
 
@@ -2,18 +2,23 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"dbbackup/internal/backup"
 	"dbbackup/internal/cloud"
+	"dbbackup/internal/compression"
 	"dbbackup/internal/database"
+	"dbbackup/internal/encryption"
 	"dbbackup/internal/pitr"
 	"dbbackup/internal/restore"
 	"dbbackup/internal/security"
@@ -33,10 +38,29 @@ var (
 	restoreNoProgress bool
 	restoreWorkdir   string
 	restoreCleanCluster bool
+	restoreStream    bool
+	
+	// Archive tier rehydration for cloud restores
+	restoreRehydratePriority string
+	restoreRehydrateDays     int
+	restoreRehydrateWait     time.Duration
+	
+	// Selective restore
+	restoreTables        []string
+	restoreSchemas       []string
+	restoreExcludeTables []string
+	restoreDatabases     []string
+	restoreOnly          []string
+	restoreMap           []string
+	restoreMapExisting   bool
+	restoreWithGlobals   bool
+	restoreResume        string
 	
 	// Encryption flags
 	restoreEncryptionKeyFile string
 	restoreEncryptionKeyEnv  string = "DBBACKUP_ENCRYPTION_KEY"
+	restoreKeyEndpoint       string
+	restoreIdentityFile      string
 	
 	// PITR restore flags (additional to pitr.go)
 	pitrBaseBackup  string
@@ -44,6 +68,7 @@ var (
 	pitrTargetDir   string
 	pitrInclusive   bool
 	pitrSkipExtract bool
+	pitrSkipWALChk  bool
 	pitrAutoStart   bool
 	pitrMonitor     bool
 )
@@ -94,6 +119,16 @@ Safety features:
   - Disk space verification
   - Optional database backup before restore
 
+With --stream, a cloud backup is piped through decryption and decompression
+into pg_restore/psql/mysql as it downloads, so the host needs no disk space for
+it. Its SHA-256 is checked against the backup's metadata on the fly, and the
+restore fails on a mismatch.
+
+--table, --schema and --exclude-table restore part of the dump. Tables come
+with their data, indexes, constraints and triggers; foreign keys to tables
+that aren't restored are skipped. PostgreSQL needs a custom-format (.dump)
+backup for this; MySQL dumps are filtered by table as they are read.
+
 Examples:
   # Preview restore
   dbbackup restore single mydb.dump.gz
@@ -109,6 +144,13 @@ Examples:
 
   # Create database if it doesn't exist
   dbbackup restore single mydb.sql --create --confirm
+
+  # Restore from S3 while downloading, without a local copy
+  dbbackup restore single s3://backups/pg/db_mydb_20250126_120000.dump --stream --confirm
+
+  # Restore two tables, or a whole schema without its audit log
+  dbbackup restore single mydb.dump --table orders --table public.customers --confirm
+  dbbackup restore single mydb.dump --schema sales --exclude-table 'sales.audit_*' --confirm
 `,
 	Args: cobra.ExactArgs(1),
 	RunE: runRestoreSingle,
@@ -123,6 +165,11 @@ var restoreClusterCmd = &cobra.Command{
 This command restores all databases that were backed up together
 in a cluster backup operation.
 
+Each restore keeps a journal next to its extraction directory
+(.restore_<timestamp>.journal.json) with the state of every database. If
+databases fail or the restore is interrupted, the extraction and journal are
+kept, and --resume <journal> restores only the databases that aren't done.
+
 Safety features:
   - Dry-run by default (use --confirm to execute)
   - Archive validation and listing
@@ -144,8 +191,28 @@ Examples:
 
 	# Disaster recovery: drop all existing databases first (clean slate)
 	dbbackup restore cluster cluster_backup.tar.gz --clean-cluster --confirm
+
+	# Restore from cloud storage, waiting up to 12h if the archive is in Glacier
+	dbbackup restore cluster s3://backups/cluster_20240101_120000.tar.gz --rehydrate-wait 12h --confirm
+
+	# Restore only some databases (only their dumps are extracted)
+	dbbackup restore cluster cluster_backup.tar.gz --database shop --database 'crm_*' --confirm
+
+	# Restore shop next to production as shop_restored (globals only with --globals)
+	dbbackup restore cluster cluster_backup.tar.gz --only shop,crm --map shop=shop_restored --confirm
+
+	# Restore one table of one database into the existing database
+	dbbackup restore cluster cluster_backup.tar.gz --database shop --table orders --confirm
+
+	# Continue a failed or interrupted restore
+	dbbackup restore cluster --resume /var/lib/pgsql/db_backups/.restore_1751248800.journal.json --confirm
 `,
-	Args: cobra.ExactArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if restoreResume != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: runRestoreCluster,
 }
 
@@ -174,6 +241,11 @@ var restorePITRCmd = &cobra.Command{
 PITR allows restoring to any point in time, not just the backup moment.
 Requires a base backup and continuous WAL archives.
 
+Base backups created with 'dbbackup backup base' record their WAL start/stop
+position and timeline. Before extracting, the WAL archive is checked to
+continue on that timeline right after the backup, and the recovery target
+must not lie before the end of the backup (skip with --skip-wal-check).
+
 Recovery Target Types:
   --target-time      Restore to specific timestamp
   --target-xid       Restore to transaction ID
@@ -232,6 +304,25 @@ func init() {
 	restoreSingleCmd.Flags().BoolVar(&restoreNoProgress, "no-progress", false, "Disable progress indicators")
 	restoreSingleCmd.Flags().StringVar(&restoreEncryptionKeyFile, "encryption-key-file", "", "Path to encryption key file (required for encrypted backups)")
 	restoreSingleCmd.Flags().StringVar(&restoreEncryptionKeyEnv, "encryption-key-env", "DBBACKUP_ENCRYPTION_KEY", "Environment variable containing encryption key")
+	restoreSingleCmd.Flags().StringVar(&restoreKeyEndpoint, "key-endpoint", "", "Vault address (default: $VAULT_ADDR) or KMS-compatible endpoint for wrapped data keys")
+	restoreSingleCmd.Flags().StringVar(&restoreIdentityFile, "identity-file", "", "age identity file (AGE-SECRET-KEY-1...) for backups encrypted to X25519 recipients")
+	restoreSingleCmd.Flags().BoolVar(&restoreStream, "stream", false, "Stream a cloud backup into the restore instead of downloading it first")
+	restoreClusterCmd.Flags().StringArrayVar(&restoreDatabases, "database", nil, "Restore only this database from the cluster archive (repeatable, wildcards allowed)")
+	restoreClusterCmd.Flags().StringSliceVar(&restoreOnly, "only", nil, "Restore only these databases (comma-separated, same as repeating --database)")
+	restoreClusterCmd.Flags().StringSliceVar(&restoreMap, "map", nil, "Restore a database under a new name, as source=target (comma-separated or repeatable); the original is left untouched")
+	restoreClusterCmd.Flags().BoolVar(&restoreMapExisting, "map-existing", false, "Allow --map targets that already exist and restore on top of them")
+	restoreClusterCmd.Flags().StringVar(&restoreResume, "resume", "", "Continue an earlier cluster restore from its journal, restoring only databases that failed or didn't run")
+	restoreClusterCmd.Flags().BoolVar(&restoreWithGlobals, "globals", false, "Also restore roles and tablespaces (MySQL: users and grants) when restoring selected databases")
+	for _, cmd := range []*cobra.Command{restoreSingleCmd, restoreClusterCmd} {
+		cmd.Flags().StringArrayVar(&restoreTables, "table", nil, "Restore only this table, as name or schema.name (repeatable, wildcards allowed)")
+		cmd.Flags().StringArrayVar(&restoreSchemas, "schema", nil, "Restore only this schema (repeatable, wildcards allowed)")
+		cmd.Flags().StringArrayVar(&restoreExcludeTables, "exclude-table", nil, "Skip this table, as name or schema.name (repeatable, wildcards allowed)")
+	}
+	for _, cmd := range []*cobra.Command{restoreSingleCmd, restoreClusterCmd, restoreChainCmd} {
+		cmd.Flags().StringVar(&restoreRehydratePriority, "rehydrate-priority", "standard", "Priority for rehydrating archived cloud backups: standard, high or bulk (bulk: S3 only)")
+		cmd.Flags().IntVar(&restoreRehydrateDays, "rehydrate-days", 3, "Days a rehydrated S3 Glacier copy stays readable")
+		cmd.Flags().DurationVar(&restoreRehydrateWait, "rehydrate-wait", 24*time.Hour, "How long to wait for archived cloud backups to be rehydrated (0: only request it)")
+	}
 
 	// Cluster restore flags
 	restoreClusterCmd.Flags().BoolVar(&restoreConfirm, "confirm", false, "Confirm and execute restore (required)")
@@ -244,6 +335,8 @@ func init() {
 	restoreClusterCmd.Flags().BoolVar(&restoreNoProgress, "no-progress", false, "Disable progress indicators")
 	restoreClusterCmd.Flags().StringVar(&restoreEncryptionKeyFile, "encryption-key-file", "", "Path to encryption key file (required for encrypted backups)")
 	restoreClusterCmd.Flags().StringVar(&restoreEncryptionKeyEnv, "encryption-key-env", "DBBACKUP_ENCRYPTION_KEY", "Environment variable containing encryption key")
+	restoreClusterCmd.Flags().StringVar(&restoreKeyEndpoint, "key-endpoint", "", "Vault address (default: $VAULT_ADDR) or KMS-compatible endpoint for wrapped data keys")
+	restoreClusterCmd.Flags().StringVar(&restoreIdentityFile, "identity-file", "", "age identity file (AGE-SECRET-KEY-1...) for backups encrypted to X25519 recipients")
 	
 	// PITR restore flags
 	restorePITRCmd.Flags().StringVar(&pitrBaseBackup, "base-backup", "", "Path to base backup file (.tar.gz) (required)")
@@ -258,8 +351,13 @@ func init() {
 	restorePITRCmd.Flags().StringVar(&pitrWALSource, "timeline", "latest", "Timeline to follow (latest or timeline ID)")
 	restorePITRCmd.Flags().BoolVar(&pitrInclusive, "inclusive", true, "Include target transaction/time")
 	restorePITRCmd.Flags().BoolVar(&pitrSkipExtract, "skip-extraction", false, "Skip base backup extraction (data dir exists)")
+	restorePITRCmd.Flags().BoolVar(&pitrSkipWALChk, "skip-wal-check", false, "Skip checking the base backup against the WAL archive")
 	restorePITRCmd.Flags().BoolVar(&pitrAutoStart, "auto-start", false, "Automatically start PostgreSQL after setup")
 	restorePITRCmd.Flags().BoolVar(&pitrMonitor, "monitor", false, "Monitor recovery progress (requires --auto-start)")
+	restorePITRCmd.Flags().StringVar(&restoreEncryptionKeyFile, "encryption-key-file", "", "Path to encryption key file (required for encrypted base backups)")
+	restorePITRCmd.Flags().StringVar(&restoreEncryptionKeyEnv, "encryption-key-env", "DBBACKUP_ENCRYPTION_KEY", "Environment variable containing encryption key")
+	restorePITRCmd.Flags().StringVar(&restoreKeyEndpoint, "key-endpoint", "", "Vault address (default: $VAULT_ADDR) or KMS-compatible endpoint for wrapped data keys")
+	restorePITRCmd.Flags().StringVar(&restoreIdentityFile, "identity-file", "", "age identity file (AGE-SECRET-KEY-1...) for backups encrypted to X25519 recipients")
 	
 	restorePITRCmd.MarkFlagRequired("base-backup")
 	restorePITRCmd.MarkFlagRequired("wal-archive")
@@ -270,6 +368,21 @@ func init() {
 func runRestoreSingle(cmd *cobra.Command, args []string) error {
 	archivePath := args[0]
 	
+	selection, err := restoreSelection()
+	if err != nil {
+		return err
+	}
+	
+	if restoreStream {
+		if selection.FiltersObjects() {
+			return fmt.Errorf("--table, --schema and --exclude-table cannot be combined with --stream")
+		}
+		if !cloud.IsCloudURI(archivePath) {
+			return fmt.Errorf("--stream requires a cloud URI (e.g. s3://bucket/path/backup.dump)")
+		}
+		return runRestoreSingleStream(archivePath)
+	}
+	
 	// Check if this is a cloud URI
 	var cleanupFunc func() error
 	
@@ -280,9 +393,10 @@ func runRestoreSingle(cmd *cobra.Command, args []string) error {
 		result, err := restore.DownloadFromCloudURI(cmd.Context(), archivePath, restore.DownloadOptions{
 			VerifyChecksum: true,
 			KeepLocal:      false, // Delete after restore
+			Rehydrate:      rehydrateOptions(),
 		})
 		if err != nil {
-			return fmt.Errorf("failed to download from cloud: %w", err)
+			return fmt.Errorf("failed to download from cloud: %w", rehydrateHint(err))
 		}
 		
 		archivePath = result.LocalPath
@@ -314,10 +428,19 @@ func runRestoreSingle(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Check if backup is encrypted and decrypt if necessary
-	if backup.IsBackupEncrypted(archivePath) {
+	// Backups encrypted while they were written are decrypted on the fly during
+	// the restore; older backups encrypted after the fact are decrypted in place
+	var encOpts *encryption.EncryptionOptions
+	if backup.IsStreamEncrypted(archivePath) {
+		log.Info("Encrypted backup detected, decrypting during restore")
+		opts, err := restoreEncryptionOptions(cmd.Context(), archivePath)
+		if err != nil {
+			return fmt.Errorf("encrypted backup requires encryption key: %w", err)
+		}
+		encOpts = opts
+	} else if backup.IsBackupEncrypted(archivePath) {
 		log.Info("Encrypted backup detected, decrypting...")
-		key, err := loadEncryptionKey(restoreEncryptionKeyFile, restoreEncryptionKeyEnv)
+		key, err := restoreEncryptionKey(cmd.Context(), archivePath)
 		if err != nil {
 			return fmt.Errorf("encrypted backup requires encryption key: %w", err)
 		}
@@ -388,6 +511,9 @@ func runRestoreSingle(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Target Database: %s\n", targetDB)
 		fmt.Printf("  Clean Before Restore: %v\n", restoreClean)
 		fmt.Printf("  Create If Missing: %v\n", restoreCreate)
+		if selection.FiltersObjects() {
+			fmt.Printf("  Selection: %s\n", selection)
+		}
 		fmt.Println("\nTo execute this restore, add --confirm flag")
 		return nil
 	}
@@ -401,6 +527,8 @@ func runRestoreSingle(cmd *cobra.Command, args []string) error {
 
 	// Create restore engine
 	engine := restore.New(cfg, log, db)
+	engine.SetEncryption(encOpts)
+	engine.SetSelection(selection)
 
 	// Setup signal handling
 	ctx, cancel := context.WithCancel(context.Background())
@@ -436,10 +564,217 @@ func runRestoreSingle(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// restoreSelection returns the databases, schemas and tables selected with
+// --database, --schema, --table and --exclude-table
+func restoreSelection() (restore.Selection, error) {
+	selection := restore.Selection{
+		Tables:        restoreTables,
+		Schemas:       restoreSchemas,
+		ExcludeTables: restoreExcludeTables,
+		Databases:     append(slices.Clone(restoreDatabases), restoreOnly...),
+	}
+	if err := selection.Validate(); err != nil {
+		return selection, fmt.Errorf("invalid selection: %w", err)
+	}
+	renames, err := restore.ParseDatabaseMap(restoreMap)
+	if err != nil {
+		return selection, fmt.Errorf("invalid --map: %w", err)
+	}
+	selection.Map = renames
+	selection.MapExisting = restoreMapExisting
+	// A subset of databases is restored without the cluster-wide objects
+	// unless they are asked for
+	selection.SkipGlobals = selection.SelectsDatabases() && !restoreWithGlobals
+	return selection, nil
+}
+
+// serverDatabases lists the databases on the server a restore writes to
+func serverDatabases(ctx context.Context) ([]string, error) {
+	db, err := database.New(cfg, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database instance: %w", err)
+	}
+	defer db.Close()
+	if err := db.Connect(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	databases, err := db.ListDatabases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+	return databases, nil
+}
+
+// rehydrateOptions returns how archived cloud backups are made readable
+func rehydrateOptions() cloud.RehydrateOptions {
+	return cloud.RehydrateOptions{
+		Priority: restoreRehydratePriority,
+		Days:     restoreRehydrateDays,
+		Wait:     restoreRehydrateWait,
+		Log:      log.Info,
+	}
+}
+
+// rehydrateHint explains what to do when an archived backup isn't readable yet
+func rehydrateHint(err error) error {
+	var archived *cloud.ArchivedError
+	if errors.As(err, &archived) {
+		return fmt.Errorf("%w; run the restore again once it is readable, or raise --rehydrate-wait", err)
+	}
+	return err
+}
+
+// runRestoreSingleStream restores a single database from a cloud backup while it downloads
+func runRestoreSingleStream(uri string) error {
+	cloudURI, err := cloud.ParseCloudURI(uri)
+	if err != nil {
+		return fmt.Errorf("invalid cloud URI: %w", err)
+	}
+	backend, err := cloud.NewBackend(cloudURI.ToConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create cloud backend: %w", err)
+	}
+	defer cloud.CloseBackend(backend)
+	
+	// Setup signal handling
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan) // Ensure signal cleanup on exit
+	
+	go func() {
+		<-sigChan
+		log.Warn("Restore interrupted by user")
+		cancel()
+	}()
+	
+	// An archived backup can't be streamed until it is rehydrated
+	if !restoreDryRun && restoreConfirm {
+		if err := cloud.Rehydrate(ctx, backend, []string{cloudURI.Path}, rehydrateOptions()); err != nil {
+			return rehydrateHint(err)
+		}
+	}
+	
+	// The metadata says whether the backup is encrypted and how its key is wrapped
+	meta, err := restore.LoadRemoteMetadata(ctx, backend, cloudURI.Path)
+	if err != nil {
+		log.Warn("Could not read backup metadata", "error", err)
+	}
+	var encOpts *encryption.EncryptionOptions
+	if meta != nil && meta.Encryption != nil && meta.Encryption.WrappedKey != "" {
+		dataKey, err := unwrapBackupKey(ctx, meta.Encryption)
+		if err != nil {
+			return fmt.Errorf("encrypted backup requires encryption key: %w", err)
+		}
+		encOpts = &encryption.EncryptionOptions{Key: dataKey}
+	} else if (meta != nil && meta.Encrypted) || restoreEncryptionKeyFile != "" || os.Getenv(restoreEncryptionKeyEnv) != "" {
+		if encOpts, err = loadEncryptionOptions(restoreEncryptionKeyFile, restoreEncryptionKeyEnv); err != nil {
+			return fmt.Errorf("encrypted backup requires encryption key: %w", err)
+		}
+	}
+	
+	// Extract database name from the object name if target not specified
+	targetDB := restoreTarget
+	if targetDB == "" {
+		targetDB = extractDBNameFromArchive(cloudURI.Path)
+		if targetDB == "" {
+			return fmt.Errorf("cannot determine database name, please specify --target")
+		}
+	} else {
+		targetDB = stripFileExtensions(targetDB)
+	}
+	
+	// Nothing is downloaded, so only the tools can be checked up front
+	if !restoreForce {
+		dbType := "postgres"
+		if meta != nil && (meta.DatabaseType == "mysql" || meta.DatabaseType == "mariadb") {
+			dbType = "mysql"
+		}
+		if err := restore.NewSafety(cfg, log).VerifyTools(dbType); err != nil {
+			return fmt.Errorf("tool verification failed: %w", err)
+		}
+	}
+	
+	if restoreDryRun || !restoreConfirm {
+		fmt.Println("\n🔍 DRY-RUN MODE - No changes will be made")
+		fmt.Printf("\nWould stream restore:\n")
+		fmt.Printf("  Object: %s\n", uri)
+		fmt.Printf("  Target Database: %s\n", targetDB)
+		fmt.Printf("  Encrypted: %v\n", encOpts != nil)
+		fmt.Printf("  Clean Before Restore: %v\n", restoreClean)
+		fmt.Printf("  Create If Missing: %v\n", restoreCreate)
+		fmt.Println("\nTo execute this restore, add --confirm flag")
+		return nil
+	}
+	
+	db, err := database.New(cfg, log)
+	if err != nil {
+		return fmt.Errorf("failed to create database instance: %w", err)
+	}
+	defer db.Close()
+	
+	engine := restore.New(cfg, log, db)
+	engine.SetEncryption(encOpts)
+	
+	log.Info("Starting streaming restore...", "database", targetDB, "object", uri)
+	
+	// Audit log: restore start
+	user := security.GetCurrentUser()
+	startTime := time.Now()
+	auditLogger.LogRestoreStart(user, targetDB, uri)
+	
+	if err := engine.RestoreSingleFromCloud(ctx, backend, cloudURI.Path, targetDB, restoreClean, restoreCreate); err != nil {
+		auditLogger.LogRestoreFailed(user, targetDB, err)
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	
+	// Audit log: restore success
+	auditLogger.LogRestoreComplete(user, targetDB, time.Since(startTime))
+	
+	log.Info("✅ Restore completed successfully", "database", targetDB)
+	return nil
+}
+
 // runRestoreCluster restores a full cluster
 func runRestoreCluster(cmd *cobra.Command, args []string) error {
+	if restoreResume != "" {
+		return runRestoreClusterResume(cmd, restoreResume)
+	}
 	archivePath := args[0]
 
+	selection, err := restoreSelection()
+	if err != nil {
+		return err
+	}
+	if restoreCleanCluster && selection.FiltersObjects() {
+		return fmt.Errorf("--clean-cluster cannot be combined with --table, --schema or --exclude-table")
+	}
+	if restoreCleanCluster && len(selection.Map) > 0 {
+		return fmt.Errorf("--clean-cluster cannot be combined with --map: renamed databases are restored next to the originals")
+	}
+
+	// Cloud archives are downloaded first, rehydrating them if they are in an archive tier
+	if cloud.IsCloudURI(archivePath) {
+		log.Info("Detected cloud URI, downloading backup...", "uri", archivePath)
+		result, err := restore.DownloadFromCloudURI(cmd.Context(), archivePath, restore.DownloadOptions{
+			VerifyChecksum: true,
+			KeepLocal:      false, // Delete after restore
+			Rehydrate:      rehydrateOptions(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to download from cloud: %w", rehydrateHint(err))
+		}
+		defer func() {
+			if err := result.Cleanup(); err != nil {
+				log.Warn("Failed to cleanup temp files", "error", err)
+			}
+		}()
+		archivePath = result.LocalPath
+		log.Info("Download completed", "local_path", archivePath)
+	}
+
 	// Convert to absolute path
 	if !filepath.IsAbs(archivePath) {
 		absPath, err := filepath.Abs(archivePath)
@@ -454,10 +789,37 @@ func runRestoreCluster(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("archive not found: %s", archivePath)
 	}
 
-	// Check if backup is encrypted and decrypt if necessary
-	if backup.IsBackupEncrypted(archivePath) {
+	// Check the selection against the archive's metadata before extracting anything
+	// Mapped databases must not be restored over existing ones
+	var clusterTargets []restore.ClusterTarget
+	if selection.SelectsDatabases() {
+		var existing []string
+		if len(selection.Map) > 0 {
+			if existing, err = serverDatabases(cmd.Context()); err != nil {
+				return err
+			}
+		}
+		clusterTargets, err = restore.PlanClusterRestore(archivePath, selection, existing)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Warn("No cluster metadata found - the selection is checked while extracting", "archive", archivePath)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	// Cluster members encrypted while they were written are decrypted on the fly
+	// during the restore; older archives encrypted after the fact are decrypted in place
+	var encOpts *encryption.EncryptionOptions
+	if backup.IsStreamEncrypted(archivePath) {
+		log.Info("Encrypted cluster backup detected, decrypting during restore")
+		opts, err := restoreEncryptionOptions(cmd.Context(), archivePath)
+		if err != nil {
+			return fmt.Errorf("encrypted backup requires encryption key: %w", err)
+		}
+		encOpts = opts
+	} else if backup.IsBackupEncrypted(archivePath) {
 		log.Info("Encrypted cluster backup detected, decrypting...")
-		key, err := loadEncryptionKey(restoreEncryptionKeyFile, restoreEncryptionKeyEnv)
+		key, err := restoreEncryptionKey(cmd.Context(), archivePath)
 		if err != nil {
 			return fmt.Errorf("encrypted backup requires encryption key: %w", err)
 		}
@@ -511,8 +873,12 @@ func runRestoreCluster(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("disk space check failed: %w", err)
 		}
 
-		// Verify tools (assume PostgreSQL for cluster backups)
-		if err := safety.VerifyTools("postgres"); err != nil {
+		// Verify tools for the configured engine
+		toolsFor := "postgres"
+		if cfg.IsMySQL() {
+			toolsFor = "mysql"
+		}
+		if err := safety.VerifyTools(toolsFor); err != nil {
 			return fmt.Errorf("tool verification failed: %w", err)
 		}
 	}	// Create database instance for pre-checks
@@ -542,8 +908,9 @@ func runRestoreCluster(cmd *cobra.Command, args []string) error {
 			"template1": true,
 		}
 
+		// With --database only the selected databases are dropped
 		for _, dbName := range allDBs {
-			if !systemDBs[dbName] {
+			if !systemDBs[dbName] && selection.IncludesDatabase(dbName) {
 				existingDBs = append(existingDBs, dbName)
 			}
 		}
@@ -560,6 +927,22 @@ func runRestoreCluster(cmd *cobra.Command, args []string) error {
 		if restoreWorkdir != "" {
 			fmt.Printf("  Working Directory: %s (alternative extraction location)\n", restoreWorkdir)
 		}
+		if selection.SelectsDatabases() || selection.FiltersObjects() {
+			fmt.Printf("  Selection: %s\n", selection)
+		}
+		if len(clusterTargets) > 0 {
+			fmt.Printf("  Databases (%d selected):\n", len(clusterTargets))
+			for _, t := range clusterTargets {
+				if t.Target != t.Source {
+					fmt.Printf("    - %s → %s (original untouched)\n", t.Source, t.Target)
+				} else {
+					fmt.Printf("    - %s\n", t.Source)
+				}
+			}
+		}
+		if selection.SkipGlobals {
+			fmt.Printf("  Global Objects: skipped (add --globals to restore them)\n")
+		}
 		if restoreCleanCluster {
 			fmt.Printf("  Clean Cluster: true (will drop %d existing database(s))\n", len(existingDBs))
 			if len(existingDBs) > 0 {
@@ -584,6 +967,8 @@ func runRestoreCluster(cmd *cobra.Command, args []string) error {
 
 	// Create restore engine
 	engine := restore.New(cfg, log, db)
+	engine.SetEncryption(encOpts)
+	engine.SetSelection(selection)
 
 	// Setup signal handling
 	ctx, cancel := context.WithCancel(context.Background())
@@ -604,6 +989,12 @@ func runRestoreCluster(cmd *cobra.Command, args []string) error {
 		log.Info("Dropping existing databases before restore...")
 		for _, dbName := range existingDBs {
 			log.Info("Dropping database", "name", dbName)
+			if cfg.IsMySQL() {
+				if err := db.DropDatabase(ctx, dbName); err != nil {
+					log.Warn("Failed to drop database", "name", dbName, "error", err)
+				}
+				continue
+			}
 			// Use CLI-based drop to avoid connection issues
 			dropCmd := exec.CommandContext(ctx, "psql",
 				"-h", cfg.Host,
@@ -640,6 +1031,98 @@ func runRestoreCluster(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runRestoreClusterResume continues a cluster restore from its journal
+func runRestoreClusterResume(cmd *cobra.Command, journalPath string) error {
+	// The journal holds the selection of the restore it continues
+	for _, name := range []string{"only", "database", "map", "map-existing", "table", "schema", "exclude-table", "globals", "clean-cluster"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s cannot be combined with --resume: the original restore's selection applies", name)
+		}
+	}
+
+	journal, err := restore.LoadJournal(journalPath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(journal.ExtractDir); err != nil {
+		return fmt.Errorf("extraction %s is gone: start a new restore from %s", journal.ExtractDir, journal.Archive)
+	}
+	remaining := journal.Remaining()
+
+	// Members encrypted while the archive was written are still encrypted
+	var encOpts *encryption.EncryptionOptions
+	if backup.IsStreamEncrypted(journal.Archive) {
+		opts, err := restoreEncryptionOptions(cmd.Context(), journal.Archive)
+		if err != nil {
+			return fmt.Errorf("encrypted backup requires encryption key: %w", err)
+		}
+		encOpts = opts
+	}
+
+	if restoreDryRun || !restoreConfirm {
+		fmt.Println("\n🔍 DRY-RUN MODE - No changes will be made")
+		fmt.Printf("\nWould resume cluster restore:\n")
+		fmt.Printf("  Archive: %s\n", journal.Archive)
+		fmt.Printf("  Extraction: %s\n", journal.ExtractDir)
+		fmt.Printf("  Started: %s\n", journal.StartedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("  Global Objects: %s\n", journal.Globals)
+		fmt.Printf("  Databases: %d restored, %d to restore\n", len(journal.Databases)-len(remaining), len(remaining))
+		for _, db := range remaining {
+			line := fmt.Sprintf("    - %s (%s", db.Name, db.State)
+			if db.Target != db.Name {
+				line = fmt.Sprintf("    - %s → %s (%s", db.Name, db.Target, db.State)
+			}
+			if db.Error != "" {
+				line += ": " + db.Error
+			}
+			fmt.Println(line + ")")
+		}
+		fmt.Println("\nTo execute this restore, add --confirm flag")
+		return nil
+	}
+
+	db, err := database.New(cfg, log)
+	if err != nil {
+		return fmt.Errorf("failed to create database instance: %w", err)
+	}
+	defer db.Close()
+
+	engine := restore.New(cfg, log, db)
+	engine.SetEncryption(encOpts)
+
+	// Setup signal handling
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan) // Ensure signal cleanup on exit
+
+	go func() {
+		<-sigChan
+		log.Warn("Restore interrupted by user")
+		cancel()
+	}()
+
+	log.Info("Resuming cluster restore...", "journal", journalPath, "remaining", len(remaining))
+
+	// Audit log: restore start
+	user := security.GetCurrentUser()
+	startTime := time.Now()
+	auditLogger.LogRestoreStart(user, "all_databases", journal.Archive)
+
+	if err := engine.ResumeCluster(ctx, journalPath); err != nil {
+		auditLogger.LogRestoreFailed(user, "all_databases", err)
+		return fmt.Errorf("cluster restore failed: %w", err)
+	}
+
+	// Audit log: restore success
+	auditLogger.LogRestoreComplete(user, "all_databases", time.Since(startTime))
+
+	log.Info("✅ Cluster restore completed successfully")
+	return nil
+}
+
 // runRestoreList lists available backup archives
 func runRestoreList(cmd *cobra.Command, args []string) error {
 	backupDir := cfg.BackupDir
@@ -721,9 +1204,8 @@ func stripFileExtensions(name string) string {
 	// Remove extensions (handle double extensions like .sql.gz.sql.gz)
 	for {
 		oldName := name
-		name = strings.TrimSuffix(name, ".tar.gz")
-		name = strings.TrimSuffix(name, ".dump.gz")
-		name = strings.TrimSuffix(name, ".sql.gz")
+		name = compression.TrimExtension(name)
+		name = strings.TrimSuffix(name, ".tar")
 		name = strings.TrimSuffix(name, ".dump")
 		name = strings.TrimSuffix(name, ".sql")
 		// If no change, we're done
@@ -819,10 +1301,20 @@ func runRestorePITR(cmd *cobra.Command, args []string) error {
 		Target:          target,
 		TargetDataDir:   pitrTargetDir,
 		SkipExtraction:  pitrSkipExtract,
+		SkipWALCheck:    pitrSkipWALChk,
 		AutoStart:       pitrAutoStart,
 		MonitorProgress: pitrMonitor,
 	}
 
+	// Encrypted base backups are decrypted while they are extracted
+	if encryption.IsEncryptedFile(pitrBaseBackup) {
+		encOpts, err := restoreEncryptionOptions(ctx, pitrBaseBackup)
+		if err != nil {
+			return fmt.Errorf("encrypted base backup requires encryption key: %w", err)
+		}
+		opts.Encryption = encOpts
+	}
+
 	// Perform PITR restore
 	if err := orchestrator.RestorePointInTime(ctx, opts); err != nil {
 		return fmt.Errorf("PITR restore failed: %w", err)
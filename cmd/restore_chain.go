@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"dbbackup/internal/backup"
+	"dbbackup/internal/cloud"
+	"dbbackup/internal/metadata"
+	"dbbackup/internal/security"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreChainTargetDir string
+
+// restoreChainCmd replays a full backup plus every incremental leading to a target
+var restoreChainCmd = &cobra.Command{
+	Use:   "chain [target-backup]",
+	Short: "Restore an incremental backup chain (base + all incrementals)",
+	Long: `Restore an incremental backup by replaying its whole chain automatically.
+
+The chain is resolved by following each backup's .meta.json back to its base
+(matched by SHA-256) until a full backup is reached. Broken chains (missing or
+mismatched bases) and forked chains (metadata that disagrees with the chain the
+backup was created on) are rejected before anything is extracted.
+
+The target can be a local archive path, a backup file name or SHA-256 found in
+--backup-dir, or a cloud URI.
+
+Examples:
+  # Preview the chain
+  dbbackup restore chain /backups/mydb_incr_20250126_150000.tar.gz --target-dir /restore/mydb
+
+  # Replay the chain into a data directory
+  dbbackup restore chain mydb_incr_20250126_150000.tar.gz --target-dir /restore/mydb --confirm
+
+  # Replay a chain stored in S3
+  dbbackup restore chain s3://backups/prod/mydb_incr_20250126_150000.tar.gz \\
+    --target-dir /restore/mydb --workdir /mnt/scratch --confirm
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestoreChain,
+}
+
+func init() {
+	restoreCmd.AddCommand(restoreChainCmd)
+
+	restoreChainCmd.Flags().StringVar(&restoreChainTargetDir, "target-dir", "", "Directory to restore the data files into (required)")
+	restoreChainCmd.Flags().BoolVar(&restoreConfirm, "confirm", false, "Confirm and execute restore (required)")
+	restoreChainCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "Show what would be done without executing")
+	restoreChainCmd.Flags().StringVar(&restoreWorkdir, "workdir", "", "Working directory for downloaded/decrypted chain links (default: backup dir)")
+	restoreChainCmd.Flags().StringVar(&restoreEncryptionKeyFile, "encryption-key-file", "", "Path to encryption key file (required for encrypted backups)")
+	restoreChainCmd.Flags().StringVar(&restoreEncryptionKeyEnv, "encryption-key-env", "DBBACKUP_ENCRYPTION_KEY", "Environment variable containing encryption key")
+	restoreChainCmd.Flags().StringVar(&restoreKeyEndpoint, "key-endpoint", "", "Vault address (default: $VAULT_ADDR) or KMS-compatible endpoint for wrapped data keys")
+	restoreChainCmd.Flags().StringVar(&restoreIdentityFile, "identity-file", "", "age identity file (AGE-SECRET-KEY-1...) for backups encrypted to X25519 recipients")
+	restoreChainCmd.MarkFlagRequired("target-dir")
+}
+
+// runRestoreChain resolves, validates and replays an incremental backup chain
+func runRestoreChain(cmd *cobra.Command, args []string) error {
+	target := args[0]
+
+	// Setup signal handling
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	go func() {
+		<-sigChan
+		log.Warn("Chain restore interrupted by user")
+		cancel()
+	}()
+
+	var resolver backup.BackupChainResolver
+	var cloudResolver *backup.CloudChainResolver
+	targetID := target
+
+	if cloud.IsCloudURI(target) {
+		cloudURI, err := cloud.ParseCloudURI(target)
+		if err != nil {
+			return fmt.Errorf("invalid cloud URI: %w", err)
+		}
+		backend, err := cloud.NewBackend(cloudURI.ToConfig())
+		if err != nil {
+			return fmt.Errorf("failed to create cloud backend: %w", err)
+		}
+		defer cloud.CloseBackend(backend)
+		cloudResolver = backup.NewCloudChainResolver(backend, "", log)
+		cloudResolver.SetRehydrateOptions(rehydrateOptions())
+		resolver = cloudResolver
+		targetID = cloudURI.BaseName()
+	} else {
+		dir := cfg.BackupDir
+		if _, err := os.Stat(target); err == nil {
+			absPath, err := filepath.Abs(target)
+			if err != nil {
+				return fmt.Errorf("invalid archive path: %w", err)
+			}
+			dir = filepath.Dir(absPath)
+			targetID = filepath.Base(absPath)
+		}
+		resolver = backup.NewFileChainResolver(dir, log)
+	}
+
+	// Resolve and validate before touching the target directory
+	chain, err := resolver.ResolveChain(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve backup chain: %w", err)
+	}
+	if err := resolver.ValidateChain(ctx, chain); err != nil {
+		return fmt.Errorf("backup chain validation failed: %w", err)
+	}
+	if len(chain) < 2 {
+		return fmt.Errorf("%s is a full backup, use 'dbbackup restore single' instead", targetID)
+	}
+
+	fmt.Printf("\n🔗 Backup chain for %s (%d links):\n", chain[0].Database, len(chain))
+	for i, link := range chain {
+		fmt.Printf("  %d. %-11s %-50s %10s  %s\n",
+			i+1,
+			link.BackupType,
+			filepath.Base(link.Path),
+			metadata.FormatSize(link.Size),
+			link.Timestamp.Format("2006-01-02 15:04:05"))
+	}
+
+	if restoreDryRun || !restoreConfirm {
+		fmt.Println("\n🔍 DRY-RUN MODE - No changes will be made")
+		fmt.Printf("  Target Directory: %s\n", restoreChainTargetDir)
+		fmt.Println("\nTo execute this restore, add --confirm flag")
+		return nil
+	}
+
+	// Scratch space for downloaded or decrypted links (removed when done)
+	workDir := restoreWorkdir
+	if workDir == "" {
+		workDir = cfg.BackupDir
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create working directory: %w", err)
+	}
+	scratchDir, err := os.MkdirTemp(workDir, ".chain_")
+	if err != nil {
+		return fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(scratchDir)
+
+	if cloudResolver != nil {
+		chain, err = cloudResolver.Fetch(ctx, chain, scratchDir)
+		if err != nil {
+			return rehydrateHint(err)
+		}
+	}
+
+	chain, err = decryptChainLinks(ctx, chain, scratchDir)
+	if err != nil {
+		return err
+	}
+
+	var engine backup.IncrementalBackupEngine
+	if dbType := strings.ToLower(chainDatabaseType(chain[0].Path)); strings.Contains(dbType, "mysql") || strings.Contains(dbType, "mariadb") {
+		engine = backup.NewMySQLIncrementalEngine(log)
+	} else {
+		engine = backup.NewPostgresIncrementalEngine(log)
+	}
+
+	user := security.GetCurrentUser()
+	startTime := time.Now()
+	auditLogger.LogRestoreStart(user, chain[0].Database, target)
+
+	if err := backup.RestoreChain(ctx, engine, chain, restoreChainTargetDir, log); err != nil {
+		auditLogger.LogRestoreFailed(user, chain[0].Database, err)
+		return fmt.Errorf("chain restore failed: %w", err)
+	}
+
+	auditLogger.LogRestoreComplete(user, chain[0].Database, time.Since(startTime))
+	log.Info("✅ Chain restore completed",
+		"links", len(chain),
+		"target_dir", restoreChainTargetDir,
+		"duration", time.Since(startTime).Round(time.Second))
+
+	return nil
+}
+
+// chainDatabaseType reads the database type recorded for a chain link
+func chainDatabaseType(path string) string {
+	meta, err := metadata.Load(path)
+	if err != nil {
+		return ""
+	}
+	return meta.DatabaseType
+}
+
+// decryptChainLinks decrypts encrypted links into scratchDir (with their metadata)
+// so the originals stay untouched. Each link may carry its own wrapped data key.
+func decryptChainLinks(ctx context.Context, chain []*backup.BackupInfo, scratchDir string) ([]*backup.BackupInfo, error) {
+	result := make([]*backup.BackupInfo, 0, len(chain))
+
+	for _, link := range chain {
+		meta, err := metadata.Load(link.Path)
+		if err != nil || !meta.Encrypted {
+			result = append(result, link)
+			continue
+		}
+
+		// Keep the original file name: the chain is validated by name against recorded metadata
+		decryptedDir := filepath.Join(scratchDir, "decrypted")
+		if err := os.MkdirAll(decryptedDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create decryption directory: %w", err)
+		}
+		decryptedPath := filepath.Join(decryptedDir, filepath.Base(link.Path))
+
+		if backup.IsStreamEncrypted(link.Path) {
+			opts, err := restoreEncryptionOptions(ctx, link.Path)
+			if err != nil {
+				return nil, fmt.Errorf("encrypted backup requires encryption key: %w", err)
+			}
+			err = backup.DecryptStreamFile(link.Path, decryptedPath, *opts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt %s: %w", filepath.Base(link.Path), err)
+			}
+		} else {
+			key, err := restoreEncryptionKey(ctx, link.Path)
+			if err != nil {
+				return nil, fmt.Errorf("encrypted backup requires encryption key: %w", err)
+			}
+			if err := backup.DecryptBackupFile(link.Path, decryptedPath, key, log); err != nil {
+				return nil, fmt.Errorf("failed to decrypt %s: %w", filepath.Base(link.Path), err)
+			}
+		}
+		if err := metadata.Save(decryptedPath+".meta.json", meta); err != nil {
+			return nil, err
+		}
+
+		decrypted := *link
+		decrypted.Path = decryptedPath
+		result = append(result, &decrypted)
+	}
+
+	return result, nil
+}
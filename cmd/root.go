@@ -61,6 +61,7 @@ For help with specific commands, use: dbbackup [command] --help`,
 				savedUser := cfg.User
 				savedDatabase := cfg.Database
 				savedCompression := cfg.CompressionLevel
+				savedCompressionAlgorithm := cfg.CompressionAlgorithm
 				savedJobs := cfg.Jobs
 				savedDumpJobs := cfg.DumpJobs
 				savedRetentionDays := cfg.RetentionDays
@@ -89,6 +90,9 @@ For help with specific commands, use: dbbackup [command] --help`,
 				if flagsSet["compression"] {
 					cfg.CompressionLevel = savedCompression
 				}
+				if flagsSet["compression-algorithm"] {
+					cfg.CompressionAlgorithm = savedCompressionAlgorithm
+				}
 				if flagsSet["jobs"] {
 					cfg.Jobs = savedJobs
 				}
@@ -140,7 +144,9 @@ func Execute(ctx context.Context, config *config.Config, logger logger.Logger) e
 	rootCmd.PersistentFlags().StringVar(&cfg.CPUWorkloadType, "cpu-workload", cfg.CPUWorkloadType, "CPU workload type (cpu-intensive|io-intensive|balanced)")
 	rootCmd.PersistentFlags().StringVar(&cfg.SSLMode, "ssl-mode", cfg.SSLMode, "SSL mode for connections")
 	rootCmd.PersistentFlags().BoolVar(&cfg.Insecure, "insecure", cfg.Insecure, "Disable SSL (shortcut for --ssl-mode=disable)")
-	rootCmd.PersistentFlags().IntVar(&cfg.CompressionLevel, "compression", cfg.CompressionLevel, "Compression level (0-9)")
+	rootCmd.PersistentFlags().IntVar(&cfg.CompressionLevel, "compression", cfg.CompressionLevel, "Compression level (0-9 for gzip, 0-19 for zstd, 0-12 for lz4)")
+	rootCmd.PersistentFlags().StringVar(&cfg.CompressionAlgorithm, "compression-algorithm", cfg.CompressionAlgorithm, "Compression algorithm for dumps, archives and WAL (gzip|zstd|lz4|none)")
+	rootCmd.PersistentFlags().BoolVar(&cfg.ZstdLong, "zstd-long", cfg.ZstdLong, "Use zstd long-distance matching (better ratio on large dumps, more memory)")
 	rootCmd.PersistentFlags().BoolVar(&cfg.NoSaveConfig, "no-save-config", false, "Don't save configuration after successful operations")
 	rootCmd.PersistentFlags().BoolVar(&cfg.NoLoadConfig, "no-config", false, "Don't load configuration from .dbbackup.conf")
 	
@@ -8,7 +8,10 @@ import (
 	"strings"
 	"time"
 
+	"dbbackup/internal/backup"
 	"dbbackup/internal/cloud"
+	"dbbackup/internal/crypto"
+	"dbbackup/internal/encryption"
 	"dbbackup/internal/metadata"
 	"dbbackup/internal/restore"
 	"dbbackup/internal/verification"
@@ -32,20 +35,25 @@ Examples:
   dbbackup verify-backup /backups/mydb.dump --quick
 
   # Verify and show detailed information
-  dbbackup verify-backup /backups/mydb.dump --verbose`,
+  dbbackup verify-backup /backups/mydb.dump --verbose
+
+  # Also check that a backup encrypted to X25519 recipients decrypts with a private key
+  dbbackup verify-backup /backups/mydb.dump --identity-file ops.agekey`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runVerifyBackup,
 }
 
 var (
-	quickVerify   bool
-	verboseVerify bool
+	quickVerify        bool
+	verboseVerify      bool
+	verifyIdentityFile string
 )
 
 func init() {
 	rootCmd.AddCommand(verifyBackupCmd)
 	verifyBackupCmd.Flags().BoolVar(&quickVerify, "quick", false, "Quick verification (size check only)")
 	verifyBackupCmd.Flags().BoolVarP(&verboseVerify, "verbose", "v", false, "Show detailed information")
+	verifyBackupCmd.Flags().StringVar(&verifyIdentityFile, "identity-file", "", "age identity file; also decrypt backups encrypted to X25519 recipients")
 }
 
 func runVerifyBackup(cmd *cobra.Command, args []string) error {
@@ -114,6 +122,15 @@ func runVerifyBackup(cmd *cobra.Command, args []string) error {
 				return fmt.Errorf("verification error: %w", err)
 			}
 
+			if result.Valid && verifyIdentityFile != "" {
+				if err := verifyDecryption(cmd.Context(), backupFile); err != nil {
+					fmt.Printf("   ❌ FAILED: %v\n\n", err)
+					failureCount++
+					continue
+				}
+				fmt.Printf("   🔓 Decrypts with identity file\n")
+			}
+
 			if result.Valid {
 				fmt.Printf("   ✅ VALID\n")
 				if verboseVerify {
@@ -157,6 +174,32 @@ func runVerifyBackup(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// verifyDecryption unwraps a backup's data key with --identity-file and, for
+// stream-encrypted backups, decrypts the whole file to authenticate every chunk
+func verifyDecryption(ctx context.Context, backupFile string) error {
+	envelope := backup.LoadEncryptionEnvelope(backupFile)
+	if envelope == nil {
+		return fmt.Errorf("backup has no wrapped data key")
+	}
+	if envelope.KeyProvider != crypto.ProviderX25519 {
+		return fmt.Errorf("backup data key is wrapped by the %s key provider, not to X25519 recipients", envelope.KeyProvider)
+	}
+
+	provider, err := envelopeKeyProvider(ctx, envelope, "", "", "", verifyIdentityFile)
+	if err != nil {
+		return err
+	}
+	dataKey, err := crypto.UnwrapDataKey(ctx, provider, envelope)
+	if err != nil {
+		return err
+	}
+
+	if encryption.IsEncryptedFile(backupFile) {
+		return backup.VerifyStreamFile(backupFile, encryption.EncryptionOptions{Key: dataKey})
+	}
+	return nil
+}
+
 // isCloudURI checks if a string is a cloud URI
 func isCloudURI(s string) bool {
 	return cloud.IsCloudURI(s)
@@ -0,0 +1,430 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbbackup/internal/compression"
+	"dbbackup/internal/metadata"
+	"dbbackup/internal/security"
+	"dbbackup/internal/wal"
+)
+
+var (
+	walStartPointRe = regexp.MustCompile(`write-ahead log start point: ([0-9A-Fa-f]+/[0-9A-Fa-f]+) on timeline (\d+)`)
+	walEndPointRe   = regexp.MustCompile(`write-ahead log end point: ([0-9A-Fa-f]+/[0-9A-Fa-f]+)`)
+	labelStartRe    = regexp.MustCompile(`START WAL LOCATION: ([0-9A-Fa-f]+/[0-9A-Fa-f]+) \(file ([0-9A-Fa-f]{24})\)`)
+	labelTimelineRe = regexp.MustCompile(`START TIMELINE: (\d+)`)
+	walSegmentRe    = regexp.MustCompile(`^[0-9A-Fa-f]{24}$`)
+)
+
+// basebackupPosition collects the WAL position reported by pg_basebackup
+type basebackupPosition struct {
+	startLSN     string
+	stopLSN      string
+	timeline     uint32
+	startWALFile string
+	segmentSize  int64
+	tablespaces  int
+}
+
+// BackupBase takes a physical base backup of the whole PostgreSQL cluster with
+// pg_basebackup (tar format, WAL streamed alongside the data). The server tars
+// are merged into a single base_<timestamp>.tar.<ext> laid out like a data
+// directory, with the required WAL under pg_wal/ and tablespaces folded in
+// place under pg_tblspc/<oid>/. Returns the path of the archive.
+func (e *Engine) BackupBase(ctx context.Context) (string, error) {
+	if !e.cfg.IsPostgreSQL() {
+		return "", fmt.Errorf("base backups require PostgreSQL (detected: %s)", e.cfg.DisplayDatabaseType())
+	}
+
+	operationID := generateOperationID()
+	tracker := e.detailedReporter.StartOperation(operationID, "cluster", "backup")
+	tracker.SetDetails("type", "base")
+	tracker.SetDetails("format", "tar")
+	tracker.SetDetails("wal_method", "stream")
+
+	prepStep := tracker.AddStep("prepare", "Preparing backup directory")
+	validBackupDir, err := security.ValidateBackupPath(e.cfg.BackupDir)
+	if err != nil {
+		err = fmt.Errorf("invalid backup directory path: %w", err)
+		prepStep.Fail(err)
+		tracker.Fail(err)
+		return "", err
+	}
+	e.cfg.BackupDir = validBackupDir
+
+	timestamp := time.Now().Format("20060102_150405")
+	outputFile := filepath.Join(e.cfg.BackupDir, fmt.Sprintf("base_%s.tar%s", timestamp, e.cfg.CompressionOptions().Algorithm.Extension()))
+
+	// pg_basebackup can't stream WAL when writing the tar to stdout, so the
+	// server tars are spooled next to the final archive and merged afterwards
+	spoolDir := filepath.Join(e.cfg.BackupDir, ".basebackup_"+timestamp)
+	if err := os.MkdirAll(spoolDir, 0700); err != nil {
+		err = fmt.Errorf("failed to create spool directory %s: %w", spoolDir, err)
+		prepStep.Fail(err)
+		tracker.Fail(err)
+		return "", err
+	}
+	defer os.RemoveAll(spoolDir)
+	prepStep.Complete("Backup directory prepared")
+	tracker.SetDetails("output_file", outputFile)
+	tracker.UpdateProgress(10, "Backup directory prepared")
+
+	startTime := time.Now()
+	execStep := tracker.AddStep("execute", "Running pg_basebackup")
+	tracker.UpdateProgress(20, "Waiting for checkpoint...")
+	pos, err := e.runBasebackup(ctx, spoolDir, "dbbackup_"+timestamp)
+	if err != nil {
+		err = fmt.Errorf("pg_basebackup failed: %w. Check that the user has the REPLICATION attribute and pg_hba.conf allows replication connections", err)
+		execStep.Fail(err)
+		tracker.Fail(err)
+		return "", err
+	}
+	execStep.Complete(fmt.Sprintf("WAL %s - %s on timeline %d", pos.startLSN, pos.stopLSN, pos.timeline))
+	tracker.UpdateProgress(60, "Base backup received")
+
+	archiveStep := tracker.AddStep("archive", "Compressing base backup")
+	checksum, err := e.mergeBasebackup(ctx, spoolDir, outputFile, pos)
+	if err != nil {
+		os.Remove(outputFile)
+		err = fmt.Errorf("failed to create base backup archive: %w", err)
+		archiveStep.Fail(err)
+		tracker.Fail(err)
+		return "", err
+	}
+	info, err := os.Stat(outputFile)
+	if err != nil {
+		err = fmt.Errorf("base backup archive not created at %s: %w", outputFile, err)
+		archiveStep.Fail(err)
+		tracker.Fail(err)
+		return "", err
+	}
+	size := formatBytes(info.Size())
+	tracker.SetDetails("file_size", size)
+	tracker.SetByteProgress(info.Size(), info.Size())
+	archiveStep.Complete(fmt.Sprintf("Archive created: %s", size))
+	tracker.UpdateProgress(85, "Archive created")
+
+	if err := security.SaveChecksum(outputFile, checksum); err != nil {
+		e.log.Warn("Failed to save checksum", "error", err)
+	} else {
+		e.log.Info("Backup checksum", "sha256", checksum)
+	}
+
+	metaStep := tracker.AddStep("metadata", "Creating metadata file")
+	if err := e.createBaseMetadata(ctx, outputFile, checksum, info.Size(), startTime, pos); err != nil {
+		e.log.Warn("Failed to create metadata file", "error", err)
+		metaStep.Fail(fmt.Errorf("metadata creation failed: %w", err))
+	} else {
+		metaStep.Complete("Metadata file created")
+	}
+
+	if e.cfg.CloudUploadEnabled() {
+		if err := e.uploadToCloud(ctx, outputFile, tracker); err != nil {
+			e.log.Warn("Cloud upload failed", "error", err)
+		}
+	}
+
+	tracker.UpdateProgress(100, "Base backup completed successfully")
+	tracker.Complete(fmt.Sprintf("Base backup completed: %s", filepath.Base(outputFile)))
+	return outputFile, nil
+}
+
+// runBasebackup runs pg_basebackup into spoolDir and parses the WAL range from its output
+func (e *Engine) runBasebackup(ctx context.Context, spoolDir, label string) (*basebackupPosition, error) {
+	args := []string{
+		"-D", spoolDir,
+		"-F", "tar",
+		"-X", "stream",
+		"--checkpoint=fast",
+		"-l", label,
+		"-v",
+		"-p", strconv.Itoa(e.cfg.Port),
+		"-U", e.cfg.User,
+		"-w",
+	}
+
+	// Only add -h flag if host is not localhost (to use Unix socket)
+	if e.cfg.Host != "localhost" && e.cfg.Host != "127.0.0.1" && e.cfg.Host != "" {
+		args = append([]string{"-h", e.cfg.Host}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "pg_basebackup", args...)
+	cmd.Env = os.Environ()
+	if e.cfg.Password != "" {
+		cmd.Env = append(cmd.Env, "PGPASSWORD="+e.cfg.Password)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start pg_basebackup: %w", err)
+	}
+
+	pos := &basebackupPosition{}
+	var lastError string
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		e.log.Debug("pg_basebackup", "output", line)
+
+		if m := walStartPointRe.FindStringSubmatch(line); m != nil {
+			pos.startLSN = m[1]
+			if tli, err := strconv.ParseUint(m[2], 10, 32); err == nil {
+				pos.timeline = uint32(tli)
+			}
+		} else if m := walEndPointRe.FindStringSubmatch(line); m != nil {
+			pos.stopLSN = m[1]
+		} else if strings.Contains(line, "error:") || strings.Contains(line, "FATAL") {
+			lastError = line
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if lastError != "" {
+			return nil, fmt.Errorf("%s: %w", lastError, err)
+		}
+		return nil, err
+	}
+
+	if pos.startLSN == "" || pos.stopLSN == "" {
+		return nil, fmt.Errorf("could not determine WAL start/end point from pg_basebackup output")
+	}
+	return pos, nil
+}
+
+// mergeBasebackup combines the spooled pg_basebackup tars into outputFile and
+// returns its SHA-256. base.tar is copied as-is, pg_wal.tar is placed under
+// pg_wal/ and each tablespace tar under pg_tblspc/<oid>/.
+func (e *Engine) mergeBasebackup(ctx context.Context, spoolDir, outputFile string, pos *basebackupPosition) (string, error) {
+	entries, err := os.ReadDir(spoolDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	var tablespaces []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, ".tar") && name != "base.tar" && name != "pg_wal.tar" {
+			tablespaces = append(tablespaces, strings.TrimSuffix(name, ".tar"))
+		}
+	}
+	pos.tablespaces = len(tablespaces)
+
+	outFile, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer outFile.Close()
+
+	// The checksum covers the bytes on disk, i.e. after encryption
+	hasher := sha256.New()
+	encWriter, err := e.wrapOutput(io.MultiWriter(outFile, hasher))
+	if err != nil {
+		return "", err
+	}
+	compWriter, err := compression.NewWriter(ctx, encWriter, e.cfg.CompressionOptions())
+	if err != nil {
+		return "", err
+	}
+	tarWriter := tar.NewWriter(compWriter)
+
+	// Tablespaces are restored in place, so drop their symlinks and the map
+	// that would point recovery back at the original locations
+	skip := map[string]bool{"tablespace_map": len(tablespaces) > 0}
+	for _, oid := range tablespaces {
+		skip["pg_tblspc/"+oid] = true
+	}
+
+	if err := e.copyTarEntries(ctx, tarWriter, filepath.Join(spoolDir, "base.tar"), "", func(hdr *tar.Header, data []byte) bool {
+		if skip[strings.TrimSuffix(hdr.Name, "/")] {
+			return false
+		}
+		if hdr.Name == "backup_label" {
+			if m := labelStartRe.FindSubmatch(data); m != nil {
+				pos.startWALFile = string(m[2])
+			}
+			if m := labelTimelineRe.FindSubmatch(data); m != nil && pos.timeline == 0 {
+				if tli, err := strconv.ParseUint(string(m[1]), 10, 32); err == nil {
+					pos.timeline = uint32(tli)
+				}
+			}
+		}
+		return true
+	}); err != nil {
+		return "", err
+	}
+
+	if err := e.copyTarEntries(ctx, tarWriter, filepath.Join(spoolDir, "pg_wal.tar"), "pg_wal/", func(hdr *tar.Header, _ []byte) bool {
+		if pos.segmentSize == 0 && hdr.Typeflag == tar.TypeReg && walSegmentRe.MatchString(filepath.Base(hdr.Name)) {
+			pos.segmentSize = hdr.Size
+		}
+		return true
+	}); err != nil {
+		return "", err
+	}
+
+	for _, oid := range tablespaces {
+		if err := e.copyTarEntries(ctx, tarWriter, filepath.Join(spoolDir, oid+".tar"), "pg_tblspc/"+oid+"/", nil); err != nil {
+			return "", err
+		}
+	}
+
+	// The manifest lets pg_verifybackup check the extracted data directory
+	if err := addFileToArchive(tarWriter, filepath.Join(spoolDir, "backup_manifest"), "backup_manifest"); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize tar: %w", err)
+	}
+	if err := compWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize compression: %w", err)
+	}
+	if err := encWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize encryption: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// copyTarEntries copies every entry of the tar at path into tw, prefixing
+// names with prefix. filter may inspect small regular files (backup_label and
+// the like are passed in full) and return false to drop an entry.
+func (e *Engine) copyTarEntries(ctx context.Context, tw *tar.Writer, path, prefix string, filter func(*tar.Header, []byte) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(bufio.NewReaderSize(f, 1<<20))
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+
+		var body io.Reader = tr
+		var data []byte
+		if filter != nil {
+			if hdr.Typeflag == tar.TypeReg && hdr.Size <= 64*1024 {
+				if data, err = io.ReadAll(tr); err != nil {
+					return fmt.Errorf("failed to read %s from %s: %w", hdr.Name, filepath.Base(path), err)
+				}
+				body = bytes.NewReader(data)
+			}
+			if !filter(hdr, data) {
+				continue
+			}
+		}
+
+		hdr.Name = prefix + hdr.Name
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write header for %s: %w", hdr.Name, err)
+		}
+		if _, err := io.Copy(tw, body); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", hdr.Name, err)
+		}
+	}
+}
+
+// addFileToArchive writes a single file from disk into tw under name
+func addFileToArchive(tw *tar.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write header for %s: %w", name, err)
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// createBaseMetadata writes the .meta.json for a base backup including its WAL range
+func (e *Engine) createBaseMetadata(ctx context.Context, backupFile, checksum string, size int64, startTime time.Time, pos *basebackupPosition) error {
+	dbVersion, _ := e.db.GetVersion(ctx)
+	if dbVersion == "" {
+		dbVersion = "unknown"
+	}
+
+	segSize := pos.segmentSize
+	if segSize == 0 {
+		segSize = wal.DefaultWALSegmentSize
+	}
+
+	physical := &metadata.PhysicalMetadata{
+		StartLSN:       pos.startLSN,
+		StopLSN:        pos.stopLSN,
+		Timeline:       pos.timeline,
+		StartWALFile:   pos.startWALFile,
+		WALSegmentSize: segSize,
+		WALMethod:      "stream",
+		Tablespaces:    pos.tablespaces,
+	}
+	if start, err := wal.ParseLSN(pos.startLSN); err == nil && physical.StartWALFile == "" {
+		physical.StartWALFile = wal.WALFileName(pos.timeline, start, segSize)
+	}
+	if stop, err := wal.ParseLSN(pos.stopLSN); err == nil {
+		physical.StopWALFile = wal.WALFileName(pos.timeline, stop, segSize)
+	}
+
+	meta := &metadata.BackupMetadata{
+		Version:         "2.0",
+		Timestamp:       startTime,
+		Database:        "cluster",
+		DatabaseType:    e.cfg.DatabaseType,
+		DatabaseVersion: dbVersion,
+		Host:            e.cfg.Host,
+		Port:            e.cfg.Port,
+		User:            e.cfg.User,
+		BackupFile:      backupFile,
+		SizeBytes:       size,
+		SHA256:          checksum,
+		Compression:     e.cfg.CompressionOptions().String(),
+		BackupType:      "base",
+		Duration:        time.Since(startTime).Seconds(),
+		ExtraInfo:       make(map[string]string),
+		Physical:        physical,
+	}
+	e.setEncryptionMetadata(meta)
+
+	if err := meta.Save(); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,476 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dbbackup/internal/cloud"
+	"dbbackup/internal/logger"
+	"dbbackup/internal/metadata"
+)
+
+// chainIndex holds every backup found in one location, keyed for chain walking
+type chainIndex struct {
+	byID       map[string]*BackupInfo   // SHA-256 -> backup
+	byName     map[string]*BackupInfo   // archive file name -> backup
+	children   map[string][]*BackupInfo // base SHA-256 -> incrementals built on it
+	duplicates map[string][]string      // SHA-256 claimed by more than one archive
+}
+
+// newChainIndex builds an index from a list of backups
+func newChainIndex(backups []*BackupInfo) *chainIndex {
+	idx := &chainIndex{
+		byID:       make(map[string]*BackupInfo),
+		byName:     make(map[string]*BackupInfo),
+		children:   make(map[string][]*BackupInfo),
+		duplicates: make(map[string][]string),
+	}
+
+	for _, b := range backups {
+		name := filepath.Base(b.Path)
+		idx.byName[name] = b
+
+		if b.Checksum != "" {
+			if existing, ok := idx.byID[b.Checksum]; ok {
+				if len(idx.duplicates[b.Checksum]) == 0 {
+					idx.duplicates[b.Checksum] = []string{filepath.Base(existing.Path)}
+				}
+				idx.duplicates[b.Checksum] = append(idx.duplicates[b.Checksum], name)
+			} else {
+				idx.byID[b.Checksum] = b
+			}
+		}
+
+		if b.Incremental != nil && b.Incremental.BaseBackupID != "" {
+			idx.children[b.Incremental.BaseBackupID] = append(idx.children[b.Incremental.BaseBackupID], b)
+		}
+	}
+
+	return idx
+}
+
+// lookup finds a backup by SHA-256 or by archive file name
+func (idx *chainIndex) lookup(id string) (*BackupInfo, error) {
+	if names, ok := idx.duplicates[id]; ok {
+		return nil, fmt.Errorf("ambiguous backup ID %s: shared by %s", id, strings.Join(names, ", "))
+	}
+	if b, ok := idx.byID[id]; ok {
+		return b, nil
+	}
+	if b, ok := idx.byName[filepath.Base(id)]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("backup not found: %s", id)
+}
+
+// findBase returns the backup an incremental was taken against
+func (idx *chainIndex) findBase(incr *BackupInfo) (*BackupInfo, error) {
+	if incr.BackupType != BackupTypeIncremental || incr.Incremental == nil {
+		return nil, fmt.Errorf("%s is not an incremental backup", filepath.Base(incr.Path))
+	}
+
+	baseID := incr.Incremental.BaseBackupID
+	if baseID == "" {
+		return nil, fmt.Errorf("broken chain: %s does not record a base backup ID", filepath.Base(incr.Path))
+	}
+	if names, ok := idx.duplicates[baseID]; ok {
+		return nil, fmt.Errorf("broken chain: base of %s is ambiguous (%s share sha256 %s)",
+			filepath.Base(incr.Path), strings.Join(names, ", "), baseID)
+	}
+
+	base, ok := idx.byID[baseID]
+	if !ok {
+		return nil, fmt.Errorf("broken chain: base backup %s (sha256 %s) of %s not found",
+			incr.Incremental.BaseBackupPath, baseID, filepath.Base(incr.Path))
+	}
+
+	return base, nil
+}
+
+// resolve walks from the target back to its full backup and returns [base, ..., target]
+func (idx *chainIndex) resolve(targetID string, log logger.Logger) ([]*BackupInfo, error) {
+	target, err := idx.lookup(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []*BackupInfo{target}
+	visited := map[*BackupInfo]bool{target: true}
+	current := target
+
+	for current.BackupType == BackupTypeIncremental {
+		base, err := idx.findBase(current)
+		if err != nil {
+			return nil, err
+		}
+		if visited[base] {
+			return nil, fmt.Errorf("broken chain: cycle detected at %s", filepath.Base(base.Path))
+		}
+		visited[base] = true
+		chain = append(chain, base)
+		current = base
+	}
+
+	// Reverse into restore order (oldest first)
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+
+	// Forks are legal (walking backwards is unambiguous) but worth surfacing
+	for _, link := range chain[:len(chain)-1] {
+		if children := idx.children[link.Checksum]; len(children) > 1 {
+			names := make([]string, 0, len(children))
+			for _, c := range children {
+				names = append(names, filepath.Base(c.Path))
+			}
+			log.Warn("Backup chain fork detected",
+				"base", filepath.Base(link.Path),
+				"incrementals", strings.Join(names, ", "))
+		}
+	}
+
+	return chain, nil
+}
+
+// validateChainLinks checks chain ordering and checksum links without touching storage
+func validateChainLinks(chain []*BackupInfo) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("empty backup chain")
+	}
+
+	if chain[0].BackupType != BackupTypeFull {
+		return fmt.Errorf("broken chain: first backup %s is %s, expected full",
+			filepath.Base(chain[0].Path), chain[0].BackupType)
+	}
+
+	names := []string{filepath.Base(chain[0].Path)}
+	for i := 1; i < len(chain); i++ {
+		prev, curr := chain[i-1], chain[i]
+		name := filepath.Base(curr.Path)
+		names = append(names, name)
+
+		if curr.BackupType != BackupTypeIncremental || curr.Incremental == nil {
+			return fmt.Errorf("broken chain: %s is not an incremental backup", name)
+		}
+		if curr.Incremental.BaseBackupID != prev.Checksum {
+			return fmt.Errorf("broken chain: %s expects base sha256 %s, but previous link %s has %s",
+				name, curr.Incremental.BaseBackupID, filepath.Base(prev.Path), prev.Checksum)
+		}
+		if curr.Timestamp.Before(prev.Timestamp) {
+			return fmt.Errorf("broken chain: %s is older than its base %s", name, filepath.Base(prev.Path))
+		}
+		if curr.Database != prev.Database {
+			return fmt.Errorf("broken chain: %s belongs to database %s, base %s to %s",
+				name, curr.Database, filepath.Base(prev.Path), prev.Database)
+		}
+	}
+
+	// The target records the chain it was created on; a different path means a forked history
+	target := chain[len(chain)-1]
+	if target.Incremental != nil && len(target.Incremental.BackupChain) > 0 {
+		recorded := target.Incremental.BackupChain
+		if strings.Join(recorded, ",") != strings.Join(names, ",") {
+			return fmt.Errorf("forked chain: %s was created on [%s] but metadata resolves to [%s]",
+				filepath.Base(target.Path), strings.Join(recorded, " → "), strings.Join(names, " → "))
+		}
+	}
+
+	return nil
+}
+
+// backupInfoFromMetadata converts stored metadata into chain information
+func backupInfoFromMetadata(meta *metadata.BackupMetadata, path string) *BackupInfo {
+	info := &BackupInfo{
+		Database:   meta.Database,
+		Timestamp:  meta.Timestamp,
+		Size:       meta.SizeBytes,
+		Checksum:   meta.SHA256,
+		Path:       path,
+		BackupType: BackupType(meta.BackupType),
+	}
+	if info.BackupType == "" {
+		info.BackupType = BackupTypeFull
+	}
+
+	if meta.Incremental != nil {
+		info.Incremental = &IncrementalMetadata{
+			BaseBackupID:        meta.Incremental.BaseBackupID,
+			BaseBackupPath:      meta.Incremental.BaseBackupPath,
+			BaseBackupTimestamp: meta.Incremental.BaseBackupTimestamp,
+			IncrementalFiles:    meta.Incremental.IncrementalFiles,
+			TotalSize:           meta.Incremental.TotalSize,
+			BackupChain:         meta.Incremental.BackupChain,
+		}
+	}
+
+	return info
+}
+
+// FileChainResolver resolves backup chains from .meta.json files in a local directory
+type FileChainResolver struct {
+	dir string
+	log logger.Logger
+}
+
+// NewFileChainResolver creates a resolver for backups stored in dir
+func NewFileChainResolver(dir string, log logger.Logger) *FileChainResolver {
+	return &FileChainResolver{
+		dir: dir,
+		log: log,
+	}
+}
+
+// load indexes every backup in the directory
+func (r *FileChainResolver) load() (*chainIndex, error) {
+	metaFiles, err := filepath.Glob(filepath.Join(r.dir, "*.meta.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan backup directory: %w", err)
+	}
+
+	var backups []*BackupInfo
+	for _, metaFile := range metaFiles {
+		// Use the on-disk location; BackupFile may point to the host the backup was taken on
+		backupFile := strings.TrimSuffix(metaFile, ".meta.json")
+		meta, err := metadata.Load(backupFile)
+		if err != nil {
+			r.log.Debug("Skipping unreadable metadata", "file", metaFile, "error", err)
+			continue
+		}
+		backups = append(backups, backupInfoFromMetadata(meta, backupFile))
+	}
+
+	return newChainIndex(backups), nil
+}
+
+// FindBaseBackup locates the base backup for an incremental backup
+func (r *FileChainResolver) FindBaseBackup(ctx context.Context, incrementalBackupID string) (*BackupInfo, error) {
+	idx, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	incr, err := idx.lookup(incrementalBackupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return idx.findBase(incr)
+}
+
+// ResolveChain returns the complete chain of backups needed for restore
+func (r *FileChainResolver) ResolveChain(ctx context.Context, targetBackupID string) ([]*BackupInfo, error) {
+	idx, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return idx.resolve(targetBackupID, r.log)
+}
+
+// ValidateChain verifies all backups in the chain exist and are linked correctly
+func (r *FileChainResolver) ValidateChain(ctx context.Context, chain []*BackupInfo) error {
+	if err := validateChainLinks(chain); err != nil {
+		return err
+	}
+
+	for _, b := range chain {
+		stat, err := os.Stat(b.Path)
+		if err != nil {
+			return fmt.Errorf("broken chain: %s is missing: %w", filepath.Base(b.Path), err)
+		}
+		if b.Size > 0 && stat.Size() != b.Size {
+			return fmt.Errorf("broken chain: %s size mismatch: expected %d bytes, got %d bytes",
+				filepath.Base(b.Path), b.Size, stat.Size())
+		}
+	}
+
+	return nil
+}
+
+// CloudChainResolver resolves backup chains from .meta.json objects in cloud storage
+type CloudChainResolver struct {
+	backend cloud.Backend
+	prefix  string
+	log     logger.Logger
+	index   *chainIndex
+
+	rehydrate cloud.RehydrateOptions
+}
+
+// NewCloudChainResolver creates a resolver for backups stored under prefix in a cloud backend
+func NewCloudChainResolver(backend cloud.Backend, prefix string, log logger.Logger) *CloudChainResolver {
+	return &CloudChainResolver{
+		backend: backend,
+		prefix:  prefix,
+		log:     log,
+	}
+}
+
+// SetRehydrateOptions sets how Fetch makes links in an archive tier readable
+func (r *CloudChainResolver) SetRehydrateOptions(opts cloud.RehydrateOptions) {
+	r.rehydrate = opts
+}
+
+// load downloads and indexes every .meta.json under the prefix (cached after first call)
+func (r *CloudChainResolver) load(ctx context.Context) (*chainIndex, error) {
+	if r.index != nil {
+		return r.index, nil
+	}
+
+	objects, err := r.backend.List(ctx, r.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cloud backups: %w", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "dbbackup-chain-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var backups []*BackupInfo
+	for _, obj := range objects {
+		if !strings.HasSuffix(obj.Name, ".meta.json") {
+			continue
+		}
+
+		backupName := strings.TrimSuffix(obj.Name, ".meta.json")
+		localBackup := filepath.Join(tempDir, backupName)
+		if err := r.backend.Download(ctx, obj.Name, localBackup+".meta.json", nil); err != nil {
+			r.log.Warn("Failed to download metadata", "file", obj.Name, "error", err)
+			continue
+		}
+
+		meta, err := metadata.Load(localBackup)
+		if err != nil {
+			r.log.Debug("Skipping unreadable metadata", "file", obj.Name, "error", err)
+			continue
+		}
+		backups = append(backups, backupInfoFromMetadata(meta, backupName))
+	}
+
+	r.index = newChainIndex(backups)
+	return r.index, nil
+}
+
+// FindBaseBackup locates the base backup for an incremental backup
+func (r *CloudChainResolver) FindBaseBackup(ctx context.Context, incrementalBackupID string) (*BackupInfo, error) {
+	idx, err := r.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	incr, err := idx.lookup(incrementalBackupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return idx.findBase(incr)
+}
+
+// ResolveChain returns the complete chain of backups needed for restore
+func (r *CloudChainResolver) ResolveChain(ctx context.Context, targetBackupID string) ([]*BackupInfo, error) {
+	idx, err := r.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return idx.resolve(targetBackupID, r.log)
+}
+
+// ValidateChain verifies all backups in the chain exist remotely and are linked correctly
+func (r *CloudChainResolver) ValidateChain(ctx context.Context, chain []*BackupInfo) error {
+	if err := validateChainLinks(chain); err != nil {
+		return err
+	}
+
+	for _, b := range chain {
+		exists, err := r.backend.Exists(ctx, b.Path)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", b.Path, err)
+		}
+		if !exists {
+			return fmt.Errorf("broken chain: %s is missing from %s", b.Path, r.backend.Name())
+		}
+	}
+
+	return nil
+}
+
+// Fetch downloads every archive in the chain (with metadata) into destDir,
+// rehydrating archived links first, verifies checksums and returns the
+// chain with local paths
+func (r *CloudChainResolver) Fetch(ctx context.Context, chain []*BackupInfo, destDir string) ([]*BackupInfo, error) {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+
+	// Rehydrate all archived links at once rather than one after another
+	paths := make([]string, len(chain))
+	for i, b := range chain {
+		paths[i] = b.Path
+	}
+	opts := r.rehydrate
+	if opts.Log == nil {
+		opts.Log = r.log.Info
+	}
+	if err := cloud.Rehydrate(ctx, r.backend, paths, opts); err != nil {
+		return nil, err
+	}
+
+	local := make([]*BackupInfo, 0, len(chain))
+	for _, b := range chain {
+		localPath := filepath.Join(destDir, filepath.Base(b.Path))
+		r.log.Info("Downloading chain link", "backup", b.Path, "size", formatBytes(b.Size))
+
+		if err := r.backend.Download(ctx, b.Path, localPath, nil); err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", b.Path, err)
+		}
+		if err := r.backend.Download(ctx, b.Path+".meta.json", localPath+".meta.json", nil); err != nil {
+			return nil, fmt.Errorf("failed to download metadata for %s: %w", b.Path, err)
+		}
+
+		if b.Checksum != "" {
+			checksum, err := metadata.CalculateSHA256(localPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to checksum %s: %w", localPath, err)
+			}
+			if checksum != b.Checksum {
+				return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", b.Path, b.Checksum, checksum)
+			}
+		}
+
+		copied := *b
+		copied.Path = localPath
+		local = append(local, &copied)
+	}
+
+	return local, nil
+}
+
+// RestoreChain replays a resolved chain into targetDir. Each incremental is applied
+// through RestoreIncremental on top of its predecessor (re-extracting a link is idempotent).
+func RestoreChain(ctx context.Context, engine IncrementalBackupEngine, chain []*BackupInfo, targetDir string, log logger.Logger) error {
+	if err := validateChainLinks(chain); err != nil {
+		return err
+	}
+	if len(chain) < 2 {
+		return fmt.Errorf("%s is a full backup; nothing to replay", filepath.Base(chain[0].Path))
+	}
+
+	for i := 1; i < len(chain); i++ {
+		log.Info("Applying chain link",
+			"step", fmt.Sprintf("%d/%d", i, len(chain)-1),
+			"base", filepath.Base(chain[i-1].Path),
+			"incremental", filepath.Base(chain[i].Path))
+
+		if err := engine.RestoreIncremental(ctx, chain[i-1].Path, chain[i].Path, targetDir); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", filepath.Base(chain[i].Path), err)
+		}
+	}
+
+	return nil
+}
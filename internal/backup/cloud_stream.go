@@ -0,0 +1,255 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"dbbackup/internal/cloud"
+	"dbbackup/internal/compression"
+	"dbbackup/internal/database"
+	"dbbackup/internal/progress"
+)
+
+// BackupSingleToCloud backs up a single database straight into cloud storage.
+// The dump is piped through compression and encryption into a streaming upload,
+// so nothing is staged in the backup directory. The SHA-256 and size are taken
+// from the stream as it passes, and the .sha256 and .meta.json objects are
+// uploaded last: a backup without metadata in the bucket never completed.
+func (e *Engine) BackupSingleToCloud(ctx context.Context, databaseName string) error {
+	operationID := generateOperationID()
+	tracker := e.detailedReporter.StartOperation(operationID, databaseName, "backup")
+
+	tracker.SetDetails("database", databaseName)
+	tracker.SetDetails("type", "single")
+	tracker.SetDetails("destination", "cloud")
+
+	prepStep := tracker.AddStep("prepare", "Connecting to cloud storage")
+	lock, err := e.objectLock(time.Now())
+	if err != nil {
+		prepStep.Fail(err)
+		tracker.Fail(err)
+		return err
+	}
+	backend, err := e.newCloudBackend(lock)
+	if err != nil {
+		err = fmt.Errorf("failed to create cloud backend: %w", err)
+		prepStep.Fail(err)
+		tracker.Fail(err)
+		return err
+	}
+	defer cloud.CloseBackend(backend)
+	prepStep.Complete(fmt.Sprintf("Streaming to %s/%s", backend.Name(), e.cfg.CloudBucket))
+	tracker.UpdateProgress(10, "Cloud storage ready")
+
+	// Same names as local backups, so restores and retention treat them alike
+	timestamp := time.Now().Format("20060102_150405")
+	var filename string
+	if e.cfg.IsPostgreSQL() {
+		filename = fmt.Sprintf("db_%s_%s.dump", databaseName, timestamp)
+	} else {
+		filename = fmt.Sprintf("db_%s_%s.sql%s", databaseName, timestamp, e.mysqlDumpExtension())
+	}
+	tracker.SetDetails("output_file", filename)
+
+	cmdStep := tracker.AddStep("command", "Building backup command")
+	options := database.BackupOptions{
+		Compression: e.cfg.CompressionLevel,
+		Parallel:    e.cfg.DumpJobs,
+		Format:      "custom",
+		Blobs:       true,
+	}
+
+	// The content recorded in the metadata is read in a snapshot the dump shares
+	content := e.captureContent(ctx, databaseName, &options)
+	defer content.release()
+
+	cmd := e.db.BuildBackupCommand(databaseName, filename, options)
+
+	// pg_dump's custom format is compressed by pg_dump; mysqldump output goes
+	// through the configured compressor
+	compress := false
+	if e.cfg.IsPostgreSQL() {
+		if cmd, err = dumpToStdout(cmd); err != nil {
+			cmdStep.Fail(err)
+			tracker.Fail(err)
+			return err
+		}
+	} else {
+		compress = e.mysqlDumpExtension() != ""
+	}
+	cmdStep.Complete("Backup command prepared")
+	tracker.UpdateProgress(30, "Backup command prepared")
+
+	streamStep := tracker.AddStep("stream", "Streaming backup to cloud storage")
+	tracker.UpdateProgress(40, "Starting database backup...")
+	e.log.Info("Streaming backup to cloud", "provider", backend.Name(), "bucket", e.cfg.CloudBucket, "file", filename)
+
+	startTime := time.Now()
+	size, checksum, err := e.streamToCloud(ctx, backend, cmd, filename, compress, tracker)
+	if err != nil {
+		err = fmt.Errorf("backup failed for %s: %w", databaseName, err)
+		streamStep.Fail(err)
+		tracker.Fail(err)
+		return err
+	}
+	tracker.SetDetails("file_size", formatBytes(size))
+	tracker.SetByteProgress(size, size)
+	streamStep.Complete(fmt.Sprintf("Streamed %s", formatBytes(size)))
+	tracker.UpdateProgress(85, "Backup streamed")
+	e.log.Info("Backup checksum", "sha256", checksum)
+	recorded := e.waitContent(content)
+
+	metaStep := tracker.AddStep("metadata", "Uploading checksum and metadata")
+	meta := e.newBackupMetadata(filename, databaseName, "single", size, checksum)
+	meta.Timestamp = startTime
+	meta.Duration = time.Since(startTime).Seconds()
+	recordContent(meta, recorded)
+	recordLock(meta, lock)
+
+	checksumData := fmt.Sprintf("%s  %s\n", checksum, filename)
+	if err := backend.UploadStream(ctx, bytes.NewReader([]byte(checksumData)), filename+".sha256"); err != nil {
+		err = fmt.Errorf("failed to upload checksum: %w", err)
+		metaStep.Fail(err)
+		tracker.Fail(err)
+		return err
+	}
+
+	// Named targets get a copy read back from the primary storage; the backup
+	// is complete once it is there, so failed targets only degrade it
+	replicas := e.replicateStream(ctx, backend, filename, meta, lock, tracker)
+	defer closeBackends(replicas)
+
+	metaData, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		err = fmt.Errorf("failed to marshal metadata: %w", err)
+		metaStep.Fail(err)
+		tracker.Fail(err)
+		return err
+	}
+	if err := backend.UploadStream(ctx, bytes.NewReader(metaData), filename+".meta.json"); err != nil {
+		err = fmt.Errorf("failed to upload metadata: %w", err)
+		metaStep.Fail(err)
+		tracker.Fail(err)
+		return err
+	}
+	for _, replica := range replicas {
+		if err := replica.UploadStream(ctx, bytes.NewReader(metaData), filename+".meta.json"); err != nil {
+			e.log.Warn("Failed to upload metadata file", "target", replica.Name(), "error", err)
+		}
+	}
+	metaStep.Complete("Checksum and metadata uploaded")
+
+	tracker.UpdateProgress(100, "Backup operation completed successfully")
+	tracker.Complete(fmt.Sprintf("Single database backup streamed to %s/%s/%s", backend.Name(), e.cfg.CloudBucket, filename))
+	e.log.Info("Backup uploaded to cloud", "provider", backend.Name(), "bucket", e.cfg.CloudBucket, "file", filename, "size", cloud.FormatSize(size))
+
+	return nil
+}
+
+// streamToCloud runs a dump command writing to stdout and uploads its output
+// as remoteName, compressing (if compress) and encrypting it on the way.
+// Returns the size and SHA-256 of the uploaded object.
+func (e *Engine) streamToCloud(ctx context.Context, backend cloud.Backend, cmdArgs []string, remoteName string, compress bool, tracker *progress.OperationTracker) (int64, string, error) {
+	if len(cmdArgs) == 0 {
+		return 0, "", fmt.Errorf("empty command")
+	}
+
+	// Cancelled when the upload fails, which also stops the dump
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	uploadErr := make(chan error, 1)
+	go func() {
+		err := backend.UploadStream(ctx, pr, remoteName)
+		if err != nil {
+			cancel()
+			pr.CloseWithError(err)
+		}
+		uploadErr <- err
+	}()
+
+	// dump | compressor | encryption | (upload, sha256, size)
+	hash := sha256.New()
+	counter := &countingWriter{}
+	out, err := e.wrapOutput(io.MultiWriter(pw, hash, counter))
+	if err != nil {
+		pw.CloseWithError(err)
+		<-uploadErr
+		return 0, "", err
+	}
+	writers := []io.WriteCloser{out}
+	if compress {
+		cw, err := compression.NewWriter(ctx, out, e.cfg.CompressionOptions())
+		if err != nil {
+			pw.CloseWithError(err)
+			<-uploadErr
+			return 0, "", err
+		}
+		writers = append([]io.WriteCloser{cw}, writers...)
+	}
+
+	dumpCmd := exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
+	dumpCmd.Env = os.Environ()
+	if e.cfg.Password != "" {
+		if e.cfg.IsPostgreSQL() {
+			dumpCmd.Env = append(dumpCmd.Env, "PGPASSWORD="+e.cfg.Password)
+		} else if e.cfg.IsMySQL() {
+			dumpCmd.Env = append(dumpCmd.Env, "MYSQL_PWD="+e.cfg.Password)
+		}
+	}
+	dumpCmd.Stdout = writers[0]
+
+	stderr, err := dumpCmd.StderrPipe()
+	if err != nil {
+		pw.CloseWithError(err)
+		<-uploadErr
+		return 0, "", fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+	go e.monitorCommandProgress(stderr, tracker)
+
+	var dumpErr error
+	if err := dumpCmd.Start(); err != nil {
+		dumpErr = fmt.Errorf("failed to start %s: %w", cmdArgs[0], err)
+	} else if err := dumpCmd.Wait(); err != nil {
+		dumpErr = fmt.Errorf("%s failed: %w", cmdArgs[0], err)
+	}
+
+	// Flush the compressor and the final encryption chunk before ending the stream
+	for _, w := range writers {
+		if err := w.Close(); err != nil && dumpErr == nil {
+			dumpErr = fmt.Errorf("failed to finalize backup stream: %w", err)
+		}
+	}
+
+	// A nil error ends the upload at EOF; anything else aborts it. If the
+	// upload failed first, the dump was killed and the upload error is the cause.
+	uploadFailed := ctx.Err() != nil
+	pw.CloseWithError(dumpErr)
+	if err := <-uploadErr; err != nil && (dumpErr == nil || uploadFailed) {
+		return 0, "", fmt.Errorf("cloud upload failed: %w", err)
+	}
+	if dumpErr != nil {
+		return 0, "", dumpErr
+	}
+
+	return counter.n, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
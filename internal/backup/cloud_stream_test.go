@@ -0,0 +1,148 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dbbackup/internal/cloud"
+	"dbbackup/internal/config"
+	"dbbackup/internal/crypto"
+	"dbbackup/internal/encryption"
+	"dbbackup/internal/logger"
+)
+
+// memBackend keeps streamed objects in memory. Only UploadStream is implemented.
+type memBackend struct {
+	cloud.Backend
+	mu      sync.Mutex
+	objects map[string][]byte
+	failAt  int // fail the upload after this many bytes (0 = never)
+}
+
+func (m *memBackend) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
+	var buf bytes.Buffer
+	if m.failAt > 0 {
+		if _, err := io.CopyN(&buf, reader, int64(m.failAt)); err != nil {
+			return err
+		}
+		return errors.New("connection reset")
+	}
+	if _, err := io.Copy(&buf, reader); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.objects == nil {
+		m.objects = make(map[string][]byte)
+	}
+	m.objects[remotePath] = buf.Bytes()
+	return nil
+}
+
+func (m *memBackend) Name() string { return "mem" }
+
+func newStreamTestEngine() *Engine {
+	cfg := &config.Config{DatabaseType: "postgres"}
+	return NewSilent(cfg, logger.NewSilent(), nil, nil)
+}
+
+func TestStreamToCloud(t *testing.T) {
+	e := newStreamTestEngine()
+	backend := &memBackend{}
+	tracker := e.detailedReporter.StartOperation("test", "testdb", "backup")
+
+	cmd := []string{"sh", "-c", "yes 'COPY public.t FROM stdin;' | head -c 200000"}
+	size, checksum, err := e.streamToCloud(context.Background(), backend, cmd, "db_testdb.dump", false, tracker)
+	if err != nil {
+		t.Fatalf("streamToCloud failed: %v", err)
+	}
+
+	got := backend.objects["db_testdb.dump"]
+	if len(got) != 200000 || size != int64(len(got)) {
+		t.Fatalf("Expected 200000 bytes uploaded and reported, got %d uploaded, %d reported", len(got), size)
+	}
+	sum := sha256.Sum256(got)
+	if checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("Checksum %s doesn't match uploaded data", checksum)
+	}
+}
+
+func TestStreamToCloudEncrypted(t *testing.T) {
+	e := newStreamTestEngine()
+	key := bytes.Repeat([]byte{7}, crypto.KeySize)
+	e.SetEncryption(&encryption.EncryptionOptions{Key: key}, nil)
+	backend := &memBackend{}
+	tracker := e.detailedReporter.StartOperation("test", "testdb", "backup")
+
+	cmd := []string{"sh", "-c", "yes 'INSERT INTO t VALUES (1);' | head -c 100000"}
+	size, checksum, err := e.streamToCloud(context.Background(), backend, cmd, "db_testdb.dump", false, tracker)
+	if err != nil {
+		t.Fatalf("streamToCloud failed: %v", err)
+	}
+
+	got := backend.objects["db_testdb.dump"]
+	sum := sha256.Sum256(got)
+	if size != int64(len(got)) || checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("Size and checksum must describe the encrypted object")
+	}
+
+	reader, err := encryption.NewDecryptionReader(bytes.NewReader(got), encryption.EncryptionOptions{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decrypt uploaded object: %v", err)
+	}
+	if len(plaintext) != 100000 || !strings.HasPrefix(string(plaintext), "INSERT INTO t") {
+		t.Errorf("Decrypted object doesn't match the dump output (%d bytes)", len(plaintext))
+	}
+}
+
+func TestStreamToCloudDumpFailure(t *testing.T) {
+	e := newStreamTestEngine()
+	backend := &memBackend{}
+	tracker := e.detailedReporter.StartOperation("test", "testdb", "backup")
+
+	cmd := []string{"sh", "-c", "echo partial; exit 3"}
+	_, _, err := e.streamToCloud(context.Background(), backend, cmd, "db_testdb.dump", false, tracker)
+	if err == nil {
+		t.Fatal("Expected error when the dump fails")
+	}
+	if strings.Contains(err.Error(), "cloud upload failed") {
+		t.Errorf("Dump failure reported as upload failure: %v", err)
+	}
+	if _, ok := backend.objects["db_testdb.dump"]; ok {
+		t.Error("Failed dump must not leave an object behind")
+	}
+}
+
+func TestStreamToCloudUploadFailure(t *testing.T) {
+	e := newStreamTestEngine()
+	backend := &memBackend{failAt: 64 * 1024}
+	tracker := e.detailedReporter.StartOperation("test", "testdb", "backup")
+
+	// An endless dump must be stopped when the upload gives up
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := e.streamToCloud(context.Background(), backend, []string{"yes"}, "db_testdb.dump", false, tracker)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "connection reset") {
+			t.Errorf("Expected upload error, got %v", err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("Dump was not stopped after the upload failed")
+	}
+}
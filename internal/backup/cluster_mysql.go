@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// mysqlSystemAccounts are internal accounts that exist on every server
+var mysqlSystemAccounts = []string{"mysql.sys", "mysql.session", "mysql.infoschema", "mariadb.sys"}
+
+// backupMySQLGrants writes users and their grants to globals.sql, the MySQL
+// equivalent of pg_dumpall --globals-only
+func (e *Engine) backupMySQLGrants(ctx context.Context, tempDir string) error {
+	excluded := make([]string, len(mysqlSystemAccounts))
+	for i, account := range mysqlSystemAccounts {
+		excluded[i] = "'" + account + "'"
+	}
+	accounts, err := e.mysqlQuery(ctx, fmt.Sprintf(
+		"SELECT CONCAT(QUOTE(user), '@', QUOTE(host)) FROM mysql.user WHERE user <> '' AND user NOT IN (%s) ORDER BY user, host",
+		strings.Join(excluded, ", ")), false)
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("-- MySQL users and grants\n")
+	sb.WriteString(fmt.Sprintf("-- Host: %s:%d\n", e.cfg.Host, e.cfg.Port))
+	sb.WriteString(fmt.Sprintf("-- Created: %s\n\n", time.Now().Format(time.RFC3339)))
+
+	for _, account := range accounts {
+		// print_identified_with_as_hex keeps binary password hashes (MySQL 8) printable;
+		// MariaDB doesn't know it, so errors are tolerated
+		statements, err := e.mysqlQuery(ctx, fmt.Sprintf(
+			"SET SESSION print_identified_with_as_hex = ON; SHOW CREATE USER %s; SHOW GRANTS FOR %s",
+			account, account), true)
+		if len(statements) == 0 {
+			e.log.Warn("Failed to read grants", "account", account, "error", err)
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("-- %s\n", account))
+		for _, stmt := range statements {
+			if strings.HasPrefix(stmt, "CREATE USER ") {
+				stmt = "CREATE USER IF NOT EXISTS " + strings.TrimPrefix(stmt, "CREATE USER ")
+			}
+			sb.WriteString(stmt + ";\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	e.log.Info("Backed up MySQL users and grants", "accounts", len(accounts))
+	return e.writeOutputFile(filepath.Join(tempDir, "globals.sql"), []byte(sb.String()))
+}
+
+// mysqlQuery runs a query with the mysql client and returns one line per row.
+// With force, statement errors don't abort the batch.
+func (e *Engine) mysqlQuery(ctx context.Context, query string, force bool) ([]string, error) {
+	args := []string{
+		"-P", fmt.Sprintf("%d", e.cfg.Port),
+		"-u", e.cfg.User,
+		"-N", "-B", "-r",
+	}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, "-e", query)
+
+	// Only add -h flag if host is not localhost (to use Unix socket)
+	if e.cfg.Host != "localhost" && e.cfg.Host != "127.0.0.1" && e.cfg.Host != "" {
+		args = append([]string{"-h", e.cfg.Host}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "mysql", args...)
+	cmd.Env = os.Environ()
+	if e.cfg.Password != "" {
+		cmd.Env = append(cmd.Env, "MYSQL_PWD="+e.cfg.Password)
+	}
+
+	output, err := cmd.Output()
+
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, err
+}
+
+// mysqlDumpExtension returns the extension of compressed single database
+// dumps; they stay uncompressed at compression level 0
+func (e *Engine) mysqlDumpExtension() string {
+	return e.cfg.CompressionOptions().Algorithm.Extension()
+}
@@ -2,17 +2,167 @@ package backup
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"dbbackup/internal/crypto"
+	"dbbackup/internal/encryption"
 	"dbbackup/internal/logger"
 	"dbbackup/internal/metadata"
+	"dbbackup/internal/security"
 )
 
+// SetEncryption enables streaming encryption: backup output is encrypted as it
+// is written, so no plaintext copy of the dump ever reaches disk. nil disables it.
+// envelope records how the data key in opts is wrapped by a key provider and is
+// stored in the backup metadata (nil when the key is used directly).
+func (e *Engine) SetEncryption(opts *encryption.EncryptionOptions, envelope *crypto.EncryptionMetadata) {
+	e.encryption = opts
+	e.envelope = envelope
+}
+
+// wrapOutput wraps w in an encryption writer when encryption is enabled
+func (e *Engine) wrapOutput(w io.Writer) (io.WriteCloser, error) {
+	return wrapWriter(w, e.encryption)
+}
+
+// wrapWriter wraps w in an encryption writer when opts is set
+func wrapWriter(w io.Writer, opts *encryption.EncryptionOptions) (io.WriteCloser, error) {
+	if opts == nil {
+		return nopWriteCloser{w}, nil
+	}
+	ew, err := encryption.NewEncryptionWriter(w, *opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+	return ew, nil
+}
+
+// createOutputFile creates a backup output file, encrypting on the way to disk
+// when encryption is enabled. Close must be checked: it writes the final chunk.
+func (e *Engine) createOutputFile(path string) (io.WriteCloser, error) {
+	return createOutputFile(path, e.encryption)
+}
+
+// createOutputFile creates a file encrypted with opts as it is written, or a
+// plain one when opts is nil
+func createOutputFile(path string, opts *encryption.EncryptionOptions) (io.WriteCloser, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	w, err := wrapWriter(f, opts)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return &outputFile{WriteCloser: w, file: f}, nil
+}
+
+// writeOutputFile writes data to path through createOutputFile
+func (e *Engine) writeOutputFile(path string, data []byte) error {
+	w, err := e.createOutputFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return w.Close()
+}
+
+// outputFile closes the encryption layer before the underlying file
+type outputFile struct {
+	io.WriteCloser
+	file *os.File
+}
+
+func (o *outputFile) Close() error {
+	err := o.WriteCloser.Close()
+	if cerr := o.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// setEncryptionMetadata marks metadata of a backup written with streaming encryption
+func (e *Engine) setEncryptionMetadata(meta *metadata.BackupMetadata) {
+	if e.encryption != nil {
+		markStreamEncrypted(meta, e.envelope)
+	}
+}
+
+// markStreamEncrypted marks metadata of a backup encrypted as it was written
+func markStreamEncrypted(meta *metadata.BackupMetadata, envelope *crypto.EncryptionMetadata) {
+	meta.Encrypted = true
+	meta.EncryptionAlgorithm = encryption.Algorithm
+	meta.Encryption = envelopeFor(envelope, encryption.Algorithm)
+}
+
+// envelopeFor returns a copy of the envelope for one backup's metadata
+func envelopeFor(envelope *crypto.EncryptionMetadata, algorithm string) *crypto.EncryptionMetadata {
+	if envelope == nil {
+		return nil
+	}
+	env := *envelope
+	env.Algorithm = algorithm
+	return &env
+}
+
+// LoadEncryptionEnvelope returns the wrapped data key recorded for a backup, or
+// nil if the backup was encrypted with a key used directly (or has no metadata).
+// Cluster members share one data key, so the first recorded envelope is returned.
+func LoadEncryptionEnvelope(backupPath string) *crypto.EncryptionMetadata {
+	// Single backup metadata also parses as cluster metadata, just without databases
+	if clusterMeta, err := metadata.LoadCluster(backupPath); err == nil && len(clusterMeta.Databases) > 0 {
+		for _, db := range clusterMeta.Databases {
+			if db.Encryption != nil && db.Encryption.WrappedKey != "" {
+				return db.Encryption
+			}
+		}
+		return nil
+	}
+	if meta, err := metadata.Load(backupPath); err == nil && meta.Encryption != nil && meta.Encryption.WrappedKey != "" {
+		return meta.Encryption
+	}
+	return nil
+}
+
+// IsStreamEncrypted reports whether a backup was encrypted while it was written
+// (as opposed to EncryptBackupFile). Such archives are decrypted while they are
+// read during restore. For cluster archives the individual dumps are encrypted.
+func IsStreamEncrypted(backupPath string) bool {
+	if encryption.IsEncryptedFile(backupPath) {
+		return true
+	}
+	if clusterMeta, err := metadata.LoadCluster(backupPath); err == nil && len(clusterMeta.Databases) > 0 {
+		for _, db := range clusterMeta.Databases {
+			if db.EncryptionAlgorithm == encryption.Algorithm {
+				return true
+			}
+		}
+		return false
+	}
+	if meta, err := metadata.Load(backupPath); err == nil {
+		return meta.EncryptionAlgorithm == encryption.Algorithm
+	}
+	return false
+}
+
 // EncryptBackupFile encrypts a backup file in-place
-// The original file is replaced with the encrypted version
-func EncryptBackupFile(backupPath string, key []byte, log logger.Logger) error {
+// The original file is replaced with the encrypted version. envelope (optional)
+// records how key is wrapped and is stored in the backup metadata.
+func EncryptBackupFile(backupPath string, key []byte, envelope *crypto.EncryptionMetadata, log logger.Logger) error {
 	log.Info("Encrypting backup file", "file", filepath.Base(backupPath))
 	
 	// Validate key
@@ -33,17 +183,33 @@ func EncryptBackupFile(backupPath string, key []byte, log logger.Logger) error {
 		return fmt.Errorf("encryption failed: %w", err)
 	}
 
+	// The checksum and size recorded for the backup must describe the
+	// encrypted file, which replaces the original
+	checksum, err := security.ChecksumFile(encryptedPath)
+	if err != nil {
+		os.Remove(encryptedPath)
+		return fmt.Errorf("failed to checksum encrypted file: %w", err)
+	}
+	info, err := os.Stat(encryptedPath)
+	if err != nil {
+		os.Remove(encryptedPath)
+		return fmt.Errorf("failed to stat encrypted file: %w", err)
+	}
+
 	// Update metadata to indicate encryption
 	metaPath := backupPath + ".meta.json"
 	if _, err := os.Stat(metaPath); err == nil {
 		// Load existing metadata
-		meta, err := metadata.Load(metaPath)
+		meta, err := metadata.Load(backupPath)
 		if err != nil {
 			log.Warn("Failed to load metadata for encryption update", "error", err)
 		} else {
 			// Mark as encrypted
 			meta.Encrypted = true
-			meta.EncryptionAlgorithm = string(crypto.AlgorithmAES256GCM)
+			meta.EncryptionAlgorithm = encryption.Algorithm
+			meta.Encryption = envelopeFor(envelope, meta.EncryptionAlgorithm)
+			meta.SHA256 = checksum
+			meta.SizeBytes = info.Size()
 
 			// Save updated metadata
 			if err := metadata.Save(metaPath, meta); err != nil {
@@ -62,6 +228,11 @@ func EncryptBackupFile(backupPath string, key []byte, log logger.Logger) error {
 	if err := os.Rename(encryptedPath, backupPath); err != nil {
 		return fmt.Errorf("failed to rename encrypted file: %w", err)
 	}
+	if _, err := os.Stat(backupPath + ".sha256"); err == nil {
+		if err := security.SaveChecksum(backupPath, checksum); err != nil {
+			return err
+		}
+	}
 
 	log.Info("Backup encrypted successfully", "file", filepath.Base(backupPath))
 	return nil
@@ -71,7 +242,7 @@ func EncryptBackupFile(backupPath string, key []byte, log logger.Logger) error {
 func IsBackupEncrypted(backupPath string) bool {
 	// Check metadata first - try cluster metadata (for cluster backups)
 	// Try cluster metadata first
-	if clusterMeta, err := metadata.LoadCluster(backupPath); err == nil {
+	if clusterMeta, err := metadata.LoadCluster(backupPath); err == nil && len(clusterMeta.Databases) > 0 {
 		// For cluster backups, check if ANY database is encrypted
 		for _, db := range clusterMeta.Databases {
 			if db.Encrypted {
@@ -124,3 +295,191 @@ func DecryptBackupFile(encryptedPath, outputPath string, key []byte, log logger.
 	log.Info("Backup decrypted successfully", "output", filepath.Base(outputPath))
 	return nil
 }
+
+// DecryptStreamFile decrypts a file written with streaming encryption into outputPath
+func DecryptStreamFile(encryptedPath, outputPath string, opts encryption.EncryptionOptions) error {
+	in, err := os.Open(encryptedPath)
+	if err != nil {
+		return fmt.Errorf("failed to open encrypted file: %w", err)
+	}
+	defer in.Close()
+
+	reader, err := encryption.NewDecryptionReader(in, opts)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	out, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		os.Remove(outputPath)
+		return fmt.Errorf("decryption failed (wrong key?): %w", err)
+	}
+	return out.Close()
+}
+
+// VerifyStreamFile decrypts a file written with streaming encryption without
+// keeping the output, which authenticates every chunk
+func VerifyStreamFile(encryptedPath string, opts encryption.EncryptionOptions) error {
+	in, err := os.Open(encryptedPath)
+	if err != nil {
+		return fmt.Errorf("failed to open encrypted file: %w", err)
+	}
+	defer in.Close()
+
+	reader, err := encryption.NewDecryptionReader(in, opts)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt: %w", err)
+	}
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("decryption failed (wrong key or corrupted data?): %w", err)
+	}
+	return nil
+}
+
+// EncryptionFormat returns the encryption format of a backup file. Legacy
+// headerless files are recognized by their metadata.
+func EncryptionFormat(backupPath string) encryption.Format {
+	if format := encryption.DetectFileFormat(backupPath); format != encryption.FormatUnknown {
+		return format
+	}
+
+	legacy := string(crypto.AlgorithmAES256GCM)
+	if clusterMeta, err := metadata.LoadCluster(backupPath); err == nil && len(clusterMeta.Databases) > 0 {
+		for _, db := range clusterMeta.Databases {
+			if db.Encrypted && db.EncryptionAlgorithm == legacy {
+				return encryption.FormatLegacyAES
+			}
+		}
+		return encryption.FormatUnknown
+	}
+	if meta, err := metadata.Load(backupPath); err == nil && meta.Encrypted && meta.EncryptionAlgorithm == legacy {
+		return encryption.FormatLegacyAES
+	}
+	return encryption.FormatUnknown
+}
+
+// MigrateEncryptedFile re-encrypts a backup file in the current container
+// format with the same key. The checksum in its metadata is updated, and so
+// is the base reference of incremental backups next to it.
+func MigrateEncryptedFile(backupPath string, opts encryption.EncryptionOptions, log logger.Logger) error {
+	in, err := os.Open(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to open encrypted file: %w", err)
+	}
+	defer in.Close()
+
+	reader, err := encryption.NewDecryptionReader(in, opts)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	tmpPath := backupPath + ".migrate.tmp"
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	fail := func(err error) error {
+		out.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	writer, err := encryption.NewEncryptionWriter(out, opts)
+	if err != nil {
+		return fail(fmt.Errorf("failed to initialize encryption: %w", err))
+	}
+	if _, err := io.Copy(writer, reader); err != nil {
+		return fail(fmt.Errorf("decryption failed (wrong key?): %w", err))
+	}
+	if err := writer.Close(); err != nil {
+		return fail(fmt.Errorf("failed to write encrypted data: %w", err))
+	}
+	if err := out.Sync(); err != nil {
+		return fail(fmt.Errorf("failed to sync encrypted file: %w", err))
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write encrypted data: %w", err)
+	}
+	in.Close()
+
+	if err := os.Rename(tmpPath, backupPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace backup file: %w", err)
+	}
+	log.Info("Backup re-encrypted", "file", filepath.Base(backupPath), "from", reader.Format())
+
+	return updateMigratedMetadata(backupPath, log)
+}
+
+// updateMigratedMetadata records the new format, size and checksum of a migrated backup
+func updateMigratedMetadata(backupPath string, log logger.Logger) error {
+	info, err := os.Stat(backupPath)
+	if err != nil {
+		return err
+	}
+
+	if clusterMeta, err := metadata.LoadCluster(backupPath); err == nil && len(clusterMeta.Databases) > 0 {
+		for i := range clusterMeta.Databases {
+			markMigrated(&clusterMeta.Databases[i])
+		}
+		clusterMeta.TotalSize = info.Size()
+		return clusterMeta.Save(backupPath)
+	}
+
+	meta, err := metadata.Load(backupPath)
+	if err != nil {
+		// WAL archives and other files without metadata
+		return nil
+	}
+
+	oldSum := meta.SHA256
+	newSum, err := metadata.CalculateSHA256(backupPath)
+	if err != nil {
+		return err
+	}
+	markMigrated(meta)
+	meta.SHA256 = newSum
+	meta.SizeBytes = info.Size()
+	if err := metadata.Save(backupPath+".meta.json", meta); err != nil {
+		return err
+	}
+
+	if oldSum != "" && oldSum != newSum {
+		relinkIncrementals(filepath.Dir(backupPath), oldSum, newSum, log)
+	}
+	return nil
+}
+
+func markMigrated(meta *metadata.BackupMetadata) {
+	if !meta.Encrypted {
+		return
+	}
+	meta.EncryptionAlgorithm = encryption.Algorithm
+	if meta.Encryption != nil {
+		meta.Encryption.Algorithm = encryption.Algorithm
+	}
+}
+
+// relinkIncrementals points incremental backups in dir whose base had checksum
+// oldSum at the base's new checksum, so chains survive the migration
+func relinkIncrementals(dir, oldSum, newSum string, log logger.Logger) {
+	metaFiles, _ := filepath.Glob(filepath.Join(dir, "*.meta.json"))
+	for _, metaFile := range metaFiles {
+		backupFile := strings.TrimSuffix(metaFile, ".meta.json")
+		meta, err := metadata.Load(backupFile)
+		if err != nil || meta.Incremental == nil || meta.Incremental.BaseBackupID != oldSum {
+			continue
+		}
+		meta.Incremental.BaseBackupID = newSum
+		if err := metadata.Save(metaFile, meta); err != nil {
+			log.Warn("Failed to update base backup reference", "file", filepath.Base(backupFile), "error", err)
+			continue
+		}
+		log.Info("Updated base backup reference", "file", filepath.Base(backupFile))
+	}
+}
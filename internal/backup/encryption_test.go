@@ -0,0 +1,111 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dbbackup/internal/crypto"
+	"dbbackup/internal/encryption"
+	"dbbackup/internal/logger"
+	"dbbackup/internal/metadata"
+)
+
+func TestMigrateEncryptedFile(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{4}, crypto.KeySize)
+	plaintext := bytes.Repeat([]byte("pg_dump output "), 20000)
+
+	// A full backup encrypted after it was written, before the container format
+	basePath := filepath.Join(dir, "testdb_base.dump")
+	writeLegacyAES(t, basePath, key, plaintext)
+	oldSum, _ := metadata.CalculateSHA256(basePath)
+	base := &metadata.BackupMetadata{
+		Database:            "testdb",
+		BackupFile:          basePath,
+		SHA256:              oldSum,
+		BackupType:          "full",
+		Encrypted:           true,
+		EncryptionAlgorithm: string(crypto.AlgorithmAES256GCM),
+	}
+	if err := base.Save(); err != nil {
+		t.Fatal(err)
+	}
+	incr := writeChainLink(t, dir, "testdb_incr_1.tar.gz", "bbb", base.Timestamp, base, nil)
+
+	if got := EncryptionFormat(basePath); got != encryption.FormatLegacyAES {
+		t.Fatalf("Expected legacy format from metadata, got %s", got)
+	}
+
+	if err := MigrateEncryptedFile(basePath, encryption.EncryptionOptions{Key: key}, logger.NewSilent()); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	if got := EncryptionFormat(basePath); got != encryption.FormatContainer {
+		t.Errorf("Expected container format after migration, got %s", got)
+	}
+	f, _ := os.Open(basePath)
+	defer f.Close()
+	reader, err := encryption.NewDecryptionReader(f, encryption.EncryptionOptions{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(reader); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Migrated file doesn't decrypt to the original data (err %v)", err)
+	}
+
+	meta, _ := metadata.Load(basePath)
+	newSum, _ := metadata.CalculateSHA256(basePath)
+	if meta.SHA256 != newSum || meta.EncryptionAlgorithm != encryption.Algorithm {
+		t.Errorf("Metadata not updated: sha256 %s, algorithm %s", meta.SHA256, meta.EncryptionAlgorithm)
+	}
+
+	incrMeta, _ := metadata.Load(incr.BackupFile)
+	if incrMeta.Incremental.BaseBackupID != newSum {
+		t.Errorf("Incremental still refers to base %s, expected %s", incrMeta.Incremental.BaseBackupID, newSum)
+	}
+}
+
+// writeLegacyAES writes the headerless format crypto.AESEncryptor used to produce
+func writeLegacyAES(t *testing.T, path string, key, data []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	nonce, _ := crypto.GenerateNonce()
+	buf.Write(nonce)
+
+	gcm := newTestGCM(t, key)
+	for len(data) > 0 {
+		n := min(crypto.BufferSize, len(data))
+		sealed := gcm.Seal(nil, nonce, data[:n], nil)
+		buf.Write([]byte{byte(len(sealed) >> 24), byte(len(sealed) >> 16), byte(len(sealed) >> 8), byte(len(sealed))})
+		buf.Write(sealed)
+		for i := len(nonce) - 1; i >= 0; i-- {
+			nonce[i]++
+			if nonce[i] != 0 {
+				break
+			}
+		}
+		data = data[n:]
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestGCM(t *testing.T, key []byte) cipher.AEAD {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gcm
+}
@@ -18,8 +18,11 @@ import (
 
 	"dbbackup/internal/checks"
 	"dbbackup/internal/cloud"
+	"dbbackup/internal/compression"
 	"dbbackup/internal/config"
+	"dbbackup/internal/crypto"
 	"dbbackup/internal/database"
+	"dbbackup/internal/encryption"
 	"dbbackup/internal/security"
 	"dbbackup/internal/logger"
 	"dbbackup/internal/metadata"
@@ -35,7 +38,9 @@ type Engine struct {
 	db               database.Database
 	progress         progress.Indicator
 	detailedReporter *progress.DetailedReporter
-	silent           bool // Silent mode for TUI
+	silent           bool                          // Silent mode for TUI
+	encryption       *encryption.EncryptionOptions // Streaming encryption (nil = disabled)
+	envelope         *crypto.EncryptionMetadata    // Wrapped data key recorded in metadata
 }
 
 // New creates a new backup engine
@@ -161,7 +166,7 @@ func (e *Engine) BackupSingle(ctx context.Context, databaseName string) error {
 	if e.cfg.IsPostgreSQL() {
 		outputFile = filepath.Join(e.cfg.BackupDir, fmt.Sprintf("db_%s_%s.dump", databaseName, timestamp))
 	} else {
-		outputFile = filepath.Join(e.cfg.BackupDir, fmt.Sprintf("db_%s_%s.sql.gz", databaseName, timestamp))
+		outputFile = filepath.Join(e.cfg.BackupDir, fmt.Sprintf("db_%s_%s.sql%s", databaseName, timestamp, e.mysqlDumpExtension()))
 	}
 	
 	tracker.SetDetails("output_file", outputFile)
@@ -178,6 +183,10 @@ func (e *Engine) BackupSingle(ctx context.Context, databaseName string) error {
 		NoPrivileges: false,
 	}
 	
+	// The content recorded in the metadata is read in a snapshot the dump shares
+	content := e.captureContent(ctx, databaseName, &options)
+	defer content.release()
+	
 	cmd := e.db.BuildBackupCommand(databaseName, outputFile, options)
 	cmdStep.Complete("Backup command prepared")
 	tracker.UpdateProgress(30, "Backup command prepared")
@@ -194,6 +203,7 @@ func (e *Engine) BackupSingle(ctx context.Context, databaseName string) error {
 	}
 	execStep.Complete("Database backup completed")
 	tracker.UpdateProgress(80, "Database backup completed")
+	recorded := e.waitContent(content)
 	
 	// Verify backup file
 	verifyStep := tracker.AddStep("verify", "Verifying backup file")
@@ -226,7 +236,7 @@ func (e *Engine) BackupSingle(ctx context.Context, databaseName string) error {
 	
 	// Create metadata file
 	metaStep := tracker.AddStep("metadata", "Creating metadata file")
-	if err := e.createMetadata(outputFile, databaseName, "single", ""); err != nil {
+	if err := e.createMetadata(outputFile, databaseName, "single", "", recorded); err != nil {
 		e.log.Warn("Failed to create metadata file", "error", err)
 		metaStep.Fail(fmt.Errorf("metadata creation failed: %w", err))
 	} else {
@@ -239,7 +249,7 @@ func (e *Engine) BackupSingle(ctx context.Context, databaseName string) error {
 	}
 	
 	// Cloud upload if enabled
-	if e.cfg.CloudEnabled && e.cfg.CloudAutoUpload {
+	if e.cfg.CloudUploadEnabled() {
 		if err := e.uploadToCloud(ctx, outputFile, tracker); err != nil {
 			e.log.Warn("Cloud upload failed", "error", err)
 			// Don't fail the backup if cloud upload fails
@@ -289,18 +299,21 @@ func (e *Engine) BackupSample(ctx context.Context, databaseName string) error {
 		operation.Complete(fmt.Sprintf("Sample backup created: %s (%s)", outputFile, size))
 	}
 	
-	// Create metadata file
-	if err := e.createMetadata(outputFile, databaseName, "sample", e.cfg.SampleStrategy); err != nil {
+	// Create metadata file. A sample holds a subset of the rows, so the
+	// source's content doesn't describe it.
+	if err := e.createMetadata(outputFile, databaseName, "sample", e.cfg.SampleStrategy, nil); err != nil {
 		e.log.Warn("Failed to create metadata file", "error", err)
 	}
 	
 	return nil
 }
 
-// BackupCluster performs a full cluster backup (PostgreSQL only)
+// BackupCluster performs a full cluster backup (all databases + globals).
+// For MySQL/MariaDB the globals are users and grants, and each schema is
+// dumped with mysqldump into dumps/<name>.sql.gz.
 func (e *Engine) BackupCluster(ctx context.Context) error {
-	if !e.cfg.IsPostgreSQL() {
-		return fmt.Errorf("cluster backup is only supported for PostgreSQL")
+	if !e.cfg.IsPostgreSQL() && !e.cfg.IsMySQL() {
+		return fmt.Errorf("cluster backup is not supported for %s", e.cfg.DisplayDatabaseType())
 	}
 	
 	operation := e.log.StartOperation("Cluster Backup")
@@ -371,7 +384,7 @@ func (e *Engine) BackupCluster(ctx context.Context) error {
 	
 	// Generate timestamp and filename
 	timestamp := time.Now().Format("20060102_150405")
-	outputFile := filepath.Join(e.cfg.BackupDir, fmt.Sprintf("cluster_%s.tar.gz", timestamp))
+	outputFile := filepath.Join(e.cfg.BackupDir, fmt.Sprintf("cluster_%s.tar%s", timestamp, e.cfg.CompressionOptions().Algorithm.Extension()))
 	tempDir := filepath.Join(e.cfg.BackupDir, fmt.Sprintf(".cluster_%s", timestamp))
 	
 	operation.Update("Starting cluster backup")
@@ -386,7 +399,11 @@ func (e *Engine) BackupCluster(ctx context.Context) error {
 	
 	// Backup globals
 	e.printf("   Backing up global objects...\n")
-	if err := e.backupGlobals(ctx, tempDir); err != nil {
+	backupGlobals := e.backupGlobals
+	if e.cfg.IsMySQL() {
+		backupGlobals = e.backupMySQLGrants
+	}
+	if err := backupGlobals(ctx, tempDir); err != nil {
 		quietProgress.Fail(fmt.Sprintf("Failed to backup globals: %v", err))
 		operation.Fail("Global backup failed")
 		return fmt.Errorf("failed to backup globals: %w", err)
@@ -419,7 +436,8 @@ func (e *Engine) BackupCluster(ctx context.Context) error {
 	
 	// Use worker pool for parallel backup
 	var successCount, failCount int32
-	var mu sync.Mutex // Protect shared resources (printf, estimator)
+	var mu sync.Mutex // Protect shared resources (printf, estimator, contents)
+	contents := make(map[string]*dumpContent, len(databases))
 	
 	// Create semaphore to limit concurrency
 	semaphore := make(chan struct{}, parallelism)
@@ -470,6 +488,38 @@ func (e *Engine) BackupCluster(ctx context.Context) error {
 				mu.Unlock()
 			}
 			
+			if e.cfg.IsMySQL() {
+				dumpFile := filepath.Join(tempDir, "dumps", name+".sql"+e.cfg.CompressionOptions().Algorithm.Extension())
+				
+				dbCtx, cancel := context.WithTimeout(ctx, 2*time.Hour)
+				defer cancel()
+				var options database.BackupOptions
+				content := e.captureContent(dbCtx, name, &options)
+				defer content.release()
+				
+				cmd := e.db.BuildBackupCommand(name, dumpFile, options)
+				err := e.executeMySQLWithCompression(dbCtx, cmd, dumpFile)
+				var recorded *dumpContent
+				if err == nil {
+					recorded = e.waitContent(content)
+				}
+				
+				mu.Lock()
+				if err != nil {
+					e.log.Warn("Failed to backup database", "database", name, "error", err)
+					e.printf("   ⚠️  WARNING: Failed to backup %s: %v\n", name, err)
+					atomic.AddInt32(&failCount, 1)
+				} else {
+					if info, err := os.Stat(dumpFile); err == nil {
+						e.printf("   ✅ Completed %s (%s)\n", name, formatBytes(info.Size()))
+					}
+					contents[name] = recorded
+					atomic.AddInt32(&successCount, 1)
+				}
+				mu.Unlock()
+				return
+			}
+			
 			dumpFile := filepath.Join(tempDir, "dumps", name+".dump")
 			
 			compressionLevel := e.cfg.CompressionLevel
@@ -500,12 +550,13 @@ func (e *Engine) BackupCluster(ctx context.Context) error {
 				NoPrivileges: false,
 			}
 			
-			cmd := e.db.BuildBackupCommand(name, dumpFile, options)
-			
 			dbCtx, cancel := context.WithTimeout(ctx, 2*time.Hour)
 			defer cancel()
+			content := e.captureContent(dbCtx, name, &options)
+			defer content.release()
+			
+			cmd := e.db.BuildBackupCommand(name, dumpFile, options)
 			err := e.executeCommand(dbCtx, cmd, dumpFile)
-			cancel()
 			
 			if err != nil {
 				e.log.Warn("Failed to backup database", "database", name, "error", err)
@@ -514,8 +565,10 @@ func (e *Engine) BackupCluster(ctx context.Context) error {
 				mu.Unlock()
 				atomic.AddInt32(&failCount, 1)
 			} else {
-				compressedCandidate := strings.TrimSuffix(dumpFile, ".dump") + ".sql.gz"
+				recorded := e.waitContent(content)
+				compressedCandidate := strings.TrimSuffix(dumpFile, ".dump") + ".sql" + e.cfg.CompressionOptions().Algorithm.Extension()
 				mu.Lock()
+				contents[name] = recorded
 				if info, err := os.Stat(compressedCandidate); err == nil {
 					e.printf("   ✅ Completed %s (%s)\n", name, formatBytes(info.Size()))
 				} else if info, err := os.Stat(dumpFile); err == nil {
@@ -555,7 +608,7 @@ func (e *Engine) BackupCluster(ctx context.Context) error {
 	}
 	
 	// Create cluster metadata file
-	if err := e.createClusterMetadata(outputFile, databases, successCountFinal, failCountFinal); err != nil {
+	if err := e.createClusterMetadata(outputFile, databases, contents, successCountFinal, failCountFinal); err != nil {
 		e.log.Warn("Failed to create cluster metadata file", "error", err)
 	}
 	
@@ -583,10 +636,25 @@ func (e *Engine) executeCommandWithProgress(ctx context.Context, cmdArgs []strin
 	}
 	
 	// For MySQL, handle compression and redirection differently
-	if e.cfg.IsMySQL() && e.cfg.CompressionLevel > 0 {
+	if e.cfg.IsMySQL() && e.mysqlDumpExtension() != "" {
 		return e.executeMySQLWithProgressAndCompression(ctx, cmdArgs, outputFile, tracker)
 	}
 	
+	// With encryption, pg_dump writes to stdout and the dump is encrypted on its way to disk
+	var outFile io.WriteCloser
+	if e.encryption != nil {
+		args, err := dumpToStdout(cmdArgs)
+		if err != nil {
+			return err
+		}
+		if outFile, err = e.createOutputFile(outputFile); err != nil {
+			return err
+		}
+		defer outFile.Close()
+		cmd.Args = args
+		cmd.Stdout = outFile
+	}
+	
 	// Get stderr pipe for progress monitoring
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
@@ -606,6 +674,11 @@ func (e *Engine) executeCommandWithProgress(ctx context.Context, cmdArgs []strin
 		return fmt.Errorf("backup command failed: %w", err)
 	}
 	
+	if outFile != nil {
+		if err := outFile.Close(); err != nil {
+			return fmt.Errorf("failed to finalize output file: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -655,24 +728,27 @@ func (e *Engine) executeMySQLWithProgressAndCompression(ctx context.Context, cmd
 		dumpCmd.Env = append(dumpCmd.Env, "MYSQL_PWD="+e.cfg.Password)
 	}
 	
-	// Create gzip command
-	gzipCmd := exec.CommandContext(ctx, "gzip", fmt.Sprintf("-%d", e.cfg.CompressionLevel))
+	// Create compressor command
+	compressCmd, err := e.cfg.CompressionOptions().Command(ctx)
+	if err != nil {
+		return err
+	}
 	
-	// Create output file
-	outFile, err := os.Create(outputFile)
+	// Create output file (encrypted while written if enabled)
+	outFile, err := e.createOutputFile(outputFile)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return err
 	}
 	defer outFile.Close()
 	
-	// Set up pipeline: mysqldump | gzip > outputfile
+	// Set up pipeline: mysqldump | compressor > outputfile
 	pipe, err := dumpCmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create pipe: %w", err)
 	}
 	
-	gzipCmd.Stdin = pipe
-	gzipCmd.Stdout = outFile
+	compressCmd.Stdin = pipe
+	compressCmd.Stdout = outFile
 	
 	// Get stderr for progress monitoring
 	stderr, err := dumpCmd.StderrPipe()
@@ -684,8 +760,8 @@ func (e *Engine) executeMySQLWithProgressAndCompression(ctx context.Context, cmd
 	go e.monitorCommandProgress(stderr, tracker)
 	
 	// Start both commands
-	if err := gzipCmd.Start(); err != nil {
-		return fmt.Errorf("failed to start gzip: %w", err)
+	if err := compressCmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", compressCmd.Args[0], err)
 	}
 	
 	if err := dumpCmd.Start(); err != nil {
@@ -697,12 +773,15 @@ func (e *Engine) executeMySQLWithProgressAndCompression(ctx context.Context, cmd
 		return fmt.Errorf("mysqldump failed: %w", err)
 	}
 	
-	// Close pipe and wait for gzip
+	// Close pipe and wait for the compressor
 	pipe.Close()
-	if err := gzipCmd.Wait(); err != nil {
-		return fmt.Errorf("gzip failed: %w", err)
+	if err := compressCmd.Wait(); err != nil {
+		return fmt.Errorf("%s failed: %w", compressCmd.Args[0], err)
 	}
 	
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("failed to finalize output file: %w", err)
+	}
 	return nil
 }
 
@@ -715,53 +794,51 @@ func (e *Engine) executeMySQLWithCompression(ctx context.Context, cmdArgs []stri
 		dumpCmd.Env = append(dumpCmd.Env, "MYSQL_PWD="+e.cfg.Password)
 	}
 	
-	// Create gzip command
-	gzipCmd := exec.CommandContext(ctx, "gzip", fmt.Sprintf("-%d", e.cfg.CompressionLevel))
+	// Create compressor command
+	compressCmd, err := e.cfg.CompressionOptions().Command(ctx)
+	if err != nil {
+		return err
+	}
 	
-	// Create output file
-	outFile, err := os.Create(outputFile)
+	// Create output file (encrypted while written if enabled)
+	outFile, err := e.createOutputFile(outputFile)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return err
 	}
 	defer outFile.Close()
 	
-	// Set up pipeline: mysqldump | gzip > outputfile
+	// Set up pipeline: mysqldump | compressor > outputfile
 	stdin, err := dumpCmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create pipe: %w", err)
 	}
-	gzipCmd.Stdin = stdin
-	gzipCmd.Stdout = outFile
+	compressCmd.Stdin = stdin
+	compressCmd.Stdout = outFile
 	
 	// Start both commands
-	if err := gzipCmd.Start(); err != nil {
-		return fmt.Errorf("failed to start gzip: %w", err)
+	if err := compressCmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", compressCmd.Args[0], err)
 	}
 	
 	if err := dumpCmd.Run(); err != nil {
 		return fmt.Errorf("mysqldump failed: %w", err)
 	}
 	
-	if err := gzipCmd.Wait(); err != nil {
-		return fmt.Errorf("gzip failed: %w", err)
+	if err := compressCmd.Wait(); err != nil {
+		return fmt.Errorf("%s failed: %w", compressCmd.Args[0], err)
 	}
 	
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("failed to finalize output file: %w", err)
+	}
 	return nil
 }
 
-// createSampleBackup creates a sample backup with reduced dataset
+// createSampleBackup creates a sample backup with reduced dataset.
+// Rows referenced through foreign keys are always included, so the
+// resulting SQL file restores without constraint violations.
 func (e *Engine) createSampleBackup(ctx context.Context, databaseName, outputFile string) error {
-	// This is a simplified implementation
-	// A full implementation would:
-	// 1. Export schema
-	// 2. Get list of tables
-	// 3. For each table, run sampling query
-	// 4. Combine into single SQL file
-	
-	// For now, we'll use a simple approach with schema-only backup first
-	// Then add sample data
-	
-	file, err := os.Create(outputFile)
+	file, err := e.createOutputFile(outputFile)
 	if err != nil {
 		return fmt.Errorf("failed to create sample backup file: %w", err)
 	}
@@ -772,47 +849,76 @@ func (e *Engine) createSampleBackup(ctx context.Context, databaseName, outputFil
 	fmt.Fprintf(file, "-- Database: %s\n", databaseName)
 	fmt.Fprintf(file, "-- Strategy: %s = %d\n", e.cfg.SampleStrategy, e.cfg.SampleValue)
 	fmt.Fprintf(file, "-- Created: %s\n", time.Now().Format(time.RFC3339))
-	fmt.Fprintf(file, "-- WARNING: This backup may have referential integrity issues!\n\n")
+	fmt.Fprintf(file, "-- Rows referenced by foreign keys are included in addition to the sample\n\n")
 	
-	// For PostgreSQL, we can use pg_dump --schema-only first
+	strategy := database.SampleStrategy{
+		Type:  e.cfg.SampleStrategy,
+		Value: e.cfg.SampleValue,
+	}
+	
+	var stats *database.SampleStats
 	if e.cfg.IsPostgreSQL() {
-		// Get schema
-		schemaCmd := e.db.BuildBackupCommand(databaseName, "/dev/stdout", database.BackupOptions{
-			SchemaOnly: true,
-			Format:     "plain",
-		})
+		// Tables first, then data, then constraints and indexes so load order
+		// doesn't matter. Sequence values follow the data.
+		if err := e.dumpSchemaSection(ctx, databaseName, "pre-data", file); err != nil {
+			return err
+		}
 		
-		cmd := exec.CommandContext(ctx, schemaCmd[0], schemaCmd[1:]...)
-		cmd.Env = os.Environ()
-		if e.cfg.Password != "" {
-			cmd.Env = append(cmd.Env, "PGPASSWORD="+e.cfg.Password)
+		fmt.Fprintf(file, "\n-- Sample data follows\n\n")
+		stats, err = e.db.WriteSample(ctx, databaseName, strategy, file)
+		if err != nil {
+			return fmt.Errorf("failed to sample data: %w", err)
 		}
-		cmd.Stdout = file
 		
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to export schema: %w", err)
+		if err := e.dumpSchemaSection(ctx, databaseName, "post-data", file); err != nil {
+			return err
+		}
+	} else {
+		// Triggers come after the data so they don't fire on the sampled rows
+		if err := e.dumpSchemaSection(ctx, databaseName, "pre-data", file); err != nil {
+			return err
 		}
 		
 		fmt.Fprintf(file, "\n-- Sample data follows\n\n")
-		
-		// Get tables and sample data
-		tables, err := e.db.ListTables(ctx, databaseName)
+		fmt.Fprintf(file, "SET FOREIGN_KEY_CHECKS=0;\n\n")
+		stats, err = e.db.WriteSample(ctx, databaseName, strategy, file)
 		if err != nil {
-			return fmt.Errorf("failed to list tables: %w", err)
-		}
-		
-		strategy := database.SampleStrategy{
-			Type:  e.cfg.SampleStrategy,
-			Value: e.cfg.SampleValue,
+			return fmt.Errorf("failed to sample data: %w", err)
 		}
+		fmt.Fprintf(file, "SET FOREIGN_KEY_CHECKS=1;\n")
 		
-		for _, table := range tables {
-			fmt.Fprintf(file, "-- Data for table: %s\n", table)
-			sampleQuery := e.db.BuildSampleQuery(databaseName, table, strategy)
-			fmt.Fprintf(file, "\\copy (%s) TO STDOUT\n\n", sampleQuery)
+		if err := e.dumpSchemaSection(ctx, databaseName, "post-data", file); err != nil {
+			return err
 		}
 	}
 	
+	e.log.Info("Sample data written",
+		"tables", stats.Tables,
+		"sampled_rows", stats.Rows,
+		"referenced_rows", stats.ParentRows)
+	
+	return file.Close()
+}
+
+// dumpSchemaSection appends the database schema (or one pg_dump section) to w
+func (e *Engine) dumpSchemaSection(ctx context.Context, databaseName, section string, w io.Writer) error {
+	schemaCmd := e.db.BuildBackupCommand(databaseName, "/dev/stdout", database.BackupOptions{
+		SchemaOnly: section == "",
+		Section:    section,
+		Format:     "plain",
+	})
+	
+	cmd := exec.CommandContext(ctx, schemaCmd[0], schemaCmd[1:]...)
+	cmd.Env = os.Environ()
+	if e.cfg.Password != "" && e.cfg.IsPostgreSQL() {
+		cmd.Env = append(cmd.Env, "PGPASSWORD="+e.cfg.Password)
+	}
+	cmd.Stdout = w
+	
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to export schema: %w", err)
+	}
+	
 	return nil
 }
 
@@ -836,71 +942,35 @@ func (e *Engine) backupGlobals(ctx context.Context, tempDir string) error {
 		return fmt.Errorf("pg_dumpall failed: %w", err)
 	}
 	
-	return os.WriteFile(globalsFile, output, 0644)
+	return e.writeOutputFile(globalsFile, output)
 }
 
-// createArchive creates a compressed tar archive
+// createArchive creates a tar archive piped through the configured compressor
+// (.tar.gz with pigz/gzip, .tar.zst or .tar.lz4)
 func (e *Engine) createArchive(ctx context.Context, sourceDir, outputFile string) error {
-	// Use pigz for faster parallel compression if available, otherwise use standard gzip
-	compressCmd := "tar"
-	compressArgs := []string{"-czf", outputFile, "-C", sourceDir, "."}
-	
-	// Check if pigz is available for faster parallel compression
-	if _, err := exec.LookPath("pigz"); err == nil {
-		// Use pigz with number of cores for parallel compression
-		compressArgs = []string{"-cf", "-", "-C", sourceDir, "."}
-		cmd := exec.CommandContext(ctx, "tar", compressArgs...)
-		
-		// Create output file
-		outFile, err := os.Create(outputFile)
-		if err != nil {
-			// Fallback to regular tar
-			goto regularTar
-		}
-		defer outFile.Close()
-		
-		// Pipe to pigz for parallel compression
-		pigzCmd := exec.CommandContext(ctx, "pigz", "-p", strconv.Itoa(e.cfg.Jobs))
-		
-		tarOut, err := cmd.StdoutPipe()
-		if err != nil {
-			outFile.Close()
-			// Fallback to regular tar
-			goto regularTar
-		}
-		pigzCmd.Stdin = tarOut
-		pigzCmd.Stdout = outFile
-		
-		// Start both commands
-		if err := pigzCmd.Start(); err != nil {
-			outFile.Close()
-			goto regularTar
-		}
-		if err := cmd.Start(); err != nil {
-			pigzCmd.Process.Kill()
-			outFile.Close()
-			goto regularTar
-		}
-		
-		// Wait for tar to finish
-		if err := cmd.Wait(); err != nil {
-			pigzCmd.Process.Kill()
-			return fmt.Errorf("tar failed: %w", err)
-		}
-		
-		// Wait for pigz to finish
-		if err := pigzCmd.Wait(); err != nil {
-			return fmt.Errorf("pigz compression failed: %w", err)
-		}
-		return nil
+	opts := e.cfg.CompressionOptions()
+	compressCmd, err := opts.Command(ctx)
+	if err != nil {
+		return err
 	}
-
-regularTar:
-	// Standard tar with gzip (fallback)
-	cmd := exec.CommandContext(ctx, compressCmd, compressArgs...)
+	e.log.Debug("Creating archive", "compression", opts.String(), "output", outputFile)
+	
+	outFile, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer outFile.Close()
+	
+	// Set up pipeline: tar | compressor > outputfile
+	cmd := exec.CommandContext(ctx, "tar", "-cf", "-", "-C", sourceDir, ".")
+	tarOut, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create tar pipe: %w", err)
+	}
+	compressCmd.Stdin = tarOut
+	compressCmd.Stdout = outFile
 	
 	// Stream stderr to avoid memory issues
-	// Use io.Copy to ensure goroutine completes when pipe closes
 	stderr, err := cmd.StderrPipe()
 	if err == nil {
 		go func() {
@@ -915,15 +985,33 @@ regularTar:
 		}()
 	}
 	
-	if err := cmd.Run(); err != nil {
+	// Start both commands
+	if err := compressCmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", compressCmd.Args[0], err)
+	}
+	if err := cmd.Start(); err != nil {
+		compressCmd.Process.Kill()
+		compressCmd.Wait()
+		return fmt.Errorf("failed to start tar: %w", err)
+	}
+	
+	// Wait for tar to finish
+	if err := cmd.Wait(); err != nil {
+		compressCmd.Process.Kill()
+		compressCmd.Wait()
 		return fmt.Errorf("tar failed: %w", err)
 	}
-	// cmd.Run() calls Wait() which closes stderr pipe, terminating the goroutine
-	return nil
+	
+	// Wait for the compressor to finish
+	if err := compressCmd.Wait(); err != nil {
+		return fmt.Errorf("%s compression failed: %w", compressCmd.Args[0], err)
+	}
+	return outFile.Close()
 }
 
-// createMetadata creates a metadata file for the backup
-func (e *Engine) createMetadata(backupFile, database, backupType, strategy string) error {
+// createMetadata creates a metadata file for the backup, recording the
+// content read alongside the dump when there is one
+func (e *Engine) createMetadata(backupFile, database, backupType, strategy string, content *dumpContent) error {
 	startTime := time.Now()
 	
 	// Get backup file information
@@ -938,41 +1026,9 @@ func (e *Engine) createMetadata(backupFile, database, backupType, strategy strin
 		return fmt.Errorf("failed to calculate checksum: %w", err)
 	}
 	
-	// Get database version
-	ctx := context.Background()
-	dbVersion, _ := e.db.GetVersion(ctx)
-	if dbVersion == "" {
-		dbVersion = "unknown"
-	}
-	
-	// Determine compression format
-	compressionFormat := "none"
-	if e.cfg.CompressionLevel > 0 {
-		if e.cfg.Jobs > 1 {
-			compressionFormat = fmt.Sprintf("pigz-%d", e.cfg.CompressionLevel)
-		} else {
-			compressionFormat = fmt.Sprintf("gzip-%d", e.cfg.CompressionLevel)
-		}
-	}
-	
-	// Create backup metadata
-	meta := &metadata.BackupMetadata{
-		Version:         "2.0",
-		Timestamp:       startTime,
-		Database:        database,
-		DatabaseType:    e.cfg.DatabaseType,
-		DatabaseVersion: dbVersion,
-		Host:            e.cfg.Host,
-		Port:            e.cfg.Port,
-		User:            e.cfg.User,
-		BackupFile:      backupFile,
-		SizeBytes:       info.Size(),
-		SHA256:          sha256,
-		Compression:     compressionFormat,
-		BackupType:      backupType,
-		Duration:        time.Since(startTime).Seconds(),
-		ExtraInfo:       make(map[string]string),
-	}
+	meta := e.newBackupMetadata(backupFile, database, backupType, info.Size(), sha256)
+	meta.Timestamp = startTime
+	meta.Duration = time.Since(startTime).Seconds()
 	
 	// Add strategy for sample backups
 	if strategy != "" {
@@ -980,6 +1036,8 @@ func (e *Engine) createMetadata(backupFile, database, backupType, strategy strin
 		meta.ExtraInfo["sample_value"] = fmt.Sprintf("%d", e.cfg.SampleValue)
 	}
 	
+	recordContent(meta, content)
+	
 	// Save metadata
 	if err := meta.Save(); err != nil {
 		return fmt.Errorf("failed to save metadata: %w", err)
@@ -1008,8 +1066,138 @@ func (e *Engine) createMetadata(backupFile, database, backupType, strategy strin
 	return nil
 }
 
-// createClusterMetadata creates metadata for cluster backups
-func (e *Engine) createClusterMetadata(backupFile string, databases []string, successCount, failCount int) error {
+// newBackupMetadata builds the metadata of a single database backup
+// whose size and checksum are already known
+func (e *Engine) newBackupMetadata(backupFile, database, backupType string, size int64, sha256 string) *metadata.BackupMetadata {
+	// Get database version
+	ctx := context.Background()
+	dbVersion, _ := e.db.GetVersion(ctx)
+	if dbVersion == "" {
+		dbVersion = "unknown"
+	}
+	
+	// Determine compression format: external compressors add their extension,
+	// custom-format dumps are compressed by pg_dump itself (zlib)
+	compressionFormat := "none"
+	if compression.FromExtension(backupFile) != compression.None {
+		compressionFormat = e.cfg.CompressionOptions().String()
+	} else if strings.HasSuffix(backupFile, ".dump") && e.cfg.CompressionLevel > 0 {
+		compressionFormat = fmt.Sprintf("gzip-%d", e.cfg.CompressionLevel)
+	}
+	
+	// Create backup metadata
+	meta := &metadata.BackupMetadata{
+		Version:         "2.0",
+		Timestamp:       time.Now(),
+		Database:        database,
+		DatabaseType:    e.cfg.DatabaseType,
+		DatabaseVersion: dbVersion,
+		Host:            e.cfg.Host,
+		Port:            e.cfg.Port,
+		User:            e.cfg.User,
+		BackupFile:      backupFile,
+		SizeBytes:       size,
+		SHA256:          sha256,
+		Compression:     compressionFormat,
+		BackupType:      backupType,
+		ExtraInfo:       make(map[string]string),
+	}
+	
+	e.setEncryptionMetadata(meta)
+	return meta
+}
+
+// contentCapture reads a database's content while it is dumped
+type contentCapture struct {
+	database string
+	snapshot *database.Snapshot
+	cancel   context.CancelFunc
+	done     chan struct{}
+	schema   *database.SchemaInfo
+	err      error
+}
+
+// dumpContent is a database's content as read alongside its dump
+type dumpContent struct {
+	schema *database.SchemaInfo
+	exact  bool // read in the snapshot the dump itself used
+}
+
+// captureContent begins a snapshot of a database right before its dump and
+// reads the content in it in the background. On PostgreSQL the dump shares
+// the snapshot through options, so the recorded row counts are exactly those
+// dumped; mysqldump --single-transaction takes its own right after, so there
+// they are only close.
+func (e *Engine) captureContent(ctx context.Context, name string, options *database.BackupOptions) *contentCapture {
+	c := &contentCapture{database: name, done: make(chan struct{})}
+	snapshot, err := e.db.BeginSnapshot(ctx, name)
+	if err != nil {
+		c.cancel = func() {}
+		c.err = err
+		close(c.done)
+		return c
+	}
+	c.snapshot = snapshot
+	options.Snapshot = snapshot.ID
+	
+	ctx, c.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(c.done)
+		c.schema, c.err = snapshot.SchemaInfo(ctx)
+	}()
+	return c
+}
+
+// release stops reading the content and ends the snapshot. It is a no-op
+// once waitContent has returned.
+func (c *contentCapture) release() {
+	c.cancel()
+	<-c.done
+	if c.snapshot != nil {
+		c.snapshot.Close()
+		c.snapshot = nil
+	}
+}
+
+// waitContent returns the content once it is read and ends the snapshot, so it
+// must only be called when the dump is done. Failing to read the content only
+// logs a warning.
+func (e *Engine) waitContent(c *contentCapture) *dumpContent {
+	<-c.done
+	exact := c.snapshot != nil && c.snapshot.ID != ""
+	c.release()
+	if c.err != nil {
+		e.log.Warn("Failed to record database content in metadata", "database", c.database, "error", c.err)
+		return nil
+	}
+	return &dumpContent{schema: c.schema, exact: exact}
+}
+
+// recordContent adds a database's tables with row counts and sizes, its
+// schema fingerprint, extensions and sequences to its metadata
+func recordContent(meta *metadata.BackupMetadata, content *dumpContent) {
+	if content == nil {
+		return
+	}
+	
+	schema := content.schema
+	meta.RowsExact = content.exact
+	for _, t := range schema.Tables {
+		meta.Tables = append(meta.Tables, metadata.TableStats{
+			Name:        t.FullName(),
+			Rows:        t.RowCount,
+			SizeBytes:   t.Size,
+			Fingerprint: t.Fingerprint(),
+		})
+	}
+	meta.SchemaFingerprint = schema.Fingerprint()
+	meta.Extensions = schema.Extensions
+	meta.Sequences = schema.Sequences
+}
+
+// createClusterMetadata creates metadata for cluster backups, with the
+// content read alongside each database's dump
+func (e *Engine) createClusterMetadata(backupFile string, databases []string, contents map[string]*dumpContent, successCount, failCount int) error {
 	startTime := time.Now()
 	
 	// Get backup file information
@@ -1048,6 +1236,7 @@ func (e *Engine) createClusterMetadata(backupFile string, databases []string, su
 			"failure_count":    fmt.Sprintf("%d", failCount),
 			"archive_sha256":   sha256,
 			"database_version": dbVersion,
+			"compression":      e.cfg.CompressionOptions().String(),
 		},
 	}
 	
@@ -1059,6 +1248,8 @@ func (e *Engine) createClusterMetadata(backupFile string, databases []string, su
 			DatabaseVersion: dbVersion,
 			Timestamp:       startTime,
 		}
+		e.setEncryptionMetadata(&dbMeta)
+		recordContent(&dbMeta, contents[dbName])
 		clusterMeta.Databases = append(clusterMeta.Databases, dbMeta)
 	}
 	
@@ -1093,72 +1284,41 @@ func (e *Engine) createClusterMetadata(backupFile string, databases []string, su
 	return nil
 }
 
-// uploadToCloud uploads a backup file to cloud storage
-func (e *Engine) uploadToCloud(ctx context.Context, backupFile string, tracker *progress.OperationTracker) error {
-	uploadStep := tracker.AddStep("cloud_upload", "Uploading to cloud storage")
-	
-	// Create cloud backend
-	cloudCfg := &cloud.Config{
-		Provider:   e.cfg.CloudProvider,
-		Bucket:     e.cfg.CloudBucket,
-		Region:     e.cfg.CloudRegion,
-		Endpoint:   e.cfg.CloudEndpoint,
-		AccessKey:  e.cfg.CloudAccessKey,
-		SecretKey:  e.cfg.CloudSecretKey,
-		Prefix:     e.cfg.CloudPrefix,
-		UseSSL:     true,
-		PathStyle:  e.cfg.CloudProvider == "minio",
-		Timeout:    300,
-		MaxRetries: 3,
-	}
-	
-	backend, err := cloud.NewBackend(cloudCfg)
-	if err != nil {
-		uploadStep.Fail(fmt.Errorf("failed to create cloud backend: %w", err))
-		return err
-	}
-	
-	// Get file info
-	info, err := os.Stat(backupFile)
-	if err != nil {
-		uploadStep.Fail(fmt.Errorf("failed to stat backup file: %w", err))
-		return err
-	}
-	
-	filename := filepath.Base(backupFile)
-	e.log.Info("Uploading backup to cloud", "file", filename, "size", cloud.FormatSize(info.Size()))
-	
-	// Progress callback
-	var lastPercent int
-	progressCallback := func(transferred, total int64) {
-		percent := int(float64(transferred) / float64(total) * 100)
-		if percent != lastPercent && percent%10 == 0 {
-			e.log.Debug("Upload progress", "percent", percent, "transferred", cloud.FormatSize(transferred), "total", cloud.FormatSize(total))
-			lastPercent = percent
-		}
-	}
-	
-	// Upload to cloud
-	err = backend.Upload(ctx, backupFile, filename, progressCallback)
+// newCloudBackend creates the cloud backend configured for uploads, applying
+// lock to everything it uploads
+func (e *Engine) newCloudBackend(lock *cloud.ObjectLock) (cloud.Backend, error) {
+	cfg := e.primaryCloudConfig()
+	cfg.Lock = lock
+	return cloud.NewBackend(cfg)
+}
+
+// objectLock returns the object lock configured for uploads started at now,
+// or nil if uploads aren't locked
+func (e *Engine) objectLock(now time.Time) (*cloud.ObjectLock, error) {
+	lock, err := cloud.NewObjectLock(e.cfg.CloudLockMode, e.cfg.CloudLockUntil, e.cfg.CloudLegalHold, now)
 	if err != nil {
-		uploadStep.Fail(fmt.Errorf("cloud upload failed: %w", err))
-		return err
+		return nil, fmt.Errorf("invalid object lock: %w", err)
 	}
-	
-	// Also upload metadata file
-	metaFile := backupFile + ".meta.json"
-	if _, err := os.Stat(metaFile); err == nil {
-		metaFilename := filepath.Base(metaFile)
-		if err := backend.Upload(ctx, metaFile, metaFilename, nil); err != nil {
-			e.log.Warn("Failed to upload metadata file", "error", err)
-			// Don't fail if metadata upload fails
-		}
+	return lock, nil
+}
+
+// primaryCloudConfig returns the cloud storage configured with --cloud or
+// the individual cloud flags
+func (e *Engine) primaryCloudConfig() *cloud.Config {
+	return &cloud.Config{
+		Provider:     e.cfg.CloudProvider,
+		Bucket:       e.cfg.CloudBucket,
+		Region:       e.cfg.CloudRegion,
+		Endpoint:     e.cfg.CloudEndpoint,
+		AccessKey:    e.cfg.CloudAccessKey,
+		SecretKey:    e.cfg.CloudSecretKey,
+		Prefix:       e.cfg.CloudPrefix,
+		UseSSL:       true,
+		PathStyle:    e.cfg.CloudProvider == "minio",
+		StorageClass: e.cfg.CloudStorageClass,
+		Timeout:      300,
+		MaxRetries:   3,
 	}
-	
-	uploadStep.Complete(fmt.Sprintf("Uploaded to %s/%s/%s", backend.Name(), e.cfg.CloudBucket, filename))
-	e.log.Info("Backup uploaded to cloud", "provider", backend.Name(), "bucket", e.cfg.CloudBucket, "file", filename)
-	
-	return nil
 }
 
 // executeCommand executes a backup command (optimized for huge databases)
@@ -1200,7 +1360,7 @@ func (e *Engine) executeCommand(ctx context.Context, cmdArgs []string, outputFil
 		"output_file", outputFile)
 	
 	// For MySQL, handle compression differently
-	if e.cfg.IsMySQL() && e.cfg.CompressionLevel > 0 {
+	if e.cfg.IsMySQL() && e.mysqlDumpExtension() != "" {
 		return e.executeMySQLWithCompression(ctx, cmdArgs, outputFile)
 	}
 	
@@ -1224,6 +1384,21 @@ func (e *Engine) executeCommand(ctx context.Context, cmdArgs []string, outputFil
 		}
 	}
 	
+	// With encryption, pg_dump writes to stdout and the dump is encrypted on its way to disk
+	var outFile io.WriteCloser
+	if e.encryption != nil {
+		args, err := dumpToStdout(cmdArgs)
+		if err != nil {
+			return err
+		}
+		if outFile, err = e.createOutputFile(outputFile); err != nil {
+			return err
+		}
+		defer outFile.Close()
+		cmd.Args = args
+		cmd.Stdout = outFile
+	}
+	
 	// Stream stderr to avoid memory issues with large databases
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
@@ -1253,25 +1428,46 @@ func (e *Engine) executeCommand(ctx context.Context, cmdArgs []string, outputFil
 		return fmt.Errorf("backup command failed: %w", err)
 	}
 	
+	if outFile != nil {
+		if err := outFile.Close(); err != nil {
+			return fmt.Errorf("failed to finalize output file: %w", err)
+		}
+	}
 	return nil
 }
 
+// dumpToStdout removes --file from a pg_dump command so it writes to stdout.
+// Directory format can't be streamed and is rejected.
+func dumpToStdout(cmdArgs []string) ([]string, error) {
+	args := make([]string, 0, len(cmdArgs))
+	for _, arg := range cmdArgs {
+		if arg == "--format=directory" || arg == "-Fd" {
+			return nil, fmt.Errorf("directory format dumps cannot be encrypted while streaming")
+		}
+		if strings.HasPrefix(arg, "--file=") {
+			continue
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
 // executeWithStreamingCompression handles plain format dumps with external compression
-// Uses: pg_dump | pigz > file.sql.gz (zero-copy streaming)
+// Uses: pg_dump | pigz/zstd/lz4 > file.sql.gz|.zst|.lz4 (zero-copy streaming)
 func (e *Engine) executeWithStreamingCompression(ctx context.Context, cmdArgs []string, outputFile string) error {
 	e.log.Debug("Using streaming compression for large database")
 	
+	opts := e.cfg.CompressionOptions()
+	
 	// Derive compressed output filename. If the output was named *.dump we replace that
-	// with *.sql.gz; otherwise append .gz to the provided output file so we don't
-	// accidentally create unwanted double extensions.
+	// with *.sql plus the compressor's extension; otherwise append the extension to the
+	// provided output file so we don't accidentally create unwanted double extensions.
 	var compressedFile string
 	lowerOut := strings.ToLower(outputFile)
 	if strings.HasSuffix(lowerOut, ".dump") {
-		compressedFile = strings.TrimSuffix(outputFile, ".dump") + ".sql.gz"
-	} else if strings.HasSuffix(lowerOut, ".sql") {
-		compressedFile = outputFile + ".gz"
+		compressedFile = strings.TrimSuffix(outputFile, ".dump") + ".sql" + opts.Algorithm.Extension()
 	} else {
-		compressedFile = outputFile + ".gz"
+		compressedFile = outputFile + opts.Algorithm.Extension()
 	}
 	
 	// Create pg_dump command
@@ -1281,27 +1477,21 @@ func (e *Engine) executeWithStreamingCompression(ctx context.Context, cmdArgs []
 		dumpCmd.Env = append(dumpCmd.Env, "PGPASSWORD="+e.cfg.Password)
 	}
 	
-	// Check for pigz (parallel gzip)
-	compressor := "gzip"
-	compressorArgs := []string{"-c"}
-	
-	if _, err := exec.LookPath("pigz"); err == nil {
-		compressor = "pigz"
-		compressorArgs = []string{"-p", strconv.Itoa(e.cfg.Jobs), "-c"}
-		e.log.Debug("Using pigz for parallel compression", "threads", e.cfg.Jobs)
+	// Create compression command (pigz when available, zstd -T for threads)
+	compressCmd, err := opts.Command(ctx)
+	if err != nil {
+		return err
 	}
+	e.log.Debug("Using external compression", "compressor", compressCmd.Args[0], "compression", opts.String(), "threads", opts.Threads)
 	
-	// Create compression command
-	compressCmd := exec.CommandContext(ctx, compressor, compressorArgs...)
-	
-	// Create output file
-	outFile, err := os.Create(compressedFile)
+	// Create output file (encrypted while written if enabled)
+	outFile, err := e.createOutputFile(compressedFile)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return err
 	}
 	defer outFile.Close()
 	
-	// Set up pipeline: pg_dump | pigz > file.sql.gz
+	// Set up pipeline: pg_dump | compressor > file
 	dumpStdout, err := dumpCmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create dump stdout pipe: %w", err)
@@ -1368,6 +1558,10 @@ func (e *Engine) executeWithStreamingCompression(ctx context.Context, cmdArgs []
 		return fmt.Errorf("compression failed: %w", err)
 	}
 	
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("failed to finalize output file: %w", err)
+	}
+	
 	e.log.Debug("Streaming compression completed", "output", compressedFile)
 	return nil
 }
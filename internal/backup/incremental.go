@@ -3,6 +3,9 @@ package backup
 import (
 	"context"
 	"time"
+
+	"dbbackup/internal/crypto"
+	"dbbackup/internal/encryption"
 )
 
 // BackupType represents the type of backup
@@ -66,6 +69,14 @@ type IncrementalBackupConfig struct {
 	
 	// CompressionLevel for the incremental archive (0-9)
 	CompressionLevel int
+	
+	// OutputDir is where the incremental archive is written (defaults to the base backup's directory)
+	OutputDir string
+	
+	// Encryption, when set, encrypts the archive as it is written. Envelope
+	// records how its data key is wrapped (nil when the key is used directly).
+	Encryption *encryption.EncryptionOptions
+	Envelope   *crypto.EncryptionMetadata
 }
 
 // BackupChainResolver resolves the chain of backups needed for restore
@@ -86,8 +97,8 @@ type IncrementalBackupEngine interface {
 	// FindChangedFiles identifies files changed since the base backup
 	FindChangedFiles(ctx context.Context, config *IncrementalBackupConfig) ([]ChangedFile, error)
 	
-	// CreateIncrementalBackup creates a new incremental backup
-	CreateIncrementalBackup(ctx context.Context, config *IncrementalBackupConfig, changedFiles []ChangedFile) error
+	// CreateIncrementalBackup creates a new incremental backup and returns the archive path
+	CreateIncrementalBackup(ctx context.Context, config *IncrementalBackupConfig, changedFiles []ChangedFile) (string, error)
 	
 	// RestoreIncremental restores an incremental backup on top of a base backup
 	RestoreIncremental(ctx context.Context, baseBackupPath, incrementalPath, targetDir string) error
@@ -102,6 +113,9 @@ type BackupInfo struct {
 	Size      int64     `json:"size"`
 	Checksum  string    `json:"checksum"`
 	
+	// Path is the local archive path or the remote object name (cloud resolvers)
+	Path string `json:"path,omitempty"`
+	
 	// New fields for incremental support
 	BackupType BackupType           `json:"backup_type"`           // "full" or "incremental"
 	Incremental *IncrementalMetadata `json:"incremental,omitempty"` // Only present for incremental backups
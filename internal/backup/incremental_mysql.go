@@ -15,6 +15,7 @@ import (
 
 	"dbbackup/internal/logger"
 	"dbbackup/internal/metadata"
+	"dbbackup/internal/security"
 )
 
 // MySQLIncrementalEngine implements incremental backups for MySQL/MariaDB
@@ -42,9 +43,9 @@ func (e *MySQLIncrementalEngine) FindChangedFiles(ctx context.Context, config *I
 		return nil, fmt.Errorf("failed to load base backup info: %w", err)
 	}
 
-	// Validate base backup is full backup  
-	if baseInfo.BackupType != "" && baseInfo.BackupType != "full" {
-		return nil, fmt.Errorf("base backup must be a full backup, got: %s", baseInfo.BackupType)
+	// Base may be a full backup or a previous incremental (chained incrementals)
+	if baseInfo.BackupType != "" && baseInfo.BackupType != "full" && baseInfo.BackupType != "incremental" {
+		return nil, fmt.Errorf("base backup must be a full or incremental backup, got: %s", baseInfo.BackupType)
 	}
 
 	baseTimestamp := baseInfo.Timestamp
@@ -180,45 +181,54 @@ func (e *MySQLIncrementalEngine) loadBackupInfo(backupPath string) (*metadata.Ba
 	return meta, nil
 }
 
-// CreateIncrementalBackup creates a new incremental backup archive for MySQL
-func (e *MySQLIncrementalEngine) CreateIncrementalBackup(ctx context.Context, config *IncrementalBackupConfig, changedFiles []ChangedFile) error {
+// CreateIncrementalBackup creates a new incremental backup archive for MySQL and returns its path
+func (e *MySQLIncrementalEngine) CreateIncrementalBackup(ctx context.Context, config *IncrementalBackupConfig, changedFiles []ChangedFile) (string, error) {
 	e.log.Info("Creating incremental backup (MySQL)",
 		"changed_files", len(changedFiles),
 		"base_backup", config.BaseBackupPath)
 
 	if len(changedFiles) == 0 {
 		e.log.Info("No changed files detected - skipping incremental backup")
-		return fmt.Errorf("no changed files since base backup")
+		return "", fmt.Errorf("no changed files since base backup")
 	}
 
 	// Load base backup metadata
 	baseInfo, err := e.loadBackupInfo(config.BaseBackupPath)
 	if err != nil {
-		return fmt.Errorf("failed to load base backup info: %w", err)
+		return "", fmt.Errorf("failed to load base backup info: %w", err)
 	}
 
 	// Generate output filename: dbname_incr_TIMESTAMP.tar.gz
 	timestamp := time.Now().Format("20060102_150405")
-	outputFile := filepath.Join(filepath.Dir(config.BaseBackupPath), 
+	outputDir := config.OutputDir
+	if outputDir == "" {
+		outputDir = filepath.Dir(config.BaseBackupPath)
+	}
+	outputFile := filepath.Join(outputDir, 
 		fmt.Sprintf("%s_incr_%s.tar.gz", baseInfo.Database, timestamp))
 
 	e.log.Info("Creating incremental archive", "output", outputFile)
 
 	// Create tar.gz archive with changed files
 	if err := e.createTarGz(ctx, outputFile, changedFiles, config); err != nil {
-		return fmt.Errorf("failed to create archive: %w", err)
+		return "", fmt.Errorf("failed to create archive: %w", err)
 	}
 
 	// Calculate checksum
 	checksum, err := e.CalculateFileChecksum(outputFile)
 	if err != nil {
-		return fmt.Errorf("failed to calculate checksum: %w", err)
+		return "", fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+
+	// Save checksum sidecar
+	if err := security.SaveChecksum(outputFile, checksum); err != nil {
+		return "", fmt.Errorf("failed to save checksum: %w", err)
 	}
 
 	// Get archive size
 	stat, err := os.Stat(outputFile)
 	if err != nil {
-		return fmt.Errorf("failed to stat archive: %w", err)
+		return "", fmt.Errorf("failed to stat archive: %w", err)
 	}
 
 	// Calculate total size of changed files
@@ -252,9 +262,13 @@ func (e *MySQLIncrementalEngine) CreateIncrementalBackup(ctx context.Context, co
 		},
 	}
 
+	if config.Encryption != nil {
+		markStreamEncrypted(metadata, config.Envelope)
+	}
+
 	// Save metadata
 	if err := metadata.Save(); err != nil {
-		return fmt.Errorf("failed to save metadata: %w", err)
+		return "", fmt.Errorf("failed to save metadata: %w", err)
 	}
 
 	e.log.Info("Incremental backup created successfully (MySQL)",
@@ -263,7 +277,7 @@ func (e *MySQLIncrementalEngine) CreateIncrementalBackup(ctx context.Context, co
 		"changed_files", len(changedFiles),
 		"checksum", checksum[:16]+"...")
 
-	return nil
+	return outputFile, nil
 }
 
 // RestoreIncremental restores a MySQL incremental backup on top of a base
@@ -307,8 +321,9 @@ func (e *MySQLIncrementalEngine) RestoreIncremental(ctx context.Context, baseBac
 		return fmt.Errorf("failed to load base backup metadata: %w", err)
 	}
 
-	if baseInfo.BackupType != "full" && baseInfo.BackupType != "" {
-		return fmt.Errorf("base backup is not a full backup (type: %s)", baseInfo.BackupType)
+	// Chained restores pass the previous incremental as base
+	if baseInfo.BackupType != "full" && baseInfo.BackupType != "incremental" && baseInfo.BackupType != "" {
+		return fmt.Errorf("base backup is not a full or incremental backup (type: %s)", baseInfo.BackupType)
 	}
 
 	// Verify checksums match
@@ -367,10 +382,10 @@ func (e *MySQLIncrementalEngine) CalculateFileChecksum(path string) (string, err
 
 // createTarGz creates a tar.gz archive with the specified changed files
 func (e *MySQLIncrementalEngine) createTarGz(ctx context.Context, outputFile string, changedFiles []ChangedFile, config *IncrementalBackupConfig) error {
-	// Import needed for tar/gzip
-	outFile, err := os.Create(outputFile)
+	// Create output file, encrypted as it is written when configured
+	outFile, err := createOutputFile(outputFile, config.Encryption)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return err
 	}
 	defer outFile.Close()
 
@@ -403,7 +418,14 @@ func (e *MySQLIncrementalEngine) createTarGz(ctx context.Context, outputFile str
 		}
 	}
 
-	return nil
+	// Each layer flushes into the next: tar, gzip, then encryption
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish compression: %w", err)
+	}
+	return outFile.Close()
 }
 
 // addFileToTar adds a single file to the tar archive
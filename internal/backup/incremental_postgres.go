@@ -13,6 +13,7 @@ import (
 
 	"dbbackup/internal/logger"
 	"dbbackup/internal/metadata"
+	"dbbackup/internal/security"
 )
 
 // PostgresIncrementalEngine implements incremental backups for PostgreSQL
@@ -40,9 +41,9 @@ func (e *PostgresIncrementalEngine) FindChangedFiles(ctx context.Context, config
 		return nil, fmt.Errorf("failed to load base backup info: %w", err)
 	}
 
-	// Validate base backup is full backup  
-	if baseInfo.BackupType != "" && baseInfo.BackupType != "full" {
-		return nil, fmt.Errorf("base backup must be a full backup, got: %s", baseInfo.BackupType)
+	// Base may be a full backup or a previous incremental (chained incrementals)
+	if baseInfo.BackupType != "" && baseInfo.BackupType != "full" && baseInfo.BackupType != "incremental" {
+		return nil, fmt.Errorf("base backup must be a full or incremental backup, got: %s", baseInfo.BackupType)
 	}
 
 	baseTimestamp := baseInfo.Timestamp
@@ -141,45 +142,54 @@ func (e *PostgresIncrementalEngine) loadBackupInfo(backupPath string) (*metadata
 	return meta, nil
 }
 
-// CreateIncrementalBackup creates a new incremental backup archive
-func (e *PostgresIncrementalEngine) CreateIncrementalBackup(ctx context.Context, config *IncrementalBackupConfig, changedFiles []ChangedFile) error {
+// CreateIncrementalBackup creates a new incremental backup archive and returns its path
+func (e *PostgresIncrementalEngine) CreateIncrementalBackup(ctx context.Context, config *IncrementalBackupConfig, changedFiles []ChangedFile) (string, error) {
 	e.log.Info("Creating incremental backup",
 		"changed_files", len(changedFiles),
 		"base_backup", config.BaseBackupPath)
 
 	if len(changedFiles) == 0 {
 		e.log.Info("No changed files detected - skipping incremental backup")
-		return fmt.Errorf("no changed files since base backup")
+		return "", fmt.Errorf("no changed files since base backup")
 	}
 
 	// Load base backup metadata
 	baseInfo, err := e.loadBackupInfo(config.BaseBackupPath)
 	if err != nil {
-		return fmt.Errorf("failed to load base backup info: %w", err)
+		return "", fmt.Errorf("failed to load base backup info: %w", err)
 	}
 
 	// Generate output filename: dbname_incr_TIMESTAMP.tar.gz
 	timestamp := time.Now().Format("20060102_150405")
-	outputFile := filepath.Join(filepath.Dir(config.BaseBackupPath), 
+	outputDir := config.OutputDir
+	if outputDir == "" {
+		outputDir = filepath.Dir(config.BaseBackupPath)
+	}
+	outputFile := filepath.Join(outputDir, 
 		fmt.Sprintf("%s_incr_%s.tar.gz", baseInfo.Database, timestamp))
 
 	e.log.Info("Creating incremental archive", "output", outputFile)
 
 	// Create tar.gz archive with changed files
 	if err := e.createTarGz(ctx, outputFile, changedFiles, config); err != nil {
-		return fmt.Errorf("failed to create archive: %w", err)
+		return "", fmt.Errorf("failed to create archive: %w", err)
 	}
 
 	// Calculate checksum
 	checksum, err := e.CalculateFileChecksum(outputFile)
 	if err != nil {
-		return fmt.Errorf("failed to calculate checksum: %w", err)
+		return "", fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+
+	// Save checksum sidecar
+	if err := security.SaveChecksum(outputFile, checksum); err != nil {
+		return "", fmt.Errorf("failed to save checksum: %w", err)
 	}
 
 	// Get archive size
 	stat, err := os.Stat(outputFile)
 	if err != nil {
-		return fmt.Errorf("failed to stat archive: %w", err)
+		return "", fmt.Errorf("failed to stat archive: %w", err)
 	}
 
 	// Calculate total size of changed files
@@ -213,9 +223,13 @@ func (e *PostgresIncrementalEngine) CreateIncrementalBackup(ctx context.Context,
 		},
 	}
 
+	if config.Encryption != nil {
+		markStreamEncrypted(metadata, config.Envelope)
+	}
+
 	// Save metadata
 	if err := metadata.Save(); err != nil {
-		return fmt.Errorf("failed to save metadata: %w", err)
+		return "", fmt.Errorf("failed to save metadata: %w", err)
 	}
 
 	e.log.Info("Incremental backup created successfully",
@@ -224,7 +238,7 @@ func (e *PostgresIncrementalEngine) CreateIncrementalBackup(ctx context.Context,
 		"changed_files", len(changedFiles),
 		"checksum", checksum[:16]+"...")
 
-	return nil
+	return outputFile, nil
 }
 
 // RestoreIncremental restores an incremental backup on top of a base
@@ -268,8 +282,9 @@ func (e *PostgresIncrementalEngine) RestoreIncremental(ctx context.Context, base
 		return fmt.Errorf("failed to load base backup metadata: %w", err)
 	}
 
-	if baseInfo.BackupType != "full" && baseInfo.BackupType != "" {
-		return fmt.Errorf("base backup is not a full backup (type: %s)", baseInfo.BackupType)
+	// Chained restores pass the previous incremental as base
+	if baseInfo.BackupType != "full" && baseInfo.BackupType != "incremental" && baseInfo.BackupType != "" {
+		return fmt.Errorf("base backup is not a full or incremental backup (type: %s)", baseInfo.BackupType)
 	}
 
 	// Verify checksums match
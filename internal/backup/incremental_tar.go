@@ -11,10 +11,10 @@ import (
 
 // createTarGz creates a tar.gz archive with the specified changed files
 func (e *PostgresIncrementalEngine) createTarGz(ctx context.Context, outputFile string, changedFiles []ChangedFile, config *IncrementalBackupConfig) error {
-	// Create output file
-	outFile, err := os.Create(outputFile)
+	// Create output file, encrypted as it is written when configured
+	outFile, err := createOutputFile(outputFile, config.Encryption)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return err
 	}
 	defer outFile.Close()
 
@@ -47,7 +47,14 @@ func (e *PostgresIncrementalEngine) createTarGz(ctx context.Context, outputFile
 		}
 	}
 
-	return nil
+	// Each layer flushes into the next: tar, gzip, then encryption
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish compression: %w", err)
+	}
+	return outFile.Close()
 }
 
 // addFileToTar adds a single file to the tar archive
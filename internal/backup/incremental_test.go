@@ -1,6 +1,7 @@
 package backup
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -8,7 +9,11 @@ import (
 	"testing"
 	"time"
 
+	"dbbackup/internal/crypto"
+	"dbbackup/internal/encryption"
 	"dbbackup/internal/logger"
+	"dbbackup/internal/metadata"
+	"dbbackup/internal/security"
 )
 
 // TestIncrementalBackupRestore tests the full incremental backup workflow
@@ -180,7 +185,7 @@ func TestIncrementalBackupRestore(t *testing.T) {
 
 	// Step 5: Create incremental backup
 	t.Log("Step 5: Creating incremental backup...")
-	if err := engine.CreateIncrementalBackup(ctx, incrConfig, changedFilesList); err != nil {
+	if _, err := engine.CreateIncrementalBackup(ctx, incrConfig, changedFilesList); err != nil {
 		t.Fatalf("Failed to create incremental backup: %v", err)
 	}
 
@@ -245,6 +250,73 @@ func TestIncrementalBackupRestore(t *testing.T) {
 	t.Log("✅ Incremental backup and restore test completed successfully")
 }
 
+// TestIncrementalBackupEncrypted checks that an incremental encrypted while it
+// is written records the checksum and size of the encrypted archive
+func TestIncrementalBackupEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	engine := &PostgresIncrementalEngine{log: logger.NewSilent()}
+	ctx := context.Background()
+
+	dataFile := filepath.Join(dir, "pgdata", "base", "12345", "1234")
+	if err := os.MkdirAll(filepath.Dir(dataFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dataFile, []byte("changed table data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(dataFile)
+
+	basePath := filepath.Join(dir, "testdb_base.tar.gz")
+	os.WriteFile(basePath, []byte("base"), 0644)
+	if err := saveTestMetadata(basePath, createTestMetadata("testdb", basePath, 4, "basechecksum", "full", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	key := bytes.Repeat([]byte{7}, crypto.KeySize)
+	config := &IncrementalBackupConfig{
+		BaseBackupPath:   basePath,
+		DataDirectory:    filepath.Join(dir, "pgdata"),
+		CompressionLevel: 6,
+		Encryption:       &encryption.EncryptionOptions{Key: key},
+	}
+	changed := []ChangedFile{{RelativePath: "base/12345/1234", AbsolutePath: dataFile, Size: info.Size(), ModTime: info.ModTime()}}
+	incrPath, err := engine.CreateIncrementalBackup(ctx, config, changed)
+	if err != nil {
+		t.Fatalf("Failed to create encrypted incremental: %v", err)
+	}
+
+	checksum, err := security.ChecksumFile(incrPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sidecar, err := security.LoadChecksum(incrPath); err != nil || sidecar != checksum {
+		t.Errorf("Checksum sidecar %s doesn't match the archive %s (err %v)", sidecar, checksum, err)
+	}
+	meta, err := metadata.Load(incrPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, _ := os.Stat(incrPath)
+	if meta.SHA256 != checksum || meta.SizeBytes != stat.Size() {
+		t.Errorf("Metadata records %s (%d bytes), archive is %s (%d bytes)", meta.SHA256, meta.SizeBytes, checksum, stat.Size())
+	}
+	if !meta.Encrypted || !IsStreamEncrypted(incrPath) {
+		t.Errorf("Expected the incremental to be marked stream encrypted: %+v", meta)
+	}
+
+	plainPath := filepath.Join(dir, "plain.tar.gz")
+	if err := DecryptStreamFile(incrPath, plainPath, encryption.EncryptionOptions{Key: key}); err != nil {
+		t.Fatalf("Failed to decrypt incremental: %v", err)
+	}
+	restoreDir := filepath.Join(dir, "restore")
+	if err := engine.extractTarGz(ctx, plainPath, restoreDir); err != nil {
+		t.Fatalf("Failed to extract decrypted incremental: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(restoreDir, "base", "12345", "1234")); err != nil || string(data) != "changed table data" {
+		t.Errorf("Unexpected restored data %q (err %v)", data, err)
+	}
+}
+
 // TestIncrementalBackupErrors tests error handling
 func TestIncrementalBackupErrors(t *testing.T) {
 	log := logger.New("info", "text")
@@ -285,7 +357,7 @@ func TestIncrementalBackupErrors(t *testing.T) {
 		}
 
 		// This should find no changed files (empty directory)
-		err := engine.CreateIncrementalBackup(ctx, config, []ChangedFile{})
+		_, err := engine.CreateIncrementalBackup(ctx, config, []ChangedFile{})
 		if err == nil {
 			t.Error("Expected error for no changed files, got nil")
 		}
@@ -0,0 +1,368 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"dbbackup/internal/cloud"
+	"dbbackup/internal/metadata"
+	"dbbackup/internal/progress"
+)
+
+// replicaRetryDelay is the wait before the first retry of a failed upload.
+// It doubles with every further retry, up to replicaMaxRetryDelay.
+var (
+	replicaRetryDelay    = 2 * time.Second
+	replicaMaxRetryDelay = time.Minute
+)
+
+// primaryTargetName names the destination configured with --cloud or the
+// individual cloud flags in the replication record
+const primaryTargetName = "primary"
+
+// replicationTarget is a destination a finished backup is uploaded to
+type replicationTarget struct {
+	name    string
+	cfg     *cloud.Config
+	retries int
+	err     error // set when the target is misconfigured
+}
+
+// replicationTargets returns the primary cloud storage (when auto-upload is
+// enabled) followed by the named targets
+func (e *Engine) replicationTargets() []replicationTarget {
+	var targets []replicationTarget
+	if e.cfg.CloudEnabled && e.cfg.CloudAutoUpload && e.cfg.CloudBucket != "" {
+		targets = append(targets, replicationTarget{
+			name:    primaryTargetName,
+			cfg:     e.primaryCloudConfig(),
+			retries: e.cfg.MaxRetries,
+		})
+	}
+
+	// Request limits a target doesn't set are those of the primary storage
+	primary := e.primaryCloudConfig()
+	for _, t := range e.cfg.CloudTargets {
+		t = t.WithEnvironment()
+		target := replicationTarget{name: t.Name, retries: t.Retries}
+		if target.retries == 0 {
+			target.retries = e.cfg.MaxRetries
+		}
+
+		uri, err := cloud.ParseCloudURI(t.URI)
+		if err != nil {
+			target.err = fmt.Errorf("invalid URI: %w", err)
+			targets = append(targets, target)
+			continue
+		}
+
+		// A target URI always names a directory, with or without a trailing slash
+		cfg := uri.ToConfig()
+		cfg.Prefix = strings.Trim(uri.Path, "/")
+		if t.Region != "" {
+			cfg.Region = t.Region
+		}
+		if t.Endpoint != "" {
+			cfg.Endpoint = t.Endpoint
+		}
+		if t.AccessKey != "" {
+			cfg.AccessKey = t.AccessKey
+		}
+		if t.SecretKey != "" {
+			cfg.SecretKey = t.SecretKey
+		}
+		cfg.StorageClass = t.StorageClass
+		cfg.UseSSL = t.UseSSL()
+		cfg.Timeout = t.Timeout
+		if cfg.Timeout == 0 {
+			cfg.Timeout = primary.Timeout
+		}
+		if cfg.MaxRetries == 0 {
+			cfg.MaxRetries = primary.MaxRetries
+		}
+		target.cfg = cfg
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// uploadToCloud uploads a backup file and its metadata to every replication
+// target concurrently, retrying each target on its own. The outcome for each
+// target is recorded in the backup's .meta.json. A backup that reached only
+// some targets is reported as degraded; an error is returned only when no
+// target has a copy.
+func (e *Engine) uploadToCloud(ctx context.Context, backupFile string, tracker *progress.OperationTracker) error {
+	targets := e.replicationTargets()
+	if len(targets) == 0 {
+		return nil
+	}
+
+	// Every copy gets the same retain-until date
+	lock, err := e.objectLock(time.Now())
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if target.cfg != nil {
+			target.cfg.Lock = lock
+		}
+	}
+
+	info, err := os.Stat(backupFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat backup file: %w", err)
+	}
+
+	filename := filepath.Base(backupFile)
+	e.log.Info("Uploading backup to cloud", "file", filename, "size", cloud.FormatSize(info.Size()), "targets", len(targets))
+
+	upload := func(ctx context.Context, backend cloud.Backend, progress cloud.ProgressCallback) error {
+		return backend.Upload(ctx, backupFile, filename, progress)
+	}
+	replicas, backends := e.replicateAll(ctx, targets, filename, upload, tracker)
+	defer closeBackends(backends)
+	status, succeeded := replicationStatus(replicas)
+
+	// Record the outcome before uploading the metadata, so every copy carries it
+	metaFile := backupFile + ".meta.json"
+	meta, err := metadata.Load(backupFile)
+	if err == nil {
+		meta.Replicas = replicas
+		meta.ReplicationStatus = status
+		if len(succeeded) > 0 {
+			recordLock(meta, lock)
+		}
+		if err := metadata.Save(metaFile, meta); err != nil {
+			e.log.Warn("Failed to record replication status", "error", err)
+		}
+	}
+
+	if _, err := os.Stat(metaFile); err == nil {
+		var wg sync.WaitGroup
+		for i, backend := range backends {
+			if backend == nil || !replicas[i].Success {
+				continue
+			}
+			wg.Add(1)
+			go func(name string, backend cloud.Backend) {
+				defer wg.Done()
+				if err := backend.Upload(ctx, metaFile, filepath.Base(metaFile), nil); err != nil {
+					// Don't fail if metadata upload fails
+					e.log.Warn("Failed to upload metadata file", "target", name, "error", err)
+				}
+			}(replicas[i].Target, backend)
+		}
+		wg.Wait()
+	}
+
+	switch status {
+	case metadata.ReplicationFailed:
+		return fmt.Errorf("upload failed on all %d targets", len(targets))
+	case metadata.ReplicationDegraded:
+		e.log.Warn("Backup replication degraded", "file", filename,
+			"succeeded", len(succeeded), "targets", len(targets), "ok", strings.Join(succeeded, ","))
+	default:
+		e.log.Info("Backup replicated", "file", filename, "targets", strings.Join(succeeded, ","))
+	}
+	return nil
+}
+
+// replicateStream copies a backup that was streamed to the primary storage,
+// and its .sha256, on to the named targets. There is no local copy, so every
+// target reads it back from the primary. The outcome is recorded in meta with
+// the primary as the first replica; the backends of the targets that got a
+// copy are returned, so the metadata can be uploaded next to it.
+func (e *Engine) replicateStream(ctx context.Context, source cloud.Backend, filename string, meta *metadata.BackupMetadata, lock *cloud.ObjectLock, tracker *progress.OperationTracker) []cloud.Backend {
+	var targets []replicationTarget
+	for _, target := range e.replicationTargets() {
+		if target.name == primaryTargetName {
+			continue
+		}
+		if target.cfg != nil {
+			target.cfg.Lock = lock
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	e.log.Info("Replicating streamed backup", "file", filename, "targets", len(targets))
+
+	upload := func(ctx context.Context, backend cloud.Backend, _ cloud.ProgressCallback) error {
+		for _, name := range []string{filename, filename + ".sha256"} {
+			reader, err := source.DownloadStream(ctx, name)
+			if err != nil {
+				return fmt.Errorf("failed to read %s back from primary storage: %w", name, err)
+			}
+			err = backend.UploadStream(ctx, reader, name)
+			reader.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	primary := metadata.ReplicaStatus{
+		Target:     primaryTargetName,
+		Provider:   e.cfg.CloudProvider,
+		Location:   e.cfg.CloudBucket,
+		Key:        strings.TrimPrefix(e.cfg.CloudPrefix+"/"+filename, "/"),
+		Success:    true,
+		Attempts:   1,
+		UploadedAt: time.Now(),
+	}
+	replicas, backends := e.replicateAll(ctx, targets, filename, upload, tracker)
+	meta.Replicas = append([]metadata.ReplicaStatus{primary}, replicas...)
+	status, succeeded := replicationStatus(meta.Replicas)
+	meta.ReplicationStatus = status
+	if status == metadata.ReplicationDegraded {
+		e.log.Warn("Backup replication degraded", "file", filename,
+			"succeeded", len(succeeded), "targets", len(meta.Replicas), "ok", strings.Join(succeeded, ","))
+	} else {
+		e.log.Info("Backup replicated", "file", filename, "targets", strings.Join(succeeded, ","))
+	}
+
+	var copies []cloud.Backend
+	for i, backend := range backends {
+		if backend != nil && replicas[i].Success {
+			copies = append(copies, backend)
+		}
+	}
+	return copies
+}
+
+// closeBackends closes the backends replicate returned
+func closeBackends(backends []cloud.Backend) {
+	for _, backend := range backends {
+		if backend != nil {
+			cloud.CloseBackend(backend)
+		}
+	}
+}
+
+// uploadFunc uploads a backup to one target's backend
+type uploadFunc func(ctx context.Context, backend cloud.Backend, progress cloud.ProgressCallback) error
+
+// replicateAll runs upload against every target concurrently
+func (e *Engine) replicateAll(ctx context.Context, targets []replicationTarget, filename string, upload uploadFunc, tracker *progress.OperationTracker) ([]metadata.ReplicaStatus, []cloud.Backend) {
+	replicas := make([]metadata.ReplicaStatus, len(targets))
+	backends := make([]cloud.Backend, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target replicationTarget) {
+			defer wg.Done()
+			replicas[i], backends[i] = e.replicate(ctx, target, filename, upload, tracker)
+		}(i, target)
+	}
+	wg.Wait()
+	return replicas, backends
+}
+
+// replicationStatus returns the overall status of the replicas and the
+// targets that have a copy
+func replicationStatus(replicas []metadata.ReplicaStatus) (string, []string) {
+	var succeeded []string
+	for _, replica := range replicas {
+		if replica.Success {
+			succeeded = append(succeeded, replica.Target)
+		}
+	}
+	switch {
+	case len(succeeded) == 0:
+		return metadata.ReplicationFailed, succeeded
+	case len(succeeded) < len(replicas):
+		return metadata.ReplicationDegraded, succeeded
+	}
+	return metadata.ReplicationComplete, succeeded
+}
+
+// recordLock records the object lock of a backup's cloud copies
+func recordLock(meta *metadata.BackupMetadata, lock *cloud.ObjectLock) {
+	if lock == nil {
+		return
+	}
+	if !lock.Until.IsZero() {
+		until := lock.Until
+		meta.LockMode = lock.Mode
+		meta.LockedUntil = &until
+	}
+	meta.LegalHold = lock.LegalHold
+}
+
+// replicate uploads a backup to one target, retrying with exponential
+// backoff. The backend is returned when the target could be configured.
+func (e *Engine) replicate(ctx context.Context, target replicationTarget, filename string, upload uploadFunc, tracker *progress.OperationTracker) (metadata.ReplicaStatus, cloud.Backend) {
+	step := tracker.AddStep("cloud_upload_"+target.name, fmt.Sprintf("Uploading to %s", target.name))
+	replica := metadata.ReplicaStatus{Target: target.name}
+
+	fail := func(err error) (metadata.ReplicaStatus, cloud.Backend) {
+		replica.Error = err.Error()
+		step.Fail(fmt.Errorf("upload to %s failed: %w", target.name, err))
+		e.log.Warn("Cloud upload failed", "target", target.name, "attempts", replica.Attempts, "error", err)
+		return replica, nil
+	}
+
+	if target.err != nil {
+		return fail(target.err)
+	}
+	replica.Provider = target.cfg.Provider
+	replica.Location = target.cfg.Bucket
+
+	backend, err := cloud.NewBackend(target.cfg)
+	if err != nil {
+		return fail(fmt.Errorf("failed to create cloud backend: %w", err))
+	}
+	replica.Key = strings.TrimPrefix(target.cfg.Prefix+"/"+filename, "/")
+
+	var lastPercent int
+	progressCallback := func(transferred, total int64) {
+		percent := int(float64(transferred) / float64(total) * 100)
+		if percent != lastPercent && percent%10 == 0 {
+			e.log.Debug("Upload progress", "target", target.name, "percent", percent, "transferred", cloud.FormatSize(transferred), "total", cloud.FormatSize(total))
+			lastPercent = percent
+		}
+	}
+
+	delay := replicaRetryDelay
+	for {
+		replica.Attempts++
+		err = upload(ctx, backend, progressCallback)
+		if err == nil || replica.Attempts > target.retries || ctx.Err() != nil {
+			break
+		}
+		e.log.Warn("Cloud upload failed, retrying", "target", target.name, "attempt", replica.Attempts, "retry_in", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			cloud.CloseBackend(backend)
+			return fail(ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, replicaMaxRetryDelay)
+	}
+	if err != nil {
+		cloud.CloseBackend(backend)
+		return fail(err)
+	}
+
+	replica.Success = true
+	replica.UploadedAt = time.Now()
+	if files, err := backend.List(ctx, filename); err == nil {
+		for _, f := range files {
+			if path.Base(f.Key) == filename {
+				replica.ETag = strings.Trim(f.ETag, `"`)
+				break
+			}
+		}
+	}
+
+	step.Complete(fmt.Sprintf("Uploaded to %s/%s/%s", backend.Name(), target.cfg.Bucket, replica.Key))
+	e.log.Info("Backup uploaded to cloud", "target", target.name, "provider", backend.Name(), "bucket", target.cfg.Bucket, "file", filename)
+	return replica, backend
+}
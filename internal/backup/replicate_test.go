@@ -0,0 +1,213 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dbbackup/internal/cloud"
+	"dbbackup/internal/config"
+	"dbbackup/internal/logger"
+	"dbbackup/internal/metadata"
+)
+
+// writeReplicaBackup creates a dummy backup with metadata to replicate
+func writeReplicaBackup(t *testing.T) string {
+	t.Helper()
+	backupFile := filepath.Join(t.TempDir(), "db_app_20250101.dump")
+	if err := os.WriteFile(backupFile, []byte("dump"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	meta := &metadata.BackupMetadata{Database: "app", BackupFile: backupFile, SizeBytes: 4}
+	if err := meta.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return backupFile
+}
+
+func TestReplicateToTargets(t *testing.T) {
+	onprem, offsite := t.TempDir(), t.TempDir()
+	cfg := &config.Config{CloudTargets: []config.CloudTarget{
+		{Name: "onprem", URI: "file://" + filepath.ToSlash(onprem) + "/pg"},
+		{Name: "offsite", URI: "file://" + filepath.ToSlash(offsite) + "/"},
+	}}
+	e := NewSilent(cfg, logger.NewSilent(), nil, nil)
+	backupFile := writeReplicaBackup(t)
+	tracker := e.detailedReporter.StartOperation("test", "app", "backup")
+
+	if err := e.uploadToCloud(context.Background(), backupFile, tracker); err != nil {
+		t.Fatalf("uploadToCloud failed: %v", err)
+	}
+
+	for _, file := range []string{
+		filepath.Join(onprem, "pg", "db_app_20250101.dump"),
+		filepath.Join(onprem, "pg", "db_app_20250101.dump.meta.json"),
+		filepath.Join(offsite, "db_app_20250101.dump"),
+		filepath.Join(offsite, "db_app_20250101.dump.meta.json"),
+	} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("Expected replica %s: %v", file, err)
+		}
+	}
+
+	meta, err := metadata.Load(backupFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ReplicationStatus != metadata.ReplicationComplete || len(meta.Replicas) != 2 {
+		t.Fatalf("Unexpected replication record: %s %+v", meta.ReplicationStatus, meta.Replicas)
+	}
+	for _, r := range meta.Replicas {
+		if !r.Success || r.Attempts != 1 || r.UploadedAt.IsZero() || r.Provider != "file" {
+			t.Errorf("Unexpected replica status %+v", r)
+		}
+	}
+	if meta.Replicas[0].Key != filepath.ToSlash(onprem)[1:]+"/pg/db_app_20250101.dump" {
+		t.Errorf("Unexpected key %s", meta.Replicas[0].Key)
+	}
+
+	// The uploaded metadata carries the replication record too
+	remote, err := metadata.Load(filepath.Join(offsite, "db_app_20250101.dump"))
+	if err != nil || remote.ReplicationStatus != metadata.ReplicationComplete {
+		t.Errorf("Expected uploaded metadata with replication status, got %+v, %v", remote, err)
+	}
+}
+
+func TestReplicationTargetsSSL(t *testing.T) {
+	cfg := &config.Config{CloudTargets: []config.CloudTarget{
+		{Name: "dr", URI: "s3://dr-backups/pg/"},
+		{Name: "onprem", URI: "minio://backups/pg/", Endpoint: "minio.internal:9000", Insecure: true},
+		{Name: "lab", URI: "minio://backups/pg/", Endpoint: "http://minio.lab:9000"},
+	}}
+	e := NewSilent(cfg, logger.NewSilent(), nil, nil)
+
+	want := map[string]bool{"dr": true, "onprem": false, "lab": false}
+	for _, target := range e.replicationTargets() {
+		if target.cfg.UseSSL != want[target.name] {
+			t.Errorf("Target %s: UseSSL = %v, want %v", target.name, target.cfg.UseSSL, want[target.name])
+		}
+	}
+}
+
+func TestReplicationTargetsTimeout(t *testing.T) {
+	cfg := &config.Config{CloudTargets: []config.CloudTarget{
+		{Name: "dr", URI: "s3://dr-backups/pg/"},
+		{Name: "slow", URI: "s3://slow-backups/pg/", Timeout: 900},
+	}}
+	e := NewSilent(cfg, logger.NewSilent(), nil, nil)
+
+	want := map[string]int{"dr": e.primaryCloudConfig().Timeout, "slow": 900}
+	for _, target := range e.replicationTargets() {
+		if target.cfg.Timeout != want[target.name] || target.cfg.MaxRetries != e.primaryCloudConfig().MaxRetries {
+			t.Errorf("Target %s: Timeout = %d, MaxRetries = %d", target.name, target.cfg.Timeout, target.cfg.MaxRetries)
+		}
+	}
+}
+
+func TestReplicateDegraded(t *testing.T) {
+	defer func(d time.Duration) { replicaRetryDelay = d }(replicaRetryDelay)
+	replicaRetryDelay = time.Millisecond
+
+	// A regular file where the target directory should be makes every upload fail
+	blocked := filepath.Join(t.TempDir(), "blocked")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	good := t.TempDir()
+	cfg := &config.Config{MaxRetries: 1, CloudTargets: []config.CloudTarget{
+		{Name: "good", URI: "file://" + filepath.ToSlash(good)},
+		{Name: "broken", URI: "file://" + filepath.ToSlash(blocked) + "/backups/", Retries: 2},
+		{Name: "typo", URI: "s4://bucket/"},
+	}}
+	e := NewSilent(cfg, logger.NewSilent(), nil, nil)
+	backupFile := writeReplicaBackup(t)
+	tracker := e.detailedReporter.StartOperation("test", "app", "backup")
+
+	if err := e.uploadToCloud(context.Background(), backupFile, tracker); err != nil {
+		t.Fatalf("Expected a degraded backup not to fail, got %v", err)
+	}
+
+	meta, err := metadata.Load(backupFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ReplicationStatus != metadata.ReplicationDegraded {
+		t.Errorf("Expected degraded replication, got %q", meta.ReplicationStatus)
+	}
+	status := make(map[string]metadata.ReplicaStatus)
+	for _, r := range meta.Replicas {
+		status[r.Target] = r
+	}
+	if r := status["good"]; !r.Success || r.Attempts != 1 {
+		t.Errorf("Unexpected status for good target: %+v", r)
+	}
+	if r := status["broken"]; r.Success || r.Attempts != 3 || r.Error == "" {
+		t.Errorf("Expected broken target to fail after 2 retries: %+v", r)
+	}
+	if r := status["typo"]; r.Success || r.Error == "" {
+		t.Errorf("Expected invalid target to be recorded as failed: %+v", r)
+	}
+}
+
+func TestReplicateAllFailed(t *testing.T) {
+	blocked := filepath.Join(t.TempDir(), "blocked")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{CloudTargets: []config.CloudTarget{
+		{Name: "broken", URI: "file://" + filepath.ToSlash(blocked) + "/backups/"},
+	}}
+	e := NewSilent(cfg, logger.NewSilent(), nil, nil)
+	backupFile := writeReplicaBackup(t)
+	tracker := e.detailedReporter.StartOperation("test", "app", "backup")
+
+	if err := e.uploadToCloud(context.Background(), backupFile, tracker); err == nil {
+		t.Fatal("Expected an error when no target has a copy")
+	}
+	if meta, err := metadata.Load(backupFile); err != nil || meta.ReplicationStatus != metadata.ReplicationFailed {
+		t.Errorf("Expected failed replication to be recorded, got %+v, %v", meta, err)
+	}
+}
+
+func TestReplicateStream(t *testing.T) {
+	primary, offsite := t.TempDir(), t.TempDir()
+	for name, data := range map[string]string{"db_app_20250101.dump": "dump", "db_app_20250101.dump.sha256": "abc  db_app_20250101.dump\n"} {
+		if err := os.WriteFile(filepath.Join(primary, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uri, err := cloud.ParseCloudURI("file://" + filepath.ToSlash(primary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceCfg := uri.ToConfig()
+	sourceCfg.Prefix = strings.Trim(uri.Path, "/")
+	source, err := cloud.NewBackend(sourceCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{CloudProvider: "file", CloudTargets: []config.CloudTarget{
+		{Name: "offsite", URI: "file://" + filepath.ToSlash(offsite)},
+	}}
+	e := NewSilent(cfg, logger.NewSilent(), nil, nil)
+	tracker := e.detailedReporter.StartOperation("test", "app", "backup")
+	meta := &metadata.BackupMetadata{Database: "app"}
+
+	copies := e.replicateStream(context.Background(), source, "db_app_20250101.dump", meta, nil, tracker)
+	if len(copies) != 1 {
+		t.Fatalf("Expected one replica backend, got %d: %+v", len(copies), meta.Replicas)
+	}
+	for _, name := range []string{"db_app_20250101.dump", "db_app_20250101.dump.sha256"} {
+		if _, err := os.Stat(filepath.Join(offsite, name)); err != nil {
+			t.Errorf("Expected replica %s: %v", name, err)
+		}
+	}
+	if meta.ReplicationStatus != metadata.ReplicationComplete || len(meta.Replicas) != 2 ||
+		meta.Replicas[0].Target != primaryTargetName || !meta.Replicas[1].Success {
+		t.Errorf("Unexpected replication record: %s %+v", meta.ReplicationStatus, meta.Replicas)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 )
@@ -181,16 +182,104 @@ func (a *AzureBackend) uploadSimple(ctx context.Context, file *os.File, blobName
 		fmt.Fprintf(os.Stderr, "Warning: failed to set blob metadata: %v\n", err)
 	}
 
+	return a.finishBlob(ctx, blockBlobClient, blobName)
+}
+
+// UploadStream uploads a stream of unknown size as a block blob. Blocks are
+// only committed once the stream ends, so a failed stream leaves no blob.
+func (a *AzureBackend) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
+	blobName := strings.TrimPrefix(remotePath, "/")
+	blockBlobClient := a.client.ServiceClient().NewContainerClient(a.containerName).NewBlockBlobClient(blobName)
+
+	// 64MB blocks allow blobs up to ~3TB (50,000 blocks)
+	_, err := blockBlobClient.UploadStream(ctx, reader, &blockblob.UploadStreamOptions{
+		BlockSize:   64 * 1024 * 1024,
+		Concurrency: 4,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload blob stream: %w", err)
+	}
+
+	return a.finishBlob(ctx, blockBlobClient, blobName)
+}
+
+// finishBlob moves a new blob to the configured access tier, then locks it.
+// The tier is set last because an archived blob's metadata can't be changed.
+func (a *AzureBackend) finishBlob(ctx context.Context, blockBlobClient *blockblob.Client, blobName string) error {
+	if tier := a.config.uploadClass(blobName); tier != "" {
+		if _, err := blockBlobClient.SetTier(ctx, blob.AccessTier(tier), nil); err != nil {
+			return fmt.Errorf("failed to set access tier: %w", err)
+		}
+	}
+	return a.lockBlob(ctx, blockBlobClient)
+}
+
+// lockBlob applies the configured object lock as an immutability policy
+// (governance: unlocked, compliance: locked) and legal hold. It runs after
+// the metadata is set, which an immutable blob no longer allows. The
+// container needs version-level immutability support.
+func (a *AzureBackend) lockBlob(ctx context.Context, blockBlobClient *blockblob.Client) error {
+	lock := a.config.Lock
+	if lock == nil {
+		return nil
+	}
+	if !lock.Until.IsZero() {
+		mode := blob.ImmutabilityPolicySettingUnlocked
+		if lock.Mode == LockCompliance {
+			mode = blob.ImmutabilityPolicySettingLocked
+		}
+		_, err := blockBlobClient.SetImmutabilityPolicy(ctx, lock.Until, &blob.SetImmutabilityPolicyOptions{Mode: &mode})
+		if err != nil {
+			return fmt.Errorf("failed to set immutability policy: %w", err)
+		}
+	}
+	if lock.LegalHold {
+		if _, err := blockBlobClient.SetLegalHold(ctx, true, nil); err != nil {
+			return fmt.Errorf("failed to set legal hold: %w", err)
+		}
+	}
 	return nil
 }
 
-// uploadBlocks uploads a file using block blob staging (for large files)
+// uploadBlocks uploads a file using block blob staging (for large files).
+// Staged blocks are recorded in the local transfer state; running the same
+// upload again skips the blocks Azure still holds and commits the list.
 func (a *AzureBackend) uploadBlocks(ctx context.Context, file *os.File, blobName string, fileSize int64, progress ProgressCallback) error {
 	blockBlobClient := a.client.ServiceClient().NewContainerClient(a.containerName).NewBlockBlobClient(blobName)
 
 	const blockSize = 100 * 1024 * 1024 // 100MB per block
 	numBlocks := (fileSize + blockSize - 1) / blockSize
 
+	state, err := loadUploadState(a.config.stateDir(), a.Name(), a.containerName, blobName, blobName, file, blockSize)
+	if err != nil {
+		return err
+	}
+
+	// Blocks staged by an earlier attempt only count if they are still in
+	// the blob's uncommitted block list (Azure discards them after 7 days)
+	staged := make(map[string]bool, len(state.Parts))
+	if len(state.Parts) > 0 {
+		uncommitted := make(map[string]int64)
+		if list, err := blockBlobClient.GetBlockList(ctx, blockblob.BlockListTypeUncommitted, nil); err == nil {
+			for _, b := range list.UncommittedBlocks {
+				if b.Name != nil && b.Size != nil {
+					uncommitted[*b.Name] = *b.Size
+				}
+			}
+		}
+		parts := state.Parts[:0]
+		for _, p := range state.Parts {
+			if size, ok := uncommitted[p.BlockID]; ok && size == p.Size {
+				staged[p.BlockID] = true
+				parts = append(parts, p)
+			}
+		}
+		state.Parts = parts
+	}
+	if err := state.Save(); err != nil {
+		return err
+	}
+
 	blockIDs := make([]string, 0, numBlocks)
 	hash := sha256.New()
 	var totalUploaded int64
@@ -205,7 +294,7 @@ func (a *AzureBackend) uploadBlocks(ctx context.Context, file *os.File, blobName
 			currentBlockSize = int(fileSize - i*blockSize)
 		}
 
-		// Read block
+		// Read block (staged blocks too, for the checksum)
 		blockData := make([]byte, currentBlockSize)
 		n, err := io.ReadFull(file, blockData)
 		if err != nil && err != io.ErrUnexpectedEOF {
@@ -217,10 +306,15 @@ func (a *AzureBackend) uploadBlocks(ctx context.Context, file *os.File, blobName
 		hash.Write(blockData)
 
 		// Upload block
-		reader := bytes.NewReader(blockData)
-		_, err = blockBlobClient.StageBlock(ctx, blockID, streaming.NopCloser(reader), nil)
-		if err != nil {
-			return fmt.Errorf("failed to stage block %d: %w", i, err)
+		if !staged[blockID] {
+			reader := bytes.NewReader(blockData)
+			_, err = blockBlobClient.StageBlock(ctx, blockID, streaming.NopCloser(reader), nil)
+			if err != nil {
+				return fmt.Errorf("failed to stage block %d (run the upload again to resume): %w", i, err)
+			}
+			if err := state.AddPart(TransferPart{Number: int32(i), BlockID: blockID, Size: int64(n)}); err != nil {
+				return err
+			}
 		}
 
 		// Update progress
@@ -231,10 +325,13 @@ func (a *AzureBackend) uploadBlocks(ctx context.Context, file *os.File, blobName
 	}
 
 	// Commit all blocks
-	_, err := blockBlobClient.CommitBlockList(ctx, blockIDs, nil)
+	_, err = blockBlobClient.CommitBlockList(ctx, blockIDs, nil)
 	if err != nil {
 		return fmt.Errorf("failed to commit block list: %w", err)
 	}
+	if err := state.Remove(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove upload state: %v\n", err)
+	}
 
 	// Store checksum as metadata
 	checksum := hex.EncodeToString(hash.Sum(nil))
@@ -248,46 +345,86 @@ func (a *AzureBackend) uploadBlocks(ctx context.Context, file *os.File, blobName
 		fmt.Fprintf(os.Stderr, "Warning: failed to set blob metadata: %v\n", err)
 	}
 
-	return nil
+	return a.finishBlob(ctx, blockBlobClient, blobName)
 }
 
-// Download downloads a file from Azure Blob Storage
+// ListIncompleteUploads lists block uploads recorded in the local state
+// directory. Azure keeps uncommitted blocks for 7 days but can't list them
+// for blobs that were never committed.
+func (a *AzureBackend) ListIncompleteUploads(ctx context.Context, prefix string) ([]IncompleteUpload, error) {
+	return localUploads(a.config, a.Name(), strings.TrimPrefix(prefix, "/"))
+}
+
+// AbortUpload discards the blocks staged for a blob that was never committed,
+// by committing an empty block list and deleting the resulting empty blob. If
+// the upload was replacing an existing blob, that blob is left alone and the
+// staged blocks expire after 7 days.
+func (a *AzureBackend) AbortUpload(ctx context.Context, upload IncompleteUpload) error {
+	blockBlobClient := a.client.ServiceClient().NewContainerClient(a.containerName).NewBlockBlobClient(upload.Key)
+
+	exists, err := a.Exists(ctx, upload.Key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := blockBlobClient.CommitBlockList(ctx, []string{}, nil); err != nil {
+			return fmt.Errorf("failed to discard staged blocks: %w", err)
+		}
+		if _, err := blockBlobClient.Delete(ctx, nil); err != nil {
+			return fmt.Errorf("failed to delete empty blob: %w", err)
+		}
+	}
+
+	return removeUploadState(a.config, a.Name(), upload.Key)
+}
+
+// Download downloads a file from Azure Blob Storage. An interrupted download
+// is continued from where it stopped the next time the same blob is downloaded.
 func (a *AzureBackend) Download(ctx context.Context, remotePath, localPath string, progress ProgressCallback) error {
 	blobName := strings.TrimPrefix(remotePath, "/")
 	blockBlobClient := a.client.ServiceClient().NewContainerClient(a.containerName).NewBlockBlobClient(blobName)
 
-	// Get blob properties to know size
+	// Get blob properties to know size and version
 	props, err := blockBlobClient.GetProperties(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to get blob properties: %w", err)
 	}
 
 	fileSize := *props.ContentLength
-
-	// Download blob
-	resp, err := blockBlobClient.DownloadStream(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to download blob: %w", err)
+	var etag azcore.ETag
+	if props.ETag != nil {
+		etag = *props.ETag
 	}
-	defer resp.Body.Close()
 
-	// Create local file
-	file, err := os.Create(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+	state := loadDownloadState(a.config.stateDir(), a.Name(), a.containerName, blobName, remotePath, localPath)
+	open := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		resp, err := blockBlobClient.DownloadStream(ctx, &blob.DownloadStreamOptions{
+			Range: blob.HTTPRange{Offset: offset},
+			AccessConditions: &blob.AccessConditions{
+				ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: &etag},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to download blob: %w", err)
+		}
+		return resp.Body, nil
 	}
-	defer file.Close()
 
-	// Wrap reader with progress tracking
-	reader := NewProgressReader(resp.Body, fileSize, progress)
+	return downloadResumable(ctx, state, fileSize, string(etag), open, progress)
+}
 
-	// Copy with progress
-	_, err = io.Copy(file, reader)
+// DownloadStream opens a blob for reading. Interrupted reads are resumed
+// from the current offset, so long streams survive transient network errors.
+func (a *AzureBackend) DownloadStream(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	blobName := strings.TrimPrefix(remotePath, "/")
+	blockBlobClient := a.client.ServiceClient().NewContainerClient(a.containerName).NewBlockBlobClient(blobName)
+
+	resp, err := blockBlobClient.DownloadStream(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return nil, fmt.Errorf("failed to download blob: %w", err)
 	}
 
-	return nil
+	return resp.NewRetryReader(ctx, &blob.RetryReaderOptions{MaxRetries: 3}), nil
 }
 
 // Delete deletes a file from Azure Blob Storage
@@ -331,6 +468,9 @@ func (a *AzureBackend) List(ctx context.Context, prefix string) ([]BackupInfo, e
 				Size:         *blob.Properties.ContentLength,
 				LastModified: *blob.Properties.LastModified,
 			}
+			if blob.Properties.AccessTier != nil {
+				file.StorageClass = string(*blob.Properties.AccessTier)
+			}
 
 			// Try to get SHA256 from metadata
 			if blob.Metadata != nil {
@@ -367,6 +507,78 @@ func (a *AzureBackend) Exists(ctx context.Context, remotePath string) (bool, err
 	return true, nil
 }
 
+// LockStatus returns the immutability policy and legal hold of a blob
+func (a *AzureBackend) LockStatus(ctx context.Context, remotePath string) (*ObjectLock, error) {
+	blobName := strings.TrimPrefix(remotePath, "/")
+	blockBlobClient := a.client.ServiceClient().NewContainerClient(a.containerName).NewBlockBlobClient(blobName)
+
+	props, err := blockBlobClient.GetProperties(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob properties: %w", err)
+	}
+
+	lock := &ObjectLock{Mode: LockGovernance, LegalHold: props.LegalHold != nil && *props.LegalHold}
+	if props.ImmutabilityPolicyMode != nil && *props.ImmutabilityPolicyMode == blob.ImmutabilityPolicyModeLocked {
+		lock.Mode = LockCompliance
+	}
+	if props.ImmutabilityPolicyExpiresOn != nil {
+		lock.Until = *props.ImmutabilityPolicyExpiresOn
+	}
+	if lock.Until.IsZero() && !lock.LegalHold {
+		return nil, nil
+	}
+	return lock, nil
+}
+
+// SetStorageClass moves a blob to another access tier
+func (a *AzureBackend) SetStorageClass(ctx context.Context, remotePath, class string) error {
+	blobName := strings.TrimPrefix(remotePath, "/")
+	blockBlobClient := a.client.ServiceClient().NewContainerClient(a.containerName).NewBlockBlobClient(blobName)
+
+	if _, err := blockBlobClient.SetTier(ctx, blob.AccessTier(class), nil); err != nil {
+		return fmt.Errorf("failed to set access tier: %w", err)
+	}
+	return nil
+}
+
+// ArchiveStatus reports whether a blob is in the archive tier and whether it
+// is being rehydrated
+func (a *AzureBackend) ArchiveStatus(ctx context.Context, remotePath string) (*ArchiveStatus, error) {
+	blobName := strings.TrimPrefix(remotePath, "/")
+	blockBlobClient := a.client.ServiceClient().NewContainerClient(a.containerName).NewBlockBlobClient(blobName)
+
+	props, err := blockBlobClient.GetProperties(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob properties: %w", err)
+	}
+
+	status := &ArchiveStatus{StorageClass: "Hot"}
+	if props.AccessTier != nil {
+		status.StorageClass = *props.AccessTier
+	}
+	status.Archived = status.StorageClass == string(blob.AccessTierArchive)
+	status.Restoring = props.ArchiveStatus != nil && *props.ArchiveStatus != ""
+	status.Readable = !status.Archived
+	return status, nil
+}
+
+// StartRehydrate moves an archived blob back to the hot tier. Standard
+// priority takes up to 15 hours, high priority usually under one.
+func (a *AzureBackend) StartRehydrate(ctx context.Context, remotePath string, opts RehydrateOptions) error {
+	blobName := strings.TrimPrefix(remotePath, "/")
+	blockBlobClient := a.client.ServiceClient().NewContainerClient(a.containerName).NewBlockBlobClient(blobName)
+
+	priority := blob.RehydratePriorityStandard
+	if p := strings.ToLower(opts.Priority); p == "high" || p == "expedited" {
+		priority = blob.RehydratePriorityHigh
+	}
+	_, err := blockBlobClient.SetTier(ctx, blob.AccessTierHot, &blob.SetTierOptions{RehydratePriority: &priority})
+	if err != nil {
+		return fmt.Errorf("failed to rehydrate archived blob: %w", err)
+	}
+	return nil
+}
+
 // GetSize returns the size of a file in Azure Blob Storage
 func (a *AzureBackend) GetSize(ctx context.Context, remotePath string) (int64, error) {
 	blobName := strings.TrimPrefix(remotePath, "/")
@@ -0,0 +1,223 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileBackend implements the Backend interface for a local directory, such
+// as an NFS or SMB mount, so that copies on a NAS get the same listing,
+// verification and retention as cloud storage.
+//
+// Keys are paths relative to the root directory (cfg.Bucket, or "/" for
+// file:// URIs). Uploads are written to a hidden ".<name>.part" file and
+// renamed into place once complete and synced to disk.
+type FileBackend struct {
+	root   string
+	prefix string
+	config *Config
+}
+
+// NewFileBackend creates a new local filesystem backend
+func NewFileBackend(cfg *Config) (*FileBackend, error) {
+	root := cfg.Bucket
+	if root == "" {
+		root = "/"
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid directory %s: %w", cfg.Bucket, err)
+	}
+
+	return &FileBackend{
+		root:   root,
+		prefix: cleanTreeKey(cfg.Prefix),
+		config: cfg,
+	}, nil
+}
+
+// Name returns the backend name
+func (f *FileBackend) Name() string {
+	return "file"
+}
+
+// key maps a remote path to a key under the prefix
+func (f *FileBackend) key(remotePath string) string {
+	return treeKey(f.prefix, filepath.ToSlash(remotePath), cleanTreeKey)
+}
+
+// localPath returns where a key is stored
+func (f *FileBackend) localPath(key string) string {
+	return filepath.Join(f.root, filepath.FromSlash(key))
+}
+
+// Upload copies a file into the directory
+func (f *FileBackend) Upload(ctx context.Context, localPath, remotePath string, progress ProgressCallback) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	var reader io.Reader = file
+	if progress != nil {
+		reader = NewProgressReader(file, stat.Size(), progress)
+	}
+
+	return f.upload(ctx, reader, remotePath)
+}
+
+// UploadStream writes everything read from reader until EOF
+func (f *FileBackend) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
+	return f.upload(ctx, reader, remotePath)
+}
+
+// upload writes to a hidden .part file, syncs it and renames it into place.
+// On failure the .part file is removed.
+func (f *FileBackend) upload(ctx context.Context, reader io.Reader, remotePath string) error {
+	target := f.localPath(f.key(remotePath))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp := filepath.FromSlash(hiddenPartPath(filepath.ToSlash(target)))
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+
+	_, err = io.Copy(out, &contextReader{ctx: ctx, r: reader})
+	if err == nil {
+		// A NAS mount may acknowledge writes it hasn't stored yet
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to move upload into place: %w", err)
+	}
+	return nil
+}
+
+// Download copies a file out of the directory. An interrupted copy is
+// continued from where it stopped the next time the same file is downloaded.
+func (f *FileBackend) Download(ctx context.Context, remotePath, localPath string, progress ProgressCallback) error {
+	key := f.key(remotePath)
+	source := f.localPath(key)
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Size and modification time identify the version of the file
+	version := fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+	state := loadDownloadState(f.config.stateDir(), f.Name(), f.root, key, remotePath, localPath)
+
+	return downloadResumable(ctx, state, info.Size(), version, func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return file, nil
+	}, progress)
+}
+
+// DownloadStream opens a file for reading
+func (f *FileBackend) DownloadStream(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	file, err := os.Open(f.localPath(f.key(remotePath)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, nil
+}
+
+// List lists files whose key starts with prefix, walking subdirectories.
+// Hidden files (including uploads in progress) are skipped.
+func (f *FileBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
+	return listTree(ctx, f.prefix, filepath.ToSlash(prefix), f.key, func(dir string) ([]treeEntry, error) {
+		dirEntries, err := os.ReadDir(f.localPath(dir))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", f.localPath(dir), err)
+		}
+
+		entries := make([]treeEntry, 0, len(dirEntries))
+		for _, de := range dirEntries {
+			info, err := de.Info()
+			if err != nil {
+				// Removed since the directory was read
+				continue
+			}
+			entries = append(entries, treeEntry{
+				Name:    de.Name(),
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+				IsDir:   info.IsDir(),
+			})
+		}
+		return entries, nil
+	})
+}
+
+// Delete deletes a file
+func (f *FileBackend) Delete(ctx context.Context, remotePath string) error {
+	if err := os.Remove(f.localPath(f.key(remotePath))); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// Exists checks if a file exists
+func (f *FileBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	if _, err := os.Stat(f.localPath(f.key(remotePath))); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check file existence: %w", err)
+	}
+	return true, nil
+}
+
+// GetSize returns the size of a file
+func (f *FileBackend) GetSize(ctx context.Context, remotePath string) (int64, error) {
+	info, err := os.Stat(f.localPath(f.key(remotePath)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// contextReader stops a copy when the context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
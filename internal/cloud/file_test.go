@@ -0,0 +1,159 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFileBackendRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	f, err := NewFileBackend(&Config{Provider: "file", Bucket: root, Prefix: "offsite/daily", StateDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	data := bytes.Repeat([]byte("INSERT INTO t VALUES (1);\n"), 10000)
+	local := filepath.Join(t.TempDir(), "db_app.dump")
+	if err := os.WriteFile(local, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Upload(ctx, local, "db_app.dump", nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	stored, err := os.ReadFile(filepath.Join(root, "offsite", "daily", "db_app.dump"))
+	if err != nil || !bytes.Equal(stored, data) {
+		t.Fatalf("Uploaded file doesn't match (%d bytes, %v)", len(stored), err)
+	}
+
+	// Full keys and bare names refer to the same file
+	for _, p := range []string{"db_app.dump", "offsite/daily/db_app.dump"} {
+		if size, err := f.GetSize(ctx, p); err != nil || size != int64(len(data)) {
+			t.Errorf("GetSize(%q) = %d, %v", p, size, err)
+		}
+	}
+
+	target := filepath.Join(t.TempDir(), "restored.dump")
+	if err := f.Download(ctx, "offsite/daily/db_app.dump", target, nil); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if got, _ := os.ReadFile(target); !bytes.Equal(got, data) {
+		t.Errorf("Downloaded file doesn't match (%d bytes)", len(got))
+	}
+
+	r, err := f.DownloadStream(ctx, "db_app.dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if !bytes.Equal(got, data) {
+		t.Errorf("Streamed file doesn't match (%d bytes)", len(got))
+	}
+
+	if err := f.Delete(ctx, "db_app.dump"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := f.Exists(ctx, "db_app.dump"); err != nil || exists {
+		t.Errorf("Expected file to be deleted (exists=%v, err=%v)", exists, err)
+	}
+}
+
+func TestFileBackendUploadStreamFailure(t *testing.T) {
+	root := t.TempDir()
+	f, err := NewFileBackend(&Config{Provider: "file", Bucket: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := io.MultiReader(bytes.NewReader(make([]byte, 100000)), errReader{})
+	if err := f.UploadStream(context.Background(), reader, "db.dump"); err == nil {
+		t.Fatal("Expected failed stream to fail the upload")
+	}
+	entries, _ := os.ReadDir(root)
+	for _, e := range entries {
+		t.Errorf("Failed upload left %s behind", e.Name())
+	}
+}
+
+func TestFileBackendList(t *testing.T) {
+	root := t.TempDir()
+	for _, p := range []string{
+		"backups/db_app_1.dump",
+		"backups/db_app_1.dump.meta.json",
+		"backups/2025/db_app_0.dump",
+		"backups/.db_app_2.dump.part",
+		"backups-old/db_app_x.dump",
+	} {
+		full := filepath.Join(root, filepath.FromSlash(p))
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := NewFileBackend(&Config{Provider: "file", Bucket: root, Prefix: "backups"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"backups/2025/db_app_0.dump", "backups/db_app_1.dump", "backups/db_app_1.dump.meta.json"}},
+		{"db_app_1", []string{"backups/db_app_1.dump", "backups/db_app_1.dump.meta.json"}},
+		{"2025/", []string{"backups/2025/db_app_0.dump"}},
+		{"missing/", nil},
+	}
+	for _, tt := range tests {
+		files, err := f.List(context.Background(), tt.prefix)
+		if err != nil {
+			t.Fatalf("List(%q) failed: %v", tt.prefix, err)
+		}
+		var keys []string
+		for _, file := range files {
+			keys = append(keys, file.Key)
+		}
+		sort.Strings(keys)
+		if strings.Join(keys, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("List(%q) = %v, want %v", tt.prefix, keys, tt.want)
+		}
+	}
+}
+
+func TestFileURIBackend(t *testing.T) {
+	root := t.TempDir()
+	uri, err := ParseCloudURI("file://" + filepath.ToSlash(root) + "/offsite/db_app.dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Provider != "file" || uri.Bucket != "/" {
+		t.Fatalf("Unexpected parse result: %+v", uri)
+	}
+
+	backend, err := NewBackend(uri.ToConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.UploadStream(context.Background(), strings.NewReader("dump"), "db_app.dump"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "offsite", "db_app.dump")); err != nil {
+		t.Errorf("Expected upload below the URI's directory: %v", err)
+	}
+	if exists, _ := backend.Exists(context.Background(), uri.Path); !exists {
+		t.Error("Expected the URI path to name the uploaded file")
+	}
+
+	if _, err := ParseCloudURI("file://nas/backups"); err == nil {
+		t.Error("Expected file URI with a remote host to be rejected")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,6 +22,7 @@ type GCSBackend struct {
 	client     *storage.Client
 	bucketName string
 	config     *Config
+	opts       []option.ClientOption // Client options, reused for resumable uploads
 }
 
 // NewGCSBackend creates a new Google Cloud Storage backend
@@ -33,34 +35,29 @@ func NewGCSBackend(cfg *Config) (*GCSBackend, error) {
 	var err error
 	ctx := context.Background()
 
-	// Support for fake-gcs-server emulator (uses endpoint override)
+	var opts []option.ClientOption
 	if cfg.Endpoint != "" {
-		// For fake-gcs-server and custom endpoints
-		client, err = storage.NewClient(ctx, option.WithEndpoint(cfg.Endpoint), option.WithoutAuthentication())
-		if err != nil {
-			return nil, fmt.Errorf("failed to create GCS client: %w", err)
-		}
-	} else {
-		// Production GCS using Application Default Credentials or service account
-		if cfg.AccessKey != "" {
-			// Use service account JSON key file
-			client, err = storage.NewClient(ctx, option.WithCredentialsFile(cfg.AccessKey))
-			if err != nil {
-				return nil, fmt.Errorf("failed to create GCS client with credentials file: %w", err)
-			}
-		} else {
-			// Use default credentials (ADC, environment variables, etc.)
-			client, err = storage.NewClient(ctx)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create GCS client: %w", err)
-			}
+		// Support for fake-gcs-server emulator (uses endpoint override)
+		opts = []option.ClientOption{option.WithEndpoint(cfg.Endpoint), option.WithoutAuthentication()}
+	} else if cfg.AccessKey != "" {
+		// Production GCS with a service account JSON key file
+		opts = []option.ClientOption{option.WithCredentialsFile(cfg.AccessKey)}
+	}
+	// Otherwise use default credentials (ADC, environment variables, etc.)
+
+	client, err = storage.NewClient(ctx, opts...)
+	if err != nil {
+		if cfg.AccessKey != "" && cfg.Endpoint == "" {
+			return nil, fmt.Errorf("failed to create GCS client with credentials file: %w", err)
 		}
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
 	}
 
 	backend := &GCSBackend{
 		client:     client,
 		bucketName: cfg.Bucket,
 		config:     cfg,
+		opts:       opts,
 	}
 
 	// Create bucket if it doesn't exist
@@ -106,12 +103,19 @@ func (g *GCSBackend) Upload(ctx context.Context, localPath, remotePath string, p
 	// Remove leading slash from remote path
 	objectName := strings.TrimPrefix(remotePath, "/")
 
+	// Large files use a resumable session that survives interruptions
+	const resumableThreshold = 100 * 1024 * 1024 // 100 MB
+	if fileSize > resumableThreshold {
+		return g.uploadResumable(ctx, file, objectName, fileSize, progress)
+	}
+
 	bucket := g.client.Bucket(g.bucketName)
 	object := bucket.Object(objectName)
 
 	// Create writer with automatic chunking for large files
 	writer := object.NewWriter(ctx)
 	writer.ChunkSize = 16 * 1024 * 1024 // 16MB chunks for streaming
+	writer.StorageClass = g.config.uploadClass(objectName)
 
 	// Wrap reader with progress tracking and hash calculation
 	hash := sha256.New()
@@ -141,48 +145,100 @@ func (g *GCSBackend) Upload(ctx context.Context, localPath, remotePath string, p
 		fmt.Fprintf(os.Stderr, "Warning: failed to set object metadata: %v\n", err)
 	}
 
+	return g.lockObject(ctx, objectName)
+}
+
+// UploadStream uploads a stream of unknown size with a resumable upload.
+// The object is only created when the stream completes; on error the
+// context is cancelled so the partial upload is discarded.
+func (g *GCSBackend) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
+	objectName := strings.TrimPrefix(remotePath, "/")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writer := g.client.Bucket(g.bucketName).Object(objectName).NewWriter(ctx)
+	writer.ChunkSize = 16 * 1024 * 1024 // 16MB chunks per resumable request
+	writer.StorageClass = g.config.uploadClass(objectName)
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		cancel()
+		writer.Close()
+		return fmt.Errorf("failed to upload object stream: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finalize upload: %w", err)
+	}
+
+	return g.lockObject(ctx, objectName)
+}
+
+// lockObject applies the configured object lock as an object retention
+// (governance: unlocked, compliance: locked) and temporary hold. The bucket
+// needs object retention enabled.
+func (g *GCSBackend) lockObject(ctx context.Context, objectName string) error {
+	lock := g.config.Lock
+	if lock == nil {
+		return nil
+	}
+	update := storage.ObjectAttrsToUpdate{}
+	if !lock.Until.IsZero() {
+		mode := "Unlocked"
+		if lock.Mode == LockCompliance {
+			mode = "Locked"
+		}
+		update.Retention = &storage.ObjectRetention{Mode: mode, RetainUntil: lock.Until}
+	}
+	if lock.LegalHold {
+		update.TemporaryHold = true
+	}
+	if _, err := g.client.Bucket(g.bucketName).Object(objectName).Update(ctx, update); err != nil {
+		return fmt.Errorf("failed to set object retention: %w", err)
+	}
 	return nil
 }
 
-// Download downloads a file from Google Cloud Storage
+// Download downloads a file from Google Cloud Storage. An interrupted
+// download is continued with a range read the next time the same object is
+// downloaded.
 func (g *GCSBackend) Download(ctx context.Context, remotePath, localPath string, progress ProgressCallback) error {
 	objectName := strings.TrimPrefix(remotePath, "/")
 
 	bucket := g.client.Bucket(g.bucketName)
 	object := bucket.Object(objectName)
 
-	// Get object attributes to know size
+	// Get object attributes to know size and generation
 	attrs, err := object.Attrs(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get object attributes: %w", err)
 	}
 
-	fileSize := attrs.Size
+	// Reads must come from the same generation of the object
+	object = object.If(storage.Conditions{GenerationMatch: attrs.Generation})
 
-	// Create reader
-	reader, err := object.NewReader(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to download object: %w", err)
+	state := loadDownloadState(g.config.stateDir(), g.Name(), g.bucketName, objectName, remotePath, localPath)
+	open := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		reader, err := object.NewRangeReader(ctx, offset, -1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download object: %w", err)
+		}
+		return reader, nil
 	}
-	defer reader.Close()
 
-	// Create local file
-	file, err := os.Create(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
+	return downloadResumable(ctx, state, attrs.Size, strconv.FormatInt(attrs.Generation, 10), open, progress)
+}
 
-	// Wrap reader with progress tracking
-	progressReader := NewProgressReader(reader, fileSize, progress)
+// DownloadStream opens an object for reading
+func (g *GCSBackend) DownloadStream(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	objectName := strings.TrimPrefix(remotePath, "/")
 
-	// Copy with progress
-	_, err = io.Copy(file, progressReader)
+	reader, err := g.client.Bucket(g.bucketName).Object(objectName).NewReader(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return nil, fmt.Errorf("failed to download object: %w", err)
 	}
 
-	return nil
+	return reader, nil
 }
 
 // Delete deletes a file from Google Cloud Storage
@@ -226,6 +282,7 @@ func (g *GCSBackend) List(ctx context.Context, prefix string) ([]BackupInfo, err
 			Name:         filepath.Base(attrs.Name),
 			Size:         attrs.Size,
 			LastModified: attrs.Updated,
+			StorageClass: attrs.StorageClass,
 		}
 
 		// Try to get SHA256 from metadata
@@ -259,6 +316,70 @@ func (g *GCSBackend) Exists(ctx context.Context, remotePath string) (bool, error
 	return true, nil
 }
 
+// LockStatus returns the retention and holds of an object. A bucket
+// retention policy counts as a governance lock.
+func (g *GCSBackend) LockStatus(ctx context.Context, remotePath string) (*ObjectLock, error) {
+	objectName := strings.TrimPrefix(remotePath, "/")
+
+	attrs, err := g.client.Bucket(g.bucketName).Object(objectName).Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object attributes: %w", err)
+	}
+
+	lock := &ObjectLock{
+		Mode:      LockGovernance,
+		Until:     attrs.RetentionExpirationTime,
+		LegalHold: attrs.TemporaryHold || attrs.EventBasedHold,
+	}
+	if r := attrs.Retention; r != nil && r.RetainUntil.After(lock.Until) {
+		lock.Until = r.RetainUntil
+		if r.Mode == "Locked" {
+			lock.Mode = LockCompliance
+		}
+	}
+	if lock.Until.IsZero() && !lock.LegalHold {
+		return nil, nil
+	}
+	return lock, nil
+}
+
+// SetStorageClass moves an object to another storage class by rewriting it
+// in place. Its metadata and retention are kept.
+func (g *GCSBackend) SetStorageClass(ctx context.Context, remotePath, class string) error {
+	object := g.client.Bucket(g.bucketName).Object(strings.TrimPrefix(remotePath, "/"))
+	attrs, err := object.Attrs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get object attributes: %w", err)
+	}
+
+	// The rewrite replaces attributes it is given, so pass the current ones on
+	copier := object.CopierFrom(object)
+	copier.StorageClass = class
+	copier.ContentType = attrs.ContentType
+	copier.Metadata = attrs.Metadata
+	copier.Retention = attrs.Retention
+	copier.TemporaryHold = attrs.TemporaryHold
+	if _, err := copier.Run(ctx); err != nil {
+		return fmt.Errorf("failed to change storage class: %w", err)
+	}
+	return nil
+}
+
+// ArchiveStatus returns an object's storage class. All GCS storage classes,
+// including ARCHIVE, can be read directly.
+func (g *GCSBackend) ArchiveStatus(ctx context.Context, remotePath string) (*ArchiveStatus, error) {
+	attrs, err := g.client.Bucket(g.bucketName).Object(strings.TrimPrefix(remotePath, "/")).Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object attributes: %w", err)
+	}
+	return &ArchiveStatus{StorageClass: attrs.StorageClass, Readable: true}, nil
+}
+
+// StartRehydrate does nothing: GCS objects never need rehydrating
+func (g *GCSBackend) StartRehydrate(ctx context.Context, remotePath string, opts RehydrateOptions) error {
+	return nil
+}
+
 // GetSize returns the size of a file in Google Cloud Storage
 func (g *GCSBackend) GetSize(ctx context.Context, remotePath string) (int64, error) {
 	objectName := strings.TrimPrefix(remotePath, "/")
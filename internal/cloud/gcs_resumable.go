@@ -0,0 +1,271 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// gcsChunkSize is how much is sent per request of a resumable upload, and so
+// how much is sent again after an interruption. GCS needs a multiple of 256KB.
+const gcsChunkSize = 16 * 1024 * 1024
+
+// errSessionExpired is returned when GCS no longer knows an upload session
+var errSessionExpired = errors.New("upload session expired")
+
+// uploadResumable uploads a file with a GCS resumable upload session. The
+// session URI and the confirmed offset are kept in the local transfer state,
+// so running the same upload again continues from the last confirmed chunk.
+// Sessions are valid for a week.
+func (g *GCSBackend) uploadResumable(ctx context.Context, file *os.File, objectName string, fileSize int64, progress ProgressCallback) error {
+	state, err := loadUploadState(g.config.stateDir(), g.Name(), g.bucketName, objectName, objectName, file, gcsChunkSize)
+	if err != nil {
+		return err
+	}
+
+	client, err := g.httpClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	offset := int64(0)
+	if state.SessionURI != "" {
+		offset, err = g.sessionOffset(ctx, client, state.SessionURI, fileSize)
+		if err != nil {
+			state.SessionURI = ""
+			offset = 0
+		}
+	}
+	if state.SessionURI == "" {
+		if state.SessionURI, err = g.startSession(ctx, client, objectName, fileSize); err != nil {
+			return err
+		}
+		state.StartedAt = time.Now()
+	}
+	state.Offset = offset
+	if err := state.Save(); err != nil {
+		return err
+	}
+
+	// The part of the file uploaded earlier still goes into the checksum
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, offset)); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if progress != nil && offset > 0 {
+		progress(offset, fileSize)
+	}
+
+	buf := make([]byte, gcsChunkSize)
+	for offset < fileSize {
+		n := int64(len(buf))
+		if offset+n > fileSize {
+			n = fileSize - offset
+		}
+		if _, err := file.ReadAt(buf[:n], offset); err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+
+		confirmed, err := g.putChunk(ctx, client, state.SessionURI, buf[:n], offset, fileSize)
+		if err != nil {
+			return fmt.Errorf("resumable upload interrupted at %s (run the upload again to resume): %w", FormatSize(offset), err)
+		}
+
+		// GCS may keep less than was sent; the rest is sent again
+		hash.Write(buf[:confirmed-offset])
+		offset = confirmed
+		state.Offset = offset
+		if err := state.Save(); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(offset, fileSize)
+		}
+	}
+
+	if err := state.Remove(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove upload state: %v\n", err)
+	}
+
+	// Store checksum as metadata
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	_, err = g.client.Bucket(g.bucketName).Object(objectName).Update(ctx, storage.ObjectAttrsToUpdate{
+		Metadata: map[string]string{
+			"sha256": checksum,
+		},
+	})
+	if err != nil {
+		// Non-fatal: upload succeeded but metadata failed
+		fmt.Fprintf(os.Stderr, "Warning: failed to set object metadata: %v\n", err)
+	}
+
+	return g.lockObject(ctx, objectName)
+}
+
+// httpClient returns an HTTP client authorized like the storage client. The
+// emulator needs no authentication.
+func (g *GCSBackend) httpClient(ctx context.Context) (*http.Client, error) {
+	if g.config.Endpoint != "" {
+		return http.DefaultClient, nil
+	}
+	opts := append([]option.ClientOption{option.WithScopes(storage.ScopeReadWrite)}, g.opts...)
+	client, _, err := htransport.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS HTTP client: %w", err)
+	}
+	return client, nil
+}
+
+// uploadURL is the JSON API endpoint that starts resumable uploads
+func (g *GCSBackend) uploadURL(objectName string) string {
+	base := "https://storage.googleapis.com"
+	if g.config.Endpoint != "" {
+		if u, err := url.Parse(g.config.Endpoint); err == nil && u.Host != "" {
+			base = u.Scheme + "://" + u.Host
+		}
+	}
+	return fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=resumable&name=%s",
+		base, url.PathEscape(g.bucketName), url.QueryEscape(objectName))
+}
+
+// startSession starts a resumable upload and returns its session URI
+func (g *GCSBackend) startSession(ctx context.Context, client *http.Client, objectName string, size int64) (string, error) {
+	attrs := map[string]string{"name": objectName}
+	if class := g.config.uploadClass(objectName); class != "" {
+		attrs["storageClass"] = class
+	}
+	body, _ := json.Marshal(attrs)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.uploadURL(objectName), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Upload-Content-Type", "application/octet-stream")
+	req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(size, 10))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to start resumable upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("failed to start resumable upload: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("failed to start resumable upload: no session URI returned")
+	}
+	return location, nil
+}
+
+// putChunk sends data at offset and returns how far GCS has stored the upload
+func (g *GCSBackend) putChunk(ctx context.Context, client *http.Client, sessionURI string, data []byte, offset, size int64) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, sessionURI, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(data))-1, size))
+
+	return sessionResponse(client, req, size)
+}
+
+// sessionOffset asks GCS how much of an upload session it has stored
+func (g *GCSBackend) sessionOffset(ctx context.Context, client *http.Client, sessionURI string, size int64) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, sessionURI, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+
+	return sessionResponse(client, req, size)
+}
+
+// sessionResponse sends a request to an upload session and returns the
+// number of bytes GCS has stored: size once the upload is complete, or the
+// end of the Range header while it is incomplete (HTTP 308)
+func sessionResponse(client *http.Client, req *http.Request, size int64) (int64, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return size, nil
+	case http.StatusPermanentRedirect:
+		return parseRangeEnd(resp.Header.Get("Range"))
+	case http.StatusNotFound, http.StatusGone:
+		return 0, errSessionExpired
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return 0, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+}
+
+// parseRangeEnd converts a "bytes=0-N" Range header into the number of bytes
+// stored (N+1). No header means nothing has been stored yet.
+func parseRangeEnd(header string) (int64, error) {
+	if header == "" {
+		return 0, nil
+	}
+	i := strings.LastIndex(header, "-")
+	if i < 0 {
+		return 0, fmt.Errorf("invalid Range header %q", header)
+	}
+	end, err := strconv.ParseInt(header[i+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Range header %q", header)
+	}
+	return end + 1, nil
+}
+
+// ListIncompleteUploads lists resumable uploads recorded in the local state
+// directory; GCS has no API to list open upload sessions
+func (g *GCSBackend) ListIncompleteUploads(ctx context.Context, prefix string) ([]IncompleteUpload, error) {
+	return localUploads(g.config, g.Name(), strings.TrimPrefix(prefix, "/"))
+}
+
+// AbortUpload cancels a resumable upload session, discarding its data
+func (g *GCSBackend) AbortUpload(ctx context.Context, upload IncompleteUpload) error {
+	if upload.UploadID != "" {
+		client, err := g.httpClient(ctx)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, upload.UploadID, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to cancel upload session: %w", err)
+		}
+		resp.Body.Close()
+
+		// A cancelled session answers 499; an expired one 404 or 410
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("failed to cancel upload session: %s", resp.Status)
+		}
+	}
+
+	return removeUploadState(g.config, g.Name(), upload.Key)
+}
@@ -12,9 +12,19 @@ type Backend interface {
 	// Upload uploads a file to cloud storage
 	Upload(ctx context.Context, localPath, remotePath string, progress ProgressCallback) error
 	
+	// UploadStream uploads everything read from reader until EOF. The size is not
+	// known up front, so nothing is staged on local disk. A read error aborts the
+	// upload and no object is left behind.
+	UploadStream(ctx context.Context, reader io.Reader, remotePath string) error
+	
 	// Download downloads a file from cloud storage
 	Download(ctx context.Context, remotePath, localPath string, progress ProgressCallback) error
 	
+	// DownloadStream opens a remote file for reading, so it can be consumed
+	// while it downloads instead of being copied to disk first. The caller
+	// must close the returned reader.
+	DownloadStream(ctx context.Context, remotePath string) (io.ReadCloser, error)
+	
 	// List lists all backup files in cloud storage
 	List(ctx context.Context, prefix string) ([]BackupInfo, error)
 	
@@ -27,7 +37,7 @@ type Backend interface {
 	// GetSize returns the size of a remote file
 	GetSize(ctx context.Context, remotePath string) (int64, error)
 	
-	// Name returns the backend name (e.g., "s3", "azure", "gcs")
+	// Name returns the backend name (e.g., "s3", "azure", "gcs", "sftp", "file", "webdav")
 	Name() string
 }
 
@@ -46,8 +56,8 @@ type ProgressCallback func(bytesTransferred, totalBytes int64)
 
 // Config contains common configuration for cloud backends
 type Config struct {
-	Provider    string // "s3", "minio", "azure", "gcs", "b2"
-	Bucket      string // Bucket or container name
+	Provider    string // "s3", "minio", "azure", "gcs", "b2", "sftp", "file", "webdav", "webdavs"
+	Bucket      string // Bucket or container name (base directory for file, host for sftp/webdav)
 	Region      string // Region (for S3)
 	Endpoint    string // Custom endpoint (for MinIO, S3-compatible)
 	AccessKey   string // Access key or account ID
@@ -58,10 +68,46 @@ type Config struct {
 	Timeout     int    // Timeout in seconds (default: 300)
 	MaxRetries  int    // Maximum retry attempts (default: 3)
 	Concurrency int    // Upload/download concurrency (default: 5)
+	StateDir    string // Where interrupted transfers are recorded (default: DefaultStateDir())
+
+	// S3, Azure and GCS only
+	StorageClass string      // Storage class of uploads (e.g. STANDARD_IA, Cool, NEARLINE)
+	Lock         *ObjectLock // Object lock applied to every upload
+
+	// SFTP only
+	KeyFile        string // SSH private key (default: $DBBACKUP_SFTP_KEY, agent, ~/.ssh keys)
+	KnownHostsFile string // known_hosts file (default: $DBBACKUP_SFTP_KNOWN_HOSTS, ~/.ssh/known_hosts)
 }
 
 // NewBackend creates a new cloud storage backend based on the provider
 func NewBackend(cfg *Config) (Backend, error) {
+	backend, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := backend.(LockingBackend); cfg.Lock != nil && !ok {
+		CloseBackend(backend)
+		return nil, fmt.Errorf("object lock is not supported by the %s backend (use s3, minio, azure or gcs)", backend.Name())
+	}
+	if cfg.StorageClass != "" {
+		if cfg.StorageClass, err = NormalizeStorageClass(backend.Name(), cfg.StorageClass); err != nil {
+			CloseBackend(backend)
+			return nil, err
+		}
+	}
+	return backend, nil
+}
+
+// CloseBackend releases the connections a backend keeps open between calls
+// (the SFTP session). Other backends have nothing to close.
+func CloseBackend(backend Backend) error {
+	if closer, ok := backend.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func newBackend(cfg *Config) (Backend, error) {
 	switch cfg.Provider {
 	case "s3", "aws":
 		return NewS3Backend(cfg)
@@ -83,8 +129,14 @@ func NewBackend(cfg *Config) (Backend, error) {
 		return NewAzureBackend(cfg)
 	case "gs", "gcs", "google":
 		return NewGCSBackend(cfg)
+	case "sftp", "ssh":
+		return NewSFTPBackend(cfg)
+	case "file", "local":
+		return NewFileBackend(cfg)
+	case "webdav", "webdavs":
+		return NewWebDAVBackend(cfg)
 	default:
-		return nil, fmt.Errorf("unsupported cloud provider: %s (supported: s3, minio, b2, azure, gcs)", cfg.Provider)
+		return nil, fmt.Errorf("unsupported cloud provider: %s (supported: s3, minio, b2, azure, gcs, sftp, file, webdav)", cfg.Provider)
 	}
 }
 
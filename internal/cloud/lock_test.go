@@ -0,0 +1,89 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewObjectLock(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		mode, until string
+		legalHold   bool
+		want        *ObjectLock
+		wantErr     string
+	}{
+		{"", "", false, nil, ""},
+		{"compliance", "30d", false, &ObjectLock{Mode: LockCompliance, Until: now.AddDate(0, 0, 30)}, ""},
+		{"GOVERNANCE", "2w", false, &ObjectLock{Mode: LockGovernance, Until: now.AddDate(0, 0, 14)}, ""},
+		{"", "36h", false, &ObjectLock{Mode: LockGovernance, Until: now.Add(36 * time.Hour)}, ""},
+		{"compliance", "2027-01-31T00:00:00Z", false, &ObjectLock{Mode: LockCompliance, Until: time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)}, ""},
+		{"", "", true, &ObjectLock{LegalHold: true}, ""},
+		{"worm", "30d", false, nil, "invalid object lock mode"},
+		{"compliance", "", false, nil, "needs a retain-until"},
+		{"compliance", "2020-01-01T00:00:00Z", false, nil, "in the past"},
+		{"compliance", "soon", false, nil, "invalid retain-until"},
+	}
+	for _, tt := range tests {
+		got, err := NewObjectLock(tt.mode, tt.until, tt.legalHold, now)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewObjectLock(%q, %q) error = %v, want %q", tt.mode, tt.until, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewObjectLock(%q, %q) failed: %v", tt.mode, tt.until, err)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && (got.Mode != tt.want.Mode || !got.Until.Equal(tt.want.Until) || got.LegalHold != tt.want.LegalHold)) {
+			t.Errorf("NewObjectLock(%q, %q) = %+v, want %+v", tt.mode, tt.until, got, tt.want)
+		}
+	}
+}
+
+// lockedFileBackend is a file backend whose objects report a lock
+type lockedFileBackend struct {
+	*FileBackend
+	locks map[string]*ObjectLock
+}
+
+func (b *lockedFileBackend) LockStatus(ctx context.Context, remotePath string) (*ObjectLock, error) {
+	return b.locks[remotePath], nil
+}
+
+func TestCheckDeletable(t *testing.T) {
+	files, err := NewFileBackend(&Config{Provider: "file", Bucket: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &lockedFileBackend{files, map[string]*ObjectLock{
+		"locked.dump":  {Mode: LockCompliance, Until: time.Now().Add(time.Hour)},
+		"expired.dump": {Mode: LockCompliance, Until: time.Now().Add(-time.Hour)},
+		"held.dump":    {LegalHold: true},
+	}}
+	ctx := context.Background()
+
+	for _, name := range []string{"locked.dump", "held.dump"} {
+		var locked *LockedError
+		if err := CheckDeletable(ctx, backend, name); !errors.As(err, &locked) {
+			t.Errorf("Expected %s to be refused, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"expired.dump", "plain.dump"} {
+		if err := CheckDeletable(ctx, backend, name); err != nil {
+			t.Errorf("Expected %s to be deletable, got %v", name, err)
+		}
+	}
+	if err := CheckDeletable(ctx, files, "locked.dump"); err != nil {
+		t.Errorf("Backends without object lock never refuse, got %v", err)
+	}
+
+	if _, err := NewBackend(&Config{Provider: "file", Bucket: t.TempDir(), Lock: &ObjectLock{LegalHold: true}}); err == nil {
+		t.Error("Expected object lock on a file backend to be rejected")
+	}
+}
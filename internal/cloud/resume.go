@@ -0,0 +1,380 @@
+package cloud
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transfer operations recorded in a TransferState
+const (
+	OperationUpload   = "upload"
+	OperationDownload = "download"
+)
+
+// TransferState records how far a large upload or download has got, so that
+// running the same transfer again continues where it stopped instead of
+// starting over. There is one JSON file per transfer in the state directory;
+// it is removed when the transfer completes or is aborted.
+type TransferState struct {
+	Operation  string         `json:"operation"`
+	Provider   string         `json:"provider"`
+	Bucket     string         `json:"bucket"`
+	Key        string         `json:"key"`         // Object key in the bucket
+	RemotePath string         `json:"remote_path"` // Remote path as given to the backend
+	LocalPath  string         `json:"local_path"`
+	Size       int64          `json:"size"`
+	ModTime    time.Time      `json:"mod_time,omitempty"` // Local file (uploads)
+	PartSize   int64          `json:"part_size,omitempty"`
+	UploadID   string         `json:"upload_id,omitempty"`   // S3 multipart upload ID
+	SessionURI string         `json:"session_uri,omitempty"` // GCS resumable session URI
+	ETag       string         `json:"etag,omitempty"`        // Object version being downloaded
+	Offset     int64          `json:"offset,omitempty"`      // Bytes the server has confirmed (GCS)
+	Parts      []TransferPart `json:"parts,omitempty"`       // Uploaded S3 parts or staged Azure blocks
+	StartedAt  time.Time      `json:"started_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// TransferPart is a part (S3) or block (Azure) that is already stored
+type TransferPart struct {
+	Number   int32  `json:"number"`
+	ETag     string `json:"etag,omitempty"`
+	Checksum string `json:"checksum,omitempty"` // S3 part CRC32
+	BlockID  string `json:"block_id,omitempty"`
+	Size     int64  `json:"size"`
+}
+
+// IncompleteUpload is an upload that was started in a bucket but never completed
+type IncompleteUpload struct {
+	Key        string    // Object key in the bucket
+	RemotePath string    // Remote path relative to the configured prefix
+	UploadID   string    // S3 upload ID or GCS session URI
+	Initiated  time.Time // When the upload was started
+	Uploaded   int64     // Bytes stored so far, if known
+	LocalPath  string    // Local file being uploaded ("" if there is no local state)
+}
+
+// ResumableBackend is implemented by backends whose interrupted uploads can be
+// listed and cleaned up. Uploads resume by calling Upload again with the same
+// local and remote paths.
+type ResumableBackend interface {
+	Backend
+
+	// ListIncompleteUploads lists uploads under prefix that were started but
+	// never completed. Providers that can't list them from the bucket report
+	// the ones recorded in the local state directory.
+	ListIncompleteUploads(ctx context.Context, prefix string) ([]IncompleteUpload, error)
+
+	// AbortUpload discards an incomplete upload, the data stored for it so far
+	// and its local state
+	AbortUpload(ctx context.Context, upload IncompleteUpload) error
+}
+
+// DefaultStateDir returns where transfer state is kept:
+// $DBBACKUP_STATE_DIR, or ~/.dbbackup/transfers
+func DefaultStateDir() string {
+	if dir := os.Getenv("DBBACKUP_STATE_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "dbbackup-transfers")
+	}
+	return filepath.Join(home, ".dbbackup", "transfers")
+}
+
+// stateDir returns the configured transfer state directory
+func (c *Config) stateDir() string {
+	if c.StateDir != "" {
+		return c.StateDir
+	}
+	return DefaultStateDir()
+}
+
+// statePath names the state file of a transfer after the object and direction
+func statePath(dir, operation, provider, bucket, key string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{operation, provider, bucket, key}, "\x00")))
+	return filepath.Join(dir, operation+"-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// loadUploadState returns the saved state of an upload of file to key, or a
+// new (unsaved) state if there is none. State saved for a different local
+// file, or for a file that has changed since, is discarded.
+func loadUploadState(dir, provider, bucket, key, remotePath string, file *os.File, partSize int64) (*TransferState, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	localPath, err := filepath.Abs(file.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	path := statePath(dir, OperationUpload, provider, bucket, key)
+	if state, err := readTransferState(path); err == nil &&
+		state.LocalPath == localPath &&
+		state.Size == info.Size() &&
+		state.ModTime.Equal(info.ModTime()) &&
+		state.PartSize == partSize {
+		return state, nil
+	}
+
+	return &TransferState{
+		Operation:  OperationUpload,
+		Provider:   provider,
+		Bucket:     bucket,
+		Key:        key,
+		RemotePath: remotePath,
+		LocalPath:  localPath,
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+		PartSize:   partSize,
+		StartedAt:  time.Now(),
+		path:       path,
+	}, nil
+}
+
+// loadDownloadState returns the saved state of a download of key to localPath,
+// or a new (unsaved) state if there is none
+func loadDownloadState(dir, provider, bucket, key, remotePath, localPath string) *TransferState {
+	path := statePath(dir, OperationDownload, provider, bucket, key)
+	if abs, err := filepath.Abs(localPath); err == nil {
+		localPath = abs
+	}
+
+	if state, err := readTransferState(path); err == nil && state.LocalPath == localPath {
+		return state
+	}
+
+	return &TransferState{
+		Operation:  OperationDownload,
+		Provider:   provider,
+		Bucket:     bucket,
+		Key:        key,
+		RemotePath: remotePath,
+		LocalPath:  localPath,
+		StartedAt:  time.Now(),
+		path:       path,
+	}
+}
+
+func readTransferState(path string) (*TransferState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state TransferState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse transfer state %s: %w", path, err)
+	}
+	state.path = path
+	return &state, nil
+}
+
+// ListTransferStates returns the transfers recorded in dir, oldest first
+func ListTransferStates(dir string) ([]*TransferState, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var states []*TransferState
+	for _, path := range paths {
+		state, err := readTransferState(path)
+		if err != nil {
+			continue
+		}
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].StartedAt.Before(states[j].StartedAt)
+	})
+	return states, nil
+}
+
+// Save writes the state to its file. The file is replaced atomically, so an
+// interruption never leaves a truncated state behind.
+func (s *TransferState) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked()
+}
+
+func (s *TransferState) saveLocked() error {
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal transfer state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write transfer state: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// AddPart records a stored part and saves the state
+func (s *TransferState) AddPart(part TransferPart) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Parts = append(s.Parts, part)
+	return s.saveLocked()
+}
+
+// Remove deletes the state file once the transfer is complete or aborted
+func (s *TransferState) Remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Completed returns the number of bytes already transferred
+func (s *TransferState) Completed() int64 {
+	if s.Operation == OperationDownload {
+		if info, err := os.Stat(partialPath(s.LocalPath)); err == nil {
+			return info.Size()
+		}
+		return 0
+	}
+	if s.Offset > 0 {
+		return s.Offset
+	}
+	var n int64
+	for _, p := range s.Parts {
+		n += p.Size
+	}
+	return n
+}
+
+// localUploads returns the incomplete uploads recorded in the state directory
+// for a bucket, for providers that can't list them from the bucket itself
+func localUploads(cfg *Config, provider, prefix string) ([]IncompleteUpload, error) {
+	states, err := ListTransferStates(cfg.stateDir())
+	if err != nil {
+		return nil, err
+	}
+
+	var uploads []IncompleteUpload
+	for _, s := range states {
+		if s.Operation != OperationUpload || s.Provider != provider || s.Bucket != cfg.Bucket ||
+			!strings.HasPrefix(s.RemotePath, prefix) {
+			continue
+		}
+		uploads = append(uploads, IncompleteUpload{
+			Key:        s.Key,
+			RemotePath: s.RemotePath,
+			UploadID:   s.UploadID + s.SessionURI,
+			Initiated:  s.StartedAt,
+			Uploaded:   s.Completed(),
+			LocalPath:  s.LocalPath,
+		})
+	}
+	return uploads, nil
+}
+
+// removeUploadState deletes the local state of an upload, if any
+func removeUploadState(cfg *Config, provider, key string) error {
+	path := statePath(cfg.stateDir(), OperationUpload, provider, cfg.Bucket, key)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// partialPath is where a download is written until it completes
+func partialPath(localPath string) string {
+	return localPath + ".part"
+}
+
+// rangeOpener opens an object for reading from offset onwards. It must fail
+// if the object is no longer the version identified by the download's ETag.
+type rangeOpener func(ctx context.Context, offset int64) (io.ReadCloser, error)
+
+// downloadResumable downloads an object of the given size and version (etag)
+// to state.LocalPath. Data goes to a .part file which is kept if the download
+// fails, so the next attempt continues from its end with a range request.
+// The .part file is renamed into place once the download is complete.
+func downloadResumable(ctx context.Context, state *TransferState, size int64, etag string, open rangeOpener, progress ProgressCallback) error {
+	localPath := state.LocalPath
+	partPath := partialPath(localPath)
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Only continue a partial file of the same version of the object
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil && state.ETag == etag && state.Size == size && info.Size() <= size {
+		offset = info.Size()
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+
+	state.ETag = etag
+	state.Size = size
+	if err := state.Save(); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create local file: %w", err)
+	}
+
+	if offset < size {
+		body, err := open(ctx, offset)
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to download: %w", err)
+		}
+
+		reader := NewProgressReader(body, size, func(transferred, total int64) {
+			if progress != nil {
+				progress(offset+transferred, total)
+			}
+		})
+		_, err = io.Copy(file, reader)
+		body.Close()
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write file (run the download again to resume): %w", err)
+		}
+	} else if progress != nil {
+		progress(size, size)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if info, err := os.Stat(partPath); err != nil || info.Size() != size {
+		os.Remove(partPath)
+		state.Remove()
+		return fmt.Errorf("download incomplete: expected %d bytes", size)
+	}
+	if err := os.Rename(partPath, localPath); err != nil {
+		return fmt.Errorf("failed to move download into place: %w", err)
+	}
+
+	return state.Remove()
+}
@@ -0,0 +1,195 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUploadStateRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	file := writeTempFile(t, bytes.Repeat([]byte("x"), 1000))
+
+	state, err := loadUploadState(dir, "s3", "bucket", "backups/db.dump", "db.dump", file, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.UploadID = "upload-1"
+	if err := state.AddPart(TransferPart{Number: 1, ETag: "\"abc\"", Size: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := loadUploadState(dir, "s3", "bucket", "backups/db.dump", "db.dump", file, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.UploadID != "upload-1" || len(again.Parts) != 1 || again.Completed() != 100 {
+		t.Errorf("Expected saved state to be reused, got upload %q with %d parts", again.UploadID, len(again.Parts))
+	}
+
+	// A different part size means the recorded parts don't fit
+	other, err := loadUploadState(dir, "s3", "bucket", "backups/db.dump", "db.dump", file, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.UploadID != "" {
+		t.Error("Expected state for another part size to be discarded")
+	}
+
+	states, err := ListTransferStates(dir)
+	if err != nil || len(states) != 1 || states[0].RemotePath != "db.dump" {
+		t.Fatalf("Expected one listed state, got %d (%v)", len(states), err)
+	}
+
+	if err := again.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if states, _ := ListTransferStates(dir); len(states) != 0 {
+		t.Error("Expected state to be removed")
+	}
+}
+
+func TestUploadStateDiscardedWhenFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	file := writeTempFile(t, []byte("first version"))
+
+	state, err := loadUploadState(dir, "azure", "container", "db.dump", "db.dump", file, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Parts = []TransferPart{{BlockID: "YmxvY2stMDAwMDAwMDA=", Size: 13}}
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(file.Name(), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh, err := loadUploadState(dir, "azure", "container", "db.dump", "db.dump", file, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fresh.Parts) != 0 {
+		t.Error("Expected state of a modified file to be discarded")
+	}
+}
+
+func TestDownloadResumable(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	local := filepath.Join(t.TempDir(), "db.dump")
+	dir := t.TempDir()
+
+	// The first attempt fails half way
+	var offsets []int64
+	failing := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		offsets = append(offsets, offset)
+		return io.NopCloser(io.MultiReader(bytes.NewReader(data[offset:5000]), errReader{})), nil
+	}
+	state := loadDownloadState(dir, "s3", "bucket", "db.dump", "db.dump", local)
+	if err := downloadResumable(context.Background(), state, int64(len(data)), "v1", failing, nil); err == nil {
+		t.Fatal("Expected first attempt to fail")
+	}
+	if _, err := os.Stat(local); !os.IsNotExist(err) {
+		t.Error("Incomplete download must not appear at the local path")
+	}
+
+	// The second attempt continues from the end of the partial file
+	working := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		offsets = append(offsets, offset)
+		return io.NopCloser(bytes.NewReader(data[offset:])), nil
+	}
+	state = loadDownloadState(dir, "s3", "bucket", "db.dump", "db.dump", local)
+	if err := downloadResumable(context.Background(), state, int64(len(data)), "v1", working, nil); err != nil {
+		t.Fatalf("Resumed download failed: %v", err)
+	}
+
+	if len(offsets) != 2 || offsets[1] != 5000 {
+		t.Errorf("Expected resume from offset 5000, got offsets %v", offsets)
+	}
+	got, err := os.ReadFile(local)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Downloaded file doesn't match (%d bytes, %v)", len(got), err)
+	}
+	if states, _ := ListTransferStates(dir); len(states) != 0 {
+		t.Error("Expected state to be removed after the download completed")
+	}
+}
+
+func TestDownloadResumableRestartsOnNewVersion(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 100)
+	local := filepath.Join(t.TempDir(), "db.dump")
+	dir := t.TempDir()
+
+	if err := os.WriteFile(partialPath(local), []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := loadDownloadState(dir, "gcs", "bucket", "db.dump", "db.dump", local)
+	state.ETag = "1"
+	state.Size = 100
+
+	var offset int64 = -1
+	open := func(ctx context.Context, o int64) (io.ReadCloser, error) {
+		offset = o
+		return io.NopCloser(bytes.NewReader(data[o:])), nil
+	}
+	if err := downloadResumable(context.Background(), state, 100, "2", open, nil); err != nil {
+		t.Fatal(err)
+	}
+	if offset != 0 {
+		t.Errorf("Expected download of a new object version to start over, started at %d", offset)
+	}
+}
+
+func TestParseRangeEnd(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int64
+	}{
+		{"", 0},
+		{"bytes=0-0", 1},
+		{"bytes=0-16777215", 16777216},
+	}
+	for _, tt := range tests {
+		if got, err := parseRangeEnd(tt.header); err != nil || got != tt.want {
+			t.Errorf("parseRangeEnd(%q) = %d, %v; want %d", tt.header, got, err, tt.want)
+		}
+	}
+	if _, err := parseRangeEnd("bytes=0-x"); err == nil {
+		t.Error("Expected error for invalid header")
+	}
+}
+
+func TestMultipartPartSize(t *testing.T) {
+	if got := multipartPartSize(200 * 1024 * 1024); got != 10*1024*1024 {
+		t.Errorf("Expected minimum part size for small files, got %d", got)
+	}
+	size := int64(500) * 1024 * 1024 * 1024
+	if parts := (size + multipartPartSize(size) - 1) / multipartPartSize(size); parts > 10000 {
+		t.Errorf("500GB file needs %d parts, more than S3 allows", parts)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func writeTempFile(t *testing.T, data []byte) *os.File {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "db.dump")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
@@ -2,17 +2,23 @@ package cloud
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 // S3Backend implements the Backend interface for AWS S3 and compatible services
@@ -62,7 +68,7 @@ func NewS3Backend(cfg *Config) (*S3Backend, error) {
 	clientOptions := []func(*s3.Options){
 		func(o *s3.Options) {
 			if cfg.Endpoint != "" {
-				o.BaseEndpoint = aws.String(cfg.Endpoint)
+				o.BaseEndpoint = aws.String(endpointURL(cfg.Endpoint, cfg.UseSSL))
 			}
 			if cfg.PathStyle {
 				o.UsePathStyle = true
@@ -80,6 +86,18 @@ func NewS3Backend(cfg *Config) (*S3Backend, error) {
 	}, nil
 }
 
+// endpointURL adds the scheme to an endpoint given as host[:port]: https,
+// or http when SSL is disabled. An explicit scheme is kept.
+func endpointURL(endpoint string, useSSL bool) string {
+	if strings.Contains(endpoint, "://") {
+		return endpoint
+	}
+	if useSSL {
+		return "https://" + endpoint
+	}
+	return "http://" + endpoint
+}
+
 // Name returns the backend name
 func (s *S3Backend) Name() string {
 	return "s3"
@@ -116,7 +134,7 @@ func (s *S3Backend) Upload(ctx context.Context, localPath, remotePath string, pr
 	const multipartThreshold = 100 * 1024 * 1024 // 100 MB
 	
 	if fileSize > multipartThreshold {
-		return s.uploadMultipart(ctx, file, key, fileSize, progress)
+		return s.uploadMultipart(ctx, file, key, remotePath, fileSize, progress)
 	}
 
 	// Simple upload for smaller files
@@ -132,10 +150,15 @@ func (s *S3Backend) uploadSimple(ctx context.Context, file *os.File, key string,
 	}
 
 	// Upload to S3
+	mode, until, hold := s.objectLock()
 	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(key),
-		Body:   reader,
+		Bucket:                    aws.String(s.bucket),
+		Key:                       aws.String(key),
+		Body:                      reader,
+		ObjectLockMode:            mode,
+		ObjectLockRetainUntilDate: until,
+		ObjectLockLegalHoldStatus: hold,
+		StorageClass:              types.StorageClass(s.config.uploadClass(key)),
 	})
 	
 	if err != nil {
@@ -145,84 +168,360 @@ func (s *S3Backend) uploadSimple(ctx context.Context, file *os.File, key string,
 	return nil
 }
 
-// uploadMultipart performs a multipart upload for large files
-func (s *S3Backend) uploadMultipart(ctx context.Context, file *os.File, key string, fileSize int64, progress ProgressCallback) error {
-	// Create uploader with custom options
-	uploader := manager.NewUploader(s.client, func(u *manager.Uploader) {
-		// Part size: 10MB
-		u.PartSize = 10 * 1024 * 1024
-		
-		// Upload up to 10 parts concurrently
-		u.Concurrency = 10
-		
-		// Leave parts on failure for debugging
-		u.LeavePartsOnError = false
+// objectLock returns the object lock settings for new objects. S3 only
+// accepts them for buckets created with Object Lock enabled.
+func (s *S3Backend) objectLock() (types.ObjectLockMode, *time.Time, types.ObjectLockLegalHoldStatus) {
+	lock := s.config.Lock
+	if lock == nil {
+		return "", nil, ""
+	}
+	var mode types.ObjectLockMode
+	var until *time.Time
+	var hold types.ObjectLockLegalHoldStatus
+	if !lock.Until.IsZero() {
+		mode = types.ObjectLockMode(strings.ToUpper(lock.Mode))
+		until = aws.Time(lock.Until)
+	}
+	if lock.LegalHold {
+		hold = types.ObjectLockLegalHoldStatusOn
+	}
+	return mode, until, hold
+}
+
+// multipartPartSize picks a part size that keeps large files within S3's
+// 10,000 part limit
+func multipartPartSize(fileSize int64) int64 {
+	const minPartSize = 10 * 1024 * 1024 // 10MB
+	const mb = 1024 * 1024
+	partSize := (fileSize/9000 + mb - 1) / mb * mb
+	if partSize < minPartSize {
+		return minPartSize
+	}
+	return partSize
+}
+
+// uploadMultipart performs a multipart upload for large files. Every part
+// that is stored is recorded in the local transfer state, so running the same
+// upload again after an interruption only sends the missing parts.
+func (s *S3Backend) uploadMultipart(ctx context.Context, file *os.File, key, remotePath string, fileSize int64, progress ProgressCallback) error {
+	partSize := multipartPartSize(fileSize)
+	state, err := loadUploadState(s.config.stateDir(), s.Name(), s.bucket, key, remotePath, file, partSize)
+	if err != nil {
+		return err
+	}
+
+	// Continue the recorded upload if S3 still has it; trust the parts S3
+	// reports over the ones in the state file
+	if state.UploadID != "" {
+		parts, err := s.listParts(ctx, key, state.UploadID)
+		if err != nil {
+			state.UploadID = ""
+			state.Parts = nil
+		} else {
+			state.Parts = parts
+		}
+	}
+	if state.UploadID == "" {
+		mode, until, hold := s.objectLock()
+		out, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:                    aws.String(s.bucket),
+			Key:                       aws.String(key),
+			ChecksumAlgorithm:         types.ChecksumAlgorithmCrc32,
+			ObjectLockMode:            mode,
+			ObjectLockRetainUntilDate: until,
+			ObjectLockLegalHoldStatus: hold,
+			StorageClass:              types.StorageClass(s.config.uploadClass(key)),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start multipart upload: %w", err)
+		}
+		state.UploadID = aws.ToString(out.UploadId)
+		state.StartedAt = time.Now()
+	}
+	if err := state.Save(); err != nil {
+		return err
+	}
+
+	numParts := int32((fileSize + partSize - 1) / partSize)
+	done := make(map[int32]bool, len(state.Parts))
+	var mu sync.Mutex
+	uploaded := int64(0)
+	for _, p := range state.Parts {
+		done[p.Number] = true
+		uploaded += p.Size
+	}
+	if progress != nil && uploaded > 0 {
+		progress(uploaded, fileSize)
+	}
+
+	// Upload the missing parts, up to 10 at a time
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	parts := make(chan int32)
+	errs := make(chan error, 10)
+	var wg sync.WaitGroup
+	for w := 0; w < 10; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range parts {
+				offset := int64(n-1) * partSize
+				size := partSize
+				if offset+size > fileSize {
+					size = fileSize - offset
+				}
+
+				out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+					Bucket:            aws.String(s.bucket),
+					Key:               aws.String(key),
+					UploadId:          aws.String(state.UploadID),
+					PartNumber:        aws.Int32(n),
+					Body:              io.NewSectionReader(file, offset, size),
+					ContentLength:     aws.Int64(size),
+					ChecksumAlgorithm: types.ChecksumAlgorithmCrc32,
+				})
+				if err == nil {
+					err = state.AddPart(TransferPart{
+						Number:   n,
+						ETag:     aws.ToString(out.ETag),
+						Checksum: aws.ToString(out.ChecksumCRC32),
+						Size:     size,
+					})
+				}
+				if err != nil {
+					errs <- fmt.Errorf("failed to upload part %d: %w", n, err)
+					cancel()
+					return
+				}
+
+				mu.Lock()
+				uploaded += size
+				if progress != nil {
+					progress(uploaded, fileSize)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for n := int32(1); n <= numParts; n++ {
+		if done[n] {
+			continue
+		}
+		select {
+		case parts <- n:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(parts)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		// The parts uploaded so far stay in the bucket for the next attempt
+		return fmt.Errorf("multipart upload interrupted (run the upload again to resume): %w", err)
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("multipart upload interrupted: %w", err)
+	}
+
+	completed := make([]types.CompletedPart, 0, len(state.Parts))
+	for _, p := range state.Parts {
+		part := types.CompletedPart{
+			ETag:       aws.String(p.ETag),
+			PartNumber: aws.Int32(p.Number),
+		}
+		if p.Checksum != "" {
+			part.ChecksumCRC32 = aws.String(p.Checksum)
+		}
+		completed = append(completed, part)
+	}
+	sort.Slice(completed, func(i, j int) bool {
+		return *completed[i].PartNumber < *completed[j].PartNumber
 	})
 
-	// Wrap file with progress reader
-	var reader io.Reader = file
-	if progress != nil {
-		reader = NewProgressReader(file, fileSize, progress)
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(state.UploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
 	}
 
-	// Upload with multipart
-	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+	return state.Remove()
+}
+
+// listParts returns the parts S3 holds for a multipart upload
+func (s *S3Backend) listParts(ctx context.Context, key, uploadID string) ([]TransferPart, error) {
+	var parts []TransferPart
+	paginator := s3.NewListPartsPaginator(s.client, &s3.ListPartsInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page.Parts {
+			parts = append(parts, TransferPart{
+				Number:   aws.ToInt32(p.PartNumber),
+				ETag:     aws.ToString(p.ETag),
+				Checksum: aws.ToString(p.ChecksumCRC32),
+				Size:     aws.ToInt64(p.Size),
+			})
+		}
+	}
+	return parts, nil
+}
+
+// ListIncompleteUploads lists multipart uploads under prefix that were never
+// completed or aborted. Their parts are stored (and billed) until then.
+func (s *S3Backend) ListIncompleteUploads(ctx context.Context, prefix string) ([]IncompleteUpload, error) {
+	local, err := localUploads(s.config, s.Name(), "")
+	if err != nil {
+		return nil, err
+	}
+	localPaths := make(map[string]string, len(local))
+	for _, u := range local {
+		localPaths[u.UploadID] = u.LocalPath
+	}
+
+	var uploads []IncompleteUpload
+	paginator := s3.NewListMultipartUploadsPaginator(s.client, &s3.ListMultipartUploadsInput{
 		Bucket: aws.String(s.bucket),
-		Key:    aws.String(key),
-		Body:   reader,
+		Prefix: aws.String(s.buildKey(prefix)),
 	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list multipart uploads: %w", err)
+		}
+		for _, u := range page.Uploads {
+			key := aws.ToString(u.Key)
+			upload := IncompleteUpload{
+				Key:        key,
+				RemotePath: strings.TrimPrefix(strings.TrimPrefix(key, s.prefix), "/"),
+				UploadID:   aws.ToString(u.UploadId),
+				Initiated:  aws.ToTime(u.Initiated),
+				LocalPath:  localPaths[aws.ToString(u.UploadId)],
+			}
+			if parts, err := s.listParts(ctx, key, upload.UploadID); err == nil {
+				for _, p := range parts {
+					upload.Uploaded += p.Size
+				}
+			}
+			uploads = append(uploads, upload)
+		}
+	}
 
+	return uploads, nil
+}
+
+// AbortUpload aborts a multipart upload, deleting its stored parts
+func (s *S3Backend) AbortUpload(ctx context.Context, upload IncompleteUpload) error {
+	_, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(upload.Key),
+		UploadId: aws.String(upload.UploadID),
+	})
 	if err != nil {
-		return fmt.Errorf("multipart upload failed: %w", err)
+		var notFound *types.NoSuchUpload
+		if !errors.As(err, &notFound) {
+			return fmt.Errorf("failed to abort multipart upload: %w", err)
+		}
 	}
 
-	return nil
+	return removeUploadState(s.config, s.Name(), upload.Key)
 }
 
-// Download downloads a file from S3
-func (s *S3Backend) Download(ctx context.Context, remotePath, localPath string, progress ProgressCallback) error {
-	// Build S3 key
+// UploadStream uploads a stream of unknown size to S3 as a multipart upload.
+// Parts are buffered in memory, so the part size bounds both memory use
+// (PartSize * Concurrency) and the largest object (10,000 parts).
+func (s *S3Backend) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
 	key := s.buildKey(remotePath)
 
-	// Get object size first
-	size, err := s.GetSize(ctx, remotePath)
+	uploader := manager.NewUploader(s.client, func(u *manager.Uploader) {
+		// 256MB parts allow objects up to 2.5TB
+		u.PartSize = 256 * 1024 * 1024
+		u.Concurrency = 4
+		
+		// Abort the multipart upload if the stream fails
+		u.LeavePartsOnError = false
+	})
+
+	mode, until, hold := s.objectLock()
+	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:                    aws.String(s.bucket),
+		Key:                       aws.String(key),
+		Body:                      reader,
+		ObjectLockMode:            mode,
+		ObjectLockRetainUntilDate: until,
+		ObjectLockLegalHoldStatus: hold,
+		StorageClass:              types.StorageClass(s.config.uploadClass(key)),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get object size: %w", err)
+		return fmt.Errorf("streaming upload failed: %w", err)
 	}
 
-	// Download from S3
-	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+	return nil
+}
+
+// Download downloads a file from S3. An interrupted download is continued
+// with a range request the next time the same file is downloaded.
+func (s *S3Backend) Download(ctx context.Context, remotePath, localPath string, progress ProgressCallback) error {
+	// Build S3 key
+	key := s.buildKey(remotePath)
+
+	// Get object size and version first
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to download from S3: %w", err)
+		return fmt.Errorf("failed to get object metadata: %w", err)
 	}
-	defer result.Body.Close()
-
-	// Create local file
-	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	etag := aws.ToString(head.ETag)
+
+	state := loadDownloadState(s.config.stateDir(), s.Name(), s.bucket, key, remotePath, localPath)
+	open := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		input := &s3.GetObjectInput{
+			Bucket:  aws.String(s.bucket),
+			Key:     aws.String(key),
+			IfMatch: aws.String(etag),
+		}
+		if offset > 0 {
+			input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+		}
+		result, err := s.client.GetObject(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download from S3: %w", err)
+		}
+		return result.Body, nil
 	}
 
-	outFile, err := os.Create(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to create local file: %w", err)
-	}
-	defer outFile.Close()
+	return downloadResumable(ctx, state, aws.ToInt64(head.ContentLength), etag, open, progress)
+}
 
-	// Copy with progress tracking
-	var reader io.Reader = result.Body
-	if progress != nil {
-		reader = NewProgressReader(result.Body, size, progress)
-	}
+// DownloadStream opens an S3 object for reading
+func (s *S3Backend) DownloadStream(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	key := s.buildKey(remotePath)
 
-	_, err = io.Copy(outFile, reader)
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
 	}
 
-	return nil
+	return result.Body, nil
 }
 
 // List lists all backup files in S3
@@ -313,6 +612,27 @@ func (s *S3Backend) Exists(ctx context.Context, remotePath string) (bool, error)
 	return true, nil
 }
 
+// LockStatus returns the object lock retention and legal hold of an object
+func (s *S3Backend) LockStatus(ctx context.Context, remotePath string) (*ObjectLock, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.buildKey(remotePath)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object metadata: %w", err)
+	}
+
+	lock := &ObjectLock{
+		Mode:      strings.ToLower(string(head.ObjectLockMode)),
+		Until:     aws.ToTime(head.ObjectLockRetainUntilDate),
+		LegalHold: head.ObjectLockLegalHoldStatus == types.ObjectLockLegalHoldStatusOn,
+	}
+	if lock.Until.IsZero() && !lock.LegalHold {
+		return nil, nil
+	}
+	return lock, nil
+}
+
 // GetSize returns the size of a remote file
 func (s *S3Backend) GetSize(ctx context.Context, remotePath string) (int64, error) {
 	key := s.buildKey(remotePath)
@@ -333,6 +653,167 @@ func (s *S3Backend) GetSize(ctx context.Context, remotePath string) (int64, erro
 	return *result.ContentLength, nil
 }
 
+// SetStorageClass moves an object to another storage class by copying it
+// onto itself. Its metadata and object lock are kept. In a versioned bucket
+// the previous version stays in its old class until it expires.
+func (s *S3Backend) SetStorageClass(ctx context.Context, remotePath, class string) error {
+	key := s.buildKey(remotePath)
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get object metadata: %w", err)
+	}
+
+	source := url.PathEscape(s.bucket + "/" + key)
+	size := aws.ToInt64(head.ContentLength)
+
+	// CopyObject is limited to 5GB; larger objects are copied in parts
+	const maxCopySize = 5 * 1024 * 1024 * 1024
+	if size <= maxCopySize {
+		_, err = s.client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:                    aws.String(s.bucket),
+			Key:                       aws.String(key),
+			CopySource:                aws.String(source),
+			StorageClass:              types.StorageClass(class),
+			MetadataDirective:         types.MetadataDirectiveCopy,
+			ObjectLockMode:            head.ObjectLockMode,
+			ObjectLockRetainUntilDate: head.ObjectLockRetainUntilDate,
+			ObjectLockLegalHoldStatus: head.ObjectLockLegalHoldStatus,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to change storage class: %w", err)
+		}
+		return nil
+	}
+
+	upload, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:                    aws.String(s.bucket),
+		Key:                       aws.String(key),
+		StorageClass:              types.StorageClass(class),
+		ContentType:               head.ContentType,
+		Metadata:                  head.Metadata,
+		ObjectLockMode:            head.ObjectLockMode,
+		ObjectLockRetainUntilDate: head.ObjectLockRetainUntilDate,
+		ObjectLockLegalHoldStatus: head.ObjectLockLegalHoldStatus,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start multipart copy: %w", err)
+	}
+	abort := func(err error) error {
+		s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucket),
+			Key:      aws.String(key),
+			UploadId: upload.UploadId,
+		})
+		return err
+	}
+
+	partSize := multipartPartSize(size)
+	var parts []types.CompletedPart
+	for n, offset := int32(1), int64(0); offset < size; n, offset = n+1, offset+partSize {
+		end := min(offset+partSize, size) - 1
+		out, err := s.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:            aws.String(s.bucket),
+			Key:               aws.String(key),
+			UploadId:          upload.UploadId,
+			PartNumber:        aws.Int32(n),
+			CopySource:        aws.String(source),
+			CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, end)),
+			CopySourceIfMatch: head.ETag,
+		})
+		if err != nil {
+			return abort(fmt.Errorf("failed to copy part %d: %w", n, err))
+		}
+		parts = append(parts, types.CompletedPart{ETag: out.CopyPartResult.ETag, PartNumber: aws.Int32(n)})
+	}
+
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(fmt.Errorf("failed to complete multipart copy: %w", err))
+	}
+	return nil
+}
+
+// ArchiveStatus reports whether an object is in Glacier Flexible Retrieval,
+// Deep Archive or an Intelligent-Tiering archive tier, and whether a
+// restored copy of it is available
+func (s *S3Backend) ArchiveStatus(ctx context.Context, remotePath string) (*ArchiveStatus, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.buildKey(remotePath)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object metadata: %w", err)
+	}
+
+	status := &ArchiveStatus{StorageClass: string(head.StorageClass)}
+	if status.StorageClass == "" {
+		status.StorageClass = "STANDARD"
+	}
+	status.Archived = head.StorageClass == types.StorageClassGlacier ||
+		head.StorageClass == types.StorageClassDeepArchive || head.ArchiveStatus != ""
+	status.Readable = !status.Archived
+
+	// x-amz-restore: ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"
+	if restore := aws.ToString(head.Restore); restore != "" {
+		status.Restoring = strings.Contains(restore, `ongoing-request="true"`)
+		if strings.Contains(restore, `ongoing-request="false"`) {
+			status.Readable = true
+			if _, expiry, ok := strings.Cut(restore, `expiry-date="`); ok {
+				status.ReadableUntil, _ = time.Parse(time.RFC1123, strings.TrimSuffix(expiry, `"`))
+			}
+		}
+	}
+	return status, nil
+}
+
+// StartRehydrate requests a temporary readable copy of an archived object
+func (s *S3Backend) StartRehydrate(ctx context.Context, remotePath string, opts RehydrateOptions) error {
+	key := s.buildKey(remotePath)
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get object metadata: %w", err)
+	}
+
+	tier := types.TierStandard
+	switch strings.ToLower(opts.Priority) {
+	case "high", "expedited":
+		tier = types.TierExpedited
+	case "bulk", "low":
+		tier = types.TierBulk
+	}
+	request := &types.RestoreRequest{GlacierJobParameters: &types.GlacierJobParameters{Tier: tier}}
+
+	// Intelligent-Tiering moves the object itself back, without a copy that expires
+	if head.ArchiveStatus == "" {
+		days := opts.Days
+		if days <= 0 {
+			days = 3
+		}
+		request.Days = aws.Int32(int32(days))
+	}
+
+	_, err = s.client.RestoreObject(ctx, &s3.RestoreObjectInput{
+		Bucket:         aws.String(s.bucket),
+		Key:            aws.String(key),
+		RestoreRequest: request,
+	})
+	if err != nil && !strings.Contains(err.Error(), "RestoreAlreadyInProgress") {
+		return fmt.Errorf("failed to request restore of archived object: %w", err)
+	}
+	return nil
+}
+
 // BucketExists checks if the bucket exists and is accessible
 func (s *S3Backend) BucketExists(ctx context.Context) (bool, error) {
 	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
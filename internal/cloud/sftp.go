@@ -0,0 +1,516 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPBackend implements the Backend interface for a plain SSH server.
+//
+// Paths are absolute on the server unless they start with "~/", which is
+// relative to the login directory. Uploads are written to a hidden
+// ".<name>.part" file next to the target and renamed into place once
+// complete, so readers never see a partial backup.
+type SFTPBackend struct {
+	addr            string
+	user            string
+	prefix          string
+	config          *Config
+	auth            []ssh.AuthMethod
+	hostKeyCallback ssh.HostKeyCallback
+	agentConn       net.Conn // Connection to ssh-agent, if one is used
+
+	mu        sync.Mutex
+	sshClient *ssh.Client
+	client    *sftp.Client
+	lost      chan struct{} // Closed when the session's connection drops
+}
+
+// NewSFTPBackend creates a new SFTP backend. The server is taken from
+// cfg.Endpoint (host[:port], default port 22) or else cfg.Bucket, and the
+// user from cfg.AccessKey (default: the current user).
+//
+// Authentication uses the key in cfg.KeyFile or $DBBACKUP_SFTP_KEY (with an
+// optional $DBBACKUP_SFTP_KEY_PASSPHRASE), the SSH agent, and the default
+// ~/.ssh keys. The server's host key must be in cfg.KnownHostsFile,
+// $DBBACKUP_SFTP_KNOWN_HOSTS or ~/.ssh/known_hosts.
+func NewSFTPBackend(cfg *Config) (*SFTPBackend, error) {
+	addr := cfg.Endpoint
+	if addr == "" {
+		addr = cfg.Bucket
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("host is required for SFTP backend")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "22")
+	}
+
+	username := cfg.AccessKey
+	if username == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("SFTP user is required: %w", err)
+		}
+		username = current.Username
+	}
+
+	auth, agentConn, err := sftpAuthMethods(cfg)
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := sftpHostKeyCallback(cfg)
+	if err != nil {
+		if agentConn != nil {
+			agentConn.Close()
+		}
+		return nil, err
+	}
+
+	return &SFTPBackend{
+		addr:            addr,
+		user:            username,
+		prefix:          cleanSFTPPath(cfg.Prefix),
+		config:          cfg,
+		auth:            auth,
+		hostKeyCallback: hostKeyCallback,
+		agentConn:       agentConn,
+	}, nil
+}
+
+// sftpAuthMethods collects the keys to offer: an explicit key file, the SSH
+// agent and the default keys in ~/.ssh. The connection to the agent is
+// returned so the backend can close it.
+func sftpAuthMethods(cfg *Config) ([]ssh.AuthMethod, net.Conn, error) {
+	var signers []ssh.Signer
+
+	keyFile := cfg.KeyFile
+	if keyFile == "" {
+		keyFile = os.Getenv("DBBACKUP_SFTP_KEY")
+	}
+	if keyFile != "" {
+		signer, err := loadSSHKey(keyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		signers = append(signers, signer)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			if signer, err := loadSSHKey(filepath.Join(home, ".ssh", name)); err == nil {
+				signers = append(signers, signer)
+			}
+		}
+	}
+
+	var methods []ssh.AuthMethod
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	var agentConn net.Conn
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			agentConn = conn
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	if len(methods) == 0 {
+		return nil, nil, fmt.Errorf("no SSH key found for SFTP (set DBBACKUP_SFTP_KEY or start ssh-agent)")
+	}
+	return methods, agentConn, nil
+}
+
+// loadSSHKey reads a private key, decrypting it with $DBBACKUP_SFTP_KEY_PASSPHRASE if needed
+func loadSSHKey(keyFile string) (ssh.Signer, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+	if passphrase := os.Getenv("DBBACKUP_SFTP_KEY_PASSPHRASE"); passphrase != "" {
+		signer, err := ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+		if err == nil {
+			return signer, nil
+		}
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s: %w", keyFile, err)
+	}
+	return signer, nil
+}
+
+// sftpHostKeyCallback verifies servers against known_hosts. There is no
+// option to skip verification: add the server with ssh-keyscan first.
+func sftpHostKeyCallback(cfg *Config) (ssh.HostKeyCallback, error) {
+	var files []string
+	switch {
+	case cfg.KnownHostsFile != "":
+		files = []string{cfg.KnownHostsFile}
+	case os.Getenv("DBBACKUP_SFTP_KNOWN_HOSTS") != "":
+		files = []string{os.Getenv("DBBACKUP_SFTP_KNOWN_HOSTS")}
+	default:
+		if home, err := os.UserHomeDir(); err == nil {
+			files = append(files, filepath.Join(home, ".ssh", "known_hosts"))
+		}
+		files = append(files, "/etc/ssh/ssh_known_hosts")
+	}
+
+	var existing []string
+	for _, f := range files {
+		if _, err := os.Stat(f); err == nil {
+			existing = append(existing, f)
+		}
+	}
+	if len(existing) == 0 {
+		return nil, fmt.Errorf("no known_hosts file found for SFTP host verification (tried %s)", strings.Join(files, ", "))
+	}
+
+	callback, err := knownhosts.New(existing...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// Name returns the backend name
+func (s *SFTPBackend) Name() string {
+	return "sftp"
+}
+
+// connect returns the SFTP session, opening it on first use and again after
+// the connection dropped
+func (s *SFTPBackend) connect(ctx context.Context) (*sftp.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		select {
+		case <-s.lost:
+			s.closeSession()
+		default:
+			return s.client, nil
+		}
+	}
+
+	timeout := time.Duration(s.config.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", s.addr, err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, s.addr, &ssh.ClientConfig{
+		User:            s.user,
+		Auth:            s.auth,
+		HostKeyCallback: s.hostKeyCallback,
+		Timeout:         timeout,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SSH handshake with %s failed: %w", s.addr, err)
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+
+	client, err := sftp.NewClient(sshClient, sftp.UseConcurrentWrites(true))
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("SFTP subsystem not available on %s: %w", s.addr, err)
+	}
+
+	lost := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(lost)
+	}()
+
+	s.sshClient, s.client, s.lost = sshClient, client, lost
+	return client, nil
+}
+
+// closeSession closes the SFTP session and its SSH connection
+func (s *SFTPBackend) closeSession() error {
+	var err error
+	if s.client != nil {
+		err = s.client.Close()
+	}
+	if s.sshClient != nil {
+		if cerr := s.sshClient.Close(); err == nil {
+			err = cerr
+		}
+	}
+	s.sshClient, s.client, s.lost = nil, nil, nil
+	return err
+}
+
+// Close closes the SFTP session and the connection to ssh-agent. The backend
+// can't be used afterwards.
+func (s *SFTPBackend) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.closeSession()
+	if s.agentConn != nil {
+		if cerr := s.agentConn.Close(); err == nil {
+			err = cerr
+		}
+		s.agentConn = nil
+	}
+	return err
+}
+
+// cleanSFTPPath normalizes a path in the backend's namespace: a key, or
+// "~" and "~/..." for the login directory
+func cleanSFTPPath(p string) string {
+	p = strings.TrimPrefix(p, "/")
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		rest := cleanTreeKey(strings.TrimPrefix(p, "~"))
+		if rest == "" {
+			return "~"
+		}
+		return "~/" + rest
+	}
+	return cleanTreeKey(p)
+}
+
+// key maps a remote path to the backend namespace
+func (s *SFTPBackend) key(remotePath string) string {
+	return treeKey(s.prefix, remotePath, cleanSFTPPath)
+}
+
+// serverPath converts a key into the path sent to the server
+func serverPath(key string) string {
+	switch {
+	case key == "~":
+		return "."
+	case strings.HasPrefix(key, "~/"):
+		return strings.TrimPrefix(key, "~/")
+	}
+	return "/" + key
+}
+
+// Upload uploads a file to the SFTP server
+func (s *SFTPBackend) Upload(ctx context.Context, localPath, remotePath string, progress ProgressCallback) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	var reader io.Reader = file
+	if progress != nil {
+		reader = NewProgressReader(file, stat.Size(), progress)
+	}
+
+	return s.upload(ctx, reader, remotePath)
+}
+
+// UploadStream uploads everything read from reader until EOF
+func (s *SFTPBackend) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
+	return s.upload(ctx, reader, remotePath)
+}
+
+// upload writes to a hidden .part file and renames it into place when
+// complete. On failure the .part file is removed.
+func (s *SFTPBackend) upload(ctx context.Context, reader io.Reader, remotePath string) error {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	target := serverPath(s.key(remotePath))
+	if dir := path.Dir(target); dir != "/" && dir != "." {
+		if err := client.MkdirAll(dir); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	tmp := hiddenPartPath(target)
+	file, err := client.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+
+	_, err = file.ReadFrom(&contextReader{ctx: ctx, r: reader})
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		client.Remove(tmp)
+		return fmt.Errorf("failed to upload to %s: %w", target, err)
+	}
+
+	if err := s.rename(client, tmp, target); err != nil {
+		client.Remove(tmp)
+		return fmt.Errorf("failed to move upload into place: %w", err)
+	}
+
+	return nil
+}
+
+// rename moves a file, replacing the target. Without the posix-rename
+// extension the server refuses to replace it, so it is removed first.
+func (s *SFTPBackend) rename(client *sftp.Client, from, to string) error {
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+		return client.PosixRename(from, to)
+	}
+	if _, err := client.Stat(to); err == nil {
+		if err := client.Remove(to); err != nil {
+			return err
+		}
+	}
+	return client.Rename(from, to)
+}
+
+// Download downloads a file from the SFTP server. An interrupted download is
+// continued from where it stopped the next time the same file is downloaded.
+func (s *SFTPBackend) Download(ctx context.Context, remotePath, localPath string, progress ProgressCallback) error {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := s.key(remotePath)
+	fi, err := client.Stat(serverPath(key))
+	if err != nil {
+		return fmt.Errorf("failed to stat remote file: %w", err)
+	}
+
+	// Size and modification time identify the version of the file
+	version := fmt.Sprintf("%d-%d", fi.Size(), fi.ModTime().Unix())
+	state := loadDownloadState(s.config.stateDir(), s.Name(), s.addr, key, remotePath, localPath)
+
+	return downloadResumable(ctx, state, fi.Size(), version, func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		return s.openReader(ctx, client, key, offset)
+	}, progress)
+}
+
+// DownloadStream opens a remote file for reading
+func (s *SFTPBackend) DownloadStream(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.openReader(ctx, client, s.key(remotePath), 0)
+}
+
+// openReader streams a remote file from offset through a pipe, with reads
+// pipelined in the background
+func (s *SFTPBackend) openReader(ctx context.Context, client *sftp.Client, key string, offset int64) (io.ReadCloser, error) {
+	file, err := client.Open(serverPath(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open remote file: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek remote file: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		_, err := file.WriteTo(pw)
+		close(done)
+		file.Close()
+		pw.CloseWithError(err)
+	}()
+	go func() {
+		// Closing the pipe unblocks the copy when the context is cancelled
+		select {
+		case <-ctx.Done():
+			pw.CloseWithError(ctx.Err())
+		case <-done:
+		}
+	}()
+
+	return pr, nil
+}
+
+// List lists files whose key starts with prefix, walking subdirectories.
+// Hidden files (including uploads in progress) are skipped.
+func (s *SFTPBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return listTree(ctx, s.prefix, prefix, s.key, func(dir string) ([]treeEntry, error) {
+		infos, err := client.ReadDir(serverPath(dir))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", serverPath(dir), err)
+		}
+
+		entries := make([]treeEntry, 0, len(infos))
+		for _, fi := range infos {
+			entries = append(entries, treeEntry{Name: fi.Name(), Size: fi.Size(), ModTime: fi.ModTime(), IsDir: fi.IsDir()})
+		}
+		return entries, nil
+	})
+}
+
+// Delete deletes a file from the SFTP server
+func (s *SFTPBackend) Delete(ctx context.Context, remotePath string) error {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Remove(serverPath(s.key(remotePath))); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// Exists checks if a file exists on the SFTP server
+func (s *SFTPBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := client.Stat(serverPath(s.key(remotePath))); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check file existence: %w", err)
+	}
+	return true, nil
+}
+
+// GetSize returns the size of a remote file
+func (s *SFTPBackend) GetSize(ctx context.Context, remotePath string) (int64, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	fi, err := client.Stat(serverPath(s.key(remotePath)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat remote file: %w", err)
+	}
+	return fi.Size(), nil
+}
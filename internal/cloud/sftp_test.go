@@ -0,0 +1,261 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// newTestSFTPBackend returns a backend talking to an in-memory SFTP server
+// over pipes, and a second client of that server to inspect its files
+func newTestSFTPBackend(t *testing.T, prefix string) (*SFTPBackend, *sftp.Client) {
+	t.Helper()
+	srv := sftp.InMemHandler()
+
+	connect := func() *sftp.Client {
+		clientR, serverW := io.Pipe()
+		serverR, clientW := io.Pipe()
+		server := sftp.NewRequestServer(struct {
+			io.Reader
+			io.WriteCloser
+		}{serverR, serverW}, srv)
+		go server.Serve()
+
+		client, err := sftp.NewClientPipe(clientR, closeBoth{clientW, clientR})
+		if err != nil {
+			t.Fatalf("handshake failed: %v", err)
+		}
+		t.Cleanup(func() {
+			server.Close()
+			client.Close()
+		})
+		return client
+	}
+
+	s := &SFTPBackend{
+		addr:   "backup.example.com:22",
+		prefix: cleanSFTPPath(prefix),
+		config: &Config{StateDir: t.TempDir()},
+		client: connect(),
+	}
+	return s, connect()
+}
+
+// closeBoth closes both of the client's pipes, as closing an SSH channel
+// would, since the in-memory server doesn't close its end
+type closeBoth struct {
+	*io.PipeWriter
+	r *io.PipeReader
+}
+
+func (c closeBoth) Close() error {
+	c.r.Close()
+	return c.PipeWriter.Close()
+}
+
+func writeSFTPFile(t *testing.T, client *sftp.Client, p string, data []byte) {
+	t.Helper()
+	if err := client.MkdirAll(path.Dir(p)); err != nil {
+		t.Fatal(err)
+	}
+	f, err := client.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readSFTPFile(t *testing.T, client *sftp.Client, p string) []byte {
+	t.Helper()
+	f, err := client.Open(p)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", p, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSFTPUploadDownload(t *testing.T) {
+	s, srv := newTestSFTPBackend(t, "srv/backups")
+	ctx := context.Background()
+
+	data := bytes.Repeat([]byte("COPY public.t FROM stdin;\n"), 20000)
+	local := filepath.Join(t.TempDir(), "db_app.dump")
+	if err := os.WriteFile(local, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Upload(ctx, local, "db_app.dump", nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if got := readSFTPFile(t, srv, "/srv/backups/db_app.dump"); !bytes.Equal(got, data) {
+		t.Fatalf("Uploaded file doesn't match (%d bytes)", len(got))
+	}
+	if _, err := srv.Stat("/srv/backups/.db_app.dump.part"); err == nil {
+		t.Error("Temporary .part file left behind")
+	}
+
+	// Uploading again replaces the file
+	if err := s.UploadStream(ctx, strings.NewReader("v2"), "db_app.dump"); err != nil {
+		t.Fatalf("Overwriting upload failed: %v", err)
+	}
+	if got := readSFTPFile(t, srv, "/srv/backups/db_app.dump"); string(got) != "v2" {
+		t.Errorf("Expected the file to be replaced, got %d bytes", len(got))
+	}
+	if err := s.Upload(ctx, local, "db_app.dump", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Full keys and bare names refer to the same file
+	for _, p := range []string{"db_app.dump", "srv/backups/db_app.dump"} {
+		if size, err := s.GetSize(ctx, p); err != nil || size != int64(len(data)) {
+			t.Errorf("GetSize(%q) = %d, %v", p, size, err)
+		}
+	}
+
+	target := filepath.Join(t.TempDir(), "restored.dump")
+	if err := s.Download(ctx, "srv/backups/db_app.dump", target, nil); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if got, _ := os.ReadFile(target); !bytes.Equal(got, data) {
+		t.Errorf("Downloaded file doesn't match (%d bytes)", len(got))
+	}
+
+	r, err := s.DownloadStream(ctx, "db_app.dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Streamed file doesn't match (%d bytes, %v)", len(got), err)
+	}
+}
+
+func TestSFTPUploadStreamFailure(t *testing.T) {
+	s, srv := newTestSFTPBackend(t, "backups")
+
+	reader := io.MultiReader(bytes.NewReader(make([]byte, 100000)), errReader{})
+	if err := s.UploadStream(context.Background(), reader, "db.dump"); err == nil {
+		t.Fatal("Expected failed stream to fail the upload")
+	}
+	files, err := srv.ReadDir("/backups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range files {
+		t.Errorf("Failed upload left %s behind", fi.Name())
+	}
+}
+
+func TestSFTPListAndDelete(t *testing.T) {
+	s, srv := newTestSFTPBackend(t, "srv/backups")
+	ctx := context.Background()
+
+	writeSFTPFile(t, srv, "/srv/backups/db_app_1.dump", []byte("1"))
+	writeSFTPFile(t, srv, "/srv/backups/db_app_1.dump.meta.json", []byte("{}"))
+	writeSFTPFile(t, srv, "/srv/backups/2025/db_app_0.dump", []byte("0"))
+	writeSFTPFile(t, srv, "/srv/backups/.db_app_2.dump.part", []byte("partial"))
+	writeSFTPFile(t, srv, "/srv/backups-old/db_app_x.dump", []byte("x"))
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"srv/backups/2025/db_app_0.dump", "srv/backups/db_app_1.dump", "srv/backups/db_app_1.dump.meta.json"}},
+		{"srv/backups/", []string{"srv/backups/2025/db_app_0.dump", "srv/backups/db_app_1.dump", "srv/backups/db_app_1.dump.meta.json"}},
+		{"db_app_1", []string{"srv/backups/db_app_1.dump", "srv/backups/db_app_1.dump.meta.json"}},
+		{"2025/", []string{"srv/backups/2025/db_app_0.dump"}},
+		{"2026/", nil},
+	}
+	for _, tt := range tests {
+		files, err := s.List(ctx, tt.prefix)
+		if err != nil {
+			t.Fatalf("List(%q) failed: %v", tt.prefix, err)
+		}
+		var keys []string
+		for _, f := range files {
+			keys = append(keys, f.Key)
+		}
+		sort.Strings(keys)
+		if strings.Join(keys, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("List(%q) = %v, want %v", tt.prefix, keys, tt.want)
+		}
+	}
+
+	if err := s.Delete(ctx, "srv/backups/db_app_1.dump"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := s.Exists(ctx, "db_app_1.dump"); err != nil || exists {
+		t.Errorf("Expected file to be deleted (exists=%v, err=%v)", exists, err)
+	}
+}
+
+func TestSFTPClose(t *testing.T) {
+	s, _ := newTestSFTPBackend(t, "")
+	agentConn, agent := net.Pipe()
+	s.agentConn = agentConn
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if s.client != nil || s.agentConn != nil {
+		t.Error("Expected the session and agent connection to be released")
+	}
+	if _, err := agent.Write([]byte("x")); err == nil {
+		t.Error("Expected the agent connection to be closed")
+	}
+}
+
+func TestSFTPPaths(t *testing.T) {
+	tests := []struct {
+		prefix, remote, server string
+	}{
+		{"srv/backups", "db.dump", "/srv/backups/db.dump"},
+		{"srv/backups", "srv/backups/db.dump", "/srv/backups/db.dump"},
+		{"", "db.dump", "/db.dump"},
+		{".", "db.dump", "/db.dump"},
+		{"~/backups", "db.dump", "backups/db.dump"},
+		{"~", "db.dump", "db.dump"},
+		{"srv", "../etc/passwd", "/srv/etc/passwd"},
+	}
+	for _, tt := range tests {
+		s := &SFTPBackend{prefix: cleanSFTPPath(tt.prefix)}
+		if got := serverPath(s.key(tt.remote)); got != tt.server {
+			t.Errorf("prefix %q, path %q: got %q, want %q", tt.prefix, tt.remote, got, tt.server)
+		}
+	}
+}
+
+func TestParseSFTPURI(t *testing.T) {
+	uri, err := ParseCloudURI("sftp://backup@vault.example.com:2222/srv/backups/db_app.dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Provider != "sftp" || uri.User != "backup" || uri.Endpoint != "vault.example.com:2222" || uri.Path != "srv/backups/db_app.dump" {
+		t.Errorf("Unexpected parse result: %+v", uri)
+	}
+
+	cfg := uri.ToConfig()
+	if cfg.AccessKey != "backup" || cfg.Endpoint != "vault.example.com:2222" || cfg.Prefix != "srv/backups" {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	if !IsCloudURI("sftp://host/path") {
+		t.Error("Expected sftp:// to be recognised as a cloud URI")
+	}
+}
@@ -0,0 +1,160 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// storageClasses lists the storage classes of each backend, warmest first
+var storageClasses = map[string][]string{
+	"s3":    {"STANDARD", "INTELLIGENT_TIERING", "STANDARD_IA", "ONEZONE_IA", "GLACIER_IR", "GLACIER", "DEEP_ARCHIVE"},
+	"azure": {"Hot", "Cool", "Cold", "Archive"},
+	"gcs":   {"STANDARD", "NEARLINE", "COLDLINE", "ARCHIVE"},
+}
+
+// NormalizeStorageClass returns a backend's spelling of a storage class,
+// which is matched case-insensitively
+func NormalizeStorageClass(backend, class string) (string, error) {
+	classes, ok := storageClasses[backend]
+	if !ok {
+		return "", fmt.Errorf("storage classes are not supported by the %s backend (use s3, azure or gcs)", backend)
+	}
+	for _, c := range classes {
+		if strings.EqualFold(c, class) {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown %s storage class %q (%s)", backend, class, strings.Join(classes, ", "))
+}
+
+// Colder reports whether storage class a is colder than b. Unknown classes,
+// including "", count as the warmest.
+func Colder(backend, a, b string) bool {
+	classes := storageClasses[backend]
+	return max(slices.Index(classes, a), 0) > max(slices.Index(classes, b), 0)
+}
+
+// uploadClass returns the storage class for a new object. Metadata and
+// checksum files stay in the default class so backups can be listed,
+// verified and planned without rehydrating anything.
+func (c *Config) uploadClass(remotePath string) string {
+	for _, suffix := range []string{".meta.json", ".sha256", ".info"} {
+		if strings.HasSuffix(remotePath, suffix) {
+			return ""
+		}
+	}
+	return c.StorageClass
+}
+
+// ArchiveStatus describes whether an object can be read directly
+type ArchiveStatus struct {
+	StorageClass  string
+	Archived      bool      // Stored in a tier that must be rehydrated before reading
+	Restoring     bool      // A rehydration is in progress
+	Readable      bool      // Can be downloaded now
+	ReadableUntil time.Time // S3: when the restored copy expires
+}
+
+// RehydrateOptions controls how archived objects are made readable
+type RehydrateOptions struct {
+	Priority string        // standard (default), high/expedited, or bulk (S3 only)
+	Days     int           // S3: how long the restored copy stays readable (default 3)
+	Wait     time.Duration // How long to wait for the objects; 0 only requests them
+
+	// Log reports progress, e.g. logger.Logger.Info (optional)
+	Log func(msg string, keysAndValues ...interface{})
+}
+
+// TieringBackend is implemented by backends with storage classes. Backends
+// created with Config.StorageClass upload into that class.
+type TieringBackend interface {
+	Backend
+
+	// SetStorageClass moves an existing object to another storage class
+	SetStorageClass(ctx context.Context, remotePath, class string) error
+
+	// ArchiveStatus reports whether an object must be rehydrated first
+	ArchiveStatus(ctx context.Context, remotePath string) (*ArchiveStatus, error)
+
+	// StartRehydrate requests a readable copy of an archived object. S3
+	// restores a temporary copy; Azure moves the blob back to the hot tier.
+	StartRehydrate(ctx context.Context, remotePath string, opts RehydrateOptions) error
+}
+
+// ArchivedError is returned when an object is still being rehydrated
+type ArchivedError struct {
+	Path         string
+	StorageClass string
+}
+
+func (e *ArchivedError) Error() string {
+	return fmt.Sprintf("%s is archived in %s and not readable yet (rehydration has been requested and can take hours)", e.Path, e.StorageClass)
+}
+
+// rehydratePollInterval is how often rehydrating objects are checked
+var rehydratePollInterval = time.Minute
+
+// Rehydrate makes archived objects readable: it requests a rehydration for
+// each one that needs it, then polls until all are readable or opts.Wait
+// has passed. Requesting them all first lets the provider work on them at
+// the same time. Backends without archive tiers need nothing.
+func Rehydrate(ctx context.Context, backend Backend, remotePaths []string, opts RehydrateOptions) error {
+	tb, ok := backend.(TieringBackend)
+	if !ok {
+		return nil
+	}
+	logf := opts.Log
+	if logf == nil {
+		logf = func(string, ...interface{}) {}
+	}
+
+	pending := make(map[string]string) // path -> storage class
+	for _, p := range remotePaths {
+		status, err := tb.ArchiveStatus(ctx, p)
+		if err != nil {
+			return err
+		}
+		if !status.Archived || status.Readable {
+			continue
+		}
+		if !status.Restoring {
+			if err := tb.StartRehydrate(ctx, p, opts); err != nil {
+				return err
+			}
+			logf("Requested rehydration of archived object", "object", p, "storage_class", status.StorageClass)
+		} else {
+			logf("Archived object is already being rehydrated", "object", p, "storage_class", status.StorageClass)
+		}
+		pending[p] = status.StorageClass
+	}
+
+	deadline := time.Now().Add(opts.Wait)
+	for len(pending) > 0 {
+		if !time.Now().Before(deadline) {
+			paths := slices.Sorted(maps.Keys(pending))
+			return &ArchivedError{Path: paths[0], StorageClass: pending[paths[0]]}
+		}
+		logf("Waiting for archived objects to be rehydrated", "objects", len(pending), "give_up_at", deadline.Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(rehydratePollInterval, time.Until(deadline))):
+		}
+
+		for p := range pending {
+			status, err := tb.ArchiveStatus(ctx, p)
+			if err != nil {
+				return err
+			}
+			if !status.Archived || status.Readable {
+				logf("Archived object is readable", "object", p)
+				delete(pending, p)
+			}
+		}
+	}
+	return nil
+}
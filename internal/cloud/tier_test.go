@@ -0,0 +1,125 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNormalizeStorageClass(t *testing.T) {
+	tests := []struct {
+		backend, class, want string
+		wantErr              bool
+	}{
+		{"s3", "glacier_ir", "GLACIER_IR", false},
+		{"s3", "DEEP_ARCHIVE", "DEEP_ARCHIVE", false},
+		{"azure", "cool", "Cool", false},
+		{"gcs", "Nearline", "NEARLINE", false},
+		{"s3", "Cool", "", true},
+		{"file", "STANDARD", "", true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeStorageClass(tt.backend, tt.class)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeStorageClass(%q, %q) = %q, %v; want %q", tt.backend, tt.class, got, err, tt.want)
+		}
+	}
+
+	if !Colder("s3", "GLACIER", "STANDARD_IA") || Colder("s3", "STANDARD", "") || !Colder("azure", "Cool", "") {
+		t.Error("Colder ordered the storage classes wrongly")
+	}
+	if _, err := NewBackend(&Config{Provider: "file", Bucket: t.TempDir(), StorageClass: "GLACIER"}); err == nil {
+		t.Error("Expected a storage class on a file backend to be rejected")
+	}
+}
+
+func TestUploadClassKeepsSidecarsStandard(t *testing.T) {
+	cfg := &Config{StorageClass: "GLACIER_IR"}
+	if got := cfg.uploadClass("pg/db_app_20250101_020000.dump"); got != "GLACIER_IR" {
+		t.Errorf("Expected the backup in GLACIER_IR, got %q", got)
+	}
+	for _, sidecar := range []string{".meta.json", ".sha256", ".info"} {
+		if got := cfg.uploadClass("pg/db_app_20250101_020000.dump" + sidecar); got != "" {
+			t.Errorf("Expected %s in the default class, got %q", sidecar, got)
+		}
+	}
+}
+
+// archiveFileBackend is a file backend whose objects are archived until
+// rehydrated, which takes `polls` status checks after the request
+type archiveFileBackend struct {
+	*FileBackend
+	mu       sync.Mutex
+	archived map[string]int // path -> status checks left once requested, -1 until requested
+	requests int
+}
+
+func (b *archiveFileBackend) SetStorageClass(ctx context.Context, remotePath, class string) error {
+	return nil
+}
+
+func (b *archiveFileBackend) ArchiveStatus(ctx context.Context, remotePath string) (*ArchiveStatus, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	left, ok := b.archived[remotePath]
+	if !ok {
+		return &ArchiveStatus{StorageClass: "STANDARD", Readable: true}, nil
+	}
+	status := &ArchiveStatus{StorageClass: "GLACIER", Archived: true, Restoring: left >= 0}
+	if left == 0 {
+		status.Readable = true
+	} else if left > 0 {
+		b.archived[remotePath] = left - 1
+	}
+	return status, nil
+}
+
+func (b *archiveFileBackend) StartRehydrate(ctx context.Context, remotePath string, opts RehydrateOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests++
+	b.archived[remotePath] = 2
+	return nil
+}
+
+func TestRehydrate(t *testing.T) {
+	defer func(d time.Duration) { rehydratePollInterval = d }(rehydratePollInterval)
+	rehydratePollInterval = time.Millisecond
+
+	files, err := NewFileBackend(&Config{Provider: "file", Bucket: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	paths := []string{"full.dump", "incr1.dump", "incr2.dump"}
+
+	backend := &archiveFileBackend{FileBackend: files, archived: map[string]int{"full.dump": -1, "incr1.dump": -1}}
+	if err := Rehydrate(ctx, backend, paths, RehydrateOptions{Wait: time.Minute}); err != nil {
+		t.Fatalf("Rehydrate failed: %v", err)
+	}
+	if backend.requests != 2 {
+		t.Errorf("Expected 2 rehydration requests, got %d", backend.requests)
+	}
+
+	// Without waiting, the request is made and the caller told to come back
+	backend = &archiveFileBackend{FileBackend: files, archived: map[string]int{"incr2.dump": -1}}
+	var archived *ArchivedError
+	if err := Rehydrate(ctx, backend, paths, RehydrateOptions{}); !errors.As(err, &archived) || archived.Path != "incr2.dump" {
+		t.Errorf("Expected an ArchivedError for incr2.dump, got %v", err)
+	}
+	if backend.requests != 1 {
+		t.Errorf("Expected the rehydration to be requested, got %d requests", backend.requests)
+	}
+
+	// A rehydration already in progress isn't requested again
+	backend.archived["incr2.dump"] = 1
+	if err := Rehydrate(ctx, backend, paths, RehydrateOptions{Wait: time.Minute}); err != nil || backend.requests != 1 {
+		t.Errorf("Expected to wait for the running rehydration, got %v after %d requests", err, backend.requests)
+	}
+
+	if err := Rehydrate(ctx, files, paths, RehydrateOptions{}); err != nil {
+		t.Errorf("Backends without archive tiers need nothing, got %v", err)
+	}
+}
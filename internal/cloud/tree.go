@@ -0,0 +1,116 @@
+package cloud
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+)
+
+// Backends that store files in a directory tree (local filesystem, SFTP,
+// WebDAV) rather than a flat bucket share how keys map to paths and how a
+// prefix is listed. Keys use forward slashes and never start with one.
+
+// treeEntry is an entry of a directory in a tree backend
+type treeEntry struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+	ETag    string
+}
+
+// cleanTreeKey normalizes a path into a key: no leading slash, no "." or ".."
+// elements, "" for the root
+func cleanTreeKey(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// treeKey maps a remote path to a key under prefix, unless it already is
+// (callers pass both bare file names and full keys)
+func treeKey(prefix, remotePath string, clean func(string) string) string {
+	p := clean(remotePath)
+	if prefix == "" || p == prefix || strings.HasPrefix(p, prefix+"/") {
+		return p
+	}
+	return clean(path.Join(prefix, p))
+}
+
+// hiddenPartPath is where an upload is written before it is renamed into
+// place. Hidden files are skipped by listTree, so it is never listed as a backup.
+func hiddenPartPath(p string) string {
+	return path.Join(path.Dir(p), "."+path.Base(p)+".part")
+}
+
+// listTree lists the files whose key starts with the key of prefix, walking
+// subdirectories. A prefix ending in "/", or naming the configured prefix
+// itself, lists that directory. readDir returns the entries of a directory
+// key ("" for the root) and nil for a directory that doesn't exist. Hidden
+// files, including uploads in progress, are skipped.
+func listTree(ctx context.Context, configured, prefix string, key func(string) string, readDir func(dir string) ([]treeEntry, error)) ([]BackupInfo, error) {
+	full := key(prefix)
+	if full != "" && (strings.HasSuffix(prefix, "/") || full == configured) {
+		full += "/"
+	}
+
+	// Start from the deepest directory the prefix names
+	dir := full
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+	}
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "." {
+		dir = ""
+	}
+
+	var files []BackupInfo
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entries, err := readDir(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name, ".") {
+				continue
+			}
+			key := entry.Name
+			if dir != "" {
+				key = dir + "/" + entry.Name
+			}
+
+			if entry.IsDir {
+				// Walk directories on the way to the prefix and inside it, but
+				// never the whole tree when there is no prefix at all
+				if strings.HasPrefix(full, key+"/") || (full != "" && strings.HasPrefix(key+"/", full)) {
+					if err := walk(key); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if !strings.HasPrefix(key, full) {
+				continue
+			}
+
+			files = append(files, BackupInfo{
+				Key:          key,
+				Name:         entry.Name,
+				Size:         entry.Size,
+				LastModified: entry.ModTime,
+				ETag:         entry.ETag,
+			})
+		}
+		return nil
+	}
+
+	if err := walk(dir); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
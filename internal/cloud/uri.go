@@ -9,11 +9,12 @@ import (
 
 // CloudURI represents a parsed cloud storage URI
 type CloudURI struct {
-	Provider string // "s3", "minio", "azure", "gcs", "b2"
-	Bucket   string // Bucket or container name
+	Provider string // "s3", "minio", "azure", "gcs", "b2", "sftp", "file", "webdav", "webdavs"
+	Bucket   string // Bucket or container name (host[:port] for SFTP and WebDAV, "/" for file)
 	Path     string // Path within bucket (without leading /)
 	Region   string // Region (optional, extracted from host)
 	Endpoint string // Custom endpoint (for MinIO, etc)
+	User     string // Login user (SFTP, WebDAV)
 	FullURI  string // Original URI string
 }
 
@@ -25,6 +26,9 @@ type CloudURI struct {
 //   - azure://container/path/file.dump
 //   - gs://bucket/path/file.dump (Google Cloud Storage)
 //   - b2://bucket/path/file.dump (Backblaze B2)
+//   - sftp://user@host:port/path/file.dump (absolute path; ~/path for the login directory)
+//   - file:///mnt/offsite/path/file.dump (local directory or NFS/SMB mount)
+//   - webdav://user@host:port/path/file.dump (webdavs:// for HTTPS)
 func ParseCloudURI(uri string) (*CloudURI, error) {
 	if uri == "" {
 		return nil, fmt.Errorf("URI cannot be empty")
@@ -44,15 +48,67 @@ func ParseCloudURI(uri string) (*CloudURI, error) {
 
 	// Validate provider
 	validProviders := map[string]bool{
-		"s3":    true,
-		"minio": true,
-		"azure": true,
-		"gs":    true,
-		"gcs":   true,
-		"b2":    true,
+		"s3":      true,
+		"minio":   true,
+		"azure":   true,
+		"gs":      true,
+		"gcs":     true,
+		"b2":      true,
+		"sftp":    true,
+		"file":    true,
+		"webdav":  true,
+		"webdavs": true,
 	}
 	if !validProviders[provider] {
-		return nil, fmt.Errorf("unsupported provider: %s (supported: s3, minio, azure, gs, gcs, b2)", provider)
+		return nil, fmt.Errorf("unsupported provider: %s (supported: s3, minio, azure, gs, gcs, b2, sftp, file, webdav, webdavs)", provider)
+	}
+
+	// Local paths are relative to the filesystem root
+	if provider == "file" {
+		if parsed.Host != "" && parsed.Host != "localhost" {
+			return nil, fmt.Errorf("file URIs must name a local path (e.g., file:///mnt/backups)")
+		}
+		return &CloudURI{
+			Provider: provider,
+			Bucket:   "/",
+			Path:     strings.TrimPrefix(parsed.Path, "/"),
+			FullURI:  uri,
+		}, nil
+	}
+
+	// WebDAV URIs name a server; the path includes any base path such as
+	// Nextcloud's /remote.php/dav/files/<user>
+	if provider == "webdav" || provider == "webdavs" {
+		if parsed.Host == "" {
+			return nil, fmt.Errorf("URI must specify a host (e.g., %s://host/path)", provider)
+		}
+		scheme := "https"
+		if provider == "webdav" {
+			scheme = "http"
+		}
+		return &CloudURI{
+			Provider: provider,
+			Bucket:   parsed.Host,
+			Path:     strings.TrimPrefix(parsed.Path, "/"),
+			Endpoint: scheme + "://" + parsed.Host,
+			User:     parsed.User.Username(),
+			FullURI:  uri,
+		}, nil
+	}
+
+	// SFTP URIs name a server rather than a bucket
+	if provider == "sftp" {
+		if parsed.Host == "" {
+			return nil, fmt.Errorf("URI must specify a host (e.g., sftp://user@host/path)")
+		}
+		return &CloudURI{
+			Provider: provider,
+			Bucket:   parsed.Host,
+			Path:     strings.TrimPrefix(parsed.Path, "/"),
+			Endpoint: parsed.Host,
+			User:     parsed.User.Username(),
+			FullURI:  uri,
+		}, nil
 	}
 
 	// Normalize provider names
@@ -133,7 +189,11 @@ func IsCloudURI(s string) bool {
 		strings.HasPrefix(s, "azure://") ||
 		strings.HasPrefix(s, "gs://") ||
 		strings.HasPrefix(s, "gcs://") ||
-		strings.HasPrefix(s, "b2://")
+		strings.HasPrefix(s, "b2://") ||
+		strings.HasPrefix(s, "sftp://") ||
+		strings.HasPrefix(s, "file://") ||
+		strings.HasPrefix(s, "webdav://") ||
+		strings.HasPrefix(s, "webdavs://")
 }
 
 // String returns the string representation of the URI
@@ -157,7 +217,7 @@ func (u *CloudURI) Join(elem ...string) string {
 	for _, e := range elem {
 		newPath = path.Join(newPath, e)
 	}
-	return fmt.Sprintf("%s://%s/%s", u.Provider, u.Bucket, newPath)
+	return fmt.Sprintf("%s://%s/%s", u.Provider, strings.TrimSuffix(u.Bucket, "/"), newPath)
 }
 
 // ToConfig converts a CloudURI to a cloud.Config
@@ -166,6 +226,7 @@ func (u *CloudURI) ToConfig() *Config {
 		Provider: u.Provider,
 		Bucket:   u.Bucket,
 		Prefix:   u.Dir(), // Use directory part as prefix
+		UseSSL:   true,
 	}
 
 	// Set region if available
@@ -178,6 +239,10 @@ func (u *CloudURI) ToConfig() *Config {
 		cfg.Endpoint = u.Endpoint
 	}
 
+	if u.User != "" {
+		cfg.AccessKey = u.User
+	}
+
 	// Provider-specific settings
 	switch u.Provider {
 	case "minio":
@@ -0,0 +1,460 @@
+package cloud
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebDAVBackend implements the Backend interface for a WebDAV server
+// (Nextcloud, ownCloud, Apache mod_dav, nginx, NAS appliances).
+//
+// Keys are paths below the endpoint URL. Uploads are PUT to a hidden file
+// and MOVEd into place once complete, so other clients never see a partial
+// backup. Missing collections are created with MKCOL.
+type WebDAVBackend struct {
+	base     *url.URL
+	user     string
+	password string
+	prefix   string
+	config   *Config
+	client   *http.Client
+}
+
+// NewWebDAVBackend creates a new WebDAV backend. The server is taken from
+// cfg.Endpoint (a URL, which may include a base path such as
+// https://cloud.example.com/remote.php/dav/files/alice) or else cfg.Bucket
+// (host[:port], https for "webdavs" and http for "webdav").
+//
+// Credentials come from cfg.AccessKey and cfg.SecretKey, or from
+// $DBBACKUP_WEBDAV_USER and $DBBACKUP_WEBDAV_PASSWORD.
+func NewWebDAVBackend(cfg *Config) (*WebDAVBackend, error) {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = cfg.Bucket
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("server is required for WebDAV")
+	}
+	if !strings.Contains(endpoint, "://") {
+		scheme := "https"
+		if cfg.Provider == "webdav" {
+			scheme = "http"
+		}
+		endpoint = scheme + "://" + endpoint
+	}
+
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebDAV endpoint %s: %w", endpoint, err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid WebDAV endpoint %s: scheme must be http or https", endpoint)
+	}
+
+	user := cfg.AccessKey
+	if user == "" {
+		user = os.Getenv("DBBACKUP_WEBDAV_USER")
+	}
+	password := cfg.SecretKey
+	if password == "" {
+		password = os.Getenv("DBBACKUP_WEBDAV_PASSWORD")
+	}
+
+	return &WebDAVBackend{
+		base:     base,
+		user:     user,
+		password: password,
+		prefix:   cleanTreeKey(cfg.Prefix),
+		config:   cfg,
+		client:   &http.Client{},
+	}, nil
+}
+
+// Name returns the backend name
+func (w *WebDAVBackend) Name() string {
+	return "webdav"
+}
+
+// webdavError is a request the server answered with an unexpected status
+type webdavError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Status     string
+}
+
+func (e *webdavError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Method, e.Path, e.Status)
+}
+
+// isWebDAVNotFound reports whether err is a 404 from the server
+func isWebDAVNotFound(err error) bool {
+	var e *webdavError
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// key maps a remote path to a key under the prefix
+func (w *WebDAVBackend) key(remotePath string) string {
+	return treeKey(w.prefix, remotePath, cleanTreeKey)
+}
+
+// urlPath returns the server path of a key
+func (w *WebDAVBackend) urlPath(key string) string {
+	return path.Join("/", w.base.Path, key)
+}
+
+// url returns the URL of a key. Collections get a trailing slash, which some
+// servers require.
+func (w *WebDAVBackend) url(key string, collection bool) string {
+	u := *w.base
+	u.Path = w.urlPath(key)
+	u.RawPath = ""
+	if collection && !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u.String()
+}
+
+// uploadPath is where an upload is written before it is moved into place.
+// Nextcloud refuses names ending in ".part", so it has its own suffix.
+func uploadPath(key string) string {
+	return path.Join(path.Dir(key), "."+path.Base(key)+".uploading")
+}
+
+// do sends a request and returns the response if its status is one of ok.
+// Otherwise the body is discarded and a *webdavError returned.
+func (w *WebDAVBackend) do(ctx context.Context, method, target string, body io.Reader, header http.Header, ok ...int) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if w.user != "" || w.password != "" {
+		req.SetBasicAuth(w.user, w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range ok {
+		if resp.StatusCode == code {
+			return resp, nil
+		}
+	}
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	return nil, &webdavError{Method: method, Path: req.URL.Path, StatusCode: resp.StatusCode, Status: resp.Status}
+}
+
+// mkcolAll creates a collection and any missing parents. Parents are only
+// created when the server reports them missing, so nothing above an
+// existing collection (such as Nextcloud's /remote.php) is touched.
+func (w *WebDAVBackend) mkcolAll(ctx context.Context, dir string) error {
+	if dir == "" || dir == "." {
+		return nil
+	}
+
+	resp, err := w.do(ctx, "MKCOL", w.url(dir, true), nil, nil, http.StatusCreated, http.StatusMethodNotAllowed)
+	if err == nil {
+		// 405: the collection exists
+		resp.Body.Close()
+		return nil
+	}
+
+	var e *webdavError
+	if !errors.As(err, &e) || e.StatusCode != http.StatusConflict {
+		return fmt.Errorf("failed to create collection %s: %w", dir, err)
+	}
+
+	// 409: the parent is missing
+	if err := w.mkcolAll(ctx, path.Dir(dir)); err != nil {
+		return err
+	}
+	resp, err = w.do(ctx, "MKCOL", w.url(dir, true), nil, nil, http.StatusCreated, http.StatusMethodNotAllowed)
+	if err != nil {
+		return fmt.Errorf("failed to create collection %s: %w", dir, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Upload uploads a file to the WebDAV server
+func (w *WebDAVBackend) Upload(ctx context.Context, localPath, remotePath string, progress ProgressCallback) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	var reader io.Reader = file
+	if progress != nil {
+		reader = NewProgressReader(file, stat.Size(), progress)
+	}
+
+	return w.upload(ctx, reader, stat.Size(), remotePath)
+}
+
+// UploadStream uploads everything read from reader until EOF, using chunked
+// transfer encoding
+func (w *WebDAVBackend) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
+	return w.upload(ctx, reader, -1, remotePath)
+}
+
+// upload PUTs to a hidden file and MOVEs it into place when complete. On
+// failure the hidden file is deleted. size is -1 if unknown.
+func (w *WebDAVBackend) upload(ctx context.Context, reader io.Reader, size int64, remotePath string) error {
+	key := w.key(remotePath)
+	if err := w.mkcolAll(ctx, path.Dir(key)); err != nil {
+		return err
+	}
+
+	tmp := uploadPath(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, w.url(tmp, false), io.NopCloser(reader))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	if w.user != "" || w.password != "" {
+		req.SetBasicAuth(w.user, w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+			err = &webdavError{Method: http.MethodPut, Path: req.URL.Path, StatusCode: resp.StatusCode, Status: resp.Status}
+		}
+	}
+	if err != nil {
+		w.remove(tmp)
+		return fmt.Errorf("failed to upload to %s: %w", key, err)
+	}
+
+	header := http.Header{
+		"Destination": {w.url(key, false)},
+		"Overwrite":   {"T"},
+	}
+	resp, err = w.do(ctx, "MOVE", w.url(tmp, false), nil, header, http.StatusCreated, http.StatusNoContent)
+	if err != nil {
+		w.remove(tmp)
+		return fmt.Errorf("failed to move upload into place: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// remove deletes a leftover upload, even if the upload's context is cancelled
+func (w *WebDAVBackend) remove(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if resp, err := w.do(ctx, http.MethodDelete, w.url(key, false), nil, nil, http.StatusOK, http.StatusNoContent); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// Download downloads a file from the WebDAV server. An interrupted download
+// is continued with a range request the next time the same file is downloaded.
+func (w *WebDAVBackend) Download(ctx context.Context, remotePath, localPath string, progress ProgressCallback) error {
+	key := w.key(remotePath)
+	entry, err := w.stat(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to stat remote file: %w", err)
+	}
+
+	version := entry.ETag
+	if version == "" {
+		version = fmt.Sprintf("%d-%d", entry.Size, entry.ModTime.Unix())
+	}
+	state := loadDownloadState(w.config.stateDir(), w.Name(), w.base.Host, key, remotePath, localPath)
+
+	return downloadResumable(ctx, state, entry.Size, version, func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		return w.get(ctx, key, offset, entry.ETag)
+	}, progress)
+}
+
+// get opens a file from offset. With an etag, the request fails if the file
+// has been replaced since.
+func (w *WebDAVBackend) get(ctx context.Context, key string, offset int64, etag string) (io.ReadCloser, error) {
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	// Weak ETags never match If-Match
+	if etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("If-Match", etag)
+	}
+
+	resp, err := w.do(ctx, http.MethodGet, w.url(key, false), nil, header, http.StatusOK, http.StatusPartialContent)
+	if err != nil {
+		return nil, err
+	}
+
+	// A server without range support sends the whole file
+	if offset > 0 && resp.StatusCode == http.StatusOK {
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	return resp.Body, nil
+}
+
+// DownloadStream opens a remote file for reading
+func (w *WebDAVBackend) DownloadStream(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	body, err := w.get(ctx, w.key(remotePath), 0, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	return body, nil
+}
+
+// davMultistatus is the body of a PROPFIND response
+type davMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength string `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+				ETag          string `xml:"DAV: getetag"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<propfind xmlns="DAV:"><prop><resourcetype/><getcontentlength/><getlastmodified/><getetag/></prop></propfind>`
+
+// propfind returns the properties of a key (depth 0) or of a collection and
+// its members (depth 1), keyed by server path without a trailing slash
+func (w *WebDAVBackend) propfind(ctx context.Context, key string, collection bool, depth string) (map[string]treeEntry, error) {
+	header := http.Header{
+		"Depth":        {depth},
+		"Content-Type": {"application/xml; charset=utf-8"},
+	}
+	resp, err := w.do(ctx, "PROPFIND", w.url(key, collection), strings.NewReader(propfindBody), header, http.StatusMultiStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var ms davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("failed to parse PROPFIND response: %w", err)
+	}
+
+	entries := make(map[string]treeEntry, len(ms.Responses))
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		p := strings.TrimSuffix(href.Path, "/")
+
+		entry := treeEntry{Name: path.Base(p)}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200") {
+				continue
+			}
+			entry.IsDir = ps.Prop.ResourceType.Collection != nil
+			entry.Size, _ = strconv.ParseInt(ps.Prop.ContentLength, 10, 64)
+			entry.ModTime, _ = http.ParseTime(ps.Prop.LastModified)
+			entry.ETag = ps.Prop.ETag
+		}
+		entries[p] = entry
+	}
+	return entries, nil
+}
+
+// stat returns the properties of a file
+func (w *WebDAVBackend) stat(ctx context.Context, key string) (treeEntry, error) {
+	entries, err := w.propfind(ctx, key, false, "0")
+	if err != nil {
+		return treeEntry{}, err
+	}
+	for _, entry := range entries {
+		return entry, nil
+	}
+	return treeEntry{}, fmt.Errorf("no properties returned for %s", key)
+}
+
+// List lists files whose key starts with prefix, walking subdirectories.
+// Hidden files (including uploads in progress) are skipped.
+func (w *WebDAVBackend) List(ctx context.Context, prefix string) ([]BackupInfo, error) {
+	return listTree(ctx, w.prefix, prefix, w.key, func(dir string) ([]treeEntry, error) {
+		members, err := w.propfind(ctx, dir, true, "1")
+		if err != nil {
+			if isWebDAVNotFound(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+
+		// The response includes the collection itself
+		self := strings.TrimSuffix(w.urlPath(dir), "/")
+		entries := make([]treeEntry, 0, len(members))
+		for p, entry := range members {
+			if p != self {
+				entries = append(entries, entry)
+			}
+		}
+		return entries, nil
+	})
+}
+
+// Delete deletes a file from the WebDAV server
+func (w *WebDAVBackend) Delete(ctx context.Context, remotePath string) error {
+	resp, err := w.do(ctx, http.MethodDelete, w.url(w.key(remotePath), false), nil, nil, http.StatusOK, http.StatusNoContent)
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Exists checks if a file exists on the WebDAV server
+func (w *WebDAVBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	if _, err := w.stat(ctx, w.key(remotePath)); err != nil {
+		if isWebDAVNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check file existence: %w", err)
+	}
+	return true, nil
+}
+
+// GetSize returns the size of a remote file
+func (w *WebDAVBackend) GetSize(ctx context.Context, remotePath string) (int64, error) {
+	entry, err := w.stat(ctx, w.key(remotePath))
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat remote file: %w", err)
+	}
+	return entry.Size, nil
+}
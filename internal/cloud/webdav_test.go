@@ -0,0 +1,208 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+// newTestWebDAV serves an in-memory WebDAV tree below /dav, behind basic auth
+func newTestWebDAV(t *testing.T) (*httptest.Server, webdav.FileSystem, *[]string) {
+	t.Helper()
+	fs := webdav.NewMemFS()
+	handler := &webdav.Handler{Prefix: "/dav", FileSystem: fs, LockSystem: webdav.NewMemLS()}
+
+	var mu sync.Mutex
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, fs, &methods
+}
+
+func newTestWebDAVBackend(t *testing.T, srv *httptest.Server, prefix string) *WebDAVBackend {
+	t.Helper()
+	w, err := NewWebDAVBackend(&Config{
+		Provider:  "webdav",
+		Endpoint:  srv.URL + "/dav",
+		AccessKey: "alice",
+		SecretKey: "secret",
+		Prefix:    prefix,
+		StateDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestWebDAVRoundTrip(t *testing.T) {
+	srv, fs, methods := newTestWebDAV(t)
+	w := newTestWebDAVBackend(t, srv, "backups/daily")
+	ctx := context.Background()
+
+	data := bytes.Repeat([]byte("INSERT INTO t VALUES (1);\n"), 10000)
+	local := filepath.Join(t.TempDir(), "db app.dump")
+	if err := os.WriteFile(local, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Upload(ctx, local, "db app.dump", nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if !strings.Contains(strings.Join(*methods, ","), "MOVE") {
+		t.Errorf("Expected upload to be moved into place, got %v", *methods)
+	}
+	if _, err := fs.Stat(ctx, "/backups/daily/.db app.dump.uploading"); !os.IsNotExist(err) {
+		t.Error("Temporary upload left behind")
+	}
+
+	for _, p := range []string{"db app.dump", "backups/daily/db app.dump"} {
+		if size, err := w.GetSize(ctx, p); err != nil || size != int64(len(data)) {
+			t.Errorf("GetSize(%q) = %d, %v", p, size, err)
+		}
+	}
+
+	target := filepath.Join(t.TempDir(), "restored.dump")
+	if err := w.Download(ctx, "backups/daily/db app.dump", target, nil); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if got, _ := os.ReadFile(target); !bytes.Equal(got, data) {
+		t.Errorf("Downloaded file doesn't match (%d bytes)", len(got))
+	}
+
+	if err := w.UploadStream(ctx, strings.NewReader("{}"), "db app.dump.meta.json"); err != nil {
+		t.Fatalf("UploadStream failed: %v", err)
+	}
+	files, err := w.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, f := range files {
+		keys = append(keys, f.Key)
+	}
+	sort.Strings(keys)
+	if want := "backups/daily/db app.dump,backups/daily/db app.dump.meta.json"; strings.Join(keys, ",") != want {
+		t.Errorf("List = %v, want %s", keys, want)
+	}
+
+	if err := w.Delete(ctx, "db app.dump"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := w.Exists(ctx, "db app.dump"); err != nil || exists {
+		t.Errorf("Expected file to be deleted (exists=%v, err=%v)", exists, err)
+	}
+}
+
+func TestWebDAVResumesDownload(t *testing.T) {
+	srv, fs, _ := newTestWebDAV(t)
+	w := newTestWebDAVBackend(t, srv, "")
+	ctx := context.Background()
+
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	file, err := fs.OpenFile(ctx, "/db.dump", os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(data)
+	file.Close()
+
+	// A previous attempt stopped half way with the same version of the file
+	entry, err := w.stat(ctx, "db.dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(t.TempDir(), "db.dump")
+	state := loadDownloadState(w.config.stateDir(), w.Name(), w.base.Host, "db.dump", "db.dump", target)
+	state.ETag, state.Size = entry.ETag, entry.Size
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(partialPath(target), data[:4000], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Download(ctx, "db.dump", target, nil); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if got, _ := os.ReadFile(target); !bytes.Equal(got, data) {
+		t.Errorf("Resumed download doesn't match (%d bytes)", len(got))
+	}
+}
+
+func TestWebDAVUploadStreamFailure(t *testing.T) {
+	srv, fs, _ := newTestWebDAV(t)
+	w := newTestWebDAVBackend(t, srv, "backups")
+
+	reader := io.MultiReader(bytes.NewReader(make([]byte, 100000)), errReader{})
+	if err := w.UploadStream(context.Background(), reader, "db.dump"); err == nil {
+		t.Fatal("Expected failed stream to fail the upload")
+	}
+
+	dir, err := fs.OpenFile(context.Background(), "/backups", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+	infos, _ := dir.Readdir(-1)
+	for _, info := range infos {
+		t.Errorf("Failed upload left %s behind", info.Name())
+	}
+}
+
+func TestWebDAVAuthFailure(t *testing.T) {
+	srv, _, _ := newTestWebDAV(t)
+	w := newTestWebDAVBackend(t, srv, "")
+	w.password = "wrong"
+
+	_, err := w.Exists(context.Background(), "db.dump")
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+}
+
+func TestParseWebDAVURI(t *testing.T) {
+	uri, err := ParseCloudURI("webdavs://alice@cloud.example.com/remote.php/dav/files/alice/backups/db.dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Provider != "webdavs" || uri.User != "alice" || uri.Endpoint != "https://cloud.example.com" ||
+		uri.Path != "remote.php/dav/files/alice/backups/db.dump" {
+		t.Errorf("Unexpected parse result: %+v", uri)
+	}
+
+	w, err := NewWebDAVBackend(uri.ToConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.url(w.key("db.dump"), false); got != "https://cloud.example.com/remote.php/dav/files/alice/backups/db.dump" {
+		t.Errorf("Unexpected URL %s", got)
+	}
+
+	plain, err := NewWebDAVBackend(&Config{Provider: "webdav", Bucket: "nas.local:8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := plain.url("backups", true); got != "http://nas.local:8080/backups/" {
+		t.Errorf("Unexpected URL %s", got)
+	}
+}
@@ -0,0 +1,194 @@
+// Package compression selects the compressor used for dumps, cluster archives
+// and WAL files. gzip runs in-process or through pigz; zstd and lz4 run their
+// command line tools, the same way pg_dump and tar are run.
+package compression
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Algorithm is a compression algorithm
+type Algorithm string
+
+const (
+	None Algorithm = "none"
+	Gzip Algorithm = "gzip"
+	Zstd Algorithm = "zstd"
+	LZ4  Algorithm = "lz4"
+)
+
+// DefaultZstdLongWindow is the window log used for zstd long-distance matching
+// (128 MB). Decompression allows windows up to 2 GB, so archives written with a
+// larger --long setting by hand can still be read.
+const (
+	DefaultZstdLongWindow = 27
+	maxZstdLongWindow     = 31
+)
+
+// Magic bytes of the supported formats
+var (
+	gzipMagic      = []byte{0x1f, 0x8b}
+	zstdMagic      = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	lz4Magic       = []byte{0x04, 0x22, 0x4d, 0x18}
+	lz4LegacyMagic = []byte{0x02, 0x21, 0x4c, 0x18}
+)
+
+// MagicSize is the number of bytes Detect needs
+const MagicSize = 4
+
+// ParseAlgorithm parses an algorithm name as given on the command line
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "gzip", "gz", "pigz":
+		return Gzip, nil
+	case "zstd", "zst":
+		return Zstd, nil
+	case "lz4":
+		return LZ4, nil
+	case "none", "off":
+		return None, nil
+	}
+	return "", fmt.Errorf("unknown compression algorithm %q (use gzip, zstd, lz4 or none)", name)
+}
+
+// Extension returns the file extension of the algorithm, including the dot
+func (a Algorithm) Extension() string {
+	switch a {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	case LZ4:
+		return ".lz4"
+	}
+	return ""
+}
+
+// MaxLevel returns the highest compression level the algorithm accepts
+func (a Algorithm) MaxLevel() int {
+	switch a {
+	case Gzip:
+		return 9
+	case Zstd:
+		return 19
+	case LZ4:
+		return 12
+	}
+	return 0
+}
+
+// FromExtension returns the algorithm a file name's extension stands for
+func FromExtension(name string) Algorithm {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz", ".tgz":
+		return Gzip
+	case ".zst", ".tzst":
+		return Zstd
+	case ".lz4":
+		return LZ4
+	}
+	return None
+}
+
+// TrimExtension removes a compression extension from a file name
+func TrimExtension(name string) string {
+	if FromExtension(name) == None {
+		return name
+	}
+	ext := filepath.Ext(name)
+	switch strings.ToLower(ext) {
+	case ".tgz", ".tzst":
+		return strings.TrimSuffix(name, ext) + ".tar"
+	}
+	return strings.TrimSuffix(name, ext)
+}
+
+// Detect returns the algorithm compressed data was written with, from its
+// first MagicSize bytes
+func Detect(header []byte) Algorithm {
+	switch {
+	case hasPrefix(header, gzipMagic):
+		return Gzip
+	case hasPrefix(header, zstdMagic):
+		return Zstd
+	case hasPrefix(header, lz4Magic), hasPrefix(header, lz4LegacyMagic):
+		return LZ4
+	}
+	return None
+}
+
+// DetectFile returns the algorithm the file at path was compressed with
+func DetectFile(path string) Algorithm {
+	f, err := os.Open(path)
+	if err != nil {
+		return None
+	}
+	defer f.Close()
+
+	header := make([]byte, MagicSize)
+	n, _ := io.ReadFull(f, header)
+	return Detect(header[:n])
+}
+
+func hasPrefix(b, prefix []byte) bool {
+	return len(b) >= len(prefix) && string(b[:len(prefix)]) == string(prefix)
+}
+
+// Options selects an algorithm and how it runs
+type Options struct {
+	Algorithm Algorithm
+	Level     int  // Clamped to the algorithm's range (config maps level 0 to None)
+	Threads   int  // Worker threads for pigz and zstd; 0 uses every core
+	Long      bool // zstd long-distance matching, for large dumps with far-apart repetitions
+}
+
+// Enabled reports whether data is compressed at all
+func (o Options) Enabled() bool {
+	return o.Algorithm != None && o.Algorithm != ""
+}
+
+// level clamps the configured level to what the algorithm accepts
+func (o Options) level() int {
+	level := o.Level
+	if level < 1 {
+		level = 1
+	}
+	if max := o.Algorithm.MaxLevel(); level > max {
+		level = max
+	}
+	return level
+}
+
+// String describes the options as recorded in backup metadata, e.g. "zstd-3"
+func (o Options) String() string {
+	if !o.Enabled() {
+		return string(None)
+	}
+	name := string(o.Algorithm)
+	if o.Algorithm == Gzip && o.Threads > 1 && hasCommand("pigz") {
+		name = "pigz"
+	}
+	s := fmt.Sprintf("%s-%d", name, o.level())
+	if o.Algorithm == Zstd && o.Long {
+		s += "-long"
+	}
+	return s
+}
+
+// Validate checks the level against the algorithm and that its tool is installed
+func (o Options) Validate() error {
+	if o.Algorithm == None || o.Algorithm == "" {
+		return nil
+	}
+	if o.Level < 0 || o.Level > o.Algorithm.MaxLevel() {
+		return fmt.Errorf("%s compression level must be between 0-%d", o.Algorithm, o.Algorithm.MaxLevel())
+	}
+	if o.Algorithm != Gzip {
+		return requireCommand(o.Algorithm)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseAlgorithm(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Algorithm
+		wantErr bool
+	}{
+		{"", Gzip, false},
+		{"gzip", Gzip, false},
+		{"pigz", Gzip, false},
+		{"ZSTD", Zstd, false},
+		{"zst", Zstd, false},
+		{"lz4", LZ4, false},
+		{"none", None, false},
+		{"brotli", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAlgorithm(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAlgorithm(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAlgorithm(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	tests := []struct {
+		name    string
+		alg     Algorithm
+		trimmed string
+	}{
+		{"db.sql.gz", Gzip, "db.sql"},
+		{"cluster_20250101.tar.zst", Zstd, "cluster_20250101.tar"},
+		{"cluster_20250101.tzst", Zstd, "cluster_20250101.tar"},
+		{"cluster_20250101.tgz", Gzip, "cluster_20250101.tar"},
+		{"000000010000000000000001.lz4", LZ4, "000000010000000000000001"},
+		{"db.dump", None, "db.dump"},
+	}
+
+	for _, tt := range tests {
+		if got := FromExtension(tt.name); got != tt.alg {
+			t.Errorf("FromExtension(%q) = %q, want %q", tt.name, got, tt.alg)
+		}
+		if got := TrimExtension(tt.name); got != tt.trimmed {
+			t.Errorf("TrimExtension(%q) = %q, want %q", tt.name, got, tt.trimmed)
+		}
+	}
+}
+
+func TestOptionsString(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{Algorithm: None, Level: 6}, "none"},
+		{Options{Algorithm: Zstd, Level: 3}, "zstd-3"},
+		{Options{Algorithm: Zstd, Level: 25, Long: true}, "zstd-19-long"},
+		{Options{Algorithm: LZ4, Level: 0}, "lz4-1"},
+		{Options{Algorithm: Gzip, Level: 6, Threads: 1}, "gzip-6"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.opts.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestValidateLevel(t *testing.T) {
+	if err := (Options{Algorithm: Gzip, Level: 10}).Validate(); err == nil {
+		t.Error("expected error for gzip level 10")
+	}
+	if err := (Options{Algorithm: Gzip, Level: 9}).Validate(); err != nil {
+		t.Errorf("unexpected error for gzip level 9: %v", err)
+	}
+	if err := (Options{Algorithm: None, Level: 42}).Validate(); err != nil {
+		t.Errorf("level should be ignored without compression: %v", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(strings.Repeat("INSERT INTO t VALUES (1, 'dbbackup');\n", 10000))
+
+	for _, alg := range []Algorithm{Gzip, Zstd, LZ4} {
+		t.Run(string(alg), func(t *testing.T) {
+			if alg != Gzip && !hasCommand(string(alg)) {
+				t.Skipf("%s not installed", alg)
+			}
+
+			var compressed bytes.Buffer
+			w, err := NewWriter(ctx, &compressed, Options{Algorithm: alg, Level: 3, Threads: 2, Long: true})
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			if got := Detect(compressed.Bytes()); got != alg {
+				t.Fatalf("Detect() = %q, want %q", got, alg)
+			}
+			if compressed.Len() >= len(data) {
+				t.Errorf("compressed size %d is not smaller than %d", compressed.Len(), len(data))
+			}
+
+			r, err := NewReader(ctx, &compressed)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer r.Close()
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("round trip mismatch: got %d bytes, want %d", len(got), len(data))
+			}
+		})
+	}
+}
+
+func TestNewReaderRejectsPlainData(t *testing.T) {
+	if _, err := NewReader(context.Background(), strings.NewReader("plain SQL")); err == nil {
+		t.Error("expected error for uncompressed data")
+	}
+}
+
+func TestCorruptStreamFails(t *testing.T) {
+	if !hasCommand("zstd") {
+		t.Skip("zstd not installed")
+	}
+
+	corrupt := append(append([]byte{}, zstdMagic...), bytes.Repeat([]byte{0xff}, 64)...)
+	r, err := NewReader(context.Background(), bytes.NewReader(corrupt))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("expected error reading corrupt zstd stream")
+	}
+}
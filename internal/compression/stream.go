@@ -0,0 +1,243 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// hasCommand reports whether a binary is on the PATH
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// requireCommand returns an error if the tool of an algorithm is missing
+func requireCommand(alg Algorithm) error {
+	if !hasCommand(string(alg)) {
+		return fmt.Errorf("%s compression requires the %s command, which was not found in PATH", alg, alg)
+	}
+	return nil
+}
+
+// Command returns a compressor that reads stdin and writes to stdout. Without
+// an algorithm the data is passed through unchanged, so pipelines stay the same.
+func (o Options) Command(ctx context.Context) (*exec.Cmd, error) {
+	level := "-" + strconv.Itoa(o.level())
+
+	switch o.Algorithm {
+	case None:
+		return exec.CommandContext(ctx, "cat"), nil
+
+	case Gzip:
+		if hasCommand("pigz") {
+			args := []string{level, "-c"}
+			if o.Threads > 0 {
+				args = append([]string{"-p", strconv.Itoa(o.Threads)}, args...)
+			}
+			return exec.CommandContext(ctx, "pigz", args...), nil
+		}
+		return exec.CommandContext(ctx, "gzip", level, "-c"), nil
+
+	case Zstd:
+		if err := requireCommand(Zstd); err != nil {
+			return nil, err
+		}
+		args := []string{"-q", "-c", level, "-T" + strconv.Itoa(o.Threads)}
+		if o.Long {
+			args = append(args, fmt.Sprintf("--long=%d", DefaultZstdLongWindow))
+		}
+		return exec.CommandContext(ctx, "zstd", args...), nil
+
+	case LZ4:
+		if err := requireCommand(LZ4); err != nil {
+			return nil, err
+		}
+		return exec.CommandContext(ctx, "lz4", "-q", "-c", level), nil
+	}
+	return nil, fmt.Errorf("no compressor for algorithm %q", o.Algorithm)
+}
+
+// DecompressCommand returns a decompressor that reads stdin and writes to stdout
+func DecompressCommand(ctx context.Context, alg Algorithm) (*exec.Cmd, error) {
+	switch alg {
+	case Gzip:
+		if hasCommand("pigz") {
+			return exec.CommandContext(ctx, "pigz", "-dc"), nil
+		}
+		return exec.CommandContext(ctx, "gzip", "-dc"), nil
+	case Zstd:
+		if err := requireCommand(Zstd); err != nil {
+			return nil, err
+		}
+		return exec.CommandContext(ctx, "zstd", "-q", "-dc", fmt.Sprintf("--long=%d", maxZstdLongWindow)), nil
+	case LZ4:
+		if err := requireCommand(LZ4); err != nil {
+			return nil, err
+		}
+		return exec.CommandContext(ctx, "lz4", "-q", "-dc"), nil
+	}
+	return nil, fmt.Errorf("no decompressor for algorithm %q", alg)
+}
+
+// ShellDecompressCommand returns the decompressor for alg as a shell command
+// line, for restore pipelines run through bash
+func ShellDecompressCommand(alg Algorithm) (string, error) {
+	cmd, err := DecompressCommand(context.Background(), alg)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(cmd.Args, " "), nil
+}
+
+// NewWriter returns a writer that compresses to w. Close flushes the
+// compressor but does not close w.
+func NewWriter(ctx context.Context, w io.Writer, opts Options) (io.WriteCloser, error) {
+	if !opts.Enabled() {
+		return nopWriteCloser{w}, nil
+	}
+	if opts.Algorithm == Gzip {
+		gz, err := gzip.NewWriterLevel(w, opts.level())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+		}
+		return gz, nil
+	}
+
+	cmd, err := opts.Command(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pw := &processWriter{cmd: cmd}
+	cmd.Stdout = w
+	cmd.Stderr = &pw.stderr
+	if pw.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, fmt.Errorf("failed to create %s pipe: %w", opts.Algorithm, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", opts.Algorithm, err)
+	}
+	return pw, nil
+}
+
+// NewReader returns a reader that decompresses r, detecting the algorithm
+// from the magic bytes
+func NewReader(ctx context.Context, r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(MagicSize)
+
+	alg := Detect(header)
+	switch alg {
+	case None:
+		return nil, fmt.Errorf("data is not compressed with a supported algorithm (gzip, zstd, lz4)")
+	case Gzip:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip stream: %w", err)
+		}
+		return gz, nil
+	}
+
+	cmd, err := DecompressCommand(ctx, alg)
+	if err != nil {
+		return nil, err
+	}
+	pr := &processReader{cmd: cmd, name: string(alg)}
+	cmd.Stdin = br
+	cmd.Stderr = &pr.stderr
+	if pr.stdout, err = cmd.StdoutPipe(); err != nil {
+		return nil, fmt.Errorf("failed to create %s pipe: %w", alg, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", alg, err)
+	}
+	return pr, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// processWriter feeds a compressor process
+type processWriter struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+}
+
+func (pw *processWriter) Write(p []byte) (int, error) {
+	n, err := pw.stdin.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("%s failed: %w", pw.cmd.Args[0], err)
+	}
+	return n, nil
+}
+
+func (pw *processWriter) Close() error {
+	pw.stdin.Close()
+	if err := pw.cmd.Wait(); err != nil {
+		return processError(pw.cmd.Args[0], err, &pw.stderr)
+	}
+	return nil
+}
+
+// processReader reads the output of a decompressor process. Errors of the
+// process (corrupt input) are returned at the end of the stream.
+type processReader struct {
+	cmd    *exec.Cmd
+	name   string
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+
+	once    sync.Once
+	waitErr error
+}
+
+func (pr *processReader) Read(p []byte) (int, error) {
+	n, err := pr.stdout.Read(p)
+	if err == io.EOF {
+		if werr := pr.wait(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (pr *processReader) wait() error {
+	pr.once.Do(func() {
+		if err := pr.cmd.Wait(); err != nil {
+			pr.waitErr = processError(pr.name, err, &pr.stderr)
+		}
+	})
+	return pr.waitErr
+}
+
+// Close stops the process; closing before the end of the stream is not an error
+func (pr *processReader) Close() error {
+	done := false
+	pr.once.Do(func() {
+		done = true
+		pr.cmd.Process.Kill()
+		pr.cmd.Wait()
+	})
+	if done {
+		return nil
+	}
+	return pr.waitErr
+}
+
+func processError(name string, err error, stderr *bytes.Buffer) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("%s failed: %w: %s", name, err, msg)
+	}
+	return fmt.Errorf("%s failed: %w", name, err)
+}
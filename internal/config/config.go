@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"dbbackup/internal/compression"
 	"dbbackup/internal/cpu"
 )
 
@@ -28,13 +29,15 @@ type Config struct {
 	Insecure     bool
 
 	// Backup options
-	BackupDir        string
-	CompressionLevel int
-	Jobs             int
-	DumpJobs         int
-	MaxCores         int
-	AutoDetectCores  bool
-	CPUWorkloadType  string // "cpu-intensive", "io-intensive", "balanced"
+	BackupDir            string
+	CompressionLevel     int
+	CompressionAlgorithm string // "gzip", "zstd", "lz4" or "none"
+	ZstdLong             bool   // zstd long-distance matching
+	Jobs                 int
+	DumpJobs             int
+	MaxCores             int
+	AutoDetectCores      bool
+	CPUWorkloadType      string // "cpu-intensive", "io-intensive", "balanced"
 
 	// CPU detection
 	CPUDetector *cpu.Detector
@@ -76,6 +79,15 @@ type Config struct {
 	AllowRoot      bool // Allow running as root/Administrator
 	CheckResources bool // Check resource limits before operations
 
+	// Grandfather-father-son retention tiers (replace RetentionDays when set)
+	KeepLast          int
+	KeepWithin        string // Keep everything younger than this (e.g. "7d", "36h")
+	KeepDaily         int
+	KeepWeekly        int
+	KeepMonthly       int
+	KeepYearly        int
+	RetentionTimezone string // IANA time zone of the tiers' calendar (default: local)
+
 	// PITR (Point-in-Time Recovery) options
 	PITREnabled    bool   // Enable WAL archiving for PITR
 	WALArchiveDir  string // Directory to store WAL archives
@@ -94,7 +106,7 @@ type Config struct {
 
 	// Cloud storage options (v2.0)
 	CloudEnabled    bool   // Enable cloud storage integration
-	CloudProvider   string // "s3", "minio", "b2", "azure", "gcs"
+	CloudProvider   string // "s3", "minio", "b2", "azure", "gcs", "sftp", "file", "webdav", "webdavs"
 	CloudBucket     string // Bucket/container name
 	CloudRegion     string // Region (for S3, GCS)
 	CloudEndpoint   string // Custom endpoint (for MinIO, B2, Azurite, fake-gcs-server)
@@ -102,6 +114,18 @@ type Config struct {
 	CloudSecretKey  string // Secret key / Account key (Azure)
 	CloudPrefix     string // Key/object prefix
 	CloudAutoUpload bool   // Automatically upload after backup
+	CloudStream     bool   // Stream single backups to the bucket without a local file
+
+	// Named replication targets, uploaded to in addition to the above
+	CloudTargets []CloudTarget
+
+	// Object lock for uploaded backups (S3, Azure, GCS)
+	CloudLockMode  string // "governance" or "compliance"
+	CloudLockUntil string // Retain-until period ("30d") or date ("2027-01-31")
+	CloudLegalHold bool   // Place a legal hold on uploaded backups
+
+	// Storage class for uploads to the primary cloud storage (S3, Azure, GCS)
+	CloudStorageClass string
 }
 
 // New creates a new configuration with default values
@@ -155,13 +179,15 @@ func New() *Config {
 		Insecure:     getEnvBool("INSECURE", false),
 
 		// Backup defaults
-		BackupDir:        backupDir,
-		CompressionLevel: getEnvInt("COMPRESS_LEVEL", 6),
-		Jobs:             getEnvInt("JOBS", getDefaultJobs(cpuInfo)),
-		DumpJobs:         getEnvInt("DUMP_JOBS", getDefaultDumpJobs(cpuInfo)),
-		MaxCores:         getEnvInt("MAX_CORES", getDefaultMaxCores(cpuInfo)),
-		AutoDetectCores:  getEnvBool("AUTO_DETECT_CORES", true),
-		CPUWorkloadType:  getEnvString("CPU_WORKLOAD_TYPE", "balanced"),
+		BackupDir:            backupDir,
+		CompressionLevel:     getEnvInt("COMPRESS_LEVEL", 6),
+		CompressionAlgorithm: getEnvString("COMPRESS_ALGORITHM", "gzip"),
+		ZstdLong:             getEnvBool("ZSTD_LONG", false),
+		Jobs:                 getEnvInt("JOBS", getDefaultJobs(cpuInfo)),
+		DumpJobs:             getEnvInt("DUMP_JOBS", getDefaultDumpJobs(cpuInfo)),
+		MaxCores:             getEnvInt("MAX_CORES", getDefaultMaxCores(cpuInfo)),
+		AutoDetectCores:      getEnvBool("AUTO_DETECT_CORES", true),
+		CPUWorkloadType:      getEnvString("CPU_WORKLOAD_TYPE", "balanced"),
 
 		// CPU detection
 		CPUDetector: cpuDetector,
@@ -200,6 +226,15 @@ func New() *Config {
 		AllowRoot:      getEnvBool("ALLOW_ROOT", false),        // Disallow root by default
 		CheckResources: getEnvBool("CHECK_RESOURCES", true),    // Check resources by default
 
+		// Retention tiers (disabled by default)
+		KeepLast:          getEnvInt("KEEP_LAST", 0),
+		KeepWithin:        getEnvString("KEEP_WITHIN", ""),
+		KeepDaily:         getEnvInt("KEEP_DAILY", 0),
+		KeepWeekly:        getEnvInt("KEEP_WEEKLY", 0),
+		KeepMonthly:       getEnvInt("KEEP_MONTHLY", 0),
+		KeepYearly:        getEnvInt("KEEP_YEARLY", 0),
+		RetentionTimezone: getEnvString("RETENTION_TIMEZONE", ""),
+
 		// TUI automation defaults (for testing)
 		TUIAutoSelect:   getEnvInt("TUI_AUTO_SELECT", -1),      // -1 = disabled
 		TUIAutoDatabase: getEnvString("TUI_AUTO_DATABASE", ""), // Empty = manual input
@@ -220,6 +255,14 @@ func New() *Config {
 		CloudSecretKey:  getEnvString("CLOUD_SECRET_KEY", getEnvString("AWS_SECRET_ACCESS_KEY", "")),
 		CloudPrefix:     getEnvString("CLOUD_PREFIX", ""),
 		CloudAutoUpload: getEnvBool("CLOUD_AUTO_UPLOAD", false),
+		CloudStream:     getEnvBool("CLOUD_STREAM", false),
+
+		// Object lock is off unless configured
+		CloudLockMode:  getEnvString("CLOUD_LOCK_MODE", ""),
+		CloudLockUntil: getEnvString("CLOUD_LOCK_UNTIL", ""),
+		CloudLegalHold: getEnvBool("CLOUD_LEGAL_HOLD", false),
+
+		CloudStorageClass: getEnvString("CLOUD_STORAGE_CLASS", ""),
 	}
 
 	// Ensure canonical defaults are enforced
@@ -248,8 +291,11 @@ func (c *Config) Validate() error {
 		return err
 	}
 
-	if c.CompressionLevel < 0 || c.CompressionLevel > 9 {
-		return &ConfigError{Field: "compression", Value: string(rune(c.CompressionLevel)), Message: "must be between 0-9"}
+	if _, err := compression.ParseAlgorithm(c.CompressionAlgorithm); err != nil {
+		return &ConfigError{Field: "compression-algorithm", Value: c.CompressionAlgorithm, Message: "must be gzip, zstd, lz4 or none"}
+	}
+	if err := c.CompressionOptions().Validate(); err != nil {
+		return &ConfigError{Field: "compression", Value: strconv.Itoa(c.CompressionLevel), Message: err.Error()}
 	}
 
 	if c.Jobs < 1 {
@@ -263,6 +309,37 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// CompressionOptions returns the compressor settings for backups. pigz and
+// zstd use one thread per job.
+func (c *Config) CompressionOptions() compression.Options {
+	algorithm, err := compression.ParseAlgorithm(c.CompressionAlgorithm)
+	if err != nil {
+		algorithm = compression.Gzip
+	}
+	// Level 0 means no compression, whatever the algorithm
+	if c.CompressionLevel == 0 {
+		algorithm = compression.None
+	}
+	return compression.Options{
+		Algorithm: algorithm,
+		Level:     c.CompressionLevel,
+		Threads:   c.Jobs,
+		Long:      c.ZstdLong,
+	}
+}
+
+// HasRetentionTiers reports whether grandfather-father-son retention is configured
+func (c *Config) HasRetentionTiers() bool {
+	return c.KeepLast > 0 || c.KeepWithin != "" || c.KeepDaily > 0 ||
+		c.KeepWeekly > 0 || c.KeepMonthly > 0 || c.KeepYearly > 0
+}
+
+// CloudUploadEnabled reports whether backups are uploaded after they complete:
+// to the primary cloud storage with auto-upload, or to named targets
+func (c *Config) CloudUploadEnabled() bool {
+	return (c.CloudEnabled && c.CloudAutoUpload) || len(c.CloudTargets) > 0
+}
+
 // IsPostgreSQL returns true if database type is PostgreSQL
 func (c *Config) IsPostgreSQL() bool {
 	return c.DatabaseType == "postgres"
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+
+	"dbbackup/internal/compression"
+)
+
+func TestCompressionOptions(t *testing.T) {
+	for _, tc := range []struct {
+		algorithm string
+		level     int
+		want      compression.Algorithm
+	}{
+		{"gzip", 6, compression.Gzip},
+		{"zstd", 3, compression.Zstd},
+		{"zstd", 0, compression.None},
+		{"lz4", 0, compression.None},
+		{"none", 6, compression.None},
+	} {
+		cfg := &Config{CompressionAlgorithm: tc.algorithm, CompressionLevel: tc.level}
+		if got := cfg.CompressionOptions().Algorithm; got != tc.want {
+			t.Errorf("%s level %d: got %s, want %s", tc.algorithm, tc.level, got, tc.want)
+		}
+	}
+}
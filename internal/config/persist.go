@@ -21,10 +21,11 @@ type LocalConfig struct {
 	SSLMode  string
 
 	// Backup settings
-	BackupDir   string
-	Compression int
-	Jobs        int
-	DumpJobs    int
+	BackupDir            string
+	Compression          int
+	CompressionAlgorithm string
+	Jobs                 int
+	DumpJobs             int
 
 	// Performance settings
 	CPUWorkload string
@@ -34,6 +35,18 @@ type LocalConfig struct {
 	RetentionDays int
 	MinBackups    int
 	MaxRetries    int
+
+	// Retention tiers
+	KeepLast          int
+	KeepWithin        string
+	KeepDaily         int
+	KeepWeekly        int
+	KeepMonthly       int
+	KeepYearly        int
+	RetentionTimezone string
+
+	// Replication targets ([target.<name>] sections)
+	Targets []CloudTarget
 }
 
 // LoadLocalConfig loads configuration from .dbbackup.conf in current directory
@@ -75,6 +88,11 @@ func LoadLocalConfig() (*LocalConfig, error) {
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
 
+		if name, ok := strings.CutPrefix(currentSection, "target."); ok {
+			parseTargetKey(cfg.target(name), key, value)
+			continue
+		}
+
 		switch currentSection {
 		case "database":
 			switch key {
@@ -101,6 +119,8 @@ func LoadLocalConfig() (*LocalConfig, error) {
 				if c, err := strconv.Atoi(value); err == nil {
 					cfg.Compression = c
 				}
+			case "compression_algorithm":
+				cfg.CompressionAlgorithm = value
 			case "jobs":
 				if j, err := strconv.Atoi(value); err == nil {
 					cfg.Jobs = j
@@ -133,6 +153,20 @@ func LoadLocalConfig() (*LocalConfig, error) {
 				if mr, err := strconv.Atoi(value); err == nil {
 					cfg.MaxRetries = mr
 				}
+			case "keep_last":
+				cfg.KeepLast, _ = strconv.Atoi(value)
+			case "keep_within":
+				cfg.KeepWithin = value
+			case "keep_daily":
+				cfg.KeepDaily, _ = strconv.Atoi(value)
+			case "keep_weekly":
+				cfg.KeepWeekly, _ = strconv.Atoi(value)
+			case "keep_monthly":
+				cfg.KeepMonthly, _ = strconv.Atoi(value)
+			case "keep_yearly":
+				cfg.KeepYearly, _ = strconv.Atoi(value)
+			case "retention_timezone":
+				cfg.RetentionTimezone = value
 			}
 		}
 	}
@@ -140,6 +174,46 @@ func LoadLocalConfig() (*LocalConfig, error) {
 	return cfg, nil
 }
 
+// target returns the replication target with the given name, adding it
+// when it isn't known yet
+func (c *LocalConfig) target(name string) *CloudTarget {
+	for i := range c.Targets {
+		if c.Targets[i].Name == name {
+			return &c.Targets[i]
+		}
+	}
+	c.Targets = append(c.Targets, CloudTarget{Name: name})
+	return &c.Targets[len(c.Targets)-1]
+}
+
+// parseTargetKey sets a key of a [target.<name>] section
+func parseTargetKey(t *CloudTarget, key, value string) {
+	switch key {
+	case "uri":
+		t.URI = value
+	case "region":
+		t.Region = value
+	case "endpoint":
+		t.Endpoint = value
+	case "access_key":
+		t.AccessKey = value
+	case "secret_key":
+		t.SecretKey = value
+	case "retries":
+		if r, err := strconv.Atoi(value); err == nil {
+			t.Retries = r
+		}
+	case "timeout":
+		if s, err := strconv.Atoi(value); err == nil {
+			t.Timeout = s
+		}
+	case "storage_class":
+		t.StorageClass = value
+	case "insecure":
+		t.Insecure, _ = strconv.ParseBool(value)
+	}
+}
+
 // SaveLocalConfig saves configuration to .dbbackup.conf in current directory
 func SaveLocalConfig(cfg *LocalConfig) error {
 	var sb strings.Builder
@@ -177,6 +251,9 @@ func SaveLocalConfig(cfg *LocalConfig) error {
 	if cfg.Compression != 0 {
 		sb.WriteString(fmt.Sprintf("compression = %d\n", cfg.Compression))
 	}
+	if cfg.CompressionAlgorithm != "" {
+		sb.WriteString(fmt.Sprintf("compression_algorithm = %s\n", cfg.CompressionAlgorithm))
+	}
 	if cfg.Jobs != 0 {
 		sb.WriteString(fmt.Sprintf("jobs = %d\n", cfg.Jobs))
 	}
@@ -206,6 +283,50 @@ func SaveLocalConfig(cfg *LocalConfig) error {
 	if cfg.MaxRetries != 0 {
 		sb.WriteString(fmt.Sprintf("max_retries = %d\n", cfg.MaxRetries))
 	}
+	for _, tier := range []struct {
+		key   string
+		count int
+	}{{"keep_last", cfg.KeepLast}, {"keep_daily", cfg.KeepDaily}, {"keep_weekly", cfg.KeepWeekly}, {"keep_monthly", cfg.KeepMonthly}, {"keep_yearly", cfg.KeepYearly}} {
+		if tier.count != 0 {
+			sb.WriteString(fmt.Sprintf("%s = %d\n", tier.key, tier.count))
+		}
+	}
+	if cfg.KeepWithin != "" {
+		sb.WriteString(fmt.Sprintf("keep_within = %s\n", cfg.KeepWithin))
+	}
+	if cfg.RetentionTimezone != "" {
+		sb.WriteString(fmt.Sprintf("retention_timezone = %s\n", cfg.RetentionTimezone))
+	}
+
+	// Replication targets
+	for _, t := range cfg.Targets {
+		sb.WriteString(fmt.Sprintf("\n[target.%s]\n", t.Name))
+		sb.WriteString(fmt.Sprintf("uri = %s\n", t.URI))
+		if t.Region != "" {
+			sb.WriteString(fmt.Sprintf("region = %s\n", t.Region))
+		}
+		if t.Endpoint != "" {
+			sb.WriteString(fmt.Sprintf("endpoint = %s\n", t.Endpoint))
+		}
+		if t.AccessKey != "" {
+			sb.WriteString(fmt.Sprintf("access_key = %s\n", t.AccessKey))
+		}
+		if t.SecretKey != "" {
+			sb.WriteString(fmt.Sprintf("secret_key = %s\n", t.SecretKey))
+		}
+		if t.Retries != 0 {
+			sb.WriteString(fmt.Sprintf("retries = %d\n", t.Retries))
+		}
+		if t.Timeout != 0 {
+			sb.WriteString(fmt.Sprintf("timeout = %d\n", t.Timeout))
+		}
+		if t.StorageClass != "" {
+			sb.WriteString(fmt.Sprintf("storage_class = %s\n", t.StorageClass))
+		}
+		if t.Insecure {
+			sb.WriteString("insecure = true\n")
+		}
+	}
 
 	configPath := filepath.Join(".", ConfigFileName)
 	// Use 0600 permissions for security (readable/writable only by owner)
@@ -247,6 +368,9 @@ func ApplyLocalConfig(cfg *Config, local *LocalConfig) {
 	if cfg.CompressionLevel == 6 && local.Compression != 0 {
 		cfg.CompressionLevel = local.Compression
 	}
+	if cfg.CompressionAlgorithm == "gzip" && local.CompressionAlgorithm != "" {
+		cfg.CompressionAlgorithm = local.CompressionAlgorithm
+	}
 	if local.Jobs != 0 {
 		cfg.Jobs = local.Jobs
 	}
@@ -268,25 +392,48 @@ func ApplyLocalConfig(cfg *Config, local *LocalConfig) {
 	if cfg.MaxRetries == 3 && local.MaxRetries != 0 {
 		cfg.MaxRetries = local.MaxRetries
 	}
+	if !cfg.HasRetentionTiers() {
+		cfg.KeepLast = local.KeepLast
+		cfg.KeepWithin = local.KeepWithin
+		cfg.KeepDaily = local.KeepDaily
+		cfg.KeepWeekly = local.KeepWeekly
+		cfg.KeepMonthly = local.KeepMonthly
+		cfg.KeepYearly = local.KeepYearly
+	}
+	if cfg.RetentionTimezone == "" && local.RetentionTimezone != "" {
+		cfg.RetentionTimezone = local.RetentionTimezone
+	}
+	if len(cfg.CloudTargets) == 0 && len(local.Targets) > 0 {
+		cfg.CloudTargets = local.Targets
+	}
 }
 
 // ConfigFromConfig creates a LocalConfig from a Config
 func ConfigFromConfig(cfg *Config) *LocalConfig {
 	return &LocalConfig{
-		DBType:        cfg.DatabaseType,
-		Host:          cfg.Host,
-		Port:          cfg.Port,
-		User:          cfg.User,
-		Database:      cfg.Database,
-		SSLMode:       cfg.SSLMode,
-		BackupDir:     cfg.BackupDir,
-		Compression:   cfg.CompressionLevel,
-		Jobs:          cfg.Jobs,
-		DumpJobs:      cfg.DumpJobs,
-		CPUWorkload:   cfg.CPUWorkloadType,
-		MaxCores:      cfg.MaxCores,
-		RetentionDays: cfg.RetentionDays,
-		MinBackups:    cfg.MinBackups,
-		MaxRetries:    cfg.MaxRetries,
+		DBType:               cfg.DatabaseType,
+		Host:                 cfg.Host,
+		Port:                 cfg.Port,
+		User:                 cfg.User,
+		Database:             cfg.Database,
+		SSLMode:              cfg.SSLMode,
+		BackupDir:            cfg.BackupDir,
+		Compression:          cfg.CompressionLevel,
+		CompressionAlgorithm: cfg.CompressionAlgorithm,
+		Jobs:                 cfg.Jobs,
+		DumpJobs:             cfg.DumpJobs,
+		CPUWorkload:          cfg.CPUWorkloadType,
+		MaxCores:             cfg.MaxCores,
+		RetentionDays:        cfg.RetentionDays,
+		MinBackups:           cfg.MinBackups,
+		MaxRetries:           cfg.MaxRetries,
+		KeepLast:             cfg.KeepLast,
+		KeepWithin:           cfg.KeepWithin,
+		KeepDaily:            cfg.KeepDaily,
+		KeepWeekly:           cfg.KeepWeekly,
+		KeepMonthly:          cfg.KeepMonthly,
+		KeepYearly:           cfg.KeepYearly,
+		RetentionTimezone:    cfg.RetentionTimezone,
+		Targets:              cfg.CloudTargets,
 	}
 }
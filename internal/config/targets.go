@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CloudTarget is a named destination that backups are replicated to. Each
+// target has its own location and credentials, so one backup can go to an
+// on-prem MinIO, an S3 bucket in another region and an Azure container.
+type CloudTarget struct {
+	Name      string
+	URI       string // Cloud URI with provider, bucket and prefix (e.g. s3://dr-backups/prod/)
+	Region    string
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Retries   int  // Upload retries after a failure (0 = MaxRetries)
+	Timeout   int  // Seconds per request (0 = as for the primary cloud storage)
+	Insecure  bool // Plain HTTP to the endpoint (e.g. an on-prem MinIO without TLS)
+
+	// Storage class for this target's uploads (e.g. GLACIER_IR, Cool, NEARLINE)
+	StorageClass string
+}
+
+// ParseCloudTarget parses a target given as name=URI
+func ParseCloudTarget(spec string) (CloudTarget, error) {
+	name, uri, ok := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	uri = strings.TrimSpace(uri)
+	if !ok || uri == "" {
+		return CloudTarget{}, fmt.Errorf("invalid target %q: expected name=URI (e.g. offsite=s3://bucket/path/)", spec)
+	}
+	if !validTargetName(name) {
+		return CloudTarget{}, fmt.Errorf("invalid target name %q: use letters, digits, '-' and '_'", name)
+	}
+	return CloudTarget{Name: name, URI: uri}, nil
+}
+
+func validTargetName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// UseSSL reports whether the target's endpoint is reached over TLS: unless
+// the target is insecure or its endpoint is an http:// URL
+func (t CloudTarget) UseSSL() bool {
+	return !t.Insecure && !strings.HasPrefix(strings.ToLower(t.Endpoint), "http://")
+}
+
+// WithEnvironment returns the target with unset settings taken from
+// TARGET_<NAME>_ACCESS_KEY, _SECRET_KEY, _REGION, _ENDPOINT,
+// _STORAGE_CLASS and _INSECURE, so that
+// credentials of targets given on the command line stay out of flags and
+// the config file
+func (t CloudTarget) WithEnvironment() CloudTarget {
+	prefix := "TARGET_" + strings.ToUpper(strings.ReplaceAll(t.Name, "-", "_")) + "_"
+	if t.AccessKey == "" {
+		t.AccessKey = os.Getenv(prefix + "ACCESS_KEY")
+	}
+	if t.SecretKey == "" {
+		t.SecretKey = os.Getenv(prefix + "SECRET_KEY")
+	}
+	if t.Region == "" {
+		t.Region = os.Getenv(prefix + "REGION")
+	}
+	if t.Endpoint == "" {
+		t.Endpoint = os.Getenv(prefix + "ENDPOINT")
+	}
+	if t.StorageClass == "" {
+		t.StorageClass = os.Getenv(prefix + "STORAGE_CLASS")
+	}
+	if !t.Insecure {
+		t.Insecure, _ = strconv.ParseBool(os.Getenv(prefix + "INSECURE"))
+	}
+	return t
+}
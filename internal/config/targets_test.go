@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseCloudTarget(t *testing.T) {
+	target, err := ParseCloudTarget("dr-east=s3://dr-backups/prod/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Name != "dr-east" || target.URI != "s3://dr-backups/prod/" {
+		t.Errorf("Unexpected target %+v", target)
+	}
+
+	for _, spec := range []string{"s3://bucket/", "=s3://bucket/", "dr=", "dr east=s3://bucket/", "a.b=s3://bucket/"} {
+		if _, err := ParseCloudTarget(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestCloudTargetWithEnvironment(t *testing.T) {
+	t.Setenv("TARGET_DR_EAST_ACCESS_KEY", "AKIAEXAMPLE")
+	t.Setenv("TARGET_DR_EAST_SECRET_KEY", "secret")
+	t.Setenv("TARGET_DR_EAST_REGION", "us-east-2")
+	t.Setenv("TARGET_DR_EAST_STORAGE_CLASS", "GLACIER_IR")
+	t.Setenv("TARGET_DR_EAST_INSECURE", "true")
+
+	target := CloudTarget{Name: "dr-east", URI: "s3://dr-backups/", Region: "eu-west-1"}.WithEnvironment()
+	if target.AccessKey != "AKIAEXAMPLE" || target.SecretKey != "secret" {
+		t.Errorf("Expected credentials from the environment, got %+v", target)
+	}
+	if target.Region != "eu-west-1" {
+		t.Errorf("Expected configured region to win, got %s", target.Region)
+	}
+	if target.StorageClass != "GLACIER_IR" {
+		t.Errorf("Expected storage class from the environment, got %q", target.StorageClass)
+	}
+	if !target.Insecure || target.UseSSL() {
+		t.Errorf("Expected an insecure target from the environment, got %+v", target)
+	}
+}
+
+func TestCloudTargetUseSSL(t *testing.T) {
+	tests := []struct {
+		target CloudTarget
+		want   bool
+	}{
+		{CloudTarget{}, true},
+		{CloudTarget{Endpoint: "https://minio:9000"}, true},
+		{CloudTarget{Endpoint: "minio:9000"}, true},
+		{CloudTarget{Endpoint: "HTTP://minio:9000"}, false},
+		{CloudTarget{Endpoint: "minio:9000", Insecure: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.target.UseSSL(); got != tt.want {
+			t.Errorf("UseSSL(%+v) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestLocalConfigTargets(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	saved := &LocalConfig{Host: "db1", Targets: []CloudTarget{
+		{Name: "minio", URI: "minio://backups/pg/", Endpoint: "minio:9000", AccessKey: "minio", SecretKey: "minio123", Insecure: true},
+		{Name: "azure", URI: "azure://container/pg/", Retries: 5, Timeout: 60, StorageClass: "Cool"},
+	}}
+	if err := SaveLocalConfig(saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadLocalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Targets) != 2 || loaded.Targets[0] != saved.Targets[0] || loaded.Targets[1] != saved.Targets[1] {
+		t.Fatalf("Targets didn't round-trip: %+v", loaded.Targets)
+	}
+
+	cfg := &Config{}
+	ApplyLocalConfig(cfg, loaded)
+	if len(cfg.CloudTargets) != 2 || cfg.CloudTargets[1].Retries != 5 {
+		t.Errorf("Expected targets to be applied, got %+v", cfg.CloudTargets)
+	}
+
+	// Targets that are already set are kept
+	cfg = &Config{CloudTargets: []CloudTarget{{Name: "cli", URI: "s3://other/"}}}
+	ApplyLocalConfig(cfg, loaded)
+	if len(cfg.CloudTargets) != 1 || cfg.CloudTargets[0].Name != "cli" {
+		t.Errorf("Expected command line targets to be kept, got %+v", cfg.CloudTargets)
+	}
+
+	if info, err := os.Stat(ConfigFileName); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected config with credentials to be private: %v", err)
+	}
+}
@@ -1,14 +1,14 @@
 package crypto
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
 
+	"dbbackup/internal/encryption"
+
 	"golang.org/x/crypto/pbkdf2"
 )
 
@@ -73,164 +73,40 @@ func ValidateKey(key []byte) error {
 	return nil
 }
 
-// Encrypt encrypts data from reader and returns an encrypted reader
+// Encrypt encrypts data from reader and returns an encrypted reader. The
+// output uses the container format of the encryption package.
 func (e *AESEncryptor) Encrypt(reader io.Reader, key []byte) (io.Reader, error) {
 	if err := ValidateKey(key); err != nil {
 		return nil, err
 	}
 
-	// Create AES cipher
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
-	}
-
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
-	}
-
-	// Generate nonce
-	nonce, err := GenerateNonce()
-	if err != nil {
-		return nil, err
-	}
-
 	// Create pipe for streaming
 	pr, pw := io.Pipe()
 
 	go func() {
-		defer pw.Close()
-
-		// Write nonce first (needed for decryption)
-		if _, err := pw.Write(nonce); err != nil {
-			pw.CloseWithError(fmt.Errorf("failed to write nonce: %w", err))
+		ew, err := encryption.NewEncryptionWriter(pw, encryption.EncryptionOptions{Key: key})
+		if err != nil {
+			pw.CloseWithError(err)
 			return
 		}
-
-		// Read plaintext in chunks and encrypt
-		buf := make([]byte, BufferSize)
-		for {
-			n, err := reader.Read(buf)
-			if n > 0 {
-				// Encrypt chunk
-				ciphertext := gcm.Seal(nil, nonce, buf[:n], nil)
-
-				// Write encrypted chunk length (4 bytes) + encrypted data
-				lengthBuf := []byte{
-					byte(len(ciphertext) >> 24),
-					byte(len(ciphertext) >> 16),
-					byte(len(ciphertext) >> 8),
-					byte(len(ciphertext)),
-				}
-				if _, err := pw.Write(lengthBuf); err != nil {
-					pw.CloseWithError(fmt.Errorf("failed to write chunk length: %w", err))
-					return
-				}
-				if _, err := pw.Write(ciphertext); err != nil {
-					pw.CloseWithError(fmt.Errorf("failed to write ciphertext: %w", err))
-					return
-				}
-
-				// Increment nonce for next chunk (simple counter mode)
-				for i := len(nonce) - 1; i >= 0; i-- {
-					nonce[i]++
-					if nonce[i] != 0 {
-						break
-					}
-				}
-			}
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				pw.CloseWithError(fmt.Errorf("read error: %w", err))
-				return
-			}
+		if _, err := io.Copy(ew, reader); err != nil {
+			pw.CloseWithError(fmt.Errorf("read error: %w", err))
+			return
 		}
+		pw.CloseWithError(ew.Close())
 	}()
 
 	return pr, nil
 }
 
-// Decrypt decrypts data from reader and returns a decrypted reader
+// Decrypt decrypts data from reader and returns a decrypted reader. Files in
+// the container format and all legacy formats (including the headerless
+// format this encryptor used to write) are detected automatically.
 func (e *AESEncryptor) Decrypt(reader io.Reader, key []byte) (io.Reader, error) {
 	if err := ValidateKey(key); err != nil {
 		return nil, err
 	}
-
-	// Create AES cipher
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
-	}
-
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
-	}
-
-	// Create pipe for streaming
-	pr, pw := io.Pipe()
-
-	go func() {
-		defer pw.Close()
-
-		// Read initial nonce
-		nonce := make([]byte, NonceSize)
-		if _, err := io.ReadFull(reader, nonce); err != nil {
-			pw.CloseWithError(fmt.Errorf("failed to read nonce: %w", err))
-			return
-		}
-
-		// Read and decrypt chunks
-		lengthBuf := make([]byte, 4)
-		for {
-			// Read chunk length
-			if _, err := io.ReadFull(reader, lengthBuf); err != nil {
-				if err == io.EOF {
-					break
-				}
-				pw.CloseWithError(fmt.Errorf("failed to read chunk length: %w", err))
-				return
-			}
-
-			chunkLen := int(lengthBuf[0])<<24 | int(lengthBuf[1])<<16 |
-				int(lengthBuf[2])<<8 | int(lengthBuf[3])
-
-			// Read encrypted chunk
-			ciphertext := make([]byte, chunkLen)
-			if _, err := io.ReadFull(reader, ciphertext); err != nil {
-				pw.CloseWithError(fmt.Errorf("failed to read ciphertext: %w", err))
-				return
-			}
-
-			// Decrypt chunk
-			plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-			if err != nil {
-				pw.CloseWithError(fmt.Errorf("decryption failed (wrong key?): %w", err))
-				return
-			}
-
-			// Write plaintext
-			if _, err := pw.Write(plaintext); err != nil {
-				pw.CloseWithError(fmt.Errorf("failed to write plaintext: %w", err))
-				return
-			}
-
-			// Increment nonce for next chunk
-			for i := len(nonce) - 1; i >= 0; i-- {
-				nonce[i]++
-				if nonce[i] != 0 {
-					break
-				}
-			}
-		}
-	}()
-
-	return pr, nil
+	return encryption.NewDecryptionReader(reader, encryption.EncryptionOptions{Key: key})
 }
 
 // EncryptFile encrypts a file
@@ -72,6 +72,18 @@ type EncryptionMetadata struct {
 	// Nonce/IV used for encryption (base64 encoded)
 	Nonce string `json:"nonce,omitempty"`
 
+	// KeyProvider that wrapped the data key (file, passphrase, vault, kms)
+	KeyProvider string `json:"key_provider,omitempty"`
+
+	// KeyID of the master key that wrapped the data key
+	KeyID string `json:"key_id,omitempty"`
+
+	// WrappedKey is the backup's data key encrypted with the master key (base64 encoded)
+	WrappedKey string `json:"wrapped_key,omitempty"`
+
+	// Recipients lists the fingerprints of the public keys the data key is wrapped to
+	Recipients []string `json:"recipients,omitempty"`
+
 	// Version of encryption format
 	Version int `json:"version"`
 }
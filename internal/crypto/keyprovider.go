@@ -0,0 +1,254 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Key provider names recorded in EncryptionMetadata
+const (
+	ProviderFile       = "file"
+	ProviderPassphrase = "passphrase"
+	ProviderVault      = "vault"
+	ProviderKMS        = "kms"
+	ProviderX25519     = "x25519"
+)
+
+// EnvelopeVersion is the version of the envelope recorded in EncryptionMetadata
+const EnvelopeVersion = 1
+
+// KeyProvider wraps and unwraps per-backup data keys with a master key it
+// controls (envelope encryption). The master key never encrypts backup data
+// itself, so it can be rotated without re-encrypting old backups.
+type KeyProvider interface {
+	// Name returns the provider name recorded in backup metadata
+	Name() string
+
+	// KeyID identifies the master key new data keys are wrapped with
+	KeyID() string
+
+	// WrapKey encrypts a data key with the master key
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+
+	// UnwrapKey decrypts a data key wrapped with the master key identified by keyID
+	UnwrapKey(ctx context.Context, wrapped []byte, keyID string) ([]byte, error)
+}
+
+// recipientLister is implemented by providers that wrap to several public keys
+type recipientLister interface {
+	Recipients() []string
+}
+
+// NewDataKey generates a random data key and wraps it with the provider.
+// The returned metadata holds everything needed to unwrap it again.
+func NewDataKey(ctx context.Context, provider KeyProvider) ([]byte, *EncryptionMetadata, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrapped, err := provider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key with %s key provider: %w", provider.Name(), err)
+	}
+
+	meta := &EncryptionMetadata{
+		KeyProvider: provider.Name(),
+		KeyID:       provider.KeyID(),
+		WrappedKey:  base64.StdEncoding.EncodeToString(wrapped),
+		Version:     EnvelopeVersion,
+	}
+	if rl, ok := provider.(recipientLister); ok {
+		meta.Recipients = rl.Recipients()
+	}
+	return dataKey, meta, nil
+}
+
+// UnwrapDataKey recovers the data key of a backup from its envelope metadata
+func UnwrapDataKey(ctx context.Context, provider KeyProvider, meta *EncryptionMetadata) ([]byte, error) {
+	if meta == nil || meta.WrappedKey == "" {
+		return nil, fmt.Errorf("backup has no wrapped data key")
+	}
+	if meta.KeyProvider != provider.Name() {
+		return nil, fmt.Errorf("data key was wrapped by the %s key provider, not %s", meta.KeyProvider, provider.Name())
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(meta.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped data key: %w", err)
+	}
+
+	dataKey, err := provider.UnwrapKey(ctx, wrapped, meta.KeyID)
+	if err != nil {
+		if len(meta.Recipients) > 0 {
+			return nil, fmt.Errorf("failed to unwrap data key with %s key provider (backup recipients: %s): %w",
+				provider.Name(), strings.Join(meta.Recipients, ", "), err)
+		}
+		return nil, fmt.Errorf("failed to unwrap data key with %s key provider: %w", provider.Name(), err)
+	}
+	if err := ValidateKey(dataKey); err != nil {
+		return nil, fmt.Errorf("unwrapped data key is invalid: %w", err)
+	}
+	return dataKey, nil
+}
+
+// RewrapDataKey re-wraps the data key of a backup with a new provider (or a
+// new master key). The backup data stays untouched; only the returned
+// metadata has to be stored in place of the old one.
+func RewrapDataKey(ctx context.Context, from, to KeyProvider, meta *EncryptionMetadata) (*EncryptionMetadata, error) {
+	dataKey, err := UnwrapDataKey(ctx, from, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := to.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key with %s key provider: %w", to.Name(), err)
+	}
+
+	rewrapped := *meta
+	rewrapped.KeyProvider = to.Name()
+	rewrapped.KeyID = to.KeyID()
+	rewrapped.Recipients = nil
+	if rl, ok := to.(recipientLister); ok {
+		rewrapped.Recipients = rl.Recipients()
+	}
+	rewrapped.WrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+	rewrapped.Version = EnvelopeVersion
+	return &rewrapped, nil
+}
+
+// FileKeyProvider wraps data keys with a local 32-byte master key
+type FileKeyProvider struct {
+	masterKey []byte
+	keyID     string
+}
+
+// NewFileKeyProvider creates a provider for a 32-byte master key. The key ID
+// is a fingerprint of the key, so restores can tell which key is needed.
+func NewFileKeyProvider(masterKey []byte) (*FileKeyProvider, error) {
+	if err := ValidateKey(masterKey); err != nil {
+		return nil, err
+	}
+	return &FileKeyProvider{masterKey: masterKey, keyID: KeyFingerprint(masterKey)}, nil
+}
+
+// KeyFingerprint returns a short, non-secret identifier for a master key
+func KeyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// Name returns the provider name
+func (p *FileKeyProvider) Name() string { return ProviderFile }
+
+// KeyID returns the master key fingerprint
+func (p *FileKeyProvider) KeyID() string { return p.keyID }
+
+// WrapKey encrypts a data key with AES-256-GCM under the master key
+func (p *FileKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	return sealKey(p.masterKey, dataKey)
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey
+func (p *FileKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte, keyID string) ([]byte, error) {
+	if keyID != "" && keyID != p.keyID {
+		return nil, fmt.Errorf("data key was wrapped with master key %s, but the provided key is %s", keyID, p.keyID)
+	}
+	return openKey(p.masterKey, wrapped)
+}
+
+// PassphraseKeyProvider wraps data keys with a key derived from a passphrase.
+// Every wrap uses a fresh salt, stored in front of the wrapped key.
+type PassphraseKeyProvider struct {
+	passphrase []byte
+}
+
+// NewPassphraseKeyProvider creates a provider for a passphrase
+func NewPassphraseKeyProvider(passphrase string) (*PassphraseKeyProvider, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase is empty")
+	}
+	return &PassphraseKeyProvider{passphrase: []byte(passphrase)}, nil
+}
+
+// Name returns the provider name
+func (p *PassphraseKeyProvider) Name() string { return ProviderPassphrase }
+
+// KeyID returns the key ID; passphrases have no identity worth recording
+func (p *PassphraseKeyProvider) KeyID() string { return "pbkdf2-sha256" }
+
+// WrapKey encrypts a data key under a PBKDF2-derived key
+func (p *PassphraseKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	salt, err := GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealKey(DeriveKey(p.passphrase, salt), dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(salt, sealed...), nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey
+func (p *PassphraseKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte, keyID string) ([]byte, error) {
+	if len(wrapped) < SaltSize {
+		return nil, fmt.Errorf("wrapped key too short")
+	}
+	salt, sealed := wrapped[:SaltSize], wrapped[SaltSize:]
+	dataKey, err := openKey(DeriveKey(p.passphrase, salt), sealed)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase?: %w", err)
+	}
+	return dataKey, nil
+}
+
+// sealKey encrypts a key with AES-256-GCM, prefixing the random nonce
+func sealKey(kek, key []byte) ([]byte, error) {
+	gcm, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := GenerateNonce()
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, key, nil), nil
+}
+
+// openKey decrypts a key sealed by sealKey
+func openKey(kek, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < NonceSize {
+		return nil, fmt.Errorf("wrapped key too short")
+	}
+	key, err := gcm.Open(nil, sealed[:NonceSize], sealed[NonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key: %w", err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
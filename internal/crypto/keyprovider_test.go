@@ -0,0 +1,267 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestFileKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	master := bytes.Repeat([]byte{7}, KeySize)
+
+	provider, err := NewFileKeyProvider(master)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	dataKey, meta, err := NewDataKey(ctx, provider)
+	if err != nil {
+		t.Fatalf("Failed to create data key: %v", err)
+	}
+	if meta.KeyProvider != ProviderFile || meta.KeyID != KeyFingerprint(master) {
+		t.Errorf("Unexpected envelope %+v", meta)
+	}
+
+	got, err := UnwrapDataKey(ctx, provider, meta)
+	if err != nil {
+		t.Fatalf("Failed to unwrap: %v", err)
+	}
+	if !bytes.Equal(got, dataKey) {
+		t.Error("Unwrapped key doesn't match data key")
+	}
+
+	// Another master key must be reported by fingerprint, not fail silently
+	other, _ := NewFileKeyProvider(bytes.Repeat([]byte{8}, KeySize))
+	if _, err := UnwrapDataKey(ctx, other, meta); err == nil || !strings.Contains(err.Error(), meta.KeyID) {
+		t.Errorf("Expected master key mismatch error, got %v", err)
+	}
+}
+
+func TestRewrapDataKey(t *testing.T) {
+	ctx := context.Background()
+	oldProvider, _ := NewPassphraseKeyProvider("old passphrase")
+	newProvider, _ := NewFileKeyProvider(bytes.Repeat([]byte{9}, KeySize))
+
+	dataKey, meta, err := NewDataKey(ctx, oldProvider)
+	if err != nil {
+		t.Fatalf("Failed to create data key: %v", err)
+	}
+
+	rewrapped, err := RewrapDataKey(ctx, oldProvider, newProvider, meta)
+	if err != nil {
+		t.Fatalf("Failed to rewrap: %v", err)
+	}
+	if rewrapped.KeyProvider != ProviderFile {
+		t.Errorf("Expected file provider, got %s", rewrapped.KeyProvider)
+	}
+
+	got, err := UnwrapDataKey(ctx, newProvider, rewrapped)
+	if err != nil {
+		t.Fatalf("Failed to unwrap rewrapped key: %v", err)
+	}
+	if !bytes.Equal(got, dataKey) {
+		t.Error("Rewrapping changed the data key")
+	}
+
+	wrong, _ := NewPassphraseKeyProvider("wrong passphrase")
+	if _, err := UnwrapDataKey(ctx, wrong, meta); err == nil {
+		t.Error("Expected error unwrapping with wrong passphrase")
+	}
+}
+
+func TestVaultTransitProvider(t *testing.T) {
+	// Fake transit engine: "encrypts" by prefixing the plaintext
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v1/backups/encrypt/db":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"ciphertext": "vault:v1:" + req["plaintext"]},
+			})
+		case "/v1/backups/decrypt/db":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"plaintext": strings.TrimPrefix(req["ciphertext"], "vault:v1:")},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	provider, err := NewVaultTransitProvider(VaultConfig{Address: server.URL, Token: "s.token", Key: "backups/db"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	dataKey, meta, err := NewDataKey(ctx, provider)
+	if err != nil {
+		t.Fatalf("Failed to create data key: %v", err)
+	}
+	if meta.KeyID != "backups/db" {
+		t.Errorf("Unexpected key ID %s", meta.KeyID)
+	}
+
+	got, err := UnwrapDataKey(ctx, provider, meta)
+	if err != nil {
+		t.Fatalf("Failed to unwrap: %v", err)
+	}
+	if !bytes.Equal(got, dataKey) {
+		t.Error("Unwrapped key doesn't match data key")
+	}
+
+	denied, _ := NewVaultTransitProvider(VaultConfig{Address: server.URL, Token: "bad", Key: "backups/db"})
+	if _, err := UnwrapDataKey(ctx, denied, meta); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected vault error, got %v", err)
+	}
+}
+
+func TestKMSKeyProvider(t *testing.T) {
+	// Fake KMS: checks signing and "encrypts" by reversing the bytes
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"MissingAuthenticationTokenException","message":"unsigned"}`))
+			return
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			plain, _ := base64.StdEncoding.DecodeString(req["Plaintext"])
+			json.NewEncoder(w).Encode(map[string]string{
+				"CiphertextBlob": base64.StdEncoding.EncodeToString(reverse(plain)),
+				"KeyId":          req["KeyId"],
+			})
+		case "TrentService.Decrypt":
+			blob, _ := base64.StdEncoding.DecodeString(req["CiphertextBlob"])
+			json.NewEncoder(w).Encode(map[string]string{
+				"Plaintext": base64.StdEncoding.EncodeToString(reverse(blob)),
+				"KeyId":     req["KeyId"],
+			})
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	provider, err := NewKMSKeyProvider(ctx, KMSConfig{
+		KeyID:       "arn:aws:kms:eu-west-1:123456789012:key/backup",
+		Endpoint:    server.URL,
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if region := provider.client.Options().Region; region != "eu-west-1" {
+		t.Errorf("Expected region from ARN, got %s", region)
+	}
+
+	dataKey, meta, err := NewDataKey(ctx, provider)
+	if err != nil {
+		t.Fatalf("Failed to create data key: %v", err)
+	}
+	got, err := UnwrapDataKey(ctx, provider, meta)
+	if err != nil {
+		t.Fatalf("Failed to unwrap: %v", err)
+	}
+	if !bytes.Equal(got, dataKey) {
+		t.Error("Unwrapped key doesn't match data key")
+	}
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
+
+func TestRecipientKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	alice, _ := GenerateX25519Identity()
+	bob, _ := GenerateX25519Identity()
+
+	provider, err := NewRecipientKeyProvider([]*X25519Recipient{alice.Recipient(), bob.Recipient()}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	dataKey, meta, err := NewDataKey(ctx, provider)
+	if err != nil {
+		t.Fatalf("Failed to create data key: %v", err)
+	}
+	if meta.KeyProvider != ProviderX25519 || len(meta.Recipients) != 2 || meta.Recipients[1] != bob.Recipient().Fingerprint() {
+		t.Errorf("Unexpected envelope %+v", meta)
+	}
+
+	// Backup hosts hold only recipients and cannot unwrap
+	if _, err := UnwrapDataKey(ctx, provider, meta); err == nil {
+		t.Error("Expected error unwrapping without an identity")
+	}
+
+	for _, id := range []*X25519Identity{alice, bob} {
+		restoreProvider, _ := NewRecipientKeyProvider(nil, []*X25519Identity{id})
+		got, err := UnwrapDataKey(ctx, restoreProvider, meta)
+		if err != nil {
+			t.Fatalf("Failed to unwrap with %s: %v", id.Recipient().Fingerprint(), err)
+		}
+		if !bytes.Equal(got, dataKey) {
+			t.Error("Unwrapped key doesn't match data key")
+		}
+	}
+
+	eve, _ := GenerateX25519Identity()
+	eveProvider, _ := NewRecipientKeyProvider(nil, []*X25519Identity{eve})
+	if _, err := UnwrapDataKey(ctx, eveProvider, meta); err == nil || !strings.Contains(err.Error(), meta.Recipients[0]) {
+		t.Errorf("Expected error listing recipients, got %v", err)
+	}
+}
+
+func TestX25519KeyEncoding(t *testing.T) {
+	// Key pair from age's test suite
+	const (
+		secret    = "AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX"
+		recipient = "age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj"
+	)
+
+	identities, err := ParseX25519Identities(strings.NewReader("# created: 2026-01-01\n" + secret + "\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse identity file: %v", err)
+	}
+	if got := identities[0].String(); got != secret {
+		t.Errorf("Identity round trip: got %s", got)
+	}
+	if got := identities[0].Recipient().String(); got != recipient {
+		t.Errorf("Expected recipient %s, got %s", recipient, got)
+	}
+
+	parsed, err := ParseX25519Recipient(recipient)
+	if err != nil {
+		t.Fatalf("Failed to parse recipient: %v", err)
+	}
+	if parsed.String() != recipient {
+		t.Errorf("Recipient round trip: got %s", parsed.String())
+	}
+
+	if _, err := ParseX25519Recipient(recipient[:len(recipient)-1] + "q"); err == nil {
+		t.Error("Expected checksum error")
+	}
+	if _, err := ParseX25519Recipient(secret); err == nil {
+		t.Error("Expected error parsing a secret key as recipient")
+	}
+}
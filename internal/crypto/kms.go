@@ -0,0 +1,94 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// KMSConfig configures the AWS KMS key provider
+type KMSConfig struct {
+	KeyID       string                  // Key ID, alias or ARN of the KMS key
+	Region      string                  // AWS region (taken from the key ARN if empty)
+	Endpoint    string                  // Custom endpoint for KMS-compatible services (optional)
+	Credentials aws.CredentialsProvider // Credentials (default AWS credential chain if nil)
+}
+
+// KMSKeyProvider wraps data keys with AWS KMS Encrypt/Decrypt, or any
+// service speaking the same API (e.g. local-kms)
+type KMSKeyProvider struct {
+	keyID  string
+	client *kms.Client
+}
+
+// NewKMSKeyProvider creates a KMS key provider
+func NewKMSKeyProvider(ctx context.Context, cfg KMSConfig) (*KMSKeyProvider, error) {
+	if cfg.KeyID == "" {
+		return nil, fmt.Errorf("KMS key ID is required (use --key-id)")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		// arn:aws:kms:<region>:<account>:key/<id>
+		if parts := strings.Split(cfg.KeyID, ":"); len(parts) >= 6 && parts[0] == "arn" {
+			region = parts[3]
+		}
+	}
+
+	options := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if cfg.Credentials != nil {
+		options = append(options, config.WithCredentialsProvider(cfg.Credentials))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if awsCfg.Region == "" {
+		return nil, fmt.Errorf("AWS region is required for KMS (set AWS_REGION or use a key ARN)")
+	}
+
+	client := kms.NewFromConfig(awsCfg, func(o *kms.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+
+	return &KMSKeyProvider{keyID: cfg.KeyID, client: client}, nil
+}
+
+// Name returns the provider name
+func (p *KMSKeyProvider) Name() string { return ProviderKMS }
+
+// KeyID returns the KMS key ID
+func (p *KMSKeyProvider) KeyID() string { return p.keyID }
+
+// WrapKey encrypts a data key with the KMS key
+func (p *KMSKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	out, err := p.client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:     aws.String(p.keyID),
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KMS Encrypt failed: %w", err)
+	}
+	return out.CiphertextBlob, nil
+}
+
+// UnwrapKey decrypts a data key with the KMS key identified by keyID
+func (p *KMSKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte, keyID string) ([]byte, error) {
+	if keyID == "" {
+		keyID = p.keyID
+	}
+	out, err := p.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(keyID),
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KMS Decrypt failed: %w", err)
+	}
+	return out.Plaintext, nil
+}
@@ -0,0 +1,154 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultConfig configures the HashiCorp Vault transit key provider
+type VaultConfig struct {
+	Address   string // Vault address, e.g. https://vault.example.com:8200
+	Token     string // Vault token with encrypt/decrypt access to the transit key
+	Namespace string // Vault Enterprise namespace (optional)
+	Key       string // Transit key as "mount/key" or just "key" (mount defaults to "transit")
+}
+
+// VaultTransitProvider wraps data keys with a Vault transit key. Vault
+// versions its keys, so rotating the transit key needs no changes here.
+type VaultTransitProvider struct {
+	address   string
+	token     string
+	namespace string
+	mount     string
+	key       string
+	client    *http.Client
+}
+
+// NewVaultTransitProvider creates a Vault transit key provider
+func NewVaultTransitProvider(cfg VaultConfig) (*VaultTransitProvider, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("vault address is required (set VAULT_ADDR or --key-endpoint)")
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("vault token is required (set VAULT_TOKEN)")
+	}
+
+	mount, key := "transit", strings.Trim(cfg.Key, "/")
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		mount, key = key[:i], key[i+1:]
+	}
+	if key == "" {
+		return nil, fmt.Errorf("vault transit key name is required (use --key-id)")
+	}
+
+	return &VaultTransitProvider{
+		address:   strings.TrimRight(cfg.Address, "/"),
+		token:     cfg.Token,
+		namespace: cfg.Namespace,
+		mount:     mount,
+		key:       key,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Name returns the provider name
+func (p *VaultTransitProvider) Name() string { return ProviderVault }
+
+// KeyID returns the transit key as "mount/key"
+func (p *VaultTransitProvider) KeyID() string { return p.mount + "/" + p.key }
+
+// WrapKey encrypts a data key with the transit key
+func (p *VaultTransitProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	req := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}
+	if err := p.call(ctx, "encrypt", p.mount, p.key, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Data.Ciphertext == "" {
+		return nil, fmt.Errorf("vault returned no ciphertext")
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+// UnwrapKey decrypts a data key with the transit key identified by keyID
+func (p *VaultTransitProvider) UnwrapKey(ctx context.Context, wrapped []byte, keyID string) ([]byte, error) {
+	mount, key := p.mount, p.key
+	if keyID != "" {
+		if i := strings.LastIndex(keyID, "/"); i >= 0 {
+			mount, key = keyID[:i], keyID[i+1:]
+		} else {
+			key = keyID
+		}
+	}
+
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	req := map[string]string{"ciphertext": string(wrapped)}
+	if err := p.call(ctx, "decrypt", mount, key, req, &resp); err != nil {
+		return nil, err
+	}
+	dataKey, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plaintext from vault: %w", err)
+	}
+	return dataKey, nil
+}
+
+// call performs a transit encrypt/decrypt request
+func (p *VaultTransitProvider) call(ctx context.Context, op, mount, key string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", p.address, mount, op, key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	req.Header.Set("Content-Type", "application/json")
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read vault response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("vault transit %s failed (%s): %s", op, resp.Status, strings.Join(vaultErr.Errors, "; "))
+		}
+		return fmt.Errorf("vault transit %s failed: %s", op, resp.Status)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid vault response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package crypto
+
+import (
+	"bufio"
+	"context"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// X25519 recipients work like age's: a data key is wrapped once per recipient
+// public key, and only the matching private key (identity) can unwrap it.
+// Keys use age's encoding, so age-keygen can create them.
+
+const (
+	x25519RecipientHRP = "age"
+	x25519IdentityHRP  = "age-secret-key-"
+	x25519Label        = "age-encryption.org/v1/X25519"
+
+	// x25519StanzaSize is the ephemeral public key plus the sealed data key
+	x25519StanzaSize = 32 + KeySize + chacha20poly1305.Overhead
+)
+
+// X25519Recipient is a public key data keys can be wrapped to
+type X25519Recipient struct {
+	pub *ecdh.PublicKey
+}
+
+// ParseX25519Recipient parses an age recipient ("age1...")
+func ParseX25519Recipient(s string) (*X25519Recipient, error) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", s, err)
+	}
+	if hrp != x25519RecipientHRP {
+		return nil, fmt.Errorf("invalid recipient %q: not an age X25519 public key", s)
+	}
+	pub, err := ecdh.X25519().NewPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", s, err)
+	}
+	return &X25519Recipient{pub: pub}, nil
+}
+
+// String returns the recipient in age encoding
+func (r *X25519Recipient) String() string {
+	s, _ := bech32Encode(x25519RecipientHRP, r.pub.Bytes())
+	return s
+}
+
+// Fingerprint returns a short identifier of the public key
+func (r *X25519Recipient) Fingerprint() string {
+	return KeyFingerprint(r.pub.Bytes())
+}
+
+// X25519Identity is a private key that unwraps data keys wrapped to its recipient
+type X25519Identity struct {
+	priv *ecdh.PrivateKey
+}
+
+// GenerateX25519Identity creates a new random identity
+func GenerateX25519Identity() (*X25519Identity, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate X25519 key: %w", err)
+	}
+	return &X25519Identity{priv: priv}, nil
+}
+
+// ParseX25519Identity parses an age identity ("AGE-SECRET-KEY-1...")
+func ParseX25519Identity(s string) (*X25519Identity, error) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	if hrp != x25519IdentityHRP {
+		return nil, fmt.Errorf("invalid identity: not an age X25519 secret key")
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	return &X25519Identity{priv: priv}, nil
+}
+
+// String returns the identity in age encoding
+func (i *X25519Identity) String() string {
+	s, _ := bech32Encode(x25519IdentityHRP, i.priv.Bytes())
+	return strings.ToUpper(s)
+}
+
+// Recipient returns the public key of the identity
+func (i *X25519Identity) Recipient() *X25519Recipient {
+	return &X25519Recipient{pub: i.priv.PublicKey()}
+}
+
+// ParseX25519Identities reads identities, one per line, as written by age-keygen.
+// Blank lines and # comments are ignored.
+func ParseX25519Identities(r io.Reader) ([]*X25519Identity, error) {
+	var identities []*X25519Identity
+	err := scanKeyLines(r, func(line string) error {
+		id, err := ParseX25519Identity(line)
+		if err != nil {
+			return err
+		}
+		identities = append(identities, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no identities found")
+	}
+	return identities, nil
+}
+
+// ParseX25519Recipients reads recipients, one per line. Blank lines and # comments are ignored.
+func ParseX25519Recipients(r io.Reader) ([]*X25519Recipient, error) {
+	var recipients []*X25519Recipient
+	err := scanKeyLines(r, func(line string) error {
+		rcpt, err := ParseX25519Recipient(line)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, rcpt)
+		return nil
+	})
+	return recipients, err
+}
+
+func scanKeyLines(r io.Reader, fn func(line string) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// RecipientKeyProvider wraps data keys to X25519 recipients. Backup hosts only
+// need the recipients (public keys); unwrapping needs one of the identities,
+// which can be kept offline.
+type RecipientKeyProvider struct {
+	recipients []*X25519Recipient
+	identities []*X25519Identity
+}
+
+// NewRecipientKeyProvider creates a provider that wraps to recipients and/or
+// unwraps with identities
+func NewRecipientKeyProvider(recipients []*X25519Recipient, identities []*X25519Identity) (*RecipientKeyProvider, error) {
+	if len(recipients) == 0 && len(identities) == 0 {
+		return nil, fmt.Errorf("no recipients or identities given")
+	}
+	return &RecipientKeyProvider{recipients: recipients, identities: identities}, nil
+}
+
+// Name returns the provider name
+func (p *RecipientKeyProvider) Name() string { return ProviderX25519 }
+
+// KeyID is empty: a data key is wrapped to several keys, see Recipients
+func (p *RecipientKeyProvider) KeyID() string { return "" }
+
+// Recipients returns the fingerprints of the recipients data keys are wrapped to
+func (p *RecipientKeyProvider) Recipients() []string {
+	fingerprints := make([]string, len(p.recipients))
+	for i, r := range p.recipients {
+		fingerprints[i] = r.Fingerprint()
+	}
+	return fingerprints
+}
+
+// WrapKey wraps a data key to every recipient
+func (p *RecipientKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	if len(p.recipients) == 0 {
+		return nil, fmt.Errorf("no recipients to encrypt to")
+	}
+
+	wrapped := make([]byte, 0, len(p.recipients)*x25519StanzaSize)
+	for _, r := range p.recipients {
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+		}
+		shared, err := ephemeral.ECDH(r.pub)
+		if err != nil {
+			return nil, fmt.Errorf("key agreement with %s failed: %w", r.Fingerprint(), err)
+		}
+		ephPub := ephemeral.PublicKey().Bytes()
+
+		aead, err := x25519WrapCipher(shared, ephPub, r.pub.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		wrapped = append(wrapped, ephPub...)
+		wrapped = aead.Seal(wrapped, make([]byte, chacha20poly1305.NonceSize), dataKey, nil)
+	}
+	return wrapped, nil
+}
+
+// UnwrapKey unwraps a data key with the first identity it was wrapped to
+func (p *RecipientKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte, keyID string) ([]byte, error) {
+	if len(p.identities) == 0 {
+		return nil, fmt.Errorf("an identity (private key) is required to decrypt (use --identity-file)")
+	}
+	if len(wrapped) == 0 || len(wrapped)%x25519StanzaSize != 0 {
+		return nil, fmt.Errorf("malformed recipient stanzas")
+	}
+
+	for _, id := range p.identities {
+		ownPub := id.priv.PublicKey().Bytes()
+		for off := 0; off < len(wrapped); off += x25519StanzaSize {
+			stanza := wrapped[off : off+x25519StanzaSize]
+			ephPub, sealed := stanza[:32], stanza[32:]
+
+			eph, err := ecdh.X25519().NewPublicKey(ephPub)
+			if err != nil {
+				continue
+			}
+			shared, err := id.priv.ECDH(eph)
+			if err != nil {
+				continue
+			}
+			aead, err := x25519WrapCipher(shared, ephPub, ownPub)
+			if err != nil {
+				return nil, err
+			}
+			if dataKey, err := aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), sealed, nil); err == nil {
+				return dataKey, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("none of the %d identities is a recipient of this backup", len(p.identities))
+}
+
+// x25519WrapCipher derives the stanza key from the shared secret like age does
+func x25519WrapCipher(shared, ephPub, recipientPub []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephPub...), recipientPub...)
+	key, err := hkdf.Key(sha256.New, shared, salt, x25519Label, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("key derivation failed: %w", err)
+	}
+	return chacha20poly1305.New(key)
+}
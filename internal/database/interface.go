@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"time"
 
 	"dbbackup/internal/config"
@@ -33,12 +34,23 @@ type Database interface {
 	GetVersion(ctx context.Context) (string, error)
 	GetDatabaseSize(ctx context.Context, database string) (int64, error)
 	GetTableRowCount(ctx context.Context, database, table string) (int64, error)
+	GetDataDirectory(ctx context.Context) (string, error)
+	GetForeignKeys(ctx context.Context, database string) ([]ForeignKey, error)
+	
+	// Queries
+	CountRows(ctx context.Context, database, table string) (int64, error)
+	QueryValue(ctx context.Context, database, query string) (string, error)
+	GetSchemaInfo(ctx context.Context, database string) (*SchemaInfo, error)
+	BeginSnapshot(ctx context.Context, database string) (*Snapshot, error)
 	
 	// Backup/Restore command building
 	BuildBackupCommand(database, outputFile string, options BackupOptions) []string
 	BuildRestoreCommand(database, inputFile string, options RestoreOptions) []string
 	BuildSampleQuery(database, table string, strategy SampleStrategy) string
 	
+	// Sampling
+	WriteSample(ctx context.Context, database string, strategy SampleStrategy, w io.Writer) (*SampleStats, error)
+	
 	// Validation
 	ValidateBackupTools() error
 }
@@ -56,6 +68,8 @@ type BackupOptions struct {
 	Clean          bool
 	IfExists       bool
 	Role           string
+	Section        string  // "pre-data", "data", "post-data"; MySQL: pre-data or post-data (triggers)
+	Snapshot       string  // Exported snapshot to dump, see Snapshot (PostgreSQL only)
 }
 
 // RestoreOptions holds options for restore operations
@@ -67,6 +81,9 @@ type RestoreOptions struct {
 	NoPrivileges      bool
 	SingleTransaction bool
 	Verbose           bool // Enable verbose output (caution: can cause OOM on large restores)
+
+	// UseList restores only the entries of a pg_restore --list file (PostgreSQL)
+	UseList string
 }
 
 // SampleStrategy defines how to sample data
@@ -91,6 +108,7 @@ type TableInfo struct {
 	Name     string
 	RowCount int64
 	Size     int64
+	DDL      []string // Normalized definition: columns, constraints and indexes
 }
 
 // New creates a new database instance based on configuration
@@ -125,6 +143,39 @@ func (b *baseDatabase) Ping(ctx context.Context) error {
 	return b.db.PingContext(ctx)
 }
 
+// queryValue returns the first column of the first row of a query as text
+func queryValue(ctx context.Context, query func(context.Context, string, ...any) (*sql.Rows, error), q string) (string, error) {
+	rows, err := query(ctx, q)
+	if err != nil {
+		return "", fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+	
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", fmt.Errorf("query failed: %w", err)
+		}
+		return "", fmt.Errorf("query returned no rows")
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("failed to read columns: %w", err)
+	}
+	values := make([]any, len(columns))
+	var first sql.NullString
+	values[0] = &first
+	for i := 1; i < len(values); i++ {
+		values[i] = new(sql.RawBytes)
+	}
+	if err := rows.Scan(values...); err != nil {
+		return "", fmt.Errorf("failed to scan result: %w", err)
+	}
+	if !first.Valid {
+		return "NULL", nil
+	}
+	return first.String, nil
+}
+
 // buildTimeout creates a context with timeout for database operations
 func buildTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
 	if timeout <= 0 {
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -192,6 +194,83 @@ func (m *MySQL) GetVersion(ctx context.Context) (string, error) {
 	return version, nil
 }
 
+// GetDataDirectory returns the server's data directory
+func (m *MySQL) GetDataDirectory(ctx context.Context) (string, error) {
+	if m.db == nil {
+		return "", fmt.Errorf("not connected to database")
+	}
+
+	var dataDir string
+	err := m.db.QueryRowContext(ctx, "SELECT @@datadir").Scan(&dataDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get data directory: %w", err)
+	}
+
+	return dataDir, nil
+}
+
+// GetForeignKeys returns the foreign keys between tables of a database
+func (m *MySQL) GetForeignKeys(ctx context.Context, database string) ([]ForeignKey, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+
+	query := `SELECT constraint_name, table_name, column_name,
+	                 referenced_table_name, referenced_column_name
+	          FROM information_schema.key_column_usage
+	          WHERE table_schema = ? AND referenced_table_schema = ?
+	            AND referenced_table_name IS NOT NULL
+	          ORDER BY table_name, constraint_name, ordinal_position`
+
+	rows, err := m.db.QueryContext(ctx, query, database, database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var fks []ForeignKey
+	for rows.Next() {
+		var name, table, column, refTable, refColumn string
+		if err := rows.Scan(&name, &table, &column, &refTable, &refColumn); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+
+		if n := len(fks); n > 0 && fks[n-1].Name == name && fks[n-1].Table == table {
+			fks[n-1].Columns = append(fks[n-1].Columns, column)
+			fks[n-1].RefColumns = append(fks[n-1].RefColumns, refColumn)
+			continue
+		}
+		fks = append(fks, ForeignKey{
+			Name:       name,
+			Table:      table,
+			Columns:    []string{column},
+			RefTable:   refTable,
+			RefColumns: []string{refColumn},
+		})
+	}
+
+	return fks, rows.Err()
+}
+
+// WriteSample writes sampled table data as INSERT statements, including every
+// parent row referenced through foreign keys so the sample restores cleanly
+func (m *MySQL) WriteSample(ctx context.Context, database string, strategy SampleStrategy, w io.Writer) (*SampleStats, error) {
+	tables, err := m.ListTables(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	fks, err := m.GetForeignKeys(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	dialect := mysqlSampleDialect{database: database}
+	s := newSampler(m.db, dialect, w, fks)
+	return s.run(ctx, tables, func(table string) string {
+		return m.BuildSampleQuery(quoteMySQLIdent(database), quoteMySQLIdent(table), strategy)
+	})
+}
+
 // GetDatabaseSize returns database size in bytes
 func (m *MySQL) GetDatabaseSize(ctx context.Context, database string) (int64, error) {
 	if m.db == nil {
@@ -235,6 +314,156 @@ func (m *MySQL) GetTableRowCount(ctx context.Context, database, table string) (i
 	return count, nil
 }
 
+// CountRows returns the exact row count of a table
+func (m *MySQL) CountRows(ctx context.Context, database, table string) (int64, error) {
+	if m.db == nil {
+		return 0, fmt.Errorf("not connected to database")
+	}
+
+	count, err := countRows(ctx, m.db, mysqlSampleDialect{database: database}.quoteTable(table))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rows of %s: %w", table, err)
+	}
+
+	return count, nil
+}
+
+// QueryValue runs a query with database as the default database and returns
+// the first column of the first row as text ("NULL" for a null value)
+func (m *MySQL) QueryValue(ctx context.Context, database, query string) (string, error) {
+	if m.db == nil {
+		return "", fmt.Errorf("not connected to database")
+	}
+
+	// USE only applies to one connection of the pool
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "USE "+quoteMySQLIdent(database)); err != nil {
+		return "", fmt.Errorf("failed to use database %s: %w", database, err)
+	}
+
+	return queryValue(ctx, conn.QueryContext, query)
+}
+
+// mysqlAutoIncrement matches the AUTO_INCREMENT counter of SHOW CREATE TABLE,
+// which moves with the data rather than the schema
+var mysqlAutoIncrement = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
+// GetSchemaInfo reads the tables of a database with their exact row counts,
+// sizes and normalized definitions, plus views and sequences (MariaDB)
+func (m *MySQL) GetSchemaInfo(ctx context.Context, database string) (*SchemaInfo, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+
+	return m.schemaInfo(ctx, m.db, database)
+}
+
+// BeginSnapshot starts a read-only consistent snapshot transaction. mysqldump
+// can't share it and takes its own right after, so the snapshot has no ID.
+func (m *MySQL) BeginSnapshot(ctx context.Context, database string) (*Snapshot, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+
+	// The transaction lives on one connection of the pool
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	for _, stmt := range []string{
+		"SET TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+	} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to begin snapshot: %w", err)
+		}
+	}
+
+	return &Snapshot{
+		db: conn,
+		read: func(ctx context.Context, db queryer) (*SchemaInfo, error) {
+			return m.schemaInfo(ctx, db, database)
+		},
+		end: func() error {
+			defer conn.Close()
+			_, err := conn.ExecContext(context.Background(), "ROLLBACK")
+			return err
+		},
+	}, nil
+}
+
+// schemaInfo reads the content of database through db
+func (m *MySQL) schemaInfo(ctx context.Context, db queryer, database string) (*SchemaInfo, error) {
+	info := &SchemaInfo{}
+	tableQuery := `SELECT table_name, COALESCE(data_length + index_length, 0)
+	               FROM information_schema.tables
+	               WHERE table_schema = ? AND table_type = 'BASE TABLE'
+	               ORDER BY table_name`
+	err := queryRows(ctx, db, tableQuery, func(rows *sql.Rows) error {
+		var t TableInfo
+		if err := rows.Scan(&t.Name, &t.Size); err != nil {
+			return err
+		}
+		info.Tables = append(info.Tables, t)
+		return nil
+	}, database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+
+	// Names qualified with the database itself are dropped, so a copy restored
+	// under another name has the same definitions
+	qualifier := quoteMySQLIdent(database) + "."
+	for i := range info.Tables {
+		t := &info.Tables[i]
+		if t.RowCount, err = countRows(ctx, db, qualifier+quoteMySQLIdent(t.Name)); err != nil {
+			return nil, fmt.Errorf("failed to count rows of %s: %w", t.Name, err)
+		}
+
+		var name, ddl string
+		query := "SHOW CREATE TABLE " + qualifier + quoteMySQLIdent(t.Name)
+		if err := db.QueryRowContext(ctx, query).Scan(&name, &ddl); err != nil {
+			return nil, fmt.Errorf("failed to read definition of %s: %w", t.Name, err)
+		}
+		ddl = mysqlAutoIncrement.ReplaceAllString(ddl, "")
+		t.DDL = []string{strings.ReplaceAll(ddl, qualifier, "")}
+	}
+
+	otherQueries := []struct {
+		query string
+		dest  *[]string
+	}{
+		{`SELECT CONCAT('view ', table_name, ' ', view_definition)
+		  FROM information_schema.views
+		  WHERE table_schema = ?
+		  ORDER BY table_name`, &info.DDL},
+		{`SELECT table_name FROM information_schema.tables
+		  WHERE table_schema = ? AND table_type = 'SEQUENCE'
+		  ORDER BY table_name`, &info.Sequences},
+	}
+	for _, q := range otherQueries {
+		err := queryRows(ctx, db, q.query, func(rows *sql.Rows) error {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return err
+			}
+			*q.dest = append(*q.dest, strings.ReplaceAll(line, qualifier, ""))
+			return nil
+		}, database)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query schema objects: %w", err)
+		}
+	}
+
+	return info, nil
+}
+
 // BuildBackupCommand builds mysqldump command
 func (m *MySQL) BuildBackupCommand(database, outputFile string, options BackupOptions) []string {
 	cmd := []string{"mysqldump"}
@@ -272,9 +501,20 @@ func (m *MySQL) BuildBackupCommand(database, outputFile string, options BackupOp
 
 	// Backup options
 	cmd = append(cmd, "--single-transaction") // Consistent backup
-	cmd = append(cmd, "--routines")           // Include stored procedures/functions
-	cmd = append(cmd, "--triggers")           // Include triggers
-	cmd = append(cmd, "--events")             // Include events
+
+	// Sections split the schema so data can be loaded in between: pre-data
+	// is the schema without triggers, post-data only the triggers, which
+	// would otherwise fire on the loaded rows
+	switch options.Section {
+	case "pre-data":
+		cmd = append(cmd, "--routines", "--events", "--skip-triggers", "--no-data")
+	case "post-data":
+		cmd = append(cmd, "--skip-routines", "--skip-events", "--triggers", "--no-create-info", "--no-data")
+	default:
+		cmd = append(cmd, "--routines") // Include stored procedures/functions
+		cmd = append(cmd, "--triggers") // Include triggers
+		cmd = append(cmd, "--events")   // Include events
+	}
 
 	if options.SchemaOnly {
 		cmd = append(cmd, "--no-data")
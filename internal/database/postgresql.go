@@ -2,7 +2,9 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strconv"
@@ -140,12 +142,17 @@ func (p *PostgreSQL) ListTables(ctx context.Context, database string) ([]string,
 		return nil, fmt.Errorf("not connected to database")
 	}
 	
+	return p.queryTables(ctx, p.db)
+}
+
+// queryTables lists user tables of the database db is connected to
+func (p *PostgreSQL) queryTables(ctx context.Context, db *sql.DB) ([]string, error) {
 	query := `SELECT schemaname||'.'||tablename as full_name
 	          FROM pg_tables 
 	          WHERE schemaname NOT IN ('information_schema', 'pg_catalog', 'pg_toast')
 	          ORDER BY schemaname, tablename`
 	
-	rows, err := p.db.QueryContext(ctx, query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tables: %w", err)
 	}
@@ -228,6 +235,183 @@ func (p *PostgreSQL) GetVersion(ctx context.Context) (string, error) {
 	return version, nil
 }
 
+// GetDataDirectory returns the server's data directory (requires superuser or pg_read_all_settings)
+func (p *PostgreSQL) GetDataDirectory(ctx context.Context) (string, error) {
+	if p.db == nil {
+		return "", fmt.Errorf("not connected to database")
+	}
+	
+	var dataDir string
+	err := p.db.QueryRowContext(ctx, "SHOW data_directory").Scan(&dataDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get data directory: %w", err)
+	}
+	
+	return dataDir, nil
+}
+
+// GetForeignKeys returns the foreign key constraints defined in a database
+func (p *PostgreSQL) GetForeignKeys(ctx context.Context, database string) ([]ForeignKey, error) {
+	db, closeDB, err := p.connectDatabase(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB()
+	
+	return p.queryForeignKeys(ctx, db)
+}
+
+// queryForeignKeys reads foreign keys from pg_constraint (columns in constraint order)
+func (p *PostgreSQL) queryForeignKeys(ctx context.Context, db *sql.DB) ([]ForeignKey, error) {
+	query := `SELECT c.conname,
+	                 ns.nspname||'.'||cl.relname,
+	                 rns.nspname||'.'||rcl.relname,
+	                 a.attname,
+	                 ra.attname
+	          FROM pg_constraint c
+	          JOIN pg_class cl ON cl.oid = c.conrelid
+	          JOIN pg_namespace ns ON ns.oid = cl.relnamespace
+	          JOIN pg_class rcl ON rcl.oid = c.confrelid
+	          JOIN pg_namespace rns ON rns.oid = rcl.relnamespace
+	          CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, refattnum, ord)
+	          JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+	          JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refattnum
+	          WHERE c.contype = 'f'
+	            AND ns.nspname NOT IN ('information_schema', 'pg_catalog', 'pg_toast')
+	          ORDER BY ns.nspname, cl.relname, c.conname, k.ord`
+	
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	defer rows.Close()
+	
+	var fks []ForeignKey
+	for rows.Next() {
+		var name, table, refTable, column, refColumn string
+		if err := rows.Scan(&name, &table, &refTable, &column, &refColumn); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		
+		if n := len(fks); n > 0 && fks[n-1].Name == name && fks[n-1].Table == table {
+			fks[n-1].Columns = append(fks[n-1].Columns, column)
+			fks[n-1].RefColumns = append(fks[n-1].RefColumns, refColumn)
+			continue
+		}
+		fks = append(fks, ForeignKey{
+			Name:       name,
+			Table:      table,
+			Columns:    []string{column},
+			RefTable:   refTable,
+			RefColumns: []string{refColumn},
+		})
+	}
+	
+	return fks, rows.Err()
+}
+
+// WriteSample writes sampled table data as COPY blocks, including every parent
+// row referenced through foreign keys so the sample restores cleanly, followed
+// by the current values of the sequences the tables own
+func (p *PostgreSQL) WriteSample(ctx context.Context, database string, strategy SampleStrategy, w io.Writer) (*SampleStats, error) {
+	db, closeDB, err := p.connectDatabase(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB()
+	
+	tables, err := p.queryTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	fks, err := p.queryForeignKeys(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	
+	s := newSampler(db, pgSampleDialect{}, w, fks)
+	stats, err := s.run(ctx, tables, func(table string) string {
+		return p.BuildSampleQuery(database, pgSampleDialect{}.quoteTable(table), strategy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := writeSequenceValues(ctx, db, w); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// writeSequenceValues writes a setval() call for every used sequence owned by
+// a table (serial and identity columns), so rows inserted after restoring a
+// sample don't collide with the sampled ones. pg_dump only emits these in its
+// data section, which samples replace.
+func writeSequenceValues(ctx context.Context, db *sql.DB, w io.Writer) error {
+	query := `SELECT 'SELECT pg_catalog.setval(' ||
+	                 quote_literal(quote_ident(s.schemaname)||'.'||quote_ident(s.sequencename)) ||
+	                 ', ' || s.last_value || ', true);'
+	          FROM pg_sequences s
+	          JOIN pg_namespace n ON n.nspname = s.schemaname
+	          JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = s.sequencename
+	          JOIN pg_depend d ON d.classid = 'pg_class'::regclass AND d.objid = c.oid
+	                          AND d.refclassid = 'pg_class'::regclass AND d.deptype IN ('a', 'i')
+	          JOIN pg_class t ON t.oid = d.refobjid AND t.relkind IN ('r', 'p')
+	          WHERE s.last_value IS NOT NULL AND ` + pgUserSchemas + `
+	          ORDER BY s.schemaname, s.sequencename`
+	
+	var setvals []string
+	err := queryRows(ctx, db, query, func(rows *sql.Rows) error {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return err
+		}
+		setvals = append(setvals, line)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read sequence values: %w", err)
+	}
+	if len(setvals) == 0 {
+		return nil
+	}
+	
+	if _, err := fmt.Fprintf(w, "\n-- Sequence values\n\n%s\n\n", strings.Join(setvals, "\n")); err != nil {
+		return fmt.Errorf("failed to write sequence values: %w", err)
+	}
+	return nil
+}
+
+// connectDatabase returns a connection to the given database on the same server.
+// The main connection is reused when it already points there.
+func (p *PostgreSQL) connectDatabase(ctx context.Context, database string) (*sql.DB, func(), error) {
+	if p.db == nil {
+		return nil, nil, fmt.Errorf("not connected to database")
+	}
+	if database == "" || database == p.cfg.Database {
+		return p.db, func() {}, nil
+	}
+	
+	cfg := *p.cfg
+	cfg.Database = database
+	other := &PostgreSQL{baseDatabase: baseDatabase{cfg: &cfg, log: p.log}}
+	
+	pool, err := pgxpool.New(ctx, other.buildPgxDSN())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database %s: %w", database, err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, nil, fmt.Errorf("failed to connect to database %s: %w", database, err)
+	}
+	
+	db := stdlib.OpenDBFromPool(pool)
+	return db, func() {
+		db.Close()
+		pool.Close()
+	}, nil
+}
+
 // GetDatabaseSize returns database size in bytes
 func (p *PostgreSQL) GetDatabaseSize(ctx context.Context, database string) (int64, error) {
 	if p.db == nil {
@@ -246,25 +430,33 @@ func (p *PostgreSQL) GetDatabaseSize(ctx context.Context, database string) (int6
 
 // GetTableRowCount returns approximate row count for a table
 func (p *PostgreSQL) GetTableRowCount(ctx context.Context, database, table string) (int64, error) {
-	if p.db == nil {
-		return 0, fmt.Errorf("not connected to database")
+	db, closeDB, err := p.connectDatabase(ctx, database)
+	if err != nil {
+		return 0, err
 	}
+	defer closeDB()
 	
+	return p.tableRowCount(ctx, db, table)
+}
+
+// tableRowCount estimates a table's rows from pg_stat_user_tables, counting
+// them when there are no statistics
+func (p *PostgreSQL) tableRowCount(ctx context.Context, db *sql.DB, table string) (int64, error) {
 	// Use pg_stat_user_tables for approximate count (faster)
 	parts := strings.Split(table, ".")
 	if len(parts) != 2 {
 		return 0, fmt.Errorf("table name must be in format schema.table")
 	}
 	
-	query := `SELECT COALESCE(n_tup_ins, 0) FROM pg_stat_user_tables 
+	query := `SELECT COALESCE(n_live_tup, 0) FROM pg_stat_user_tables 
 	          WHERE schemaname = $1 AND relname = $2`
 	
 	var count int64
-	err := p.db.QueryRowContext(ctx, query, parts[0], parts[1]).Scan(&count)
+	err := db.QueryRowContext(ctx, query, parts[0], parts[1]).Scan(&count)
 	if err != nil {
 		// Fallback to exact count if stats not available
-		exactQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", table)
-		err = p.db.QueryRowContext(ctx, exactQuery).Scan(&count)
+		exactQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", pgSampleDialect{}.quoteTable(table))
+		err = db.QueryRowContext(ctx, exactQuery).Scan(&count)
 		if err != nil {
 			return 0, fmt.Errorf("failed to get table row count: %w", err)
 		}
@@ -273,6 +465,187 @@ func (p *PostgreSQL) GetTableRowCount(ctx context.Context, database, table strin
 	return count, nil
 }
 
+// CountRows returns the exact row count of a table (schema.table, as returned by ListTables)
+func (p *PostgreSQL) CountRows(ctx context.Context, database, table string) (int64, error) {
+	db, closeDB, err := p.connectDatabase(ctx, database)
+	if err != nil {
+		return 0, err
+	}
+	defer closeDB()
+	
+	count, err := countRows(ctx, db, pgSampleDialect{}.quoteTable(table))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rows of %s: %w", table, err)
+	}
+	
+	return count, nil
+}
+
+// QueryValue runs a query in a database and returns the first column of the
+// first row as text ("NULL" for a null value)
+func (p *PostgreSQL) QueryValue(ctx context.Context, database, query string) (string, error) {
+	db, closeDB, err := p.connectDatabase(ctx, database)
+	if err != nil {
+		return "", err
+	}
+	defer closeDB()
+	
+	return queryValue(ctx, db.QueryContext, query)
+}
+
+// pgUserSchemas excludes system schemas from catalog queries on namespace n
+const pgUserSchemas = `n.nspname NOT IN ('information_schema', 'pg_catalog', 'pg_toast')
+	            AND n.nspname NOT LIKE 'pg_temp%' AND n.nspname NOT LIKE 'pg_toast_temp%'`
+
+// GetSchemaInfo reads the tables of a database with their exact row counts,
+// sizes and normalized definitions, plus views, extensions and sequences
+func (p *PostgreSQL) GetSchemaInfo(ctx context.Context, database string) (*SchemaInfo, error) {
+	db, closeDB, err := p.connectDatabase(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB()
+	
+	return p.schemaInfo(ctx, db)
+}
+
+// BeginSnapshot starts a read-only repeatable read transaction in database and
+// exports its snapshot, so pg_dump --snapshot dumps exactly what it sees
+func (p *PostgreSQL) BeginSnapshot(ctx context.Context, database string) (*Snapshot, error) {
+	db, closeDB, err := p.connectDatabase(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		closeDB()
+		return nil, fmt.Errorf("failed to begin snapshot: %w", err)
+	}
+	var id string
+	if err := tx.QueryRowContext(ctx, "SELECT pg_export_snapshot()").Scan(&id); err != nil {
+		tx.Rollback()
+		closeDB()
+		return nil, fmt.Errorf("failed to export snapshot: %w", err)
+	}
+	
+	return &Snapshot{
+		ID:   id,
+		db:   tx,
+		read: p.schemaInfo,
+		end: func() error {
+			defer closeDB()
+			return tx.Rollback()
+		},
+	}, nil
+}
+
+// schemaInfo reads the content of the database db is connected to
+func (p *PostgreSQL) schemaInfo(ctx context.Context, db queryer) (*SchemaInfo, error) {
+	info := &SchemaInfo{}
+	index := make(map[string]int) // schema.table -> position in info.Tables
+	
+	tableQuery := `SELECT n.nspname, c.relname, pg_total_relation_size(c.oid)
+	               FROM pg_class c
+	               JOIN pg_namespace n ON n.oid = c.relnamespace
+	               WHERE c.relkind IN ('r', 'p') AND ` + pgUserSchemas + `
+	               ORDER BY n.nspname, c.relname`
+	err := queryRows(ctx, db, tableQuery, func(rows *sql.Rows) error {
+		var t TableInfo
+		if err := rows.Scan(&t.Schema, &t.Name, &t.Size); err != nil {
+			return err
+		}
+		index[t.FullName()] = len(info.Tables)
+		info.Tables = append(info.Tables, t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	
+	for i := range info.Tables {
+		t := &info.Tables[i]
+		if t.RowCount, err = countRows(ctx, db, pgSampleDialect{}.quoteTable(t.FullName())); err != nil {
+			return nil, fmt.Errorf("failed to count rows of %s: %w", t.FullName(), err)
+		}
+	}
+	
+	// Definitions, each line tied to a table: columns in order, then
+	// constraints and indexes by name
+	definitionQueries := []string{
+		`SELECT n.nspname||'.'||c.relname,
+		        'column '||a.attname||' '||format_type(a.atttypid, a.atttypmod)||
+		        CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END||
+		        COALESCE(' DEFAULT '||pg_get_expr(d.adbin, d.adrelid), '')
+		 FROM pg_attribute a
+		 JOIN pg_class c ON c.oid = a.attrelid
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		 WHERE a.attnum > 0 AND NOT a.attisdropped AND c.relkind IN ('r', 'p') AND ` + pgUserSchemas + `
+		 ORDER BY n.nspname, c.relname, a.attnum`,
+		`SELECT n.nspname||'.'||c.relname, 'constraint '||co.conname||' '||pg_get_constraintdef(co.oid)
+		 FROM pg_constraint co
+		 JOIN pg_class c ON c.oid = co.conrelid
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE ` + pgUserSchemas + `
+		 ORDER BY n.nspname, c.relname, co.conname`,
+		`SELECT n.nspname||'.'||c.relname, pg_get_indexdef(i.indexrelid)
+		 FROM pg_index i
+		 JOIN pg_class c ON c.oid = i.indrelid
+		 JOIN pg_class ic ON ic.oid = i.indexrelid
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE c.relkind IN ('r', 'p') AND ` + pgUserSchemas + `
+		 ORDER BY n.nspname, c.relname, ic.relname`,
+	}
+	for _, query := range definitionQueries {
+		err := queryRows(ctx, db, query, func(rows *sql.Rows) error {
+			var table, line string
+			if err := rows.Scan(&table, &line); err != nil {
+				return err
+			}
+			if i, ok := index[table]; ok {
+				info.Tables[i].DDL = append(info.Tables[i].DDL, line)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query table definitions: %w", err)
+		}
+	}
+	
+	otherQueries := []struct {
+		query string
+		dest  *[]string
+	}{
+		{`SELECT 'view '||n.nspname||'.'||c.relname||' '||pg_get_viewdef(c.oid)
+		  FROM pg_class c
+		  JOIN pg_namespace n ON n.oid = c.relnamespace
+		  WHERE c.relkind IN ('v', 'm') AND ` + pgUserSchemas + `
+		  ORDER BY n.nspname, c.relname`, &info.DDL},
+		{`SELECT extname||' '||extversion FROM pg_extension ORDER BY extname`, &info.Extensions},
+		{`SELECT n.nspname||'.'||c.relname
+		  FROM pg_class c
+		  JOIN pg_namespace n ON n.oid = c.relnamespace
+		  WHERE c.relkind = 'S' AND ` + pgUserSchemas + `
+		  ORDER BY n.nspname, c.relname`, &info.Sequences},
+	}
+	for _, q := range otherQueries {
+		err := queryRows(ctx, db, q.query, func(rows *sql.Rows) error {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return err
+			}
+			*q.dest = append(*q.dest, line)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query schema objects: %w", err)
+		}
+	}
+	
+	return info, nil
+}
+
 // BuildBackupCommand builds pg_dump command
 func (p *PostgreSQL) BuildBackupCommand(database, outputFile string, options BackupOptions) []string {
 	cmd := []string{"pg_dump"}
@@ -324,6 +697,12 @@ func (p *PostgreSQL) BuildBackupCommand(database, outputFile string, options Bac
 	if options.Role != "" {
 		cmd = append(cmd, "--role="+options.Role)
 	}
+	if options.Section != "" {
+		cmd = append(cmd, "--section="+options.Section)
+	}
+	if options.Snapshot != "" {
+		cmd = append(cmd, "--snapshot="+options.Snapshot)
+	}
 	
 	// Database
 	cmd = append(cmd, "--dbname="+database)
@@ -370,6 +749,9 @@ func (p *PostgreSQL) BuildRestoreCommand(database, inputFile string, options Res
 	if options.SingleTransaction {
 		cmd = append(cmd, "--single-transaction")
 	}
+	if options.UseList != "" {
+		cmd = append(cmd, "--use-list="+options.UseList)
+	}
 	
 	// NOTE: --exit-on-error removed because it causes entire restore to fail on
 	// "already exists" errors. PostgreSQL continues on ignorable errors by default
@@ -385,7 +767,10 @@ func (p *PostgreSQL) BuildRestoreCommand(database, inputFile string, options Res
 	
 	// Database and input
 	cmd = append(cmd, "--dbname="+database)
-	cmd = append(cmd, inputFile)
+	// Without an input file pg_restore reads the archive from stdin
+	if inputFile != "" {
+		cmd = append(cmd, inputFile)
+	}
 	
 	return cmd
 }
@@ -0,0 +1,500 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// sampleBatchSize is the number of parent keys fetched per lookup query
+const sampleBatchSize = 200
+
+// ForeignKey describes a foreign key constraint between two tables.
+// Table names use the same format as ListTables, columns are in constraint order.
+type ForeignKey struct {
+	Name       string
+	Table      string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+// SampleStats summarizes a sample extraction
+type SampleStats struct {
+	Tables     int
+	Rows       int64 // Rows selected by the sampling strategy
+	ParentRows int64 // Extra rows pulled in to satisfy foreign keys
+}
+
+// sampleDialect holds the engine specific parts of sample extraction
+type sampleDialect interface {
+	// quoteTable quotes a table name as returned by ListTables
+	quoteTable(table string) string
+	quoteIdent(name string) string
+	// selectColumn returns an expression yielding the column's text representation
+	selectColumn(name string) string
+	// columnsQuery returns a query listing the table's writable columns in
+	// ordinal order; generated columns are left out since restores reject values for them
+	columnsQuery(table string) (string, []any)
+	placeholder(n int) string
+	// newWriter returns a writer emitting restorable data for one table
+	newWriter(w io.Writer, table string, columns []string) sampleRowWriter
+}
+
+// sampleRowWriter emits rows for one table
+type sampleRowWriter interface {
+	writeRow(values []sql.NullString) error
+	close() error
+}
+
+// sampler extracts a referentially consistent subset of a database:
+// every table is sampled with the configured strategy, then rows referenced
+// by foreign keys of already included rows are added until nothing is missing
+type sampler struct {
+	db      *sql.DB
+	dialect sampleDialect
+	out     *bufio.Writer
+
+	fks       map[string][]ForeignKey // child table -> outgoing foreign keys
+	refSets   map[string][][]string   // parent table -> referenced column sets
+	columns   map[string][]string
+	included  map[string]map[string]map[string]bool // table -> column set -> written key values
+	requested map[string]map[string]map[string]bool // table -> column set -> queued key values
+	pending   map[string]map[string][][]string      // table -> column set -> missing key values
+
+	stats SampleStats
+}
+
+func newSampler(db *sql.DB, dialect sampleDialect, w io.Writer, fks []ForeignKey) *sampler {
+	s := &sampler{
+		db:        db,
+		dialect:   dialect,
+		out:       bufio.NewWriterSize(w, 256*1024),
+		fks:       make(map[string][]ForeignKey),
+		refSets:   make(map[string][][]string),
+		columns:   make(map[string][]string),
+		included:  make(map[string]map[string]map[string]bool),
+		requested: make(map[string]map[string]map[string]bool),
+		pending:   make(map[string]map[string][][]string),
+	}
+
+	for _, fk := range fks {
+		s.fks[fk.Table] = append(s.fks[fk.Table], fk)
+		if !s.hasRefSet(fk.RefTable, fk.RefColumns) {
+			s.refSets[fk.RefTable] = append(s.refSets[fk.RefTable], fk.RefColumns)
+		}
+	}
+
+	return s
+}
+
+func (s *sampler) hasRefSet(table string, cols []string) bool {
+	for _, set := range s.refSets[table] {
+		if columnSetKey(set) == columnSetKey(cols) {
+			return true
+		}
+	}
+	return false
+}
+
+// run samples every table and then closes over foreign key references
+func (s *sampler) run(ctx context.Context, tables []string, buildQuery func(table string) string) (*SampleStats, error) {
+	known := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		known[table] = true
+		cols, err := s.tableColumns(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		s.columns[table] = cols
+	}
+
+	// Drop references to tables we can't sample (e.g. excluded or other schemas)
+	for table, fks := range s.fks {
+		var kept []ForeignKey
+		for _, fk := range fks {
+			if known[fk.RefTable] {
+				kept = append(kept, fk)
+			}
+		}
+		s.fks[table] = kept
+	}
+
+	// Phase 1: apply the sampling strategy to every table
+	for _, table := range tables {
+		query := fmt.Sprintf("SELECT %s FROM (%s) s", s.selectList(table), buildQuery(table))
+		n, err := s.copyRows(ctx, table, query, nil, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to sample table %s: %w", table, err)
+		}
+		s.stats.Rows += n
+		s.stats.Tables++
+	}
+
+	// Phase 2: fetch referenced parent rows until the subset is closed
+	for len(s.pending) > 0 {
+		pending := s.pending
+		s.pending = make(map[string]map[string][][]string)
+
+		for _, table := range sortedKeys(pending) {
+			for _, setKey := range sortedKeys(pending[table]) {
+				cols := strings.Split(setKey, "\x1f")
+				keys := pending[table][setKey]
+				for start := 0; start < len(keys); start += sampleBatchSize {
+					end := start + sampleBatchSize
+					if end > len(keys) {
+						end = len(keys)
+					}
+					query, args := s.lookupQuery(table, cols, keys[start:end])
+					n, err := s.copyRows(ctx, table, query, args, setKey)
+					if err != nil {
+						return nil, fmt.Errorf("failed to fetch referenced rows from %s: %w", table, err)
+					}
+					s.stats.ParentRows += n
+				}
+			}
+		}
+	}
+
+	if err := s.out.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write sample data: %w", err)
+	}
+
+	return &s.stats, nil
+}
+
+// tableColumns reads the names of a table's writable columns in ordinal order
+func (s *sampler) tableColumns(ctx context.Context, table string) ([]string, error) {
+	query, args := s.dialect.columnsQuery(table)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		cols = append(cols, col)
+	}
+	return cols, rows.Err()
+}
+
+func (s *sampler) selectList(table string) string {
+	cols := s.columns[table]
+	exprs := make([]string, len(cols))
+	for i, col := range cols {
+		exprs[i] = s.dialect.selectColumn(col)
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// lookupQuery builds a query selecting rows whose cols match one of keys
+func (s *sampler) lookupQuery(table string, cols []string, keys [][]string) (string, []any) {
+	quoted := make([]string, len(cols))
+	for i, col := range cols {
+		quoted[i] = s.dialect.quoteIdent(col)
+	}
+
+	args := make([]any, 0, len(keys)*len(cols))
+	tuples := make([]string, len(keys))
+	for i, key := range keys {
+		ph := make([]string, len(key))
+		for j, v := range key {
+			args = append(args, v)
+			ph[j] = s.dialect.placeholder(len(args))
+		}
+		tuples[i] = "(" + strings.Join(ph, ", ") + ")"
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE (%s) IN (%s)",
+		s.selectList(table), s.dialect.quoteTable(table),
+		strings.Join(quoted, ", "), strings.Join(tuples, ", "))
+	return query, args
+}
+
+// copyRows writes the rows returned by query and records their keys.
+// When dedupeSet is set, rows already included under that column set are skipped.
+func (s *sampler) copyRows(ctx context.Context, table, query string, args []any, dedupeSet string) (int64, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	cols := s.columns[table]
+	index := make(map[string]int, len(cols))
+	for i, col := range cols {
+		index[col] = i
+	}
+
+	writer := s.dialect.newWriter(s.out, table, cols)
+	var count int64
+
+	for rows.Next() {
+		values := make([]sql.NullString, len(cols))
+		dest := make([]any, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return count, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if dedupeSet != "" {
+			if key, ok := rowKey(values, index, strings.Split(dedupeSet, "\x1f")); ok && s.included[table][dedupeSet][key] {
+				continue
+			}
+		}
+
+		s.recordRow(table, values, index)
+		if err := writer.writeRow(values); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	return count, writer.close()
+}
+
+// recordRow marks the row's keys as included and queues missing parents
+func (s *sampler) recordRow(table string, values []sql.NullString, index map[string]int) {
+	for _, set := range s.refSets[table] {
+		if key, ok := rowKey(values, index, set); ok {
+			markKey(s.included, table, columnSetKey(set), key)
+		}
+	}
+
+	for _, fk := range s.fks[table] {
+		key, ok := rowKey(values, index, fk.Columns)
+		if !ok {
+			// NULL foreign keys reference nothing
+			continue
+		}
+		setKey := columnSetKey(fk.RefColumns)
+		if s.included[fk.RefTable][setKey][key] || s.requested[fk.RefTable][setKey][key] {
+			continue
+		}
+
+		markKey(s.requested, fk.RefTable, setKey, key)
+		if s.pending[fk.RefTable] == nil {
+			s.pending[fk.RefTable] = make(map[string][][]string)
+		}
+		s.pending[fk.RefTable][setKey] = append(s.pending[fk.RefTable][setKey], splitKey(key))
+	}
+}
+
+func markKey(keys map[string]map[string]map[string]bool, table, setKey, key string) {
+	if keys[table] == nil {
+		keys[table] = make(map[string]map[string]bool)
+	}
+	if keys[table][setKey] == nil {
+		keys[table][setKey] = make(map[string]bool)
+	}
+	keys[table][setKey][key] = true
+}
+
+// rowKey joins the values of cols; ok is false if any of them is NULL
+func rowKey(values []sql.NullString, index map[string]int, cols []string) (string, bool) {
+	parts := make([]string, len(cols))
+	for i, col := range cols {
+		pos, found := index[col]
+		if !found || !values[pos].Valid {
+			return "", false
+		}
+		parts[i] = values[pos].String
+	}
+	return strings.Join(parts, "\x00"), true
+}
+
+func splitKey(key string) []string {
+	return strings.Split(key, "\x00")
+}
+
+func columnSetKey(cols []string) string {
+	return strings.Join(cols, "\x1f")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pgSampleDialect emits COPY blocks for PostgreSQL
+type pgSampleDialect struct{}
+
+func (pgSampleDialect) quoteTable(table string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return quotePgIdent(schema) + "." + quotePgIdent(name)
+	}
+	return quotePgIdent(table)
+}
+
+func (pgSampleDialect) quoteIdent(name string) string {
+	return quotePgIdent(name)
+}
+
+func (pgSampleDialect) selectColumn(name string) string {
+	return quotePgIdent(name) + "::text"
+}
+
+func (pgSampleDialect) placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (d pgSampleDialect) columnsQuery(table string) (string, []any) {
+	return `SELECT attname FROM pg_attribute
+	        WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped AND attgenerated = ''
+	        ORDER BY attnum`, []any{d.quoteTable(table)}
+}
+
+func (d pgSampleDialect) newWriter(w io.Writer, table string, columns []string) sampleRowWriter {
+	return &pgCopyWriter{w: w, header: fmt.Sprintf("COPY %s (%s) FROM stdin;\n", d.quoteTable(table), quoteAll(columns, quotePgIdent))}
+}
+
+func quotePgIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// pgCopyWriter writes rows in COPY text format, opening the block lazily
+type pgCopyWriter struct {
+	w      io.Writer
+	header string
+	open   bool
+}
+
+var pgCopyEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (c *pgCopyWriter) writeRow(values []sql.NullString) error {
+	if !c.open {
+		if _, err := io.WriteString(c.w, c.header); err != nil {
+			return err
+		}
+		c.open = true
+	}
+
+	fields := make([]string, len(values))
+	for i, v := range values {
+		if !v.Valid {
+			fields[i] = `\N`
+		} else {
+			fields[i] = pgCopyEscaper.Replace(v.String)
+		}
+	}
+	_, err := io.WriteString(c.w, strings.Join(fields, "\t")+"\n")
+	return err
+}
+
+func (c *pgCopyWriter) close() error {
+	if !c.open {
+		return nil
+	}
+	_, err := io.WriteString(c.w, "\\.\n\n")
+	return err
+}
+
+// mysqlSampleDialect emits extended INSERT statements for MySQL
+type mysqlSampleDialect struct {
+	database string
+}
+
+func (d mysqlSampleDialect) quoteTable(table string) string {
+	return quoteMySQLIdent(d.database) + "." + quoteMySQLIdent(table)
+}
+
+func (mysqlSampleDialect) quoteIdent(name string) string {
+	return quoteMySQLIdent(name)
+}
+
+func (mysqlSampleDialect) selectColumn(name string) string {
+	return quoteMySQLIdent(name)
+}
+
+func (mysqlSampleDialect) placeholder(int) string {
+	return "?"
+}
+
+// columnsQuery matches VIRTUAL GENERATED and STORED GENERATED columns only:
+// MySQL 8 reports columns with a default expression as DEFAULT_GENERATED
+func (d mysqlSampleDialect) columnsQuery(table string) (string, []any) {
+	return `SELECT column_name FROM information_schema.columns
+	        WHERE table_schema = ? AND table_name = ?
+	          AND extra NOT LIKE '%VIRTUAL GENERATED%' AND extra NOT LIKE '%STORED GENERATED%'
+	        ORDER BY ordinal_position`, []any{d.database, table}
+}
+
+func (mysqlSampleDialect) newWriter(w io.Writer, table string, columns []string) sampleRowWriter {
+	return &mysqlInsertWriter{w: w, prefix: fmt.Sprintf("INSERT INTO %s (%s) VALUES\n", quoteMySQLIdent(table), quoteAll(columns, quoteMySQLIdent))}
+}
+
+func quoteMySQLIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// mysqlInsertBatch is the number of rows per INSERT statement
+const mysqlInsertBatch = 100
+
+// mysqlInsertWriter groups rows into extended INSERT statements
+type mysqlInsertWriter struct {
+	w      io.Writer
+	prefix string
+	rows   int
+}
+
+var mysqlEscaper = strings.NewReplacer(`\`, `\\`, "'", `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+
+func (m *mysqlInsertWriter) writeRow(values []sql.NullString) error {
+	sep := ",\n"
+	if m.rows == 0 {
+		sep = m.prefix
+	}
+
+	fields := make([]string, len(values))
+	for i, v := range values {
+		if !v.Valid {
+			fields[i] = "NULL"
+		} else {
+			fields[i] = "'" + mysqlEscaper.Replace(v.String) + "'"
+		}
+	}
+	if _, err := io.WriteString(m.w, sep+"("+strings.Join(fields, ",")+")"); err != nil {
+		return err
+	}
+
+	m.rows++
+	if m.rows == mysqlInsertBatch {
+		return m.close()
+	}
+	return nil
+}
+
+func (m *mysqlInsertWriter) close() error {
+	if m.rows == 0 {
+		return nil
+	}
+	m.rows = 0
+	_, err := io.WriteString(m.w, ";\n")
+	return err
+}
+
+func quoteAll(names []string, quote func(string) string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
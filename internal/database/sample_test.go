@@ -0,0 +1,71 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestPgCopyWriter(t *testing.T) {
+	var buf strings.Builder
+	w := pgSampleDialect{}.newWriter(&buf, "public.orders", []string{"id", "note"})
+
+	// Nothing is written for tables without rows
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no output for empty table, got %q", buf.String())
+	}
+
+	w.writeRow([]sql.NullString{{String: "1", Valid: true}, {String: "a\tb\\c\nd", Valid: true}})
+	w.writeRow([]sql.NullString{{String: "2", Valid: true}, {}})
+	w.close()
+
+	want := "COPY \"public\".\"orders\" (\"id\", \"note\") FROM stdin;\n" +
+		"1\ta\\tb\\\\c\\nd\n" +
+		"2\t\\N\n" +
+		"\\.\n\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected COPY output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestMySQLInsertWriter(t *testing.T) {
+	var buf strings.Builder
+	w := mysqlSampleDialect{database: "shop"}.newWriter(&buf, "orders", []string{"id", "note"})
+
+	w.writeRow([]sql.NullString{{String: "1", Valid: true}, {String: "it's", Valid: true}})
+	w.writeRow([]sql.NullString{{String: "2", Valid: true}, {}})
+	w.close()
+
+	want := "INSERT INTO `orders` (`id`, `note`) VALUES\n('1','it\\'s'),\n('2',NULL);\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected INSERT output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestSamplerQueuesMissingParents(t *testing.T) {
+	fks := []ForeignKey{
+		{Name: "orders_customer_fk", Table: "orders", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}},
+	}
+	s := newSampler(nil, pgSampleDialect{}, &strings.Builder{}, fks)
+	s.columns["orders"] = []string{"id", "customer_id"}
+	index := map[string]int{"id": 0, "customer_id": 1}
+
+	s.recordRow("orders", []sql.NullString{{String: "1", Valid: true}, {String: "7", Valid: true}}, index)
+	s.recordRow("orders", []sql.NullString{{String: "2", Valid: true}, {String: "7", Valid: true}}, index)
+	s.recordRow("orders", []sql.NullString{{String: "3", Valid: true}, {}}, index)
+
+	keys := s.pending["customers"][columnSetKey([]string{"id"})]
+	if len(keys) != 1 || keys[0][0] != "7" {
+		t.Fatalf("Expected a single pending parent key 7, got %v", keys)
+	}
+
+	// A parent that was already written is not requested again
+	s.recordRow("customers", []sql.NullString{{String: "8", Valid: true}}, map[string]int{"id": 0})
+	s.recordRow("orders", []sql.NullString{{String: "4", Valid: true}, {String: "8", Valid: true}}, index)
+	if got := len(s.pending["customers"][columnSetKey([]string{"id"})]); got != 1 {
+		t.Errorf("Expected parent 8 to be satisfied by included row, got %d pending keys", got)
+	}
+}
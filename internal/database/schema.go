@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+)
+
+// SchemaInfo describes what a database contains: its tables with row counts,
+// sizes and definitions, and the objects not tied to a table
+type SchemaInfo struct {
+	Tables     []TableInfo
+	DDL        []string // Normalized definitions of views (not tied to a table)
+	Extensions []string // "name version", PostgreSQL only
+	Sequences  []string // schema.name for PostgreSQL, name for MariaDB
+}
+
+// FullName returns the table name in the format of ListTables
+func (t TableInfo) FullName() string {
+	if t.Schema == "" {
+		return t.Name
+	}
+	return t.Schema + "." + t.Name
+}
+
+// Fingerprint hashes the table's normalized definition
+func (t TableInfo) Fingerprint() string {
+	return hashLines(t.DDL)
+}
+
+// Fingerprint hashes the normalized definitions of the schema. It changes
+// when a table, column, constraint, index, view, extension or sequence is
+// added, removed or altered, but not when only data changes.
+func (s *SchemaInfo) Fingerprint() string {
+	var lines []string
+	for _, t := range s.Tables {
+		lines = append(lines, "table "+t.FullName())
+		lines = append(lines, t.DDL...)
+	}
+	lines = append(lines, s.DDL...)
+	for _, ext := range s.Extensions {
+		lines = append(lines, "extension "+ext)
+	}
+	for _, seq := range s.Sequences {
+		lines = append(lines, "sequence "+seq)
+	}
+	return hashLines(lines)
+}
+
+func hashLines(lines []string) string {
+	hash := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// queryer runs queries on a database, or in a transaction or connection
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// queryRows runs a query and calls scan for every row
+func queryRows(ctx context.Context, db queryer, query string, scan func(*sql.Rows) error, args ...any) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// countRows returns the exact row count of a quoted table
+func countRows(ctx context.Context, db queryer, quoted string) (int64, error) {
+	var count int64
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoted).Scan(&count)
+	return count, err
+}
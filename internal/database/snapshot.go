@@ -0,0 +1,26 @@
+package database
+
+import "context"
+
+// Snapshot is a read-only transaction that sees a database as it was when
+// the snapshot began. Backups read their content in one while the dump runs.
+// On PostgreSQL it is exported: a dump given its ID (BackupOptions.Snapshot)
+// sees exactly the same data. MySQL can't share one, so ID is empty there.
+type Snapshot struct {
+	ID string
+
+	db   queryer
+	read func(ctx context.Context, db queryer) (*SchemaInfo, error)
+	end  func() error
+}
+
+// SchemaInfo reads the database's content as the snapshot sees it, with
+// exact row counts
+func (s *Snapshot) SchemaInfo(ctx context.Context) (*SchemaInfo, error) {
+	return s.read(ctx, s.db)
+}
+
+// Close ends the snapshot. A dump sharing it must have started by then.
+func (s *Snapshot) Close() error {
+	return s.end()
+}
@@ -0,0 +1,245 @@
+package drill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dbbackup/internal/database"
+	"dbbackup/internal/metadata"
+)
+
+// Checks are the sanity checks run against a restored backup
+type Checks struct {
+	// MinTables is the number of tables the restore must at least produce (default 1)
+	MinTables int `json:"min_tables,omitempty"`
+
+	// RowCounts maps tables (schema.table for PostgreSQL) to the number of
+	// rows they had when the backup was taken
+	RowCounts map[string]int64 `json:"row_counts,omitempty"`
+
+	Assertions []Assertion `json:"assertions,omitempty"`
+}
+
+// Assertion is a custom SQL check. The query must return a single value,
+// which has to equal Expect, or be true (true, t, 1, yes) when Expect is empty.
+type Assertion struct {
+	Name   string `json:"name,omitempty"`
+	SQL    string `json:"sql"`
+	Expect string `json:"expect,omitempty"`
+}
+
+// LoadChecks reads checks from a JSON file
+func LoadChecks(path string) (*Checks, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checks file: %w", err)
+	}
+	var checks Checks
+	if err := json.Unmarshal(data, &checks); err != nil {
+		return nil, fmt.Errorf("failed to parse checks file %s: %w", path, err)
+	}
+	for i, a := range checks.Assertions {
+		if strings.TrimSpace(a.SQL) == "" {
+			return nil, fmt.Errorf("assertion %d in %s has no sql", i+1, path)
+		}
+	}
+	return &checks, nil
+}
+
+// ParseRowCounts parses --expect-rows values given as table=count, e.g.
+// "public.orders=1200"
+func ParseRowCounts(specs []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(specs))
+	for _, spec := range specs {
+		table, value, ok := strings.Cut(spec, "=")
+		table = strings.TrimSpace(table)
+		count, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if !ok || table == "" || err != nil || count < 0 {
+			return nil, fmt.Errorf("invalid row count %q: expected table=count (e.g. public.orders=1200)", spec)
+		}
+		counts[table] = count
+	}
+	return counts, nil
+}
+
+// MergeRowCounts adds row counts to the checks, overriding counts for the same tables
+func (c *Checks) MergeRowCounts(counts map[string]int64) {
+	if len(counts) == 0 {
+		return
+	}
+	if c.RowCounts == nil {
+		c.RowCounts = make(map[string]int64, len(counts))
+	}
+	maps.Copy(c.RowCounts, counts)
+}
+
+// querier is the part of database.Database the checks use
+type querier interface {
+	ListTables(ctx context.Context, database string) ([]string, error)
+	CountRows(ctx context.Context, database, table string) (int64, error)
+	QueryValue(ctx context.Context, database, query string) (string, error)
+}
+
+// runChecks runs the checks against the restored database and returns their
+// outcome along with the number of tables found
+func runChecks(ctx context.Context, db querier, database string, checks *Checks) ([]metadata.DrillCheck, int) {
+	var results []metadata.DrillCheck
+
+	minTables := max(checks.MinTables, 1)
+	tables, err := db.ListTables(ctx, database)
+	tableCheck := metadata.DrillCheck{Name: "tables restored", Expected: fmt.Sprintf(">= %d", minTables)}
+	if err != nil {
+		tableCheck.Error = err.Error()
+	} else {
+		tableCheck.Actual = strconv.Itoa(len(tables))
+		tableCheck.Passed = len(tables) >= minTables
+	}
+	results = append(results, tableCheck)
+
+	names := make([]string, 0, len(checks.RowCounts))
+	for table := range checks.RowCounts {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+	for _, table := range names {
+		check := metadata.DrillCheck{
+			Name:     "row count " + table,
+			Expected: strconv.FormatInt(checks.RowCounts[table], 10),
+		}
+		count, err := db.CountRows(ctx, database, table)
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			check.Actual = strconv.FormatInt(count, 10)
+			check.Passed = count == checks.RowCounts[table]
+		}
+		results = append(results, check)
+	}
+
+	for i, a := range checks.Assertions {
+		check := metadata.DrillCheck{Name: a.Name, Expected: a.Expect}
+		if check.Name == "" {
+			check.Name = fmt.Sprintf("assertion %d", i+1)
+		}
+		if check.Expected == "" {
+			check.Expected = "true"
+		}
+		value, err := db.QueryValue(ctx, database, a.SQL)
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			check.Actual = value
+			check.Passed = assertionPassed(value, a.Expect)
+		}
+		results = append(results, check)
+	}
+
+	return results, len(tables)
+}
+
+// schemaReader is the part of database.Database the content checks use
+type schemaReader interface {
+	GetSchemaInfo(ctx context.Context, database string) (*database.SchemaInfo, error)
+}
+
+// contentChecks compares the restored database to the content recorded in the
+// backup's metadata: every table must be back with the rows counted when the
+// backup was taken, and the schema fingerprint must match. Row counts must
+// match exactly only when they were read in the dump's own snapshot; otherwise
+// the difference is only reported. The fingerprint is only compared on the
+// server version the backup was taken from, since other versions normalize
+// definitions differently.
+func contentChecks(ctx context.Context, db schemaReader, target string, meta *metadata.BackupMetadata, serverVersion string) []metadata.DrillCheck {
+	if meta == nil || (len(meta.Tables) == 0 && meta.SchemaFingerprint == "") {
+		return nil
+	}
+
+	schema, err := db.GetSchemaInfo(ctx, target)
+	if err != nil {
+		return []metadata.DrillCheck{{Name: "content matches backup", Error: err.Error()}}
+	}
+
+	var results []metadata.DrillCheck
+	if len(meta.Tables) > 0 {
+		restored := make(map[string]int64, len(schema.Tables)) // name -> rows
+		for _, t := range schema.Tables {
+			restored[t.FullName()] = t.RowCount
+		}
+		var missing []string
+		for _, t := range meta.Tables {
+			if _, ok := restored[t.Name]; !ok {
+				missing = append(missing, t.Name)
+			}
+		}
+		check := metadata.DrillCheck{
+			Name:     "tables match backup",
+			Expected: fmt.Sprintf("%d tables", len(meta.Tables)),
+			Actual:   fmt.Sprintf("%d tables", len(schema.Tables)),
+			Passed:   len(missing) == 0,
+		}
+		if len(missing) > 0 {
+			check.Actual = "missing " + strings.Join(missing, ", ")
+		}
+		results = append(results, check)
+
+		// Without RowsExact the counts come from a snapshot taken just before
+		// the dump's (MySQL), so rows written in between legitimately differ
+		for _, t := range meta.Tables {
+			rows, ok := restored[t.Name]
+			if !ok {
+				continue
+			}
+			check := metadata.DrillCheck{
+				Name:     "rows match backup " + t.Name,
+				Expected: strconv.FormatInt(t.Rows, 10),
+				Actual:   strconv.FormatInt(rows, 10),
+				Passed:   rows == t.Rows || !meta.RowsExact,
+			}
+			if !meta.RowsExact {
+				check.Expected = "about " + check.Expected
+				if rows != t.Rows {
+					check.Actual = fmt.Sprintf("%d (%+d since the count)", rows, rows-t.Rows)
+				}
+			}
+			results = append(results, check)
+		}
+	}
+
+	if meta.SchemaFingerprint != "" && meta.DatabaseVersion == serverVersion {
+		fingerprint := schema.Fingerprint()
+		results = append(results, metadata.DrillCheck{
+			Name:     "schema fingerprint",
+			Expected: shortHash(meta.SchemaFingerprint),
+			Actual:   shortHash(fingerprint),
+			Passed:   fingerprint == meta.SchemaFingerprint,
+		})
+	}
+	return results
+}
+
+// shortHash abbreviates a fingerprint for display
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// assertionPassed compares the value an assertion query returned
+func assertionPassed(value, expect string) bool {
+	value = strings.TrimSpace(value)
+	if expect != "" {
+		return value == expect
+	}
+	switch strings.ToLower(value) {
+	case "true", "t", "1", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package drill
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dbbackup/internal/database"
+	"dbbackup/internal/metadata"
+)
+
+// fakeDB answers the checks' queries from fixed values
+type fakeDB struct {
+	tables []string
+	rows   map[string]int64
+	values map[string]string
+}
+
+func (f *fakeDB) ListTables(ctx context.Context, database string) ([]string, error) {
+	return f.tables, nil
+}
+
+func (f *fakeDB) CountRows(ctx context.Context, database, table string) (int64, error) {
+	count, ok := f.rows[table]
+	if !ok {
+		return 0, fmt.Errorf("relation %s does not exist", table)
+	}
+	return count, nil
+}
+
+func (f *fakeDB) QueryValue(ctx context.Context, database, query string) (string, error) {
+	value, ok := f.values[query]
+	if !ok {
+		return "", fmt.Errorf("syntax error")
+	}
+	return value, nil
+}
+
+func TestRunChecks(t *testing.T) {
+	db := &fakeDB{
+		tables: []string{"public.customers", "public.orders"},
+		rows:   map[string]int64{"public.customers": 5120, "public.orders": 120430},
+		values: map[string]string{"SELECT 0": "0", "SELECT true": "true", "SELECT NULL": "NULL"},
+	}
+	checks := &Checks{
+		RowCounts: map[string]int64{"public.orders": 120431, "public.customers": 5120, "public.invoices": 10},
+		Assertions: []Assertion{
+			{Name: "no orphans", SQL: "SELECT 0", Expect: "0"},
+			{SQL: "SELECT true"},
+			{SQL: "SELECT NULL"},
+			{SQL: "SELEC 1"},
+		},
+	}
+
+	results, tables := runChecks(context.Background(), db, "drill_shop", checks)
+	if tables != 2 {
+		t.Errorf("Expected 2 tables, got %d", tables)
+	}
+
+	want := []struct {
+		name   string
+		passed bool
+	}{
+		{"tables restored", true},
+		{"row count public.customers", true},
+		{"row count public.invoices", false},
+		{"row count public.orders", false},
+		{"no orphans", true},
+		{"assertion 2", true},
+		{"assertion 3", false},
+		{"assertion 4", false},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %+v", len(want), results)
+	}
+	for i, w := range want {
+		if results[i].Name != w.name || results[i].Passed != w.passed {
+			t.Errorf("Result %d: got %+v, want %s passed=%v", i, results[i], w.name, w.passed)
+		}
+	}
+	if results[3].Expected != "120431" || results[3].Actual != "120430" {
+		t.Errorf("Unexpected row count result: %+v", results[3])
+	}
+	if results[2].Error == "" || results[7].Error == "" {
+		t.Error("Expected errors to be recorded")
+	}
+
+	// An empty restore fails even without checks
+	results, _ = runChecks(context.Background(), &fakeDB{}, "drill_shop", &Checks{})
+	if len(results) != 1 || results[0].Passed {
+		t.Errorf("Expected an empty database to fail, got %+v", results)
+	}
+}
+
+// fakeSchema returns a fixed schema
+type fakeSchema struct {
+	schema *database.SchemaInfo
+}
+
+func (f *fakeSchema) GetSchemaInfo(ctx context.Context, name string) (*database.SchemaInfo, error) {
+	return f.schema, nil
+}
+
+func TestContentChecks(t *testing.T) {
+	schema := &database.SchemaInfo{
+		Tables: []database.TableInfo{
+			{Schema: "public", Name: "customers", RowCount: 5120, DDL: []string{"column id integer NOT NULL"}},
+			{Schema: "public", Name: "orders", RowCount: 120431, DDL: []string{"column id bigint NOT NULL"}},
+		},
+		Extensions: []string{"pgcrypto 1.3"},
+	}
+	meta := &metadata.BackupMetadata{
+		DatabaseVersion:   "PostgreSQL 16.4",
+		SchemaFingerprint: schema.Fingerprint(),
+		Tables:            []metadata.TableStats{{Name: "public.customers", Rows: 5120}, {Name: "public.orders", Rows: 120431}},
+		RowsExact:         true,
+	}
+
+	results := contentChecks(context.Background(), &fakeSchema{schema}, "drill_shop", meta, "PostgreSQL 16.4")
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 checks, got %+v", results)
+	}
+	for _, check := range results {
+		if !check.Passed {
+			t.Errorf("Expected matching content to pass, got %+v", check)
+		}
+	}
+
+	// The fingerprint is only compared on the server version of the backup
+	results = contentChecks(context.Background(), &fakeSchema{schema}, "drill_shop", meta, "PostgreSQL 17.0")
+	if len(results) != 3 || results[0].Name != "tables match backup" || results[2].Name != "rows match backup public.orders" {
+		t.Errorf("Expected only the table and row checks on another version, got %+v", results)
+	}
+
+	meta.Tables[1].Rows = 120000
+	meta.Tables = append(meta.Tables, metadata.TableStats{Name: "public.invoices", Rows: 300})
+	meta.SchemaFingerprint = "0123456789abcdef"
+	results = contentChecks(context.Background(), &fakeSchema{schema}, "drill_shop", meta, "PostgreSQL 16.4")
+	if len(results) != 4 || results[0].Passed || !results[1].Passed || results[2].Passed || results[3].Passed {
+		t.Fatalf("Expected missing tables, lost rows and a changed schema to fail, got %+v", results)
+	}
+	if !strings.Contains(results[0].Actual, "public.invoices") {
+		t.Errorf("Expected the missing table to be named, got %s", results[0].Actual)
+	}
+	if results[2].Expected != "120000" || results[2].Actual != "120431" {
+		t.Errorf("Unexpected row check: %+v", results[2])
+	}
+
+	// Counts not read in the dump's snapshot only report the difference
+	meta.RowsExact = false
+	results = contentChecks(context.Background(), &fakeSchema{schema}, "drill_shop", meta, "PostgreSQL 16.4")
+	if len(results) != 4 || !results[2].Passed {
+		t.Fatalf("Expected differing rows to pass without exact counts, got %+v", results)
+	}
+	if results[2].Expected != "about 120000" || results[2].Actual != "120431 (+431 since the count)" {
+		t.Errorf("Unexpected row check: %+v", results[2])
+	}
+
+	if results := contentChecks(context.Background(), &fakeSchema{schema}, "drill_shop", &metadata.BackupMetadata{}, ""); results != nil {
+		t.Errorf("Expected no checks without recorded content, got %+v", results)
+	}
+}
+
+func TestLoadChecks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checks.json")
+	data := `{"min_tables": 2, "row_counts": {"public.orders": 12}, "assertions": [{"sql": "SELECT true"}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checks, err := LoadChecks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checks.MinTables != 2 || checks.RowCounts["public.orders"] != 12 || len(checks.Assertions) != 1 {
+		t.Errorf("Unexpected checks: %+v", checks)
+	}
+
+	counts, err := ParseRowCounts([]string{"public.orders=13", "customers = 4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks.MergeRowCounts(counts)
+	if checks.RowCounts["public.orders"] != 13 || checks.RowCounts["customers"] != 4 {
+		t.Errorf("Unexpected row counts after merge: %v", checks.RowCounts)
+	}
+
+	for _, spec := range []string{"orders", "orders=", "=12", "orders=-1", "orders=many"} {
+		if _, err := ParseRowCounts([]string{spec}); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(`{"assertions": [{"name": "empty"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadChecks(path); err == nil {
+		t.Error("Expected an assertion without sql to be rejected")
+	}
+}
+
+func TestScratchName(t *testing.T) {
+	at := time.Date(2026, 10, 16, 10, 23, 0, 0, time.UTC)
+	for database, want := range map[string]string{
+		"shop":        "drill_shop_20261016_102300",
+		"Sales-EU.v2": "drill_sales_eu_v2_20261016_102300",
+		"":            "drill_backup_20261016_102300",
+	} {
+		if got := ScratchName(database, at); got != want {
+			t.Errorf("ScratchName(%q) = %s, want %s", database, got, want)
+		}
+	}
+	if got := ScratchName(strings.Repeat("warehouse", 10), at); len(got) > 63 {
+		t.Errorf("Scratch name %s is too long", got)
+	}
+}
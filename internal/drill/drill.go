@@ -0,0 +1,189 @@
+// Package drill proves that backups restore: a backup is restored into a
+// scratch database, checked and dropped again, and the outcome is recorded in
+// the backup's metadata as evidence.
+package drill
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"dbbackup/internal/config"
+	"dbbackup/internal/database"
+	"dbbackup/internal/encryption"
+	"dbbackup/internal/logger"
+	"dbbackup/internal/metadata"
+	"dbbackup/internal/restore"
+)
+
+// dropTimeout bounds dropping the scratch database, which also runs after an interruption
+const dropTimeout = 2 * time.Minute
+
+// Options configures a restore drill
+type Options struct {
+	BackupFile string
+	Checks     Checks
+
+	// InstanceBin is a PostgreSQL or MySQL binary directory to start a local
+	// server from. The configured server is used when it is empty.
+	InstanceBin string
+
+	Keep       bool // Leave the scratch database (or the instance's data directory) behind
+	Record     bool // Append the outcome to the backup's metadata
+	Encryption *encryption.EncryptionOptions
+}
+
+// Run restores a backup into a scratch database, runs the checks against it
+// and drops it again. The outcome is returned and, with Record, appended to
+// the backup's metadata; failed drills are recorded too. An error is returned
+// when the drill failed.
+func Run(ctx context.Context, cfg *config.Config, log logger.Logger, opts Options) (*metadata.DrillRecord, error) {
+	format := restore.DetectArchiveFormat(opts.BackupFile)
+	switch {
+	case format == restore.FormatUnknown:
+		return nil, fmt.Errorf("unknown archive format: %s", opts.BackupFile)
+	case format.IsClusterBackup():
+		return nil, fmt.Errorf("%s is a cluster archive: drills restore single database backups", opts.BackupFile)
+	case opts.InstanceBin == "" && format.IsMySQL() != cfg.IsMySQL():
+		return nil, fmt.Errorf("%s is a %s backup but the configured database type is %s", opts.BackupFile, format, cfg.DatabaseType)
+	}
+
+	meta, err := metadata.Load(opts.BackupFile)
+	if err != nil {
+		log.Warn("Backup has no metadata, the drill won't be recorded", "backup", opts.BackupFile, "error", err)
+		meta = nil
+	}
+
+	start := time.Now()
+	record := &metadata.DrillRecord{Timestamp: start}
+	err = run(ctx, cfg, log, opts, format, meta, record)
+	record.Duration = time.Since(start).Seconds()
+	record.Success = err == nil
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if meta != nil && opts.Record {
+		meta.Drills = append(meta.Drills, *record)
+		if saveErr := metadata.Save(opts.BackupFile+".meta.json", meta); saveErr != nil {
+			log.Warn("Failed to record drill in backup metadata", "error", saveErr)
+		}
+	}
+	return record, err
+}
+
+func run(ctx context.Context, cfg *config.Config, log logger.Logger, opts Options, format restore.ArchiveFormat, meta *metadata.BackupMetadata, record *metadata.DrillRecord) error {
+	source := ""
+	if meta != nil {
+		source = meta.Database
+	}
+	target := ScratchName(source, record.Timestamp)
+
+	drillCfg := *cfg
+	if opts.InstanceBin != "" {
+		inst, err := startInstance(ctx, opts.InstanceBin, format.IsMySQL(), log)
+		if err != nil {
+			return err
+		}
+		defer inst.stop(opts.Keep)
+		if err := inst.configure(&drillCfg); err != nil {
+			return err
+		}
+		record.Target = fmt.Sprintf("%s on %s", target, inst)
+	} else {
+		record.Target = fmt.Sprintf("%s on %s:%d", target, cfg.Host, cfg.Port)
+	}
+
+	db, err := database.New(&drillCfg, log)
+	if err != nil {
+		return fmt.Errorf("failed to create database instance: %w", err)
+	}
+	defer db.Close()
+	if err := db.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", record.Target, err)
+	}
+	if version, err := db.GetVersion(ctx); err == nil {
+		record.ServerVersion = version
+	}
+
+	exists, err := db.DatabaseExists(ctx, target)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("scratch database %s already exists", target)
+	}
+
+	// The scratch database is created before the restore, so it is dropped
+	// even when the restore fails halfway
+	if drillCfg.IsMySQL() {
+		if err := db.CreateDatabase(ctx, target); err != nil {
+			return err
+		}
+	}
+	if !opts.Keep && opts.InstanceBin == "" {
+		defer func() {
+			dropCtx, cancel := context.WithTimeout(context.Background(), dropTimeout)
+			defer cancel()
+			if err := db.DropDatabase(dropCtx, target); err != nil {
+				log.Warn("Failed to drop scratch database", "database", target, "error", err)
+			}
+		}()
+	}
+
+	engine := restore.New(&drillCfg, log, db)
+	engine.SetEncryption(opts.Encryption)
+
+	log.Info("Restoring backup into scratch database", "backup", opts.BackupFile, "target", record.Target)
+	restoreStart := time.Now()
+	err = engine.RestoreSingle(ctx, opts.BackupFile, target, false, drillCfg.IsPostgreSQL())
+	record.RestoreDuration = time.Since(restoreStart).Seconds()
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+
+	// Checks run on a connection to the scratch database itself
+	checkCfg := drillCfg
+	checkCfg.Database = target
+	checkDB, err := database.New(&checkCfg, log)
+	if err != nil {
+		return fmt.Errorf("failed to create database instance: %w", err)
+	}
+	defer checkDB.Close()
+	if err := checkDB.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to scratch database: %w", err)
+	}
+
+	checkStart := time.Now()
+	record.Checks, record.Tables = runChecks(ctx, checkDB, target, &opts.Checks)
+	record.Checks = append(record.Checks, contentChecks(ctx, checkDB, target, meta, record.ServerVersion)...)
+	record.CheckDuration = time.Since(checkStart).Seconds()
+
+	var failed []string
+	for _, check := range record.Checks {
+		if !check.Passed {
+			failed = append(failed, check.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d checks failed: %s", len(failed), len(record.Checks), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// ScratchName returns the name of the database a drill restores into, e.g.
+// drill_shop_20261016_102300
+func ScratchName(database string, at time.Time) string {
+	name := strings.Trim(unsafeNameChars.ReplaceAllString(strings.ToLower(database), "_"), "_")
+	if name == "" {
+		name = "backup"
+	}
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	return "drill_" + name + "_" + at.Format("20060102_150405")
+}
@@ -0,0 +1,269 @@
+package drill
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"dbbackup/internal/config"
+	"dbbackup/internal/logger"
+)
+
+// instanceStartTimeout bounds how long a local server may take to accept connections
+const instanceStartTimeout = 2 * time.Minute
+
+// instance is a throwaway PostgreSQL or MySQL server started from a binary
+// directory, listening on 127.0.0.1 on a free port, with its data in a
+// temporary directory
+type instance struct {
+	mysql  bool
+	binDir string
+	dir    string // Temporary directory holding data, socket and server log
+	port   int
+	user   string
+	server *exec.Cmd     // mysqld; PostgreSQL is managed with pg_ctl
+	exited chan struct{} // Closed once mysqld has exited
+	log    logger.Logger
+
+	savedEnv map[string]*string // Values before setEnv; nil for unset variables
+}
+
+// startInstance initializes and starts a local server from binDir
+func startInstance(ctx context.Context, binDir string, mysql bool, log logger.Logger) (*instance, error) {
+	dir, err := os.MkdirTemp("", "dbbackup-drill-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instance directory: %w", err)
+	}
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	inst := &instance{mysql: mysql, binDir: binDir, dir: dir, port: port, log: log}
+	if mysql {
+		inst.user = "root"
+		err = inst.startMySQL(ctx)
+	} else {
+		inst.user = config.GetCurrentOSUser()
+		err = inst.startPostgreSQL(ctx)
+	}
+	if err != nil {
+		inst.stop(false)
+		return nil, err
+	}
+
+	// The restore runs the client tools of the same installation, and psql
+	// calls without -h find the instance's socket
+	inst.setEnv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if !mysql {
+		inst.setEnv("PGHOST", dir)
+	}
+
+	log.Info("Started local instance for drill", "bin", binDir, "port", port, "dir", dir)
+	return inst, nil
+}
+
+// configure points cfg at the instance
+func (i *instance) configure(cfg *config.Config) error {
+	dbType := "postgres"
+	if i.mysql {
+		dbType = "mysql"
+	}
+	if err := cfg.SetDatabaseType(dbType); err != nil {
+		return err
+	}
+	cfg.Host = "127.0.0.1"
+	cfg.Port = i.port
+	cfg.User = i.user
+	cfg.Password = ""
+	cfg.Database = ""
+	if !i.mysql {
+		cfg.Database = "postgres"
+	}
+	return nil
+}
+
+// String describes the instance for the drill record
+func (i *instance) String() string {
+	kind := "PostgreSQL"
+	if i.mysql {
+		kind = "MySQL"
+	}
+	return fmt.Sprintf("local %s instance from %s on port %d", kind, i.binDir, i.port)
+}
+
+func (i *instance) startPostgreSQL(ctx context.Context) error {
+	dataDir := filepath.Join(i.dir, "data")
+	initdb := exec.CommandContext(ctx, filepath.Join(i.binDir, "initdb"),
+		"-D", dataDir, "-U", i.user, "--auth=trust", "--encoding=UTF8", "--no-sync")
+	if output, err := initdb.CombinedOutput(); err != nil {
+		return fmt.Errorf("initdb failed: %w\nOutput: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	// Durability doesn't matter for a scratch server
+	options := fmt.Sprintf("-p %d -c listen_addresses=127.0.0.1 -c unix_socket_directories='%s' -c fsync=off -c full_page_writes=off",
+		i.port, i.dir)
+	start := exec.CommandContext(ctx, filepath.Join(i.binDir, "pg_ctl"),
+		"-D", dataDir, "-l", filepath.Join(i.dir, "server.log"), "-o", options,
+		"-w", "-t", strconv.Itoa(int(instanceStartTimeout.Seconds())), "start")
+	if output, err := start.CombinedOutput(); err != nil {
+		return fmt.Errorf("pg_ctl start failed: %w\nOutput: %s\nSee %s", err,
+			strings.TrimSpace(string(output)), filepath.Join(i.dir, "server.log"))
+	}
+	return nil
+}
+
+func (i *instance) startMySQL(ctx context.Context) error {
+	dataDir := filepath.Join(i.dir, "data")
+	mysqld := filepath.Join(i.binDir, "mysqld")
+
+	var initialize *exec.Cmd
+	if installDB := filepath.Join(i.binDir, "mariadb-install-db"); fileExists(installDB) {
+		initialize = exec.CommandContext(ctx, installDB, "--no-defaults", "--datadir="+dataDir,
+			"--auth-root-authentication-method=normal", "--skip-test-db")
+	} else {
+		initialize = exec.CommandContext(ctx, mysqld, "--no-defaults", "--initialize-insecure", "--datadir="+dataDir)
+	}
+	if os.Geteuid() == 0 {
+		initialize.Args = append(initialize.Args, "--user=root")
+	}
+	if output, err := initialize.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to initialize MySQL data directory: %w\nOutput: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	args := []string{
+		"--no-defaults",
+		"--datadir=" + dataDir,
+		"--port=" + strconv.Itoa(i.port),
+		"--bind-address=127.0.0.1",
+		"--socket=" + filepath.Join(i.dir, "mysqld.sock"),
+		"--pid-file=" + filepath.Join(i.dir, "mysqld.pid"),
+		"--log-error=" + filepath.Join(i.dir, "server.log"),
+	}
+	if os.Geteuid() == 0 {
+		args = append(args, "--user=root")
+	}
+	// Not bound to ctx: the server is stopped gracefully by stop
+	i.server = exec.Command(mysqld, args...)
+	if err := i.server.Start(); err != nil {
+		return fmt.Errorf("failed to start mysqld: %w", err)
+	}
+
+	i.exited = make(chan struct{})
+	var exitErr error
+	go func() {
+		exitErr = i.server.Wait()
+		close(i.exited)
+	}()
+
+	deadline := time.Now().Add(instanceStartTimeout)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(i.port)), time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-i.exited:
+			return fmt.Errorf("mysqld exited during startup: %v (see %s)", exitErr, filepath.Join(i.dir, "server.log"))
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf("mysqld did not accept connections within %s (see %s)", instanceStartTimeout, filepath.Join(i.dir, "server.log"))
+}
+
+// stop shuts the server down and, unless keepData is set, removes its
+// directory. The environment is restored either way.
+func (i *instance) stop(keepData bool) error {
+	for key, value := range i.savedEnv {
+		if value == nil {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, *value)
+		}
+	}
+	i.savedEnv = nil
+
+	var err error
+	if i.mysql {
+		err = i.stopMySQL()
+	} else if fileExists(filepath.Join(i.dir, "data", "postmaster.pid")) {
+		stop := exec.Command(filepath.Join(i.binDir, "pg_ctl"), "-D", filepath.Join(i.dir, "data"), "-m", "fast", "-w", "stop")
+		if output, stopErr := stop.CombinedOutput(); stopErr != nil {
+			err = fmt.Errorf("pg_ctl stop failed: %w\nOutput: %s", stopErr, strings.TrimSpace(string(output)))
+		}
+	}
+	if err != nil {
+		i.log.Warn("Failed to stop local instance", "dir", i.dir, "error", err)
+		return err
+	}
+
+	if keepData {
+		return nil
+	}
+	return os.RemoveAll(i.dir)
+}
+
+// stopMySQL asks mysqld to shut down and kills it if it doesn't
+func (i *instance) stopMySQL() error {
+	if i.server == nil || i.server.Process == nil {
+		return nil
+	}
+	select {
+	case <-i.exited:
+		return nil
+	default:
+	}
+
+	if err := i.server.Process.Signal(syscall.SIGTERM); err != nil {
+		i.server.Process.Kill()
+	}
+	select {
+	case <-i.exited:
+		return nil
+	case <-time.After(time.Minute):
+		i.server.Process.Kill()
+		<-i.exited
+		return fmt.Errorf("mysqld did not shut down within a minute and was killed")
+	}
+}
+
+// setEnv sets an environment variable until the instance is stopped
+func (i *instance) setEnv(key, value string) {
+	if i.savedEnv == nil {
+		i.savedEnv = make(map[string]*string)
+	}
+	if _, saved := i.savedEnv[key]; !saved {
+		if previous, ok := os.LookupEnv(key); ok {
+			i.savedEnv[key] = &previous
+		} else {
+			i.savedEnv[key] = nil
+		}
+	}
+	os.Setenv(key, value)
+}
+
+// freePort returns a TCP port on 127.0.0.1 that nothing listens on
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
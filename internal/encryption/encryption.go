@@ -1,10 +1,13 @@
 package encryption
 
 import (
+	"bufio"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hkdf"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 	"io"
 
@@ -14,38 +17,72 @@ import (
 const (
 	// AES-256 requires 32-byte keys
 	KeySize = 32
-	
+
 	// Nonce size for GCM
 	NonceSize = 12
-	
+
 	// Salt size for key derivation
 	SaltSize = 32
-	
-	// PBKDF2 iterations (100,000 is recommended minimum)
-	PBKDF2Iterations = 100000
-	
-	// Magic header to identify encrypted files
-	EncryptedFileMagic = "DBBACKUP_ENCRYPTED_V1"
+
+	// PBKDF2 iterations for passphrases (OWASP recommended minimum)
+	PBKDF2Iterations = 600000
+
+	// ChunkSize is the plaintext size of every chunk but the last
+	ChunkSize = 64 * 1024
+
+	// ContainerMagic identifies files in the encrypted container format
+	ContainerMagic = "DBBACKUP-ENC"
+
+	// ContainerVersion is the container format version written by EncryptionWriter
+	ContainerVersion = 2
+
+	// Algorithm is recorded in backup metadata for files written by EncryptionWriter
+	Algorithm = "aes-256-gcm-chunked"
+)
+
+// Cipher and key derivation identifiers stored in the container header
+const (
+	CipherAES256GCM uint8 = 1
+
+	// KDFHKDF derives the file key from a 32-byte key with HKDF-SHA256
+	KDFHKDF uint8 = 1
+
+	// KDFPBKDF2 derives the file key from a passphrase with PBKDF2-SHA256
+	KDFPBKDF2 uint8 = 2
+)
+
+const (
+	headerSize       = 64
+	noncePrefixSize  = 7
+	finalChunkFlag   = 1 << 31
+	hkdfInfo         = "dbbackup-enc v2 file key"
+	maxChunkOverhead = 16 // GCM tag
 )
 
-// EncryptionHeader stores metadata for encrypted files
+// EncryptionHeader is the header of the encrypted container format:
+//
+//	magic "DBBACKUP-ENC" | version | cipher | kdf | reserved |
+//	chunk size (uint32) | kdf iterations (uint32) | salt (32) | nonce prefix (7) | reserved
+//
+// The 64 header bytes are authenticated with every chunk.
 type EncryptionHeader struct {
-	Magic      [22]byte // "DBBACKUP_ENCRYPTED_V1" (21 bytes + null)
-	Version    uint8    // Version number (1)
-	Algorithm  uint8    // Algorithm ID (1 = AES-256-GCM)
-	Salt       [32]byte // Salt for key derivation
-	Nonce      [12]byte // GCM nonce
-	Reserved   [32]byte // Reserved for future use
+	Version     uint8
+	Cipher      uint8
+	KDF         uint8
+	ChunkSize   uint32
+	Iterations  uint32
+	Salt        [SaltSize]byte
+	NoncePrefix [noncePrefixSize]byte
 }
 
 // EncryptionOptions configures encryption behavior
 type EncryptionOptions struct {
 	// Key is the encryption key (32 bytes for AES-256)
 	Key []byte
-	
+
 	// Passphrase for key derivation (alternative to direct key)
 	Passphrase string
-	
+
 	// Salt for key derivation (if empty, will be generated)
 	Salt []byte
 }
@@ -73,86 +110,70 @@ func GenerateKey() ([]byte, error) {
 	return key, nil
 }
 
-// NewEncryptionWriter creates an encrypted writer that wraps an underlying writer
-// Data written to this writer will be encrypted before being written to the underlying writer
+// NewEncryptionWriter creates an encrypted writer that wraps an underlying writer.
+// Data is written in the container format: a header with a random per-file salt,
+// then AES-256-GCM chunks bound to the header, their position and (for the last
+// one) the end of the stream, so reordered or truncated files fail to decrypt.
 func NewEncryptionWriter(w io.Writer, opts EncryptionOptions) (*EncryptionWriter, error) {
-	// Derive or validate key
-	var key []byte
-	var salt []byte
-	
-	if opts.Passphrase != "" {
-		// Derive key from passphrase
-		if len(opts.Salt) == 0 {
-			var err error
-			salt, err = GenerateSalt()
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			salt = opts.Salt
-		}
-		key = DeriveKey(opts.Passphrase, salt)
-	} else if len(opts.Key) > 0 {
-		if len(opts.Key) != KeySize {
-			return nil, fmt.Errorf("invalid key size: expected %d bytes, got %d", KeySize, len(opts.Key))
-		}
-		key = opts.Key
-		// Generate salt even when using direct key (for header)
+	header := EncryptionHeader{
+		Version:   ContainerVersion,
+		Cipher:    CipherAES256GCM,
+		ChunkSize: ChunkSize,
+	}
+
+	salt := opts.Salt
+	if len(salt) == 0 {
 		var err error
-		salt, err = GenerateSalt()
-		if err != nil {
+		if salt, err = GenerateSalt(); err != nil {
 			return nil, err
 		}
-	} else {
-		return nil, fmt.Errorf("either Key or Passphrase must be provided")
-	}
-	
-	// Create AES cipher
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	} else if len(salt) != SaltSize {
+		return nil, fmt.Errorf("invalid salt size: expected %d bytes, got %d", SaltSize, len(salt))
 	}
-	
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	copy(header.Salt[:], salt)
+
+	switch {
+	case opts.Passphrase != "":
+		header.KDF = KDFPBKDF2
+		header.Iterations = PBKDF2Iterations
+	case len(opts.Key) > 0:
+		header.KDF = KDFHKDF
+	default:
+		return nil, fmt.Errorf("either Key or Passphrase must be provided")
 	}
-	
-	// Generate nonce
-	nonce := make([]byte, NonceSize)
-	if _, err := rand.Read(nonce); err != nil {
+
+	if _, err := rand.Read(header.NoncePrefix[:]); err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
-	
-	// Write header
-	header := EncryptionHeader{
-		Version:   1,
-		Algorithm: 1, // AES-256-GCM
+
+	gcm, err := header.cipher(opts)
+	if err != nil {
+		return nil, err
 	}
-	copy(header.Magic[:], []byte(EncryptedFileMagic))
-	copy(header.Salt[:], salt)
-	copy(header.Nonce[:], nonce)
-	
-	if err := writeHeader(w, &header); err != nil {
+
+	raw := header.marshal()
+	if _, err := w.Write(raw); err != nil {
 		return nil, fmt.Errorf("failed to write header: %w", err)
 	}
-	
+
 	return &EncryptionWriter{
 		writer: w,
 		gcm:    gcm,
-		nonce:  nonce,
-		buffer: make([]byte, 0, 64*1024), // 64KB buffer
+		header: raw,
+		prefix: header.NoncePrefix,
+		buffer: make([]byte, 0, 2*ChunkSize),
 	}, nil
 }
 
 // EncryptionWriter encrypts data written to it
 type EncryptionWriter struct {
-	writer io.Writer
-	gcm    cipher.AEAD
-	nonce  []byte
-	buffer []byte
-	closed bool
+	writer  io.Writer
+	gcm     cipher.AEAD
+	header  []byte
+	prefix  [noncePrefixSize]byte
+	counter uint32
+	buffer  []byte
+	closed  bool
 }
 
 // Write encrypts and writes data
@@ -160,239 +181,301 @@ func (ew *EncryptionWriter) Write(p []byte) (n int, err error) {
 	if ew.closed {
 		return 0, fmt.Errorf("writer is closed")
 	}
-	
-	// Accumulate data in buffer
+
 	ew.buffer = append(ew.buffer, p...)
-	
-	// If buffer is large enough, encrypt and write
-	const chunkSize = 64 * 1024 // 64KB chunks
-	for len(ew.buffer) >= chunkSize {
-		chunk := ew.buffer[:chunkSize]
-		encrypted := ew.gcm.Seal(nil, ew.nonce, chunk, nil)
-		
-		// Write encrypted chunk size (4 bytes) then chunk
-		size := uint32(len(encrypted))
-		sizeBytes := []byte{
-			byte(size >> 24),
-			byte(size >> 16),
-			byte(size >> 8),
-			byte(size),
-		}
-		if _, err := ew.writer.Write(sizeBytes); err != nil {
-			return n, err
-		}
-		if _, err := ew.writer.Write(encrypted); err != nil {
-			return n, err
+
+	// Keep at least one byte back, so the last chunk is only sealed on Close
+	for len(ew.buffer) > ChunkSize {
+		if err := ew.writeChunk(ew.buffer[:ChunkSize], false); err != nil {
+			return 0, err
 		}
-		
-		// Move remaining data to start of buffer
-		ew.buffer = ew.buffer[chunkSize:]
-		n += chunkSize
-		
-		// Increment nonce for next chunk
-		incrementNonce(ew.nonce)
-	}
-	
+		ew.buffer = ew.buffer[ChunkSize:]
+	}
+
 	return len(p), nil
 }
 
-// Close flushes remaining data and finalizes encryption
+// Close seals the final chunk. It does not close the underlying writer.
 func (ew *EncryptionWriter) Close() error {
 	if ew.closed {
 		return nil
 	}
 	ew.closed = true
-	
-	// Encrypt and write remaining buffer
-	if len(ew.buffer) > 0 {
-		encrypted := ew.gcm.Seal(nil, ew.nonce, ew.buffer, nil)
-		
-		size := uint32(len(encrypted))
-		sizeBytes := []byte{
-			byte(size >> 24),
-			byte(size >> 16),
-			byte(size >> 8),
-			byte(size),
-		}
-		if _, err := ew.writer.Write(sizeBytes); err != nil {
-			return err
-		}
-		if _, err := ew.writer.Write(encrypted); err != nil {
-			return err
-		}
+	return ew.writeChunk(ew.buffer, true)
+}
+
+func (ew *EncryptionWriter) writeChunk(plaintext []byte, final bool) error {
+	nonce := chunkNonce(ew.prefix, ew.counter, final)
+	sealed := ew.gcm.Seal(nil, nonce, plaintext, ew.header)
+
+	frame := uint32(len(sealed))
+	if final {
+		frame |= finalChunkFlag
 	}
-	
-	// Write final zero-length chunk to signal end
-	if _, err := ew.writer.Write([]byte{0, 0, 0, 0}); err != nil {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], frame)
+	if _, err := ew.writer.Write(size[:]); err != nil {
 		return err
 	}
-	
+	if _, err := ew.writer.Write(sealed); err != nil {
+		return err
+	}
+
+	ew.counter++
+	if ew.counter == 0 {
+		return fmt.Errorf("encrypted stream too large: chunk counter exhausted")
+	}
 	return nil
 }
 
-// NewDecryptionReader creates a decrypted reader from an encrypted stream
+// NewDecryptionReader creates a decrypted reader from an encrypted stream. The
+// format is detected from the data: the container format and the legacy V1
+// stream and WAL formats by their header, anything else is read as the legacy
+// headerless format of crypto.AESEncryptor (which needs a 32-byte Key).
 func NewDecryptionReader(r io.Reader, opts EncryptionOptions) (*DecryptionReader, error) {
-	// Read and parse header
-	header, err := readHeader(r)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read header: %w", err)
+	if opts.Passphrase == "" && len(opts.Key) == 0 {
+		return nil, fmt.Errorf("either Key or Passphrase must be provided")
 	}
-	
-	// Verify magic
-	if string(header.Magic[:len(EncryptedFileMagic)]) != EncryptedFileMagic {
-		return nil, fmt.Errorf("not an encrypted backup file")
+	if len(opts.Key) > 0 && len(opts.Key) != KeySize {
+		return nil, fmt.Errorf("invalid key size: expected %d bytes, got %d", KeySize, len(opts.Key))
 	}
-	
-	// Verify version
-	if header.Version != 1 {
-		return nil, fmt.Errorf("unsupported encryption version: %d", header.Version)
+
+	br := bufio.NewReader(r)
+	peek, _ := br.Peek(maxMagicSize)
+	format := DetectFormat(peek)
+
+	var (
+		reader io.Reader
+		err    error
+	)
+	switch format {
+	case FormatContainer:
+		reader, err = newContainerReader(br, opts)
+	case FormatLegacyStream:
+		reader, err = newLegacyStreamReader(br, opts)
+	case FormatLegacyWAL:
+		reader, err = newLegacyWALReader(br, opts)
+	default:
+		format = FormatLegacyAES
+		reader, err = newLegacyAESReader(br, opts)
 	}
-	
-	// Verify algorithm
-	if header.Algorithm != 1 {
-		return nil, fmt.Errorf("unsupported encryption algorithm: %d", header.Algorithm)
+	if err != nil {
+		return nil, err
 	}
-	
-	// Derive or validate key
-	var key []byte
-	if opts.Passphrase != "" {
-		key = DeriveKey(opts.Passphrase, header.Salt[:])
-	} else if len(opts.Key) > 0 {
-		if len(opts.Key) != KeySize {
-			return nil, fmt.Errorf("invalid key size: expected %d bytes, got %d", KeySize, len(opts.Key))
-		}
-		key = opts.Key
-	} else {
-		return nil, fmt.Errorf("either Key or Passphrase must be provided")
+
+	return &DecryptionReader{reader: reader, format: format}, nil
+}
+
+// DecryptionReader decrypts data from an encrypted stream
+type DecryptionReader struct {
+	reader io.Reader
+	format Format
+}
+
+// Read decrypts and returns data
+func (dr *DecryptionReader) Read(p []byte) (int, error) {
+	return dr.reader.Read(p)
+}
+
+// Format returns the format of the stream being decrypted
+func (dr *DecryptionReader) Format() Format {
+	return dr.format
+}
+
+// containerReader decrypts the container format
+type containerReader struct {
+	reader    io.Reader
+	gcm       cipher.AEAD
+	header    []byte
+	prefix    [noncePrefixSize]byte
+	counter   uint32
+	maxSealed uint32
+	buffer    []byte
+	eof       bool
+}
+
+func newContainerReader(r io.Reader, opts EncryptionOptions) (*containerReader, error) {
+	raw := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
 	}
-	
-	// Create AES cipher
-	block, err := aes.NewCipher(key)
+	header, err := unmarshalHeader(raw)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
+		return nil, err
 	}
-	
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
+
+	gcm, err := header.cipher(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
+		return nil, err
 	}
-	
-	nonce := make([]byte, NonceSize)
-	copy(nonce, header.Nonce[:])
-	
-	return &DecryptionReader{
-		reader: r,
-		gcm:    gcm,
-		nonce:  nonce,
-		buffer: make([]byte, 0),
+
+	return &containerReader{
+		reader:    r,
+		gcm:       gcm,
+		header:    raw,
+		prefix:    header.NoncePrefix,
+		maxSealed: header.ChunkSize + maxChunkOverhead,
 	}, nil
 }
 
-// DecryptionReader decrypts data from an encrypted stream
-type DecryptionReader struct {
-	reader io.Reader
-	gcm    cipher.AEAD
-	nonce  []byte
-	buffer []byte
-	eof    bool
+func (cr *containerReader) Read(p []byte) (int, error) {
+	for len(cr.buffer) == 0 {
+		if cr.eof {
+			return 0, io.EOF
+		}
+		if err := cr.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, cr.buffer)
+	cr.buffer = cr.buffer[n:]
+	return n, nil
 }
 
-// Read decrypts and returns data
-func (dr *DecryptionReader) Read(p []byte) (n int, err error) {
-	// If we have buffered data, return it first
-	if len(dr.buffer) > 0 {
-		n = copy(p, dr.buffer)
-		dr.buffer = dr.buffer[n:]
-		return n, nil
-	}
-	
-	// If EOF reached, return EOF
-	if dr.eof {
-		return 0, io.EOF
-	}
-	
-	// Read next chunk size
-	sizeBytes := make([]byte, 4)
-	if _, err := io.ReadFull(dr.reader, sizeBytes); err != nil {
+func (cr *containerReader) readChunk() error {
+	// The stream must end with a chunk flagged as final, so running out of
+	// data before it means the file was truncated
+	var size [4]byte
+	if _, err := io.ReadFull(cr.reader, size[:]); err != nil {
 		if err == io.EOF {
-			dr.eof = true
-			return 0, io.EOF
+			return fmt.Errorf("encrypted stream is truncated: %w", io.ErrUnexpectedEOF)
+		}
+		return err
+	}
+	frame := binary.BigEndian.Uint32(size[:])
+	final := frame&finalChunkFlag != 0
+	length := frame &^ finalChunkFlag
+	if length > cr.maxSealed {
+		return fmt.Errorf("corrupted encrypted stream: chunk of %d bytes exceeds chunk size", length)
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(cr.reader, sealed); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
 		}
-		return 0, err
-	}
-	
-	size := uint32(sizeBytes[0])<<24 | uint32(sizeBytes[1])<<16 | uint32(sizeBytes[2])<<8 | uint32(sizeBytes[3])
-	
-	// Zero-length chunk signals end of stream
-	if size == 0 {
-		dr.eof = true
-		return 0, io.EOF
-	}
-	
-	// Read encrypted chunk
-	encrypted := make([]byte, size)
-	if _, err := io.ReadFull(dr.reader, encrypted); err != nil {
-		return 0, err
-	}
-	
-	// Decrypt chunk
-	decrypted, err := dr.gcm.Open(nil, dr.nonce, encrypted, nil)
+		return fmt.Errorf("encrypted stream is truncated: %w", err)
+	}
+
+	plaintext, err := cr.gcm.Open(nil, chunkNonce(cr.prefix, cr.counter, final), sealed, cr.header)
 	if err != nil {
-		return 0, fmt.Errorf("decryption failed (wrong key?): %w", err)
-	}
-	
-	// Increment nonce for next chunk
-	incrementNonce(dr.nonce)
-	
-	// Return as much as fits in p, buffer the rest
-	n = copy(p, decrypted)
-	if n < len(decrypted) {
-		dr.buffer = decrypted[n:]
-	}
-	
-	return n, nil
+		return fmt.Errorf("decryption failed (wrong key?): %w", err)
+	}
+	cr.counter++
+
+	if final {
+		cr.eof = true
+		var extra [1]byte
+		if n, _ := cr.reader.Read(extra[:]); n > 0 {
+			return fmt.Errorf("corrupted encrypted stream: data after final chunk")
+		}
+	}
+	cr.buffer = plaintext
+	return nil
 }
 
-// Helper functions
+// chunkNonce is the nonce prefix, the big-endian chunk counter and the final flag
+func chunkNonce(prefix [noncePrefixSize]byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, NonceSize)
+	copy(nonce, prefix[:])
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	if final {
+		nonce[NonceSize-1] = 1
+	}
+	return nonce
+}
+
+// cipher derives the file key described by the header and returns its AEAD
+func (h *EncryptionHeader) cipher(opts EncryptionOptions) (cipher.AEAD, error) {
+	var key []byte
+	switch h.KDF {
+	case KDFPBKDF2:
+		if opts.Passphrase == "" {
+			return nil, fmt.Errorf("file is encrypted with a passphrase, but a key was given")
+		}
+		key = pbkdf2.Key([]byte(opts.Passphrase), h.Salt[:], int(h.Iterations), KeySize, sha256.New)
+	case KDFHKDF:
+		if len(opts.Key) != KeySize {
+			return nil, fmt.Errorf("file is encrypted with a %d-byte key, but a passphrase was given", KeySize)
+		}
+		var err error
+		key, err = hkdf.Key(sha256.New, opts.Key, h.Salt[:], hkdfInfo, KeySize)
+		if err != nil {
+			return nil, fmt.Errorf("key derivation failed: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key derivation: %d", h.KDF)
+	}
+	return newGCM(key)
+}
 
-func writeHeader(w io.Writer, h *EncryptionHeader) error {
-	data := make([]byte, 100) // Total header size
-	copy(data[0:22], h.Magic[:])
-	data[22] = h.Version
-	data[23] = h.Algorithm
+func (h *EncryptionHeader) marshal() []byte {
+	data := make([]byte, headerSize)
+	copy(data[0:12], ContainerMagic)
+	data[12] = h.Version
+	data[13] = h.Cipher
+	data[14] = h.KDF
+	binary.BigEndian.PutUint32(data[16:20], h.ChunkSize)
+	binary.BigEndian.PutUint32(data[20:24], h.Iterations)
 	copy(data[24:56], h.Salt[:])
-	copy(data[56:68], h.Nonce[:])
-	copy(data[68:100], h.Reserved[:])
-	
-	_, err := w.Write(data)
-	return err
+	copy(data[56:63], h.NoncePrefix[:])
+	return data
 }
 
-func readHeader(r io.Reader) (*EncryptionHeader, error) {
-	data := make([]byte, 100)
-	if _, err := io.ReadFull(r, data); err != nil {
-		return nil, err
+func unmarshalHeader(data []byte) (*EncryptionHeader, error) {
+	if string(data[0:12]) != ContainerMagic {
+		return nil, fmt.Errorf("not an encrypted backup file")
 	}
-	
+
 	header := &EncryptionHeader{
-		Version:   data[22],
-		Algorithm: data[23],
+		Version:    data[12],
+		Cipher:     data[13],
+		KDF:        data[14],
+		ChunkSize:  binary.BigEndian.Uint32(data[16:20]),
+		Iterations: binary.BigEndian.Uint32(data[20:24]),
 	}
-	copy(header.Magic[:], data[0:22])
 	copy(header.Salt[:], data[24:56])
-	copy(header.Nonce[:], data[56:68])
-	copy(header.Reserved[:], data[68:100])
-	
+	copy(header.NoncePrefix[:], data[56:63])
+
+	if header.Version != ContainerVersion {
+		return nil, fmt.Errorf("unsupported encryption version: %d", header.Version)
+	}
+	if header.Cipher != CipherAES256GCM {
+		return nil, fmt.Errorf("unsupported encryption algorithm: %d", header.Cipher)
+	}
+	if header.ChunkSize == 0 || header.ChunkSize > 16*1024*1024 {
+		return nil, fmt.Errorf("invalid chunk size: %d", header.ChunkSize)
+	}
+	if header.KDF == KDFPBKDF2 && (header.Iterations == 0 || header.Iterations > 100*PBKDF2Iterations) {
+		return nil, fmt.Errorf("invalid PBKDF2 iteration count: %d", header.Iterations)
+	}
 	return header, nil
 }
 
-func incrementNonce(nonce []byte) {
-	// Increment nonce as a big-endian counter
-	for i := len(nonce) - 1; i >= 0; i-- {
-		nonce[i]++
-		if nonce[i] != 0 {
-			break
-		}
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
 	}
+	return gcm, nil
+}
+
+// IsEncrypted reports whether data starts with the header of an encrypted file
+// (container format or a legacy format that has a header)
+func IsEncrypted(header []byte) bool {
+	switch DetectFormat(header) {
+	case FormatContainer, FormatLegacyStream, FormatLegacyWAL:
+		return true
+	}
+	return false
+}
+
+// IsEncryptedFile reports whether the file at path starts with an encryption header
+func IsEncryptedFile(path string) bool {
+	return DetectFileFormat(path) != FormatUnknown
 }
@@ -2,8 +2,13 @@ package encryption
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"io"
 	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
 )
 
 func TestEncryptDecrypt(t *testing.T) {
@@ -232,3 +237,178 @@ func TestKeyDerivation(t *testing.T) {
 	
 	t.Log("✅ Key derivation successful")
 }
+
+func TestTruncatedStream(t *testing.T) {
+	original := bytes.Repeat([]byte("backup data "), 200000)
+	opts := EncryptionOptions{Passphrase: "test-password"}
+
+	var encrypted bytes.Buffer
+	writer, err := NewEncryptionWriter(&encrypted, opts)
+	if err != nil {
+		t.Fatalf("Failed to create encryption writer: %v", err)
+	}
+	if _, err := writer.Write(original); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	if !IsEncrypted(encrypted.Bytes()) {
+		t.Error("Encrypted stream not detected by its header")
+	}
+	if IsEncrypted(original) {
+		t.Error("Plaintext detected as encrypted")
+	}
+
+	// Dropping the end marker cuts the stream at a chunk boundary, which
+	// must not look like a clean EOF
+	truncated := encrypted.Bytes()[:encrypted.Len()-4]
+	reader, err := NewDecryptionReader(bytes.NewReader(truncated), opts)
+	if err != nil {
+		t.Fatalf("Failed to create decryption reader: %v", err)
+	}
+	if _, err := io.ReadAll(reader); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected unexpected EOF reading truncated stream, got %v", err)
+	}
+}
+
+// sealChunks writes length-prefixed GCM chunks with an incrementing nonce, as
+// the legacy formats did
+func sealChunks(t *testing.T, w *bytes.Buffer, key, nonce, data []byte, chunk int) {
+	t.Helper()
+	gcm, err := newGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce = append([]byte(nil), nonce...)
+	for len(data) > 0 {
+		n := min(chunk, len(data))
+		sealed := gcm.Seal(nil, nonce, data[:n], nil)
+		binary.Write(w, binary.BigEndian, uint32(len(sealed)))
+		w.Write(sealed)
+		incrementNonce(nonce)
+		data = data[n:]
+	}
+}
+
+func TestLegacyFormats(t *testing.T) {
+	original := bytes.Repeat([]byte("legacy backup data "), 10000)
+	key := bytes.Repeat([]byte{3}, KeySize)
+	nonce := bytes.Repeat([]byte{9}, NonceSize)
+	salt := bytes.Repeat([]byte{5}, SaltSize)
+
+	// V1 stream: 100-byte header, chunks, zero-length end marker
+	var v1 bytes.Buffer
+	header := make([]byte, legacyStreamHeaderSize)
+	copy(header, LegacyStreamMagic)
+	header[22], header[23] = 1, 1
+	copy(header[24:56], salt)
+	copy(header[56:68], nonce)
+	v1.Write(header)
+	sealChunks(t, &v1, pbkdf2.Key([]byte("v1 passphrase"), salt, LegacyPBKDF2Iterations, KeySize, sha256.New), nonce, original, 64*1024)
+	v1.Write([]byte{0, 0, 0, 0})
+
+	// WAL: magic, nonce, whole file sealed with a fixed-salt passphrase key
+	var wal bytes.Buffer
+	wal.WriteString(LegacyWALMagic)
+	walGCM, _ := newGCM(pbkdf2.Key([]byte("wal passphrase"), []byte(legacyWALSalt), legacyWALIterations, KeySize, sha256.New))
+	wal.Write(walGCM.Seal(nonce, nonce, original, nil))
+
+	// Headerless AES: nonce and chunks up to EOF
+	var headerless bytes.Buffer
+	headerless.Write(nonce)
+	sealChunks(t, &headerless, key, nonce, original, 64*1024)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		opts   EncryptionOptions
+		format Format
+	}{
+		{"StreamV1", v1.Bytes(), EncryptionOptions{Passphrase: "v1 passphrase"}, FormatLegacyStream},
+		{"WAL", wal.Bytes(), EncryptionOptions{Passphrase: "wal passphrase"}, FormatLegacyWAL},
+		{"Headerless", headerless.Bytes(), EncryptionOptions{Key: key}, FormatLegacyAES},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewDecryptionReader(bytes.NewReader(tt.data), tt.opts)
+			if err != nil {
+				t.Fatalf("Failed to create decryption reader: %v", err)
+			}
+			if reader.Format() != tt.format {
+				t.Errorf("Expected format %s, got %s", tt.format, reader.Format())
+			}
+			decrypted, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("Failed to decrypt: %v", err)
+			}
+			if !bytes.Equal(decrypted, original) {
+				t.Error("Decrypted data doesn't match original")
+			}
+		})
+	}
+}
+
+func TestContainerTampering(t *testing.T) {
+	original := bytes.Repeat([]byte("0123456789abcdef"), 3*ChunkSize/16)
+	key, _ := GenerateKey()
+	opts := EncryptionOptions{Key: key}
+
+	var encrypted bytes.Buffer
+	writer, err := NewEncryptionWriter(&encrypted, opts)
+	if err != nil {
+		t.Fatalf("Failed to create encryption writer: %v", err)
+	}
+	writer.Write(original)
+	writer.Close()
+
+	if DetectFormat(encrypted.Bytes()) != FormatContainer {
+		t.Fatalf("Expected container format, got %s", DetectFormat(encrypted.Bytes()))
+	}
+
+	decrypt := func(data []byte) error {
+		reader, err := NewDecryptionReader(bytes.NewReader(data), opts)
+		if err != nil {
+			return err
+		}
+		_, err = io.ReadAll(reader)
+		return err
+	}
+	if err := decrypt(encrypted.Bytes()); err != nil {
+		t.Fatalf("Failed to decrypt untouched stream: %v", err)
+	}
+
+	frame := 4 + ChunkSize + maxChunkOverhead
+	data := encrypted.Bytes()
+
+	// Swapping two full chunks breaks their position binding
+	swapped := append([]byte(nil), data...)
+	first := swapped[headerSize : headerSize+frame]
+	second := append([]byte(nil), swapped[headerSize+frame:headerSize+2*frame]...)
+	copy(swapped[headerSize+frame:], first)
+	copy(swapped[headerSize:], second)
+	if err := decrypt(swapped); err == nil {
+		t.Error("Expected error decrypting reordered chunks")
+	}
+
+	// The header is authenticated with every chunk
+	header := append([]byte(nil), data...)
+	header[20]++ // KDF iterations field, unused for keys
+	if err := decrypt(header); err == nil {
+		t.Error("Expected error decrypting with a modified header")
+	}
+
+	// Dropping the final chunk must not look like a clean end of stream
+	if err := decrypt(data[:headerSize+2*frame]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected unexpected EOF without final chunk, got %v", err)
+	}
+
+	// Every file gets its own salt
+	var again bytes.Buffer
+	writer, _ = NewEncryptionWriter(&again, opts)
+	writer.Close()
+	if bytes.Equal(again.Bytes()[24:56], data[24:56]) {
+		t.Error("Two files share a salt")
+	}
+}
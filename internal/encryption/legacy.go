@@ -0,0 +1,279 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Format identifies how an encrypted file was written
+type Format int
+
+const (
+	// FormatUnknown has no known header: unencrypted, or the legacy
+	// headerless format (which cannot be told apart from plaintext)
+	FormatUnknown Format = iota
+
+	// FormatContainer is the current container format (EncryptionWriter)
+	FormatContainer
+
+	// FormatLegacyStream is the V1 chunked format ("DBBACKUP_ENCRYPTED_V1")
+	FormatLegacyStream
+
+	// FormatLegacyWAL is the whole-file WAL format ("WALENC01")
+	FormatLegacyWAL
+
+	// FormatLegacyAES is the headerless format of crypto.AESEncryptor
+	FormatLegacyAES
+)
+
+// String returns a description of the format
+func (f Format) String() string {
+	switch f {
+	case FormatContainer:
+		return fmt.Sprintf("dbbackup container v%d", ContainerVersion)
+	case FormatLegacyStream:
+		return "legacy stream v1"
+	case FormatLegacyWAL:
+		return "legacy WAL v1"
+	case FormatLegacyAES:
+		return "legacy AES (headerless)"
+	}
+	return "unknown"
+}
+
+// Legacy format constants. These files can still be decrypted, but are no
+// longer written.
+const (
+	// LegacyStreamMagic starts files of the V1 chunked format
+	LegacyStreamMagic = "DBBACKUP_ENCRYPTED_V1"
+
+	// LegacyPBKDF2Iterations is the passphrase iteration count of the V1 format
+	LegacyPBKDF2Iterations = 100000
+
+	// LegacyWALMagic starts encrypted WAL files written before the container format
+	LegacyWALMagic = "WALENC01"
+
+	// legacyWALSalt was the fixed salt for WAL passphrases
+	legacyWALSalt = "dbbackup-wal-encryption-v1"
+
+	legacyWALIterations    = 600000
+	legacyStreamHeaderSize = 100
+	legacyMaxChunk         = 16 * 1024 * 1024
+)
+
+// maxMagicSize is the number of bytes DetectFormat needs
+const maxMagicSize = len(LegacyStreamMagic)
+
+// DetectFormat returns the format of encrypted data from its first bytes
+func DetectFormat(header []byte) Format {
+	hasPrefix := func(magic string) bool {
+		return len(header) >= len(magic) && string(header[:len(magic)]) == magic
+	}
+	switch {
+	case hasPrefix(ContainerMagic):
+		return FormatContainer
+	case hasPrefix(LegacyStreamMagic):
+		return FormatLegacyStream
+	case hasPrefix(LegacyWALMagic):
+		return FormatLegacyWAL
+	}
+	return FormatUnknown
+}
+
+// DetectFileFormat returns the encryption format of the file at path
+func DetectFileFormat(path string) Format {
+	f, err := os.Open(path)
+	if err != nil {
+		return FormatUnknown
+	}
+	defer f.Close()
+
+	header := make([]byte, maxMagicSize)
+	n, _ := io.ReadFull(f, header)
+	return DetectFormat(header[:n])
+}
+
+// chunkedReader decrypts the legacy formats made of length-prefixed GCM chunks
+// with an incrementing nonce
+type chunkedReader struct {
+	reader io.Reader
+	gcm    cipher.AEAD
+	nonce  []byte
+	buffer []byte
+	eof    bool
+
+	// terminated formats end with a zero-length chunk; others end at EOF
+	terminated bool
+}
+
+func (cr *chunkedReader) Read(p []byte) (int, error) {
+	for len(cr.buffer) == 0 {
+		if cr.eof {
+			return 0, io.EOF
+		}
+		if err := cr.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, cr.buffer)
+	cr.buffer = cr.buffer[n:]
+	return n, nil
+}
+
+func (cr *chunkedReader) readChunk() error {
+	var size [4]byte
+	if _, err := io.ReadFull(cr.reader, size[:]); err != nil {
+		if err == io.EOF {
+			if !cr.terminated {
+				cr.eof = true
+				return nil
+			}
+			return fmt.Errorf("encrypted stream is truncated: %w", io.ErrUnexpectedEOF)
+		}
+		return err
+	}
+
+	length := binary.BigEndian.Uint32(size[:])
+	if length == 0 && cr.terminated {
+		cr.eof = true
+		return nil
+	}
+	if length > legacyMaxChunk {
+		return fmt.Errorf("corrupted encrypted stream: chunk of %d bytes", length)
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(cr.reader, sealed); err != nil {
+		return fmt.Errorf("encrypted stream is truncated: %w", err)
+	}
+
+	plaintext, err := cr.gcm.Open(nil, cr.nonce, sealed, nil)
+	if err != nil {
+		return fmt.Errorf("decryption failed (wrong key?): %w", err)
+	}
+	incrementNonce(cr.nonce)
+
+	cr.buffer = plaintext
+	return nil
+}
+
+// newLegacyStreamReader reads the V1 format: a 100-byte header (magic, version,
+// algorithm, salt, nonce) followed by chunks ending with a zero-length chunk
+func newLegacyStreamReader(r io.Reader, opts EncryptionOptions) (*chunkedReader, error) {
+	header := make([]byte, legacyStreamHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if header[22] != 1 {
+		return nil, fmt.Errorf("unsupported encryption version: %d", header[22])
+	}
+	if header[23] != 1 {
+		return nil, fmt.Errorf("unsupported encryption algorithm: %d", header[23])
+	}
+	salt, nonce := header[24:56], header[56:68]
+
+	key := opts.Key
+	if opts.Passphrase != "" {
+		key = pbkdf2.Key([]byte(opts.Passphrase), salt, LegacyPBKDF2Iterations, KeySize, sha256.New)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chunkedReader{
+		reader:     r,
+		gcm:        gcm,
+		nonce:      append([]byte(nil), nonce...),
+		terminated: true,
+	}, nil
+}
+
+// newLegacyAESReader reads the headerless format of crypto.AESEncryptor: a
+// nonce followed by chunks up to EOF. It only supports raw keys.
+func newLegacyAESReader(r io.Reader, opts EncryptionOptions) (*chunkedReader, error) {
+	if len(opts.Key) != KeySize {
+		return nil, fmt.Errorf("file has no encryption header; legacy headerless files need the %d-byte key, not a passphrase", KeySize)
+	}
+	gcm, err := newGCM(opts.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, NonceSize)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, fmt.Errorf("failed to read nonce: %w", err)
+	}
+
+	return &chunkedReader{reader: r, gcm: gcm, nonce: nonce}, nil
+}
+
+// legacyWALReader reads the legacy WAL format: "WALENC01", a nonce and the
+// whole file sealed at once. WAL segments are small, so it is decrypted in
+// memory on the first Read.
+type legacyWALReader struct {
+	reader    io.Reader
+	gcm       cipher.AEAD
+	plaintext []byte
+	done      bool
+}
+
+func newLegacyWALReader(r io.Reader, opts EncryptionOptions) (*legacyWALReader, error) {
+	magic := make([]byte, len(LegacyWALMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	// A 32-byte key was used directly; anything else was a passphrase with a fixed salt
+	key := opts.Key
+	if len(key) != KeySize {
+		key = pbkdf2.Key([]byte(opts.Passphrase), []byte(legacyWALSalt), legacyWALIterations, KeySize, sha256.New)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &legacyWALReader{reader: r, gcm: gcm}, nil
+}
+
+func (lr *legacyWALReader) Read(p []byte) (int, error) {
+	if !lr.done {
+		lr.done = true
+		data, err := io.ReadAll(lr.reader)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read encrypted data: %w", err)
+		}
+		if len(data) < NonceSize {
+			return 0, fmt.Errorf("encrypted WAL file is truncated: %w", io.ErrUnexpectedEOF)
+		}
+		lr.plaintext, err = lr.gcm.Open(nil, data[:NonceSize], data[NonceSize:], nil)
+		if err != nil {
+			return 0, fmt.Errorf("decryption failed (wrong key?): %w", err)
+		}
+	}
+
+	if len(lr.plaintext) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, lr.plaintext)
+	lr.plaintext = lr.plaintext[n:]
+	return n, nil
+}
+
+func incrementNonce(nonce []byte) {
+	// Increment nonce as a big-endian counter
+	for i := len(nonce) - 1; i >= 0; i-- {
+		nonce[i]++
+		if nonce[i] != 0 {
+			break
+		}
+	}
+}
@@ -3,6 +3,8 @@ package logger
 // NullLogger is a logger that discards all output (useful for testing)
 type NullLogger struct{}
 
+var _ Logger = (*NullLogger)(nil)
+
 // NewNullLogger creates a new null logger
 func NewNullLogger() *NullLogger {
 	return &NullLogger{}
@@ -14,6 +16,9 @@ func (l *NullLogger) Error(msg string, args ...any) {}
 func (l *NullLogger) Debug(msg string, args ...any) {}
 func (l *NullLogger) Time(msg string, args ...any)  {}
 
+func (l *NullLogger) WithFields(fields map[string]interface{}) Logger { return l }
+func (l *NullLogger) WithField(key string, value interface{}) Logger  { return l }
+
 func (l *NullLogger) StartOperation(name string) OperationLogger {
 	return &nullOperation{}
 }
@@ -0,0 +1,220 @@
+package metadata
+
+import (
+	"sort"
+	"strings"
+)
+
+// Table diff statuses
+const (
+	TableAdded     = "added"
+	TableRemoved   = "removed"
+	TableChanged   = "changed"
+	TableUnchanged = "unchanged"
+)
+
+// TableDiff compares a table between two backups. Old is nil for added
+// tables, New for removed ones.
+type TableDiff struct {
+	Name              string
+	Status            string
+	Old               *TableStats
+	New               *TableStats
+	DefinitionChanged bool // Columns, constraints or indexes differ
+}
+
+// RowDelta returns the change in the row count
+func (t TableDiff) RowDelta() int64 {
+	return t.rows(t.New) - t.rows(t.Old)
+}
+
+// SizeDelta returns the change in size, including indexes
+func (t TableDiff) SizeDelta() int64 {
+	var before, after int64
+	if t.Old != nil {
+		before = t.Old.SizeBytes
+	}
+	if t.New != nil {
+		after = t.New.SizeBytes
+	}
+	return after - before
+}
+
+func (t TableDiff) rows(stats *TableStats) int64 {
+	if stats == nil {
+		return 0
+	}
+	return stats.Rows
+}
+
+// DatabaseDiff is what changed in a database between two backups, computed
+// from their metadata alone
+type DatabaseDiff struct {
+	Database      string
+	SchemaChanged bool        // The schema fingerprints differ
+	Tables        []TableDiff // All tables of both backups, sorted by name
+
+	ExtensionsAdded   []string
+	ExtensionsRemoved []string
+	ExtensionsUpdated []string // "name old -> new"
+	SequencesAdded    []string
+	SequencesRemoved  []string
+}
+
+// Changed reports whether anything differs between the backups
+func (d *DatabaseDiff) Changed() bool {
+	if d.SchemaChanged || len(d.ExtensionsAdded)+len(d.ExtensionsRemoved)+len(d.ExtensionsUpdated) > 0 ||
+		len(d.SequencesAdded)+len(d.SequencesRemoved) > 0 {
+		return true
+	}
+	for _, t := range d.Tables {
+		if t.Status != TableUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// HasContent reports whether the backup recorded its tables and schema
+// fingerprint. Backups taken before they were recorded, and sample backups,
+// can't be diffed.
+func (m *BackupMetadata) HasContent() bool {
+	return m.SchemaFingerprint != "" || len(m.Tables) > 0
+}
+
+// Diff compares the content recorded in two backups' metadata
+func Diff(old, new *BackupMetadata) *DatabaseDiff {
+	diff := &DatabaseDiff{
+		Database:      new.Database,
+		SchemaChanged: old.SchemaFingerprint != new.SchemaFingerprint,
+	}
+
+	oldTables := make(map[string]*TableStats, len(old.Tables))
+	for i := range old.Tables {
+		oldTables[old.Tables[i].Name] = &old.Tables[i]
+	}
+	newTables := make(map[string]*TableStats, len(new.Tables))
+	for i := range new.Tables {
+		newTables[new.Tables[i].Name] = &new.Tables[i]
+	}
+
+	for name, before := range oldTables {
+		after, ok := newTables[name]
+		if !ok {
+			diff.Tables = append(diff.Tables, TableDiff{Name: name, Status: TableRemoved, Old: before})
+			continue
+		}
+		t := TableDiff{
+			Name:              name,
+			Status:            TableUnchanged,
+			Old:               before,
+			New:               after,
+			DefinitionChanged: before.Fingerprint != after.Fingerprint,
+		}
+		if t.DefinitionChanged || t.RowDelta() != 0 || t.SizeDelta() != 0 {
+			t.Status = TableChanged
+		}
+		diff.Tables = append(diff.Tables, t)
+	}
+	for name, after := range newTables {
+		if _, ok := oldTables[name]; !ok {
+			diff.Tables = append(diff.Tables, TableDiff{Name: name, Status: TableAdded, New: after})
+		}
+	}
+	sort.Slice(diff.Tables, func(i, j int) bool { return diff.Tables[i].Name < diff.Tables[j].Name })
+
+	// Extensions are recorded as "name version" and matched by name
+	oldExt := extensionVersions(old.Extensions)
+	newExt := extensionVersions(new.Extensions)
+	for _, name := range sortedKeys(oldExt) {
+		after, ok := newExt[name]
+		switch {
+		case !ok:
+			diff.ExtensionsRemoved = append(diff.ExtensionsRemoved, name)
+		case after != oldExt[name]:
+			diff.ExtensionsUpdated = append(diff.ExtensionsUpdated, name+" "+oldExt[name]+" -> "+after)
+		}
+	}
+	for _, name := range sortedKeys(newExt) {
+		if _, ok := oldExt[name]; !ok {
+			diff.ExtensionsAdded = append(diff.ExtensionsAdded, name)
+		}
+	}
+
+	diff.SequencesAdded, diff.SequencesRemoved = diffLists(old.Sequences, new.Sequences)
+	return diff
+}
+
+// ClusterDiff compares the databases of two cluster backups
+type ClusterDiff struct {
+	DatabasesAdded   []string
+	DatabasesRemoved []string
+	Databases        []*DatabaseDiff // Databases in both backups, sorted by name
+}
+
+// DiffCluster compares two cluster backups database by database
+func DiffCluster(old, new *ClusterMetadata) *ClusterDiff {
+	diff := &ClusterDiff{}
+	oldDBs := make(map[string]*BackupMetadata, len(old.Databases))
+	for i := range old.Databases {
+		oldDBs[old.Databases[i].Database] = &old.Databases[i]
+	}
+
+	var oldNames, newNames []string
+	for _, db := range old.Databases {
+		oldNames = append(oldNames, db.Database)
+	}
+	for _, db := range new.Databases {
+		newNames = append(newNames, db.Database)
+	}
+	diff.DatabasesAdded, diff.DatabasesRemoved = diffLists(oldNames, newNames)
+
+	for i := range new.Databases {
+		if before, ok := oldDBs[new.Databases[i].Database]; ok {
+			diff.Databases = append(diff.Databases, Diff(before, &new.Databases[i]))
+		}
+	}
+	sort.Slice(diff.Databases, func(i, j int) bool { return diff.Databases[i].Database < diff.Databases[j].Database })
+	return diff
+}
+
+func extensionVersions(extensions []string) map[string]string {
+	versions := make(map[string]string, len(extensions))
+	for _, ext := range extensions {
+		name, version, _ := strings.Cut(ext, " ")
+		versions[name] = version
+	}
+	return versions
+}
+
+// diffLists returns the sorted entries only in new and only in old
+func diffLists(old, new []string) (added, removed []string) {
+	inOld := make(map[string]bool, len(old))
+	for _, s := range old {
+		inOld[s] = true
+	}
+	inNew := make(map[string]bool, len(new))
+	for _, s := range new {
+		inNew[s] = true
+		if !inOld[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range old {
+		if !inNew[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := &BackupMetadata{
+		Database:          "shop",
+		SchemaFingerprint: "aaa",
+		Tables: []TableStats{
+			{Name: "public.customers", Rows: 5120, SizeBytes: 1 << 20, Fingerprint: "c1"},
+			{Name: "public.orders", Rows: 120000, SizeBytes: 64 << 20, Fingerprint: "o1"},
+			{Name: "public.legacy", Rows: 10, SizeBytes: 8192, Fingerprint: "l1"},
+		},
+		Extensions: []string{"pgcrypto 1.3", "postgis 3.3.2", "hstore 1.8"},
+		Sequences:  []string{"public.orders_id_seq", "public.legacy_id_seq"},
+	}
+	new := &BackupMetadata{
+		Database:          "shop",
+		SchemaFingerprint: "bbb",
+		Tables: []TableStats{
+			{Name: "public.customers", Rows: 5120, SizeBytes: 1 << 20, Fingerprint: "c1"},
+			{Name: "public.orders", Rows: 120431, SizeBytes: 65 << 20, Fingerprint: "o2"},
+			{Name: "public.invoices", Rows: 300, SizeBytes: 16384, Fingerprint: "i1"},
+		},
+		Extensions: []string{"pgcrypto 1.3", "postgis 3.4.0", "citext 1.6"},
+		Sequences:  []string{"public.orders_id_seq", "public.invoices_id_seq"},
+	}
+
+	diff := Diff(old, new)
+	if !diff.SchemaChanged || !diff.Changed() {
+		t.Errorf("Expected a schema change, got %+v", diff)
+	}
+
+	want := map[string]string{
+		"public.customers": TableUnchanged,
+		"public.invoices":  TableAdded,
+		"public.legacy":    TableRemoved,
+		"public.orders":    TableChanged,
+	}
+	if len(diff.Tables) != len(want) {
+		t.Fatalf("Expected %d tables, got %+v", len(want), diff.Tables)
+	}
+	for i, name := range []string{"public.customers", "public.invoices", "public.legacy", "public.orders"} {
+		if diff.Tables[i].Name != name || diff.Tables[i].Status != want[name] {
+			t.Errorf("Table %d: got %s %s, want %s %s", i, diff.Tables[i].Name, diff.Tables[i].Status, name, want[name])
+		}
+	}
+	orders := diff.Tables[3]
+	if !orders.DefinitionChanged || orders.RowDelta() != 431 || orders.SizeDelta() != 1<<20 {
+		t.Errorf("Unexpected orders diff: %+v", orders)
+	}
+	if diff.Tables[1].RowDelta() != 300 || diff.Tables[2].RowDelta() != -10 {
+		t.Errorf("Unexpected row deltas for added and removed tables")
+	}
+
+	if !reflect.DeepEqual(diff.ExtensionsAdded, []string{"citext"}) ||
+		!reflect.DeepEqual(diff.ExtensionsRemoved, []string{"hstore"}) ||
+		!reflect.DeepEqual(diff.ExtensionsUpdated, []string{"postgis 3.3.2 -> 3.4.0"}) {
+		t.Errorf("Unexpected extension diff: %+v", diff)
+	}
+	if !reflect.DeepEqual(diff.SequencesAdded, []string{"public.invoices_id_seq"}) ||
+		!reflect.DeepEqual(diff.SequencesRemoved, []string{"public.legacy_id_seq"}) {
+		t.Errorf("Unexpected sequence diff: %+v", diff)
+	}
+
+	if Diff(old, old).Changed() {
+		t.Error("Expected a backup to be unchanged against itself")
+	}
+}
+
+func TestDiffCluster(t *testing.T) {
+	old := &ClusterMetadata{Databases: []BackupMetadata{
+		{Database: "shop", SchemaFingerprint: "aaa"},
+		{Database: "crm", SchemaFingerprint: "ccc"},
+	}}
+	new := &ClusterMetadata{Databases: []BackupMetadata{
+		{Database: "shop", SchemaFingerprint: "bbb"},
+		{Database: "analytics", SchemaFingerprint: "ddd"},
+	}}
+
+	diff := DiffCluster(old, new)
+	if !reflect.DeepEqual(diff.DatabasesAdded, []string{"analytics"}) || !reflect.DeepEqual(diff.DatabasesRemoved, []string{"crm"}) {
+		t.Errorf("Unexpected database changes: %+v", diff)
+	}
+	if len(diff.Databases) != 1 || diff.Databases[0].Database != "shop" || !diff.Databases[0].SchemaChanged {
+		t.Errorf("Unexpected database diffs: %+v", diff.Databases)
+	}
+}
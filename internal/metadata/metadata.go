@@ -9,6 +9,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"dbbackup/internal/crypto"
 )
 
 // BackupMetadata contains comprehensive information about a backup
@@ -24,7 +26,7 @@ type BackupMetadata struct {
 	BackupFile      string            `json:"backup_file"`
 	SizeBytes       int64             `json:"size_bytes"`
 	SHA256          string            `json:"sha256"`
-	Compression     string            `json:"compression"` // none, gzip, pigz
+	Compression     string            `json:"compression"` // none, or algorithm-level: gzip-6, pigz-6, zstd-3, zstd-19-long, lz4-1
 	BackupType      string            `json:"backup_type"` // full, incremental (for v2.2)
 	BaseBackup      string            `json:"base_backup,omitempty"`
 	Duration        float64           `json:"duration_seconds"`
@@ -34,8 +36,101 @@ type BackupMetadata struct {
 	Encrypted           bool   `json:"encrypted"`                      // Whether backup is encrypted
 	EncryptionAlgorithm string `json:"encryption_algorithm,omitempty"` // e.g., "aes-256-gcm"
 	
+	// Envelope encryption: the backup's data key wrapped by a key provider
+	Encryption *crypto.EncryptionMetadata `json:"encryption,omitempty"`
+	
 	// Incremental backup fields (v2.2+)
 	Incremental *IncrementalMetadata `json:"incremental,omitempty"` // Only present for incremental backups
+	
+	// Physical base backup fields
+	Physical *PhysicalMetadata `json:"physical,omitempty"` // Only present for pg_basebackup base backups
+	
+	// Replication to cloud targets: complete, degraded (some targets failed) or failed
+	ReplicationStatus string          `json:"replication_status,omitempty"`
+	Replicas          []ReplicaStatus `json:"replicas,omitempty"`
+	
+	// Object lock on the cloud copies: governance or compliance mode until
+	// LockedUntil, and/or a legal hold without expiry
+	LockMode    string     `json:"lock_mode,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LegalHold   bool       `json:"legal_hold,omitempty"`
+	
+	// Content when the backup was taken: tables with row counts and sizes, a
+	// hash of the normalized schema, extensions and sequences. RowsExact is set
+	// when the counts were read in the dump's own snapshot (PostgreSQL); on
+	// MySQL they come from a snapshot taken just before the dump's.
+	Tables            []TableStats `json:"tables,omitempty"`
+	RowsExact         bool         `json:"rows_exact,omitempty"`
+	SchemaFingerprint string       `json:"schema_fingerprint,omitempty"`
+	Extensions        []string     `json:"extensions,omitempty"`
+	Sequences         []string     `json:"sequences,omitempty"`
+	
+	// Restore drills run against this backup, oldest first
+	Drills []DrillRecord `json:"drills,omitempty"`
+}
+
+// Replication status values
+const (
+	ReplicationComplete = "complete"
+	ReplicationDegraded = "degraded"
+	ReplicationFailed   = "failed"
+)
+
+// ReplicaStatus records the upload of a backup to one cloud target
+type ReplicaStatus struct {
+	Target     string    `json:"target"`   // Target name ("primary" for the --cloud destination)
+	Provider   string    `json:"provider"` // s3, azure, gcs, sftp, ...
+	Location   string    `json:"location"` // Bucket or host
+	Key        string    `json:"key"`      // Remote path of the backup file
+	Success    bool      `json:"success"`
+	ETag       string    `json:"etag,omitempty"` // As reported by the target after upload
+	Attempts   int       `json:"attempts"`
+	UploadedAt time.Time `json:"uploaded_at,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// TableStats describes a table when the backup was taken
+type TableStats struct {
+	Name        string `json:"name"`        // schema.table for PostgreSQL
+	Rows        int64  `json:"rows"`        // Counted alongside the dump, see RowsExact
+	SizeBytes   int64  `json:"size_bytes"`  // Including indexes
+	Fingerprint string `json:"fingerprint"` // Hash of the table's normalized definition
+}
+
+// DrillRecord is the outcome of a restore drill: the backup restored into a
+// scratch database that was checked and dropped again
+type DrillRecord struct {
+	Timestamp       time.Time    `json:"timestamp"`
+	Target          string       `json:"target"`                   // Scratch database, or the local instance it was restored into
+	ServerVersion   string       `json:"server_version,omitempty"` // Version of the server restored into
+	Success         bool         `json:"success"`
+	RestoreDuration float64      `json:"restore_duration_seconds"`
+	CheckDuration   float64      `json:"check_duration_seconds"`
+	Duration        float64      `json:"duration_seconds"`
+	Tables          int          `json:"tables"` // Tables found after the restore
+	Checks          []DrillCheck `json:"checks,omitempty"`
+	Error           string       `json:"error,omitempty"`
+}
+
+// DrillCheck is one sanity check of a restore drill
+type DrillCheck struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// PhysicalMetadata contains the WAL position of a physical base backup (used for PITR)
+type PhysicalMetadata struct {
+	StartLSN       string `json:"start_lsn"`        // WAL location where the backup started (e.g. "0/2000028")
+	StopLSN        string `json:"stop_lsn"`         // WAL location where the backup became consistent
+	Timeline       uint32 `json:"timeline"`         // Timeline the backup was taken on
+	StartWALFile   string `json:"start_wal_file"`   // First WAL segment needed for recovery
+	StopWALFile    string `json:"stop_wal_file"`    // Segment containing StopLSN
+	WALSegmentSize int64  `json:"wal_segment_size"` // Server wal_segment_size in bytes
+	WALMethod      string `json:"wal_method"`       // pg_basebackup --wal-method (stream)
+	Tablespaces    int    `json:"tablespaces"`      // Number of tablespaces folded into the archive
 }
 
 // IncrementalMetadata contains metadata specific to incremental backups
@@ -0,0 +1,114 @@
+package pitr
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"dbbackup/internal/metadata"
+	"dbbackup/internal/wal"
+)
+
+// ValidateBaseBackupWAL checks that a physical base backup can be rolled
+// forward with the archived WAL files: the archive must continue on the
+// backup's timeline right after the segment where the backup became
+// consistent, and the recovery target must not lie before that point.
+// Problems that only limit how far recovery can go are returned as warnings.
+func ValidateBaseBackupWAL(meta *metadata.BackupMetadata, files []wal.WALArchiveInfo, target *RecoveryTarget) ([]string, error) {
+	phys := meta.Physical
+	if phys == nil {
+		return nil, fmt.Errorf("%s has no WAL position metadata (not created with 'backup base')", meta.BackupFile)
+	}
+
+	segSize := phys.WALSegmentSize
+	if segSize <= 0 {
+		segSize = wal.DefaultWALSegmentSize
+	}
+	stopLSN, err := wal.ParseLSN(phys.StopLSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stop LSN in backup metadata: %w", err)
+	}
+	stopSeg := stopLSN / uint64(segSize)
+
+	// Recovery target must not precede the point where the backup is consistent
+	if target != nil {
+		switch target.Type {
+		case TargetTypeLSN:
+			if targetLSN, err := wal.ParseLSN(target.Value); err == nil && targetLSN < stopLSN {
+				return nil, fmt.Errorf("recovery target LSN %s is before the base backup end point %s; use an earlier base backup",
+					target.Value, phys.StopLSN)
+			}
+		case TargetTypeTime:
+			if t, zoned, ok := parseTargetTime(target.Value); ok && t.Before(meta.Timestamp) {
+				if zoned {
+					return nil, fmt.Errorf("recovery target time %s is before the base backup was taken (%s); use an earlier base backup",
+						target.Value, meta.Timestamp.Format(time.RFC3339))
+				}
+				return []string{fmt.Sprintf("recovery target time %s appears to be before the base backup was taken (%s)",
+					target.Value, meta.Timestamp.Format(time.RFC3339))}, nil
+			}
+		}
+	}
+
+	var segments []uint64
+	laterTimelines := 0
+	for _, f := range files {
+		switch {
+		case f.Timeline == phys.Timeline:
+			segments = append(segments, wal.AbsoluteSegment(f.Segment, segSize))
+		case f.Timeline > phys.Timeline:
+			laterTimelines++
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+
+	if len(segments) == 0 && laterTimelines == 0 {
+		return nil, fmt.Errorf("WAL archive has no segments for timeline %d or later (base backup ends in %s)",
+			phys.Timeline, phys.StopWALFile)
+	}
+
+	// The WAL up to stopSeg is bundled in the base backup under pg_wal/;
+	// everything after it has to come from the archive without gaps
+	var warnings []string
+	next := stopSeg + 1
+	gapped := false
+	for _, seg := range segments {
+		if seg < next {
+			continue
+		}
+		if seg > next {
+			missing := wal.WALFileName(phys.Timeline, next*uint64(segSize), segSize)
+			if next == stopSeg+1 {
+				return nil, fmt.Errorf("WAL archive is missing segment %s directly after the base backup (ends in %s)",
+					missing, phys.StopWALFile)
+			}
+			warnings = append(warnings, fmt.Sprintf("WAL archive has a gap at segment %s; recovery cannot proceed past it", missing))
+			gapped = true
+			break
+		}
+		next++
+	}
+
+	if !gapped && next == stopSeg+1 && laterTimelines == 0 {
+		warnings = append(warnings, fmt.Sprintf("WAL archive has no segments after the base backup (ends in %s); recovery can only reach the end of the backup",
+			phys.StopWALFile))
+	}
+
+	return warnings, nil
+}
+
+// parseTargetTime parses a recovery target time. zoned reports whether the
+// value carried a time zone; otherwise it is interpreted in local time.
+func parseTargetTime(value string) (t time.Time, zoned bool, ok bool) {
+	for _, format := range []string{time.RFC3339Nano, "2006-01-02 15:04:05-07:00", "2006-01-02 15:04:05-07"} {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, true, true
+		}
+	}
+	for _, format := range []string{"2006-01-02 15:04:05.999999", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return t, false, true
+		}
+	}
+	return time.Time{}, false, false
+}
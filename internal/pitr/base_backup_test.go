@@ -0,0 +1,107 @@
+package pitr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"dbbackup/internal/metadata"
+	"dbbackup/internal/wal"
+)
+
+// walFiles builds archive entries for the given segment file names
+func walFiles(t *testing.T, names ...string) []wal.WALArchiveInfo {
+	t.Helper()
+	var files []wal.WALArchiveInfo
+	for _, name := range names {
+		tli, seg, err := wal.ParseWALFileName(name)
+		if err != nil {
+			t.Fatalf("bad WAL name %s: %v", name, err)
+		}
+		files = append(files, wal.WALArchiveInfo{WALFileName: name, Timeline: tli, Segment: seg})
+	}
+	return files
+}
+
+func TestValidateBaseBackupWAL(t *testing.T) {
+	taken := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	meta := &metadata.BackupMetadata{
+		BackupFile: "base_20250301_120000.tar.gz",
+		Timestamp:  taken,
+		Physical: &metadata.PhysicalMetadata{
+			StartLSN:       "0/FF000028",
+			StopLSN:        "0/FF000138",
+			Timeline:       1,
+			StopWALFile:    "0000000100000000000000FF",
+			WALSegmentSize: wal.DefaultWALSegmentSize,
+		},
+	}
+	lsnTarget := func(lsn string) *RecoveryTarget { return &RecoveryTarget{Type: TargetTypeLSN, Value: lsn} }
+
+	t.Run("Contiguous", func(t *testing.T) {
+		// Segment after 0/FF is 1/00 with 16MB segments
+		files := walFiles(t, "0000000100000000000000FE", "000000010000000100000000", "000000010000000100000001")
+		warnings, err := ValidateBaseBackupWAL(meta, files, lsnTarget("1/1000000"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(warnings) != 0 {
+			t.Errorf("Unexpected warnings: %v", warnings)
+		}
+	})
+
+	t.Run("GapAfterBackup", func(t *testing.T) {
+		files := walFiles(t, "000000010000000100000001")
+		_, err := ValidateBaseBackupWAL(meta, files, lsnTarget("1/1000000"))
+		if err == nil || !strings.Contains(err.Error(), "000000010000000100000000") {
+			t.Errorf("Expected missing segment error, got %v", err)
+		}
+	})
+
+	t.Run("LaterGapIsWarning", func(t *testing.T) {
+		files := walFiles(t, "000000010000000100000000", "000000010000000100000005")
+		warnings, err := ValidateBaseBackupWAL(meta, files, lsnTarget("1/1000000"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "000000010000000100000001") {
+			t.Errorf("Expected gap warning, got %v", warnings)
+		}
+	})
+
+	t.Run("WrongTimeline", func(t *testing.T) {
+		// Archive from an older timeline cannot roll this backup forward
+		timeline2 := *meta
+		phys := *meta.Physical
+		phys.Timeline = 2
+		timeline2.Physical = &phys
+		files := walFiles(t, "000000010000000100000000")
+		if _, err := ValidateBaseBackupWAL(&timeline2, files, lsnTarget("1/1000000")); err == nil {
+			t.Error("Expected error for archive without matching timeline")
+		}
+	})
+
+	t.Run("TargetBeforeBackup", func(t *testing.T) {
+		files := walFiles(t, "000000010000000100000000")
+		if _, err := ValidateBaseBackupWAL(meta, files, lsnTarget("0/FE000000")); err == nil {
+			t.Error("Expected error for LSN target before backup end")
+		}
+		timeTarget := &RecoveryTarget{Type: TargetTypeTime, Value: "2025-03-01T11:00:00Z"}
+		if _, err := ValidateBaseBackupWAL(meta, files, timeTarget); err == nil {
+			t.Error("Expected error for time target before backup")
+		}
+	})
+}
+
+func TestWALFileName(t *testing.T) {
+	lsn, err := wal.ParseLSN("1/A2000028")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := wal.WALFileName(3, lsn, wal.DefaultWALSegmentSize); got != "0000000300000001000000A2" {
+		t.Errorf("Unexpected WAL file name %s", got)
+	}
+	if got := wal.FormatLSN(lsn); got != "1/A2000028" {
+		t.Errorf("Unexpected LSN %s", got)
+	}
+}
@@ -181,10 +181,10 @@ func (rcg *RecoveryConfigGenerator) generateRestoreCommand(walArchiveDir string)
 	// The restore_command is executed by PostgreSQL to fetch WAL files
 	// %f = WAL filename, %p = full path to copy WAL file to
 	
-	// Try multiple extensions (.gz.enc, .enc, .gz, plain)
+	// Try multiple extensions (.gz.enc, .enc, .gz, .zst, .lz4, plain)
 	// This handles compressed and/or encrypted WAL files
-	return fmt.Sprintf(`bash -c 'for ext in .gz.enc .enc .gz ""; do [ -f "%s/%%f$ext" ] && { [ -z "$ext" ] && cp "%s/%%f$ext" "%%p" || case "$ext" in *.gz.enc) gpg -d "%s/%%f$ext" | gunzip > "%%p" ;; *.enc) gpg -d "%s/%%f$ext" > "%%p" ;; *.gz) gunzip -c "%s/%%f$ext" > "%%p" ;; esac; exit 0; }; done; exit 1'`,
-		walArchiveDir, walArchiveDir, walArchiveDir, walArchiveDir, walArchiveDir)
+	return fmt.Sprintf(`bash -c 'for ext in .gz.enc .enc .gz .zst .lz4 ""; do [ -f "%s/%%f$ext" ] && { [ -z "$ext" ] && cp "%s/%%f$ext" "%%p" || case "$ext" in *.gz.enc) gpg -d "%s/%%f$ext" | gunzip > "%%p" ;; *.enc) gpg -d "%s/%%f$ext" > "%%p" ;; *.gz) gunzip -c "%s/%%f$ext" > "%%p" ;; *.zst) zstd -q -dc --long=31 "%s/%%f$ext" > "%%p" ;; *.lz4) lz4 -q -dc "%s/%%f$ext" > "%%p" ;; esac; exit 0; }; done; exit 1'`,
+		walArchiveDir, walArchiveDir, walArchiveDir, walArchiveDir, walArchiveDir, walArchiveDir, walArchiveDir)
 }
 
 // ValidateDataDirectory validates that the target directory is suitable for recovery
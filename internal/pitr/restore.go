@@ -3,14 +3,19 @@ package pitr
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"dbbackup/internal/compression"
 	"dbbackup/internal/config"
+	"dbbackup/internal/encryption"
 	"dbbackup/internal/logger"
+	"dbbackup/internal/metadata"
+	"dbbackup/internal/wal"
 )
 
 // RestoreOrchestrator orchestrates Point-in-Time Recovery operations
@@ -31,7 +36,7 @@ func NewRestoreOrchestrator(cfg *config.Config, log logger.Logger) *RestoreOrche
 
 // RestoreOptions holds options for PITR restore
 type RestoreOptions struct {
-	BaseBackupPath  string          // Path to base backup file (.tar.gz, .sql, or directory)
+	BaseBackupPath  string          // Path to base backup file (.tar.gz, .tar.zst, .tar.lz4, .sql, or directory)
 	WALArchiveDir   string          // Path to WAL archive directory
 	Target          *RecoveryTarget // Recovery target
 	TargetDataDir   string          // PostgreSQL data directory to restore to
@@ -39,6 +44,9 @@ type RestoreOptions struct {
 	SkipExtraction  bool            // Skip base backup extraction (data dir already exists)
 	AutoStart       bool            // Automatically start PostgreSQL after recovery
 	MonitorProgress bool            // Monitor recovery progress
+	SkipWALCheck    bool            // Skip checking the base backup's WAL range against the archive
+
+	Encryption *encryption.EncryptionOptions // Key for base backups encrypted while they were written
 }
 
 // RestorePointInTime performs a Point-in-Time Recovery
@@ -58,7 +66,14 @@ func (ro *RestoreOrchestrator) RestorePointInTime(ctx context.Context, opts *Res
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
-	// Step 2: Extract base backup (if needed)
+	// Step 2: Check that the base backup matches the WAL archive
+	if !opts.SkipExtraction && !opts.SkipWALCheck {
+		if err := ro.checkBaseBackupWAL(opts); err != nil {
+			return fmt.Errorf("base backup does not match WAL archive: %w", err)
+		}
+	}
+
+	// Step 3: Extract base backup (if needed)
 	if !opts.SkipExtraction {
 		if err := ro.extractBaseBackup(ctx, opts); err != nil {
 			return fmt.Errorf("base backup extraction failed: %w", err)
@@ -67,19 +82,19 @@ func (ro *RestoreOrchestrator) RestorePointInTime(ctx context.Context, opts *Res
 		ro.log.Info("Skipping base backup extraction (--skip-extraction)")
 	}
 
-	// Step 3: Detect PostgreSQL version
+	// Step 4: Detect PostgreSQL version
 	pgVersion, err := ro.configGen.DetectPostgreSQLVersion(opts.TargetDataDir)
 	if err != nil {
 		return fmt.Errorf("failed to detect PostgreSQL version: %w", err)
 	}
 	ro.log.Info("PostgreSQL version detected", "version", pgVersion)
 
-	// Step 4: Backup existing recovery config (if any)
+	// Step 5: Backup existing recovery config (if any)
 	if err := ro.configGen.BackupExistingConfig(opts.TargetDataDir); err != nil {
 		ro.log.Warn("Failed to backup existing recovery config", "error", err)
 	}
 
-	// Step 5: Generate recovery configuration
+	// Step 6: Generate recovery configuration
 	recoveryConfig := &RecoveryConfig{
 		Target:            opts.Target,
 		WALArchiveDir:     opts.WALArchiveDir,
@@ -196,6 +211,37 @@ func (ro *RestoreOrchestrator) validateInputs(opts *RestoreOptions) error {
 	return nil
 }
 
+// checkBaseBackupWAL compares the WAL range recorded by 'backup base' with the WAL archive
+func (ro *RestoreOrchestrator) checkBaseBackupWAL(opts *RestoreOptions) error {
+	meta, err := metadata.Load(opts.BaseBackupPath)
+	if err != nil || meta.Physical == nil {
+		ro.log.Warn("Base backup has no WAL position metadata - skipping WAL archive check", "path", opts.BaseBackupPath)
+		return nil
+	}
+
+	ro.log.Info("Checking base backup against WAL archive...",
+		"start_lsn", meta.Physical.StartLSN,
+		"stop_lsn", meta.Physical.StopLSN,
+		"timeline", meta.Physical.Timeline)
+
+	archiver := wal.NewArchiver(ro.config, ro.log)
+	files, err := archiver.ListArchivedWALFiles(wal.ArchiveConfig{ArchiveDir: opts.WALArchiveDir})
+	if err != nil {
+		return err
+	}
+
+	warnings, err := ValidateBaseBackupWAL(meta, files, opts.Target)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		ro.log.Warn(warning)
+	}
+
+	ro.log.Info("✅ Base backup matches WAL archive", "wal_files", len(files))
+	return nil
+}
+
 // extractBaseBackup extracts the base backup to the target directory
 func (ro *RestoreOrchestrator) extractBaseBackup(ctx context.Context, opts *RestoreOptions) error {
 	ro.log.Info("Extracting base backup...", "source", opts.BaseBackupPath, "dest", opts.TargetDataDir)
@@ -208,29 +254,51 @@ func (ro *RestoreOrchestrator) extractBaseBackup(ctx context.Context, opts *Rest
 	// Determine backup format and extract
 	backupPath := opts.BaseBackupPath
 
-	// Check if encrypted
-	if strings.HasSuffix(backupPath, ".enc") {
-		ro.log.Info("Backup is encrypted - decryption not yet implemented in PITR module")
-		return fmt.Errorf("encrypted backups not yet supported for PITR restore (use manual decryption)")
+	// Encrypted backups are decrypted on the fly; legacy .enc files have no
+	// header, so they are recognized by name
+	if encryption.IsEncryptedFile(backupPath) || strings.HasSuffix(backupPath, ".enc") {
+		return ro.extractEncryptedBackup(ctx, backupPath, opts)
 	}
 
 	// Check format
-	if strings.HasSuffix(backupPath, ".tar.gz") || strings.HasSuffix(backupPath, ".tgz") {
-		return ro.extractTarGzBackup(ctx, backupPath, opts.TargetDataDir)
+	if compression.FromExtension(backupPath) != compression.None {
+		return ro.extractCompressedTarBackup(ctx, backupPath, opts.TargetDataDir)
 	} else if strings.HasSuffix(backupPath, ".tar") {
 		return ro.extractTarBackup(ctx, backupPath, opts.TargetDataDir)
 	} else if stat, err := os.Stat(backupPath); err == nil && stat.IsDir() {
 		return ro.copyDirectoryBackup(ctx, backupPath, opts.TargetDataDir)
 	}
 
-	return fmt.Errorf("unsupported backup format: %s (expected .tar.gz, .tar, or directory)", backupPath)
+	return fmt.Errorf("unsupported backup format: %s (expected .tar.gz, .tar.zst, .tar.lz4, .tar, or directory)", backupPath)
 }
 
-// extractTarGzBackup extracts a .tar.gz backup
-func (ro *RestoreOrchestrator) extractTarGzBackup(ctx context.Context, source, dest string) error {
-	ro.log.Info("Extracting tar.gz backup...")
+// extractCompressedTarBackup extracts a .tar.gz, .tar.zst or .tar.lz4 backup;
+// the algorithm is taken from the magic bytes, not the name
+func (ro *RestoreOrchestrator) extractCompressedTarBackup(ctx context.Context, source, dest string) error {
+	ro.log.Info("Extracting compressed tar backup...", "compression", compression.DetectFile(source))
 
-	cmd := exec.CommandContext(ctx, "tar", "-xzf", source, "-C", dest)
+	file, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open base backup: %w", err)
+	}
+	defer file.Close()
+
+	return ro.extractTarStream(ctx, file, true, dest)
+}
+
+// extractTarStream pipes a tar stream into tar, decompressing it first if needed
+func (ro *RestoreOrchestrator) extractTarStream(ctx context.Context, r io.Reader, compressed bool, dest string) error {
+	if compressed {
+		decompressed, err := compression.NewReader(ctx, r)
+		if err != nil {
+			return fmt.Errorf("failed to decompress base backup: %w", err)
+		}
+		defer decompressed.Close()
+		r = decompressed
+	}
+
+	cmd := exec.CommandContext(ctx, "tar", "-xf", "-", "-C", dest)
+	cmd.Stdin = r
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -242,6 +310,29 @@ func (ro *RestoreOrchestrator) extractTarGzBackup(ctx context.Context, source, d
 	return nil
 }
 
+// extractEncryptedBackup decrypts a base backup and pipes it into tar, so no
+// plaintext copy of the archive is written
+func (ro *RestoreOrchestrator) extractEncryptedBackup(ctx context.Context, source string, opts *RestoreOptions) error {
+	if opts.Encryption == nil {
+		return fmt.Errorf("base backup %s is encrypted: provide the key with --encryption-key-file or --encryption-key-env", source)
+	}
+	ro.log.Info("Extracting encrypted backup...")
+
+	file, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open base backup: %w", err)
+	}
+	defer file.Close()
+
+	reader, err := encryption.NewDecryptionReader(file, *opts.Encryption)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt base backup: %w", err)
+	}
+
+	name := strings.TrimSuffix(source, ".enc")
+	return ro.extractTarStream(ctx, reader, compression.FromExtension(name) != compression.None, opts.TargetDataDir)
+}
+
 // extractTarBackup extracts a .tar backup
 func (ro *RestoreOrchestrator) extractTarBackup(ctx context.Context, source, dest string) error {
 	ro.log.Info("Extracting tar backup...")
@@ -33,6 +33,9 @@ type DownloadOptions struct {
 	VerifyChecksum bool   // Verify SHA-256 checksum after download
 	KeepLocal      bool   // Keep downloaded file (don't delete temp)
 	TempDir        string // Temp directory (default: os.TempDir())
+
+	// How a backup in an archive tier is made readable before downloading
+	Rehydrate cloud.RehydrateOptions
 }
 
 // DownloadResult contains information about a downloaded backup
@@ -53,8 +56,10 @@ func (d *CloudDownloader) Download(ctx context.Context, remotePath string, opts
 		tempDir = os.TempDir()
 	}
 
-	// Create unique temp subdirectory
-	tempSubDir := filepath.Join(tempDir, fmt.Sprintf("dbbackup-download-%d", os.Getpid()))
+	// Temp subdirectory named after the object, so that an interrupted
+	// download is found and resumed by the next attempt
+	id := sha256.Sum256([]byte(d.backend.Name() + ":" + remotePath))
+	tempSubDir := filepath.Join(tempDir, "dbbackup-download-"+hex.EncodeToString(id[:6]))
 	if err := os.MkdirAll(tempSubDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
@@ -63,6 +68,14 @@ func (d *CloudDownloader) Download(ctx context.Context, remotePath string, opts
 	filename := filepath.Base(remotePath)
 	localPath := filepath.Join(tempSubDir, filename)
 
+	// Archived backups must be rehydrated before they can be read
+	if opts.Rehydrate.Log == nil {
+		opts.Rehydrate.Log = d.log.Info
+	}
+	if err := cloud.Rehydrate(ctx, d.backend, []string{remotePath}, opts.Rehydrate); err != nil {
+		return nil, err
+	}
+
 	d.log.Info("Downloading backup from cloud", "remote", remotePath, "local", localPath)
 
 	// Get file size for progress tracking
@@ -86,8 +99,8 @@ func (d *CloudDownloader) Download(ctx context.Context, remotePath string, opts
 
 	// Download file
 	if err := d.backend.Download(ctx, remotePath, localPath, progressCallback); err != nil {
-		// Cleanup on failure
-		os.RemoveAll(tempSubDir)
+		// The partial download is kept for the next attempt to resume
+		d.log.Warn("Download interrupted, partial file kept for resume", "dir", tempSubDir)
 		return nil, fmt.Errorf("download failed: %w", err)
 	}
 
@@ -124,7 +137,7 @@ func (d *CloudDownloader) Download(ctx context.Context, remotePath string, opts
 
 		// Check against metadata if available
 		if result.MetadataPath != "" {
-			meta, err := metadata.Load(result.MetadataPath)
+			meta, err := metadata.Load(localPath)
 			if err != nil {
 				d.log.Warn("Failed to load metadata for verification", "error", err)
 			} else if meta.SHA256 != "" && meta.SHA256 != checksum {
@@ -201,6 +214,7 @@ func DownloadFromCloudURI(ctx context.Context, uri string, opts DownloadOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cloud backend: %w", err)
 	}
+	defer cloud.CloseBackend(backend)
 
 	// Create downloader
 	log := logger.New("info", "text")
@@ -0,0 +1,60 @@
+package restore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dbbackup/internal/metadata"
+)
+
+// writeBackup stores a backup and its metadata in dir, as a backup run would
+func writeBackup(t *testing.T, dir, name string, data []byte, sha string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	meta := &metadata.BackupMetadata{BackupFile: path, SHA256: sha}
+	if err := meta.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadFromFileURI(t *testing.T) {
+	remote := t.TempDir()
+	data := bytes.Repeat([]byte("COPY public.t FROM stdin;\n"), 1000)
+	hash := sha256.Sum256(data)
+	sum := hex.EncodeToString(hash[:])
+	writeBackup(t, remote, "db_app.dump", data, sum)
+
+	uri := "file://" + filepath.ToSlash(filepath.Join(remote, "db_app.dump"))
+	result, err := DownloadFromCloudURI(context.Background(), uri, DownloadOptions{VerifyChecksum: true, TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer result.Cleanup()
+
+	if result.SHA256 != sum || result.MetadataPath == "" {
+		t.Errorf("Expected verified download with metadata, got %+v", result)
+	}
+	if got, _ := os.ReadFile(result.LocalPath); !bytes.Equal(got, data) {
+		t.Errorf("Downloaded file doesn't match (%d bytes)", len(got))
+	}
+}
+
+func TestDownloadFromFileURIChecksumMismatch(t *testing.T) {
+	remote := t.TempDir()
+	writeBackup(t, remote, "db_app.dump", []byte("corrupted"), strings.Repeat("0", 64))
+
+	uri := "file://" + filepath.ToSlash(filepath.Join(remote, "db_app.dump"))
+	_, err := DownloadFromCloudURI(context.Background(), uri, DownloadOptions{VerifyChecksum: true, TempDir: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch, got %v", err)
+	}
+}
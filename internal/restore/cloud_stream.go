@@ -0,0 +1,305 @@
+package restore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"strings"
+
+	"dbbackup/internal/cloud"
+	"dbbackup/internal/compression"
+	"dbbackup/internal/database"
+	"dbbackup/internal/encryption"
+	"dbbackup/internal/metadata"
+)
+
+// maxSidecarSize bounds the .meta.json and .sha256 objects read into memory
+const maxSidecarSize = 16 * 1024 * 1024
+
+// LoadRemoteMetadata reads the .meta.json stored next to a backup in cloud storage
+func LoadRemoteMetadata(ctx context.Context, backend cloud.Backend, remotePath string) (*metadata.BackupMetadata, error) {
+	data, err := readSidecar(ctx, backend, remotePath+".meta.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var meta metadata.BackupMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	return &meta, nil
+}
+
+// remoteChecksum returns the SHA-256 recorded for a cloud backup, from its
+// metadata or else its .sha256 object ("" if neither has one)
+func remoteChecksum(ctx context.Context, backend cloud.Backend, remotePath string, meta *metadata.BackupMetadata) string {
+	if meta != nil && meta.SHA256 != "" {
+		return meta.SHA256
+	}
+	data, err := readSidecar(ctx, backend, remotePath+".sha256")
+	if err != nil {
+		return ""
+	}
+	if fields := strings.Fields(string(data)); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// readSidecar downloads a small object stored next to a backup
+func readSidecar(ctx context.Context, backend cloud.Backend, remotePath string) ([]byte, error) {
+	exists, err := backend.Exists(ctx, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%s not found", remotePath)
+	}
+
+	r, err := backend.DownloadStream(ctx, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(io.LimitReader(r, maxSidecarSize))
+}
+
+// RestoreSingleFromCloud restores a single database straight from a cloud
+// object: it is piped through decryption and decompression into pg_restore,
+// psql or mysql while it downloads, so no local copy is needed.
+//
+// The object's SHA-256 is computed as it streams. The tail of the stream is
+// held back until the checksum has been compared with the one recorded in
+// the backup's metadata, so on a mismatch the restore tool never sees a
+// complete archive and the restore fails.
+func (e *Engine) RestoreSingleFromCloud(ctx context.Context, backend cloud.Backend, remotePath, targetDB string, cleanFirst, createIfMissing bool) error {
+	operation := e.log.StartOperation("Single Database Restore (streaming)")
+	name := path.Base(remotePath)
+
+	meta, err := LoadRemoteMetadata(ctx, backend, remotePath)
+	if err != nil {
+		e.log.Warn("No metadata for cloud backup", "error", err)
+	}
+	expected := remoteChecksum(ctx, backend, remotePath, meta)
+	if expected == "" {
+		e.log.Warn("No checksum recorded for cloud backup, restoring without verification (use with caution)")
+	}
+
+	if e.dryRun {
+		e.log.Info("DRY RUN: Would stream single database restore", "object", remotePath, "target", targetDB)
+		return nil
+	}
+
+	if createIfMissing {
+		e.log.Info("Checking if target database exists", "database", targetDB)
+		if err := e.ensureDatabaseExists(ctx, targetDB); err != nil {
+			operation.Fail(fmt.Sprintf("Failed to create database: %v", err))
+			return fmt.Errorf("failed to create database '%s': %w", targetDB, err)
+		}
+	}
+
+	// Cancelled on a checksum mismatch, which also kills the restore command
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	object, err := backend.DownloadStream(ctx, remotePath)
+	if err != nil {
+		operation.Fail(fmt.Sprintf("Download failed: %v", err))
+		return err
+	}
+	defer object.Close()
+
+	verifier := newChecksumReader(object, expected, cancel)
+	input, format, err := e.openStream(verifier, name, meta)
+	if err != nil {
+		operation.Fail(err.Error())
+		return err
+	}
+	defer input.Close()
+	e.log.Info("Detected archive format", "format", format, "object", remotePath)
+
+	var cmd []string
+	switch {
+	case format.IsPostgreSQLDump():
+		cmd = e.db.BuildRestoreCommand(targetDB, "", singleRestoreOptions(cleanFirst))
+	case format.IsPostgreSQLSQL():
+		cmd = e.psqlStdinCommand(targetDB)
+	case format.IsMySQL():
+		cmd = e.db.BuildRestoreCommand(targetDB, "", database.RestoreOptions{})
+	default:
+		operation.Fail("Unsupported archive format")
+		return fmt.Errorf("unsupported archive format: %s", format)
+	}
+
+	e.progress.Start(fmt.Sprintf("Restoring database '%s' from %s (streaming)", targetDB, name))
+
+	err = e.executeRestoreCommandWithInput(ctx, cmd, input)
+	if err == nil {
+		// The restore tool may stop short of the end of the object; the rest
+		// still has to be read for the checksum to cover all of it
+		err = verifier.finish()
+	}
+	if verr := verifier.mismatch(); verr != nil {
+		err = verr
+	}
+	if err != nil {
+		e.progress.Fail(fmt.Sprintf("Restore failed: %v", err))
+		operation.Fail(fmt.Sprintf("Restore failed: %v", err))
+		return err
+	}
+
+	if expected != "" {
+		e.log.Info("✓ Archive checksum verified successfully", "sha256", expected)
+	}
+	e.progress.Complete(fmt.Sprintf("Database '%s' restored successfully", targetDB))
+	operation.Complete(fmt.Sprintf("Restored database '%s' from %s", targetDB, remotePath))
+	return nil
+}
+
+// openStream decrypts and decompresses a backup stream as its first bytes
+// require, and works out its format the way DetectArchiveFormat does for files
+func (e *Engine) openStream(r io.Reader, name string, meta *metadata.BackupMetadata) (io.ReadCloser, ArchiveFormat, error) {
+	reader := &archiveReader{Reader: bufio.NewReader(r)}
+
+	if encryption.IsEncrypted(peek(reader.Reader, 16)) {
+		if e.encryption == nil {
+			return nil, FormatUnknown, fmt.Errorf("%s is encrypted: provide the key with --encryption-key-file or --encryption-key-env", name)
+		}
+		dr, err := encryption.NewDecryptionReader(reader.Reader, *e.encryption)
+		if err != nil {
+			return nil, FormatUnknown, fmt.Errorf("failed to decrypt %s: %w", name, err)
+		}
+		reader.Reader = bufio.NewReader(dr)
+	}
+
+	alg := compression.Detect(peek(reader.Reader, compression.MagicSize))
+	if alg != compression.None {
+		cr, err := compression.NewReader(context.Background(), reader.Reader)
+		if err != nil {
+			return nil, FormatUnknown, fmt.Errorf("failed to decompress %s (wrong key?): %w", name, err)
+		}
+		reader.Reader = bufio.NewReader(cr)
+		reader.closers = append(reader.closers, cr)
+	}
+
+	lower := strings.ToLower(name)
+	switch {
+	case string(peek(reader.Reader, 5)) == "PGDMP":
+		return reader, postgresDumpFormats[alg], nil
+	case meta != nil && (meta.DatabaseType == "mysql" || meta.DatabaseType == "mariadb"),
+		strings.Contains(lower, "mysql"), strings.Contains(lower, "mariadb"):
+		return reader, mysqlSQLFormats[alg], nil
+	case strings.HasSuffix(compression.TrimExtension(lower), ".tar"):
+		reader.Close()
+		return nil, FormatUnknown, fmt.Errorf("%s is a cluster archive; streaming restore supports single database backups only", name)
+	}
+	return reader, postgresSQLFormats[alg], nil
+}
+
+// peek returns up to n bytes from the start of a buffered stream without consuming them
+func peek(r io.Reader, n int) []byte {
+	header, _ := r.(*bufio.Reader).Peek(n)
+	return header
+}
+
+// verifyHoldback is how much of a stream checksumReader keeps back until the
+// checksum has been compared. Encrypted streams end with an authenticated
+// final chunk smaller than this, so a truncated stream can't decrypt either.
+const verifyHoldback = 256 * 1024
+
+// checksumReader computes the SHA-256 of a stream while it is read, and
+// withholds the last verifyHoldback bytes until the whole stream has been
+// hashed. On a mismatch it calls abort and fails instead of releasing them.
+type checksumReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	expected string
+	abort    func()
+	held     bytes.Buffer
+	buf      []byte
+	eof      bool
+	err      error
+}
+
+func newChecksumReader(r io.Reader, expected string, abort func()) *checksumReader {
+	return &checksumReader{
+		r:        r,
+		hash:     sha256.New(),
+		expected: strings.ToLower(expected),
+		abort:    abort,
+		buf:      make([]byte, 64*1024),
+	}
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	holdback := 0
+	if c.expected != "" {
+		holdback = verifyHoldback
+	}
+
+	for c.err == nil && !c.eof && c.held.Len() <= holdback {
+		n, err := c.r.Read(c.buf)
+		c.hash.Write(c.buf[:n])
+		c.held.Write(c.buf[:n])
+		if err == io.EOF {
+			c.eof = true
+			c.verify()
+		} else if err != nil {
+			c.err = err
+		}
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	available := c.held.Len()
+	if !c.eof {
+		available -= holdback
+	}
+	if available <= 0 {
+		return 0, io.EOF
+	}
+	if len(p) > available {
+		p = p[:available]
+	}
+	return c.held.Read(p)
+}
+
+// verify compares the checksum once the stream has been read to the end
+func (c *checksumReader) verify() {
+	if c.expected == "" {
+		return
+	}
+	if actual := hex.EncodeToString(c.hash.Sum(nil)); actual != c.expected {
+		c.err = fmt.Errorf("checksum mismatch: expected %s, got %s", c.expected, actual)
+		c.held.Reset()
+		if c.abort != nil {
+			c.abort()
+		}
+	}
+}
+
+// finish reads whatever the consumer left unread, so the checksum covers the
+// whole stream, and returns any error (including a mismatch)
+func (c *checksumReader) finish() error {
+	if _, err := io.Copy(io.Discard, c); err != nil {
+		return err
+	}
+	return c.err
+}
+
+// mismatch returns the checksum error, if the stream didn't match
+func (c *checksumReader) mismatch() error {
+	if c.eof {
+		return c.err
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package restore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"dbbackup/internal/config"
+	"dbbackup/internal/crypto"
+	"dbbackup/internal/encryption"
+	"dbbackup/internal/logger"
+	"dbbackup/internal/metadata"
+)
+
+func TestChecksumReader(t *testing.T) {
+	data := bytes.Repeat([]byte("COPY public.t FROM stdin;\n"), 50000)
+	sum := sha256.Sum256(data)
+
+	aborted := false
+	r := newChecksumReader(bytes.NewReader(data), hex.EncodeToString(sum[:]), func() { aborted = true })
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, data) || aborted {
+		t.Errorf("Expected the stream unchanged and not aborted (%d of %d bytes, aborted %v)", len(got), len(data), aborted)
+	}
+}
+
+func TestChecksumReaderMismatch(t *testing.T) {
+	data := bytes.Repeat([]byte("COPY public.t FROM stdin;\n"), 50000)
+
+	aborted := false
+	r := newChecksumReader(bytes.NewReader(data), strings.Repeat("0", 64), func() { aborted = true })
+	got, err := io.ReadAll(r)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Expected checksum mismatch, got %v", err)
+	}
+	if !aborted {
+		t.Error("Expected abort to be called on mismatch")
+	}
+	// The consumer must never see the end of the stream
+	if len(got) > len(data)-verifyHoldback {
+		t.Errorf("Released %d bytes of %d before the checksum was verified", len(got), len(data))
+	}
+	if r.mismatch() == nil {
+		t.Error("Expected mismatch() to report the error")
+	}
+}
+
+func TestChecksumReaderFinish(t *testing.T) {
+	data := bytes.Repeat([]byte{1}, 3*verifyHoldback)
+	sum := sha256.Sum256(data)
+
+	// A consumer that stops early still gets the whole stream verified
+	r := newChecksumReader(bytes.NewReader(data), hex.EncodeToString(sum[:]), nil)
+	if _, err := r.Read(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.finish(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestOpenStream(t *testing.T) {
+	e := NewSilent(&config.Config{}, logger.NewSilent(), nil)
+	script := []byte("CREATE TABLE t (id int);\n")
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(script)
+	gz.Close()
+
+	key := bytes.Repeat([]byte{9}, crypto.KeySize)
+	var encrypted bytes.Buffer
+	ew, err := encryption.NewEncryptionWriter(&encrypted, encryption.EncryptionOptions{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ew.Write([]byte("PGDMP\x01\x0e\x00"))
+	ew.Close()
+
+	tests := []struct {
+		name   string
+		object string
+		data   []byte
+		meta   *metadata.BackupMetadata
+		key    []byte
+		want   ArchiveFormat
+	}{
+		{"gzipped SQL", "db_app_20250101_120000.sql.gz", compressed.Bytes(), nil, nil, FormatPostgreSQLSQLGz},
+		{"MySQL by metadata", "db_app_20250101_120000.sql.gz", compressed.Bytes(), &metadata.BackupMetadata{DatabaseType: "mysql"}, nil, FormatMySQLSQLGz},
+		{"encrypted custom dump", "db_app_20250101_120000.dump", encrypted.Bytes(), nil, key, FormatPostgreSQLDump},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e.encryption = nil
+			if tt.key != nil {
+				e.encryption = &encryption.EncryptionOptions{Key: tt.key}
+			}
+
+			r, format, err := e.openStream(bytes.NewReader(tt.data), tt.object, tt.meta)
+			if err != nil {
+				t.Fatalf("openStream failed: %v", err)
+			}
+			defer r.Close()
+
+			if format != tt.want {
+				t.Errorf("Format = %s, want %s", format, tt.want)
+			}
+			if got, _ := io.ReadAll(r); len(got) == 0 {
+				t.Error("Expected decoded content")
+			}
+		})
+	}
+
+	e.encryption = nil
+	if _, _, err := e.openStream(bytes.NewReader(encrypted.Bytes()), "db_app.dump", nil); err == nil {
+		t.Error("Expected error for an encrypted stream without a key")
+	}
+}
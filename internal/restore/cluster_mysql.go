@@ -0,0 +1,75 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// restoreMySQLGrants replays users and grants from a MySQL cluster backup.
+// Statements run with --force so accounts that already exist don't abort the rest.
+func (e *Engine) restoreMySQLGrants(ctx context.Context, globalsFile string) error {
+	file, err := e.openArchive(globalsFile, false)
+	if err != nil {
+		return fmt.Errorf("failed to open globals file: %w", err)
+	}
+	defer file.Close()
+
+	cmd := e.mysqlCommand(ctx, "--force")
+	cmd.Stdin = file
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to restore users and grants: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// recreateMySQLDatabase drops and recreates a schema (clean slate)
+func (e *Engine) recreateMySQLDatabase(ctx context.Context, dbName string) error {
+	name := strings.ReplaceAll(dbName, "`", "``")
+	query := fmt.Sprintf("DROP DATABASE IF EXISTS `%s`; CREATE DATABASE `%s`", name, name)
+
+	output, err := e.mysqlCommand(ctx, "-e", query).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to recreate database '%s': %w (output: %s)", dbName, err, strings.TrimSpace(string(output)))
+	}
+
+	e.log.Info("Recreated database", "name", dbName)
+	return nil
+}
+
+// ensureMySQLDatabase creates a schema unless it already exists
+func (e *Engine) ensureMySQLDatabase(ctx context.Context, dbName string) error {
+	query := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", strings.ReplaceAll(dbName, "`", "``"))
+
+	output, err := e.mysqlCommand(ctx, "-e", query).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to create database '%s': %w (output: %s)", dbName, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// mysqlCommand builds a mysql client command with connection settings
+func (e *Engine) mysqlCommand(ctx context.Context, extraArgs ...string) *exec.Cmd {
+	args := []string{
+		"-P", fmt.Sprintf("%d", e.cfg.Port),
+		"-u", e.cfg.User,
+	}
+	args = append(args, extraArgs...)
+
+	// Only add -h flag if host is not localhost (to use Unix socket)
+	if e.cfg.Host != "localhost" && e.cfg.Host != "127.0.0.1" && e.cfg.Host != "" {
+		args = append([]string{"-h", e.cfg.Host}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "mysql", args...)
+	cmd.Env = os.Environ()
+	if e.cfg.Password != "" {
+		cmd.Env = append(cmd.Env, "MYSQL_PWD="+e.cfg.Password)
+	}
+	return cmd
+}
@@ -0,0 +1,130 @@
+package restore
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"dbbackup/internal/metadata"
+)
+
+// databaseNamePattern restricts the names databases are restored under
+var databaseNamePattern = regexp.MustCompile(`^[A-Za-z0-9_$-]{1,63}$`)
+
+// ParseDatabaseMap parses --map values given as source=target, e.g.
+// "shop=shop_restored". Each database can be renamed once and no two
+// databases can be restored under the same name.
+func ParseDatabaseMap(specs []string) (map[string]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	renames := make(map[string]string, len(specs))
+	targets := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		source, target, ok := strings.Cut(spec, "=")
+		source = strings.TrimSpace(source)
+		target = strings.TrimSpace(target)
+		if !ok || source == "" || target == "" {
+			return nil, fmt.Errorf("invalid mapping %q: expected source=target (e.g. shop=shop_restored)", spec)
+		}
+		if !databaseNamePattern.MatchString(target) {
+			return nil, fmt.Errorf("invalid mapping %q: target names may only use letters, digits, _, $ and -", spec)
+		}
+		if _, dup := renames[source]; dup {
+			return nil, fmt.Errorf("database %s is mapped more than once", source)
+		}
+		if other, dup := targets[target]; dup {
+			return nil, fmt.Errorf("databases %s and %s are both mapped to %s", other, source, target)
+		}
+		renames[source] = target
+		targets[target] = source
+	}
+	return renames, nil
+}
+
+// SelectsDatabases reports whether only some databases of a cluster archive
+// are restored
+func (s Selection) SelectsDatabases() bool {
+	return len(s.Databases) > 0 || len(s.Map) > 0
+}
+
+// TargetName returns the name a database of a cluster archive is restored under
+func (s Selection) TargetName(database string) string {
+	if target, ok := s.Map[database]; ok {
+		return target
+	}
+	return database
+}
+
+// checkMapTargets refuses mapped databases whose target already exists,
+// unless the selection allows it
+func checkMapTargets(sel Selection, existing []string) error {
+	if sel.MapExisting {
+		return nil
+	}
+	sources := make([]string, 0, len(sel.Map))
+	for source := range sel.Map {
+		sources = append(sources, source)
+	}
+	slices.Sort(sources)
+	for _, source := range sources {
+		if target := sel.Map[source]; slices.Contains(existing, target) {
+			return fmt.Errorf("cannot restore %s as %s: database %s already exists (drop it, map to another name, or use --map-existing to restore on top of it)", source, target, target)
+		}
+	}
+	return nil
+}
+
+// ClusterTarget is a database of a cluster archive and where it is restored
+type ClusterTarget struct {
+	Source string
+	Target string
+}
+
+// PlanClusterRestore lists the databases of a cluster archive that the
+// selection restores, from the archive's .meta.json, so nothing needs to be
+// extracted to check the selection. It fails when the selection matches
+// nothing or maps a database the archive doesn't have, and, unless
+// sel.MapExisting is set, when a database is mapped to one of the existing
+// databases of the server (nil when they aren't known).
+func PlanClusterRestore(archivePath string, sel Selection, existing []string) ([]ClusterTarget, error) {
+	// Checked first, as it doesn't need the metadata
+	if err := checkMapTargets(sel, existing); err != nil {
+		return nil, err
+	}
+
+	meta, err := metadata.LoadCluster(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var available []string
+	var targets []ClusterTarget
+	for _, db := range meta.Databases {
+		available = append(available, db.Database)
+		if sel.IncludesDatabase(db.Database) {
+			targets = append(targets, ClusterTarget{Source: db.Database, Target: sel.TargetName(db.Database)})
+		}
+	}
+
+	for source := range sel.Map {
+		if !slices.Contains(available, source) {
+			return nil, fmt.Errorf("cannot map %s: the archive has no such database (available: %s)", source, strings.Join(available, ", "))
+		}
+		if !sel.IncludesDatabase(source) {
+			return nil, fmt.Errorf("cannot map %s: it is not selected for restore", source)
+		}
+	}
+	seen := make(map[string]string, len(targets))
+	for _, t := range targets {
+		if other, dup := seen[t.Target]; dup {
+			return nil, fmt.Errorf("databases %s and %s would both be restored as %s", other, t.Source, t.Target)
+		}
+		seen[t.Target] = t.Source
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no databases in the archive match %s (available: %s)", sel, strings.Join(available, ", "))
+	}
+	return targets, nil
+}
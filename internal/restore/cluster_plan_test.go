@@ -0,0 +1,82 @@
+package restore
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"dbbackup/internal/metadata"
+)
+
+func TestParseDatabaseMap(t *testing.T) {
+	renames, err := ParseDatabaseMap([]string{"shop=shop_restored", " crm = crm_2025 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renames["shop"] != "shop_restored" || renames["crm"] != "crm_2025" {
+		t.Errorf("Unexpected mapping: %v", renames)
+	}
+
+	for _, specs := range [][]string{
+		{"shop"},
+		{"=shop"},
+		{"shop=shop; DROP DATABASE x"},
+		{"shop=a", "shop=b"},
+		{"shop=restored", "crm=restored"},
+	} {
+		if _, err := ParseDatabaseMap(specs); err == nil {
+			t.Errorf("Expected %q to be rejected", specs)
+		}
+	}
+}
+
+func TestPlanClusterRestore(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "cluster_20250630_020000.tar.gz")
+	meta := &metadata.ClusterMetadata{
+		Databases: []metadata.BackupMetadata{{Database: "shop"}, {Database: "crm"}, {Database: "analytics"}},
+	}
+	if err := meta.Save(archivePath); err != nil {
+		t.Fatal(err)
+	}
+
+	// --map alone selects the mapped databases
+	targets, err := PlanClusterRestore(archivePath, Selection{Map: map[string]string{"shop": "shop_restored"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(targets, []ClusterTarget{{Source: "shop", Target: "shop_restored"}}) {
+		t.Errorf("Unexpected plan: %v", targets)
+	}
+
+	sel := Selection{Databases: []string{"shop", "crm"}, Map: map[string]string{"shop": "shop_restored"}}
+	targets, err = PlanClusterRestore(archivePath, sel, []string{"postgres", "shop", "crm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(targets, []ClusterTarget{{"shop", "shop_restored"}, {"crm", "crm"}}) {
+		t.Errorf("Unexpected plan: %v", targets)
+	}
+
+	for _, bad := range []Selection{
+		{Databases: []string{"billing"}},
+		{Map: map[string]string{"billing": "billing_restored"}},
+		{Databases: []string{"crm"}, Map: map[string]string{"shop": "shop_restored"}},
+		{Databases: []string{"shop", "crm"}, Map: map[string]string{"shop": "crm"}},
+	} {
+		if _, err := PlanClusterRestore(archivePath, bad, nil); err == nil {
+			t.Errorf("Expected %s to be rejected", bad)
+		}
+	}
+
+	// Mapping onto a database that exists needs MapExisting
+	existing := []string{"postgres", "shop", "shop_restored"}
+	sel = Selection{Map: map[string]string{"shop": "shop_restored"}}
+	if _, err := PlanClusterRestore(archivePath, sel, existing); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an existing target to be rejected, got %v", err)
+	}
+	sel.MapExisting = true
+	if _, err := PlanClusterRestore(archivePath, sel, existing); err != nil {
+		t.Errorf("Expected MapExisting to allow an existing target: %v", err)
+	}
+}
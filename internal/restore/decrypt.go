@@ -0,0 +1,98 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"dbbackup/internal/compression"
+	"dbbackup/internal/encryption"
+)
+
+// SetEncryption sets the key for archives written with streaming encryption.
+// Such archives are decrypted while they are read, so no plaintext copy is
+// written to disk.
+func (e *Engine) SetEncryption(opts *encryption.EncryptionOptions) {
+	e.encryption = opts
+}
+
+// isEncrypted reports whether a file was written with streaming encryption
+func (e *Engine) isEncrypted(path string) bool {
+	return encryption.IsEncryptedFile(path)
+}
+
+// openArchive opens a backup file for reading, decrypting it on the fly when
+// needed. With decompress the (decrypted) content is decompressed as well,
+// with the algorithm taken from its magic bytes.
+func (e *Engine) openArchive(path string, decompress bool) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	reader := &archiveReader{Reader: file, closers: []io.Closer{file}}
+
+	if encryption.IsEncryptedFile(path) {
+		if e.encryption == nil {
+			file.Close()
+			return nil, fmt.Errorf("%s is encrypted: provide the key with --encryption-key-file or --encryption-key-env", path)
+		}
+		dr, err := encryption.NewDecryptionReader(file, *e.encryption)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+		reader.Reader = dr
+	}
+
+	if decompress {
+		cr, err := compression.NewReader(context.Background(), reader.Reader)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to decompress %s (wrong key?): %w", path, err)
+		}
+		reader.Reader = cr
+		reader.closers = append([]io.Closer{cr}, reader.closers...)
+	}
+
+	return reader, nil
+}
+
+// archiveReader closes every layer of an opened archive
+type archiveReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *archiveReader) Close() error {
+	var firstErr error
+	for _, c := range r.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// executeRestoreFromArchive runs a restore command that reads the archive from stdin
+func (e *Engine) executeRestoreFromArchive(ctx context.Context, cmdArgs []string, archivePath string, decompress bool) error {
+	input, err := e.openArchive(archivePath, decompress)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	return e.executeRestoreCommandWithInput(ctx, cmdArgs, input)
+}
+
+// decompressCommand returns the shell command that decompresses an unencrypted
+// archive to stdout, for restore pipelines run through bash. The algorithm is
+// taken from the magic bytes, or the extension if the file can't be read.
+func decompressCommand(archivePath string) (string, error) {
+	alg := compression.DetectFile(archivePath)
+	if alg == compression.None {
+		alg = compression.FromExtension(archivePath)
+	}
+	return compression.ShellDecompressCommand(alg)
+}
@@ -3,17 +3,21 @@ package restore
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"dbbackup/internal/checks"
+	"dbbackup/internal/compression"
 	"dbbackup/internal/config"
 	"dbbackup/internal/database"
+	"dbbackup/internal/encryption"
 	"dbbackup/internal/logger"
 	"dbbackup/internal/progress"
 	"dbbackup/internal/security"
@@ -27,6 +31,10 @@ type Engine struct {
 	progress         progress.Indicator
 	detailedReporter *progress.DetailedReporter
 	dryRun           bool
+	encryption       *encryption.EncryptionOptions
+
+	// selection limits the restore to some databases, schemas or tables
+	selection Selection
 }
 
 // New creates a new restore engine
@@ -129,7 +137,7 @@ func (e *Engine) RestoreSingle(ctx context.Context, archivePath, targetDB string
 	e.log.Info("Detected archive format", "format", format, "path", archivePath)
 
 	// Check version compatibility for PostgreSQL dumps
-	if format == FormatPostgreSQLDump || format == FormatPostgreSQLDumpGz {
+	if format.IsPostgreSQLDump() {
 		if compatResult, err := e.CheckRestoreVersionCompatibility(ctx, archivePath); err == nil && compatResult != nil {
 			e.log.Info(compatResult.Message,
 				"source_version", compatResult.SourceVersion.Full,
@@ -170,13 +178,13 @@ func (e *Engine) RestoreSingle(ctx context.Context, archivePath, targetDB string
 
 	// Handle different archive formats
 	var err error
-	switch format {
-	case FormatPostgreSQLDump, FormatPostgreSQLDumpGz:
-		err = e.restorePostgreSQLDump(ctx, archivePath, targetDB, format == FormatPostgreSQLDumpGz, cleanFirst)
-	case FormatPostgreSQLSQL, FormatPostgreSQLSQLGz:
-		err = e.restorePostgreSQLSQL(ctx, archivePath, targetDB, format == FormatPostgreSQLSQLGz)
-	case FormatMySQLSQL, FormatMySQLSQLGz:
-		err = e.restoreMySQLSQL(ctx, archivePath, targetDB, format == FormatMySQLSQLGz)
+	switch {
+	case format.IsPostgreSQLDump():
+		err = e.restorePostgreSQLDump(ctx, archivePath, targetDB, format.IsCompressed(), cleanFirst)
+	case format.IsPostgreSQLSQL():
+		err = e.restorePostgreSQLSQL(ctx, archivePath, targetDB, format.IsCompressed())
+	case format.IsMySQL():
+		err = e.restoreMySQLSQL(ctx, archivePath, targetDB, format.IsCompressed())
 	default:
 		operation.Fail("Unsupported archive format")
 		return fmt.Errorf("unsupported archive format: %s", format)
@@ -196,13 +204,16 @@ func (e *Engine) RestoreSingle(ctx context.Context, archivePath, targetDB string
 // restorePostgreSQLDump restores from PostgreSQL custom dump format
 func (e *Engine) restorePostgreSQLDump(ctx context.Context, archivePath, targetDB string, compressed bool, cleanFirst bool) error {
 	// Build restore command
-	opts := database.RestoreOptions{
-		Parallel:          1,
-		Clean:             cleanFirst,
-		NoOwner:           true,
-		NoPrivileges:      true,
-		SingleTransaction: false, // CRITICAL: Disabled to prevent lock exhaustion with large objects
-		Verbose:           true,  // Enable verbose for single database restores (not cluster)
+	opts := singleRestoreOptions(cleanFirst)
+	cleanup, err := e.applySelection(ctx, archivePath, compressed, &opts)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if e.isEncrypted(archivePath) {
+		// Decrypt while streaming the archive into pg_restore's stdin
+		return e.executeRestoreFromArchive(ctx, e.db.BuildRestoreCommand(targetDB, "", opts), archivePath, compressed)
 	}
 
 	cmd := e.db.BuildRestoreCommand(targetDB, archivePath, opts)
@@ -215,6 +226,18 @@ func (e *Engine) restorePostgreSQLDump(ctx context.Context, archivePath, targetD
 	return e.executeRestoreCommand(ctx, cmd)
 }
 
+// singleRestoreOptions returns the pg_restore options for a single database restore
+func singleRestoreOptions(cleanFirst bool) database.RestoreOptions {
+	return database.RestoreOptions{
+		Parallel:          1,
+		Clean:             cleanFirst,
+		NoOwner:           true,
+		NoPrivileges:      true,
+		SingleTransaction: false, // CRITICAL: Disabled to prevent lock exhaustion with large objects
+		Verbose:           true,  // Enable verbose for single database restores (not cluster)
+	}
+}
+
 // restorePostgreSQLDumpWithOwnership restores from PostgreSQL custom dump with ownership control
 func (e *Engine) restorePostgreSQLDumpWithOwnership(ctx context.Context, archivePath, targetDB string, compressed bool, preserveOwnership bool) error {
 	// Build restore command with ownership control
@@ -233,6 +256,17 @@ func (e *Engine) restorePostgreSQLDumpWithOwnership(ctx context.Context, archive
 		"noOwner", opts.NoOwner,
 		"noPrivileges", opts.NoPrivileges)
 
+	cleanup, err := e.applySelection(ctx, archivePath, compressed, &opts)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if e.isEncrypted(archivePath) {
+		// Decrypt while streaming the archive into pg_restore's stdin
+		return e.executeRestoreFromArchive(ctx, e.db.BuildRestoreCommand(targetDB, "", opts), archivePath, compressed)
+	}
+
 	cmd := e.db.BuildRestoreCommand(targetDB, archivePath, opts)
 
 	if compressed {
@@ -245,6 +279,10 @@ func (e *Engine) restorePostgreSQLDumpWithOwnership(ctx context.Context, archive
 
 // restorePostgreSQLSQL restores from PostgreSQL SQL script
 func (e *Engine) restorePostgreSQLSQL(ctx context.Context, archivePath, targetDB string, compressed bool) error {
+	if e.selection.FiltersObjects() {
+		return fmt.Errorf("%s is a plain SQL dump: selecting tables or schemas needs a custom-format (.dump) backup", filepath.Base(archivePath))
+	}
+
 	// Use psql for SQL scripts
 	var cmd []string
 
@@ -254,7 +292,16 @@ func (e *Engine) restorePostgreSQLSQL(ctx context.Context, archivePath, targetDB
 		hostArg = fmt.Sprintf("-h %s -p %d", e.cfg.Host, e.cfg.Port)
 	}
 
+	if e.isEncrypted(archivePath) {
+		// Decrypt (and decompress) in-process and feed the script to psql's stdin
+		return e.executeRestoreFromArchive(ctx, e.psqlStdinCommand(targetDB), archivePath, compressed)
+	}
+
 	if compressed {
+		decompressCmd, err := decompressCommand(archivePath)
+		if err != nil {
+			return err
+		}
 		psqlCmd := fmt.Sprintf("psql -U %s -d %s", e.cfg.User, targetDB)
 		if hostArg != "" {
 			psqlCmd = fmt.Sprintf("psql %s -U %s -d %s", hostArg, e.cfg.User, targetDB)
@@ -262,7 +309,7 @@ func (e *Engine) restorePostgreSQLSQL(ctx context.Context, archivePath, targetDB
 		// Set PGPASSWORD in the bash command for password-less auth
 		cmd = []string{
 			"bash", "-c",
-			fmt.Sprintf("PGPASSWORD='%s' gunzip -c %s | %s", e.cfg.Password, archivePath, psqlCmd),
+			fmt.Sprintf("PGPASSWORD='%s' %s %s | %s", e.cfg.Password, decompressCmd, archivePath, psqlCmd),
 		}
 	} else {
 		if hostArg != "" {
@@ -287,28 +334,65 @@ func (e *Engine) restorePostgreSQLSQL(ctx context.Context, archivePath, targetDB
 	return e.executeRestoreCommand(ctx, cmd)
 }
 
+// psqlStdinCommand returns a psql command that runs the script on its stdin
+func (e *Engine) psqlStdinCommand(targetDB string) []string {
+	cmd := []string{"psql"}
+	// For localhost, omit -h to use Unix socket (avoids Ident auth issues)
+	if e.cfg.Host != "localhost" && e.cfg.Host != "" {
+		cmd = append(cmd, "-h", e.cfg.Host, "-p", fmt.Sprintf("%d", e.cfg.Port))
+	}
+	return append(cmd, "-U", e.cfg.User, "-d", targetDB)
+}
+
 // restoreMySQLSQL restores from MySQL SQL script
 func (e *Engine) restoreMySQLSQL(ctx context.Context, archivePath, targetDB string, compressed bool) error {
 	options := database.RestoreOptions{}
 
 	cmd := e.db.BuildRestoreCommand(targetDB, archivePath, options)
 
-	if compressed {
+	if e.selection.FiltersObjects() {
+		// Drop the unselected tables from the script on its way to mysql
+		input, err := e.openArchive(archivePath, compressed)
+		if err != nil {
+			return err
+		}
+		defer input.Close()
+
+		filtered := e.filteredMySQLInput(input)
+		defer filtered.Close()
+		return e.executeRestoreCommandWithInput(ctx, cmd, filtered)
+	}
+
+	if compressed && !e.isEncrypted(archivePath) {
 		// For compressed SQL, decompress on the fly
+		decompressCmd, err := decompressCommand(archivePath)
+		if err != nil {
+			return err
+		}
 		cmd = []string{
 			"bash", "-c",
-			fmt.Sprintf("gunzip -c %s | %s", archivePath, strings.Join(cmd, " ")),
+			fmt.Sprintf("%s %s | %s", decompressCmd, archivePath, strings.Join(cmd, " ")),
 		}
+		return e.executeRestoreCommand(ctx, cmd)
 	}
 
-	return e.executeRestoreCommand(ctx, cmd)
+	// mysql reads the script from stdin; encrypted dumps are decrypted on the way
+	return e.executeRestoreFromArchive(ctx, cmd, archivePath, compressed)
 }
 
 // executeRestoreCommand executes a restore command
 func (e *Engine) executeRestoreCommand(ctx context.Context, cmdArgs []string) error {
+	return e.executeRestoreCommandWithInput(ctx, cmdArgs, nil)
+}
+
+// executeRestoreCommandWithInput executes a restore command, feeding stdin to it when set
+func (e *Engine) executeRestoreCommandWithInput(ctx context.Context, cmdArgs []string, stdin io.Reader) error {
 	e.log.Info("Executing restore command", "command", strings.Join(cmdArgs, " "))
 
 	cmd := exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
 
 	// Set environment variables
 	cmd.Env = append(os.Environ(),
@@ -383,15 +467,15 @@ func (e *Engine) executeRestoreCommand(ctx context.Context, cmdArgs []string) er
 
 // executeRestoreWithDecompression handles decompression during restore
 func (e *Engine) executeRestoreWithDecompression(ctx context.Context, archivePath string, restoreCmd []string) error {
-	// Check if pigz is available for faster decompression
-	decompressCmd := "gunzip"
-	if _, err := exec.LookPath("pigz"); err == nil {
-		decompressCmd = "pigz"
-		e.log.Info("Using pigz for parallel decompression")
+	// pigz is used for gzip when available, for parallel decompression
+	decompressCmd, err := decompressCommand(archivePath)
+	if err != nil {
+		return err
 	}
+	e.log.Info("Using external decompression", "command", decompressCmd)
 
 	// Build pipeline: decompress | restore
-	pipeline := fmt.Sprintf("%s -dc %s | %s", decompressCmd, archivePath, strings.Join(restoreCmd, " "))
+	pipeline := fmt.Sprintf("%s %s | %s", decompressCmd, archivePath, strings.Join(restoreCmd, " "))
 	cmd := exec.CommandContext(ctx, "bash", "-c", pipeline)
 
 	cmd.Env = append(os.Environ(),
@@ -477,17 +561,20 @@ func (e *Engine) previewRestore(archivePath, targetDB string, format ArchiveForm
 	}
 	fmt.Printf("Target Database: %s\n", targetDB)
 	fmt.Printf("Target Host: %s:%d\n", e.cfg.Host, e.cfg.Port)
+	if e.selection.FiltersObjects() {
+		fmt.Printf("Selection: %s\n", e.selection)
+	}
 
 	fmt.Println("\nOperations that would be performed:")
-	switch format {
-	case FormatPostgreSQLDump:
+	switch {
+	case format == FormatPostgreSQLDump:
 		fmt.Printf("  1. Execute: pg_restore -d %s %s\n", targetDB, archivePath)
-	case FormatPostgreSQLDumpGz:
-		fmt.Printf("  1. Decompress: %s\n", archivePath)
+	case format.IsPostgreSQLDump():
+		fmt.Printf("  1. Decompress (%s): %s\n", format.Compression(), archivePath)
 		fmt.Printf("  2. Execute: pg_restore -d %s\n", targetDB)
-	case FormatPostgreSQLSQL, FormatPostgreSQLSQLGz:
+	case format.IsPostgreSQLSQL():
 		fmt.Printf("  1. Execute: psql -d %s -f %s\n", targetDB, archivePath)
-	case FormatMySQLSQL, FormatMySQLSQLGz:
+	case format.IsMySQL():
 		fmt.Printf("  1. Execute: mysql %s < %s\n", targetDB, archivePath)
 	}
 
@@ -499,7 +586,7 @@ func (e *Engine) previewRestore(archivePath, targetDB string, format ArchiveForm
 	return nil
 }
 
-// RestoreCluster restores a full cluster from a tar.gz archive
+// RestoreCluster restores a full cluster from a tar archive (gzip, zstd, lz4 or uncompressed)
 func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 	operation := e.log.StartOperation("Cluster Restore")
 
@@ -526,7 +613,7 @@ func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 	}
 
 	format := DetectArchiveFormat(archivePath)
-	if format != FormatClusterTarGz {
+	if !format.IsClusterBackup() {
 		operation.Fail("Invalid cluster archive format")
 		return fmt.Errorf("not a cluster archive: %s (detected format: %s)", archivePath, format)
 	}
@@ -562,51 +649,36 @@ func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 		operation.Fail("Failed to create temporary directory")
 		return fmt.Errorf("failed to create temp directory: %w", err)
 	}
-	defer os.RemoveAll(tempDir)
+	// Once the journal is written the extraction is kept until every
+	// database is restored, so a failed restore can be resumed from it
+	var journal *Journal
+	defer func() {
+		if journal == nil {
+			os.RemoveAll(tempDir)
+		}
+	}()
 
 	// Extract archive
-	e.log.Info("Extracting cluster archive", "archive", archivePath, "tempDir", tempDir)
-	if err := e.extractArchive(ctx, archivePath, tempDir); err != nil {
-		operation.Fail("Archive extraction failed")
-		return fmt.Errorf("failed to extract archive: %w", err)
-	}
-
-	// Check if user has superuser privileges (required for ownership restoration)
-	e.progress.Update("Checking privileges...")
-	isSuperuser, err := e.checkSuperuser(ctx)
-	if err != nil {
-		e.log.Warn("Could not verify superuser status", "error", err)
-		isSuperuser = false // Assume not superuser if check fails
-	}
-
-	if !isSuperuser {
-		e.log.Warn("Current user is not a superuser - database ownership may not be fully restored")
-		e.progress.Update("⚠️  Warning: Non-superuser - ownership restoration limited")
-		time.Sleep(2 * time.Second) // Give user time to see warning
-	} else {
-		e.log.Info("Superuser privileges confirmed - full ownership restoration enabled")
-	}
-
-	// Restore global objects FIRST (roles, tablespaces) - CRITICAL for ownership
-	globalsFile := filepath.Join(tempDir, "globals.sql")
-	if _, err := os.Stat(globalsFile); err == nil {
-		e.log.Info("Restoring global objects (roles, tablespaces)")
-		e.progress.Update("Restoring global objects (roles, tablespaces)...")
-		if err := e.restoreGlobals(ctx, globalsFile); err != nil {
-			e.log.Error("Failed to restore global objects", "error", err)
-			if isSuperuser {
-				// If we're superuser and can't restore globals, this is a problem
-				e.progress.Fail("Failed to restore global objects")
-				operation.Fail("Global objects restoration failed")
-				return fmt.Errorf("failed to restore global objects: %w", err)
-			} else {
-				e.log.Warn("Continuing without global objects (may cause ownership issues)")
-			}
-		} else {
-			e.log.Info("Successfully restored global objects")
+	if e.selection.SelectsDatabases() {
+		// Only the selected dumps are written to disk
+		e.log.Info("Extracting selected databases from cluster archive", "archive", archivePath,
+			"selection", e.selection.String(), "tempDir", tempDir)
+		databases, err := e.extractSelected(ctx, archivePath, tempDir)
+		if err != nil {
+			operation.Fail("Archive extraction failed")
+			return fmt.Errorf("failed to extract archive: %w", err)
+		}
+		if !slices.ContainsFunc(databases, e.selection.IncludesDatabase) {
+			operation.Fail("No databases selected")
+			return fmt.Errorf("no databases in the archive match %s (available: %s)",
+				e.selection, strings.Join(databases, ", "))
 		}
 	} else {
-		e.log.Warn("No globals.sql file found in backup - roles and tablespaces will not be restored")
+		e.log.Info("Extracting cluster archive", "archive", archivePath, "tempDir", tempDir)
+		if err := e.extractArchive(ctx, archivePath, tempDir); err != nil {
+			operation.Fail("Archive extraction failed")
+			return fmt.Errorf("failed to extract archive: %w", err)
+		}
 	}
 
 	// Restore individual databases
@@ -622,16 +694,114 @@ func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 		return fmt.Errorf("failed to read dumps directory: %w", err)
 	}
 
-	var failedDBs []string
-	totalDBs := 0
+	journal, err = newJournal(archivePath, tempDir, e.selection, entries)
+	if err != nil {
+		operation.Fail("Failed to write restore journal")
+		return err
+	}
+	e.log.Info("Restore journal written", "journal", journal.Path())
 
-	// Count total databases
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			totalDBs++
+	return e.restoreClusterDatabases(ctx, operation, journal)
+}
+
+// ResumeCluster continues a cluster restore from its journal. Databases that
+// were restored are skipped; failed, pending and interrupted ones are
+// restored again from the extraction the journal kept.
+func (e *Engine) ResumeCluster(ctx context.Context, journalPath string) error {
+	operation := e.log.StartOperation("Cluster Restore (resume)")
+
+	journal, err := LoadJournal(journalPath)
+	if err != nil {
+		operation.Fail("Failed to read restore journal")
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(journal.ExtractDir, "dumps")); err != nil {
+		operation.Fail("Extraction not found")
+		return fmt.Errorf("extraction of the journaled restore is gone (%s): start a new restore from %s", journal.ExtractDir, journal.Archive)
+	}
+
+	// The selection of the original run applies
+	e.selection = journal.Selection
+	remaining := journal.Remaining()
+	e.log.Info("Resuming cluster restore", "archive", journal.Archive, "extraction", journal.ExtractDir,
+		"restored", len(journal.Databases)-len(remaining), "remaining", len(remaining))
+
+	e.progress.Start(fmt.Sprintf("Resuming cluster restore from %s", filepath.Base(journal.Archive)))
+	return e.restoreClusterDatabases(ctx, operation, journal)
+}
+
+// restoreClusterDatabases restores the global objects and every database of
+// the journal that isn't restored yet, recording each outcome. The extraction
+// and journal are removed when all are done.
+func (e *Engine) restoreClusterDatabases(ctx context.Context, operation logger.OperationLogger, journal *Journal) error {
+	tempDir := journal.ExtractDir
+	dumpsDir := filepath.Join(tempDir, "dumps")
+	entries, err := os.ReadDir(dumpsDir)
+	if err != nil {
+		operation.Fail("Failed to read dumps directory")
+		return fmt.Errorf("failed to read dumps directory: %w", err)
+	}
+
+	// Check if user has superuser privileges (required for ownership restoration)
+	isSuperuser := false
+	if e.cfg.IsPostgreSQL() {
+		e.progress.Update("Checking privileges...")
+		isSuperuser, err = e.checkSuperuser(ctx)
+		if err != nil {
+			e.log.Warn("Could not verify superuser status", "error", err)
+			isSuperuser = false // Assume not superuser if check fails
+		}
+
+		if !isSuperuser {
+			e.log.Warn("Current user is not a superuser - database ownership may not be fully restored")
+			e.progress.Update("⚠️  Warning: Non-superuser - ownership restoration limited")
+			time.Sleep(2 * time.Second) // Give user time to see warning
+		} else {
+			e.log.Info("Superuser privileges confirmed - full ownership restoration enabled")
 		}
 	}
 
+	// Restore global objects FIRST (roles, tablespaces / users, grants) - CRITICAL for ownership
+	globalsFile := filepath.Join(tempDir, "globals.sql")
+	restoreGlobals, globalsLabel := e.restoreGlobals, "roles, tablespaces"
+	if e.cfg.IsMySQL() {
+		restoreGlobals, globalsLabel = e.restoreMySQLGrants, "users, grants"
+	}
+	switch {
+	case journal.Globals == JournalSkipped:
+		e.log.Info(fmt.Sprintf("Skipping global objects (%s) - only selected databases are restored", globalsLabel))
+	case journal.Globals == JournalDone:
+		e.log.Info(fmt.Sprintf("Global objects (%s) already restored", globalsLabel))
+	default:
+		if _, err := os.Stat(globalsFile); err == nil {
+			e.log.Info(fmt.Sprintf("Restoring global objects (%s)", globalsLabel))
+			e.progress.Update(fmt.Sprintf("Restoring global objects (%s)...", globalsLabel))
+			if err := restoreGlobals(ctx, globalsFile); err != nil {
+				e.log.Error("Failed to restore global objects", "error", err)
+				journal.SetGlobals(JournalFailed)
+				if isSuperuser {
+					// If we're superuser and can't restore globals, this is a problem
+					e.progress.Fail("Failed to restore global objects")
+					operation.Fail("Global objects restoration failed")
+					return fmt.Errorf("failed to restore global objects: %w (resume with --resume %s)", err, journal.Path())
+				} else {
+					e.log.Warn("Continuing without global objects (may cause ownership issues)")
+				}
+			} else {
+				e.log.Info("Successfully restored global objects")
+				journal.SetGlobals(JournalDone)
+			}
+		} else {
+			e.log.Warn(fmt.Sprintf("No globals.sql file found in backup - %s will not be restored", globalsLabel))
+			journal.SetGlobals(JournalSkipped)
+		}
+	}
+
+	var failedDBs []string
+	// Databases restored by an earlier run are skipped
+	remaining := journal.Remaining()
+	totalDBs := len(remaining)
+
 	// Create ETA estimator for database restores
 	estimator := progress.NewETAEstimator("Restoring cluster", totalDBs)
 	e.progress.SetEstimator(estimator)
@@ -663,16 +833,16 @@ func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 	semaphore := make(chan struct{}, parallelism)
 	var wg sync.WaitGroup
 
-	dbIndex := 0
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	for dbIndex, entry := range remaining {
+		// After a cancellation the rest stays pending in the journal
+		if ctx.Err() != nil {
+			break
 		}
 
 		wg.Add(1)
 		semaphore <- struct{}{} // Acquire
 
-		go func(idx int, filename string) {
+		go func(idx int, entry JournalDatabase) {
 			defer wg.Done()
 			defer func() { <-semaphore }() // Release
 
@@ -681,28 +851,101 @@ func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 			estimator.UpdateProgress(idx)
 			mu.Unlock()
 
-			dumpFile := filepath.Join(dumpsDir, filename)
-			dbName := filename
-			dbName = strings.TrimSuffix(dbName, ".dump")
-			dbName = strings.TrimSuffix(dbName, ".sql.gz")
+			dumpFile := filepath.Join(dumpsDir, entry.File)
+			dbName := entry.Name
+			// Renamed databases are restored next to the original, which stays untouched
+			targetDB := entry.Target
+			keepExisting := e.selection.FiltersObjects() || targetDB != dbName
+
+			// Record the outcome in the journal
+			entry, err := journal.Start(dbName)
+			if err != nil {
+				e.log.Warn("Failed to update restore journal", "error", err)
+			}
+			var dbErr error
+			defer func() {
+				if err := journal.Finish(dbName, dbErr); err != nil {
+					e.log.Warn("Failed to update restore journal", "error", err)
+				}
+			}()
+			// The first attempt records whether it creates the renamed
+			// database, and doesn't restore over one that already exists
+			// unless the selection allows it
+			renamed := targetDB != dbName && !e.selection.FiltersObjects()
+			if renamed && entry.Attempts == 1 {
+				exists, err := e.databaseExists(ctx, targetDB)
+				if err == nil && exists && !e.selection.MapExisting {
+					err = fmt.Errorf("database %s already exists (use --map-existing to restore on top of it)", targetDB)
+				}
+				if err != nil {
+					dbErr = err
+					e.log.Error("Refusing to restore renamed database", "name", dbName, "target", targetDB, "error", err)
+					failedDBsMu.Lock()
+					failedDBs = append(failedDBs, fmt.Sprintf("%s: %v", dbName, err))
+					failedDBsMu.Unlock()
+					atomic.AddInt32(&failCount, 1)
+					return
+				}
+				if !exists {
+					if err := journal.SetCreated(dbName); err != nil {
+						e.log.Warn("Failed to update restore journal", "error", err)
+					}
+					entry.Created = true
+				}
+			}
+			// A renamed database left over from a failed attempt is started
+			// over, but only when this restore created it
+			retryRenamed := entry.Attempts > 1 && renamed && entry.Created
 
 			dbProgress := 15 + int(float64(idx)/float64(totalDBs)*85.0)
 
 			mu.Lock()
 			statusMsg := fmt.Sprintf("Restoring database %s (%d/%d)", dbName, idx+1, totalDBs)
+			if targetDB != dbName {
+				statusMsg = fmt.Sprintf("Restoring database %s as %s (%d/%d)", dbName, targetDB, idx+1, totalDBs)
+			}
 			e.progress.Update(statusMsg)
-			e.log.Info("Restoring database", "name", dbName, "file", dumpFile, "progress", dbProgress)
+			e.log.Info("Restoring database", "name", dbName, "target", targetDB, "file", dumpFile, "progress", dbProgress)
 			mu.Unlock()
 
-			// STEP 1: Drop existing database completely (clean slate)
-			e.log.Info("Dropping existing database for clean restore", "name", dbName)
-			if err := e.dropDatabaseIfExists(ctx, dbName); err != nil {
-				e.log.Warn("Could not drop existing database", "name", dbName, "error", err)
+			if e.cfg.IsMySQL() {
+				prepare := e.recreateMySQLDatabase
+				if keepExisting && !retryRenamed {
+					// Restoring some tables into the existing database, or next to it
+					prepare = e.ensureMySQLDatabase
+				}
+				restoreErr := prepare(ctx, targetDB)
+				if restoreErr == nil {
+					restoreErr = e.restoreMySQLSQL(ctx, dumpFile, targetDB, compression.FromExtension(dumpFile) != compression.None)
+				}
+				if restoreErr != nil {
+					dbErr = restoreErr
+					mu.Lock()
+					e.log.Error("Failed to restore database", "name", dbName, "file", dumpFile, "error", restoreErr)
+					mu.Unlock()
+					failedDBsMu.Lock()
+					failedDBs = append(failedDBs, fmt.Sprintf("%s: restore failed: %v", dbName, restoreErr))
+					failedDBsMu.Unlock()
+					atomic.AddInt32(&failCount, 1)
+					return
+				}
+				atomic.AddInt32(&successCount, 1)
+				return
+			}
+
+			// STEP 1: Drop existing database completely (clean slate), unless
+			// only some of its objects are restored or it is restored under a new name
+			if !keepExisting || retryRenamed {
+				e.log.Info("Dropping existing database for clean restore", "name", targetDB)
+				if err := e.dropDatabaseIfExists(ctx, targetDB); err != nil {
+					e.log.Warn("Could not drop existing database", "name", targetDB, "error", err)
+				}
 			}
 
 			// STEP 2: Create fresh database
-			if err := e.ensureDatabaseExists(ctx, dbName); err != nil {
-				e.log.Error("Failed to create database", "name", dbName, "error", err)
+			if err := e.ensureDatabaseExists(ctx, targetDB); err != nil {
+				dbErr = err
+				e.log.Error("Failed to create database", "name", targetDB, "error", err)
 				failedDBsMu.Lock()
 				failedDBs = append(failedDBs, fmt.Sprintf("%s: failed to create database: %v", dbName, err))
 				failedDBsMu.Unlock()
@@ -712,22 +955,24 @@ func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 
 			// STEP 3: Restore with ownership preservation if superuser
 			preserveOwnership := isSuperuser
-			isCompressedSQL := strings.HasSuffix(dumpFile, ".sql.gz")
+			isCompressedSQL := compression.FromExtension(dumpFile) != compression.None &&
+				strings.HasSuffix(compression.TrimExtension(dumpFile), ".sql")
 
 			var restoreErr error
 			if isCompressedSQL {
 				mu.Lock()
-				e.log.Info("Detected compressed SQL format, using psql + gunzip", "file", dumpFile, "database", dbName)
+				e.log.Info("Detected compressed SQL format, using psql + decompression", "file", dumpFile, "database", targetDB)
 				mu.Unlock()
-				restoreErr = e.restorePostgreSQLSQL(ctx, dumpFile, dbName, true)
+				restoreErr = e.restorePostgreSQLSQL(ctx, dumpFile, targetDB, true)
 			} else {
 				mu.Lock()
-				e.log.Info("Detected custom dump format, using pg_restore", "file", dumpFile, "database", dbName)
+				e.log.Info("Detected custom dump format, using pg_restore", "file", dumpFile, "database", targetDB)
 				mu.Unlock()
-				restoreErr = e.restorePostgreSQLDumpWithOwnership(ctx, dumpFile, dbName, false, preserveOwnership)
+				restoreErr = e.restorePostgreSQLDumpWithOwnership(ctx, dumpFile, targetDB, false, preserveOwnership)
 			}
 
 			if restoreErr != nil {
+				dbErr = restoreErr
 				mu.Lock()
 				e.log.Error("Failed to restore database", "name", dbName, "file", dumpFile, "error", restoreErr)
 				mu.Unlock()
@@ -757,9 +1002,7 @@ func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 			}
 
 			atomic.AddInt32(&successCount, 1)
-		}(dbIndex, entry.Name())
-
-		dbIndex++
+		}(dbIndex, entry)
 	}
 
 	// Wait for all restores to complete
@@ -768,6 +1011,12 @@ func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 	successCountFinal := int(atomic.LoadInt32(&successCount))
 	failCountFinal := int(atomic.LoadInt32(&failCount))
 
+	if err := ctx.Err(); err != nil {
+		operation.Fail("Cluster restore interrupted")
+		return fmt.Errorf("cluster restore interrupted after %d of %d databases: %w (continue with --resume %s)",
+			successCountFinal, totalDBs, err, journal.Path())
+	}
+
 	if failCountFinal > 0 {
 		failedList := strings.Join(failedDBs, "\n  ")
 		
@@ -780,7 +1029,13 @@ func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 		e.progress.Fail(fmt.Sprintf("Cluster restore: %d succeeded, %d failed out of %d total", successCountFinal, failCountFinal, totalDBs))
 		operation.Complete(fmt.Sprintf("Partial restore: %d/%d databases succeeded", successCountFinal, totalDBs))
 		
-		return fmt.Errorf("cluster restore completed with %d failures:\n  %s", failCountFinal, failedList)
+		return fmt.Errorf("cluster restore completed with %d failures:\n  %s\nThe extraction is kept: retry the failed databases with --resume %s",
+			failCountFinal, failedList, journal.Path())
+	}
+
+	// Every database is restored: the extraction and journal aren't needed anymore
+	if err := journal.Complete(); err != nil {
+		e.log.Warn("Failed to clean up after restore", "error", err)
 	}
 
 	e.progress.Complete(fmt.Sprintf("Cluster restored successfully: %d databases", successCountFinal))
@@ -788,9 +1043,27 @@ func (e *Engine) RestoreCluster(ctx context.Context, archivePath string) error {
 	return nil
 }
 
-// extractArchive extracts a tar.gz archive
+// extractArchive extracts a cluster archive, compressed or not
 func (e *Engine) extractArchive(ctx context.Context, archivePath, destDir string) error {
-	cmd := exec.CommandContext(ctx, "tar", "-xzf", archivePath, "-C", destDir)
+	cmd := exec.CommandContext(ctx, "tar", "-xf", archivePath, "-C", destDir)
+
+	// Compressed archives are decompressed in front of tar, whatever the algorithm
+	if alg := compression.DetectFile(archivePath); alg != compression.None {
+		file, err := os.Open(archivePath)
+		if err != nil {
+			return fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer file.Close()
+
+		reader, err := compression.NewReader(ctx, file)
+		if err != nil {
+			return fmt.Errorf("failed to decompress archive: %w", err)
+		}
+		defer reader.Close()
+
+		cmd = exec.CommandContext(ctx, "tar", "-xf", "-", "-C", destDir)
+		cmd.Stdin = reader
+	}
 
 	// Stream stderr to avoid memory issues - tar can produce lots of output for large archives
 	stderr, err := cmd.StderrPipe()
@@ -823,7 +1096,6 @@ func (e *Engine) restoreGlobals(ctx context.Context, globalsFile string) error {
 		"-p", fmt.Sprintf("%d", e.cfg.Port),
 		"-U", e.cfg.User,
 		"-d", "postgres",
-		"-f", globalsFile,
 	}
 
 	// Only add -h flag if host is not localhost (to use Unix socket for peer auth)
@@ -831,7 +1103,22 @@ func (e *Engine) restoreGlobals(ctx context.Context, globalsFile string) error {
 		args = append([]string{"-h", e.cfg.Host}, args...)
 	}
 
+	// Encrypted globals are decrypted on the fly and piped into psql
+	var input io.ReadCloser
+	if e.isEncrypted(globalsFile) {
+		var err error
+		if input, err = e.openArchive(globalsFile, false); err != nil {
+			return err
+		}
+		defer input.Close()
+	} else {
+		args = append(args, "-f", globalsFile)
+	}
+
 	cmd := exec.CommandContext(ctx, "psql", args...)
+	if input != nil {
+		cmd.Stdin = input
+	}
 
 	cmd.Env = append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", e.cfg.Password))
 
@@ -969,6 +1256,33 @@ func (e *Engine) dropDatabaseIfExists(ctx context.Context, dbName string) error
 	return nil
 }
 
+// databaseExists reports whether a database exists on the target server
+func (e *Engine) databaseExists(ctx context.Context, dbName string) (bool, error) {
+	name := strings.ReplaceAll(dbName, "'", "''")
+	var cmd *exec.Cmd
+	if e.cfg.IsMySQL() {
+		cmd = e.mysqlCommand(ctx, "-N", "-e", fmt.Sprintf("SELECT 1 FROM information_schema.schemata WHERE schema_name = '%s'", name))
+	} else {
+		args := []string{
+			"-p", fmt.Sprintf("%d", e.cfg.Port),
+			"-U", e.cfg.User,
+			"-d", "postgres",
+			"-tAc", fmt.Sprintf("SELECT 1 FROM pg_database WHERE datname = '%s'", name),
+		}
+		if e.cfg.Host != "localhost" && e.cfg.Host != "127.0.0.1" && e.cfg.Host != "" {
+			args = append([]string{"-h", e.cfg.Host}, args...)
+		}
+		cmd = exec.CommandContext(ctx, "psql", args...)
+		cmd.Env = append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", e.cfg.Password))
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether database %s exists: %w (output: %s)", dbName, err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)) == "1", nil
+}
+
 // ensureDatabaseExists checks if a database exists and creates it if not
 func (e *Engine) ensureDatabaseExists(ctx context.Context, dbName string) error {
 	// Skip creation for postgres and template databases - they should already exist
@@ -1062,8 +1376,31 @@ func (e *Engine) previewClusterRestore(archivePath string) error {
 
 	fmt.Println("\nOperations that would be performed:")
 	fmt.Println("  1. Extract cluster archive to temporary directory")
-	fmt.Println("  2. Restore global objects (roles, tablespaces)")
-	fmt.Println("  3. Restore all databases found in archive")
+	switch {
+	case e.selection.SkipGlobals:
+		fmt.Println("  2. Skip global objects")
+	case e.cfg.IsMySQL():
+		fmt.Println("  2. Restore global objects (users, grants)")
+	default:
+		fmt.Println("  2. Restore global objects (roles, tablespaces)")
+	}
+	if targets, err := PlanClusterRestore(archivePath, e.selection, nil); err == nil && e.selection.SelectsDatabases() {
+		fmt.Println("  3. Restore selected databases:")
+		for _, t := range targets {
+			if t.Target != t.Source {
+				fmt.Printf("     - %s as %s (original untouched)\n", t.Source, t.Target)
+			} else {
+				fmt.Printf("     - %s\n", t.Source)
+			}
+		}
+	} else if e.selection.SelectsDatabases() {
+		fmt.Printf("  3. Restore databases matching %s\n", e.selection)
+	} else {
+		fmt.Println("  3. Restore all databases found in archive")
+	}
+	if e.selection.FiltersObjects() {
+		fmt.Printf("     Only %s; existing databases are kept\n", Selection{Tables: e.selection.Tables, Schemas: e.selection.Schemas, ExcludeTables: e.selection.ExcludeTables})
+	}
 	fmt.Println("  4. Cleanup temporary files")
 
 	fmt.Println("\n⚠️  WARNING: This will restore multiple databases.")
@@ -1088,7 +1425,12 @@ func (e *Engine) detectLargeObjectsInDumps(dumpsDir string, entries []os.DirEntr
 		dumpFile := filepath.Join(dumpsDir, entry.Name())
 		
 		// Skip compressed SQL files (can't easily check without decompressing)
-		if strings.HasSuffix(dumpFile, ".sql.gz") {
+		if compression.FromExtension(dumpFile) != compression.None {
+			continue
+		}
+
+		// Encrypted dumps can't be listed without decrypting them first
+		if e.isEncrypted(dumpFile) {
 			continue
 		}
 		
@@ -1,82 +1,119 @@
 package restore
 
 import (
-	"compress/gzip"
+	"context"
 	"io"
 	"os"
 	"strings"
+
+	"dbbackup/internal/compression"
+	"dbbackup/internal/encryption"
 )
 
 // ArchiveFormat represents the type of backup archive
 type ArchiveFormat string
 
 const (
-	FormatPostgreSQLDump   ArchiveFormat = "PostgreSQL Dump (.dump)"
-	FormatPostgreSQLDumpGz ArchiveFormat = "PostgreSQL Dump Compressed (.dump.gz)"
-	FormatPostgreSQLSQL    ArchiveFormat = "PostgreSQL SQL (.sql)"
-	FormatPostgreSQLSQLGz  ArchiveFormat = "PostgreSQL SQL Compressed (.sql.gz)"
-	FormatMySQLSQL         ArchiveFormat = "MySQL SQL (.sql)"
-	FormatMySQLSQLGz       ArchiveFormat = "MySQL SQL Compressed (.sql.gz)"
-	FormatClusterTarGz     ArchiveFormat = "Cluster Archive (.tar.gz)"
-	FormatUnknown          ArchiveFormat = "Unknown"
+	FormatPostgreSQLDump    ArchiveFormat = "PostgreSQL Dump (.dump)"
+	FormatPostgreSQLDumpGz  ArchiveFormat = "PostgreSQL Dump Compressed (.dump.gz)"
+	FormatPostgreSQLDumpZst ArchiveFormat = "PostgreSQL Dump Compressed (.dump.zst)"
+	FormatPostgreSQLDumpLz4 ArchiveFormat = "PostgreSQL Dump Compressed (.dump.lz4)"
+	FormatPostgreSQLSQL     ArchiveFormat = "PostgreSQL SQL (.sql)"
+	FormatPostgreSQLSQLGz   ArchiveFormat = "PostgreSQL SQL Compressed (.sql.gz)"
+	FormatPostgreSQLSQLZst  ArchiveFormat = "PostgreSQL SQL Compressed (.sql.zst)"
+	FormatPostgreSQLSQLLz4  ArchiveFormat = "PostgreSQL SQL Compressed (.sql.lz4)"
+	FormatMySQLSQL          ArchiveFormat = "MySQL SQL (.sql)"
+	FormatMySQLSQLGz        ArchiveFormat = "MySQL SQL Compressed (.sql.gz)"
+	FormatMySQLSQLZst       ArchiveFormat = "MySQL SQL Compressed (.sql.zst)"
+	FormatMySQLSQLLz4       ArchiveFormat = "MySQL SQL Compressed (.sql.lz4)"
+	FormatClusterTar        ArchiveFormat = "Cluster Archive (.tar)"
+	FormatClusterTarGz      ArchiveFormat = "Cluster Archive (.tar.gz)"
+	FormatClusterTarZst     ArchiveFormat = "Cluster Archive (.tar.zst)"
+	FormatClusterTarLz4     ArchiveFormat = "Cluster Archive (.tar.lz4)"
+	FormatUnknown           ArchiveFormat = "Unknown"
 )
 
-// DetectArchiveFormat detects the format of a backup archive from its filename and content
+// Formats by compression algorithm, for the kinds of archive that can be compressed
+var (
+	postgresDumpFormats = map[compression.Algorithm]ArchiveFormat{
+		compression.None: FormatPostgreSQLDump,
+		compression.Gzip: FormatPostgreSQLDumpGz,
+		compression.Zstd: FormatPostgreSQLDumpZst,
+		compression.LZ4:  FormatPostgreSQLDumpLz4,
+	}
+	postgresSQLFormats = map[compression.Algorithm]ArchiveFormat{
+		compression.None: FormatPostgreSQLSQL,
+		compression.Gzip: FormatPostgreSQLSQLGz,
+		compression.Zstd: FormatPostgreSQLSQLZst,
+		compression.LZ4:  FormatPostgreSQLSQLLz4,
+	}
+	mysqlSQLFormats = map[compression.Algorithm]ArchiveFormat{
+		compression.None: FormatMySQLSQL,
+		compression.Gzip: FormatMySQLSQLGz,
+		compression.Zstd: FormatMySQLSQLZst,
+		compression.LZ4:  FormatMySQLSQLLz4,
+	}
+	clusterFormats = map[compression.Algorithm]ArchiveFormat{
+		compression.None: FormatClusterTar,
+		compression.Gzip: FormatClusterTarGz,
+		compression.Zstd: FormatClusterTarZst,
+		compression.LZ4:  FormatClusterTarLz4,
+	}
+)
+
+// DetectArchiveFormat detects the format of a backup archive from its filename and content.
+// The compression algorithm is read from the magic bytes when the file can be
+// read, so a .sql.gz that was really written by zstd is still restored; the
+// extension is only used for files that are missing or encrypted.
 func DetectArchiveFormat(filename string) ArchiveFormat {
 	lower := strings.ToLower(filename)
+	encrypted := encryption.IsEncryptedFile(filename)
 
-	// Check for cluster archives first (most specific)
-	if strings.Contains(lower, "cluster") && strings.HasSuffix(lower, ".tar.gz") {
-		return FormatClusterTarGz
-	}
-
-	// For .dump files, check if they're actually custom format or SQL text
-	if strings.HasSuffix(lower, ".dump.gz") {
-		if isCustomFormat(filename, true) {
-			return FormatPostgreSQLDumpGz
+	alg := compression.FromExtension(lower)
+	base := compression.TrimExtension(lower)
+	if !encrypted {
+		if detected := compression.DetectFile(filename); detected != compression.None {
+			alg = detected
 		}
-		// If not custom format, treat as SQL
-		return FormatPostgreSQLSQLGz
 	}
 
-	if strings.HasSuffix(lower, ".dump") {
-		if isCustomFormat(filename, false) {
-			return FormatPostgreSQLDump
+	// Check for cluster archives first (most specific)
+	if strings.HasSuffix(base, ".tar") {
+		// Plain tars are only cluster archives by name; base backups are tars too
+		if alg == compression.None && !strings.Contains(lower, "cluster") {
+			return FormatUnknown
 		}
-		// If not custom format, treat as SQL
-		return FormatPostgreSQLSQL
+		return clusterFormats[alg]
 	}
 
-	// Check for compressed SQL formats
-	if strings.HasSuffix(lower, ".sql.gz") {
-		// Determine if MySQL or PostgreSQL based on naming convention
-		if strings.Contains(lower, "mysql") || strings.Contains(lower, "mariadb") {
-			return FormatMySQLSQLGz
+	// For .dump files, check if they're actually custom format or SQL text.
+	// Encrypted dumps can't be inspected; .dump is always pg_dump's custom format.
+	if strings.HasSuffix(base, ".dump") {
+		if encrypted || isCustomFormat(filename, alg != compression.None) {
+			return postgresDumpFormats[alg]
 		}
-		return FormatPostgreSQLSQLGz
+		// If not custom format, treat as SQL
+		return postgresSQLFormats[alg]
 	}
 
-	// Check for uncompressed SQL formats
-	if strings.HasSuffix(lower, ".sql") {
+	// Check for SQL formats
+	if strings.HasSuffix(base, ".sql") {
 		// Determine if MySQL or PostgreSQL based on naming convention
 		if strings.Contains(lower, "mysql") || strings.Contains(lower, "mariadb") {
-			return FormatMySQLSQL
+			return mysqlSQLFormats[alg]
 		}
-		return FormatPostgreSQLSQL
-	}
-
-	if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
-		return FormatClusterTarGz
+		return postgresSQLFormats[alg]
 	}
 
 	return FormatUnknown
 }
 
-// isCustomFormat checks if a file is PostgreSQL custom format (has PGDMP signature)
+// isCustomFormat checks if a file is PostgreSQL custom format (has PGDMP signature).
+// Files that can't be read are assumed to be, as their name says.
 func isCustomFormat(filename string, compressed bool) bool {
 	file, err := os.Open(filename)
 	if err != nil {
-		return false
+		return true
 	}
 	defer file.Close()
 
@@ -84,17 +121,17 @@ func isCustomFormat(filename string, compressed bool) bool {
 
 	// Handle compression
 	if compressed {
-		gz, err := gzip.NewReader(file)
+		cr, err := compression.NewReader(context.Background(), file)
 		if err != nil {
 			return false
 		}
-		defer gz.Close()
-		reader = gz
+		defer cr.Close()
+		reader = cr
 	}
 
 	// Read first 5 bytes to check for PGDMP signature
 	buffer := make([]byte, 5)
-	n, err := reader.Read(buffer)
+	n, err := io.ReadFull(reader, buffer)
 	if err != nil || n < 5 {
 		return false
 	}
@@ -102,31 +139,59 @@ func isCustomFormat(filename string, compressed bool) bool {
 	return string(buffer) == "PGDMP"
 }
 
+// Compression returns the algorithm the archive is compressed with
+func (f ArchiveFormat) Compression() compression.Algorithm {
+	switch f {
+	case FormatPostgreSQLDumpGz, FormatPostgreSQLSQLGz, FormatMySQLSQLGz, FormatClusterTarGz:
+		return compression.Gzip
+	case FormatPostgreSQLDumpZst, FormatPostgreSQLSQLZst, FormatMySQLSQLZst, FormatClusterTarZst:
+		return compression.Zstd
+	case FormatPostgreSQLDumpLz4, FormatPostgreSQLSQLLz4, FormatMySQLSQLLz4, FormatClusterTarLz4:
+		return compression.LZ4
+	}
+	return compression.None
+}
+
 // IsCompressed returns true if the archive format is compressed
 func (f ArchiveFormat) IsCompressed() bool {
-	return f == FormatPostgreSQLDumpGz ||
-		f == FormatPostgreSQLSQLGz ||
-		f == FormatMySQLSQLGz ||
-		f == FormatClusterTarGz
+	return f.Compression() != compression.None
 }
 
 // IsClusterBackup returns true if the archive is a cluster backup
 func (f ArchiveFormat) IsClusterBackup() bool {
-	return f == FormatClusterTarGz
+	return f == FormatClusterTar ||
+		f == FormatClusterTarGz ||
+		f == FormatClusterTarZst ||
+		f == FormatClusterTarLz4
 }
 
-// IsPostgreSQL returns true if the archive is PostgreSQL format
-func (f ArchiveFormat) IsPostgreSQL() bool {
+// IsPostgreSQLDump returns true if the archive is a pg_dump custom format dump
+func (f ArchiveFormat) IsPostgreSQLDump() bool {
 	return f == FormatPostgreSQLDump ||
 		f == FormatPostgreSQLDumpGz ||
-		f == FormatPostgreSQLSQL ||
+		f == FormatPostgreSQLDumpZst ||
+		f == FormatPostgreSQLDumpLz4
+}
+
+// IsPostgreSQLSQL returns true if the archive is a PostgreSQL SQL script
+func (f ArchiveFormat) IsPostgreSQLSQL() bool {
+	return f == FormatPostgreSQLSQL ||
 		f == FormatPostgreSQLSQLGz ||
-		f == FormatClusterTarGz
+		f == FormatPostgreSQLSQLZst ||
+		f == FormatPostgreSQLSQLLz4
+}
+
+// IsPostgreSQL returns true if the archive is PostgreSQL format
+func (f ArchiveFormat) IsPostgreSQL() bool {
+	return f.IsPostgreSQLDump() || f.IsPostgreSQLSQL() || f.IsClusterBackup()
 }
 
 // IsMySQL returns true if format is MySQL
 func (f ArchiveFormat) IsMySQL() bool {
-	return f == FormatMySQLSQL || f == FormatMySQLSQLGz
+	return f == FormatMySQLSQL ||
+		f == FormatMySQLSQLGz ||
+		f == FormatMySQLSQLZst ||
+		f == FormatMySQLSQLLz4
 }
 
 // String returns human-readable format name
@@ -136,16 +201,34 @@ func (f ArchiveFormat) String() string {
 		return "PostgreSQL Dump"
 	case FormatPostgreSQLDumpGz:
 		return "PostgreSQL Dump (gzip)"
+	case FormatPostgreSQLDumpZst:
+		return "PostgreSQL Dump (zstd)"
+	case FormatPostgreSQLDumpLz4:
+		return "PostgreSQL Dump (lz4)"
 	case FormatPostgreSQLSQL:
 		return "PostgreSQL SQL"
 	case FormatPostgreSQLSQLGz:
 		return "PostgreSQL SQL (gzip)"
+	case FormatPostgreSQLSQLZst:
+		return "PostgreSQL SQL (zstd)"
+	case FormatPostgreSQLSQLLz4:
+		return "PostgreSQL SQL (lz4)"
 	case FormatMySQLSQL:
 		return "MySQL SQL"
 	case FormatMySQLSQLGz:
 		return "MySQL SQL (gzip)"
+	case FormatMySQLSQLZst:
+		return "MySQL SQL (zstd)"
+	case FormatMySQLSQLLz4:
+		return "MySQL SQL (lz4)"
+	case FormatClusterTar:
+		return "Cluster Archive (tar)"
 	case FormatClusterTarGz:
 		return "Cluster Archive (tar.gz)"
+	case FormatClusterTarZst:
+		return "Cluster Archive (tar.zst)"
+	case FormatClusterTarLz4:
+		return "Cluster Archive (tar.lz4)"
 	default:
 		return "Unknown"
 	}
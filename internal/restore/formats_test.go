@@ -37,6 +37,21 @@ func TestDetectArchiveFormat(t *testing.T) {
 			filename: "cluster_backup_20241107.tar.gz",
 			want:     FormatClusterTarGz,
 		},
+		{
+			name:     "Cluster backup zstd",
+			filename: "cluster_20241107.tar.zst",
+			want:     FormatClusterTarZst,
+		},
+		{
+			name:     "Cluster backup lz4",
+			filename: "cluster_20241107.tar.lz4",
+			want:     FormatClusterTarLz4,
+		},
+		{
+			name:     "MySQL SQL zstd",
+			filename: "mysql_mydb.sql.zst",
+			want:     FormatMySQLSQLZst,
+		},
 		{
 			name:     "MySQL SQL script",
 			filename: "mydb.sql",
@@ -96,6 +111,9 @@ func TestArchiveFormat_IsCompressed(t *testing.T) {
 		{FormatMySQLSQL, false},
 		{FormatMySQLSQLGz, true},
 		{FormatClusterTarGz, true},
+		{FormatClusterTarZst, true},
+		{FormatPostgreSQLSQLLz4, true},
+		{FormatClusterTar, false},
 		{FormatUnknown, false},
 	}
 
@@ -121,6 +139,9 @@ func TestArchiveFormat_IsClusterBackup(t *testing.T) {
 		{FormatMySQLSQL, false},
 		{FormatMySQLSQLGz, false},
 		{FormatClusterTarGz, true},
+		{FormatClusterTarZst, true},
+		{FormatClusterTarLz4, true},
+		{FormatMySQLSQLZst, false},
 		{FormatUnknown, false},
 	}
 
@@ -204,6 +225,18 @@ func TestDetectArchiveFormatWithRealFiles(t *testing.T) {
 			content:  []byte{0x1f, 0x8b, 0x08},
 			want:     FormatUnknown, // .gz without proper extension
 		},
+		{
+			name:     "zstd data named .sql.gz",
+			filename: "mydb.sql.gz",
+			content:  []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00},
+			want:     FormatPostgreSQLSQLZst, // magic bytes win over the extension
+		},
+		{
+			name:     "lz4 cluster archive",
+			filename: "cluster_20250101.tar.lz4",
+			content:  []byte{0x04, 0x22, 0x4d, 0x18, 0x00},
+			want:     FormatClusterTarLz4,
+		},
 	}
 
 	for _, tc := range testCases {
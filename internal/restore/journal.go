@@ -0,0 +1,207 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// JournalState is the restore state of a database in a cluster restore
+type JournalState string
+
+const (
+	JournalPending JournalState = "pending"
+	JournalRunning JournalState = "running"
+	JournalDone    JournalState = "done"
+	JournalFailed  JournalState = "failed"
+	JournalSkipped JournalState = "skipped" // Global objects that weren't selected
+)
+
+// JournalDatabase is one database of a journaled cluster restore
+type JournalDatabase struct {
+	Name       string       `json:"name"`
+	Target     string       `json:"target"` // Name it is restored under
+	File       string       `json:"file"`   // Dump file in the extraction's dumps/
+	State      JournalState `json:"state"`
+	Attempts   int          `json:"attempts"`
+	Created    bool         `json:"created,omitempty"` // The restore created Target, so a retry may drop it
+	Error      string       `json:"error,omitempty"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+}
+
+// Journal records the progress of a cluster restore, so that one that failed
+// or was interrupted can be resumed from its extraction instead of starting
+// over. It is saved after every state change.
+type Journal struct {
+	Archive    string            `json:"archive"`
+	ExtractDir string            `json:"extract_dir"`
+	Selection  Selection         `json:"selection"`
+	Globals    JournalState      `json:"globals"`
+	Databases  []JournalDatabase `json:"databases"`
+	StartedAt  time.Time         `json:"started_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// newJournal starts the journal of a cluster restore extracted to extractDir,
+// with a pending entry for each dump. It is saved next to the extraction.
+func newJournal(archivePath, extractDir string, sel Selection, dumps []os.DirEntry) (*Journal, error) {
+	j := &Journal{
+		Archive:    archivePath,
+		ExtractDir: extractDir,
+		Selection:  sel,
+		Globals:    JournalPending,
+		StartedAt:  time.Now(),
+		path:       extractDir + ".journal.json",
+	}
+	if sel.SkipGlobals {
+		j.Globals = JournalSkipped
+	}
+	for _, dump := range dumps {
+		if dump.IsDir() {
+			continue
+		}
+		name := clusterDumpDatabase(dump.Name())
+		j.Databases = append(j.Databases, JournalDatabase{
+			Name:   name,
+			Target: sel.TargetName(name),
+			File:   dump.Name(),
+			State:  JournalPending,
+		})
+	}
+	if err := j.Save(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// LoadJournal reads the journal of an earlier cluster restore
+func LoadJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read restore journal: %w", err)
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse restore journal %s: %w", path, err)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	j.path = path
+	return &j, nil
+}
+
+// Path returns where the journal is saved
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Save writes the journal. The file is replaced atomically, so an
+// interruption never leaves a truncated journal behind.
+func (j *Journal) Save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.saveLocked()
+}
+
+func (j *Journal) saveLocked() error {
+	j.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal restore journal: %w", err)
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write restore journal: %w", err)
+	}
+	return os.Rename(tmp, j.path)
+}
+
+// Remaining returns the databases that still need restoring: pending,
+// failed, and running ones whose restore was cut off
+func (j *Journal) Remaining() []JournalDatabase {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var remaining []JournalDatabase
+	for _, db := range j.Databases {
+		if db.State != JournalDone {
+			remaining = append(remaining, db)
+		}
+	}
+	return remaining
+}
+
+// Start marks a database as being restored and returns its entry
+func (j *Journal) Start(name string) (JournalDatabase, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.Databases {
+		if db := &j.Databases[i]; db.Name == name {
+			db.State = JournalRunning
+			db.Attempts++
+			db.Error = ""
+			db.StartedAt = time.Now()
+			db.FinishedAt = time.Time{}
+			return *db, j.saveLocked()
+		}
+	}
+	return JournalDatabase{}, fmt.Errorf("database %s is not in the restore journal", name)
+}
+
+// SetCreated records that the restore creates a database's target
+func (j *Journal) SetCreated(name string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.Databases {
+		if db := &j.Databases[i]; db.Name == name {
+			db.Created = true
+			return j.saveLocked()
+		}
+	}
+	return fmt.Errorf("database %s is not in the restore journal", name)
+}
+
+// Finish records the outcome of a database's restore
+func (j *Journal) Finish(name string, restoreErr error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.Databases {
+		if db := &j.Databases[i]; db.Name == name {
+			db.State = JournalDone
+			if restoreErr != nil {
+				db.State = JournalFailed
+				db.Error = restoreErr.Error()
+			}
+			db.FinishedAt = time.Now()
+			return j.saveLocked()
+		}
+	}
+	return fmt.Errorf("database %s is not in the restore journal", name)
+}
+
+// SetGlobals records the outcome of the global objects' restore
+func (j *Journal) SetGlobals(state JournalState) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Globals = state
+	return j.saveLocked()
+}
+
+// Complete removes the journal and the extraction once every database is
+// restored
+func (j *Journal) Complete() error {
+	if err := os.RemoveAll(j.ExtractDir); err != nil {
+		return fmt.Errorf("failed to remove extraction %s: %w", j.ExtractDir, err)
+	}
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove restore journal: %w", err)
+	}
+	return nil
+}
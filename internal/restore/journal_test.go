@@ -0,0 +1,87 @@
+package restore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	extractDir := filepath.Join(t.TempDir(), ".restore_1751248800")
+	dumpsDir := filepath.Join(extractDir, "dumps")
+	if err := os.MkdirAll(dumpsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"shop.dump", "crm.sql.gz", "analytics.dump"} {
+		if err := os.WriteFile(filepath.Join(dumpsDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dumps, err := os.ReadDir(dumpsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sel := Selection{Map: map[string]string{"shop": "shop_restored"}, SkipGlobals: true}
+	journal, err := newJournal("/backups/cluster_20250630_020000.tar.gz", extractDir, sel, dumps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journal.Path() != extractDir+".journal.json" || journal.Globals != JournalSkipped {
+		t.Errorf("Unexpected journal %s with globals %s", journal.Path(), journal.Globals)
+	}
+
+	for _, name := range []string{"analytics", "crm", "shop"} {
+		entry, err := journal.Start(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Attempts != 1 || entry.State != JournalRunning {
+			t.Errorf("Unexpected entry after start: %+v", entry)
+		}
+	}
+	if err := journal.SetCreated("shop"); err != nil {
+		t.Fatal(err)
+	}
+	journal.Finish("analytics", nil)
+	journal.Finish("crm", errors.New("connection reset"))
+	// shop stays running, as if the process had been killed
+
+	loaded, err := LoadJournal(journal.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining := loaded.Remaining()
+	if len(remaining) != 2 || remaining[0].Name != "crm" || remaining[1].Name != "shop" {
+		t.Fatalf("Expected crm and shop to remain, got %+v", remaining)
+	}
+	if remaining[0].State != JournalFailed || remaining[0].Error != "connection reset" {
+		t.Errorf("Expected crm to have failed, got %+v", remaining[0])
+	}
+	if remaining[1].Target != "shop_restored" || loaded.Selection.Map["shop"] != "shop_restored" {
+		t.Errorf("Expected the mapping to be kept, got %+v and %v", remaining[1], loaded.Selection)
+	}
+	if !remaining[1].Created || remaining[0].Created {
+		t.Errorf("Expected only shop's target to be recorded as created, got %+v", remaining)
+	}
+
+	// A retry counts as another attempt
+	if entry, _ := loaded.Start("crm"); entry.Attempts != 2 || entry.Error != "" {
+		t.Errorf("Unexpected entry on retry: %+v", entry)
+	}
+	loaded.Finish("crm", nil)
+	loaded.Finish("shop", nil)
+	if len(loaded.Remaining()) != 0 {
+		t.Errorf("Expected nothing to remain, got %+v", loaded.Remaining())
+	}
+
+	if err := loaded.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{extractDir, journal.Path()} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", path)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package restore
 
 import (
-	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -9,7 +8,9 @@ import (
 	"os/exec"
 	"strings"
 
+	"dbbackup/internal/compression"
 	"dbbackup/internal/config"
+	"dbbackup/internal/encryption"
 	"dbbackup/internal/logger"
 )
 
@@ -51,18 +52,24 @@ func (s *Safety) ValidateArchive(archivePath string) error {
 		return fmt.Errorf("unknown archive format: %s", archivePath)
 	}
 
+	// Encrypted content can't be inspected here; every chunk is
+	// authenticated while it is decrypted during the restore
+	if encryption.IsEncryptedFile(archivePath) {
+		return nil
+	}
+
 	// Validate based on format
-	switch format {
-	case FormatPostgreSQLDump:
+	switch {
+	case format == FormatPostgreSQLDump:
 		return s.validatePgDump(archivePath)
-	case FormatPostgreSQLDumpGz:
-		return s.validatePgDumpGz(archivePath)
-	case FormatPostgreSQLSQL, FormatMySQLSQL:
+	case format.IsPostgreSQLDump():
+		return s.validatePgDumpCompressed(archivePath)
+	case format == FormatPostgreSQLSQL, format == FormatMySQLSQL:
 		return s.validateSQLScript(archivePath)
-	case FormatPostgreSQLSQLGz, FormatMySQLSQLGz:
-		return s.validateSQLScriptGz(archivePath)
-	case FormatClusterTarGz:
-		return s.validateTarGz(archivePath)
+	case format.IsPostgreSQLSQL(), format.IsMySQL():
+		return s.validateSQLScriptCompressed(archivePath)
+	case format.IsClusterBackup():
+		return s.validateTar(archivePath, format.Compression())
 	}
 
 	return nil
@@ -101,30 +108,15 @@ func (s *Safety) validatePgDump(path string) error {
 	return fmt.Errorf("does not appear to be a PostgreSQL dump file")
 }
 
-// validatePgDumpGz validates compressed PostgreSQL dump
-func (s *Safety) validatePgDumpGz(path string) error {
-	file, err := os.Open(path)
+// validatePgDumpCompressed validates compressed PostgreSQL dump
+func (s *Safety) validatePgDumpCompressed(path string) error {
+	buffer, err := readDecompressedHeader(path, 512)
 	if err != nil {
-		return fmt.Errorf("cannot open file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	// Open gzip reader
-	gz, err := gzip.NewReader(file)
-	if err != nil {
-		return fmt.Errorf("not a valid gzip file: %w", err)
-	}
-	defer gz.Close()
-
-	// Read first 512 bytes
-	buffer := make([]byte, 512)
-	n, err := gz.Read(buffer)
-	if err != nil && err != io.EOF {
-		return fmt.Errorf("cannot read gzip contents: %w", err)
-	}
-
-	if n < 5 {
-		return fmt.Errorf("gzip archive too small")
+	if len(buffer) < 5 {
+		return fmt.Errorf("compressed archive too small")
 	}
 
 	// Check for PGDMP signature
@@ -132,7 +124,7 @@ func (s *Safety) validatePgDumpGz(path string) error {
 		return nil
 	}
 
-	content := strings.ToLower(string(buffer[:n]))
+	content := strings.ToLower(string(buffer))
 	if strings.Contains(content, "postgresql") || strings.Contains(content, "pg_dump") {
 		return nil
 	}
@@ -162,54 +154,68 @@ func (s *Safety) validateSQLScript(path string) error {
 	return fmt.Errorf("does not appear to contain SQL content")
 }
 
-// validateSQLScriptGz validates compressed SQL script
-func (s *Safety) validateSQLScriptGz(path string) error {
+// validateSQLScriptCompressed validates compressed SQL script
+func (s *Safety) validateSQLScriptCompressed(path string) error {
+	buffer, err := readDecompressedHeader(path, 1024)
+	if err != nil {
+		return err
+	}
+
+	content := strings.ToLower(string(buffer))
+	if containsSQLKeywords(content) {
+		return nil
+	}
+
+	return fmt.Errorf("does not appear to contain SQL content")
+}
+
+// readDecompressedHeader returns up to size bytes of a compressed file's content
+func readDecompressedHeader(path string, size int) ([]byte, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("cannot open file: %w", err)
+		return nil, fmt.Errorf("cannot open file: %w", err)
 	}
 	defer file.Close()
 
-	gz, err := gzip.NewReader(file)
+	reader, err := compression.NewReader(context.Background(), file)
 	if err != nil {
-		return fmt.Errorf("not a valid gzip file: %w", err)
+		return nil, fmt.Errorf("not a valid compressed file: %w", err)
 	}
-	defer gz.Close()
+	defer reader.Close()
 
-	buffer := make([]byte, 1024)
-	n, err := gz.Read(buffer)
-	if err != nil && err != io.EOF {
-		return fmt.Errorf("cannot read gzip contents: %w", err)
+	buffer := make([]byte, size)
+	n, err := io.ReadFull(reader, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("cannot read compressed contents: %w", err)
 	}
+	return buffer[:n], nil
+}
 
-	content := strings.ToLower(string(buffer[:n]))
-	if containsSQLKeywords(content) {
+// validateTar validates a cluster archive: the magic bytes of compressed
+// archives must match the algorithm, plain tars must have a ustar header
+func (s *Safety) validateTar(path string, alg compression.Algorithm) error {
+	if alg != compression.None {
+		if detected := compression.DetectFile(path); detected != alg {
+			return fmt.Errorf("not a valid %s file", alg)
+		}
 		return nil
 	}
 
-	return fmt.Errorf("does not appear to contain SQL content")
-}
-
-// validateTarGz validates tar.gz archive
-func (s *Safety) validateTarGz(path string) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("cannot open file: %w", err)
 	}
 	defer file.Close()
 
-	// Check gzip magic number
-	buffer := make([]byte, 3)
-	n, err := file.Read(buffer)
-	if err != nil || n < 3 {
+	buffer := make([]byte, 262)
+	if _, err := io.ReadFull(file, buffer); err != nil {
 		return fmt.Errorf("cannot read file header")
 	}
-
-	if buffer[0] == 0x1f && buffer[1] == 0x8b {
-		return nil // Valid gzip header
+	if string(buffer[257:262]) == "ustar" {
+		return nil
 	}
 
-	return fmt.Errorf("not a valid gzip file")
+	return fmt.Errorf("not a valid tar file")
 }
 
 // containsSQLKeywords checks if content contains SQL keywords
@@ -0,0 +1,532 @@
+package restore
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dbbackup/internal/compression"
+	"dbbackup/internal/database"
+)
+
+// Selection picks what to restore from an archive. Table patterns are a name
+// or schema.name and may use shell wildcards (orders_*, sales.*). The zero
+// Selection restores everything.
+type Selection struct {
+	Tables        []string `json:"tables,omitempty"`         // Tables to restore (with their data, indexes, constraints and triggers)
+	Schemas       []string `json:"schemas,omitempty"`        // Schemas to restore in full (MySQL: databases of a multi-database dump)
+	ExcludeTables []string `json:"exclude_tables,omitempty"` // Tables to leave out
+	Databases     []string `json:"databases,omitempty"`      // Databases to restore from a cluster archive
+
+	// Cluster restores of some databases
+	Map         map[string]string `json:"map,omitempty"`          // Restores a database under another name; alone it also selects the database
+	MapExisting bool              `json:"map_existing,omitempty"` // Allow Map targets that already exist, restoring on top of them
+	SkipGlobals bool              `json:"skip_globals,omitempty"` // Leave out roles and tablespaces (MySQL: users and grants)
+}
+
+// SetSelection restricts the restore to part of the archive
+func (e *Engine) SetSelection(sel Selection) {
+	e.selection = sel
+}
+
+// Validate checks that every pattern is well-formed
+func (s Selection) Validate() error {
+	for _, patterns := range [][]string{s.Tables, s.Schemas, s.ExcludeTables, s.Databases} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil || p == "" {
+				return fmt.Errorf("invalid pattern %q", p)
+			}
+		}
+	}
+	return nil
+}
+
+// FiltersObjects reports whether only some objects of each dump are restored
+func (s Selection) FiltersObjects() bool {
+	return len(s.Tables) > 0 || len(s.Schemas) > 0 || len(s.ExcludeTables) > 0
+}
+
+// IncludesDatabase reports whether a database of a cluster archive is restored
+func (s Selection) IncludesDatabase(name string) bool {
+	if len(s.Databases) == 0 && len(s.Map) > 0 {
+		_, ok := s.Map[name]
+		return ok
+	}
+	return len(s.Databases) == 0 || matchAny(s.Databases, name)
+}
+
+// includesTable reports whether a table (or view or sequence) is restored.
+// schema is "" when the dump doesn't say.
+func (s Selection) includesTable(schema, name string) bool {
+	selected := len(s.Tables) == 0 && len(s.Schemas) == 0 ||
+		matchTable(s.Tables, schema, name) || matchAny(s.Schemas, schema)
+	return selected && !matchTable(s.ExcludeTables, schema, name)
+}
+
+// String describes the selection, e.g. "tables orders, sales.*; excluding audit_log"
+func (s Selection) String() string {
+	var parts []string
+	for _, part := range []struct {
+		label    string
+		patterns []string
+	}{{"databases", s.Databases}, {"schemas", s.Schemas}, {"tables", s.Tables}, {"excluding", s.ExcludeTables}} {
+		if len(part.patterns) > 0 {
+			parts = append(parts, part.label+" "+strings.Join(part.patterns, ", "))
+		}
+	}
+	if len(s.Map) > 0 {
+		var renames []string
+		for _, source := range slices.Sorted(maps.Keys(s.Map)) {
+			renames = append(renames, source+" as "+s.Map[source])
+		}
+		parts = append(parts, "renaming "+strings.Join(renames, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTable matches "name" patterns against the table name and
+// "schema.name" patterns against both. Without a known schema only the
+// table part of a qualified pattern is compared.
+func matchTable(patterns []string, schema, name string) bool {
+	for _, p := range patterns {
+		if ps, pn, qualified := strings.Cut(p, "."); qualified {
+			if ok, _ := path.Match(ps, schema); !ok && schema != "" {
+				continue
+			}
+			p = pn
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tocEntry is one line of a pg_restore --list, with the dependencies that
+// --verbose adds below it
+type tocEntry struct {
+	line   string
+	id     int
+	desc   string // Object type, e.g. TABLE, TABLE DATA, FK CONSTRAINT
+	schema string
+	name   string // Tag; "table name" for constraints, triggers and defaults
+	deps   []int
+}
+
+// tocDescs are the multi-word object types of a TOC, longest first
+var tocDescs = []string{
+	"MATERIALIZED VIEW DATA", "SEQUENCE OWNED BY", "MATERIALIZED VIEW", "CHECK CONSTRAINT",
+	"FOREIGN TABLE", "FK CONSTRAINT", "BLOB METADATA", "SECURITY LABEL", "INDEX ATTACH",
+	"ROW SECURITY", "SEQUENCE SET", "TABLE ATTACH", "DEFAULT ACL", "TABLE DATA",
+}
+
+// parseTOC parses the output of pg_restore --list --verbose, e.g.
+//
+//	215; 1259 16386 TABLE public orders postgres
+//	;	depends on: 214
+func parseTOC(r io.Reader) ([]tocEntry, error) {
+	var entries []tocEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if deps, ok := strings.CutPrefix(line, ";\tdepends on:"); ok && len(entries) > 0 {
+			last := &entries[len(entries)-1]
+			for _, f := range strings.Fields(deps) {
+				if id, err := strconv.Atoi(f); err == nil {
+					last.deps = append(last.deps, id)
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ";") || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		idStr, rest, ok := strings.Cut(line, ";")
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if !ok || err != nil {
+			return nil, fmt.Errorf("unexpected TOC line %q", line)
+		}
+		// Skip the catalog OID and object OID
+		fields := strings.SplitN(strings.TrimLeft(rest, " "), " ", 3)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("unexpected TOC line %q", line)
+		}
+		rest = fields[2]
+
+		entry := tocEntry{line: line, id: id}
+		entry.desc, _, _ = strings.Cut(rest, " ")
+		for _, desc := range tocDescs {
+			if strings.HasPrefix(rest, desc+" ") {
+				entry.desc = desc
+				break
+			}
+		}
+		rest = strings.TrimPrefix(rest, entry.desc+" ")
+		entry.schema, rest, _ = strings.Cut(rest, " ")
+		// The owner comes last and is empty for some objects
+		if i := strings.LastIndex(rest, " "); i >= 0 {
+			rest = rest[:i]
+		}
+		entry.name = rest
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// relationDescs are the object types a selection picks by name
+var relationDescs = []string{"TABLE", "VIEW", "MATERIALIZED VIEW", "SEQUENCE", "FOREIGN TABLE"}
+
+// attachedDescs are the object types restored with the relations they depend on
+var attachedDescs = []string{
+	"TABLE DATA", "MATERIALIZED VIEW DATA", "INDEX", "INDEX ATTACH", "CONSTRAINT", "CHECK CONSTRAINT",
+	"FK CONSTRAINT", "TRIGGER", "DEFAULT", "SEQUENCE", "SEQUENCE OWNED BY", "SEQUENCE SET",
+	"COMMENT", "ACL", "POLICY", "ROW SECURITY", "RULE", "STATISTICS", "SECURITY LABEL",
+}
+
+// selectTOC returns the entries that restore the selection, in TOC order.
+// Tables are restored with their schema, their data and the indexes,
+// constraints, triggers, defaults and sequences that depend on them. Objects that
+// depend on a relation that isn't restored, such as foreign keys from
+// other tables, are left out.
+func selectTOC(entries []tocEntry, sel Selection) ([]tocEntry, error) {
+	byID := make(map[int]*tocEntry, len(entries))
+	for i := range entries {
+		byID[entries[i].id] = &entries[i]
+	}
+	isRelation := func(e *tocEntry) bool { return slices.Contains(relationDescs, e.desc) }
+
+	included := make(map[int]bool)
+	everything := len(sel.Tables) == 0 && len(sel.Schemas) == 0
+	for _, e := range entries {
+		switch {
+		case isRelation(&e):
+			included[e.id] = sel.includesTable(e.schema, e.name)
+		case e.desc == "SCHEMA":
+			included[e.id] = everything || matchAny(sel.Schemas, e.name)
+		default:
+			included[e.id] = everything || matchAny(sel.Schemas, e.schema)
+		}
+	}
+
+	// missingRelation reports whether an entry depends on a relation that
+	// isn't restored. Sequences can be pulled in later, so they are only
+	// checked with withSequences.
+	missingRelation := func(e *tocEntry, withSequences bool) bool {
+		for _, dep := range e.deps {
+			if d, ok := byID[dep]; ok && isRelation(d) && !included[dep] && (withSequences || d.desc != "SEQUENCE") {
+				return true
+			}
+		}
+		return false
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := range entries {
+			e := &entries[i]
+			if included[e.id] {
+				// Sequences used by a restored column default
+				for _, dep := range e.deps {
+					if d, ok := byID[dep]; ok && d.desc == "SEQUENCE" && !included[dep] &&
+						!matchTable(sel.ExcludeTables, d.schema, d.name) {
+						included[dep] = true
+						changed = true
+					}
+				}
+				continue
+			}
+			if !slices.Contains(attachedDescs, e.desc) || missingRelation(e, false) {
+				continue
+			}
+			for _, dep := range e.deps {
+				if included[dep] {
+					included[e.id] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	// Drop whatever still depends on a relation that is left out, such as
+	// views on excluded tables
+	for changed := true; changed; {
+		changed = false
+		for i := range entries {
+			e := &entries[i]
+			if included[e.id] && e.desc != "SCHEMA" && missingRelation(e, true) {
+				included[e.id] = false
+				changed = true
+			}
+		}
+	}
+
+	// A restored relation can't be created without its schema
+	schemas := make(map[string]bool)
+	for _, e := range entries {
+		if included[e.id] && isRelation(&e) {
+			schemas[e.schema] = true
+		}
+	}
+	for _, e := range entries {
+		if e.desc == "SCHEMA" && schemas[e.name] {
+			included[e.id] = true
+		}
+	}
+
+	var selected []tocEntry
+	relations := 0
+	for _, e := range entries {
+		if included[e.id] {
+			selected = append(selected, e)
+			if isRelation(&e) {
+				relations++
+			}
+		}
+	}
+	if relations == 0 && (len(sel.Tables) > 0 || len(sel.Schemas) > 0) {
+		return nil, fmt.Errorf("no tables in the archive match %s", sel)
+	}
+	return selected, nil
+}
+
+// applySelection points a pg_restore at the TOC entries of the selection.
+// The returned func removes the list file again.
+func (e *Engine) applySelection(ctx context.Context, archivePath string, compressed bool, opts *database.RestoreOptions) (func(), error) {
+	if !e.selection.FiltersObjects() {
+		return func() {}, nil
+	}
+	list, err := e.writeTOCList(ctx, archivePath, compressed)
+	if err != nil {
+		return nil, err
+	}
+	opts.UseList = list
+	return func() { os.Remove(list) }, nil
+}
+
+// writeTOCList writes the pg_restore --use-list file restoring the selection
+// from a custom-format dump, and returns its path
+func (e *Engine) writeTOCList(ctx context.Context, archivePath string, compressed bool) (string, error) {
+	input, err := e.openArchive(archivePath, compressed)
+	if err != nil {
+		return "", err
+	}
+	defer input.Close()
+
+	// pg_restore only reads the TOC at the start of the archive
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "pg_restore", "--list", "--verbose")
+	cmd.Stdin = input
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to read archive TOC: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+
+	entries, err := parseTOC(&stdout)
+	if err != nil {
+		return "", err
+	}
+	selected, err := selectTOC(entries, e.selection)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", filepath.Base(archivePath), err)
+	}
+
+	list, err := os.CreateTemp("", "dbbackup-toc-*.list")
+	if err != nil {
+		return "", fmt.Errorf("failed to create TOC list: %w", err)
+	}
+	defer list.Close()
+	for _, entry := range selected {
+		fmt.Fprintln(list, entry.line)
+	}
+	if err := list.Close(); err != nil {
+		os.Remove(list.Name())
+		return "", fmt.Errorf("failed to write TOC list: %w", err)
+	}
+
+	e.log.Info("Restoring selected objects", "archive", filepath.Base(archivePath),
+		"selection", e.selection.String(), "objects", len(selected), "of", len(entries))
+	return list.Name(), nil
+}
+
+// filterMySQLDump streams a mysqldump script, dropping the structure, data
+// and triggers of tables that aren't selected. Tables are recognized by the
+// "-- Table structure for table" and "-- Dumping data for table" comments
+// mysqldump writes before them. Views are only kept when no tables or
+// schemas are selected. The session settings at the start and end of the
+// dump are always kept.
+func filterMySQLDump(dst io.Writer, src io.Reader, sel Selection) error {
+	br := bufio.NewReaderSize(src, 64*1024)
+	bw := bufio.NewWriterSize(dst, 64*1024)
+	keepViews := len(sel.Tables) == 0 && len(sel.Schemas) == 0
+
+	keep, database := true, ""
+	matched := false
+	lineStart := true
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if len(chunk) > 0 && lineStart {
+			line := string(chunk)
+			switch {
+			case strings.HasPrefix(line, "-- Current Database: "):
+				database = backtickName(line)
+				keep = true
+			case strings.HasPrefix(line, "-- Table structure for table "),
+				strings.HasPrefix(line, "-- Dumping data for table "):
+				keep = sel.includesTable(database, backtickName(line))
+				matched = matched || keep
+			case strings.HasPrefix(line, "-- Temporary view structure for view "),
+				strings.HasPrefix(line, "-- Temporary table structure for view "),
+				strings.HasPrefix(line, "-- Final view structure for view "):
+				keep = keepViews && !matchTable(sel.ExcludeTables, database, backtickName(line))
+			case strings.HasPrefix(line, "-- Dumping routines for database "),
+				strings.HasPrefix(line, "-- Dumping events for database "):
+				keep = keepViews || matchAny(sel.Schemas, database)
+			case strings.HasPrefix(line, "/*!") && strings.Contains(line, "=@OLD_"):
+				// Session settings restored at the end of the dump
+				keep = true
+			}
+		}
+		if len(chunk) > 0 {
+			if keep {
+				if _, werr := bw.Write(chunk); werr != nil {
+					return werr
+				}
+			}
+			lineStart = chunk[len(chunk)-1] == '\n'
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if !matched && (len(sel.Tables) > 0 || len(sel.Schemas) > 0) {
+		return fmt.Errorf("no tables in the dump match %s", sel)
+	}
+	return nil
+}
+
+// backtickName returns the name quoted in a mysqldump comment line
+func backtickName(line string) string {
+	start := strings.Index(line, "`")
+	end := strings.LastIndex(line, "`")
+	if start < 0 || end <= start {
+		return ""
+	}
+	return strings.ReplaceAll(line[start+1:end], "``", "`")
+}
+
+// filteredMySQLInput returns the dump with the selection applied, for
+// feeding to mysql. Closing it stops the filter.
+func (e *Engine) filteredMySQLInput(input io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(filterMySQLDump(pw, input, e.selection))
+	}()
+	return pr
+}
+
+// extractSelected extracts globals.sql and the dumps of the selected
+// databases from a cluster archive, skipping the rest of it. It returns the
+// names of all databases in the archive.
+func (e *Engine) extractSelected(ctx context.Context, archivePath, destDir string) ([]string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = bufio.NewReaderSize(file, 1<<20)
+	if compression.DetectFile(archivePath) != compression.None {
+		cr, err := compression.NewReader(ctx, reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress archive: %w", err)
+		}
+		defer cr.Close()
+		reader = cr
+	}
+
+	var databases []string
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if hdr.Typeflag != tar.TypeReg || !filepath.IsLocal(name) {
+			continue
+		}
+
+		if dir, file := path.Split(name); dir == "dumps/" {
+			dbName := clusterDumpDatabase(file)
+			databases = append(databases, dbName)
+			if !e.selection.IncludesDatabase(dbName) {
+				continue
+			}
+		} else if dir != "" {
+			continue
+		}
+
+		target := filepath.Join(destDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		_, err = io.Copy(out, tr)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+	}
+	return databases, nil
+}
+
+// clusterDumpDatabase returns the database a file in dumps/ belongs to
+func clusterDumpDatabase(filename string) string {
+	dbName := compression.TrimExtension(filename)
+	dbName = strings.TrimSuffix(dbName, ".dump")
+	return strings.TrimSuffix(dbName, ".sql")
+}
@@ -0,0 +1,249 @@
+package restore
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testTOC is abridged pg_restore --list --verbose output
+const testTOC = `;
+; Archive created at 2025-06-30 02:00:01 UTC
+;     dbname: shop
+;
+; Selected TOC Entries:
+;
+5; 2615 2200 SCHEMA - public pg_database_owner
+3371; 0 0 COMMENT - SCHEMA public pg_database_owner
+;	depends on: 5
+6; 2615 16390 SCHEMA - sales postgres
+215; 1259 16386 TABLE public customers postgres
+;	depends on: 5
+216; 1259 16391 SEQUENCE public customers_id_seq postgres
+;	depends on: 5
+3372; 0 0 SEQUENCE OWNED BY public customers_id_seq postgres
+;	depends on: 216 215
+217; 1259 16392 TABLE public orders postgres
+;	depends on: 5
+218; 1259 16400 TABLE sales invoices postgres
+;	depends on: 6
+219; 1259 16401 VIEW public order_totals postgres
+;	depends on: 217 5
+3210; 2604 16393 DEFAULT public customers id postgres
+;	depends on: 216 215
+3360; 0 16386 TABLE DATA public customers postgres
+;	depends on: 215
+3361; 0 16392 TABLE DATA public orders postgres
+;	depends on: 217
+3362; 0 16400 TABLE DATA sales invoices postgres
+;	depends on: 218
+3373; 0 0 SEQUENCE SET public customers_id_seq postgres
+;	depends on: 216
+3212; 2606 16394 CONSTRAINT public customers customers_pkey postgres
+;	depends on: 215
+3214; 2606 16395 CONSTRAINT public orders orders_pkey postgres
+;	depends on: 217
+3215; 1259 16396 INDEX public orders_customer_idx postgres
+;	depends on: 217
+3216; 2606 16397 FK CONSTRAINT public orders orders_customer_id_fkey postgres
+;	depends on: 217 215 3212
+`
+
+func TestParseTOC(t *testing.T) {
+	entries, err := parseTOC(strings.NewReader(testTOC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 18 {
+		t.Fatalf("Expected 18 entries, got %d", len(entries))
+	}
+
+	fk := entries[len(entries)-1]
+	if fk.desc != "FK CONSTRAINT" || fk.schema != "public" || fk.name != "orders orders_customer_id_fkey" {
+		t.Errorf("Misparsed %q: %+v", fk.line, fk)
+	}
+	if !slices.Equal(fk.deps, []int{217, 215, 3212}) {
+		t.Errorf("Expected the FK to depend on 217 215 3212, got %v", fk.deps)
+	}
+	if entries[0].desc != "SCHEMA" || entries[0].name != "public" {
+		t.Errorf("Misparsed %q: %+v", entries[0].line, entries[0])
+	}
+
+	if _, err := parseTOC(strings.NewReader("not a TOC\n")); err == nil {
+		t.Error("Expected a malformed TOC to be rejected")
+	}
+}
+
+func TestSelectTOC(t *testing.T) {
+	entries, err := parseTOC(strings.NewReader(testTOC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		sel  Selection
+		want []int
+	}{
+		{
+			// Its schema, but no foreign key to the missing customers table
+			name: "table",
+			sel:  Selection{Tables: []string{"orders"}},
+			want: []int{5, 217, 3361, 3214, 3215},
+		},
+		{
+			// The sequence behind the id default comes along
+			name: "table with sequence",
+			sel:  Selection{Tables: []string{"public.cust*"}},
+			want: []int{5, 215, 216, 3372, 3210, 3360, 3373, 3212},
+		},
+		{
+			// The schema is created before the table
+			name: "table in another schema",
+			sel:  Selection{Tables: []string{"sales.invoices"}},
+			want: []int{6, 218, 3362},
+		},
+		{
+			name: "related tables",
+			sel:  Selection{Tables: []string{"orders", "customers"}},
+			want: []int{5, 215, 216, 3372, 217, 3210, 3360, 3361, 3373, 3212, 3214, 3215, 3216},
+		},
+		{
+			name: "schema",
+			sel:  Selection{Schemas: []string{"sales"}},
+			want: []int{6, 218, 3362},
+		},
+		{
+			// The view on orders can't be restored without it
+			name: "exclude",
+			sel:  Selection{ExcludeTables: []string{"orders"}},
+			want: []int{5, 3371, 6, 215, 216, 3372, 218, 3210, 3360, 3362, 3373, 3212},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := selectTOC(entries, tt.sel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, e := range selected {
+				ids = append(ids, e.id)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("Selected %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	if _, err := selectTOC(entries, Selection{Tables: []string{"invoice"}}); err == nil {
+		t.Error("Expected an error when no table matches")
+	}
+}
+
+const testMySQLDump = "-- MySQL dump 10.13\n" +
+	"/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;\n" +
+	"\n" +
+	"--\n" +
+	"-- Table structure for table `customers`\n" +
+	"--\n" +
+	"CREATE TABLE `customers` (`id` int);\n" +
+	"--\n" +
+	"-- Dumping data for table `customers`\n" +
+	"--\n" +
+	"INSERT INTO `customers` VALUES (1);\n" +
+	"--\n" +
+	"-- Table structure for table `orders`\n" +
+	"--\n" +
+	"CREATE TABLE `orders` (`id` int);\n" +
+	"--\n" +
+	"-- Dumping data for table `orders`\n" +
+	"--\n" +
+	"INSERT INTO `orders` VALUES (1);\n" +
+	"--\n" +
+	"-- Final view structure for view `order_totals`\n" +
+	"--\n" +
+	"CREATE VIEW `order_totals` AS SELECT 1;\n" +
+	"/*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;\n" +
+	"-- Dump completed\n"
+
+func TestFilterMySQLDump(t *testing.T) {
+	var out strings.Builder
+	if err := filterMySQLDump(&out, strings.NewReader(testMySQLDump), Selection{Tables: []string{"orders"}}); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{"SET @OLD_CHARACTER_SET_CLIENT", "CREATE TABLE `orders`", "INSERT INTO `orders`", "=@OLD_CHARACTER_SET_CLIENT", "Dump completed"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Filtered dump lacks %q", want)
+		}
+	}
+	for _, unwanted := range []string{"`customers`", "CREATE VIEW"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Filtered dump still has %q", unwanted)
+		}
+	}
+
+	out.Reset()
+	if err := filterMySQLDump(&out, strings.NewReader(testMySQLDump), Selection{ExcludeTables: []string{"customers"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); strings.Contains(got, "`customers`") || !strings.Contains(got, "CREATE VIEW") {
+		t.Errorf("Excluding customers should keep the rest, got:\n%s", got)
+	}
+
+	if err := filterMySQLDump(&out, strings.NewReader(testMySQLDump), Selection{Tables: []string{"invoices"}}); err == nil {
+		t.Error("Expected an error when no table matches")
+	}
+}
+
+func TestExtractSelected(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "cluster_20250630_020000.tar.gz")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"./", "./globals.sql", "./dumps/", "./dumps/shop.dump", "./dumps/crm.sql.gz", "./dumps/analytics.dump", "../escape.sql"} {
+		hdr := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(name))}
+		if strings.HasSuffix(name, "/") {
+			hdr = &tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			tw.Write([]byte(name))
+		}
+	}
+	tw.Close()
+	gz.Close()
+	file.Close()
+
+	destDir := filepath.Join(dir, "extract")
+	e := &Engine{selection: Selection{Databases: []string{"shop", "c*"}}}
+	databases, err := e.extractSelected(t.Context(), archivePath, destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(databases, []string{"shop", "crm", "analytics"}) {
+		t.Errorf("Expected all databases to be listed, got %v", databases)
+	}
+
+	for _, name := range []string{"globals.sql", "dumps/shop.dump", "dumps/crm.sql.gz"} {
+		if _, err := os.Stat(filepath.Join(destDir, name)); err != nil {
+			t.Errorf("Expected %s to be extracted: %v", name, err)
+		}
+	}
+	for _, name := range []string{"dumps/analytics.dump", "../escape.sql"} {
+		if _, err := os.Stat(filepath.Join(destDir, name)); err == nil {
+			t.Errorf("Expected %s to be skipped", name)
+		}
+	}
+}
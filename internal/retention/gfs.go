@@ -0,0 +1,162 @@
+package retention
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HasTiers reports whether the policy uses grandfather-father-son tiers
+func (p Policy) HasTiers() bool {
+	return p.KeepLast > 0 || p.KeepWithin > 0 || p.KeepDaily > 0 ||
+		p.KeepWeekly > 0 || p.KeepMonthly > 0 || p.KeepYearly > 0
+}
+
+// TiersString describes the tiers, e.g. "daily 7, weekly 4 (Europe/Berlin)"
+func (p Policy) TiersString() string {
+	if !p.HasTiers() {
+		return ""
+	}
+	var parts []string
+	for _, tier := range []struct {
+		name  string
+		count int
+	}{{"last", p.KeepLast}, {"daily", p.KeepDaily}, {"weekly", p.KeepWeekly}, {"monthly", p.KeepMonthly}, {"yearly", p.KeepYearly}} {
+		if tier.count > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", tier.name, tier.count))
+		}
+	}
+	if p.KeepWithin > 0 {
+		parts = append(parts, "within "+FormatWithin(p.KeepWithin))
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, ", "), p.location())
+}
+
+func (p Policy) location() *time.Location {
+	if p.Location == nil {
+		return time.Local
+	}
+	return p.Location
+}
+
+// selectTiers returns for each backup of a group, newest first, the tiers
+// that keep it. Calendar tiers keep the newest backup of each of their last
+// N days, weeks, months or years that have a backup, using the calendar of
+// the policy's location, so a backup at 23:30 UTC may count for the next
+// day in Europe.
+func selectTiers(items []Item, p Policy, now time.Time) [][]string {
+	reasons := make([][]string, len(items))
+	loc := p.location()
+
+	for i, item := range items {
+		if i < p.MinBackups {
+			reasons[i] = append(reasons[i], fmt.Sprintf("one of the %d newest", p.MinBackups))
+		}
+		if i < p.KeepLast {
+			reasons[i] = append(reasons[i], fmt.Sprintf("last %d", p.KeepLast))
+		}
+		if p.KeepWithin > 0 && !item.Timestamp.Before(now.Add(-p.KeepWithin)) {
+			reasons[i] = append(reasons[i], "within "+FormatWithin(p.KeepWithin))
+		}
+	}
+
+	bucket := func(count int, tier string, period func(t time.Time) string) {
+		seen := make(map[string]bool)
+		for i, item := range items {
+			if len(seen) == count {
+				return
+			}
+			key := period(item.Timestamp.In(loc))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			reasons[i] = append(reasons[i], tier+" "+key)
+		}
+	}
+	bucket(p.KeepDaily, "daily", func(t time.Time) string { return t.Format("2006-01-02") })
+	bucket(p.KeepWeekly, "weekly", func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+	bucket(p.KeepMonthly, "monthly", func(t time.Time) string { return t.Format("2006-01") })
+	bucket(p.KeepYearly, "yearly", func(t time.Time) string { return t.Format("2006") })
+
+	return reasons
+}
+
+// protectChains keeps the base of every kept incremental, and its base in
+// turn, so that no kept backup becomes impossible to restore
+func protectChains(plan *Plan) {
+	type location struct{ group, index int }
+	findDeleted := func(id, name string) (location, bool) {
+		for gi, g := range plan.Groups {
+			for di, d := range g.Delete {
+				if (id != "" && d.ID == id) || (name != "" && path.Base(filepath.ToSlash(d.Path)) == name) {
+					return location{gi, di}, true
+				}
+			}
+		}
+		return location{}, false
+	}
+
+	var pending []Item
+	for _, d := range plan.Kept() {
+		pending = append(pending, d.Item)
+	}
+	for len(pending) > 0 {
+		item := pending[0]
+		pending = pending[1:]
+		if item.BaseID == "" && item.BaseName == "" {
+			continue
+		}
+		loc, ok := findDeleted(item.BaseID, item.BaseName)
+		if !ok {
+			continue
+		}
+
+		group := &plan.Groups[loc.group]
+		base := group.Delete[loc.index]
+		group.Delete = append(group.Delete[:loc.index], group.Delete[loc.index+1:]...)
+		base.Reason = "base of kept " + path.Base(filepath.ToSlash(item.Path))
+		group.Keep = append(group.Keep, base)
+		sort.SliceStable(group.Keep, func(i, j int) bool {
+			return group.Keep[i].Timestamp.After(group.Keep[j].Timestamp)
+		})
+		pending = append(pending, base.Item)
+	}
+}
+
+// ParseWithin parses a --keep-within duration. Besides Go durations ("36h")
+// it accepts whole days, weeks and years: "7d", "2w", "1y".
+func ParseWithin(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour, 'y': 365 * 24 * time.Hour}
+	if unit, ok := units[s[len(s)-1]]; ok {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q (e.g. 36h, 7d, 2w, 1y)", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q (e.g. 36h, 7d, 2w, 1y)", s)
+	}
+	return d, nil
+}
+
+// FormatWithin formats a --keep-within duration the way ParseWithin reads it
+func FormatWithin(d time.Duration) string {
+	day := 24 * time.Hour
+	if d >= day && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
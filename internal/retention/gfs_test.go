@@ -0,0 +1,166 @@
+package retention
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// dailyItems returns one backup per day at 02:00 UTC between from and to
+func dailyItems(from, to time.Time) []Item {
+	var items []Item
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		ts := time.Date(d.Year(), d.Month(), d.Day(), 2, 0, 0, 0, time.UTC)
+		items = append(items, Item{Path: "db_app_" + ts.Format("20060102") + ".dump", Database: "app", BackupType: "single", Timestamp: ts})
+	}
+	return items
+}
+
+func keptReasons(plan *Plan) map[string]string {
+	kept := make(map[string]string)
+	for _, d := range plan.Kept() {
+		kept[strings.TrimSuffix(strings.TrimPrefix(d.Path, "db_app_"), ".dump")] = d.Reason
+	}
+	return kept
+}
+
+func TestGFSTiers(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	items := dailyItems(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), now)
+	policy := Policy{KeepDaily: 7, KeepWeekly: 4, KeepMonthly: 12, KeepYearly: 3, Location: time.UTC}
+
+	plan := NewPlan("/backups", items, policy, now)
+	kept := keptReasons(plan)
+
+	want := []string{
+		// Daily
+		"20250630", "20250629", "20250628", "20250627", "20250626", "20250625", "20250624",
+		// Weekly (2025-06-30 is the Monday of W27)
+		"20250622", "20250615",
+		// Monthly
+		"20250531", "20250430", "20250331", "20250228", "20250131",
+		"20241231", "20241130", "20241031", "20240930", "20240831", "20240731",
+		// Yearly
+		"20231231",
+	}
+	if len(kept) != len(want) {
+		t.Errorf("Expected %d backups kept, got %d: %v", len(want), len(kept), kept)
+	}
+	for _, day := range want {
+		if _, ok := kept[day]; !ok {
+			t.Errorf("Expected %s to be kept", day)
+		}
+	}
+
+	if got := kept["20250629"]; got != "daily 2025-06-29, weekly 2025-W26" {
+		t.Errorf("Unexpected reason for 2025-06-29: %q", got)
+	}
+	if got := kept["20250630"]; got != "daily 2025-06-30, weekly 2025-W27, monthly 2025-06, yearly 2025" {
+		t.Errorf("Unexpected reason for 2025-06-30: %q", got)
+	}
+	if got := kept["20231231"]; got != "yearly 2023" {
+		t.Errorf("Unexpected reason for 2023-12-31: %q", got)
+	}
+	for _, d := range plan.Deletions() {
+		if d.Reason != "not selected by any tier" {
+			t.Errorf("Unexpected deletion reason %q", d.Reason)
+		}
+	}
+	if plan.RetentionDays != 0 || plan.Tiers != "daily 7, weekly 4, monthly 12, yearly 3 (UTC)" {
+		t.Errorf("Unexpected plan policy: %d days, tiers %q", plan.RetentionDays, plan.Tiers)
+	}
+}
+
+func TestGFSKeepLastAndWithin(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	items := dailyItems(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), now)
+
+	plan := NewPlan("/backups", items, Policy{KeepLast: 2, KeepWithin: 5 * 24 * time.Hour, MinBackups: 1}, now)
+	kept := keptReasons(plan)
+	if len(kept) != 5 {
+		t.Errorf("Expected the backups of the last 5 days, got %v", kept)
+	}
+	if got := kept["20250630"]; got != "one of the 1 newest, last 2, within 5d" {
+		t.Errorf("Unexpected reason %q", got)
+	}
+	if got := kept["20250626"]; got != "within 5d" {
+		t.Errorf("Unexpected reason %q", got)
+	}
+}
+
+func TestGFSTimezone(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	items := []Item{
+		{Path: "late", Database: "app", Timestamp: time.Date(2025, 6, 29, 23, 30, 0, 0, time.UTC)},
+		{Path: "morning", Database: "app", Timestamp: time.Date(2025, 6, 29, 10, 0, 0, 0, time.UTC)},
+	}
+
+	// Both on the same day in UTC: only the newest is the daily backup
+	plan := NewPlan("/backups", items, Policy{KeepDaily: 2, Location: time.UTC}, now)
+	if len(plan.Kept()) != 1 || plan.Kept()[0].Path != "late" {
+		t.Errorf("Expected one daily backup in UTC, got %+v", plan.Kept())
+	}
+
+	// In Central Europe the late backup was taken on the next day
+	cest := time.FixedZone("CEST", 2*60*60)
+	plan = NewPlan("/backups", items, Policy{KeepDaily: 2, Location: cest}, now)
+	if len(plan.Kept()) != 2 || plan.Kept()[0].Reason != "daily 2025-06-30" {
+		t.Errorf("Expected two daily backups in CEST, got %+v", plan.Kept())
+	}
+}
+
+func TestPlanKeepsIncrementalChains(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	items := []Item{
+		{Path: "/b/db_app_20250101_020000.dump", Database: "app", BackupType: "full", Timestamp: now.AddDate(0, 0, -180), ID: "sha-full-old"},
+		{Path: "/b/db_app_20250601_020000.dump", Database: "app", BackupType: "full", Timestamp: now.AddDate(0, 0, -29), ID: "sha-full-new"},
+		{Path: "/b/app_incr_20250102_020000.tar.gz", Database: "app", BackupType: "incremental", Timestamp: now.AddDate(0, 0, -179),
+			ID: "sha-incr1", BaseID: "sha-full-old"},
+		// Only the file name of its base is known (e.g. read from cloud metadata)
+		{Path: "/b/app_incr_20250103_020000.tar.gz", Database: "app", BackupType: "incremental", Timestamp: now.AddDate(0, 0, -1),
+			BaseName: "app_incr_20250102_020000.tar.gz"},
+	}
+
+	for name, policy := range map[string]Policy{
+		"days":  {RetentionDays: 30},
+		"tiers": {KeepLast: 1},
+	} {
+		plan := NewPlan("/b", items, policy, now)
+		reasons := make(map[string]string)
+		for _, d := range plan.Kept() {
+			reasons[d.Path] = d.Reason
+		}
+		if got := reasons["/b/app_incr_20250102_020000.tar.gz"]; got != "base of kept app_incr_20250103_020000.tar.gz" {
+			t.Errorf("%s: expected the first incremental to be kept as base, got %q", name, got)
+		}
+		if got := reasons["/b/db_app_20250101_020000.dump"]; got != "base of kept app_incr_20250102_020000.tar.gz" {
+			t.Errorf("%s: expected the full backup to be kept as base of the chain, got %q", name, got)
+		}
+		if len(plan.Deletions()) != 0 {
+			t.Errorf("%s: expected nothing to be deleted, got %+v", name, plan.Deletions())
+		}
+	}
+}
+
+func TestParseWithin(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":    0,
+		"36h": 36 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"1y":  365 * 24 * time.Hour,
+	}
+	for in, want := range tests {
+		if got, err := ParseWithin(in); err != nil || got != want {
+			t.Errorf("ParseWithin(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"d", "-1d", "7x", "soon"} {
+		if _, err := ParseWithin(in); err == nil {
+			t.Errorf("Expected %q to be rejected", in)
+		}
+	}
+	if got := FormatWithin(7 * 24 * time.Hour); got != "7d" {
+		t.Errorf("FormatWithin = %s", got)
+	}
+}
@@ -0,0 +1,72 @@
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"dbbackup/internal/cloud"
+)
+
+// TierRule moves backups older than Age to StorageClass
+type TierRule struct {
+	Age          time.Duration
+	StorageClass string
+}
+
+// ParseTierRule parses a --rule given as age=class, e.g. "30d=STANDARD_IA".
+// The class is checked against the backend when the rules are planned.
+func ParseTierRule(spec string) (TierRule, error) {
+	age, class, ok := strings.Cut(spec, "=")
+	age = strings.TrimSpace(age)
+	class = strings.TrimSpace(class)
+	if !ok || age == "" || class == "" {
+		return TierRule{}, fmt.Errorf("invalid rule %q: expected age=class (e.g. 30d=STANDARD_IA)", spec)
+	}
+	d, err := ParseWithin(age)
+	if err != nil {
+		return TierRule{}, fmt.Errorf("invalid rule %q: %w", spec, err)
+	}
+	return TierRule{Age: d, StorageClass: class}, nil
+}
+
+// Transition moves one backup file to a colder storage class
+type Transition struct {
+	Item
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PlanTransitions returns the backups that the rules move to a colder class.
+// A backup gets the coldest class of the rules its age matches; backups are
+// never moved to a warmer class. Only the backup files move: metadata stays
+// readable in the default class.
+func PlanTransitions(backend string, items []Item, rules []TierRule, now time.Time) ([]Transition, error) {
+	normalized := make([]TierRule, len(rules))
+	for i, rule := range rules {
+		class, err := cloud.NormalizeStorageClass(backend, rule.StorageClass)
+		if err != nil {
+			return nil, err
+		}
+		normalized[i] = TierRule{Age: rule.Age, StorageClass: class}
+	}
+
+	var transitions []Transition
+	for _, item := range items {
+		target := ""
+		for _, rule := range normalized {
+			if item.Timestamp.Before(now.Add(-rule.Age)) && (target == "" || cloud.Colder(backend, rule.StorageClass, target)) {
+				target = rule.StorageClass
+			}
+		}
+		if target != "" && cloud.Colder(backend, target, item.StorageClass) {
+			transitions = append(transitions, Transition{Item: item, From: item.StorageClass, To: target})
+		}
+	}
+
+	sort.Slice(transitions, func(i, j int) bool {
+		return transitions[i].Timestamp.Before(transitions[j].Timestamp)
+	})
+	return transitions, nil
+}
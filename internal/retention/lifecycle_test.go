@@ -0,0 +1,53 @@
+package retention
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlanTransitions(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	items := dailyItems(now.AddDate(0, 0, -120), now)
+	items[0].StorageClass = "DEEP_ARCHIVE" // Already colder than any rule
+	items[1].StorageClass = "GLACIER"      // Already where the rules want it
+
+	var rules []TierRule
+	for _, spec := range []string{"90d=glacier", "30d=STANDARD_IA"} {
+		rule, err := ParseTierRule(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+
+	transitions, err := PlanTransitions("s3", items, rules, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+	for _, tr := range transitions {
+		counts[tr.To]++
+		age := now.Sub(tr.Timestamp)
+		if (tr.To == "GLACIER") != (age > 90*24*time.Hour) {
+			t.Errorf("%s (%s old) moved to %s", tr.Path, age, tr.To)
+		}
+		if tr.Path == items[0].Path || tr.Path == items[1].Path {
+			t.Errorf("%s in %s should stay where it is", tr.Path, tr.From)
+		}
+	}
+	// The backups taken 30-89 days ago go to STANDARD_IA, the 31 older ones
+	// to GLACIER except the two above
+	if counts["STANDARD_IA"] != 60 || counts["GLACIER"] != 29 {
+		t.Errorf("Unexpected transitions: %v", counts)
+	}
+
+	if _, err := PlanTransitions("azure", items, rules, now); err == nil {
+		t.Error("Expected S3 storage classes to be rejected for Azure")
+	}
+	for _, spec := range []string{"30d", "=Cool", "soon=Cool"} {
+		if _, err := ParseTierRule(spec); err == nil {
+			t.Errorf("Expected rule %q to be rejected", spec)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"dbbackup/internal/cloud"
+	"dbbackup/internal/metadata"
+)
+
+// companionSuffixes are the files stored next to a backup that belong to it.
+// They are kept and deleted together with the backup.
+var companionSuffixes = []string{".meta.json", ".sha256", ".info"}
+
+// Item is a backup considered by the planner, with its companion files
+type Item struct {
+	Path       string    `json:"path"` // Local path or object key of the backup file
+	Database   string    `json:"database"`
+	BackupType string    `json:"backup_type"`
+	Timestamp  time.Time `json:"timestamp"`
+	SizeBytes  int64     `json:"size_bytes"` // Backup file plus companions
+	Companions []string  `json:"companions,omitempty"`
+
+	// Incremental chains: the SHA-256 of this backup and the SHA-256 or file
+	// name of the backup an incremental was taken against
+	ID       string `json:"id,omitempty"`
+	BaseID   string `json:"base_id,omitempty"`
+	BaseName string `json:"base_name,omitempty"`
+
+	// Storage class of a cloud backup file ("" when unknown or local)
+	StorageClass string `json:"storage_class,omitempty"`
+}
+
+// Decision is what the plan does with one backup, and why
+type Decision struct {
+	Item
+	Reason string `json:"reason"`
+}
+
+// Group holds the decisions for the backups of one database and backup type,
+// newest first
+type Group struct {
+	Database   string     `json:"database"`
+	BackupType string     `json:"backup_type"`
+	Keep       []Decision `json:"keep"`
+	Delete     []Decision `json:"delete"`
+}
+
+// Plan is a reviewable list of the backups a retention policy keeps and
+// deletes. It can be saved as JSON and applied later.
+type Plan struct {
+	Location      string    `json:"location"` // Directory or cloud URI
+	CreatedAt     time.Time `json:"created_at"`
+	RetentionDays int       `json:"retention_days"`
+	MinBackups    int       `json:"min_backups"` // Per database and backup type
+	Tiers         string    `json:"tiers,omitempty"`
+	Groups        []Group   `json:"groups"`
+
+	// Companion files whose backup is gone. They are listed, never deleted.
+	Orphans []string `json:"orphans,omitempty"`
+}
+
+// NewPlan groups backups by database and backup type and decides for each
+// one: the policy.MinBackups newest of every group are kept, the others are
+// deleted once they are older than policy.RetentionDays, or, with tiers,
+// unless a tier selects them. The base of a kept incremental is always kept.
+func NewPlan(location string, items []Item, policy Policy, now time.Time) *Plan {
+	plan := &Plan{
+		Location:      location,
+		CreatedAt:     now,
+		RetentionDays: policy.RetentionDays,
+		MinBackups:    policy.MinBackups,
+		Tiers:         policy.TiersString(),
+		Groups:        make([]Group, 0),
+	}
+	if policy.HasTiers() {
+		plan.RetentionDays = 0
+	}
+
+	byGroup := make(map[[2]string][]Item)
+	for _, item := range items {
+		id := [2]string{item.Database, item.BackupType}
+		byGroup[id] = append(byGroup[id], item)
+	}
+
+	cutoff := now.AddDate(0, 0, -policy.RetentionDays)
+	for id, groupItems := range byGroup {
+		sort.Slice(groupItems, func(i, j int) bool {
+			return groupItems[i].Timestamp.After(groupItems[j].Timestamp)
+		})
+
+		group := Group{Database: id[0], BackupType: id[1], Keep: make([]Decision, 0), Delete: make([]Decision, 0)}
+		if policy.HasTiers() {
+			reasons := selectTiers(groupItems, policy, now)
+			for i, item := range groupItems {
+				if len(reasons[i]) > 0 {
+					group.Keep = append(group.Keep, Decision{item, strings.Join(reasons[i], ", ")})
+				} else {
+					group.Delete = append(group.Delete, Decision{item, "not selected by any tier"})
+				}
+			}
+			plan.Groups = append(plan.Groups, group)
+			continue
+		}
+		for i, item := range groupItems {
+			switch {
+			case i < policy.MinBackups:
+				group.Keep = append(group.Keep, Decision{item, fmt.Sprintf("one of the %d newest", policy.MinBackups)})
+			case !item.Timestamp.Before(cutoff):
+				group.Keep = append(group.Keep, Decision{item, fmt.Sprintf("within %d days", policy.RetentionDays)})
+			default:
+				group.Delete = append(group.Delete, Decision{item, fmt.Sprintf("older than %d days", policy.RetentionDays)})
+			}
+		}
+		plan.Groups = append(plan.Groups, group)
+	}
+
+	sort.Slice(plan.Groups, func(i, j int) bool {
+		if plan.Groups[i].Database != plan.Groups[j].Database {
+			return plan.Groups[i].Database < plan.Groups[j].Database
+		}
+		return plan.Groups[i].BackupType < plan.Groups[j].BackupType
+	})
+	protectChains(plan)
+	return plan
+}
+
+// Kept returns the backups the plan keeps
+func (p *Plan) Kept() []Decision {
+	var kept []Decision
+	for _, g := range p.Groups {
+		kept = append(kept, g.Keep...)
+	}
+	return kept
+}
+
+// Deletions returns the backups the plan deletes
+func (p *Plan) Deletions() []Decision {
+	var deletions []Decision
+	for _, g := range p.Groups {
+		deletions = append(deletions, g.Delete...)
+	}
+	return deletions
+}
+
+// ReclaimBytes returns the space deleting the planned backups frees
+func (p *Plan) ReclaimBytes() int64 {
+	var total int64
+	for _, d := range p.Deletions() {
+		total += d.SizeBytes
+	}
+	return total
+}
+
+// Apply deletes the planned backups with remove, each backup file before its
+// companions. Companions of a backup that couldn't be deleted are left alone,
+// so the backup stays usable. With dryRun nothing is removed.
+func (p *Plan) Apply(remove func(path string) error, dryRun bool) *CleanupResult {
+	result := &CleanupResult{
+		Deleted: make([]string, 0),
+		Kept:    make([]string, 0),
+		Errors:  make([]error, 0),
+	}
+	for _, d := range p.Kept() {
+		result.Kept = append(result.Kept, d.Path)
+	}
+
+	deletions := p.Deletions()
+	result.TotalBackups = len(result.Kept) + len(deletions)
+	result.EligibleForDeletion = len(deletions)
+
+	for _, d := range deletions {
+		if dryRun {
+			result.Deleted = append(result.Deleted, d.Path)
+			continue
+		}
+		if err := remove(d.Path); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to delete %s: %w", d.Path, err))
+			continue
+		}
+		result.Deleted = append(result.Deleted, d.Path)
+		result.SpaceFreed += d.SizeBytes
+		for _, companion := range d.Companions {
+			if err := remove(companion); err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("failed to delete %s: %w", companion, err))
+			}
+		}
+	}
+	return result
+}
+
+// Save writes the plan as JSON
+func (p *Plan) Save(file string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// LoadPlan reads a plan saved with Save
+func LoadPlan(file string) (*Plan, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	return &plan, nil
+}
+
+// LocalItems returns the backups in a directory that have a .meta.json.
+// Cluster metadata lacks the database and backup type, so those come from
+// the file name like for cloud backups.
+func LocalItems(dir string) ([]Item, error) {
+	metaFiles, err := filepath.Glob(filepath.Join(dir, "*.meta.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan directory: %w", err)
+	}
+
+	items := make([]Item, 0, len(metaFiles))
+	for _, metaFile := range metaFiles {
+		// The metadata may have been written before the backup was moved here,
+		// so its location is where the backup is
+		backupFile := strings.TrimSuffix(metaFile, ".meta.json")
+		meta, err := metadata.Load(backupFile)
+		if err != nil {
+			// Skip invalid metadata files
+			continue
+		}
+
+		info, err := os.Stat(backupFile)
+		if err != nil {
+			// Only the metadata is left: an orphan, not a backup
+			continue
+		}
+
+		database, backupType, ts, _ := parseBackupName(filepath.Base(backupFile))
+		item := Item{
+			Path:       backupFile,
+			Database:   meta.Database,
+			BackupType: meta.BackupType,
+			Timestamp:  meta.Timestamp,
+			SizeBytes:  info.Size(),
+			ID:         meta.SHA256,
+		}
+		if meta.Incremental != nil {
+			item.BaseID = meta.Incremental.BaseBackupID
+			if meta.Incremental.BaseBackupPath != "" {
+				item.BaseName = filepath.Base(meta.Incremental.BaseBackupPath)
+			}
+		}
+		if item.Database == "" {
+			item.Database = database
+		}
+		if item.BackupType == "" {
+			item.BackupType = backupType
+		}
+		if item.Timestamp.IsZero() {
+			item.Timestamp = ts
+			if ts.IsZero() {
+				item.Timestamp = info.ModTime()
+			}
+		}
+
+		for _, suffix := range companionSuffixes {
+			if info, err := os.Stat(backupFile + suffix); err == nil {
+				item.Companions = append(item.Companions, backupFile+suffix)
+				item.SizeBytes += info.Size()
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// FileItem returns the item of a local backup without metadata. Like for
+// cloud backups, database, backup type and time come from its file name,
+// falling back to the file's modification time, so it is grouped with the
+// backups of the same database that have metadata.
+func FileItem(backupFile string, modTime time.Time, size int64) Item {
+	database, backupType, ts, ok := parseBackupName(filepath.Base(backupFile))
+	if !ok {
+		ts = modTime
+	}
+	return Item{
+		Path:       backupFile,
+		Database:   database,
+		BackupType: backupType,
+		Timestamp:  ts,
+		SizeBytes:  size,
+	}
+}
+
+// CloudItems groups listed objects into backups and their companions.
+// Database, backup type and time come from the backup's file name, falling
+// back to the object's modification time. Companions without a backup are
+// returned as orphans.
+func CloudItems(files []cloud.BackupInfo) (items []Item, orphans []string) {
+	backups := make(map[string]int)
+	for _, f := range files {
+		if _, ok := companionOf(f.Key); ok {
+			continue
+		}
+		database, backupType, ts, ok := parseBackupName(path.Base(f.Key))
+		if !ok {
+			ts = f.LastModified
+		}
+		backups[f.Key] = len(items)
+		items = append(items, Item{
+			Path:         f.Key,
+			Database:     database,
+			BackupType:   backupType,
+			Timestamp:    ts,
+			SizeBytes:    f.Size,
+			StorageClass: f.StorageClass,
+		})
+	}
+
+	for _, f := range files {
+		backup, ok := companionOf(f.Key)
+		if !ok {
+			continue
+		}
+		if i, found := backups[backup]; found {
+			items[i].Companions = append(items[i].Companions, f.Key)
+			items[i].SizeBytes += f.Size
+		} else {
+			orphans = append(orphans, f.Key)
+		}
+	}
+	return items, orphans
+}
+
+// ReadCloudChains reads the .meta.json of every incremental backup to learn
+// which backup it was taken against, so that the plan keeps the bases of
+// the incrementals it keeps. Other backups are matched by file name.
+func ReadCloudChains(ctx context.Context, backend cloud.Backend, items []Item) error {
+	for i := range items {
+		if items[i].BackupType != "incremental" {
+			continue
+		}
+		metaKey := items[i].Path + ".meta.json"
+		if !slices.Contains(items[i].Companions, metaKey) {
+			continue
+		}
+
+		r, err := backend.DownloadStream(ctx, metaKey)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", metaKey, err)
+		}
+		var meta metadata.BackupMetadata
+		err = json.NewDecoder(r).Decode(&meta)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", metaKey, err)
+		}
+
+		items[i].ID = meta.SHA256
+		if meta.Incremental != nil {
+			items[i].BaseID = meta.Incremental.BaseBackupID
+			if meta.Incremental.BaseBackupPath != "" {
+				items[i].BaseName = path.Base(filepath.ToSlash(meta.Incremental.BaseBackupPath))
+			}
+		}
+	}
+	return nil
+}
+
+// KeepLocked moves the backups a plan would delete that are still under
+// object lock to the kept ones: the storage would refuse the delete, or on
+// S3 only hide the backup behind a delete marker. It returns the backups it
+// kept.
+func KeepLocked(ctx context.Context, backend cloud.Backend, plan *Plan) ([]Decision, error) {
+	lb, ok := backend.(cloud.LockingBackend)
+	if !ok {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var locked []Decision
+	for gi := range plan.Groups {
+		group := &plan.Groups[gi]
+		var deletions []Decision
+		for _, d := range group.Delete {
+			lock, err := lb.LockStatus(ctx, d.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check object lock of %s: %w", d.Path, err)
+			}
+			if !lock.Active(now) {
+				deletions = append(deletions, d)
+				continue
+			}
+			d.Reason = "immutable, " + lock.String()
+			group.Keep = append(group.Keep, d)
+			locked = append(locked, d)
+		}
+		group.Delete = deletions
+		sort.SliceStable(group.Keep, func(i, j int) bool {
+			return group.Keep[i].Timestamp.After(group.Keep[j].Timestamp)
+		})
+	}
+
+	// A locked incremental needs its base as much as any other kept one
+	protectChains(plan)
+	return locked, nil
+}
+
+// companionOf returns the backup key a companion file belongs to. A file
+// with a companion suffix whose backup isn't listed is still a companion
+// (an orphan), never a backup of its own.
+func companionOf(key string) (string, bool) {
+	for _, suffix := range companionSuffixes {
+		if backup, ok := strings.CutSuffix(key, suffix); ok && backup != "" {
+			return backup, true
+		}
+	}
+	return "", false
+}
+
+// backupNamePattern matches the timestamp every backup file name ends with
+var backupNamePattern = regexp.MustCompile(`^(.+)_(\d{8}_\d{6})(\..*)?$`)
+
+// parseBackupName derives the database, backup type and creation time from
+// a file name written by dbbackup:
+//
+//	db_<database>_<timestamp>.dump              single
+//	<database>_incr_<timestamp>.tar.gz          incremental
+//	sample_<database>_<strategy><n>_<timestamp> sample
+//	cluster_<timestamp>.tar.gz                  cluster
+//	base_<timestamp>.tar.gz                     base (physical, whole cluster)
+//
+// ok is false for other names; database is then the name without extensions.
+func parseBackupName(name string) (database, backupType string, ts time.Time, ok bool) {
+	m := backupNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		stem, _, _ := strings.Cut(name, ".")
+		return stem, "", time.Time{}, false
+	}
+	ts, err := time.ParseInLocation("20060102_150405", m[2], time.Local)
+	if err != nil {
+		stem, _, _ := strings.Cut(name, ".")
+		return stem, "", time.Time{}, false
+	}
+
+	prefix := m[1]
+	switch {
+	case prefix == "cluster":
+		return "cluster", "cluster", ts, true
+	case prefix == "base":
+		return "cluster", "base", ts, true
+	case strings.HasPrefix(prefix, "db_"):
+		return strings.TrimPrefix(prefix, "db_"), "single", ts, true
+	case strings.HasSuffix(prefix, "_incr"):
+		return strings.TrimSuffix(prefix, "_incr"), "incremental", ts, true
+	case strings.HasPrefix(prefix, "sample_"):
+		database := strings.TrimPrefix(prefix, "sample_")
+		if i := strings.LastIndex(database, "_"); i > 0 {
+			database = database[:i]
+		}
+		return database, "sample", ts, true
+	}
+	return prefix, "", ts, true
+}
+
+// FilterItems returns the items whose file name matches a glob pattern
+func FilterItems(items []Item, pattern string) ([]Item, error) {
+	if pattern == "" {
+		return items, nil
+	}
+	var matched []Item
+	for _, item := range items {
+		ok, err := filepath.Match(pattern, path.Base(filepath.ToSlash(item.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to match pattern: %w", err)
+		}
+		if ok {
+			matched = append(matched, item)
+		}
+	}
+	return matched, nil
+}
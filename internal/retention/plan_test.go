@@ -0,0 +1,292 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"dbbackup/internal/cloud"
+	"dbbackup/internal/metadata"
+)
+
+var planNow = time.Date(2025, 6, 30, 12, 0, 0, 0, time.Local)
+
+func daysAgo(n int) time.Time {
+	return planNow.AddDate(0, 0, -n)
+}
+
+func TestNewPlanGroupsByDatabase(t *testing.T) {
+	var items []Item
+	for _, age := range []int{1, 10, 20, 40} {
+		items = append(items, Item{Path: "app_" + daysAgo(age).Format("0102"), Database: "app", BackupType: "single", Timestamp: daysAgo(age)})
+	}
+	// A rarely backed up database keeps its newest backups even when old
+	for _, age := range []int{50, 60, 70} {
+		items = append(items, Item{Path: "crm_" + daysAgo(age).Format("0102"), Database: "crm", BackupType: "single", Timestamp: daysAgo(age)})
+	}
+	items = append(items, Item{Path: "app_incr", Database: "app", BackupType: "incremental", Timestamp: daysAgo(30)})
+
+	plan := NewPlan("/backups", items, Policy{RetentionDays: 7, MinBackups: 2}, planNow)
+
+	got := make(map[string]string)
+	for _, g := range plan.Groups {
+		var keep, del []string
+		for _, d := range g.Keep {
+			keep = append(keep, d.Path)
+		}
+		for _, d := range g.Delete {
+			del = append(del, d.Path)
+		}
+		got[g.Database+"/"+g.BackupType] = strings.Join(keep, ",") + " | " + strings.Join(del, ",")
+	}
+	want := map[string]string{
+		"app/single":      "app_" + daysAgo(1).Format("0102") + ",app_" + daysAgo(10).Format("0102") + " | app_" + daysAgo(20).Format("0102") + ",app_" + daysAgo(40).Format("0102"),
+		"app/incremental": "app_incr | ",
+		"crm/single":      "crm_" + daysAgo(50).Format("0102") + ",crm_" + daysAgo(60).Format("0102") + " | crm_" + daysAgo(70).Format("0102"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Unexpected groups %v", got)
+	}
+	for group, w := range want {
+		if got[group] != w {
+			t.Errorf("%s: got %q, want %q", group, got[group], w)
+		}
+	}
+	if plan.Groups[0].Database != "app" || plan.Groups[0].BackupType != "incremental" {
+		t.Errorf("Expected groups sorted by database and type, got %s/%s first", plan.Groups[0].Database, plan.Groups[0].BackupType)
+	}
+}
+
+func TestFileItem(t *testing.T) {
+	modTime := time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		file, database, backupType string
+		ts                         time.Time
+	}{
+		{"/backups/db_app_20250101_020000.dump", "app", "single", time.Date(2025, 1, 1, 2, 0, 0, 0, time.Local)},
+		{"/backups/app_incr_20250102_020000.tar.gz", "app", "incremental", time.Date(2025, 1, 2, 2, 0, 0, 0, time.Local)},
+		{"/backups/cluster_20250103_020000.tar.gz", "cluster", "cluster", time.Date(2025, 1, 3, 2, 0, 0, 0, time.Local)},
+		{"/backups/manual.sql.gz", "manual", "", modTime},
+	}
+	for _, tt := range tests {
+		item := FileItem(tt.file, modTime, 42)
+		if item.Path != tt.file || item.Database != tt.database || item.BackupType != tt.backupType ||
+			!item.Timestamp.Equal(tt.ts) || item.SizeBytes != 42 {
+			t.Errorf("FileItem(%s) = %+v", tt.file, item)
+		}
+	}
+
+	// An archive without metadata joins the group of the backups with it
+	withMeta := Item{Path: "/backups/db_app_20250104_020000.dump", Database: "app", BackupType: "single", Timestamp: daysAgo(1)}
+	plan := NewPlan("/backups", []Item{withMeta, FileItem(tests[0].file, modTime, 42)}, Policy{RetentionDays: 7, MinBackups: 1}, planNow)
+	if len(plan.Groups) != 1 {
+		t.Errorf("Expected one group, got %d", len(plan.Groups))
+	}
+}
+
+func TestCloudItems(t *testing.T) {
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := []cloud.BackupInfo{
+		{Key: "backups/db_app_20250101_020000.dump", Size: 100},
+		{Key: "backups/db_app_20250101_020000.dump.meta.json", Size: 2},
+		{Key: "backups/db_app_20250101_020000.dump.sha256", Size: 3},
+		{Key: "backups/db_my_app_20250102_020000.sql.zst", Size: 10},
+		{Key: "backups/cluster_20250103_020000.tar.gz", Size: 1000},
+		{Key: "backups/app_incr_20250104_020000.tar.gz", Size: 5},
+		{Key: "backups/sample_app_ratio10_20250105_020000.sql", Size: 1},
+		{Key: "backups/db_gone_20240101_020000.dump.meta.json", Size: 2},
+		{Key: "backups/manual.dump", Size: 7, LastModified: old},
+	}
+
+	items, orphans := CloudItems(files)
+	if strings.Join(orphans, ",") != "backups/db_gone_20240101_020000.dump.meta.json" {
+		t.Errorf("Unexpected orphans %v", orphans)
+	}
+
+	byPath := make(map[string]Item)
+	for _, item := range items {
+		byPath[item.Path] = item
+	}
+	if len(byPath) != 6 {
+		t.Fatalf("Expected 6 backups, got %v", items)
+	}
+
+	app := byPath["backups/db_app_20250101_020000.dump"]
+	sort.Strings(app.Companions)
+	if app.SizeBytes != 105 || strings.Join(app.Companions, ",") != "backups/db_app_20250101_020000.dump.meta.json,backups/db_app_20250101_020000.dump.sha256" {
+		t.Errorf("Expected companions to belong to the backup, got %+v", app)
+	}
+	if !app.Timestamp.Equal(time.Date(2025, 1, 1, 2, 0, 0, 0, time.Local)) {
+		t.Errorf("Expected time from the file name, got %v", app.Timestamp)
+	}
+
+	for key, want := range map[string]string{
+		"backups/db_app_20250101_020000.dump":            "app/single",
+		"backups/db_my_app_20250102_020000.sql.zst":      "my_app/single",
+		"backups/cluster_20250103_020000.tar.gz":         "cluster/cluster",
+		"backups/app_incr_20250104_020000.tar.gz":        "app/incremental",
+		"backups/sample_app_ratio10_20250105_020000.sql": "app/sample",
+		"backups/manual.dump":                            "manual/",
+	} {
+		if got := byPath[key].Database + "/" + byPath[key].BackupType; got != want {
+			t.Errorf("%s: got %s, want %s", key, got, want)
+		}
+	}
+	if !byPath["backups/manual.dump"].Timestamp.Equal(old) {
+		t.Error("Expected modification time for a name without timestamp")
+	}
+}
+
+// writeLocalBackup creates a backup file with metadata and a .sha256 file
+func writeLocalBackup(t *testing.T, dir, name, database string, ts time.Time) string {
+	t.Helper()
+	backupFile := filepath.Join(dir, name)
+	if err := os.WriteFile(backupFile, []byte("dump"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(backupFile+".sha256", []byte("sum"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	meta := &metadata.BackupMetadata{
+		Database:   database,
+		BackupType: "single",
+		Timestamp:  ts,
+		// Written on another host before the backup was copied here
+		BackupFile: "/var/lib/dbbackup/" + name,
+	}
+	if err := metadata.Save(backupFile+".meta.json", meta); err != nil {
+		t.Fatal(err)
+	}
+	return backupFile
+}
+
+func TestApplyPolicyDeletesCompanions(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	oldApp := writeLocalBackup(t, dir, "db_app_1.dump", "app", now.AddDate(0, 0, -40))
+	writeLocalBackup(t, dir, "db_app_2.dump", "app", now.AddDate(0, 0, -1))
+	oldCRM := writeLocalBackup(t, dir, "db_crm_1.dump", "crm", now.AddDate(0, 0, -40))
+
+	dry, err := ApplyPolicy(dir, Policy{RetentionDays: 30, MinBackups: 1, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dry.Deleted) != 1 || dry.Deleted[0] != oldApp {
+		t.Fatalf("Expected only the old app backup to be planned for deletion, got %v", dry.Deleted)
+	}
+	if _, err := os.Stat(oldApp); err != nil {
+		t.Fatal("Dry run deleted a backup")
+	}
+
+	result, err := ApplyPolicy(dir, Policy{RetentionDays: 30, MinBackups: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Deleted) != 1 || len(result.Errors) != 0 || result.TotalBackups != 3 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	for _, file := range []string{oldApp, oldApp + ".meta.json", oldApp + ".sha256"} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be deleted", filepath.Base(file))
+		}
+	}
+	// The only crm backup is kept however old it is
+	if _, err := os.Stat(oldCRM); err != nil {
+		t.Error("Expected the newest crm backup to be kept")
+	}
+}
+
+func TestPlanApplyKeepsCompanionsOnFailure(t *testing.T) {
+	plan := &Plan{Groups: []Group{{
+		Database: "app",
+		Delete: []Decision{
+			{Item: Item{Path: "a.dump", Companions: []string{"a.dump.meta.json"}, SizeBytes: 10}},
+			{Item: Item{Path: "b.dump", Companions: []string{"b.dump.meta.json"}, SizeBytes: 20}},
+		},
+	}}}
+
+	var removed []string
+	result := plan.Apply(func(path string) error {
+		if path == "a.dump" {
+			return errors.New("access denied")
+		}
+		removed = append(removed, path)
+		return nil
+	}, false)
+
+	if strings.Join(removed, ",") != "b.dump,b.dump.meta.json" {
+		t.Errorf("Unexpected removals %v", removed)
+	}
+	if len(result.Errors) != 1 || result.SpaceFreed != 20 || len(result.Deleted) != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+}
+
+func TestPlanSaveLoad(t *testing.T) {
+	items := []Item{{Path: "s3-key.dump", Database: "app", BackupType: "single", Timestamp: daysAgo(90), Companions: []string{"s3-key.dump.meta.json"}}}
+	plan := NewPlan("s3://bucket/backups/", items, Policy{RetentionDays: 30}, planNow)
+
+	file := filepath.Join(t.TempDir(), "plan.json")
+	if err := plan.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPlan(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletions := loaded.Deletions()
+	if loaded.Location != "s3://bucket/backups/" || len(deletions) != 1 || deletions[0].Reason != "older than 30 days" ||
+		deletions[0].Companions[0] != "s3-key.dump.meta.json" {
+		t.Errorf("Plan didn't round-trip: %+v", loaded)
+	}
+}
+
+// lockingBackend reports a lock for some keys
+type lockingBackend struct {
+	cloud.Backend
+	locks map[string]*cloud.ObjectLock
+}
+
+func (b *lockingBackend) LockStatus(ctx context.Context, key string) (*cloud.ObjectLock, error) {
+	return b.locks[key], nil
+}
+
+func TestKeepLocked(t *testing.T) {
+	until := time.Now().AddDate(0, 1, 0)
+	backend := &lockingBackend{locks: map[string]*cloud.ObjectLock{
+		"b/app_incr_20250502_020000.tar.gz": {Mode: cloud.LockCompliance, Until: until},
+		"b/db_app_20250402_020000.dump":     {Mode: cloud.LockGovernance, Until: time.Now().AddDate(0, 0, -1)},
+	}}
+	items := []Item{
+		{Path: "b/db_app_20250401_020000.dump", Database: "app", BackupType: "full", Timestamp: daysAgo(90)},
+		{Path: "b/db_app_20250402_020000.dump", Database: "app", BackupType: "full", Timestamp: daysAgo(89)},
+		{Path: "b/db_app_20250629_020000.dump", Database: "app", BackupType: "full", Timestamp: daysAgo(1)},
+		{Path: "b/app_incr_20250502_020000.tar.gz", Database: "app", BackupType: "incremental", Timestamp: daysAgo(59),
+			BaseName: "db_app_20250401_020000.dump"},
+		{Path: "b/app_incr_20250629_020000.tar.gz", Database: "app", BackupType: "incremental", Timestamp: daysAgo(1)},
+	}
+	plan := NewPlan("s3://bucket/b", items, Policy{RetentionDays: 30, MinBackups: 1}, planNow)
+
+	locked, err := KeepLocked(context.Background(), backend, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locked) != 1 || locked[0].Path != "b/app_incr_20250502_020000.tar.gz" ||
+		!strings.HasPrefix(locked[0].Reason, "immutable, compliance mode until ") {
+		t.Errorf("Expected only the locked incremental to be kept, got %+v", locked)
+	}
+
+	var deleted []string
+	for _, d := range plan.Deletions() {
+		deleted = append(deleted, d.Path)
+	}
+	// The locked incremental's base is kept too; the expired lock doesn't count
+	if strings.Join(deleted, ",") != "b/db_app_20250402_020000.dump" {
+		t.Errorf("Unexpected deletions %v", deleted)
+	}
+}
@@ -48,7 +48,7 @@ func ValidateArchivePath(path string) (string, error) {
 
 	// Must have a valid archive extension
 	ext := strings.ToLower(filepath.Ext(cleaned))
-	validExtensions := []string{".dump", ".sql", ".gz", ".tar"}
+	validExtensions := []string{".dump", ".sql", ".gz", ".zst", ".lz4", ".tar"}
 	
 	valid := false
 	for _, validExt := range validExtensions {
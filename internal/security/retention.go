@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"dbbackup/internal/logger"
+	"dbbackup/internal/retention"
 )
 
 // RetentionPolicy defines backup retention rules
@@ -15,6 +16,16 @@ type RetentionPolicy struct {
 	RetentionDays int
 	MinBackups    int // Minimum backups to keep regardless of age
 	log           logger.Logger
+
+	// Grandfather-father-son tiers (see retention.Policy). When any is set,
+	// backups are kept per database by tier and RetentionDays is not used.
+	KeepLast    int
+	KeepWithin  time.Duration
+	KeepDaily   int
+	KeepWeekly  int
+	KeepMonthly int
+	KeepYearly  int
+	Location    *time.Location
 }
 
 // NewRetentionPolicy creates a new retention policy
@@ -34,8 +45,25 @@ type ArchiveInfo struct {
 	Database string
 }
 
+// policy returns the tiers as a retention planner policy
+func (rp *RetentionPolicy) policy() retention.Policy {
+	return retention.Policy{
+		MinBackups:  rp.MinBackups,
+		KeepLast:    rp.KeepLast,
+		KeepWithin:  rp.KeepWithin,
+		KeepDaily:   rp.KeepDaily,
+		KeepWeekly:  rp.KeepWeekly,
+		KeepMonthly: rp.KeepMonthly,
+		KeepYearly:  rp.KeepYearly,
+		Location:    rp.Location,
+	}
+}
+
 // CleanupOldBackups removes backups older than retention period
 func (rp *RetentionPolicy) CleanupOldBackups(backupDir string) (int, int64, error) {
+	if rp.policy().HasTiers() {
+		return rp.cleanupByTiers(backupDir)
+	}
 	if rp.RetentionDays <= 0 {
 		return 0, 0, nil // Retention disabled
 	}
@@ -109,6 +137,55 @@ func (rp *RetentionPolicy) CleanupOldBackups(backupDir string) (int, int64, erro
 	return deletedCount, freedSpace, nil
 }
 
+// cleanupByTiers removes the backups no tier keeps. Backups are grouped by
+// database and backup type, taken from the file name of archives without
+// metadata, and backups with metadata keep their incremental chains.
+func (rp *RetentionPolicy) cleanupByTiers(backupDir string) (int, int64, error) {
+	items, err := retention.LocalItems(backupDir)
+	if err != nil {
+		return 0, 0, err
+	}
+	archives, err := rp.scanBackupArchives(backupDir)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to scan backup directory: %w", err)
+	}
+
+	known := make(map[string]bool, len(items))
+	for _, item := range items {
+		known[item.Path] = true
+	}
+	for _, archive := range archives {
+		if known[archive.Path] {
+			continue
+		}
+		item := retention.FileItem(archive.Path, archive.ModTime, archive.Size)
+		if _, err := os.Stat(archive.Path + ".sha256"); err == nil {
+			item.Companions = append(item.Companions, archive.Path+".sha256")
+		}
+		items = append(items, item)
+	}
+
+	plan := retention.NewPlan(backupDir, items, rp.policy(), time.Now())
+	for _, d := range plan.Deletions() {
+		rp.log.Info("Removing old backup",
+			"file", filepath.Base(d.Path),
+			"age_days", int(time.Since(d.Timestamp).Hours()/24),
+			"reason", d.Reason)
+	}
+
+	result := plan.Apply(retention.RemoveFile, false)
+	for _, err := range result.Errors {
+		rp.log.Warn("Failed to remove old backup", "error", err)
+	}
+	if len(result.Deleted) > 0 {
+		rp.log.Info("Cleanup completed",
+			"deleted_backups", len(result.Deleted),
+			"freed_space_mb", result.SpaceFreed/1024/1024,
+			"tiers", plan.Tiers)
+	}
+	return len(result.Deleted), result.SpaceFreed, nil
+}
+
 // scanBackupArchives scans directory for backup archives
 func (rp *RetentionPolicy) scanBackupArchives(backupDir string) ([]ArchiveInfo, error) {
 	var archives []ArchiveInfo
@@ -153,6 +230,8 @@ func isBackupArchive(name string) bool {
 	return (filepath.Ext(name) == ".dump" ||
 		filepath.Ext(name) == ".sql" ||
 		filepath.Ext(name) == ".gz" ||
+		filepath.Ext(name) == ".zst" ||
+		filepath.Ext(name) == ".lz4" ||
 		filepath.Ext(name) == ".tar") &&
 		name != ".sha256" &&
 		name != ".meta"
@@ -12,6 +12,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"dbbackup/internal/compression"
 	"dbbackup/internal/config"
 	"dbbackup/internal/logger"
 	"dbbackup/internal/restore"
@@ -367,9 +368,8 @@ func stripFileExtensions(name string) string {
 	// Remove extensions (handle double extensions like .sql.gz.sql.gz)
 	for {
 		oldName := name
-		name = strings.TrimSuffix(name, ".tar.gz")
-		name = strings.TrimSuffix(name, ".dump.gz")
-		name = strings.TrimSuffix(name, ".sql.gz")
+		name = compression.TrimExtension(name)
+		name = strings.TrimSuffix(name, ".tar")
 		name = strings.TrimSuffix(name, ".dump")
 		name = strings.TrimSuffix(name, ".sql")
 		// If no change, we're done
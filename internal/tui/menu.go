@@ -294,7 +294,7 @@ func (m MenuModel) View() string {
 		}
 		selector := fmt.Sprintf("Target Engine: %s", strings.Join(options, menuStyle.Render("  |  ")))
 		s += dbSelectorLabelStyle.Render(selector) + "\n"
-		hint := infoStyle.Render("Switch with ←/→ or t • Cluster backup requires PostgreSQL")
+		hint := infoStyle.Render("Switch with ←/→ or t")
 		s += hint + "\n"
 	}
 
@@ -341,8 +341,8 @@ func (m MenuModel) handleSampleBackup() (tea.Model, tea.Cmd) {
 
 // handleClusterBackup shows confirmation and executes cluster backup
 func (m MenuModel) handleClusterBackup() (tea.Model, tea.Cmd) {
-	if !m.config.IsPostgreSQL() {
-		m.message = errorStyle.Render("❌ Cluster backup is available only for PostgreSQL targets")
+	if !m.config.IsPostgreSQL() && !m.config.IsMySQL() {
+		m.message = errorStyle.Render("❌ Cluster backup is not available for " + m.config.DisplayDatabaseType())
 		return m, nil
 	}
 	confirm := NewConfirmationModelWithAction(m.config, m.logger, m,
@@ -388,8 +388,8 @@ func (m MenuModel) handleRestoreSingle() (tea.Model, tea.Cmd) {
 
 // handleRestoreCluster opens archive browser for cluster restore
 func (m MenuModel) handleRestoreCluster() (tea.Model, tea.Cmd) {
-	if !m.config.IsPostgreSQL() {
-		m.message = errorStyle.Render("❌ Cluster restore is available only for PostgreSQL")
+	if !m.config.IsPostgreSQL() && !m.config.IsMySQL() {
+		m.message = errorStyle.Render("❌ Cluster restore is not available for " + m.config.DisplayDatabaseType())
 		return m, nil
 	}
 	browser := NewArchiveBrowser(m.config, m.logger, m, m.ctx, "restore-cluster")
@@ -289,7 +289,7 @@ func NewSettingsModel(cfg *config.Config, log logger.Logger, parent tea.Model) S
 			DisplayName: "Cloud Provider",
 			Value:       func(c *config.Config) string { return c.CloudProvider },
 			Update: func(c *config.Config, v string) error {
-				providers := []string{"s3", "minio", "b2", "azure", "gcs"}
+				providers := []string{"s3", "minio", "b2", "azure", "gcs", "sftp", "file", "webdav", "webdavs"}
 				currentIdx := -1
 				for i, p := range providers {
 					if c.CloudProvider == p {
@@ -302,7 +302,7 @@ func NewSettingsModel(cfg *config.Config, log logger.Logger, parent tea.Model) S
 				return nil
 			},
 			Type:        "selector",
-			Description: "Cloud storage provider (press Enter to cycle: S3 → MinIO → B2 → Azure → GCS)",
+			Description: "Cloud storage provider (press Enter to cycle: S3 → MinIO → B2 → Azure → GCS → SFTP → File → WebDAV → WebDAVS)",
 		},
 		{
 			Key:         "cloud_bucket",
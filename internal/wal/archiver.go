@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"dbbackup/internal/compression"
 	"dbbackup/internal/config"
 	"dbbackup/internal/logger"
 )
@@ -21,12 +22,22 @@ type Archiver struct {
 
 // ArchiveConfig holds WAL archiving configuration
 type ArchiveConfig struct {
-	ArchiveDir      string // Directory to store archived WAL files
-	CompressWAL     bool   // Compress WAL files with gzip
-	EncryptWAL      bool   // Encrypt WAL files
-	EncryptionKey   []byte // 32-byte key for AES-256-GCM encryption
-	RetentionDays   int    // Days to keep WAL archives
-	VerifyChecksum  bool   // Verify WAL file checksums
+	ArchiveDir           string              // Directory to store archived WAL files
+	CompressWAL          bool                // Compress WAL files
+	Compression          compression.Options // Algorithm and level for CompressWAL; gzip level 6 if unset
+	EncryptWAL           bool                // Encrypt WAL files
+	EncryptionKey        []byte              // 32-byte key for AES-256-GCM encryption
+	EncryptionPassphrase string              // Alternative to EncryptionKey; every file gets its own salt
+	RetentionDays        int                 // Days to keep WAL archives
+	VerifyChecksum       bool                // Verify WAL file checksums
+}
+
+// compressionOptions returns the compressor for WAL files
+func (c ArchiveConfig) compressionOptions() compression.Options {
+	if c.Compression.Algorithm == "" {
+		return compression.Options{Algorithm: compression.Gzip, Level: 6} // balanced
+	}
+	return c.Compression
 }
 
 // WALArchiveInfo contains metadata about an archived WAL file
@@ -77,11 +88,12 @@ func (a *Archiver) ArchiveWALFile(ctx context.Context, walFilePath, walFileName
 	// Process WAL file: compression and/or encryption
 	var archivePath string
 	var archivedSize int64
+	compress := config.CompressWAL && config.compressionOptions().Enabled()
 	
-	if config.CompressWAL && config.EncryptWAL {
+	if compress && config.EncryptWAL {
 		// Compress then encrypt
 		archivePath, archivedSize, err = a.compressAndEncryptWAL(walFilePath, walFileName, config)
-	} else if config.CompressWAL {
+	} else if compress {
 		// Compress only
 		archivePath, archivedSize, err = a.compressWAL(walFilePath, walFileName, config)
 	} else if config.EncryptWAL {
@@ -104,7 +116,7 @@ func (a *Archiver) ArchiveWALFile(ctx context.Context, walFilePath, walFileName
 		Timeline:     timeline,
 		Segment:      segment,
 		ArchivedAt:   time.Now(),
-		Compressed:   config.CompressWAL,
+		Compressed:   compress,
 		Encrypted:    config.EncryptWAL,
 	}
 
@@ -147,12 +159,13 @@ func (a *Archiver) copyWAL(walFilePath, walFileName string, config ArchiveConfig
 	return archivePath, written, nil
 }
 
-// compressWAL compresses a WAL file using gzip
+// compressWAL compresses a WAL file
 func (a *Archiver) compressWAL(walFilePath, walFileName string, config ArchiveConfig) (string, int64, error) {
-	archivePath := filepath.Join(config.ArchiveDir, walFileName+".gz")
+	opts := config.compressionOptions()
+	archivePath := filepath.Join(config.ArchiveDir, walFileName+opts.Algorithm.Extension())
 	
 	compressor := NewCompressor(a.log)
-	compressedSize, err := compressor.CompressWALFile(walFilePath, archivePath, 6) // gzip level 6 (balanced)
+	compressedSize, err := compressor.CompressWALFile(walFilePath, archivePath, opts)
 	if err != nil {
 		return "", 0, fmt.Errorf("WAL compression failed: %w", err)
 	}
@@ -166,7 +179,8 @@ func (a *Archiver) encryptWAL(walFilePath, walFileName string, config ArchiveCon
 	
 	encryptor := NewEncryptor(a.log)
 	encOpts := EncryptionOptions{
-		Key: config.EncryptionKey,
+		Key:        config.EncryptionKey,
+		Passphrase: config.EncryptionPassphrase,
 	}
 	
 	encryptedSize, err := encryptor.EncryptWALFile(walFilePath, archivePath, encOpts)
@@ -186,18 +200,20 @@ func (a *Archiver) compressAndEncryptWAL(walFilePath, walFileName string, config
 	}
 	defer os.RemoveAll(tempDir) // Clean up temp dir
 
-	tempCompressed := filepath.Join(tempDir, walFileName+".gz")
+	opts := config.compressionOptions()
+	tempCompressed := filepath.Join(tempDir, walFileName+opts.Algorithm.Extension())
 	compressor := NewCompressor(a.log)
-	_, err := compressor.CompressWALFile(walFilePath, tempCompressed, 6)
+	_, err := compressor.CompressWALFile(walFilePath, tempCompressed, opts)
 	if err != nil {
 		return "", 0, fmt.Errorf("WAL compression failed: %w", err)
 	}
 
 	// Step 2: Encrypt compressed file
-	archivePath := filepath.Join(config.ArchiveDir, walFileName+".gz.enc")
+	archivePath := filepath.Join(config.ArchiveDir, walFileName+opts.Algorithm.Extension()+".enc")
 	encryptor := NewEncryptor(a.log)
 	encOpts := EncryptionOptions{
-		Key: config.EncryptionKey,
+		Key:        config.EncryptionKey,
+		Passphrase: config.EncryptionPassphrase,
 	}
 	
 	encryptedSize, err := encryptor.EncryptWALFile(tempCompressed, archivePath, encOpts)
@@ -214,10 +230,8 @@ func (a *Archiver) compressAndEncryptWAL(walFilePath, walFileName string, config
 // - Next 8 hex digits: log file ID
 // - Last 8 hex digits: segment number
 func ParseWALFileName(filename string) (timeline uint32, segment uint64, err error) {
-	// Remove any extensions (.gz, .enc, etc.)
-	base := filepath.Base(filename)
-	base = strings.TrimSuffix(base, ".gz")
-	base = strings.TrimSuffix(base, ".enc")
+	// Remove any extensions (.gz, .zst, .enc, etc.)
+	base, _, _ := trimArchiveSuffixes(filepath.Base(filename))
 
 	// WAL files are 24 hex characters
 	if len(base) != 24 {
@@ -239,6 +253,21 @@ func ParseWALFileName(filename string) (timeline uint32, segment uint64, err err
 	return timeline, segment, nil
 }
 
+// trimArchiveSuffixes strips the compression and encryption extensions the
+// archiver adds to WAL file names
+func trimArchiveSuffixes(filename string) (base string, compressed, encrypted bool) {
+	base = filename
+	if strings.HasSuffix(base, ".enc") {
+		base = strings.TrimSuffix(base, ".enc")
+		encrypted = true
+	}
+	if compression.FromExtension(base) != compression.None {
+		base = compression.TrimExtension(base)
+		compressed = true
+	}
+	return base, compressed, encrypted
+}
+
 // ListArchivedWALFiles returns all WAL files in the archive directory
 func (a *Archiver) ListArchivedWALFiles(config ArchiveConfig) ([]WALArchiveInfo, error) {
 	entries, err := os.ReadDir(config.ArchiveDir)
@@ -256,8 +285,8 @@ func (a *Archiver) ListArchivedWALFiles(config ArchiveConfig) ([]WALArchiveInfo,
 		}
 
 		filename := entry.Name()
-		// Skip non-WAL files (must be 24 hex chars possibly with .gz/.enc extensions)
-		baseName := strings.TrimSuffix(strings.TrimSuffix(filename, ".gz"), ".enc")
+		// Skip non-WAL files (must be 24 hex chars possibly with compression/.enc extensions)
+		baseName, compressed, encrypted := trimArchiveSuffixes(filename)
 		if len(baseName) != 24 {
 			continue
 		}
@@ -281,8 +310,8 @@ func (a *Archiver) ListArchivedWALFiles(config ArchiveConfig) ([]WALArchiveInfo,
 			Timeline:     timeline,
 			Segment:      segment,
 			ArchivedAt:   info.ModTime(),
-			Compressed:   strings.HasSuffix(filename, ".gz"),
-			Encrypted:    strings.HasSuffix(filename, ".enc"),
+			Compressed:   compressed,
+			Encrypted:    encrypted,
 		})
 	}
 
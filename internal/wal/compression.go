@@ -1,12 +1,13 @@
 package wal
 
 import (
-	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 
+	"dbbackup/internal/compression"
 	"dbbackup/internal/logger"
 )
 
@@ -22,10 +23,10 @@ func NewCompressor(log logger.Logger) *Compressor {
 	}
 }
 
-// CompressWALFile compresses a WAL file using gzip
-// Returns the path to the compressed file and the compressed size
-func (c *Compressor) CompressWALFile(sourcePath, destPath string, level int) (int64, error) {
-	c.log.Debug("Compressing WAL file", "source", sourcePath, "dest", destPath, "level", level)
+// CompressWALFile compresses a WAL file with the given algorithm and level
+// Returns the compressed size
+func (c *Compressor) CompressWALFile(sourcePath, destPath string, opts compression.Options) (int64, error) {
+	c.log.Debug("Compressing WAL file", "source", sourcePath, "dest", destPath, "compression", opts.String())
 
 	// Open source file
 	srcFile, err := os.Open(sourcePath)
@@ -48,22 +49,22 @@ func (c *Compressor) CompressWALFile(sourcePath, destPath string, level int) (in
 	}
 	defer dstFile.Close()
 
-	// Create gzip writer with specified compression level
-	gzWriter, err := gzip.NewWriterLevel(dstFile, level)
+	// Create compressor with specified algorithm and level
+	compWriter, err := compression.NewWriter(context.Background(), dstFile, opts)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create gzip writer: %w", err)
+		return 0, err
 	}
-	defer gzWriter.Close()
 
 	// Copy and compress
-	_, err = io.Copy(gzWriter, srcFile)
+	_, err = io.Copy(compWriter, srcFile)
 	if err != nil {
+		compWriter.Close()
 		return 0, fmt.Errorf("compression failed: %w", err)
 	}
 
-	// Close gzip writer to flush buffers
-	if err := gzWriter.Close(); err != nil {
-		return 0, fmt.Errorf("failed to close gzip writer: %w", err)
+	// Close compressor to flush buffers
+	if err := compWriter.Close(); err != nil {
+		return 0, fmt.Errorf("failed to finish compression: %w", err)
 	}
 
 	// Sync to disk
@@ -88,7 +89,8 @@ func (c *Compressor) CompressWALFile(sourcePath, destPath string, level int) (in
 	return compressedSize, nil
 }
 
-// DecompressWALFile decompresses a gzipped WAL file
+// DecompressWALFile decompresses a WAL file; the algorithm is detected from
+// the magic bytes
 func (c *Compressor) DecompressWALFile(sourcePath, destPath string) (int64, error) {
 	c.log.Debug("Decompressing WAL file", "source", sourcePath, "dest", destPath)
 
@@ -99,12 +101,12 @@ func (c *Compressor) DecompressWALFile(sourcePath, destPath string) (int64, erro
 	}
 	defer srcFile.Close()
 
-	// Create gzip reader
-	gzReader, err := gzip.NewReader(srcFile)
+	// Create decompressor
+	compReader, err := compression.NewReader(context.Background(), srcFile)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create gzip reader (file may be corrupted): %w", err)
+		return 0, fmt.Errorf("failed to create decompressor (file may be corrupted): %w", err)
 	}
-	defer gzReader.Close()
+	defer compReader.Close()
 
 	// Create destination file
 	dstFile, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
@@ -114,7 +116,7 @@ func (c *Compressor) DecompressWALFile(sourcePath, destPath string) (int64, erro
 	defer dstFile.Close()
 
 	// Decompress
-	written, err := io.Copy(dstFile, gzReader)
+	written, err := io.Copy(dstFile, compReader)
 	if err != nil {
 		return 0, fmt.Errorf("decompression failed: %w", err)
 	}
@@ -129,9 +131,9 @@ func (c *Compressor) DecompressWALFile(sourcePath, destPath string) (int64, erro
 }
 
 // CompressAndArchive compresses a WAL file and archives it in one operation
-func (c *Compressor) CompressAndArchive(walPath, archiveDir string, level int) (archivePath string, compressedSize int64, err error) {
+func (c *Compressor) CompressAndArchive(walPath, archiveDir string, opts compression.Options) (archivePath string, compressedSize int64, err error) {
 	walFileName := filepath.Base(walPath)
-	compressedFileName := walFileName + ".gz"
+	compressedFileName := walFileName + opts.Algorithm.Extension()
 	archivePath = filepath.Join(archiveDir, compressedFileName)
 
 	// Ensure archive directory exists
@@ -140,7 +142,7 @@ func (c *Compressor) CompressAndArchive(walPath, archiveDir string, level int) (
 	}
 
 	// Compress directly to archive location
-	compressedSize, err = c.CompressWALFile(walPath, archivePath, level)
+	compressedSize, err = c.CompressWALFile(walPath, archivePath, opts)
 	if err != nil {
 		// Clean up partial file on error
 		os.Remove(archivePath)
@@ -177,15 +179,18 @@ func (c *Compressor) VerifyCompressedFile(compressedPath string) error {
 	}
 	defer file.Close()
 
-	gzReader, err := gzip.NewReader(file)
+	compReader, err := compression.NewReader(context.Background(), file)
 	if err != nil {
-		return fmt.Errorf("invalid gzip format: %w", err)
+		return fmt.Errorf("invalid compressed format: %w", err)
 	}
-	defer gzReader.Close()
+	defer compReader.Close()
 
 	// Read first few bytes to verify decompression works
 	buf := make([]byte, 1024)
-	_, err = gzReader.Read(buf)
+	_, err = io.ReadFull(compReader, buf)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
 	if err != nil && err != io.EOF {
 		return fmt.Errorf("decompression verification failed: %w", err)
 	}
@@ -0,0 +1,48 @@
+package wal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultWALSegmentSize is PostgreSQL's default wal_segment_size (16MB)
+const DefaultWALSegmentSize int64 = 16 * 1024 * 1024
+
+// ParseLSN converts a textual LSN (e.g. "0/3000028") into a WAL byte position
+func ParseLSN(lsn string) (uint64, error) {
+	hi, lo, ok := strings.Cut(strings.TrimSpace(lsn), "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid LSN %q: expected format XXX/XXXXXXXX", lsn)
+	}
+
+	high, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q: %w", lsn, err)
+	}
+	low, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q: %w", lsn, err)
+	}
+
+	return high<<32 | low, nil
+}
+
+// FormatLSN converts a WAL byte position into PostgreSQL's textual LSN format
+func FormatLSN(pos uint64) string {
+	return fmt.Sprintf("%X/%X", pos>>32, pos&0xFFFFFFFF)
+}
+
+// WALFileName returns the name of the segment file containing pos on timeline
+func WALFileName(timeline uint32, pos uint64, segSize int64) string {
+	segsPerID := uint64(0x100000000) / uint64(segSize)
+	segNo := pos / uint64(segSize)
+	return fmt.Sprintf("%08X%08X%08X", timeline, segNo/segsPerID, segNo%segsPerID)
+}
+
+// AbsoluteSegment converts the segment value returned by ParseWALFileName
+// (log ID and segment packed into 64 bits) into a contiguous segment number
+func AbsoluteSegment(segment uint64, segSize int64) uint64 {
+	segsPerID := uint64(0x100000000) / uint64(segSize)
+	return (segment>>32)*segsPerID + segment&0xFFFFFFFF
+}
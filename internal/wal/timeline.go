@@ -203,9 +203,7 @@ func (tm *TimelineManager) scanWALSegments(archiveDir string, history *TimelineH
 		filename := filepath.Base(walFile)
 		
 		// Remove extensions
-		filename = strings.TrimSuffix(filename, ".gz.enc")
-		filename = strings.TrimSuffix(filename, ".enc")
-		filename = strings.TrimSuffix(filename, ".gz")
+		filename, _, _ = trimArchiveSuffixes(filename)
 
 		// Skip non-WAL files
 		if len(filename) != 24 {
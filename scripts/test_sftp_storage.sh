@@ -0,0 +1,289 @@
+#!/bin/bash
+
+# SFTP (OpenSSH) Testing Script for dbbackup
+# Tests backup, restore, verify, and cleanup against a local OpenSSH server
+
+set -e
+
+# Colors for output
+RED='\033[0;31m'
+GREEN='\033[0;32m'
+YELLOW='\033[1;33m'
+BLUE='\033[0;34m'
+NC='\033[0m' # No Color
+
+# Test configuration
+SFTP_HOST="localhost"
+SFTP_PORT="2222"
+SFTP_USER="backup"
+TEST_DIR="./sftp-test"
+SFTP_URI="sftp://$SFTP_USER@$SFTP_HOST:$SFTP_PORT/~/backups/"
+CLOUD_FLAGS="--cloud-provider sftp --cloud-bucket $SFTP_HOST:$SFTP_PORT --cloud-access-key $SFTP_USER --cloud-prefix ~/backups"
+
+# Key and host key used by the backend
+export DBBACKUP_SFTP_KEY="$TEST_DIR/keys/id_ed25519"
+export DBBACKUP_SFTP_KNOWN_HOSTS="$TEST_DIR/known_hosts"
+
+# Database connection details (from docker-compose)
+POSTGRES_HOST="localhost"
+POSTGRES_PORT="5436"
+POSTGRES_USER="testuser"
+POSTGRES_PASS="testpass"
+POSTGRES_DB="testdb"
+
+# Test counters
+TESTS_PASSED=0
+TESTS_FAILED=0
+
+# Functions
+print_header() {
+    echo -e "\n${BLUE}=== $1 ===${NC}\n"
+}
+
+print_success() {
+    echo -e "${GREEN}✓ $1${NC}"
+    ((TESTS_PASSED++))
+}
+
+print_error() {
+    echo -e "${RED}✗ $1${NC}"
+    ((TESTS_FAILED++))
+}
+
+print_info() {
+    echo -e "${YELLOW}ℹ $1${NC}"
+}
+
+# Create the client key before the server starts, so it can be authorized
+create_keys() {
+    print_header "Creating SSH Key"
+    mkdir -p "$TEST_DIR/keys"
+    if [ ! -f "$DBBACKUP_SFTP_KEY" ]; then
+        ssh-keygen -t ed25519 -N "" -f "$DBBACKUP_SFTP_KEY" > /dev/null
+        print_success "Created test key"
+    else
+        print_info "Using existing test key"
+    fi
+}
+
+wait_for_sftp() {
+    print_info "Waiting for OpenSSH server to be ready..."
+    for i in {1..30}; do
+        if ssh-keyscan -p $SFTP_PORT $SFTP_HOST > "$DBBACKUP_SFTP_KNOWN_HOSTS.tmp" 2>/dev/null && \
+            [ -s "$DBBACKUP_SFTP_KNOWN_HOSTS.tmp" ]; then
+            # ssh-keyscan writes [localhost]:2222 entries for non-standard ports
+            mv "$DBBACKUP_SFTP_KNOWN_HOSTS.tmp" "$DBBACKUP_SFTP_KNOWN_HOSTS"
+            print_success "OpenSSH server is ready (host key recorded)"
+            return 0
+        fi
+        sleep 1
+    done
+    print_error "OpenSSH server failed to start"
+    return 1
+}
+
+# Build dbbackup if needed
+build_dbbackup() {
+    print_header "Building dbbackup"
+    if [ ! -f "./dbbackup" ]; then
+        go build -o dbbackup .
+        print_success "Built dbbackup binary"
+    else
+        print_info "Using existing dbbackup binary"
+    fi
+}
+
+# Start services
+start_services() {
+    print_header "Starting OpenSSH and Database Services"
+    docker-compose -f docker-compose.sftp.yml up -d
+
+    sleep 5
+    wait_for_sftp
+
+    print_info "Waiting for PostgreSQL..."
+    sleep 3
+
+    print_success "All services started"
+}
+
+# Stop services
+stop_services() {
+    print_header "Stopping Services"
+    docker-compose -f docker-compose.sftp.yml down
+    print_success "Services stopped"
+}
+
+# Create test data in the database
+create_test_data() {
+    print_header "Creating Test Data"
+
+    PGPASSWORD=$POSTGRES_PASS psql -h $POSTGRES_HOST -p $POSTGRES_PORT -U $POSTGRES_USER -d $POSTGRES_DB <<EOSQL
+DROP TABLE IF EXISTS test_table;
+CREATE TABLE test_table (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO test_table (name) VALUES ('SFTP Test 1'), ('SFTP Test 2'), ('SFTP Test 3');
+EOSQL
+    print_success "Created PostgreSQL test data"
+}
+
+# Test 1: PostgreSQL backup to SFTP
+test_postgres_backup() {
+    print_header "Test 1: PostgreSQL Backup to SFTP"
+
+    ./dbbackup backup single $POSTGRES_DB \
+        --host $POSTGRES_HOST \
+        --port $POSTGRES_PORT \
+        --user $POSTGRES_USER \
+        --password $POSTGRES_PASS \
+        --backup-dir ./backups \
+        --cloud "$SFTP_URI"
+
+    if [ $? -eq 0 ]; then
+        print_success "PostgreSQL backup uploaded over SFTP"
+    else
+        print_error "PostgreSQL backup failed"
+        return 1
+    fi
+}
+
+# Test 2: List backups
+test_list_backups() {
+    print_header "Test 2: List SFTP Backups"
+
+    if ./dbbackup cloud list $CLOUD_FLAGS | grep -q "$POSTGRES_DB"; then
+        print_success "Listed SFTP backups"
+    else
+        print_error "Backup not found in listing"
+        return 1
+    fi
+
+    # Uploads are renamed into place, so no partial files may be left
+    if ssh -i "$DBBACKUP_SFTP_KEY" -o UserKnownHostsFile="$DBBACKUP_SFTP_KNOWN_HOSTS" \
+        -p $SFTP_PORT $SFTP_USER@$SFTP_HOST "ls -a backups" | grep -q '\.part$'; then
+        print_error "Partial upload left on the server"
+    else
+        print_success "No partial uploads left on the server"
+    fi
+}
+
+# Test 3: Verify backup
+test_verify_backup() {
+    print_header "Test 3: Verify SFTP Backup"
+
+    BACKUP=$(./dbbackup cloud list $CLOUD_FLAGS | grep -o "db_${POSTGRES_DB}_[^ ]*\.dump" | head -1)
+    ./dbbackup verify-backup "$SFTP_URI$BACKUP"
+
+    if [ $? -eq 0 ]; then
+        print_success "Backup verification successful"
+    else
+        print_error "Backup verification failed"
+        return 1
+    fi
+}
+
+# Test 4: Restore from SFTP
+test_restore_from_sftp() {
+    print_header "Test 4: Restore from SFTP"
+
+    BACKUP=$(./dbbackup cloud list $CLOUD_FLAGS | grep -o "db_${POSTGRES_DB}_[^ ]*\.dump" | head -1)
+    ./dbbackup restore single "$SFTP_URI$BACKUP" \
+        --host $POSTGRES_HOST \
+        --port $POSTGRES_PORT \
+        --user $POSTGRES_USER \
+        --password $POSTGRES_PASS \
+        --target testdb_restored \
+        --create \
+        --confirm
+
+    if [ $? -eq 0 ]; then
+        print_success "Restored from SFTP backup"
+
+        COUNT=$(PGPASSWORD=$POSTGRES_PASS psql -h $POSTGRES_HOST -p $POSTGRES_PORT -U $POSTGRES_USER -d testdb_restored -t -c "SELECT COUNT(*) FROM test_table;")
+        if [ "$COUNT" -eq 3 ]; then
+            print_success "Restored data verified (3 rows)"
+        else
+            print_error "Restored data incorrect (expected 3 rows, got $COUNT)"
+        fi
+    else
+        print_error "Restore from SFTP failed"
+        return 1
+    fi
+}
+
+# Test 5: Cleanup old backups
+test_cleanup() {
+    print_header "Test 5: Cleanup Old Backups"
+
+    for i in {1..4}; do
+        ./dbbackup backup single $POSTGRES_DB \
+            --host $POSTGRES_HOST \
+            --port $POSTGRES_PORT \
+            --user $POSTGRES_USER \
+            --password $POSTGRES_PASS \
+            --backup-dir ./backups \
+            --cloud "$SFTP_URI"
+        sleep 1
+    done
+
+    print_success "Created additional test backups"
+
+    ./dbbackup cleanup "$SFTP_URI" --retention-days 0 --min-backups 2
+
+    if [ $? -eq 0 ]; then
+        COUNT=$(./dbbackup cloud list $CLOUD_FLAGS | grep -c "db_${POSTGRES_DB}_.*\.dump" || true)
+        if [ "$COUNT" -le 2 ]; then
+            print_success "Verified cleanup (kept 2 backups)"
+        else
+            print_error "Cleanup failed (expected 2 backups, found $COUNT)"
+        fi
+    else
+        print_error "Cleanup failed"
+        return 1
+    fi
+}
+
+# Main test execution
+main() {
+    print_header "SFTP (OpenSSH) Integration Tests"
+
+    # Setup
+    build_dbbackup
+    create_keys
+    start_services
+    create_test_data
+
+    # Run tests
+    test_postgres_backup
+    test_list_backups
+    test_verify_backup
+    test_restore_from_sftp
+    test_cleanup
+
+    # Cleanup
+    print_header "Cleanup"
+    rm -rf ./backups
+
+    # Summary
+    print_header "Test Summary"
+    echo -e "${GREEN}Passed: $TESTS_PASSED${NC}"
+    echo -e "${RED}Failed: $TESTS_FAILED${NC}"
+
+    if [ $TESTS_FAILED -eq 0 ]; then
+        print_success "All tests passed!"
+        stop_services
+        rm -rf "$TEST_DIR"
+        exit 0
+    else
+        print_error "Some tests failed"
+        print_info "Leaving services running for debugging"
+        print_info "Run 'docker-compose -f docker-compose.sftp.yml down' to stop services"
+        exit 1
+    fi
+}
+
+# Run main
+main